@@ -57,6 +57,7 @@ import (
 	"sigs.k8s.io/cloud-provider-azure/cmd/cloud-controller-manager/app/options"
 	armmetrics "sigs.k8s.io/cloud-provider-azure/pkg/azclient/metrics"
 	"sigs.k8s.io/cloud-provider-azure/pkg/log"
+	"sigs.k8s.io/cloud-provider-azure/pkg/nodeipam/ipam"
 	"sigs.k8s.io/cloud-provider-azure/pkg/provider"
 	"sigs.k8s.io/cloud-provider-azure/pkg/trace"
 	"sigs.k8s.io/cloud-provider-azure/pkg/trace/metrics"
@@ -71,6 +72,12 @@ const (
 	ConfigzName = "cloudcontrollermanager.config.k8s.io"
 )
 
+// servicePlanHandler serves the plans of the LoadBalancer services. The cloud is set after it is initialized in Run.
+var servicePlanHandler = provider.NewServicePlanHandler()
+
+// nodeIPAMAllocationHandler serves the pod CIDR allocations. The allocator is set after the node ipam controller is started.
+var nodeIPAMAllocationHandler = ipam.NewAllocationHandler()
+
 // NewCloudControllerManagerCommand creates a *cobra.Command object with default parameters
 func NewCloudControllerManagerCommand() *cobra.Command {
 	s, err := options.NewCloudControllerManagerOptions()
@@ -324,6 +331,8 @@ func StartHTTPServer(ctx context.Context, c *cloudcontrollerconfig.CompletedConf
 		unsecuredMux := genericcontrollermanager.NewBaseHandler(&c.ComponentConfig.Generic.Debugging, healthzHandler)
 
 		unsecuredMux.Handle("/metrics/v2", traceProvider.MetricsHTTPHandler()) // Add metricsv2 endpoint
+		unsecuredMux.Handle(provider.ServicePlanPath, servicePlanHandler)
+		unsecuredMux.Handle(ipam.AllocationsPath, nodeIPAMAllocationHandler)
 
 		handler := genericcontrollermanager.BuildHandlerChain(unsecuredMux, &c.Authorization, &c.Authentication)
 		// TODO: handle stoppedCh returned by c.SecureServing.Serve
@@ -362,6 +371,13 @@ func Run(ctx context.Context, c *cloudcontrollerconfig.CompletedConfig, h *contr
 	if cloud == nil {
 		klog.Fatalf("cloud provider is nil, please check if the --cloud-config is set properly")
 	}
+	if az, ok := cloud.(*provider.Cloud); ok {
+		servicePlanHandler.SetCloud(az, c.ComponentConfig.KubeCloudShared.ClusterName)
+		az.StartDriftDetection(ctx, c.ComponentConfig.KubeCloudShared.ClusterName)
+		az.StartOrphanedResourceGC(ctx, c.ComponentConfig.KubeCloudShared.ClusterName)
+		az.StartPrivateLinkServiceConnectionApproval(ctx, c.ComponentConfig.KubeCloudShared.ClusterName)
+		az.StartServiceMigration(ctx)
+	}
 
 	if !cloud.HasClusterID() {
 		if c.ComponentConfig.KubeCloudShared.AllowUntaggedCloud {
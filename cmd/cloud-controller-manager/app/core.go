@@ -205,19 +205,53 @@ func startNodeIpamController(ctx context.Context, _ genericcontrollermanager.Con
 	// get list of node cidr mask sizes
 	nodeCIDRMaskSizes := getNodeCIDRMaskSizes(clusterCIDRs, nodeCIDRMaskSizeIPv4, nodeCIDRMaskSizeIPv6)
 
+	// get list of node pools with their own cidrs
+	var nodePools []ipam.NodePool
+	if configFile := strings.TrimSpace(completedConfig.NodeIPAMControllerConfig.NodePoolCIDRsConfigFile); configFile != "" {
+		nodePoolCIDRsConfig, err := ipam.LoadNodePoolCIDRsConfig(configFile)
+		if err != nil {
+			return nil, false, err
+		}
+		nodePools, err = ipam.NewNodePools(nodePoolCIDRsConfig, clusterCIDRs)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid node pool cidrs config file %s: %w", configFile, err)
+		}
+	}
+
+	// get list of the additional cluster cidrs added without restarting
+	var additionalClusterCIDRs []*net.IPNet
+	kubeClient := completedConfig.ClientBuilder.ClientOrDie("node-controller")
+	configMapName, configMapNamespace := completedConfig.NodeIPAMControllerConfig.ClusterCIDRsConfigMapName, completedConfig.NodeIPAMControllerConfig.ClusterCIDRsConfigMapNamespace
+	if configMapName != "" {
+		// a bad ConfigMap must not stop the controller, the watcher retries it on the next update
+		additionalClusterCIDRs, err = ipam.GetAdditionalClusterCIDRs(ctx, kubeClient, configMapNamespace, configMapName)
+		if err != nil {
+			klog.Errorf("failed to get additional cluster cidrs, skipping them: %v", err)
+			additionalClusterCIDRs = nil
+		}
+	}
+
 	nodeIpamController, err := nodeipamcontroller.NewNodeIpamController(
 		completedConfig.SharedInformers.Core().V1().Nodes(),
 		cloud,
-		completedConfig.ClientBuilder.ClientOrDie("node-controller"),
+		kubeClient,
 		clusterCIDRs,
 		serviceCIDR,
 		secondaryServiceCIDR,
 		nodeCIDRMaskSizes,
+		nodePools,
+		additionalClusterCIDRs,
 		ipam.CIDRAllocatorType(completedConfig.ComponentConfig.KubeCloudShared.CIDRAllocatorType),
 	)
 	if err != nil {
 		return nil, true, err
 	}
+	nodeIPAMAllocationHandler.SetAllocator(nodeIpamController.CIDRAllocator())
+	if configMapName != "" {
+		if err := ipam.RunClusterCIDRConfigMapWatcher(ctx, kubeClient, configMapNamespace, configMapName, nodeIpamController.CIDRAllocator(), ResyncPeriod(completedConfig)()); err != nil {
+			return nil, true, err
+		}
+	}
 	go nodeIpamController.Run(ctx)
 	return nil, true, nil
 }
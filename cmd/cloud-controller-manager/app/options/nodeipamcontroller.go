@@ -40,6 +40,9 @@ func (o *NodeIPAMControllerOptions) AddFlags(fs *pflag.FlagSet) {
 	fs.Int32Var(&o.NodeCIDRMaskSize, "node-cidr-mask-size", consts.DefaultNodeCIDRMaskSize, "Mask size for node cidr in cluster. Default is 24 for IPv4 and 64 for IPv6.")
 	fs.Int32Var(&o.NodeCIDRMaskSizeIPv4, "node-cidr-mask-size-ipv4", 0, "Mask size for IPv4 node cidr in dual-stack cluster. Default is 24.")
 	fs.Int32Var(&o.NodeCIDRMaskSizeIPv6, "node-cidr-mask-size-ipv6", 0, "Mask size for IPv6 node cidr in dual-stack cluster. Default is 64.")
+	fs.StringVar(&o.NodePoolCIDRsConfigFile, "node-pool-cidrs-config-file", "", "Path of the file that configures the node pools allocating pod CIDRs from their own CIDRs, selected by node labels. Only supported by the RangeAllocator.")
+	fs.StringVar(&o.ClusterCIDRsConfigMapName, "cluster-cidrs-configmap-name", "", "The name of the ConfigMap of the additional cluster CIDRs that are watched and added without restarting. The CIDRs are comma separated in the additionalClusterCIDRs key. Only supported by the RangeAllocator.")
+	fs.StringVar(&o.ClusterCIDRsConfigMapNamespace, "cluster-cidrs-configmap-namespace", "kube-system", "The namespace of the ConfigMap of the additional cluster CIDRs, default to 'kube-system'.")
 }
 
 // ApplyTo fills up NodeIpamController config with options.
@@ -60,6 +63,9 @@ func (o *NodeIPAMControllerOptions) ApplyTo(cfg *nodeipamconfig.NodeIPAMControll
 	cfg.NodeCIDRMaskSize = o.NodeCIDRMaskSize
 	cfg.NodeCIDRMaskSizeIPv4 = o.NodeCIDRMaskSizeIPv4
 	cfg.NodeCIDRMaskSizeIPv6 = o.NodeCIDRMaskSizeIPv6
+	cfg.NodePoolCIDRsConfigFile = o.NodePoolCIDRsConfigFile
+	cfg.ClusterCIDRsConfigMapName = o.ClusterCIDRsConfigMapName
+	cfg.ClusterCIDRsConfigMapNamespace = o.ClusterCIDRsConfigMapNamespace
 
 	return nil
 }
@@ -82,10 +88,11 @@ func (o *NodeIPAMControllerOptions) Validate() []error {
 func defaultNodeIPAMControllerOptions() *NodeIPAMControllerOptions {
 	return &NodeIPAMControllerOptions{
 		&nodeipamconfig.NodeIPAMControllerConfiguration{
-			ServiceCIDR:          "",
-			NodeCIDRMaskSize:     consts.DefaultNodeCIDRMaskSize,
-			NodeCIDRMaskSizeIPv4: 0,
-			NodeCIDRMaskSizeIPv6: 0,
+			ServiceCIDR:                    "",
+			NodeCIDRMaskSize:               consts.DefaultNodeCIDRMaskSize,
+			NodeCIDRMaskSizeIPv4:           0,
+			NodeCIDRMaskSizeIPv6:           0,
+			ClusterCIDRsConfigMapNamespace: "kube-system",
 		},
 	}
 }
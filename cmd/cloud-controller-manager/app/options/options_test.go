@@ -99,7 +99,8 @@ func TestDefaultFlags(t *testing.T) {
 		},
 		NodeIPAMController: &NodeIPAMControllerOptions{
 			NodeIPAMControllerConfiguration: &config.NodeIPAMControllerConfiguration{
-				NodeCIDRMaskSize: consts.DefaultNodeCIDRMaskSize,
+				NodeCIDRMaskSize:               consts.DefaultNodeCIDRMaskSize,
+				ClusterCIDRsConfigMapNamespace: "kube-system",
 			},
 		},
 		SecureServing: (&apiserveroptions.SecureServingOptions{
@@ -245,7 +246,8 @@ func TestAddFlags(t *testing.T) {
 		},
 		NodeIPAMController: &NodeIPAMControllerOptions{
 			NodeIPAMControllerConfiguration: &config.NodeIPAMControllerConfiguration{
-				NodeCIDRMaskSize: consts.DefaultNodeCIDRMaskSize,
+				NodeCIDRMaskSize:               consts.DefaultNodeCIDRMaskSize,
+				ClusterCIDRsConfigMapNamespace: "kube-system",
 			},
 		},
 		SecureServing: (&apiserveroptions.SecureServingOptions{
@@ -0,0 +1,61 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The lb-plan command prints the changes that the cloud provider would make to the Azure
+// resources of a LoadBalancer service, computed against a snapshot of the resources.
+
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/spf13/cobra"
+	"k8s.io/component-base/logs"
+	"k8s.io/klog/v2"
+)
+
+func main() {
+	var opts planOptions
+
+	command := &cobra.Command{
+		Use:   "lb-plan serviceManifest",
+		Short: "Plan the reconciliation of a LoadBalancer service",
+		Long: `Print the changes of the load balancer, public IPs and security group that the cloud provider would make
+for the service as JSON, computed against a snapshot of the Azure resources without sending any request to Azure`,
+		Args: cobra.ExactArgs(1),
+		Run: func(_ *cobra.Command, args []string) {
+			opts.serviceFile = args[0]
+			if err := runPlan(context.Background(), opts, os.Stdout); err != nil {
+				klog.Errorf("Failed to plan the service: %v", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	logs.InitLogs()
+	defer logs.FlushLogs()
+
+	// Flags
+	command.Flags().StringVar(&opts.cloudConfigFile, "cloud-config", "", "The path to the cloud provider configuration file")
+	command.Flags().StringVar(&opts.snapshotFile, "snapshot", "",
+		"The path to the JSON snapshot of the Azure resources with the fields loadBalancers, publicIPAddresses, securityGroup and services")
+	command.Flags().StringVar(&opts.clusterName, "cluster-name", "kubernetes", "The name of the cluster")
+
+	if err := command.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
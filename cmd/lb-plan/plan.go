@@ -0,0 +1,106 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/provider"
+	"sigs.k8s.io/cloud-provider-azure/pkg/provider/config"
+)
+
+type planOptions struct {
+	cloudConfigFile string
+	snapshotFile    string
+	clusterName     string
+	serviceFile     string
+}
+
+// runPlan plans the service in the manifest against the snapshot and writes the plan to out.
+func runPlan(ctx context.Context, opts planOptions, out io.Writer) error {
+	cfg := &config.Config{}
+	if opts.cloudConfigFile != "" {
+		f, err := os.Open(opts.cloudConfigFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		cfg, err = config.ParseConfig(f)
+		if err != nil {
+			return fmt.Errorf("parse cloud config %s: %w", opts.cloudConfigFile, err)
+		}
+	}
+	az, err := provider.NewCloudForPlan(cfg)
+	if err != nil {
+		return err
+	}
+
+	service, err := readService(opts.serviceFile)
+	if err != nil {
+		return err
+	}
+
+	inventory := &provider.PlanInventory{}
+	if opts.snapshotFile != "" {
+		data, err := os.ReadFile(opts.snapshotFile)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, inventory); err != nil {
+			return fmt.Errorf("parse snapshot %s: %w", opts.snapshotFile, err)
+		}
+	}
+
+	plan, err := az.PlanService(ctx, opts.clusterName, service, inventory)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(plan)
+}
+
+// readService reads the service from the YAML or JSON manifest.
+func readService(path string) (*v1.Service, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	service := &v1.Service{}
+	if err := yaml.Unmarshal(data, service); err != nil {
+		return nil, fmt.Errorf("parse service manifest %s: %w", path, err)
+	}
+
+	// The names of the frontend IP configurations and rules are derived from the UID of the service.
+	if service.UID == "" {
+		return nil, fmt.Errorf("service manifest %s has no metadata.uid, use the manifest of an existing service", path)
+	}
+	// The API server defaults the IP families of the service.
+	if len(service.Spec.IPFamilies) == 0 {
+		service.Spec.IPFamilies = []v1.IPFamily{v1.IPv4Protocol}
+	}
+	return service, nil
+}
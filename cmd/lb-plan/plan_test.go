@@ -0,0 +1,90 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/provider"
+)
+
+const testServiceManifest = `apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: default
+  uid: 4b8b4b5a-27a6-4a2e-9e0b-0b6f3c1f2e11
+spec:
+  type: LoadBalancer
+  ports:
+  - port: 80
+    protocol: TCP
+    nodePort: 30080
+`
+
+func writeTestFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestRunPlan(t *testing.T) {
+	opts := planOptions{
+		clusterName: "kubernetes",
+		serviceFile: writeTestFile(t, "service.yaml", testServiceManifest),
+		snapshotFile: writeTestFile(t, "snapshot.json", `{
+  "securityGroup": {"name": "nsg", "properties": {"securityRules": []}}
+}`),
+	}
+
+	var out bytes.Buffer
+	assert.NoError(t, runPlan(context.Background(), opts, &out))
+
+	var plan provider.ServicePlan
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &plan))
+	assert.Equal(t, "default/web", plan.Service)
+	assert.Equal(t, "kubernetes", plan.LoadBalancer)
+	assert.NotEmpty(t, plan.Changes)
+	assert.Equal(t, provider.PlanResourceTypeLoadBalancer, plan.Changes[0].ResourceType)
+	assert.Equal(t, provider.PlanActionCreate, plan.Changes[0].Action)
+}
+
+func TestReadService(t *testing.T) {
+	service, err := readService(writeTestFile(t, "service.yaml", testServiceManifest))
+	assert.NoError(t, err)
+	assert.Equal(t, "web", service.Name)
+	assert.Len(t, service.Spec.IPFamilies, 1)
+
+	_, err = readService(writeTestFile(t, "service.yaml", `apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  type: LoadBalancer
+`))
+	assert.ErrorContains(t, err, "metadata.uid")
+
+	_, err = readService(filepath.Join(t.TempDir(), "not-found.yaml"))
+	assert.Error(t, err)
+}
@@ -4,6 +4,7 @@ go 1.23.1
 
 require (
 	github.com/pires/go-proxyproto v0.8.0
+	google.golang.org/grpc v1.65.0
 	k8s.io/component-base v0.31.3
 	k8s.io/klog/v2 v2.130.1
 )
@@ -33,6 +34,7 @@ require (
 	golang.org/x/net v0.26.0 // indirect
 	golang.org/x/sys v0.21.0 // indirect
 	golang.org/x/text v0.16.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240701130421-f6361c86f094 // indirect
 	google.golang.org/protobuf v1.34.2 // indirect
 	gopkg.in/inf.v0 v0.9.1 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
@@ -0,0 +1,228 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// healthPolicy determines how the results of the health targets are aggregated.
+type healthPolicy string
+
+const (
+	// healthPolicyAll reports healthy if all targets are healthy.
+	healthPolicyAll healthPolicy = "all"
+	// healthPolicyAny reports healthy if any target is healthy.
+	healthPolicyAny healthPolicy = "any"
+)
+
+func parseHealthPolicy(policy string) (healthPolicy, error) {
+	switch healthPolicy(strings.ToLower(policy)) {
+	case healthPolicyAll:
+		return healthPolicyAll, nil
+	case healthPolicyAny:
+		return healthPolicyAny, nil
+	default:
+		return "", fmt.Errorf("unsupported health policy %q, supported values are %q and %q", policy, healthPolicyAll, healthPolicyAny)
+	}
+}
+
+// targetResult is the result of checking a health target.
+type targetResult struct {
+	Target     string
+	StatusCode int
+	// ExpectedStatusCodes are the status codes that the target is healthy with, which are 2xx if not set.
+	ExpectedStatusCodes statusCodes
+	// Status is the serving status of a gRPC target.
+	Status   string
+	Err      error
+	Duration time.Duration
+}
+
+// Healthy returns true if the target responds with an expected status code.
+func (r targetResult) Healthy() bool {
+	if r.Err != nil {
+		return false
+	}
+	if r.ExpectedStatusCodes != nil {
+		return r.ExpectedStatusCodes.Has(r.StatusCode)
+	}
+	return r.StatusCode >= http.StatusOK && r.StatusCode < http.StatusMultipleChoices
+}
+
+func (r targetResult) String() string {
+	if r.Err != nil {
+		return fmt.Sprintf("error: %s", r.Err)
+	}
+	if r.Status != "" {
+		return r.Status
+	}
+	return fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode))
+}
+
+// healthAggregator checks the local health targets for each probe request,
+// and reports healthy if the targets are healthy according to the policy.
+type healthAggregator struct {
+	targets []*url.URL
+	policy  healthPolicy
+	client  *http.Client
+}
+
+// newHealthAggregator returns a healthAggregator of the targets. A target without a path
+// is checked with the path and the query of the probe request.
+func newHealthAggregator(targets []string, policy healthPolicy, timeout time.Duration) (*healthAggregator, error) {
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no health target is specified")
+	}
+	urls := make([]*url.URL, 0, len(targets))
+	for _, target := range targets {
+		u, err := url.Parse(target)
+		if err != nil {
+			return nil, fmt.Errorf("invalid health target %q: %w", target, err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+			return nil, fmt.Errorf("invalid health target %q: must be an absolute http or https URL", target)
+		}
+		urls = append(urls, u)
+	}
+	return &healthAggregator{
+		targets: urls,
+		policy:  policy,
+		client: &http.Client{
+			Timeout: timeout,
+			// The health endpoints are local, and their redirects are not followed.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}, nil
+}
+
+// targetURL returns the URL to check the target with for the probe request.
+func targetURL(target *url.URL, r *http.Request) string {
+	if target.Path != "" && target.Path != "/" {
+		return target.String()
+	}
+	u := *target
+	u.Path = r.URL.Path
+	u.RawQuery = r.URL.RawQuery
+	return u.String()
+}
+
+// check checks all targets concurrently, and returns the results in the order of the targets.
+func (a *healthAggregator) check(ctx context.Context, r *http.Request) []targetResult {
+	results := make([]targetResult, len(a.targets))
+	var wg sync.WaitGroup
+	for i, target := range a.targets {
+		wg.Add(1)
+		go func(i int, target *url.URL) {
+			defer wg.Done()
+			results[i] = checkTarget(ctx, a.client, target.String(), targetURL(target, r))
+			recordTargetCheck(results[i])
+		}(i, target)
+	}
+	wg.Wait()
+	return results
+}
+
+// checkTarget sends a GET request to the URL of the target, and returns the status code of the response.
+func checkTarget(ctx context.Context, client *http.Client, target, u string) targetResult {
+	start := time.Now()
+	result := targetResult{Target: target}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		result.Err = err
+		result.Duration = time.Since(start)
+		return result
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	result.StatusCode = resp.StatusCode
+	result.Duration = time.Since(start)
+	return result
+}
+
+// healthy aggregates the results of the targets by the policy.
+func (a *healthAggregator) healthy(results []targetResult) bool {
+	for _, result := range results {
+		if a.policy == healthPolicyAny && result.Healthy() {
+			return true
+		}
+		if a.policy == healthPolicyAll && !result.Healthy() {
+			return false
+		}
+	}
+	return a.policy == healthPolicyAll
+}
+
+func (a *healthAggregator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	results := a.check(r.Context(), r)
+	healthy := a.healthy(results)
+
+	statusCode := http.StatusOK
+	if !healthy {
+		statusCode = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(statusCode)
+	for _, result := range results {
+		fmt.Fprintf(w, "%s: %s\n", result.Target, result)
+	}
+
+	duration := time.Since(start)
+	recordProbe(healthy, duration)
+	logAccess(r, statusCode, duration, results)
+}
+
+// logAccess logs the probe request, with the information of the PROXY protocol header if any.
+func logAccess(r *http.Request, statusCode int, duration time.Duration, results []targetResult) {
+	keysAndValues := []interface{}{
+		"remoteAddr", r.RemoteAddr,
+		"method", r.Method,
+		"path", r.URL.Path,
+		"status", statusCode,
+		"duration", duration,
+	}
+	if info := proxyHeaderInfoFromContext(r.Context()); info != nil {
+		keysAndValues = append(keysAndValues, info.keysAndValues()...)
+	}
+	var unhealthyTargets []string
+	for _, result := range results {
+		if !result.Healthy() {
+			unhealthyTargets = append(unhealthyTargets, fmt.Sprintf("%s: %s", result.Target, result))
+		}
+	}
+	if len(unhealthyTargets) > 0 {
+		keysAndValues = append(keysAndValues, "unhealthyTargets", unhealthyTargets)
+	}
+	klog.V(2).InfoS("Health probe", keysAndValues...)
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pires/go-proxyproto"
+	"github.com/pires/go-proxyproto/tlvparse"
+)
+
+func TestHealthAggregator(t *testing.T) {
+	var (
+		lock  sync.Mutex
+		paths []string
+	)
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		paths = append(paths, r.URL.Path)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer healthy.Close()
+	unhealthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unhealthy.Close()
+
+	for _, tc := range []struct {
+		desc       string
+		targets    []string
+		policy     healthPolicy
+		statusCode int
+	}{
+		{
+			desc:       "all targets are healthy",
+			targets:    []string{healthy.URL, healthy.URL + "/health"},
+			policy:     healthPolicyAll,
+			statusCode: http.StatusOK,
+		},
+		{
+			desc:       "one of the targets is unhealthy with policy all",
+			targets:    []string{healthy.URL, unhealthy.URL},
+			policy:     healthPolicyAll,
+			statusCode: http.StatusServiceUnavailable,
+		},
+		{
+			desc:       "one of the targets is unhealthy with policy any",
+			targets:    []string{unhealthy.URL, healthy.URL},
+			policy:     healthPolicyAny,
+			statusCode: http.StatusOK,
+		},
+		{
+			desc:       "all targets are unhealthy with policy any",
+			targets:    []string{unhealthy.URL, "http://127.0.0.1:1"},
+			policy:     healthPolicyAny,
+			statusCode: http.StatusServiceUnavailable,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			paths = nil
+			aggregator, err := newHealthAggregator(tc.targets, tc.policy, time.Second)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			w := httptest.NewRecorder()
+			aggregator.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+			if w.Code != tc.statusCode {
+				t.Errorf("expected status %d, got %d: %s", tc.statusCode, w.Code, w.Body.String())
+			}
+			for _, path := range paths {
+				if path != "/healthz" && path != "/health" {
+					t.Errorf("unexpected path %q", path)
+				}
+			}
+		})
+	}
+}
+
+func TestNewHealthAggregatorInvalidTarget(t *testing.T) {
+	for _, target := range []string{"localhost:10256", "tcp://localhost:10256", "http://"} {
+		if _, err := newHealthAggregator([]string{target}, healthPolicyAll, time.Second); err == nil {
+			t.Errorf("expected error for target %q", target)
+		}
+	}
+	if _, err := parseHealthPolicy("some"); err == nil {
+		t.Errorf("expected error for unsupported policy")
+	}
+}
+
+func TestTargetURL(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/healthz?verbose=true", nil)
+	for target, expected := range map[string]string{
+		"http://localhost:10256":        "http://localhost:10256/healthz?verbose=true",
+		"http://localhost:10256/":       "http://localhost:10256/healthz?verbose=true",
+		"http://localhost:9099/health":  "http://localhost:9099/health",
+		"https://localhost:9099/readyz": "https://localhost:9099/readyz",
+	} {
+		u, _ := url.Parse(target)
+		if actual := targetURL(u, r); actual != expected {
+			t.Errorf("expected %q for target %q, got %q", expected, target, actual)
+		}
+	}
+}
+
+func TestParseProxyHeader(t *testing.T) {
+	header := proxyproto.HeaderProxyFromAddrs(2,
+		&net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 12345},
+		&net.TCPAddr{IP: net.ParseIP("10.0.0.2"), Port: 10356},
+	)
+	if err := header.SetTLVs([]proxyproto.TLV{
+		{Type: tlvparse.PP2_TYPE_AZURE, Value: []byte{tlvparse.PP2_SUBTYPE_AZURE_PRIVATEENDPOINT_LINKID, 0x01, 0x02, 0x00, 0x00}},
+		{Type: proxyproto.PP2_TYPE_AUTHORITY, Value: []byte("example.com")},
+		{Type: proxyproto.PP2_TYPE_UNIQUE_ID, Value: []byte{0xab, 0xcd}},
+		{Type: 0xE1, Value: []byte{0x01}},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	info := parseProxyHeader(header)
+	if info.Err != nil {
+		t.Fatalf("unexpected error: %v", info.Err)
+	}
+	if !info.HasLinkID || info.LinkID != 0x0201 {
+		t.Errorf("expected LinkID 0x0201, got %v %#x", info.HasLinkID, info.LinkID)
+	}
+	if info.Source != "10.0.0.1:12345" || info.Destination != "10.0.0.2:10356" {
+		t.Errorf("unexpected addresses %s -> %s", info.Source, info.Destination)
+	}
+	if info.Authority != "example.com" || info.UniqueID != "abcd" {
+		t.Errorf("unexpected authority %q or unique ID %q", info.Authority, info.UniqueID)
+	}
+	if len(info.UnknownTLVs) != 1 || info.UnknownTLVs[0] != "0xe1" {
+		t.Errorf("unexpected unknown TLVs %v", info.UnknownTLVs)
+	}
+}
@@ -17,44 +17,94 @@ limitations under the License.
 package main
 
 import (
+	"crypto/tls"
 	"flag"
 	"fmt"
 	"net"
 	"net/http"
-	"net/http/httputil"
-	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/pires/go-proxyproto"
 
 	"k8s.io/component-base/logs"
+	"k8s.io/component-base/metrics/legacyregistry"
 	"k8s.io/klog/v2"
 )
 
+// stringSliceFlag is a flag that can be repeated or split by comma.
+type stringSliceFlag []string
+
+func (f *stringSliceFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringSliceFlag) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*f = append(*f, v)
+		}
+	}
+	return nil
+}
+
 func main() {
 	logs.InitLogs()
 	defer logs.FlushLogs()
 
-	var healthCheckPort, targetPort int
+	var (
+		healthCheckPort, targetPort, metricsPort int
+		targets                                  stringSliceFlag
+		policy, tlsCertFile, tlsKeyFile          string
+		targetTimeout                            time.Duration
+		enableProbeTranslation                   bool
+	)
 	flag.IntVar(&healthCheckPort, "health-check-port", 10356, "Port number for the health check service that exposes to the user and will be forwarded to the targetPort.")
-	flag.IntVar(&targetPort, "target-port", 10256, "Port number that receives the forwarded traffic from the health check port, and will be listened by the kube-proxy.")
+	flag.IntVar(&targetPort, "target-port", 10256, "Port number that receives the forwarded traffic from the health check port, and will be listened by the kube-proxy. It is ignored if --target is set.")
+	flag.Var(&targets, "target", "URL of a local health endpoint, which can be repeated or split by comma, e.g. http://localhost:10256 for the kube-proxy and http://localhost:9099/health for a CNI agent. The path of the probe request is used if the URL has no path.")
+	flag.StringVar(&policy, "policy", string(healthPolicyAll), "Policy of aggregating the health targets, `all` reports healthy if all targets are healthy, and `any` reports healthy if any target is healthy.")
+	flag.DurationVar(&targetTimeout, "target-timeout", 5*time.Second, "Timeout of checking a health target.")
+	flag.StringVar(&tlsCertFile, "tls-cert-file", "", "File containing the certificate to serve the health check over HTTPS. HTTPS is enabled if both --tls-cert-file and --tls-key-file are set.")
+	flag.StringVar(&tlsKeyFile, "tls-key-file", "", "File containing the private key matching --tls-cert-file.")
+	flag.BoolVar(&enableProbeTranslation, "enable-probe-translation", false, "Translate the probes on /grpc/<port>[/<service>], /http/<port>/<status codes><path> and /https/<port>/<status codes><path> into the gRPC health checks and the HTTP(S) checks with expected status codes of the ports on the node.")
+	flag.IntVar(&metricsPort, "metrics-port", 0, "Port number for serving the Prometheus metrics on /metrics. The metrics are disabled unless it is set.")
 	flag.Parse()
 
-	targetUrl, _ := url.Parse(fmt.Sprintf("http://localhost:%s", strconv.Itoa(targetPort)))
-
-	proxy := httputil.NewSingleHostReverseProxy(targetUrl)
-	klog.Infof("target url: %s", targetUrl)
+	if len(targets) == 0 {
+		targets = stringSliceFlag{fmt.Sprintf("http://localhost:%s", strconv.Itoa(targetPort))}
+	}
+	healthPolicy, err := parseHealthPolicy(policy)
+	if err != nil {
+		klog.Fatalf("invalid --policy: %s", err)
+	}
+	aggregator, err := newHealthAggregator(targets, healthPolicy, targetTimeout)
+	if err != nil {
+		klog.Fatalf("invalid --target: %s", err)
+	}
+	klog.Infof("health targets: %v, policy: %s", []string(targets), healthPolicy)
 
-	http.Handle("/", proxy)
-	klog.Infof("proxying from port %d to port %d", healthCheckPort, targetPort)
+	if metricsPort != 0 {
+		registerMetrics()
+		go func() {
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", legacyregistry.Handler())
+			klog.Infof("serving metrics on port %d", metricsPort)
+			if err := http.ListenAndServe(fmt.Sprintf("0.0.0.0:%s", strconv.Itoa(metricsPort)), mux); err != nil {
+				klog.Errorf("failed to serve metrics: %s", err)
+				panic(err)
+			}
+		}()
+	}
 
 	listener, err := net.Listen("tcp", fmt.Sprintf("0.0.0.0:%s", strconv.Itoa(healthCheckPort)))
 	if err != nil {
-		klog.Errorf("failed to listen on port %d: %s", targetPort, err)
+		klog.Errorf("failed to listen on port %d: %s", healthCheckPort, err)
 		panic(err)
 	}
 	klog.Infof("listening on port %d", healthCheckPort)
 
+	// The PROXY protocol header is sent before the TLS handshake.
 	proxyListener := &proxyproto.Listener{Listener: listener}
 	defer func(proxyListener *proxyproto.Listener) {
 		err := proxyListener.Close()
@@ -63,9 +113,37 @@ func main() {
 			panic(err)
 		}
 	}(proxyListener)
+	var serveListener net.Listener = proxyListener
+	if tlsCertFile != "" && tlsKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(tlsCertFile, tlsKeyFile)
+		if err != nil {
+			klog.Errorf("failed to load the TLS certificate: %s", err)
+			panic(err)
+		}
+		serveListener = tls.NewListener(proxyListener, &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		})
+		klog.Infof("serving HTTPS on port %d", healthCheckPort)
+	}
 
+	mux := http.NewServeMux()
+	mux.Handle("/", aggregator)
+	if enableProbeTranslation {
+		translator := newProbeTranslator(targetTimeout)
+		for _, scheme := range []string{translateSchemeGRPC, translateSchemeHTTP, translateSchemeHTTPS} {
+			mux.Handle(fmt.Sprintf("/%s/", scheme), translator)
+		}
+		klog.Infof("translating the gRPC and HTTP(S) probes")
+	}
+
+	server := &http.Server{
+		Handler:           mux,
+		ConnContext:       withConn,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
 	klog.Infof("listening on port with proxy listener %d", healthCheckPort)
-	err = http.Serve(proxyListener, nil)
+	err = server.Serve(serveListener)
 	if err != nil {
 		klog.Errorf("failed to serve: %s", err)
 		panic(err)
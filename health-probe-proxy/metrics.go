@@ -0,0 +1,101 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"time"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+const (
+	metricsNamespace = "health_probe_proxy"
+
+	resultHealthy   = "healthy"
+	resultUnhealthy = "unhealthy"
+	resultError     = "error"
+)
+
+var (
+	probeRequests = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      metricsNamespace,
+			Name:           "probe_requests_total",
+			Help:           "Number of health probe requests by the aggregated result",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"result"},
+	)
+	probeRequestDuration = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Namespace:      metricsNamespace,
+			Name:           "probe_request_duration_seconds",
+			Help:           "Latency of health probe requests by the aggregated result",
+			Buckets:        metrics.DefBuckets,
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"result"},
+	)
+	targetChecks = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      metricsNamespace,
+			Name:           "target_checks_total",
+			Help:           "Number of checks of the health targets by the result",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"target", "result"},
+	)
+	targetCheckDuration = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Namespace:      metricsNamespace,
+			Name:           "target_check_duration_seconds",
+			Help:           "Latency of checks of the health targets",
+			Buckets:        metrics.DefBuckets,
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"target"},
+	)
+)
+
+// registerMetrics registers the metrics of the health probe proxy.
+func registerMetrics() {
+	legacyregistry.MustRegister(probeRequests, probeRequestDuration, targetChecks, targetCheckDuration)
+}
+
+// recordProbe records the aggregated result and the latency of a probe request.
+func recordProbe(healthy bool, duration time.Duration) {
+	result := resultUnhealthy
+	if healthy {
+		result = resultHealthy
+	}
+	probeRequests.WithLabelValues(result).Inc()
+	probeRequestDuration.WithLabelValues(result).Observe(duration.Seconds())
+}
+
+// recordTargetCheck records the result and the latency of checking a health target.
+func recordTargetCheck(r targetResult) {
+	result := resultUnhealthy
+	switch {
+	case r.Err != nil:
+		result = resultError
+	case r.Healthy():
+		result = resultHealthy
+	}
+	targetChecks.WithLabelValues(r.Target, result).Inc()
+	targetCheckDuration.WithLabelValues(r.Target).Observe(r.Duration.Seconds())
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"net"
+
+	"github.com/pires/go-proxyproto"
+	"github.com/pires/go-proxyproto/tlvparse"
+)
+
+// connContextKey is the key of the connection of a request in the request context.
+type connContextKey struct{}
+
+// withConn stores the connection in the context, which is used as http.Server.ConnContext.
+func withConn(ctx context.Context, c net.Conn) context.Context {
+	return context.WithValue(ctx, connContextKey{}, c)
+}
+
+// proxyHeaderInfo is the information of the PROXY protocol header of a connection.
+type proxyHeaderInfo struct {
+	Version     byte
+	Source      string
+	Destination string
+	// LinkID is the LinkID of the Azure private endpoint, which is set if HasLinkID is true.
+	LinkID    uint32
+	HasLinkID bool
+	Authority string
+	UniqueID  string
+	// SSLVersion is set if the client connects to the proxy over TLS.
+	SSLVersion string
+	// UnknownTLVs are the types of the TLVs that are not parsed.
+	UnknownTLVs []string
+	// Err is the error of parsing the TLVs.
+	Err error
+}
+
+// proxyHeaderInfoFromContext returns the information of the PROXY protocol header of the connection of
+// the request, or nil if the connection does not start with a PROXY protocol header.
+func proxyHeaderInfoFromContext(ctx context.Context) *proxyHeaderInfo {
+	c, ok := ctx.Value(connContextKey{}).(net.Conn)
+	if !ok {
+		return nil
+	}
+	if tlsConn, ok := c.(*tls.Conn); ok {
+		c = tlsConn.NetConn()
+	}
+	proxyConn, ok := c.(*proxyproto.Conn)
+	if !ok {
+		return nil
+	}
+	header := proxyConn.ProxyHeader()
+	if header == nil {
+		return nil
+	}
+	return parseProxyHeader(header)
+}
+
+// parseProxyHeader parses the addresses and the TLVs of the PROXY protocol header.
+func parseProxyHeader(header *proxyproto.Header) *proxyHeaderInfo {
+	info := &proxyHeaderInfo{Version: header.Version}
+	if header.SourceAddr != nil {
+		info.Source = header.SourceAddr.String()
+	}
+	if header.DestinationAddr != nil {
+		info.Destination = header.DestinationAddr.String()
+	}
+	if header.Version != 2 {
+		return info
+	}
+
+	tlvs, err := header.TLVs()
+	if err != nil {
+		info.Err = err
+		return info
+	}
+	info.LinkID, info.HasLinkID = tlvparse.FindAzurePrivateEndpointLinkID(tlvs)
+	for _, tlv := range tlvs {
+		switch tlv.Type {
+		case proxyproto.PP2_TYPE_AUTHORITY:
+			info.Authority = string(tlv.Value)
+		case proxyproto.PP2_TYPE_UNIQUE_ID:
+			info.UniqueID = hex.EncodeToString(tlv.Value)
+		case proxyproto.PP2_TYPE_SSL:
+			if ssl, err := tlvparse.SSL(tlv); err == nil {
+				info.SSLVersion, _ = ssl.SSLVersion()
+			}
+		case proxyproto.PP2_TYPE_NOOP, tlvparse.PP2_TYPE_AZURE:
+		default:
+			info.UnknownTLVs = append(info.UnknownTLVs, fmt.Sprintf("0x%02x", byte(tlv.Type)))
+		}
+	}
+	return info
+}
+
+// keysAndValues returns the fields of the header for structured logging.
+func (info *proxyHeaderInfo) keysAndValues() []interface{} {
+	rv := []interface{}{
+		"proxyProtocolVersion", info.Version,
+		"proxySource", info.Source,
+		"proxyDestination", info.Destination,
+	}
+	if info.HasLinkID {
+		rv = append(rv, "privateEndpointLinkID", info.LinkID)
+	}
+	if info.Authority != "" {
+		rv = append(rv, "authority", info.Authority)
+	}
+	if info.UniqueID != "" {
+		rv = append(rv, "uniqueID", info.UniqueID)
+	}
+	if info.SSLVersion != "" {
+		rv = append(rv, "sslVersion", info.SSLVersion)
+	}
+	if len(info.UnknownTLVs) > 0 {
+		rv = append(rv, "unknownTLVs", info.UnknownTLVs)
+	}
+	if info.Err != nil {
+		rv = append(rv, "tlvError", info.Err.Error())
+	}
+	return rv
+}
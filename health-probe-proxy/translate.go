@@ -0,0 +1,260 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pires/go-proxyproto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	translateSchemeGRPC  = "grpc"
+	translateSchemeHTTP  = "http"
+	translateSchemeHTTPS = "https"
+)
+
+// statusCodeRange is an inclusive range of HTTP status codes.
+type statusCodeRange struct {
+	From, To int
+}
+
+// statusCodes are the status codes that a backend is considered healthy with.
+type statusCodes []statusCodeRange
+
+// parseStatusCodes parses the comma separated status codes or ranges of status codes, e.g. `200-299,401`.
+func parseStatusCodes(s string) (statusCodes, error) {
+	var codes statusCodes
+	for _, item := range strings.Split(s, ",") {
+		from, to, isRange := strings.Cut(strings.TrimSpace(item), "-")
+		if !isRange {
+			to = from
+		}
+		fromCode, err := strconv.Atoi(from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid status code %q", item)
+		}
+		toCode, err := strconv.Atoi(to)
+		if err != nil {
+			return nil, fmt.Errorf("invalid status code %q", item)
+		}
+		if fromCode < 100 || toCode > 599 || fromCode > toCode {
+			return nil, fmt.Errorf("invalid status code %q, the status codes must be in the range of 100-599", item)
+		}
+		codes = append(codes, statusCodeRange{From: fromCode, To: toCode})
+	}
+	return codes, nil
+}
+
+// Has returns true if the status code is one of the status codes.
+func (codes statusCodes) Has(code int) bool {
+	for _, r := range codes {
+		if code >= r.From && code <= r.To {
+			return true
+		}
+	}
+	return false
+}
+
+// translatedProbe is a probe that Azure load balancer does not support natively, which is described by the
+// request path of the HTTP probe sent to the proxy:
+//   - /grpc/<port>[/<service>] checks the gRPC health service of the port.
+//   - /http/<port>/<status codes><path> and /https/<port>/<status codes><path> check the path of the port,
+//     and expect one of the status codes.
+type translatedProbe struct {
+	Scheme      string
+	Port        int
+	GRPCService string
+	StatusCodes statusCodes
+	// PathAndQuery is the path and the query to check the HTTP(S) backend with.
+	PathAndQuery string
+}
+
+// Target returns the name of the probe in the metrics and the logs, which is the scheme and the port only,
+// so that the paths and the services in the requests don't blow up the cardinality of the metrics.
+func (p *translatedProbe) Target() string {
+	return fmt.Sprintf("%s:%d", p.Scheme, p.Port)
+}
+
+// parseTranslatedProbe parses the translated probe from the request URL.
+func parseTranslatedProbe(u *url.URL) (*translatedProbe, error) {
+	parts := strings.SplitN(strings.TrimPrefix(u.Path, "/"), "/", 4)
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("missing backend port in %q", u.Path)
+	}
+	port, err := strconv.Atoi(parts[1])
+	if err != nil || port <= 0 || port > 65535 {
+		return nil, fmt.Errorf("invalid backend port %q", parts[1])
+	}
+	probe := &translatedProbe{Scheme: parts[0], Port: port}
+
+	switch probe.Scheme {
+	case translateSchemeGRPC:
+		if len(parts) > 3 {
+			return nil, fmt.Errorf("invalid gRPC probe path %q", u.Path)
+		}
+		if len(parts) == 3 {
+			probe.GRPCService = parts[2]
+		}
+	case translateSchemeHTTP, translateSchemeHTTPS:
+		if len(parts) < 3 {
+			return nil, fmt.Errorf("missing expected status codes in %q", u.Path)
+		}
+		if probe.StatusCodes, err = parseStatusCodes(parts[2]); err != nil {
+			return nil, err
+		}
+		probe.PathAndQuery = "/"
+		if len(parts) == 4 {
+			probe.PathAndQuery += parts[3]
+		}
+		if u.RawQuery != "" {
+			probe.PathAndQuery += "?" + u.RawQuery
+		}
+	default:
+		return nil, fmt.Errorf("unsupported probe scheme %q", probe.Scheme)
+	}
+	return probe, nil
+}
+
+// probeTranslator translates the gRPC health checks and the HTTP(S) checks with expected status codes into
+// HTTP responses that Azure load balancer understands, which are 200 if the backend is healthy and 503 otherwise.
+type probeTranslator struct {
+	timeout time.Duration
+	client  *http.Client
+}
+
+func newProbeTranslator(timeout time.Duration) *probeTranslator {
+	return &probeTranslator{
+		timeout: timeout,
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				// Azure load balancer does not verify the certificates of HTTPS probes either.
+				//nolint:gosec
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			},
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// backendHost returns the IP address of the node that the probe is sent to, so that the backend port is
+// checked on the same address as Azure load balancer would check it.
+func backendHost(ctx context.Context) string {
+	c, ok := ctx.Value(connContextKey{}).(net.Conn)
+	if !ok {
+		return "localhost"
+	}
+	if tlsConn, ok := c.(*tls.Conn); ok {
+		c = tlsConn.NetConn()
+	}
+	// The local address of a PROXY protocol connection is the destination in the header.
+	if proxyConn, ok := c.(*proxyproto.Conn); ok {
+		c = proxyConn.Raw()
+	}
+	if addr, ok := c.LocalAddr().(*net.TCPAddr); ok && !addr.IP.IsUnspecified() {
+		return addr.IP.String()
+	}
+	return "localhost"
+}
+
+func (t *probeTranslator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	probe, err := parseTranslatedProbe(r.URL)
+	if err != nil {
+		klog.V(2).InfoS("Invalid translated probe", "path", r.URL.Path, "err", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		logAccess(r, http.StatusBadRequest, time.Since(start), nil)
+		return
+	}
+
+	address := net.JoinHostPort(backendHost(r.Context()), strconv.Itoa(probe.Port))
+	var result targetResult
+	if probe.Scheme == translateSchemeGRPC {
+		result = t.checkGRPC(r.Context(), probe.Target(), address, probe.GRPCService)
+	} else {
+		result = t.checkHTTP(r.Context(), probe.Target(), fmt.Sprintf("%s://%s%s", probe.Scheme, address, probe.PathAndQuery), probe.StatusCodes)
+	}
+	recordTargetCheck(result)
+	healthy := result.Healthy()
+
+	statusCode := http.StatusOK
+	if !healthy {
+		statusCode = http.StatusServiceUnavailable
+	}
+	// The body is generic, because the errors of the backend are not for the prober to see.
+	// They are in the access log instead.
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(statusCode)
+	fmt.Fprintln(w, http.StatusText(statusCode))
+
+	duration := time.Since(start)
+	recordProbe(healthy, duration)
+	logAccess(r, statusCode, duration, []targetResult{result})
+}
+
+// checkGRPC checks the backend with the gRPC health checking protocol, which is healthy if the service is SERVING.
+func (t *probeTranslator) checkGRPC(ctx context.Context, target, address, service string) (result targetResult) {
+	start := time.Now()
+	result.Target = target
+	defer func() {
+		result.Duration = time.Since(start)
+	}()
+
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Status = resp.GetStatus().String()
+	result.StatusCode = http.StatusServiceUnavailable
+	if resp.GetStatus() == healthpb.HealthCheckResponse_SERVING {
+		result.StatusCode = http.StatusOK
+	}
+	return result
+}
+
+// checkHTTP checks the HTTP(S) backend, which is healthy if it responds with one of the expected status codes.
+func (t *probeTranslator) checkHTTP(ctx context.Context, target, u string, expected statusCodes) targetResult {
+	result := checkTarget(ctx, t.client, target, u)
+	result.ExpectedStatusCodes = expected
+	return result
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestParseTranslatedProbe(t *testing.T) {
+	for path, expected := range map[string]*translatedProbe{
+		"/grpc/30080":                {Scheme: "grpc", Port: 30080},
+		"/grpc/30080/my.pkg.Greeter": {Scheme: "grpc", Port: 30080, GRPCService: "my.pkg.Greeter"},
+		"/http/30080/200-299,401/":   {Scheme: "http", Port: 30080, StatusCodes: statusCodes{{200, 299}, {401, 401}}, PathAndQuery: "/"},
+		"/https/30443/200/readyz?verbose=true": {
+			Scheme: "https", Port: 30443, StatusCodes: statusCodes{{200, 200}}, PathAndQuery: "/readyz?verbose=true",
+		},
+		"/grpc":                 nil,
+		"/grpc/0":               nil,
+		"/grpc/30080/svc/extra": nil,
+		"/http/30080":           nil,
+		"/http/30080/600/":      nil,
+		"/http/30080/299-200/":  nil,
+		"/tcp/30080":            nil,
+	} {
+		u, _ := url.Parse(path)
+		actual, err := parseTranslatedProbe(u)
+		if expected == nil {
+			if err == nil {
+				t.Errorf("expected error for %q", path)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for %q: %v", path, err)
+			continue
+		}
+		if fmt.Sprint(actual) != fmt.Sprint(expected) {
+			t.Errorf("expected %+v for %q, got %+v", expected, path, actual)
+		}
+	}
+}
+
+func TestProbeTranslatorHTTP(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/unauthorized" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+	port := backend.Listener.Addr().(*net.TCPAddr).Port
+
+	translator := newProbeTranslator(time.Second)
+	for path, statusCode := range map[string]int{
+		fmt.Sprintf("/http/%d/200/healthz", port):              http.StatusOK,
+		fmt.Sprintf("/http/%d/401/healthz", port):              http.StatusServiceUnavailable,
+		fmt.Sprintf("/http/%d/200-299,401/unauthorized", port): http.StatusOK,
+		fmt.Sprintf("/http/%d/200/unauthorized", port):         http.StatusServiceUnavailable,
+		"/http/1/200/":   http.StatusServiceUnavailable,
+		"/http/abc/200/": http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		translator.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != statusCode {
+			t.Errorf("expected status %d for %q, got %d: %s", statusCode, path, w.Code, w.Body.String())
+		}
+		// the errors of the backend are not exposed to the prober
+		if expected := http.StatusText(statusCode) + "\n"; w.Body.String() != expected {
+			t.Errorf("expected body %q for %q, got %q", expected, path, w.Body.String())
+		}
+	}
+}
+
+func TestTranslatedProbeTarget(t *testing.T) {
+	for path, expected := range map[string]string{
+		"/grpc/50051/my.pkg.Greeter":       "grpc:50051",
+		"/http/8080/200/healthz?a=b":       "http:8080",
+		"/https/8443/200-299/any/path/xyz": "https:8443",
+	} {
+		u, _ := url.Parse(path)
+		probe, err := parseTranslatedProbe(u)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %v", path, err)
+		}
+		if probe.Target() != expected {
+			t.Errorf("expected target %q for %q, got %q", expected, path, probe.Target())
+		}
+	}
+}
+
+func TestProbeTranslatorGRPC(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("my.pkg.Greeter", healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus("my.pkg.Stopped", healthpb.HealthCheckResponse_NOT_SERVING)
+	server := grpc.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	defer server.Stop()
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	translator := newProbeTranslator(time.Second)
+	for path, statusCode := range map[string]int{
+		fmt.Sprintf("/grpc/%d", port):                http.StatusOK,
+		fmt.Sprintf("/grpc/%d/my.pkg.Greeter", port): http.StatusOK,
+		fmt.Sprintf("/grpc/%d/my.pkg.Stopped", port): http.StatusServiceUnavailable,
+		fmt.Sprintf("/grpc/%d/my.pkg.Unknown", port): http.StatusServiceUnavailable,
+	} {
+		w := httptest.NewRecorder()
+		translator.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != statusCode {
+			t.Errorf("expected status %d for %q, got %d: %s", statusCode, path, w.Code, w.Body.String())
+		}
+	}
+}
@@ -101,6 +101,16 @@ func (f *KubernetesServiceFixture) WithAllowedServiceTags(parts ...string) *Kube
 	return f
 }
 
+func (f *KubernetesServiceFixture) WithPortAllowedIPRanges(port int32, parts ...string) *KubernetesServiceFixture {
+	f.svc.Annotations[consts.BuildAnnotationKeyForPort(port, consts.PortAnnotationAllowedIPRanges)] = strings.Join(parts, ",")
+	return f
+}
+
+func (f *KubernetesServiceFixture) WithPortAllowedServiceTags(port int32, parts ...string) *KubernetesServiceFixture {
+	f.svc.Annotations[consts.BuildAnnotationKeyForPort(port, consts.PortAnnotationAllowedServiceTags)] = strings.Join(parts, ",")
+	return f
+}
+
 func (f *KubernetesServiceFixture) WithDisableFloatingIP() *KubernetesServiceFixture {
 	f.svc.Annotations[consts.ServiceAnnotationDisableLoadBalancerFloatingIP] = "true"
 	return f
@@ -34,10 +34,12 @@ import (
 	"sigs.k8s.io/cloud-provider-azure/pkg/azclient/privatednszonegroupclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azclient/privateendpointclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azclient/privatelinkserviceclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/azclient/privaterecordsetclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azclient/privatezoneclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azclient/providerclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azclient/publicipaddressclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azclient/publicipprefixclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/azclient/recordsetclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azclient/registryclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azclient/resourcegroupclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azclient/roleassignmentclient"
@@ -78,10 +80,14 @@ type ClientFactory interface {
 	GetPrivateDNSZoneGroupClient() privatednszonegroupclient.Interface
 	GetPrivateEndpointClient() privateendpointclient.Interface
 	GetPrivateLinkServiceClient() privatelinkserviceclient.Interface
+	GetPrivateRecordSetClient() privaterecordsetclient.Interface
+	GetPrivateRecordSetClientForSub(subscriptionID string) (privaterecordsetclient.Interface, error)
 	GetPrivateZoneClient() privatezoneclient.Interface
 	GetProviderClient() providerclient.Interface
 	GetPublicIPAddressClient() publicipaddressclient.Interface
 	GetPublicIPPrefixClient() publicipprefixclient.Interface
+	GetRecordSetClient() recordsetclient.Interface
+	GetRecordSetClientForSub(subscriptionID string) (recordsetclient.Interface, error)
 	GetRegistryClient() registryclient.Interface
 	GetResourceGroupClient() resourcegroupclient.Interface
 	GetRoleAssignmentClient() roleassignmentclient.Interface
@@ -42,10 +42,12 @@ import (
 	"sigs.k8s.io/cloud-provider-azure/pkg/azclient/privatednszonegroupclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azclient/privateendpointclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azclient/privatelinkserviceclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/azclient/privaterecordsetclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azclient/privatezoneclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azclient/providerclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azclient/publicipaddressclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azclient/publicipprefixclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/azclient/recordsetclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azclient/registryclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azclient/resourcegroupclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azclient/roleassignmentclient"
@@ -85,10 +87,12 @@ type ClientFactoryImpl struct {
 	privatednszonegroupclientInterface      privatednszonegroupclient.Interface
 	privateendpointclientInterface          privateendpointclient.Interface
 	privatelinkserviceclientInterface       privatelinkserviceclient.Interface
+	privaterecordsetclientInterface         sync.Map
 	privatezoneclientInterface              privatezoneclient.Interface
 	providerclientInterface                 providerclient.Interface
 	publicipaddressclientInterface          publicipaddressclient.Interface
 	publicipprefixclientInterface           publicipprefixclient.Interface
+	recordsetclientInterface                sync.Map
 	registryclientInterface                 registryclient.Interface
 	resourcegroupclientInterface            resourcegroupclient.Interface
 	roleassignmentclientInterface           roleassignmentclient.Interface
@@ -219,6 +223,12 @@ func NewClientFactory(config *ClientFactoryConfig, armConfig *ARMClientConfig, c
 		return nil, err
 	}
 
+	//initialize privaterecordsetclient
+	_, err = factory.GetPrivateRecordSetClientForSub(config.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
 	//initialize privatezoneclient
 	factory.privatezoneclientInterface, err = factory.createPrivateZoneClient(config.SubscriptionID)
 	if err != nil {
@@ -243,6 +253,12 @@ func NewClientFactory(config *ClientFactoryConfig, armConfig *ARMClientConfig, c
 		return nil, err
 	}
 
+	//initialize recordsetclient
+	_, err = factory.GetRecordSetClientForSub(config.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
 	//initialize registryclient
 	factory.registryclientInterface, err = factory.createRegistryClient(config.SubscriptionID)
 	if err != nil {
@@ -833,6 +849,48 @@ func (factory *ClientFactoryImpl) GetPrivateLinkServiceClient() privatelinkservi
 	return factory.privatelinkserviceclientInterface
 }
 
+func (factory *ClientFactoryImpl) createPrivateRecordSetClient(subscription string) (privaterecordsetclient.Interface, error) {
+	//initialize privaterecordsetclient
+	options, err := GetDefaultResourceClientOption(factory.armConfig, factory.facotryConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	//add ratelimit policy
+	ratelimitOption := factory.facotryConfig.GetRateLimitConfig("privateDNSRateLimit")
+	rateLimitPolicy := ratelimit.NewRateLimitPolicy(ratelimitOption)
+	if rateLimitPolicy != nil {
+		options.ClientOptions.PerCallPolicies = append(options.ClientOptions.PerCallPolicies, rateLimitPolicy)
+	}
+	for _, optionMutFn := range factory.clientOptionsMutFn {
+		if optionMutFn != nil {
+			optionMutFn(options)
+		}
+	}
+	return privaterecordsetclient.New(subscription, factory.cred, options)
+}
+
+func (factory *ClientFactoryImpl) GetPrivateRecordSetClient() privaterecordsetclient.Interface {
+	clientImp, _ := factory.privaterecordsetclientInterface.Load(strings.ToLower(factory.facotryConfig.SubscriptionID))
+	return clientImp.(privaterecordsetclient.Interface)
+}
+func (factory *ClientFactoryImpl) GetPrivateRecordSetClientForSub(subscriptionID string) (privaterecordsetclient.Interface, error) {
+	if subscriptionID == "" {
+		subscriptionID = factory.facotryConfig.SubscriptionID
+	}
+	clientImp, loaded := factory.privaterecordsetclientInterface.Load(strings.ToLower(subscriptionID))
+	if loaded {
+		return clientImp.(privaterecordsetclient.Interface), nil
+	}
+	//It's not thread safe, but it's ok for now. because it will be called once.
+	clientImp, err := factory.createPrivateRecordSetClient(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	factory.privaterecordsetclientInterface.Store(strings.ToLower(subscriptionID), clientImp)
+	return clientImp.(privaterecordsetclient.Interface), nil
+}
+
 func (factory *ClientFactoryImpl) createPrivateZoneClient(subscription string) (privatezoneclient.Interface, error) {
 	//initialize privatezoneclient
 	options, err := GetDefaultResourceClientOption(factory.armConfig, factory.facotryConfig)
@@ -929,6 +987,42 @@ func (factory *ClientFactoryImpl) GetPublicIPPrefixClient() publicipprefixclient
 	return factory.publicipprefixclientInterface
 }
 
+func (factory *ClientFactoryImpl) createRecordSetClient(subscription string) (recordsetclient.Interface, error) {
+	//initialize recordsetclient
+	options, err := GetDefaultResourceClientOption(factory.armConfig, factory.facotryConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, optionMutFn := range factory.clientOptionsMutFn {
+		if optionMutFn != nil {
+			optionMutFn(options)
+		}
+	}
+	return recordsetclient.New(subscription, factory.cred, options)
+}
+
+func (factory *ClientFactoryImpl) GetRecordSetClient() recordsetclient.Interface {
+	clientImp, _ := factory.recordsetclientInterface.Load(strings.ToLower(factory.facotryConfig.SubscriptionID))
+	return clientImp.(recordsetclient.Interface)
+}
+func (factory *ClientFactoryImpl) GetRecordSetClientForSub(subscriptionID string) (recordsetclient.Interface, error) {
+	if subscriptionID == "" {
+		subscriptionID = factory.facotryConfig.SubscriptionID
+	}
+	clientImp, loaded := factory.recordsetclientInterface.Load(strings.ToLower(subscriptionID))
+	if loaded {
+		return clientImp.(recordsetclient.Interface), nil
+	}
+	//It's not thread safe, but it's ok for now. because it will be called once.
+	clientImp, err := factory.createRecordSetClient(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	factory.recordsetclientInterface.Store(strings.ToLower(subscriptionID), clientImp)
+	return clientImp.(recordsetclient.Interface), nil
+}
+
 func (factory *ClientFactoryImpl) createRegistryClient(subscription string) (registryclient.Interface, error) {
 	//initialize registryclient
 	options, err := GetDefaultResourceClientOption(factory.armConfig, factory.facotryConfig)
@@ -47,10 +47,12 @@ import (
 	privatednszonegroupclient "sigs.k8s.io/cloud-provider-azure/pkg/azclient/privatednszonegroupclient"
 	privateendpointclient "sigs.k8s.io/cloud-provider-azure/pkg/azclient/privateendpointclient"
 	privatelinkserviceclient "sigs.k8s.io/cloud-provider-azure/pkg/azclient/privatelinkserviceclient"
+	privaterecordsetclient "sigs.k8s.io/cloud-provider-azure/pkg/azclient/privaterecordsetclient"
 	privatezoneclient "sigs.k8s.io/cloud-provider-azure/pkg/azclient/privatezoneclient"
 	providerclient "sigs.k8s.io/cloud-provider-azure/pkg/azclient/providerclient"
 	publicipaddressclient "sigs.k8s.io/cloud-provider-azure/pkg/azclient/publicipaddressclient"
 	publicipprefixclient "sigs.k8s.io/cloud-provider-azure/pkg/azclient/publicipprefixclient"
+	recordsetclient "sigs.k8s.io/cloud-provider-azure/pkg/azclient/recordsetclient"
 	registryclient "sigs.k8s.io/cloud-provider-azure/pkg/azclient/registryclient"
 	resourcegroupclient "sigs.k8s.io/cloud-provider-azure/pkg/azclient/resourcegroupclient"
 	roleassignmentclient "sigs.k8s.io/cloud-provider-azure/pkg/azclient/roleassignmentclient"
@@ -406,6 +408,35 @@ func (mr *MockClientFactoryMockRecorder) GetPrivateLinkServiceClient() *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPrivateLinkServiceClient", reflect.TypeOf((*MockClientFactory)(nil).GetPrivateLinkServiceClient))
 }
 
+// GetPrivateRecordSetClient mocks base method.
+func (m *MockClientFactory) GetPrivateRecordSetClient() privaterecordsetclient.Interface {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPrivateRecordSetClient")
+	ret0, _ := ret[0].(privaterecordsetclient.Interface)
+	return ret0
+}
+
+// GetPrivateRecordSetClient indicates an expected call of GetPrivateRecordSetClient.
+func (mr *MockClientFactoryMockRecorder) GetPrivateRecordSetClient() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPrivateRecordSetClient", reflect.TypeOf((*MockClientFactory)(nil).GetPrivateRecordSetClient))
+}
+
+// GetPrivateRecordSetClientForSub mocks base method.
+func (m *MockClientFactory) GetPrivateRecordSetClientForSub(subscriptionID string) (privaterecordsetclient.Interface, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPrivateRecordSetClientForSub", subscriptionID)
+	ret0, _ := ret[0].(privaterecordsetclient.Interface)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPrivateRecordSetClientForSub indicates an expected call of GetPrivateRecordSetClientForSub.
+func (mr *MockClientFactoryMockRecorder) GetPrivateRecordSetClientForSub(subscriptionID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPrivateRecordSetClientForSub", reflect.TypeOf((*MockClientFactory)(nil).GetPrivateRecordSetClientForSub), subscriptionID)
+}
+
 // GetPrivateZoneClient mocks base method.
 func (m *MockClientFactory) GetPrivateZoneClient() privatezoneclient.Interface {
 	m.ctrl.T.Helper()
@@ -462,6 +493,35 @@ func (mr *MockClientFactoryMockRecorder) GetPublicIPPrefixClient() *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPublicIPPrefixClient", reflect.TypeOf((*MockClientFactory)(nil).GetPublicIPPrefixClient))
 }
 
+// GetRecordSetClient mocks base method.
+func (m *MockClientFactory) GetRecordSetClient() recordsetclient.Interface {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRecordSetClient")
+	ret0, _ := ret[0].(recordsetclient.Interface)
+	return ret0
+}
+
+// GetRecordSetClient indicates an expected call of GetRecordSetClient.
+func (mr *MockClientFactoryMockRecorder) GetRecordSetClient() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRecordSetClient", reflect.TypeOf((*MockClientFactory)(nil).GetRecordSetClient))
+}
+
+// GetRecordSetClientForSub mocks base method.
+func (m *MockClientFactory) GetRecordSetClientForSub(subscriptionID string) (recordsetclient.Interface, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRecordSetClientForSub", subscriptionID)
+	ret0, _ := ret[0].(recordsetclient.Interface)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRecordSetClientForSub indicates an expected call of GetRecordSetClientForSub.
+func (mr *MockClientFactoryMockRecorder) GetRecordSetClientForSub(subscriptionID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRecordSetClientForSub", reflect.TypeOf((*MockClientFactory)(nil).GetRecordSetClientForSub), subscriptionID)
+}
+
 // GetRegistryClient mocks base method.
 func (m *MockClientFactory) GetRegistryClient() registryclient.Interface {
 	m.ctrl.T.Helper()
@@ -0,0 +1,79 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package privaterecordsetclient
+
+import (
+	"context"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	armprivatedns "github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/privatedns/armprivatedns"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/azclient/metrics"
+)
+
+const GetOperationName = "RecordSetsClient.Get"
+
+// Get gets the RecordSet
+func (client *Client) Get(ctx context.Context, resourceGroupName string, zoneName string, recordType armprivatedns.RecordType, relativeRecordSetName string) (result *armprivatedns.RecordSet, err error) {
+	metricsCtx := metrics.BeginARMRequest(client.subscriptionID, resourceGroupName, "PrivateRecordSet", "get")
+	defer func() { metricsCtx.Observe(ctx, err) }()
+	ctx, endSpan := runtime.StartSpan(ctx, GetOperationName, client.tracer, nil)
+	defer endSpan(err)
+	resp, err := client.RecordSetsClient.Get(ctx, resourceGroupName, zoneName, recordType, relativeRecordSetName, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.RecordSet, nil
+}
+
+const CreateOrUpdateOperationName = "RecordSetsClient.Create"
+
+// CreateOrUpdate creates the RecordSet if etag is empty, or updates it if its etag matches.
+func (client *Client) CreateOrUpdate(ctx context.Context, resourceGroupName string, zoneName string, recordType armprivatedns.RecordType, relativeRecordSetName string, resource armprivatedns.RecordSet, etag string) (result *armprivatedns.RecordSet, err error) {
+	metricsCtx := metrics.BeginARMRequest(client.subscriptionID, resourceGroupName, "PrivateRecordSet", "create_or_update")
+	defer func() { metricsCtx.Observe(ctx, err) }()
+	ctx, endSpan := runtime.StartSpan(ctx, CreateOrUpdateOperationName, client.tracer, nil)
+	defer endSpan(err)
+	options := &armprivatedns.RecordSetsClientCreateOrUpdateOptions{}
+	if etag == "" {
+		options.IfNoneMatch = to.Ptr("*")
+	} else {
+		options.IfMatch = to.Ptr(etag)
+	}
+	resp, err := client.RecordSetsClient.CreateOrUpdate(ctx, resourceGroupName, zoneName, recordType, relativeRecordSetName, resource, options)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.RecordSet, nil
+}
+
+const DeleteOperationName = "RecordSetsClient.Delete"
+
+// Delete deletes the RecordSet, only if its etag matches when etag is not empty.
+func (client *Client) Delete(ctx context.Context, resourceGroupName string, zoneName string, recordType armprivatedns.RecordType, relativeRecordSetName string, etag string) (err error) {
+	metricsCtx := metrics.BeginARMRequest(client.subscriptionID, resourceGroupName, "PrivateRecordSet", "delete")
+	defer func() { metricsCtx.Observe(ctx, err) }()
+	ctx, endSpan := runtime.StartSpan(ctx, DeleteOperationName, client.tracer, nil)
+	defer endSpan(err)
+	options := &armprivatedns.RecordSetsClientDeleteOptions{}
+	if etag != "" {
+		options.IfMatch = to.Ptr(etag)
+	}
+	_, err = client.RecordSetsClient.Delete(ctx, resourceGroupName, zoneName, recordType, relativeRecordSetName, options)
+	return err
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package privaterecordsetclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	armprivatedns "github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/privatedns/armprivatedns"
+)
+
+type fakeCredential struct{}
+
+func (fakeCredential) GetToken(_ context.Context, _ policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "fake"}, nil
+}
+
+// fakeTransport records the requests and responds with an empty record set.
+type fakeTransport struct {
+	requests []*http.Request
+}
+
+func (t *fakeTransport) Do(req *http.Request) (*http.Response, error) {
+	t.requests = append(t.requests, req)
+	statusCode := http.StatusOK
+	if req.Method == http.MethodDelete {
+		statusCode = http.StatusNoContent
+	}
+	return &http.Response{
+		StatusCode: statusCode,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader("{}")),
+		Request:    req,
+	}, nil
+}
+
+func TestConditionalRequests(t *testing.T) {
+	transport := &fakeTransport{}
+	client, err := New("subscription", fakeCredential{}, &arm.ClientOptions{
+		ClientOptions: policy.ClientOptions{
+			Transport: transport,
+			Retry:     policy.RetryOptions{MaxRetries: -1},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := context.Background()
+	if _, err := client.CreateOrUpdate(ctx, "rg", "example.internal", armprivatedns.RecordTypeA, "app", armprivatedns.RecordSet{}, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := client.CreateOrUpdate(ctx, "rg", "example.internal", armprivatedns.RecordTypeA, "app", armprivatedns.RecordSet{}, "etag"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := client.Delete(ctx, "rg", "example.internal", armprivatedns.RecordTypeA, "app", "etag"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := client.Delete(ctx, "rg", "example.internal", armprivatedns.RecordTypeA, "app", ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []struct {
+		ifMatch     string
+		ifNoneMatch string
+	}{
+		{ifNoneMatch: "*"},
+		{ifMatch: "etag"},
+		{ifMatch: "etag"},
+		{},
+	}
+	if len(transport.requests) != len(expected) {
+		t.Fatalf("expected %d requests, got %d", len(expected), len(transport.requests))
+	}
+	for i, req := range transport.requests {
+		if got := req.Header.Get("If-Match"); got != expected[i].ifMatch {
+			t.Errorf("request %d: expected If-Match %q, got %q", i, expected[i].ifMatch, got)
+		}
+		if got := req.Header.Get("If-None-Match"); got != expected[i].ifNoneMatch {
+			t.Errorf("request %d: expected If-None-Match %q, got %q", i, expected[i].ifNoneMatch, got)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// +azure:enableclientgen:=true
+package privaterecordsetclient
+
+import (
+	"context"
+
+	armprivatedns "github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/privatedns/armprivatedns"
+)
+
+// Interface is the client of the record sets in the Azure Private DNS zones.
+// The etag passed to CreateOrUpdate and Delete is sent in the If-Match header if it is not empty.
+// CreateOrUpdate with an empty etag only creates the record set, and fails if the record set already exists.
+// +azure:client:verbs=,resource=PrivateRecordSet,packageName=github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/privatedns/armprivatedns,packageAlias=armprivatedns,clientName=RecordSetsClient,expand=false,rateLimitKey=privateDNSRateLimit,crossSubFactory=true
+type Interface interface {
+	Get(ctx context.Context, resourceGroupName string, zoneName string, recordType armprivatedns.RecordType, relativeRecordSetName string) (*armprivatedns.RecordSet, error)
+	CreateOrUpdate(ctx context.Context, resourceGroupName string, zoneName string, recordType armprivatedns.RecordType, relativeRecordSetName string, resource armprivatedns.RecordSet, etag string) (*armprivatedns.RecordSet, error)
+	Delete(ctx context.Context, resourceGroupName string, zoneName string, recordType armprivatedns.RecordType, relativeRecordSetName string, etag string) error
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recordsetclient
+
+import (
+	"context"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	armdns "github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/azclient/metrics"
+)
+
+const GetOperationName = "RecordSetsClient.Get"
+
+// Get gets the RecordSet
+func (client *Client) Get(ctx context.Context, resourceGroupName string, zoneName string, recordType armdns.RecordType, relativeRecordSetName string) (result *armdns.RecordSet, err error) {
+	metricsCtx := metrics.BeginARMRequest(client.subscriptionID, resourceGroupName, "RecordSet", "get")
+	defer func() { metricsCtx.Observe(ctx, err) }()
+	ctx, endSpan := runtime.StartSpan(ctx, GetOperationName, client.tracer, nil)
+	defer endSpan(err)
+	resp, err := client.RecordSetsClient.Get(ctx, resourceGroupName, zoneName, relativeRecordSetName, recordType, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.RecordSet, nil
+}
+
+const CreateOrUpdateOperationName = "RecordSetsClient.Create"
+
+// CreateOrUpdate creates the RecordSet if etag is empty, or updates it if its etag matches.
+func (client *Client) CreateOrUpdate(ctx context.Context, resourceGroupName string, zoneName string, recordType armdns.RecordType, relativeRecordSetName string, resource armdns.RecordSet, etag string) (result *armdns.RecordSet, err error) {
+	metricsCtx := metrics.BeginARMRequest(client.subscriptionID, resourceGroupName, "RecordSet", "create_or_update")
+	defer func() { metricsCtx.Observe(ctx, err) }()
+	ctx, endSpan := runtime.StartSpan(ctx, CreateOrUpdateOperationName, client.tracer, nil)
+	defer endSpan(err)
+	options := &armdns.RecordSetsClientCreateOrUpdateOptions{}
+	if etag == "" {
+		options.IfNoneMatch = to.Ptr("*")
+	} else {
+		options.IfMatch = to.Ptr(etag)
+	}
+	resp, err := client.RecordSetsClient.CreateOrUpdate(ctx, resourceGroupName, zoneName, relativeRecordSetName, recordType, resource, options)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.RecordSet, nil
+}
+
+const DeleteOperationName = "RecordSetsClient.Delete"
+
+// Delete deletes the RecordSet, only if its etag matches when etag is not empty.
+func (client *Client) Delete(ctx context.Context, resourceGroupName string, zoneName string, recordType armdns.RecordType, relativeRecordSetName string, etag string) (err error) {
+	metricsCtx := metrics.BeginARMRequest(client.subscriptionID, resourceGroupName, "RecordSet", "delete")
+	defer func() { metricsCtx.Observe(ctx, err) }()
+	ctx, endSpan := runtime.StartSpan(ctx, DeleteOperationName, client.tracer, nil)
+	defer endSpan(err)
+	options := &armdns.RecordSetsClientDeleteOptions{}
+	if etag != "" {
+		options.IfMatch = to.Ptr(etag)
+	}
+	_, err = client.RecordSetsClient.Delete(ctx, resourceGroupName, zoneName, relativeRecordSetName, recordType, options)
+	return err
+}
@@ -276,6 +276,24 @@ const (
 	// to specify the DNS label name for the service.
 	ServiceAnnotationDNSLabelName = "service.beta.kubernetes.io/azure-dns-label-name"
 
+	// ServiceAnnotationDNSZoneID is the annotation used on the service to specify the resource ID of the
+	// Azure DNS zone or the Azure Private DNS zone, in which the A and AAAA records of the service are managed.
+	ServiceAnnotationDNSZoneID = "service.beta.kubernetes.io/azure-dns-zone-id"
+
+	// ServiceAnnotationDNSRecordName is the annotation used on the service to specify the name of the DNS
+	// records of the service relative to the zone, e.g. `app` for `app.contoso.internal`. It is required
+	// if ServiceAnnotationDNSZoneID is set.
+	ServiceAnnotationDNSRecordName = "service.beta.kubernetes.io/azure-dns-record-name"
+
+	// ServiceAnnotationDNSRecordTTL is the annotation used on the service to specify the TTL in seconds of
+	// the DNS records of the service. The default value is 300.
+	ServiceAnnotationDNSRecordTTL = "service.beta.kubernetes.io/azure-dns-record-ttl"
+
+	// ServiceAnnotationDNSRecordOwned is written back to the service by the cloud provider after the DNS records
+	// of the service are created. The value is "<zone ID>/<record name>", and is used to clean up the records
+	// in the previous zone when ServiceAnnotationDNSZoneID or ServiceAnnotationDNSRecordName is changed.
+	ServiceAnnotationDNSRecordOwned = "service.beta.kubernetes.io/azure-dns-record-owned"
+
 	// ServiceAnnotationSharedSecurityRule is the annotation used on the service
 	// to specify that the service should be exposed using an Azure security rule
 	// that may be shared with other service, trading specificity of rules for an
@@ -290,6 +308,20 @@ const (
 	// ServiceAnnotationIPTagsForPublicIP specifies the iptags used when dynamically creating a public ip
 	ServiceAnnotationIPTagsForPublicIP = "service.beta.kubernetes.io/azure-pip-ip-tags"
 
+	// ServiceAnnotationPIPDdosProtectionMode specifies the DDoS protection mode of the managed public ip, which is
+	// `Enabled`, `Disabled` or `VirtualNetworkInherited`. It overrides `publicIPDdosProtectionMode` in the cloud config.
+	ServiceAnnotationPIPDdosProtectionMode = "service.beta.kubernetes.io/azure-pip-ddos-protection-mode"
+
+	// ServiceAnnotationPIPDdosProtectionPlanID specifies the resource ID of the DDoS protection plan associated with
+	// the managed public ip, which is only supported with the `Enabled` DDoS protection mode.
+	// It overrides `publicIPDdosProtectionPlanID` in the cloud config.
+	ServiceAnnotationPIPDdosProtectionPlanID = "service.beta.kubernetes.io/azure-pip-ddos-protection-plan-id"
+
+	// ServiceAnnotationPIPRoutingPreference specifies the routing preference of the managed public ip, which is
+	// `Internet` or `Microsoft`. It overrides `publicIPRoutingPreference` in the cloud config. The routing preference
+	// can only be set when the public ip is created.
+	ServiceAnnotationPIPRoutingPreference = "service.beta.kubernetes.io/azure-pip-routing-preference"
+
 	// ServiceAnnotationAllowedServiceTags is the annotation used on the service
 	// to specify a list of allowed service tags separated by comma
 	// Refer https://docs.microsoft.com/en-us/azure/virtual-network/security-overview#service-tags for all supported service tags.
@@ -350,6 +382,45 @@ const (
 	// ServiceAnnotationDisableTCPReset is the annotation used on the service to disable TCP reset on the load balancer.
 	ServiceAnnotationDisableTCPReset = "service.beta.kubernetes.io/azure-load-balancer-disable-tcp-reset"
 
+	// ServiceAnnotationGatewayLoadBalancerFrontendIPConfigID is the ID of the gateway load balancer frontend IP configuration
+	// the public frontend IP configurations of the service are chained to. An empty value removes the chaining, and the
+	// chaining set outside the cloud provider is kept if the annotation is not set. It is not supported on internal services.
+	ServiceAnnotationGatewayLoadBalancerFrontendIPConfigID = "service.beta.kubernetes.io/azure-load-balancer-gateway-frontend-ip-configuration-id"
+
+	// ServiceAnnotationLoadBalancerOutboundBackendPool is the name of the backend pool on the load balancer of the service
+	// whose outbound traffic is SNATed by the public IPs of the service. An outbound rule is created for each frontend IP
+	// configuration of the service, so the egress IPs are the ones specified by the public IP name or public IP prefix
+	// annotations. For dual-stack services the IPv6 backend pool is the one with the `-IPv6` suffix.
+	// It is only supported on public standard load balancers.
+	ServiceAnnotationLoadBalancerOutboundBackendPool = "service.beta.kubernetes.io/azure-load-balancer-outbound-backend-pool"
+
+	// ServiceAnnotationLoadBalancerOutboundAllocatedPorts is the number of SNAT ports allocated to each instance of the
+	// outbound backend pool. It must be a multiple of 8 between 0 and 64000, and 0 means the default port allocation.
+	ServiceAnnotationLoadBalancerOutboundAllocatedPorts = "service.beta.kubernetes.io/azure-load-balancer-outbound-allocated-ports"
+
+	// ServiceAnnotationLoadBalancerOutboundIdleTimeout is the idle timeout of the outbound connections in minutes.
+	// It must be between 4 and 120, and defaults to 4.
+	ServiceAnnotationLoadBalancerOutboundIdleTimeout = "service.beta.kubernetes.io/azure-load-balancer-outbound-idle-timeout"
+
+	// ServiceAnnotationLoadBalancerInboundNATPortMappings is written back to the service by the cloud provider when any
+	// port of the service has an inbound NAT frontend port range. The value is a JSON object keyed by "<protocol>/<port>",
+	// and each value maps the frontend port to the backend: the node name, or the pod IP for the podIP backend pool type.
+	ServiceAnnotationLoadBalancerInboundNATPortMappings = "service.beta.kubernetes.io/azure-load-balancer-inbound-nat-port-mappings"
+
+	// ServiceAnnotationLoadBalancerDrift is written back to the service by the drift detection when the Azure resources
+	// of the service are drifted and the drift reconciliation is enabled. The value is a hash of the drifted fields,
+	// and the change of the annotation triggers the reconciliation of the service.
+	ServiceAnnotationLoadBalancerDrift = "service.beta.kubernetes.io/azure-load-balancer-drift"
+
+	// ServiceAnnotationLoadBalancerPinned pins the service to the load balancer it is using when set to "true",
+	// so that it is never moved to another load balancer by the service migration of multiple standard load balancers.
+	ServiceAnnotationLoadBalancerPinned = "service.beta.kubernetes.io/azure-load-balancer-pinned"
+
+	// ServiceAnnotationLoadBalancerMigration is written back to the service when the service migration of multiple
+	// standard load balancers starts to move the service off its load balancer. The value is the start time of the
+	// migration, and the change of the annotation triggers the reconciliation of the service.
+	ServiceAnnotationLoadBalancerMigration = "service.beta.kubernetes.io/azure-load-balancer-migration"
+
 	// ServiceTagKey is the service key applied for public IP tags.
 	ServiceTagKey       = "k8s-azure-service"
 	LegacyServiceTagKey = "service"
@@ -359,6 +430,15 @@ const (
 	// ServiceUsingDNSKey is the service name consuming the DNS label on the public IP
 	ServiceUsingDNSKey       = "k8s-azure-dns-label-service"
 	LegacyServiceUsingDNSKey = "kubernetes-dns-label-service"
+	// DNSRecordServiceKey and DNSRecordClusterNameKey are the metadata keys of the DNS record sets
+	// owned by the service. The metadata keys of the record sets cannot contain hyphens.
+	DNSRecordServiceKey     = "k8s_azure_service"
+	DNSRecordClusterNameKey = "k8s_azure_cluster_name"
+	// DNSRecordDefaultTTL is the default TTL in seconds of the DNS records of the services.
+	DNSRecordDefaultTTL = 300
+	// MaxTagValueLength and MaxTagCount are the limits of the tags of an Azure resource.
+	MaxTagValueLength = 256
+	MaxTagCount       = 50
 
 	// DefaultLoadBalancerSourceRanges is the default value of the load balancer source ranges
 	DefaultLoadBalancerSourceRanges = "0.0.0.0/0"
@@ -377,10 +457,39 @@ const (
 	BackendPoolIDTemplate = "/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Network/loadBalancers/%s/backendAddressPools/%s"
 	// LoadBalancerProbeIDTemplate is the template of the load balancer probe
 	LoadBalancerProbeIDTemplate = "/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Network/loadBalancers/%s/probes/%s"
+	// PublicIPAddressIDTemplate is the template of the public IP address
+	PublicIPAddressIDTemplate = "/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Network/publicIPAddresses/%s"
+	// ApplicationSecurityGroupIDTemplate is the template of the application security group
+	ApplicationSecurityGroupIDTemplate = "/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Network/applicationSecurityGroups/%s"
+	// SubnetIDTemplate is the template of the subnet
+	SubnetIDTemplate = "/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Network/virtualNetworks/%s/subnets/%s"
 
 	// InternalLoadBalancerNameSuffix is load balancer suffix
 	InternalLoadBalancerNameSuffix = "-internal"
 
+	// AutoScaledLoadBalancerNamePlaceholder is replaced by the name of the multiple standard
+	// load balancer configuration in the name template of the generated load balancers.
+	AutoScaledLoadBalancerNamePlaceholder = "{name}"
+	// AutoScaledLoadBalancerIndexPlaceholder is replaced by the index of the generated load balancer.
+	AutoScaledLoadBalancerIndexPlaceholder = "{index}"
+	// DefaultAutoScaledLoadBalancerNameTemplate is the default name template of the generated load balancers.
+	DefaultAutoScaledLoadBalancerNameTemplate = AutoScaledLoadBalancerNamePlaceholder + "-" + AutoScaledLoadBalancerIndexPlaceholder
+
+	// IPTagTypeRoutingPreference is the type of the ip tag that sets the routing preference of a public ip.
+	IPTagTypeRoutingPreference = "RoutingPreference"
+	// PublicIPRoutingPreferenceInternet routes the traffic of the public ip over the ISP network.
+	PublicIPRoutingPreferenceInternet = "Internet"
+	// PublicIPRoutingPreferenceMicrosoft routes the traffic of the public ip over the Microsoft global network, which is the default.
+	PublicIPRoutingPreferenceMicrosoft = "Microsoft"
+
+	// PublicIPPrefixPoolIndexPlaceholder is replaced by the index of the created prefix in the name template
+	// of the public IP prefix pool.
+	PublicIPPrefixPoolIndexPlaceholder = "{index}"
+	// DefaultPublicIPPrefixPoolPrefixLength is the default length of the created IPv4 public IP prefixes.
+	DefaultPublicIPPrefixPoolPrefixLength = 28
+	// DefaultPublicIPPrefixPoolIPv6PrefixLength is the default length of the created IPv6 public IP prefixes.
+	DefaultPublicIPPrefixPoolIPv6PrefixLength = 124
+
 	// FrontendIPConfigNameMaxLength is the max length of the frontend IP configuration
 	FrontendIPConfigNameMaxLength = 80
 	// LoadBalancerRuleNameMaxLength is the max length of the load balancing rule
@@ -454,6 +563,21 @@ const (
 
 	// DefaultRouteUpdateIntervalInSeconds defines the route reconciling interval.
 	DefaultRouteUpdateIntervalInSeconds = 30
+
+	// RouteTableRouteLimit is the maximum number of routes in a route table.
+	RouteTableRouteLimit = 400
+
+	// RouteTableOwnedRoutesKeyPrefix is the prefix of the route table tags that record the routes owned by a cluster.
+	// It is followed by the cluster name and the index of the tag, e.g. k8s-azure-owned-routes-kubernetes-0, because
+	// the comma separated route names are split across several tags to fit into the tag value limit.
+	RouteTableOwnedRoutesKeyPrefix = "k8s-azure-owned-routes-"
+)
+
+// Route reconcile mode
+const (
+	RouteReconcileModeNone   = "none"
+	RouteReconcileModeReport = "report"
+	RouteReconcileModeRepair = "repair"
 )
 
 // cloud provider config secret
@@ -463,6 +587,16 @@ const (
 	DefaultCloudProviderConfigSecKey       = "cloud-config"
 )
 
+// multiple standard load balancer state configmap
+const (
+	// MultipleStandardLoadBalancerStateConfigMapName is the name of the ConfigMap that persists the load balancer
+	// configurations generated by AutoScale and the progress of the service migrations, which are not in the cloud
+	// provider configuration.
+	MultipleStandardLoadBalancerStateConfigMapName      = "azure-multiple-standard-load-balancers"
+	MultipleStandardLoadBalancerStateConfigMapNamespace = "kube-system"
+	MultipleStandardLoadBalancerStateConfigMapKey       = "state"
+)
+
 // RateLimited error string
 const RateLimited = "rate limited"
 
@@ -475,6 +609,16 @@ const (
 	PortAnnotationNoLBRule      PortParams = "no_lb_rule"
 	// NoHealthProbeRule determines whether the port is only used for health probe. no lb probe rule will be created.
 	PortAnnotationNoHealthProbeRule PortParams = "no_probe_rule"
+	// PortAnnotationInboundNATFrontendPortRange is the frontend port range of the inbound NAT rule of the port, in the form of
+	// "<start>-<end>". Each backend of the service is reachable from its own frontend port in the range.
+	// It requires a standard load balancer, and floating IP to be disabled unless the backend pool type is podIP.
+	PortAnnotationInboundNATFrontendPortRange PortParams = "inbound_nat_frontend_port_range"
+	// PortAnnotationAllowedIPRanges is the comma separated IP ranges allowed to access the port. If it or
+	// PortAnnotationAllowedServiceTags is set, the allowed IP ranges and service tags of the service are not
+	// applied to the port, and the port is only allowed from the IP ranges and service tags of the port.
+	PortAnnotationAllowedIPRanges PortParams = "allowed_ip_ranges"
+	// PortAnnotationAllowedServiceTags is the comma separated service tags allowed to access the port.
+	PortAnnotationAllowedServiceTags PortParams = "allowed_service_tags"
 )
 
 type PortParams string
@@ -506,6 +650,19 @@ const (
 	// `/healthz` would be configured by default.
 	HealthProbeParamsRequestPath  HealthProbeParams = "request-path"
 	HealthProbeDefaultRequestPath string            = "/"
+
+	// HealthProbeParamsGRPCService determines the service name in the gRPC health check request, which is only
+	// useful when the protocol is `grpc`. The overall health of the server is checked if not set.
+	HealthProbeParamsGRPCService HealthProbeParams = "grpc-service"
+
+	// HealthProbeParamsExpectedStatusCodes determines the comma separated status codes or ranges of status codes,
+	// e.g. `200-299,401`, that the backend is considered healthy with. This is only useful for HTTP and HTTPS.
+	// Azure load balancer only accepts 200, so the probe is translated by the health-probe-proxy if it is set.
+	HealthProbeParamsExpectedStatusCodes HealthProbeParams = "expected-status-codes"
+
+	// HealthProbeProtocolGRPC is the protocol of the health probe params to check the backend with the gRPC health
+	// checking protocol over HTTP/2. The probe is translated by the health-probe-proxy.
+	HealthProbeProtocolGRPC = "grpc"
 )
 
 type HealthProbeParams string
@@ -546,15 +703,33 @@ const (
 	// automatically approved, only works when visibility is set to "*".
 	ServiceAnnotationPLSAutoApproval = "service.beta.kubernetes.io/azure-pls-auto-approval"
 
+	// ServiceAnnotationPLSApprovedEndpoints determines a space separated list of private endpoint resource IDs whose pending
+	// connections to the PLS are approved by the private endpoint connection approval.
+	ServiceAnnotationPLSApprovedEndpoints = "service.beta.kubernetes.io/azure-pls-approved-endpoints"
+
+	// ServiceAnnotationPLSRejectUnapprovedConnections determines whether the pending private endpoint connections to the PLS
+	// that are not approved by any policy are rejected. It overrides `rejectUnapprovedPrivateLinkServiceConnections` in the cloud config.
+	ServiceAnnotationPLSRejectUnapprovedConnections = "service.beta.kubernetes.io/azure-pls-reject-unapproved-connections"
+
+	// ServiceAnnotationPLSConnectionStates is written back to the service by the private endpoint connection approval.
+	// The value is a JSON object mapping the private endpoint resource IDs to the states of their connections to the PLS.
+	ServiceAnnotationPLSConnectionStates = "service.beta.kubernetes.io/azure-pls-connection-states"
+
 	// ID string used to create a not existing PLS placehold in plsCache to avoid redundant
 	PrivateLinkServiceNotExistID = "PrivateLinkServiceNotExistID"
 
-	// Key of tag indicating owner service of the PLS.
+	// Key of tag indicating owner service of the PLS. The owners are separated by commas if the PLS is shared by
+	// the services using the same LB frontend.
 	OwnerServiceTagKey = "k8s-azure-owner-service"
 
 	// Key of tag indicating cluster name of the service that owns PLS.
 	ClusterNameTagKey = "k8s-azure-cluster-name"
 
+	// States of the private endpoint connections to the PLS.
+	PrivateEndpointConnectionStatusPending  = "Pending"
+	PrivateEndpointConnectionStatusApproved = "Approved"
+	PrivateEndpointConnectionStatusRejected = "Rejected"
+
 	// Default number of IP configs for PLS
 	PLSDefaultNumOfIPConfig = 1
 )
@@ -571,6 +746,13 @@ const (
 
 	DefaultLoadBalancerBackendPoolUpdateIntervalInSeconds = 30
 
+	// ServiceMigrationInterval is the interval of checking the services to be moved across multiple standard load balancers.
+	ServiceMigrationInterval = 30 * time.Second
+	// ServiceMigrationTimeout is the timeout of moving a service to another load balancer.
+	ServiceMigrationTimeout = 10 * time.Minute
+	// ServiceMigrationRetryInterval is the interval of moving a service again after it fails to be moved.
+	ServiceMigrationRetryInterval = 30 * time.Minute
+
 	ServiceNameLabel = "kubernetes.io/service-name"
 )
 
@@ -583,6 +765,13 @@ const (
 	SharedProbeName                                          = "cluster-service-shared-health-probe"
 )
 
+// Orphaned resource garbage collection mode
+const (
+	OrphanedResourceGCModeReport                  = "report"
+	OrphanedResourceGCModeEnforce                 = "enforce"
+	DefaultOrphanedResourceGCGracePeriodInSeconds = 3600
+)
+
 // VM power state
 const (
 	VMPowerStatePrefix       = "PowerState/"
@@ -0,0 +1,62 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+)
+
+var driftMetrics = registerDriftMetrics()
+
+// resourceDriftMetrics is the metrics of the Azure resources that differ from the desired state of the services.
+type resourceDriftMetrics struct {
+	drift *metrics.GaugeVec
+}
+
+// SetServiceResourceDrift marks the field of the Azure resource of the service as drifted.
+func SetServiceResourceDrift(service, resourceType, resource, field string) {
+	driftMetrics.drift.WithLabelValues(service, resourceType, resource, field).Set(1)
+}
+
+// ResetServiceResourceDrift removes all drifted fields of the service.
+func ResetServiceResourceDrift(service string) {
+	driftMetrics.drift.DeletePartialMatch(prometheus.Labels{"service": service})
+}
+
+// registerDriftMetrics registers the drift metrics.
+func registerDriftMetrics() *resourceDriftMetrics {
+	metrics := &resourceDriftMetrics{
+		drift: metrics.NewGaugeVec(
+			&metrics.GaugeOpts{
+				Namespace:      consts.AzureMetricsNamespace,
+				Name:           "resource_drift",
+				Help:           "Fields of the Azure resources that differ from the desired state of a service",
+				StabilityLevel: metrics.ALPHA,
+			},
+			[]string{"service", "resource_type", "resource", "field"},
+		),
+	}
+
+	legacyregistry.MustRegister(metrics.drift)
+
+	return metrics
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+)
+
+var orphanMetrics = registerOrphanMetrics()
+
+// orphanedResourceMetrics is the metrics of the Azure resources whose owning service no longer exists.
+type orphanedResourceMetrics struct {
+	orphaned *metrics.GaugeVec
+}
+
+// SetOrphanedResource marks the Azure resource as orphaned. The service is empty if the owning service is unknown.
+func SetOrphanedResource(resourceType, resource, service string) {
+	orphanMetrics.orphaned.WithLabelValues(resourceType, resource, service).Set(1)
+}
+
+// ResetOrphanedResources removes all orphaned resources.
+func ResetOrphanedResources() {
+	orphanMetrics.orphaned.Reset()
+}
+
+// registerOrphanMetrics registers the orphaned resource metrics.
+func registerOrphanMetrics() *orphanedResourceMetrics {
+	metrics := &orphanedResourceMetrics{
+		orphaned: metrics.NewGaugeVec(
+			&metrics.GaugeOpts{
+				Namespace:      consts.AzureMetricsNamespace,
+				Name:           "orphaned_resources",
+				Help:           "Azure resources managed by the cloud provider whose owning service no longer exists",
+				StabilityLevel: metrics.ALPHA,
+			},
+			[]string{"resource_type", "resource", "service"},
+		),
+	}
+
+	legacyregistry.MustRegister(metrics.orphaned)
+
+	return metrics
+}
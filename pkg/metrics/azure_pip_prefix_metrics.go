@@ -0,0 +1,66 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+)
+
+var pipPrefixMetrics = registerPublicIPPrefixMetrics()
+
+// publicIPPrefixMetrics is the metrics of the public IP prefixes in the public IP prefix pool.
+type publicIPPrefixMetrics struct {
+	allocated *metrics.GaugeVec
+	capacity  *metrics.GaugeVec
+}
+
+// SetPublicIPPrefixUtilization records the number of allocated public IPs and the number of addresses of the prefix.
+func SetPublicIPPrefixUtilization(prefix, ipVersion string, allocated, capacity int) {
+	pipPrefixMetrics.allocated.WithLabelValues(prefix, ipVersion).Set(float64(allocated))
+	pipPrefixMetrics.capacity.WithLabelValues(prefix, ipVersion).Set(float64(capacity))
+}
+
+// registerPublicIPPrefixMetrics registers the public IP prefix metrics.
+func registerPublicIPPrefixMetrics() *publicIPPrefixMetrics {
+	metrics := &publicIPPrefixMetrics{
+		allocated: metrics.NewGaugeVec(
+			&metrics.GaugeOpts{
+				Namespace:      consts.AzureMetricsNamespace,
+				Name:           "public_ip_prefix_allocated_addresses",
+				Help:           "Number of public IPs allocated from the public IP prefix in the public IP prefix pool",
+				StabilityLevel: metrics.ALPHA,
+			},
+			[]string{"prefix", "ip_version"},
+		),
+		capacity: metrics.NewGaugeVec(
+			&metrics.GaugeOpts{
+				Namespace:      consts.AzureMetricsNamespace,
+				Name:           "public_ip_prefix_capacity_addresses",
+				Help:           "Number of addresses of the public IP prefix in the public IP prefix pool",
+				StabilityLevel: metrics.ALPHA,
+			},
+			[]string{"prefix", "ip_version"},
+		),
+	}
+
+	legacyregistry.MustRegister(metrics.allocated, metrics.capacity)
+
+	return metrics
+}
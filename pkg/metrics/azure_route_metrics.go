@@ -0,0 +1,93 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+)
+
+const (
+	RouteOwnerCluster = "cluster"
+	RouteOwnerForeign = "foreign"
+)
+
+var routeMetrics = registerRouteTableMetrics()
+
+// routeTableMetrics is the metrics of the routes in the route tables of the cluster.
+type routeTableMetrics struct {
+	usage *metrics.GaugeVec
+	limit *metrics.GaugeVec
+	drift *metrics.GaugeVec
+}
+
+// SetRouteTableUsage records the number of routes in the route table owned by the cluster and by others, and the route limit.
+func SetRouteTableUsage(routeTable string, owned, foreign, limit int) {
+	routeMetrics.usage.WithLabelValues(routeTable, RouteOwnerCluster).Set(float64(owned))
+	routeMetrics.usage.WithLabelValues(routeTable, RouteOwnerForeign).Set(float64(foreign))
+	routeMetrics.limit.WithLabelValues(routeTable).Set(float64(limit))
+}
+
+// SetRouteDrift records a field of the route that differs from the desired state.
+func SetRouteDrift(routeTable, route, field string) {
+	routeMetrics.drift.WithLabelValues(routeTable, route, field).Set(1)
+}
+
+// ResetRouteDrift removes the drift records of the routes in the route table.
+func ResetRouteDrift(routeTable string) {
+	routeMetrics.drift.DeletePartialMatch(prometheus.Labels{"route_table": routeTable})
+}
+
+// registerRouteTableMetrics registers the route table metrics.
+func registerRouteTableMetrics() *routeTableMetrics {
+	metrics := &routeTableMetrics{
+		usage: metrics.NewGaugeVec(
+			&metrics.GaugeOpts{
+				Namespace:      consts.AzureMetricsNamespace,
+				Name:           "route_table_usage",
+				Help:           "Number of routes in the route table by owner, which is the cluster or others",
+				StabilityLevel: metrics.ALPHA,
+			},
+			[]string{"route_table", "owner"},
+		),
+		limit: metrics.NewGaugeVec(
+			&metrics.GaugeOpts{
+				Namespace:      consts.AzureMetricsNamespace,
+				Name:           "route_table_limit",
+				Help:           "Maximum number of routes in the route table",
+				StabilityLevel: metrics.ALPHA,
+			},
+			[]string{"route_table"},
+		),
+		drift: metrics.NewGaugeVec(
+			&metrics.GaugeOpts{
+				Namespace:      consts.AzureMetricsNamespace,
+				Name:           "route_drift",
+				Help:           "Fields of the routes of the nodes that differ from the desired state",
+				StabilityLevel: metrics.ALPHA,
+			},
+			[]string{"route_table", "route", "field"},
+		),
+	}
+
+	legacyregistry.MustRegister(metrics.usage, metrics.limit, metrics.drift)
+
+	return metrics
+}
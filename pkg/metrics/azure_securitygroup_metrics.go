@@ -0,0 +1,72 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+)
+
+const (
+	SecurityGroupUsageRules                = "rules"
+	SecurityGroupUsageSourceAddresses      = "source_addresses"
+	SecurityGroupUsageDestinationAddresses = "destination_addresses"
+)
+
+var securityGroupMetrics = registerSecurityGroupMetrics()
+
+// securityGroupUsageMetrics is the metrics of the usage of the security group against the Azure limits.
+type securityGroupUsageMetrics struct {
+	usage *metrics.GaugeVec
+	limit *metrics.GaugeVec
+}
+
+// SetSecurityGroupUsage records the usage and the limit of the given resource of the security group.
+func SetSecurityGroupUsage(securityGroup, resource string, usage, limit int) {
+	securityGroupMetrics.usage.WithLabelValues(securityGroup, resource).Set(float64(usage))
+	securityGroupMetrics.limit.WithLabelValues(securityGroup, resource).Set(float64(limit))
+}
+
+// registerSecurityGroupMetrics registers the security group metrics.
+func registerSecurityGroupMetrics() *securityGroupUsageMetrics {
+	metrics := &securityGroupUsageMetrics{
+		usage: metrics.NewGaugeVec(
+			&metrics.GaugeOpts{
+				Namespace:      consts.AzureMetricsNamespace,
+				Name:           "security_group_usage",
+				Help:           "Number of rules, source addresses or destination addresses in the security group",
+				StabilityLevel: metrics.ALPHA,
+			},
+			[]string{"security_group", "resource"},
+		),
+		limit: metrics.NewGaugeVec(
+			&metrics.GaugeOpts{
+				Namespace:      consts.AzureMetricsNamespace,
+				Name:           "security_group_limit",
+				Help:           "Maximum number of rules, source addresses or destination addresses in the security group",
+				StabilityLevel: metrics.ALPHA,
+			},
+			[]string{"security_group", "resource"},
+		),
+	}
+
+	legacyregistry.MustRegister(metrics.usage, metrics.limit)
+
+	return metrics
+}
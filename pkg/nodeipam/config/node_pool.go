@@ -0,0 +1,45 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NodePoolCIDRsConfiguration is the content of the node pool CIDRs configuration file.
+type NodePoolCIDRsConfiguration struct {
+	// NodePools are the node pools with their own pod CIDR ranges. A node uses the first node pool
+	// that it matches, or the cluster CIDRs if it matches none of them.
+	NodePools []NodePoolCIDRConfiguration `json:"nodePools" yaml:"nodePools"`
+}
+
+// NodePoolCIDRConfiguration describes the pod CIDR ranges of the nodes selected by the node selector.
+// Like the cluster CIDRs, a node pool has a CIDR of each IP family in a dual-stack cluster.
+type NodePoolCIDRConfiguration struct {
+	// Name of the node pool.
+	Name string `json:"name" yaml:"name"`
+	// NodeSelector selects the nodes of the node pool by labels.
+	NodeSelector *metav1.LabelSelector `json:"nodeSelector" yaml:"nodeSelector"`
+	// IPv4CIDR is the IPv4 CIDR that the IPv4 pod CIDRs of the nodes are allocated from.
+	IPv4CIDR string `json:"ipv4CIDR,omitempty" yaml:"ipv4CIDR,omitempty"`
+	// NodeCIDRMaskSizeIPv4 is the mask size of the IPv4 pod CIDRs of the nodes. Default is 24.
+	NodeCIDRMaskSizeIPv4 int32 `json:"nodeCIDRMaskSizeIPv4,omitempty" yaml:"nodeCIDRMaskSizeIPv4,omitempty"`
+	// IPv6CIDR is the IPv6 CIDR that the IPv6 pod CIDRs of the nodes are allocated from.
+	IPv6CIDR string `json:"ipv6CIDR,omitempty" yaml:"ipv6CIDR,omitempty"`
+	// NodeCIDRMaskSizeIPv6 is the mask size of the IPv6 pod CIDRs of the nodes. Default is 64.
+	NodeCIDRMaskSizeIPv6 int32 `json:"nodeCIDRMaskSizeIPv6,omitempty" yaml:"nodeCIDRMaskSizeIPv6,omitempty"`
+}
@@ -32,4 +32,12 @@ type NodeIPAMControllerConfiguration struct {
 	// NodeCIDRMaskSizeIPv6 is the mask size for IPv6 node cidr in dual-stack cluster.
 	// This can be used only with dual stack clusters and is incompatible with single stack clusters.
 	NodeCIDRMaskSizeIPv6 int32
+	// NodePoolCIDRsConfigFile is the path of the file of the node pool CIDRs configuration. The nodes selected by a node
+	// pool get their pod CIDRs from the CIDRs of the node pool instead of the cluster CIDRs.
+	NodePoolCIDRsConfigFile string
+	// ClusterCIDRsConfigMapName is the name of the ConfigMap of the additional cluster CIDRs. The additional cluster
+	// CIDRs are added at runtime, and the allocation spills over into them when the cluster CIDRs are full.
+	ClusterCIDRsConfigMapName string
+	// ClusterCIDRsConfigMapNamespace is the namespace of the ConfigMap of the additional cluster CIDRs.
+	ClusterCIDRsConfigMapNamespace string
 }
@@ -0,0 +1,87 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ipam
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/nodeipam/ipam/cidrset"
+)
+
+// AllocationsPath is the path of the debug endpoint dumping the pod CIDR allocations.
+const AllocationsPath = "/debug/nodeipam/allocations"
+
+// CIDRSetAllocations is the allocations of a cluster cidr or of a cidr of a node pool.
+type CIDRSetAllocations struct {
+	// NodePool is the name of the node pool, empty for the cluster cidrs
+	NodePool string `json:"nodePool,omitempty"`
+	cidrset.Dump
+}
+
+// AllocationDumper is implemented by the CIDR allocators that can dump their allocations.
+type AllocationDumper interface {
+	// DumpAllocations returns the allocations of all the cidrs of the allocator.
+	DumpAllocations() []CIDRSetAllocations
+}
+
+// AllocationHandler serves the allocations of the CIDR allocator at AllocationsPath
+// so that the cluster cidr expansion can be planned.
+type AllocationHandler struct {
+	lock   sync.RWMutex
+	dumper AllocationDumper
+}
+
+// NewAllocationHandler creates an AllocationHandler. It serves 503 until the allocator is set.
+func NewAllocationHandler() *AllocationHandler {
+	return &AllocationHandler{}
+}
+
+// SetAllocator sets the CIDR allocator to dump. Allocators that cannot dump their allocations are ignored.
+func (h *AllocationHandler) SetAllocator(allocator CIDRAllocator) {
+	dumper, ok := allocator.(AllocationDumper)
+	if !ok {
+		klog.V(2).Infof("AllocationHandler: CIDR allocator %T does not support dumping allocations", allocator)
+		return
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.dumper = dumper
+}
+
+// ServeHTTP implements http.Handler.
+func (h *AllocationHandler) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	h.lock.RLock()
+	dumper := h.dumper
+	h.lock.RUnlock()
+
+	if dumper == nil {
+		http.Error(w, "the node ipam controller is not running", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(dumper.DumpAllocations()); err != nil {
+		klog.Errorf("AllocationHandler: failed to write the allocations: %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ipam
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/nodeipam/ipam/cidrset"
+	"sigs.k8s.io/cloud-provider-azure/pkg/util/controller/testutil"
+)
+
+func newTestRangeAllocator(t *testing.T, allocatorParams CIDRAllocatorParams, nodes ...*v1.Node) *rangeAllocator {
+	fakeNodeHandler := &testutil.FakeNodeHandler{
+		Existing:  nodes,
+		Clientset: fake.NewSimpleClientset(),
+	}
+	nodeList, _ := fakeNodeHandler.List(context.TODO(), metav1.ListOptions{})
+	allocator, err := NewCIDRRangeAllocator(fakeNodeHandler, getFakeNodeInformer(fakeNodeHandler), allocatorParams, nodeList)
+	if err != nil {
+		t.Fatalf("failed to create CIDRRangeAllocator with error %v", err)
+	}
+	ra := allocator.(*rangeAllocator)
+	ra.recorder = testutil.NewFakeRecorder()
+	return ra
+}
+
+func TestAllocationHandler(t *testing.T) {
+	handler := NewAllocationHandler()
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, AllocationsPath, nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	_, clusterCIDR, _ := net.ParseCIDR("10.10.0.0/22")
+	_, poolCIDR, _ := net.ParseCIDR("10.10.3.0/24")
+	ra := newTestRangeAllocator(t, CIDRAllocatorParams{
+		ClusterCIDRs:      []*net.IPNet{clusterCIDR},
+		NodeCIDRMaskSizes: []int{24},
+		NodePools: []NodePool{{
+			Name:              "gpu",
+			NodeSelector:      labels.SelectorFromSet(labels.Set{"pool": "gpu"}),
+			ClusterCIDRs:      []*net.IPNet{poolCIDR},
+			NodeCIDRMaskSizes: []int{26},
+		}},
+	}, &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node0"},
+		Spec:       v1.NodeSpec{PodCIDRs: []string{"10.10.1.0/24"}},
+	})
+	handler.SetAllocator(ra)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, AllocationsPath, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var allocations []CIDRSetAllocations
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &allocations))
+	assert.Equal(t, []CIDRSetAllocations{
+		{
+			Dump: cidrset.Dump{
+				ClusterCIDR:      "10.10.0.0/22",
+				NodeMaskSize:     24,
+				MaxCIDRs:         4,
+				AllocatedCIDRs:   2,
+				LargestFreeBlock: 1,
+				Allocated:        []string{"10.10.1.0/24", "10.10.3.0/24"},
+				Free:             []string{"10.10.0.0/24", "10.10.2.0/24"},
+			},
+		},
+		{
+			NodePool: "gpu",
+			Dump: cidrset.Dump{
+				ClusterCIDR:      "10.10.3.0/24",
+				NodeMaskSize:     26,
+				MaxCIDRs:         4,
+				AllocatedCIDRs:   0,
+				LargestFreeBlock: 4,
+				Allocated:        []string{},
+				Free:             []string{"10.10.3.0/24"},
+			},
+		},
+	}, allocations)
+}
+
+func TestAllocationFailureEvent(t *testing.T) {
+	_, clusterCIDR, _ := net.ParseCIDR("10.10.0.0/24")
+	ra := newTestRangeAllocator(t, CIDRAllocatorParams{
+		ClusterCIDRs:      []*net.IPNet{clusterCIDR},
+		NodeCIDRMaskSizes: []int{24},
+	}, &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node0"},
+		Spec:       v1.NodeSpec{PodCIDRs: []string{"10.10.0.0/24"}},
+	})
+
+	err := ra.AllocateOrOccupyCIDR(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}})
+	assert.Error(t, err)
+
+	recorder := ra.recorder.(*testutil.FakeRecorder)
+	recorder.Lock()
+	defer recorder.Unlock()
+	var warnings []*v1.Event
+	for _, event := range recorder.Events {
+		if event.Type == v1.EventTypeWarning {
+			warnings = append(warnings, event)
+		}
+	}
+	assert.Len(t, warnings, 1)
+	assert.Equal(t, cidrAllocationFailedReason, warnings[0].Reason)
+	assert.Equal(t, "node1", warnings[0].InvolvedObject.Name)
+	assert.Contains(t, warnings[0].Message, cidrset.ErrCIDRRangeNoCIDRsRemaining.Error())
+}
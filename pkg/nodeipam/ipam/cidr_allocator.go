@@ -58,6 +58,9 @@ const (
 
 	// cidrUpdateRetries is the no. of times a NodeSpec update will be retried before dropping it.
 	cidrUpdateRetries = 3
+
+	// cidrAllocationFailedReason is the reason of the node events when the pod cidrs cannot be allocated.
+	cidrAllocationFailedReason = "CIDRAllocationFailed"
 )
 
 // CIDRAllocator is an interface implemented by things that know how
@@ -84,6 +87,17 @@ type CIDRAllocatorParams struct {
 	SecondaryServiceCIDR *net.IPNet
 	// NodeCIDRMaskSizes is list of node cidr mask sizes
 	NodeCIDRMaskSizes []int
+	// NodePools is list of node pools that have their own cidrs, only supported by the range allocator
+	NodePools []NodePool
+	// AdditionalClusterCIDRs is list of cluster cidrs added at runtime, only supported by the range allocator
+	AdditionalClusterCIDRs []*net.IPNet
+}
+
+// ClusterCIDRExpander is implemented by the CIDR allocators that accept additional cluster cidrs at runtime.
+type ClusterCIDRExpander interface {
+	// AddClusterCIDRs adds the cluster cidrs. The allocation spills over into them when
+	// the cluster cidr of the same ip family is full.
+	AddClusterCIDRs(cidrs []*net.IPNet) error
 }
 
 // New creates a new CIDR range allocator.
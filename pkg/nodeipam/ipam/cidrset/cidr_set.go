@@ -144,6 +144,7 @@ func (s *CidrSet) UpdateSubnetMaskSize(newNodeMaskSize int, nodeNamePodCIDRMap m
 			}
 		}
 	}
+	s.updateMetrics()
 
 	return nil
 }
@@ -211,6 +212,7 @@ func (s *CidrSet) AllocateNext() (*net.IPNet, error) {
 	defer s.Unlock()
 
 	if s.allocatedCIDRs == s.maxCIDRs {
+		cidrSetAllocationFailures.WithLabelValues(s.label, allocationFailureReasonExhausted).Inc()
 		return nil, ErrCIDRRangeNoCIDRsRemaining
 	}
 	candidate := s.nextCandidate
@@ -228,7 +230,7 @@ func (s *CidrSet) AllocateNext() (*net.IPNet, error) {
 	// Update metrics
 	cidrSetAllocations.WithLabelValues(s.label).Inc()
 	cidrSetAllocationTriesPerRequest.WithLabelValues(s.label).Observe(float64(i))
-	cidrSetUsage.WithLabelValues(s.label).Set(float64(s.allocatedCIDRs) / float64(s.maxCIDRs))
+	s.updateMetrics()
 
 	return s.indexToCIDRBlock(candidate, 0), nil
 }
@@ -241,6 +243,7 @@ func (s *CidrSet) AllocateNextWithNodeMaskSize(nodeMaskSize int) (*net.IPNet, er
 
 	relativeSize := 1 << (s.nodeMaskSize - nodeMaskSize)
 	if s.maxCIDRs-s.allocatedCIDRs < relativeSize {
+		cidrSetAllocationFailures.WithLabelValues(s.label, allocationFailureReasonExhausted).Inc()
 		return nil, ErrCIDRRangeNoCIDRsRemaining
 	}
 
@@ -274,6 +277,7 @@ func (s *CidrSet) AllocateNextWithNodeMaskSize(nodeMaskSize int) (*net.IPNet, er
 	}
 
 	if !succeeded {
+		cidrSetAllocationFailures.WithLabelValues(s.label, allocationFailureReasonFragmented).Inc()
 		return nil, ErrCIDRRangeNoCIDRsRemaining
 	}
 
@@ -285,7 +289,7 @@ func (s *CidrSet) AllocateNextWithNodeMaskSize(nodeMaskSize int) (*net.IPNet, er
 	// Update metrics
 	cidrSetAllocations.WithLabelValues(s.label).Inc()
 	cidrSetAllocationTriesPerRequest.WithLabelValues(s.label).Observe(float64(tries))
-	cidrSetUsage.WithLabelValues(s.label).Set(float64(s.allocatedCIDRs) / float64(s.maxCIDRs))
+	s.updateMetrics()
 
 	return s.indexToCIDRBlock(i, nodeMaskSize), nil
 }
@@ -357,7 +361,7 @@ func (s *CidrSet) Release(cidr *net.IPNet) error {
 		}
 	}
 
-	cidrSetUsage.WithLabelValues(s.label).Set(float64(s.allocatedCIDRs) / float64(s.maxCIDRs))
+	s.updateMetrics()
 	return nil
 }
 
@@ -380,7 +384,7 @@ func (s *CidrSet) Occupy(cidr *net.IPNet) (err error) {
 		}
 	}
 
-	cidrSetUsage.WithLabelValues(s.label).Set(float64(s.allocatedCIDRs) / float64(s.maxCIDRs))
+	s.updateMetrics()
 	return nil
 }
 
@@ -409,3 +413,106 @@ func (s *CidrSet) getIndexForIP(ip net.IP) (int, error) {
 
 	return 0, fmt.Errorf("invalid IP: %v", ip)
 }
+
+// updateMetrics updates the usage metrics of the CidrSet. Must be called with the lock held.
+func (s *CidrSet) updateMetrics() {
+	cidrSetUsage.WithLabelValues(s.label).Set(float64(s.allocatedCIDRs) / float64(s.maxCIDRs))
+	cidrSetAllocatedCIDRs.WithLabelValues(s.label).Set(float64(s.allocatedCIDRs))
+	cidrSetFreeCIDRs.WithLabelValues(s.label).Set(float64(s.maxCIDRs - s.allocatedCIDRs))
+	cidrSetLargestFreeBlock.WithLabelValues(s.label).Set(float64(s.largestFreeBlock()))
+}
+
+// largestFreeBlock returns the number of node CIDRs in the largest contiguous free block.
+// Must be called with the lock held.
+func (s *CidrSet) largestFreeBlock() int {
+	words := s.used.Bits()
+	largest, current := 0, 0
+	for i := 0; i < s.maxCIDRs; {
+		// skip the words that are all free or all used
+		if i%bits.UintSize == 0 && i+bits.UintSize <= s.maxCIDRs {
+			var word big.Word
+			if i/bits.UintSize < len(words) {
+				word = words[i/bits.UintSize]
+			}
+			if word == 0 {
+				current += bits.UintSize
+				i += bits.UintSize
+				continue
+			}
+			if word == ^big.Word(0) {
+				largest, current = max(largest, current), 0
+				i += bits.UintSize
+				continue
+			}
+		}
+		if s.used.Bit(i) == 0 {
+			current++
+		} else {
+			largest, current = max(largest, current), 0
+		}
+		i++
+	}
+	return max(largest, current)
+}
+
+// Dump is a snapshot of the allocations of a CidrSet.
+type Dump struct {
+	// ClusterCIDR is the CIDR that the node CIDRs are allocated from
+	ClusterCIDR string `json:"clusterCIDR"`
+	// NodeMaskSize is the mask size of the node CIDRs
+	NodeMaskSize int `json:"nodeMaskSize"`
+	// MaxCIDRs is the number of node CIDRs in the cluster CIDR
+	MaxCIDRs int `json:"maxCIDRs"`
+	// AllocatedCIDRs is the number of allocated node CIDRs
+	AllocatedCIDRs int `json:"allocatedCIDRs"`
+	// LargestFreeBlock is the number of node CIDRs in the largest contiguous free block
+	LargestFreeBlock int `json:"largestFreeBlock"`
+	// Allocated is the allocated ranges, merged into the fewest CIDRs
+	Allocated []string `json:"allocated"`
+	// Free is the free ranges, merged into the fewest CIDRs
+	Free []string `json:"free"`
+}
+
+// Dump returns the allocation bitmap of the CidrSet as CIDR ranges.
+func (s *CidrSet) Dump() Dump {
+	s.Lock()
+	defer s.Unlock()
+
+	dump := Dump{
+		ClusterCIDR:      s.clusterCIDR.String(),
+		NodeMaskSize:     s.nodeMaskSize,
+		MaxCIDRs:         s.maxCIDRs,
+		AllocatedCIDRs:   s.allocatedCIDRs,
+		LargestFreeBlock: s.largestFreeBlock(),
+		Allocated:        []string{},
+		Free:             []string{},
+	}
+	begin := 0
+	for i := 1; i <= s.maxCIDRs; i++ {
+		if i < s.maxCIDRs && s.used.Bit(i) == s.used.Bit(begin) {
+			continue
+		}
+		if s.used.Bit(begin) == 1 {
+			dump.Allocated = append(dump.Allocated, s.indexRangeToCIDRs(begin, i-1)...)
+		} else {
+			dump.Free = append(dump.Free, s.indexRangeToCIDRs(begin, i-1)...)
+		}
+		begin = i
+	}
+	return dump
+}
+
+// indexRangeToCIDRs converts the node CIDRs from begin to end, inclusive, to the fewest aligned CIDRs.
+func (s *CidrSet) indexRangeToCIDRs(begin, end int) []string {
+	var cidrs []string
+	for begin <= end {
+		// the largest aligned block that starts at begin and does not exceed end
+		size := 1 << (bits.Len(uint(end-begin+1)) - 1)
+		for begin%size != 0 {
+			size >>= 1
+		}
+		cidrs = append(cidrs, s.indexToCIDRBlock(begin, s.nodeMaskSize-bits.TrailingZeros(uint(size))).String())
+		begin += size
+	}
+	return cidrs
+}
@@ -24,6 +24,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"k8s.io/component-base/metrics"
 	"k8s.io/component-base/metrics/testutil"
 	"k8s.io/klog/v2"
 )
@@ -1209,6 +1210,79 @@ func TestCidrSetMetrics(t *testing.T) {
 
 }
 
+func TestCidrSetFreeBlockMetricsAndDump(t *testing.T) {
+	const clusterCIDRStr = "10.1.0.0/24"
+	_, clusterCIDR, _ := net.ParseCIDR(clusterCIDRStr)
+	// We have 16 free cidrs
+	a, err := NewCIDRSet(clusterCIDR, 28)
+	if err != nil {
+		t.Fatalf("unexpected error creating CidrSet: %v", err)
+	}
+	clearMetrics(map[string]string{"clusterCIDR": clusterCIDRStr})
+
+	for _, occupied := range []string{"10.1.0.0/28", "10.1.0.32/28", "10.1.0.80/28", "10.1.0.128/25"} {
+		_, cidr, _ := net.ParseCIDR(occupied)
+		assert.NoError(t, a.Occupy(cidr))
+	}
+	expectGauge := func(name string, gauge metrics.GaugeMetric, expected float64) {
+		value, err := testutil.GetGaugeMetricValue(gauge)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, value, name)
+	}
+	expectGauge("allocated", cidrSetAllocatedCIDRs.WithLabelValues(clusterCIDRStr), 11)
+	expectGauge("free", cidrSetFreeCIDRs.WithLabelValues(clusterCIDRStr), 5)
+	expectGauge("largest free block", cidrSetLargestFreeBlock.WithLabelValues(clusterCIDRStr), 2)
+
+	assert.Equal(t, Dump{
+		ClusterCIDR:      clusterCIDRStr,
+		NodeMaskSize:     28,
+		MaxCIDRs:         16,
+		AllocatedCIDRs:   11,
+		LargestFreeBlock: 2,
+		Allocated:        []string{"10.1.0.0/28", "10.1.0.32/28", "10.1.0.80/28", "10.1.0.128/25"},
+		Free:             []string{"10.1.0.16/28", "10.1.0.48/28", "10.1.0.64/28", "10.1.0.96/27"},
+	}, a.Dump())
+
+	// 5 free cidrs but none of the free blocks has 4 contiguous cidrs
+	_, err = a.AllocateNextWithNodeMaskSize(26)
+	assert.Equal(t, ErrCIDRRangeNoCIDRsRemaining, err)
+	fragmented, err := testutil.GetCounterMetricValue(cidrSetAllocationFailures.WithLabelValues(clusterCIDRStr, allocationFailureReasonFragmented))
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1), fragmented)
+
+	// 5 free cidrs are not enough for 8
+	_, err = a.AllocateNextWithNodeMaskSize(25)
+	assert.Equal(t, ErrCIDRRangeNoCIDRsRemaining, err)
+	exhausted, err := testutil.GetCounterMetricValue(cidrSetAllocationFailures.WithLabelValues(clusterCIDRStr, allocationFailureReasonExhausted))
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1), exhausted)
+}
+
+func TestLargestFreeBlock(t *testing.T) {
+	_, clusterCIDR, _ := net.ParseCIDR(cidr)
+	// We have 256 free cidrs in 4 words of the bitmap
+	a, err := NewCIDRSet(clusterCIDR, 24)
+	if err != nil {
+		t.Fatalf("unexpected error creating CidrSet: %v", err)
+	}
+	assert.Equal(t, 256, a.largestFreeBlock())
+
+	_, first, _ := net.ParseCIDR("10.0.0.0/24")
+	assert.NoError(t, a.Occupy(first))
+	assert.Equal(t, 255, a.largestFreeBlock())
+
+	_, middle, _ := net.ParseCIDR("10.0.100.0/24")
+	assert.NoError(t, a.Occupy(middle))
+	assert.Equal(t, 155, a.largestFreeBlock())
+
+	_, firstHalf, _ := net.ParseCIDR("10.0.0.0/17")
+	assert.NoError(t, a.Occupy(firstHalf))
+	assert.Equal(t, 128, a.largestFreeBlock())
+
+	assert.NoError(t, a.Occupy(clusterCIDR))
+	assert.Equal(t, 0, a.largestFreeBlock())
+}
+
 func TestCidrSetMetricsHistogram(t *testing.T) {
 	_, clusterCIDR, _ := net.ParseCIDR(cidr)
 	// We have 256 free cidrs
@@ -1306,6 +1380,9 @@ func clearMetrics(labels map[string]string) {
 	cidrSetReleases.Delete(labels)
 	cidrSetUsage.Delete(labels)
 	cidrSetAllocationTriesPerRequest.Delete(labels)
+	cidrSetAllocatedCIDRs.Delete(labels)
+	cidrSetFreeCIDRs.Delete(labels)
+	cidrSetLargestFreeBlock.Delete(labels)
 }
 
 type testMetrics struct {
@@ -25,6 +25,14 @@ import (
 
 const nodeIpamSubsystem = "node_ipam_controller"
 
+const (
+	// allocationFailureReasonExhausted is the reason of the allocation failures when there are not enough free CIDRs
+	allocationFailureReasonExhausted = "exhausted"
+	// allocationFailureReasonFragmented is the reason of the allocation failures when there are enough free CIDRs
+	// but none of the contiguous free blocks is large enough
+	allocationFailureReasonFragmented = "fragmented"
+)
+
 var (
 	cidrSetAllocations = metrics.NewCounterVec(
 		&metrics.CounterOpts{
@@ -53,6 +61,42 @@ var (
 		},
 		[]string{"clusterCIDR"},
 	)
+	cidrSetAllocatedCIDRs = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Subsystem:      nodeIpamSubsystem,
+			Name:           "cidrset_allocated_cidrs",
+			Help:           "Gauge measuring number of allocated node CIDRs.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"clusterCIDR"},
+	)
+	cidrSetFreeCIDRs = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Subsystem:      nodeIpamSubsystem,
+			Name:           "cidrset_free_cidrs",
+			Help:           "Gauge measuring number of free node CIDRs.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"clusterCIDR"},
+	)
+	cidrSetLargestFreeBlock = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Subsystem:      nodeIpamSubsystem,
+			Name:           "cidrset_largest_free_block_cidrs",
+			Help:           "Gauge measuring number of node CIDRs in the largest contiguous free block.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"clusterCIDR"},
+	)
+	cidrSetAllocationFailures = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      nodeIpamSubsystem,
+			Name:           "cidrset_allocation_failures_total",
+			Help:           "Counter measuring total number of failed CIDR allocations by reason.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"clusterCIDR", "reason"},
+	)
 	cidrSetAllocationTriesPerRequest = metrics.NewHistogramVec(
 		&metrics.HistogramOpts{
 			Subsystem:      nodeIpamSubsystem,
@@ -73,6 +117,10 @@ func registerCidrsetMetrics() {
 		legacyregistry.MustRegister(cidrSetAllocations)
 		legacyregistry.MustRegister(cidrSetReleases)
 		legacyregistry.MustRegister(cidrSetUsage)
+		legacyregistry.MustRegister(cidrSetAllocatedCIDRs)
+		legacyregistry.MustRegister(cidrSetFreeCIDRs)
+		legacyregistry.MustRegister(cidrSetLargestFreeBlock)
+		legacyregistry.MustRegister(cidrSetAllocationFailures)
 		legacyregistry.MustRegister(cidrSetAllocationTriesPerRequest)
 	})
 }
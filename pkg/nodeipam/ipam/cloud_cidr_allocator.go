@@ -92,6 +92,13 @@ func NewCloudCIDRAllocator(
 		klog.Fatalf("kubeClient is nil when starting NodeController")
 	}
 
+	if len(allocatorParams.NodePools) > 0 {
+		return nil, fmt.Errorf("cloudCIDRAllocator does not support node pool cidrs")
+	}
+	if len(allocatorParams.AdditionalClusterCIDRs) > 0 {
+		return nil, fmt.Errorf("cloudCIDRAllocator does not support additional cluster cidrs")
+	}
+
 	eventBroadcaster := record.NewBroadcaster()
 	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: "cidrAllocator"})
 	eventBroadcaster.StartStructuredLogging(0)
@@ -392,6 +399,7 @@ func (ca *cloudCIDRAllocator) AllocateOrOccupyCIDR(node *v1.Node) error {
 		if err != nil {
 			ca.removeNodeFromProcessing(node.Name)
 			nodeutil.RecordNodeStatusChange(ca.recorder, node, "CIDRNotAvailable")
+			nodeutil.RecordNodeEvent(ca.recorder, node, v1.EventTypeWarning, cidrAllocationFailedReason, err.Error())
 			return fmt.Errorf("failed to allocate cidr from cluster cidr at idx:%v: %w", i, err)
 		}
 		allocated.allocatedCIDRs[i] = podCIDR
@@ -505,3 +513,12 @@ func (ca *cloudCIDRAllocator) ReleaseCIDR(node *v1.Node) error {
 
 	return nil
 }
+
+// DumpAllocations returns the allocations of the cluster cidrs.
+func (ca *cloudCIDRAllocator) DumpAllocations() []CIDRSetAllocations {
+	allocations := make([]CIDRSetAllocations, 0, len(ca.cidrSets))
+	for _, cidrSet := range ca.cidrSets {
+		allocations = append(allocations, CIDRSetAllocations{Dump: cidrSet.Dump()})
+	}
+	return allocations
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ipam
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	netutils "k8s.io/utils/net"
+)
+
+// AdditionalClusterCIDRsKey is the key of the comma separated additional cluster cidrs in the ConfigMap.
+const AdditionalClusterCIDRsKey = "additionalClusterCIDRs"
+
+// parseAdditionalClusterCIDRs returns the additional cluster cidrs in the ConfigMap.
+func parseAdditionalClusterCIDRs(configMap *v1.ConfigMap) ([]*net.IPNet, error) {
+	value := strings.TrimSpace(configMap.Data[AdditionalClusterCIDRsKey])
+	if value == "" {
+		return nil, nil
+	}
+
+	var cidrs []*net.IPNet
+	for _, cidrStr := range strings.Split(value, ",") {
+		_, cidr, err := netutils.ParseCIDRSloppy(strings.TrimSpace(cidrStr))
+		if err != nil {
+			return nil, fmt.Errorf("invalid cidr %q in ConfigMap %s/%s: %w", cidrStr, configMap.Namespace, configMap.Name, err)
+		}
+		cidrs = append(cidrs, cidr)
+	}
+	return cidrs, nil
+}
+
+// GetAdditionalClusterCIDRs reads the additional cluster cidrs from the ConfigMap.
+// It returns no cidrs if the ConfigMap does not exist.
+func GetAdditionalClusterCIDRs(ctx context.Context, kubeClient clientset.Interface, namespace, name string) ([]*net.IPNet, error) {
+	configMap, err := kubeClient.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			klog.V(2).Infof("GetAdditionalClusterCIDRs: ConfigMap %s/%s not found", namespace, name)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get ConfigMap %s/%s: %w", namespace, name, err)
+	}
+	return parseAdditionalClusterCIDRs(configMap)
+}
+
+// RunClusterCIDRConfigMapWatcher watches the ConfigMap and adds the additional cluster cidrs
+// in it to the allocator until the context is done. The removed cidrs stay in the allocator
+// until the controller restarts.
+func RunClusterCIDRConfigMapWatcher(ctx context.Context, kubeClient clientset.Interface, namespace, name string, allocator CIDRAllocator, resync time.Duration) error {
+	expander, ok := allocator.(ClusterCIDRExpander)
+	if !ok {
+		return fmt.Errorf("CIDR allocator %T does not support additional cluster cidrs", allocator)
+	}
+
+	factory := informers.NewSharedInformerFactoryWithOptions(kubeClient, resync,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+		}),
+	)
+	onConfigMap := func(obj interface{}) {
+		configMap, ok := obj.(*v1.ConfigMap)
+		if !ok {
+			return
+		}
+		cidrs, err := parseAdditionalClusterCIDRs(configMap)
+		if err != nil {
+			klog.Errorf("RunClusterCIDRConfigMapWatcher: %v", err)
+			return
+		}
+		if err := expander.AddClusterCIDRs(cidrs); err != nil {
+			klog.Errorf("RunClusterCIDRConfigMapWatcher: failed to add cluster cidrs from ConfigMap %s/%s: %v", namespace, name, err)
+		}
+	}
+	_, err := factory.Core().V1().ConfigMaps().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: onConfigMap,
+		UpdateFunc: func(_, newObj interface{}) {
+			onConfigMap(newObj)
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch ConfigMap %s/%s: %w", namespace, name, err)
+	}
+
+	factory.Start(ctx.Done())
+	return nil
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ipam
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGetAdditionalClusterCIDRs(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset()
+	cidrs, err := GetAdditionalClusterCIDRs(context.TODO(), kubeClient, "kube-system", "cluster-cidrs")
+	assert.NoError(t, err)
+	assert.Empty(t, cidrs)
+
+	kubeClient = fake.NewSimpleClientset(&v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "cluster-cidrs"},
+		Data:       map[string]string{AdditionalClusterCIDRsKey: "10.20.0.0/16, ace:cab:deca::/48"},
+	})
+	cidrs, err = GetAdditionalClusterCIDRs(context.TODO(), kubeClient, "kube-system", "cluster-cidrs")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.20.0.0/16", "ace:cab:deca::/48"}, cidrsAsString(cidrs))
+
+	kubeClient = fake.NewSimpleClientset(&v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "cluster-cidrs"},
+		Data:       map[string]string{AdditionalClusterCIDRsKey: "10.20.0.0/16,invalid"},
+	})
+	_, err = GetAdditionalClusterCIDRs(context.TODO(), kubeClient, "kube-system", "cluster-cidrs")
+	assert.Error(t, err)
+}
+
+func TestRunClusterCIDRConfigMapWatcher(t *testing.T) {
+	_, clusterCIDR, _ := net.ParseCIDR("10.10.0.0/24")
+	ra := newTestRangeAllocator(t, CIDRAllocatorParams{
+		ClusterCIDRs:      []*net.IPNet{clusterCIDR},
+		NodeCIDRMaskSizes: []int{24},
+	})
+
+	configMap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "cluster-cidrs"},
+		Data:       map[string]string{AdditionalClusterCIDRsKey: "10.20.0.0/16"},
+	}
+	kubeClient := fake.NewSimpleClientset(configMap)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, RunClusterCIDRConfigMapWatcher(ctx, kubeClient, "kube-system", "cluster-cidrs", ra, 0))
+
+	additionalCIDRs := func() []string {
+		ra.additionalCIDRsLock.RLock()
+		defer ra.additionalCIDRsLock.RUnlock()
+		var cidrs []string
+		for _, additional := range ra.additionalCIDRs {
+			cidrs = append(cidrs, additional.clusterCIDR.String())
+		}
+		return cidrs
+	}
+	waitForCIDRs := func(expected []string) {
+		err := wait.PollUntilContextTimeout(ctx, 10*time.Millisecond, wait.ForeverTestTimeout, true, func(context.Context) (bool, error) {
+			return assert.ObjectsAreEqual(expected, additionalCIDRs()), nil
+		})
+		assert.NoError(t, err, "expected %v, got %v", expected, additionalCIDRs())
+	}
+	waitForCIDRs([]string{"10.20.0.0/16"})
+
+	configMap = configMap.DeepCopy()
+	configMap.Data[AdditionalClusterCIDRsKey] = "10.20.0.0/16,10.30.0.0/16"
+	_, err := kubeClient.CoreV1().ConfigMaps("kube-system").Update(ctx, configMap, metav1.UpdateOptions{})
+	assert.NoError(t, err)
+	waitForCIDRs([]string{"10.20.0.0/16", "10.30.0.0/16"})
+}
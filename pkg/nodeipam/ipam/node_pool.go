@@ -0,0 +1,158 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ipam
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	netutils "k8s.io/utils/net"
+	"sigs.k8s.io/yaml"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+	nodeipamconfig "sigs.k8s.io/cloud-provider-azure/pkg/nodeipam/config"
+)
+
+// NodePool is a group of nodes selected by labels that get their pod CIDRs
+// from their own CIDRs instead of the cluster CIDRs.
+type NodePool struct {
+	// Name of the node pool
+	Name string
+	// NodeSelector selects the nodes of the node pool
+	NodeSelector labels.Selector
+	// ClusterCIDRs is list of the node pool cidrs, in the same ip family order as the cluster cidrs
+	ClusterCIDRs []*net.IPNet
+	// NodeCIDRMaskSizes is list of node cidr mask sizes
+	NodeCIDRMaskSizes []int
+}
+
+// LoadNodePoolCIDRsConfig reads the node pool CIDRs configuration file.
+func LoadNodePoolCIDRsConfig(path string) (*nodeipamconfig.NodePoolCIDRsConfiguration, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read node pool cidrs config file %s: %w", path, err)
+	}
+
+	cfg := &nodeipamconfig.NodePoolCIDRsConfiguration{}
+	if err := yaml.Unmarshal(content, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse node pool cidrs config file %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// NewNodePools validates the node pool CIDRs configuration against the cluster cidrs
+// and returns the node pools in the order of the configuration.
+func NewNodePools(cfg *nodeipamconfig.NodePoolCIDRsConfiguration, clusterCIDRs []*net.IPNet) ([]NodePool, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	names := sets.NewString()
+	nodePools := make([]NodePool, 0, len(cfg.NodePools))
+	for _, poolCfg := range cfg.NodePools {
+		name := strings.TrimSpace(poolCfg.Name)
+		if name == "" {
+			return nil, fmt.Errorf("node pool name must not be empty")
+		}
+		if names.Has(name) {
+			return nil, fmt.Errorf("node pool %s is configured more than once", name)
+		}
+		names.Insert(name)
+
+		if poolCfg.NodeSelector == nil {
+			return nil, fmt.Errorf("node pool %s must have a nodeSelector", name)
+		}
+		selector, err := metav1.LabelSelectorAsSelector(poolCfg.NodeSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid nodeSelector of node pool %s: %w", name, err)
+		}
+
+		pool := NodePool{
+			Name:              name,
+			NodeSelector:      selector,
+			ClusterCIDRs:      make([]*net.IPNet, len(clusterCIDRs)),
+			NodeCIDRMaskSizes: make([]int, len(clusterCIDRs)),
+		}
+		hasIPv4, hasIPv6 := false, false
+		for idx, clusterCIDR := range clusterCIDRs {
+			cidr, maskSize, family := poolCfg.IPv4CIDR, int(poolCfg.NodeCIDRMaskSizeIPv4), "IPv4"
+			if maskSize == 0 {
+				maskSize = consts.DefaultNodeMaskCIDRIPv4
+			}
+			if netutils.IsIPv6CIDR(clusterCIDR) {
+				cidr, maskSize, family = poolCfg.IPv6CIDR, int(poolCfg.NodeCIDRMaskSizeIPv6), "IPv6"
+				if maskSize == 0 {
+					maskSize = consts.DefaultNodeMaskCIDRIPv6
+				}
+				hasIPv6 = true
+			} else {
+				hasIPv4 = true
+			}
+
+			if cidr == "" {
+				return nil, fmt.Errorf("node pool %s must have an %s cidr because the cluster cidrs have one", name, family)
+			}
+			_, poolCIDR, err := netutils.ParseCIDRSloppy(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s cidr %s of node pool %s: %w", family, cidr, name, err)
+			}
+			if netutils.IsIPv6CIDR(poolCIDR) != netutils.IsIPv6CIDR(clusterCIDR) {
+				return nil, fmt.Errorf("%s cidr %s of node pool %s is not an %s cidr", family, cidr, name, family)
+			}
+			// the pod cidrs out of the cluster cidrs are not covered by the routes and the masquerade rules
+			if !cidrContains(clusterCIDR, poolCIDR) {
+				return nil, fmt.Errorf("%s cidr %s of node pool %s is not in cluster cidr %s", family, cidr, name, clusterCIDR.String())
+			}
+			if ones, _ := poolCIDR.Mask.Size(); ones > maskSize {
+				return nil, fmt.Errorf("node cidr mask size %d of node pool %s must not be less than the mask size of %s", maskSize, name, poolCIDR.String())
+			}
+			pool.ClusterCIDRs[idx] = poolCIDR
+			pool.NodeCIDRMaskSizes[idx] = maskSize
+		}
+		if (poolCfg.IPv4CIDR != "" && !hasIPv4) || (poolCfg.IPv6CIDR != "" && !hasIPv6) {
+			return nil, fmt.Errorf("node pool %s has a cidr of an ip family that is not in the cluster cidrs", name)
+		}
+
+		for _, other := range nodePools {
+			for idx := range pool.ClusterCIDRs {
+				if cidrsOverlap(pool.ClusterCIDRs[idx], other.ClusterCIDRs[idx]) {
+					return nil, fmt.Errorf("cidr %s of node pool %s overlaps with cidr %s of node pool %s",
+						pool.ClusterCIDRs[idx].String(), name, other.ClusterCIDRs[idx].String(), other.Name)
+				}
+			}
+		}
+		nodePools = append(nodePools, pool)
+	}
+	return nodePools, nil
+}
+
+// cidrContains returns true if the outer cidr contains the whole inner cidr.
+func cidrContains(outer, inner *net.IPNet) bool {
+	outerOnes, _ := outer.Mask.Size()
+	innerOnes, _ := inner.Mask.Size()
+	return outerOnes <= innerOnes && outer.Contains(inner.IP)
+}
+
+// cidrsOverlap returns true if either of the cidrs contains the other one.
+func cidrsOverlap(a, b *net.IPNet) bool {
+	return a.Contains(b.IP.Mask(a.Mask)) || b.Contains(a.IP.Mask(b.Mask))
+}
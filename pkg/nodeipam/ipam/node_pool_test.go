@@ -0,0 +1,163 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ipam
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	nodeipamconfig "sigs.k8s.io/cloud-provider-azure/pkg/nodeipam/config"
+)
+
+func TestLoadNodePoolCIDRsConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "node-pools.yaml")
+	content := `nodePools:
+- name: gpu
+  nodeSelector:
+    matchLabels:
+      pool: gpu
+  ipv4CIDR: 10.20.0.0/16
+  nodeCIDRMaskSizeIPv4: 26
+`
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0600))
+
+	cfg, err := LoadNodePoolCIDRsConfig(path)
+	assert.NoError(t, err)
+	assert.Equal(t, &nodeipamconfig.NodePoolCIDRsConfiguration{
+		NodePools: []nodeipamconfig.NodePoolCIDRConfiguration{
+			{
+				Name:                 "gpu",
+				NodeSelector:         &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "gpu"}},
+				IPv4CIDR:             "10.20.0.0/16",
+				NodeCIDRMaskSizeIPv4: 26,
+			},
+		},
+	}, cfg)
+
+	_, err = LoadNodePoolCIDRsConfig(filepath.Join(t.TempDir(), "not-found.yaml"))
+	assert.Error(t, err)
+}
+
+func TestNewNodePools(t *testing.T) {
+	_, clusterCIDRv4, _ := net.ParseCIDR("10.0.0.0/8")
+	_, clusterCIDRv6, _ := net.ParseCIDR("ace:cab:deca::/48")
+	gpuSelector := &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "gpu"}}
+	cpuSelector := &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "cpu"}}
+
+	for _, tc := range []struct {
+		desc          string
+		clusterCIDRs  []*net.IPNet
+		nodePools     []nodeipamconfig.NodePoolCIDRConfiguration
+		expectedCIDRs [][]string
+		expectedMasks [][]int
+		expectedErr   bool
+	}{
+		{
+			desc:         "dual-stack node pools with default mask sizes",
+			clusterCIDRs: []*net.IPNet{clusterCIDRv6, clusterCIDRv4},
+			nodePools: []nodeipamconfig.NodePoolCIDRConfiguration{
+				{Name: "gpu", NodeSelector: gpuSelector, IPv4CIDR: "10.20.0.0/16", IPv6CIDR: "ace:cab:deca:1::/64", NodeCIDRMaskSizeIPv6: 80},
+				{Name: "cpu", NodeSelector: cpuSelector, IPv4CIDR: "10.30.0.0/16", IPv6CIDR: "ace:cab:deca:2::/64"},
+			},
+			expectedCIDRs: [][]string{{"ace:cab:deca:1::/64", "10.20.0.0/16"}, {"ace:cab:deca:2::/64", "10.30.0.0/16"}},
+			expectedMasks: [][]int{{80, 24}, {64, 24}},
+		},
+		{
+			desc:         "empty name",
+			clusterCIDRs: []*net.IPNet{clusterCIDRv4},
+			nodePools:    []nodeipamconfig.NodePoolCIDRConfiguration{{NodeSelector: gpuSelector, IPv4CIDR: "10.20.0.0/16"}},
+			expectedErr:  true,
+		},
+		{
+			desc:         "duplicated names",
+			clusterCIDRs: []*net.IPNet{clusterCIDRv4},
+			nodePools: []nodeipamconfig.NodePoolCIDRConfiguration{
+				{Name: "gpu", NodeSelector: gpuSelector, IPv4CIDR: "10.20.0.0/16"},
+				{Name: "gpu", NodeSelector: cpuSelector, IPv4CIDR: "10.30.0.0/16"},
+			},
+			expectedErr: true,
+		},
+		{
+			desc:         "missing node selector",
+			clusterCIDRs: []*net.IPNet{clusterCIDRv4},
+			nodePools:    []nodeipamconfig.NodePoolCIDRConfiguration{{Name: "gpu", IPv4CIDR: "10.20.0.0/16"}},
+			expectedErr:  true,
+		},
+		{
+			desc:         "missing cidr of the cluster ip family",
+			clusterCIDRs: []*net.IPNet{clusterCIDRv4, clusterCIDRv6},
+			nodePools:    []nodeipamconfig.NodePoolCIDRConfiguration{{Name: "gpu", NodeSelector: gpuSelector, IPv4CIDR: "10.20.0.0/16"}},
+			expectedErr:  true,
+		},
+		{
+			desc:         "cidr of an ip family out of the cluster",
+			clusterCIDRs: []*net.IPNet{clusterCIDRv4},
+			nodePools:    []nodeipamconfig.NodePoolCIDRConfiguration{{Name: "gpu", NodeSelector: gpuSelector, IPv4CIDR: "10.20.0.0/16", IPv6CIDR: "ace:cab:deca:1::/64"}},
+			expectedErr:  true,
+		},
+		{
+			desc:         "node cidr mask size less than the node pool cidr mask size",
+			clusterCIDRs: []*net.IPNet{clusterCIDRv4},
+			nodePools:    []nodeipamconfig.NodePoolCIDRConfiguration{{Name: "gpu", NodeSelector: gpuSelector, IPv4CIDR: "10.20.0.0/26", NodeCIDRMaskSizeIPv4: 24}},
+			expectedErr:  true,
+		},
+		{
+			desc:         "node pool cidr out of the cluster cidr",
+			clusterCIDRs: []*net.IPNet{clusterCIDRv4},
+			nodePools:    []nodeipamconfig.NodePoolCIDRConfiguration{{Name: "gpu", NodeSelector: gpuSelector, IPv4CIDR: "192.168.0.0/16"}},
+			expectedErr:  true,
+		},
+		{
+			desc:         "node pool cidr larger than the cluster cidr",
+			clusterCIDRs: []*net.IPNet{clusterCIDRv6},
+			nodePools:    []nodeipamconfig.NodePoolCIDRConfiguration{{Name: "gpu", NodeSelector: gpuSelector, IPv6CIDR: "ace:cab::/32", NodeCIDRMaskSizeIPv6: 48}},
+			expectedErr:  true,
+		},
+		{
+			desc:         "overlapping node pools",
+			clusterCIDRs: []*net.IPNet{clusterCIDRv4},
+			nodePools: []nodeipamconfig.NodePoolCIDRConfiguration{
+				{Name: "gpu", NodeSelector: gpuSelector, IPv4CIDR: "10.20.0.0/16"},
+				{Name: "cpu", NodeSelector: cpuSelector, IPv4CIDR: "10.20.128.0/17"},
+			},
+			expectedErr: true,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			nodePools, err := NewNodePools(&nodeipamconfig.NodePoolCIDRsConfiguration{NodePools: tc.nodePools}, tc.clusterCIDRs)
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, len(tc.nodePools), len(nodePools))
+			for i, pool := range nodePools {
+				assert.Equal(t, tc.nodePools[i].Name, pool.Name)
+				assert.Equal(t, tc.expectedCIDRs[i], cidrsAsString(pool.ClusterCIDRs))
+				assert.Equal(t, tc.expectedMasks[i], pool.NodeCIDRMaskSizes)
+				assert.True(t, pool.NodeSelector.Matches(labels.Set(tc.nodePools[i].NodeSelector.MatchLabels)))
+			}
+		})
+	}
+}
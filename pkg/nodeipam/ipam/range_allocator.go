@@ -18,6 +18,7 @@ package ipam
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"strings"
@@ -25,7 +26,9 @@ import (
 
 	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/sets"
 	informers "k8s.io/client-go/informers/core/v1"
@@ -36,6 +39,7 @@ import (
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
+	netutils "k8s.io/utils/net"
 
 	"sigs.k8s.io/cloud-provider-azure/pkg/nodeipam/ipam/cidrset"
 	nodeutil "sigs.k8s.io/cloud-provider-azure/pkg/util/controller/node"
@@ -49,12 +53,39 @@ type nodeReservedCIDRs struct {
 	nodeName       string
 }
 
+// nodePoolCIDRSets tracks the cidrs of a node pool, the nodes selected by the
+// node pool get their pod cidrs from here instead of the cluster cidrs
+type nodePoolCIDRSets struct {
+	name         string
+	nodeSelector labels.Selector
+	clusterCIDRs []*net.IPNet
+	cidrSets     []*cidrset.CidrSet
+}
+
+// additionalClusterCIDR is a cluster cidr added at runtime, the allocation spills
+// over into it when the cluster cidr of the same ip family is full
+type additionalClusterCIDR struct {
+	// index of the cluster cidr of the same ip family
+	idx         int
+	clusterCIDR *net.IPNet
+	cidrSet     *cidrset.CidrSet
+}
+
 type rangeAllocator struct {
 	client clientset.Interface
 	// cluster cidrs as passed in during controller creation
 	clusterCIDRs []*net.IPNet
 	// for each entry in clusterCIDRs we maintain a list of what is used and what is not
 	cidrSets []*cidrset.CidrSet
+	// node pools with their own cidrs, a node uses the first node pool it matches
+	nodePools []nodePoolCIDRSets
+	// node cidr mask sizes and service cidrs, used by the cluster cidrs added at runtime
+	nodeCIDRMaskSizes    []int
+	serviceCIDR          *net.IPNet
+	secondaryServiceCIDR *net.IPNet
+	// cluster cidrs added at runtime, in the order they are added
+	additionalCIDRs     []additionalClusterCIDR
+	additionalCIDRsLock sync.RWMutex
 	// nodeLister is able to list/get nodes and is populated by the shared informer passed to controller
 	nodeLister corelisters.NodeLister
 	// nodesSynced returns true if the node shared informer has been synced at least once.
@@ -95,10 +126,37 @@ func NewCIDRRangeAllocator(client clientset.Interface, nodeInformer informers.No
 		cidrSets[idx] = cidrSet
 	}
 
+	nodePools := make([]nodePoolCIDRSets, len(allocatorParams.NodePools))
+	for i, pool := range allocatorParams.NodePools {
+		if len(pool.ClusterCIDRs) != len(allocatorParams.ClusterCIDRs) || len(pool.NodeCIDRMaskSizes) != len(pool.ClusterCIDRs) {
+			return nil, fmt.Errorf("node pool %s must have one cidr and node cidr mask size for each cluster cidr", pool.Name)
+		}
+		poolCIDRSets := make([]*cidrset.CidrSet, len(pool.ClusterCIDRs))
+		for idx, cidr := range pool.ClusterCIDRs {
+			cidrSet, err := cidrset.NewCIDRSet(cidr, pool.NodeCIDRMaskSizes[idx])
+			if err != nil {
+				return nil, fmt.Errorf("failed to create cidr set for node pool %s: %w", pool.Name, err)
+			}
+			poolCIDRSets[idx] = cidrSet
+			// the cidrs of the node pool are not assignable to the nodes out of the node pool
+			filterOutNodePoolRange(allocatorParams.ClusterCIDRs, cidrSets, pool.Name, cidr)
+		}
+		nodePools[i] = nodePoolCIDRSets{
+			name:         pool.Name,
+			nodeSelector: pool.NodeSelector,
+			clusterCIDRs: pool.ClusterCIDRs,
+			cidrSets:     poolCIDRSets,
+		}
+	}
+
 	ra := &rangeAllocator{
 		client:                client,
 		clusterCIDRs:          allocatorParams.ClusterCIDRs,
 		cidrSets:              cidrSets,
+		nodePools:             nodePools,
+		nodeCIDRMaskSizes:     allocatorParams.NodeCIDRMaskSizes,
+		serviceCIDR:           allocatorParams.ServiceCIDR,
+		secondaryServiceCIDR:  allocatorParams.SecondaryServiceCIDR,
 		nodeLister:            nodeInformer.Lister(),
 		nodesSynced:           nodeInformer.Informer().HasSynced,
 		nodeCIDRUpdateChannel: make(chan nodeReservedCIDRs, cidrUpdateQueueSize),
@@ -108,16 +166,29 @@ func NewCIDRRangeAllocator(client clientset.Interface, nodeInformer informers.No
 
 	if allocatorParams.ServiceCIDR != nil {
 		filterOutServiceRange(ra.clusterCIDRs, ra.cidrSets, allocatorParams.ServiceCIDR)
+		for _, pool := range ra.nodePools {
+			filterOutServiceRange(pool.clusterCIDRs, pool.cidrSets, allocatorParams.ServiceCIDR)
+		}
 	} else {
 		klog.V(0).Info("No Service CIDR provided. Skipping filtering out service addresses.")
 	}
 
 	if allocatorParams.SecondaryServiceCIDR != nil {
 		filterOutServiceRange(ra.clusterCIDRs, ra.cidrSets, allocatorParams.SecondaryServiceCIDR)
+		for _, pool := range ra.nodePools {
+			filterOutServiceRange(pool.clusterCIDRs, pool.cidrSets, allocatorParams.SecondaryServiceCIDR)
+		}
 	} else {
 		klog.V(0).Info("No Secondary Service CIDR provided. Skipping filtering out secondary service addresses.")
 	}
 
+	// the additional cluster cidrs must be known before occupying the pod cidrs of the existing nodes
+	for _, cidr := range allocatorParams.AdditionalClusterCIDRs {
+		if _, err := ra.addClusterCIDR(cidr); err != nil {
+			klog.Errorf("Skipping additional cluster cidr %v: %v", cidr, err)
+		}
+	}
+
 	if nodeList != nil {
 		for i, node := range nodeList.Items {
 			if len(node.Spec.PodCIDRs) == 0 {
@@ -241,7 +312,7 @@ func (r *rangeAllocator) occupyCIDRs(node *v1.Node) error {
 			return fmt.Errorf("node:%s has an allocated cidr: %v at index:%v that does not exist in cluster cidrs configuration", node.Name, cidr, idx)
 		}
 
-		if err := r.cidrSets[idx].Occupy(podCIDR); err != nil {
+		if err := r.getCIDRSet(idx, podCIDR).Occupy(podCIDR); err != nil {
 			return fmt.Errorf("failed to mark cidr[%v] at idx [%v] as occupied for node: %v: %w", podCIDR, idx, node.Name, err)
 		}
 	}
@@ -265,10 +336,11 @@ func (r *rangeAllocator) AllocateOrOccupyCIDR(node *v1.Node) error {
 	}
 
 	// allocate pod cidrs
-	allocatedCIDRs, err := r.allocatePodCIDRs()
+	allocatedCIDRs, err := r.allocatePodCIDRs(node)
 	if err != nil {
 		r.removeNodeFromProcessing(node.Name)
 		nodeutil.RecordNodeStatusChange(r.recorder, node, "CIDRNotAvailable")
+		nodeutil.RecordNodeEvent(r.recorder, node, v1.EventTypeWarning, cidrAllocationFailedReason, err.Error())
 		return fmt.Errorf("failed to allocate cidr for node %s: %w", node.Name, err)
 	}
 	allocated := nodeReservedCIDRs{
@@ -302,7 +374,7 @@ func (r *rangeAllocator) ReleaseCIDR(node *v1.Node) error {
 		}
 
 		klog.V(4).Infof("release CIDR %s for node:%v", cidr, node.Name)
-		if err = r.cidrSets[idx].Release(podCIDR); err != nil {
+		if err = r.getCIDRSet(idx, podCIDR).Release(podCIDR); err != nil {
 			return fmt.Errorf("error when releasing CIDR %v: %w", cidr, err)
 		}
 	}
@@ -330,13 +402,180 @@ func filterOutServiceRange(clusterCIDRs []*net.IPNet, cidrSets []*cidrset.CidrSe
 	}
 }
 
-func (r *rangeAllocator) allocatePodCIDRs() ([]*net.IPNet, error) {
-	allocatedCIDRs := make([]*net.IPNet, len(r.cidrSets))
-	for idx := range r.cidrSets {
-		podCIDR, err := r.cidrSets[idx].AllocateNext()
+// Marks all CIDRs with subNetMaskSize that belongs to the node pool CIDR as used across all cidrs
+// so that they are only assignable to the nodes of the node pool.
+func filterOutNodePoolRange(clusterCIDRs []*net.IPNet, cidrSets []*cidrset.CidrSet, nodePoolName string, nodePoolCIDR *net.IPNet) {
+	for idx, cidr := range clusterCIDRs {
+		if !cidrsOverlap(cidr, nodePoolCIDR) {
+			continue
+		}
+
+		if err := cidrSets[idx].Occupy(nodePoolCIDR); err != nil {
+			klog.Errorf("Error filtering out cidr %v of node pool %s out cluster cidr:%v (index:%v): %v", nodePoolCIDR, nodePoolName, cidr, idx, err)
+		}
+	}
+}
+
+// getCIDRSets returns the cidrSets of the first node pool selecting the node,
+// or the cidrSets of the cluster cidrs if no node pool selects it.
+func (r *rangeAllocator) getCIDRSets(node *v1.Node) (cidrSets []*cidrset.CidrSet, fromNodePool bool) {
+	if node != nil {
+		nodeLabels := labels.Set(node.Labels)
+		for _, pool := range r.nodePools {
+			if pool.nodeSelector.Matches(nodeLabels) {
+				klog.V(4).Infof("Node %s is selected by node pool %s", node.Name, pool.name)
+				return pool.cidrSets, true
+			}
+		}
+	}
+	return r.cidrSets, false
+}
+
+// getCIDRSet returns the cidrSet at idx that the pod cidr is allocated from. The node pools
+// are found by the pod cidr rather than the node labels, so that the pod cidrs are
+// tracked correctly even if the labels of the node have changed since the allocation.
+func (r *rangeAllocator) getCIDRSet(idx int, podCIDR *net.IPNet) *cidrset.CidrSet {
+	for _, pool := range r.nodePools {
+		if idx < len(pool.clusterCIDRs) && pool.clusterCIDRs[idx].Contains(podCIDR.IP) {
+			return pool.cidrSets[idx]
+		}
+	}
+
+	r.additionalCIDRsLock.RLock()
+	defer r.additionalCIDRsLock.RUnlock()
+	for _, additional := range r.additionalCIDRs {
+		if additional.idx == idx && additional.clusterCIDR.Contains(podCIDR.IP) {
+			return additional.cidrSet
+		}
+	}
+	return r.cidrSets[idx]
+}
+
+// allocateFromAdditionalCIDRs allocates the next free cidr from the cluster cidrs added at runtime
+// of the same ip family as the cluster cidr at idx. It returns allocErr if there is none.
+func (r *rangeAllocator) allocateFromAdditionalCIDRs(idx int, allocErr error) (*net.IPNet, error) {
+	r.additionalCIDRsLock.RLock()
+	defer r.additionalCIDRsLock.RUnlock()
+
+	for _, additional := range r.additionalCIDRs {
+		if additional.idx != idx {
+			continue
+		}
+		podCIDR, err := additional.cidrSet.AllocateNext()
+		if err == nil {
+			klog.V(4).Infof("Cluster cidr %v is full, allocated %v from cluster cidr %v", r.clusterCIDRs[idx], podCIDR, additional.clusterCIDR)
+			return podCIDR, nil
+		}
+		allocErr = err
+	}
+	return nil, allocErr
+}
+
+// AddClusterCIDRs adds the cluster cidrs at runtime. The allocation spills over into them in order
+// when the cluster cidr of the same ip family is full. The cidrs that are already added are ignored.
+func (r *rangeAllocator) AddClusterCIDRs(cidrs []*net.IPNet) error {
+	var errs []error
+	for _, cidr := range cidrs {
+		cidrSet, err := r.addClusterCIDR(cidr)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if cidrSet == nil {
+			continue
+		}
+
+		// occupy the pod cidrs of the existing nodes in the new cluster cidr, e.g. the cidr was added before the restart
+		nodes, err := r.nodeLister.List(labels.Everything())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to list nodes to occupy the pod cidrs in cluster cidr %v: %w", cidr, err))
+			continue
+		}
+		for _, node := range nodes {
+			for _, podCIDRStr := range node.Spec.PodCIDRs {
+				_, podCIDR, err := net.ParseCIDR(podCIDRStr)
+				if err != nil || !cidr.Contains(podCIDR.IP) {
+					continue
+				}
+				if err := cidrSet.Occupy(podCIDR); err != nil {
+					errs = append(errs, fmt.Errorf("failed to mark cidr %v of node %s as occupied: %w", podCIDR, node.Name, err))
+				}
+			}
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// addClusterCIDR adds a cluster cidr and returns its cidrSet, or nil if the cidr is already added.
+func (r *rangeAllocator) addClusterCIDR(cidr *net.IPNet) (*cidrset.CidrSet, error) {
+	r.additionalCIDRsLock.Lock()
+	defer r.additionalCIDRsLock.Unlock()
+
+	idx := -1
+	for i, clusterCIDR := range r.clusterCIDRs {
+		if netutils.IsIPv6CIDR(clusterCIDR) == netutils.IsIPv6CIDR(cidr) {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return nil, fmt.Errorf("cluster cidr %v does not match the ip family of any cluster cidr", cidr)
+	}
+
+	for _, additional := range r.additionalCIDRs {
+		if additional.clusterCIDR.String() == cidr.String() {
+			return nil, nil
+		}
+		if cidrsOverlap(additional.clusterCIDR, cidr) {
+			return nil, fmt.Errorf("cluster cidr %v overlaps with the added cluster cidr %v", cidr, additional.clusterCIDR)
+		}
+	}
+	for _, clusterCIDR := range r.clusterCIDRs {
+		if cidrsOverlap(clusterCIDR, cidr) {
+			return nil, fmt.Errorf("cluster cidr %v overlaps with cluster cidr %v", cidr, clusterCIDR)
+		}
+	}
+	for _, pool := range r.nodePools {
+		for _, poolCIDR := range pool.clusterCIDRs {
+			if cidrsOverlap(poolCIDR, cidr) {
+				return nil, fmt.Errorf("cluster cidr %v overlaps with cidr %v of node pool %s", cidr, poolCIDR, pool.name)
+			}
+		}
+	}
+
+	if maskSize, _ := cidr.Mask.Size(); maskSize > r.nodeCIDRMaskSizes[idx] {
+		return nil, fmt.Errorf("mask size of cluster cidr %v must be less than or equal to the node cidr mask size %d", cidr, r.nodeCIDRMaskSizes[idx])
+	}
+	cidrSet, err := cidrset.NewCIDRSet(cidr, r.nodeCIDRMaskSizes[idx])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cidr set for cluster cidr %v: %w", cidr, err)
+	}
+	for _, serviceCIDR := range []*net.IPNet{r.serviceCIDR, r.secondaryServiceCIDR} {
+		if serviceCIDR != nil {
+			filterOutServiceRange([]*net.IPNet{cidr}, []*cidrset.CidrSet{cidrSet}, serviceCIDR)
+		}
+	}
+
+	r.additionalCIDRs = append(r.additionalCIDRs, additionalClusterCIDR{
+		idx:         idx,
+		clusterCIDR: cidr,
+		cidrSet:     cidrSet,
+	})
+	klog.Infof("Added cluster cidr %v with node cidr mask size %d", cidr, r.nodeCIDRMaskSizes[idx])
+	return cidrSet, nil
+}
+
+func (r *rangeAllocator) allocatePodCIDRs(node *v1.Node) ([]*net.IPNet, error) {
+	cidrSets, fromNodePool := r.getCIDRSets(node)
+	allocatedCIDRs := make([]*net.IPNet, len(cidrSets))
+	for idx := range cidrSets {
+		podCIDR, err := cidrSets[idx].AllocateNext()
+		if errors.Is(err, cidrset.ErrCIDRRangeNoCIDRsRemaining) && !fromNodePool {
+			podCIDR, err = r.allocateFromAdditionalCIDRs(idx, err)
+		}
 		if err != nil {
 			for i := 0; i < idx; i++ {
-				if releaseErr := r.cidrSets[idx].Release(allocatedCIDRs[i]); releaseErr != nil {
+				if releaseErr := r.getCIDRSet(i, allocatedCIDRs[i]).Release(allocatedCIDRs[i]); releaseErr != nil {
 					// continue releasing the rest
 					klog.Errorf("Error releasing allocated CIDR at index %d for node: %v", i, releaseErr)
 				}
@@ -371,9 +610,10 @@ func (r *rangeAllocator) updateCIDRsAllocation(data nodeReservedCIDRs) (dataToRe
 
 	// this happens when node patch fails, we release the CIDRs allocated and retry
 	if data.allocatedCIDRs == nil {
-		allocatedCIDRs, err := r.allocatePodCIDRs()
+		allocatedCIDRs, err := r.allocatePodCIDRs(node)
 		if err != nil {
 			nodeutil.RecordNodeStatusChange(r.recorder, node, "CIDRNotAvailable")
+			nodeutil.RecordNodeEvent(r.recorder, node, v1.EventTypeWarning, cidrAllocationFailedReason, err.Error())
 			return data, fmt.Errorf("failed to allocate cidr for node %s: %w", data.nodeName, err)
 		}
 		data.allocatedCIDRs = allocatedCIDRs
@@ -401,7 +641,7 @@ func (r *rangeAllocator) updateCIDRsAllocation(data nodeReservedCIDRs) (dataToRe
 	if len(node.Spec.PodCIDRs) != 0 {
 		klog.Errorf("Node %v already has a CIDR allocated %v. Releasing the new one.", node.Name, node.Spec.PodCIDRs)
 		for idx, cidr := range data.allocatedCIDRs {
-			if releaseErr := r.cidrSets[idx].Release(cidr); releaseErr != nil {
+			if releaseErr := r.getCIDRSet(idx, cidr).Release(cidr); releaseErr != nil {
 				klog.Errorf("Error when releasing CIDR idx:%v value: %v err:%v", idx, cidr, releaseErr)
 			}
 		}
@@ -423,7 +663,7 @@ func (r *rangeAllocator) updateCIDRsAllocation(data nodeReservedCIDRs) (dataToRe
 	if !apierrors.IsServerTimeout(err) {
 		klog.Errorf("CIDR assignment for node %v failed: %v. Releasing allocated CIDR", node.Name, err)
 		for idx, cidr := range data.allocatedCIDRs {
-			if releaseErr := r.cidrSets[idx].Release(cidr); releaseErr != nil {
+			if releaseErr := r.getCIDRSet(idx, cidr).Release(cidr); releaseErr != nil {
 				klog.Errorf("Error releasing allocated CIDR for node %v: %v", node.Name, releaseErr)
 			}
 		}
@@ -432,6 +672,27 @@ func (r *rangeAllocator) updateCIDRsAllocation(data nodeReservedCIDRs) (dataToRe
 	return data, err
 }
 
+// DumpAllocations returns the allocations of the cluster cidrs, including the ones added at runtime,
+// and of the cidrs of the node pools.
+func (r *rangeAllocator) DumpAllocations() []CIDRSetAllocations {
+	allocations := make([]CIDRSetAllocations, 0, len(r.cidrSets))
+	for _, cidrSet := range r.cidrSets {
+		allocations = append(allocations, CIDRSetAllocations{Dump: cidrSet.Dump()})
+	}
+	for _, pool := range r.nodePools {
+		for _, cidrSet := range pool.cidrSets {
+			allocations = append(allocations, CIDRSetAllocations{NodePool: pool.name, Dump: cidrSet.Dump()})
+		}
+	}
+
+	r.additionalCIDRsLock.RLock()
+	defer r.additionalCIDRsLock.RUnlock()
+	for _, additional := range r.additionalCIDRs {
+		allocations = append(allocations, CIDRSetAllocations{Dump: additional.cidrSet.Dump()})
+	}
+	return allocations
+}
+
 // converts a slice of cidrs into <c-1>,<c-2>,<c-n>
 func cidrsAsString(inCIDRs []*net.IPNet) []string {
 	outCIDRs := make([]string, len(inCIDRs))
@@ -22,13 +22,17 @@ import (
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
+
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/informers"
 	coreinformers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/kubernetes/fake"
 
+	"sigs.k8s.io/cloud-provider-azure/pkg/nodeipam/ipam/cidrset"
 	"sigs.k8s.io/cloud-provider-azure/pkg/util/controller/testutil"
 )
 
@@ -527,6 +531,76 @@ func TestAllocateOrOccupyCIDRSuccess(t *testing.T) {
 				0: "10.10.1.0/24",
 			},
 		},
+		{
+			description: "Allocate from the node pool selecting the node",
+			fakeNodeHandler: &testutil.FakeNodeHandler{
+				Existing: []*v1.Node{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:   "node0",
+							Labels: map[string]string{"pool": "gpu"},
+						},
+					},
+				},
+				Clientset: fake.NewSimpleClientset(),
+			},
+			allocatorParams: CIDRAllocatorParams{
+				ClusterCIDRs: func() []*net.IPNet {
+					_, clusterCIDR, _ := net.ParseCIDR("10.10.0.0/16")
+					return []*net.IPNet{clusterCIDR}
+				}(),
+				ServiceCIDR:          nil,
+				SecondaryServiceCIDR: nil,
+				NodeCIDRMaskSizes:    []int{24},
+				NodePools: func() []NodePool {
+					_, poolCIDR, _ := net.ParseCIDR("10.10.128.0/17")
+					return []NodePool{{
+						Name:              "gpu",
+						NodeSelector:      labels.SelectorFromSet(labels.Set{"pool": "gpu"}),
+						ClusterCIDRs:      []*net.IPNet{poolCIDR},
+						NodeCIDRMaskSizes: []int{26},
+					}}
+				}(),
+			},
+			expectedAllocatedCIDR: map[int]string{
+				0: "10.10.128.0/26",
+			},
+		},
+		{
+			description: "Correctly filter out node pool CIDRs for nodes out of the node pool",
+			fakeNodeHandler: &testutil.FakeNodeHandler{
+				Existing: []*v1.Node{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:   "node0",
+							Labels: map[string]string{"pool": "cpu"},
+						},
+					},
+				},
+				Clientset: fake.NewSimpleClientset(),
+			},
+			allocatorParams: CIDRAllocatorParams{
+				ClusterCIDRs: func() []*net.IPNet {
+					_, clusterCIDR, _ := net.ParseCIDR("10.10.0.0/16")
+					return []*net.IPNet{clusterCIDR}
+				}(),
+				ServiceCIDR:          nil,
+				SecondaryServiceCIDR: nil,
+				NodeCIDRMaskSizes:    []int{24},
+				NodePools: func() []NodePool {
+					_, poolCIDR, _ := net.ParseCIDR("10.10.0.0/23")
+					return []NodePool{{
+						Name:              "gpu",
+						NodeSelector:      labels.SelectorFromSet(labels.Set{"pool": "gpu"}),
+						ClusterCIDRs:      []*net.IPNet{poolCIDR},
+						NodeCIDRMaskSizes: []int{26},
+					}}
+				}(),
+			},
+			expectedAllocatedCIDR: map[int]string{
+				0: "10.10.2.0/24",
+			},
+		},
 	}
 
 	// test function
@@ -942,3 +1016,160 @@ func TestRetryAllocatingPodCidr(t *testing.T) {
 		testFunc(tc)
 	}
 }
+
+func TestNodePoolCIDRsOccupyAndRelease(t *testing.T) {
+	_, clusterCIDR, _ := net.ParseCIDR("10.10.0.0/16")
+	_, poolCIDR, _ := net.ParseCIDR("10.10.255.0/24")
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "node0",
+			// the labels of the node no longer match the node pool
+			Labels: map[string]string{"pool": "cpu"},
+		},
+		Spec: v1.NodeSpec{
+			PodCIDRs: []string{"10.10.255.0/26"},
+		},
+	}
+	fakeNodeHandler := &testutil.FakeNodeHandler{
+		Existing:  []*v1.Node{node},
+		Clientset: fake.NewSimpleClientset(),
+	}
+	nodeList, _ := fakeNodeHandler.List(context.TODO(), metav1.ListOptions{})
+	allocator, err := NewCIDRRangeAllocator(fakeNodeHandler, getFakeNodeInformer(fakeNodeHandler), CIDRAllocatorParams{
+		ClusterCIDRs:      []*net.IPNet{clusterCIDR},
+		NodeCIDRMaskSizes: []int{24},
+		NodePools: []NodePool{{
+			Name:              "gpu",
+			NodeSelector:      labels.SelectorFromSet(labels.Set{"pool": "gpu"}),
+			ClusterCIDRs:      []*net.IPNet{poolCIDR},
+			NodeCIDRMaskSizes: []int{26},
+		}},
+	}, nodeList)
+	if err != nil {
+		t.Fatalf("failed to create CIDRRangeAllocator with error %v", err)
+	}
+	ra := allocator.(*rangeAllocator)
+
+	// the pod cidr of the existing node is recovered in the cidr set of the node pool
+	gpuNode := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"pool": "gpu"}}}
+	allocated, err := ra.allocatePodCIDRs(gpuNode)
+	if err != nil {
+		t.Fatalf("unexpected error when allocating pod cidrs: %v", err)
+	}
+	if allocated[0].String() != "10.10.255.64/26" {
+		t.Errorf("expected 10.10.255.64/26 allocated for the node pool, got %v", allocated[0])
+	}
+
+	// the pod cidr is released back to the node pool regardless of the node labels
+	if err := ra.ReleaseCIDR(node); err != nil {
+		t.Fatalf("unexpected error when releasing pod cidrs: %v", err)
+	}
+	// the cidr set allocates round robin, so the released cidr is the last one left
+	for _, expected := range []string{"10.10.255.128/26", "10.10.255.192/26"} {
+		allocated, err = ra.allocatePodCIDRs(gpuNode)
+		if err != nil {
+			t.Fatalf("unexpected error when allocating pod cidrs: %v", err)
+		}
+		if allocated[0].String() != expected {
+			t.Errorf("expected %s allocated for the node pool, got %v", expected, allocated[0])
+		}
+	}
+	allocated, err = ra.allocatePodCIDRs(gpuNode)
+	if err != nil {
+		t.Fatalf("unexpected error when allocating pod cidrs: %v", err)
+	}
+	if allocated[0].String() != "10.10.255.0/26" {
+		t.Errorf("expected the released 10.10.255.0/26 allocated again, got %v", allocated[0])
+	}
+
+	// the node out of the node pool is allocated from the cluster cidr
+	allocated, err = ra.allocatePodCIDRs(node)
+	if err != nil {
+		t.Fatalf("unexpected error when allocating pod cidrs: %v", err)
+	}
+	if allocated[0].String() != "10.10.0.0/24" {
+		t.Errorf("expected 10.10.0.0/24 allocated from the cluster cidr, got %v", allocated[0])
+	}
+}
+
+func TestAddClusterCIDRs(t *testing.T) {
+	_, clusterCIDR, _ := net.ParseCIDR("10.10.0.0/24")
+	_, additionalCIDR, _ := net.ParseCIDR("10.20.0.0/23")
+	nodes := []*v1.Node{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "node0"},
+			Spec:       v1.NodeSpec{PodCIDRs: []string{"10.10.0.0/24"}},
+		},
+		{
+			// allocated from the additional cluster cidr before the restart
+			ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+			Spec:       v1.NodeSpec{PodCIDRs: []string{"10.20.0.0/24"}},
+		},
+	}
+	newNode := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node2"}}
+
+	t.Run("spill over into the cluster cidrs added at runtime", func(t *testing.T) {
+		ra := newTestRangeAllocator(t, CIDRAllocatorParams{
+			ClusterCIDRs:      []*net.IPNet{clusterCIDR},
+			NodeCIDRMaskSizes: []int{24},
+		}, nodes...)
+
+		_, err := ra.allocatePodCIDRs(newNode)
+		assert.ErrorIs(t, err, cidrset.ErrCIDRRangeNoCIDRsRemaining)
+
+		assert.NoError(t, ra.AddClusterCIDRs([]*net.IPNet{additionalCIDR}))
+		// the cidrs that are already added are ignored
+		assert.NoError(t, ra.AddClusterCIDRs([]*net.IPNet{additionalCIDR}))
+		assert.Len(t, ra.additionalCIDRs, 1)
+
+		// the pod cidr of node1 is occupied when the cluster cidr is added
+		allocated, err := ra.allocatePodCIDRs(newNode)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"10.20.1.0/24"}, cidrsAsString(allocated))
+
+		// the pod cidr is released back to the added cluster cidr
+		assert.NoError(t, ra.ReleaseCIDR(nodes[1]))
+		allocated, err = ra.allocatePodCIDRs(newNode)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"10.20.0.0/24"}, cidrsAsString(allocated))
+	})
+
+	t.Run("recover the pod cidrs in the additional cluster cidrs after restart", func(t *testing.T) {
+		ra := newTestRangeAllocator(t, CIDRAllocatorParams{
+			ClusterCIDRs:           []*net.IPNet{clusterCIDR},
+			NodeCIDRMaskSizes:      []int{24},
+			AdditionalClusterCIDRs: []*net.IPNet{additionalCIDR},
+		}, nodes...)
+
+		allocated, err := ra.allocatePodCIDRs(newNode)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"10.20.1.0/24"}, cidrsAsString(allocated))
+	})
+
+	t.Run("skip invalid additional cluster cidrs at startup", func(t *testing.T) {
+		_, invalidCIDR, _ := net.ParseCIDR("10.10.0.0/16")
+		ra := newTestRangeAllocator(t, CIDRAllocatorParams{
+			ClusterCIDRs:           []*net.IPNet{clusterCIDR},
+			NodeCIDRMaskSizes:      []int{24},
+			AdditionalClusterCIDRs: []*net.IPNet{invalidCIDR, additionalCIDR},
+		}, nodes...)
+
+		assert.Len(t, ra.additionalCIDRs, 1)
+		allocated, err := ra.allocatePodCIDRs(newNode)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"10.20.1.0/24"}, cidrsAsString(allocated))
+	})
+
+	t.Run("reject invalid cluster cidrs", func(t *testing.T) {
+		ra := newTestRangeAllocator(t, CIDRAllocatorParams{
+			ClusterCIDRs:      []*net.IPNet{clusterCIDR},
+			NodeCIDRMaskSizes: []int{24},
+		})
+
+		for _, cidr := range []string{"10.10.0.0/16", "ace:cab:deca::/48", "10.30.0.0/25"} {
+			_, invalidCIDR, _ := net.ParseCIDR(cidr)
+			assert.Error(t, ra.AddClusterCIDRs([]*net.IPNet{invalidCIDR}), cidr)
+		}
+		assert.Empty(t, ra.additionalCIDRs)
+	})
+}
@@ -117,6 +117,8 @@ func NewNodeIpamController(
 	serviceCIDR *net.IPNet,
 	secondaryServiceCIDR *net.IPNet,
 	nodeCIDRMaskSizes []int,
+	nodePools []ipam.NodePool,
+	additionalClusterCIDRs []*net.IPNet,
 	allocatorType ipam.CIDRAllocatorType) (*Controller, error) {
 
 	if kubeClient == nil {
@@ -169,10 +171,12 @@ func NewNodeIpamController(
 	var err error
 
 	allocatorParams := ipam.CIDRAllocatorParams{
-		ClusterCIDRs:         clusterCIDRs,
-		ServiceCIDR:          ic.serviceCIDR,
-		SecondaryServiceCIDR: ic.secondaryServiceCIDR,
-		NodeCIDRMaskSizes:    nodeCIDRMaskSizes,
+		ClusterCIDRs:           clusterCIDRs,
+		ServiceCIDR:            ic.serviceCIDR,
+		SecondaryServiceCIDR:   ic.secondaryServiceCIDR,
+		NodeCIDRMaskSizes:      nodeCIDRMaskSizes,
+		NodePools:              nodePools,
+		AdditionalClusterCIDRs: additionalClusterCIDRs,
 	}
 
 	ic.cidrAllocator, err = ipam.New(kubeClient, cloud, nodeInformer, ic.allocatorType, allocatorParams)
@@ -186,6 +190,11 @@ func NewNodeIpamController(
 	return ic, nil
 }
 
+// CIDRAllocator returns the CIDR allocator of the controller.
+func (nc *Controller) CIDRAllocator() ipam.CIDRAllocator {
+	return nc.cidrAllocator
+}
+
 // Run starts an asynchronous loop that monitors the status of cluster nodes.
 func (nc *Controller) Run(ctx context.Context) {
 	defer utilruntime.HandleCrash()
@@ -55,7 +55,7 @@ func newTestNodeIpamController(clusterCIDR []*net.IPNet, serviceCIDR *net.IPNet,
 	fakeAZ := &providerazure.Cloud{}
 	return NewNodeIpamController(
 		fakeNodeInformer, fakeAZ, clientSet,
-		clusterCIDR, serviceCIDR, secondaryServiceCIDR, nodeCIDRMaskSizes, allocatorType,
+		clusterCIDR, serviceCIDR, secondaryServiceCIDR, nodeCIDRMaskSizes, nil, nil, allocatorType,
 	)
 }
 
@@ -0,0 +1,174 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package applicationsecuritygroup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v6"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/azclient/utils"
+	"sigs.k8s.io/cloud-provider-azure/pkg/cache"
+	"sigs.k8s.io/cloud-provider-azure/pkg/util/errutils"
+)
+
+// Generate mocks for the client and repository interfaces
+//go:generate mockgen -destination=./mock_repo.go -package=applicationsecuritygroup -copyright_file ../../../hack/boilerplate/boilerplate.generatego.txt -source=repo.go Client,Repository
+
+const (
+	DefaultCacheTTL = 10 * time.Minute
+)
+
+var (
+	ErrMissingApplicationSecurityGroupName = fmt.Errorf("missing ApplicationSecurityGroup name")
+)
+
+// Client is the client of the application security groups.
+type Client interface {
+	Get(ctx context.Context, resourceGroupName string, resourceName string) (*armnetwork.ApplicationSecurityGroup, error)
+	CreateOrUpdate(ctx context.Context, resourceGroupName string, resourceName string, resource armnetwork.ApplicationSecurityGroup) (*armnetwork.ApplicationSecurityGroup, error)
+	Delete(ctx context.Context, resourceGroupName string, resourceName string) error
+}
+
+type client struct {
+	*armnetwork.ApplicationSecurityGroupsClient
+}
+
+// NewClient returns a Client of the application security groups in the given subscription.
+func NewClient(subscriptionID string, credential azcore.TokenCredential, options *arm.ClientOptions) (Client, error) {
+	if options == nil {
+		options = utils.GetDefaultOption()
+	}
+	c, err := armnetwork.NewApplicationSecurityGroupsClient(subscriptionID, credential, options)
+	if err != nil {
+		return nil, err
+	}
+	return &client{ApplicationSecurityGroupsClient: c}, nil
+}
+
+func (c *client) Get(ctx context.Context, resourceGroupName string, resourceName string) (*armnetwork.ApplicationSecurityGroup, error) {
+	resp, err := c.ApplicationSecurityGroupsClient.Get(ctx, resourceGroupName, resourceName, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.ApplicationSecurityGroup, nil
+}
+
+func (c *client) CreateOrUpdate(ctx context.Context, resourceGroupName string, resourceName string, resource armnetwork.ApplicationSecurityGroup) (*armnetwork.ApplicationSecurityGroup, error) {
+	resp, err := utils.NewPollerWrapper(c.ApplicationSecurityGroupsClient.BeginCreateOrUpdate(ctx, resourceGroupName, resourceName, resource, nil)).WaitforPollerResp(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if resp != nil {
+		return &resp.ApplicationSecurityGroup, nil
+	}
+	return nil, nil
+}
+
+func (c *client) Delete(ctx context.Context, resourceGroupName string, resourceName string) error {
+	_, err := utils.NewPollerWrapper(c.ApplicationSecurityGroupsClient.BeginDelete(ctx, resourceGroupName, resourceName, nil)).WaitforPollerResp(ctx)
+	return err
+}
+
+// Repository manages the application security groups in a resource group.
+type Repository interface {
+	// Get returns the application security group with the given name, or nil if it is not found.
+	Get(ctx context.Context, name string, crt cache.AzureCacheReadType) (*armnetwork.ApplicationSecurityGroup, error)
+	CreateOrUpdate(ctx context.Context, asg armnetwork.ApplicationSecurityGroup) (*armnetwork.ApplicationSecurityGroup, error)
+	// Delete deletes the application security group with the given name, and succeeds if it is not found.
+	Delete(ctx context.Context, name string) error
+}
+
+type repo struct {
+	resourceGroup string
+	client        Client
+	cache         cache.Resource
+}
+
+func NewRepo(
+	client Client,
+	resourceGroup string,
+	cacheTTL time.Duration,
+	disableAPICallCache bool,
+) (Repository, error) {
+	getter := func(ctx context.Context, key string) (interface{}, error) {
+		asg, err := client.Get(ctx, resourceGroup, key)
+		found, err := errutils.CheckResourceExistsFromAzcoreError(err)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, nil
+		}
+
+		return asg, nil
+	}
+
+	if cacheTTL == 0 {
+		cacheTTL = DefaultCacheTTL
+	}
+	c, err := cache.NewTimedCache(cacheTTL, getter, disableAPICallCache)
+	if err != nil {
+		return nil, fmt.Errorf("new ApplicationSecurityGroup cache: %w", err)
+	}
+
+	return &repo{
+		resourceGroup: resourceGroup,
+		client:        client,
+		cache:         c,
+	}, nil
+}
+
+func (r *repo) Get(ctx context.Context, name string, crt cache.AzureCacheReadType) (*armnetwork.ApplicationSecurityGroup, error) {
+	asg, err := r.cache.GetWithDeepCopy(ctx, name, crt)
+	if err != nil {
+		return nil, fmt.Errorf("get ApplicationSecurityGroup: %w", err)
+	}
+	if asg == nil {
+		return nil, nil
+	}
+
+	return asg.(*armnetwork.ApplicationSecurityGroup), nil
+}
+
+func (r *repo) CreateOrUpdate(ctx context.Context, asg armnetwork.ApplicationSecurityGroup) (*armnetwork.ApplicationSecurityGroup, error) {
+	if asg.Name == nil {
+		return nil, ErrMissingApplicationSecurityGroupName
+	}
+
+	rv, err := r.client.CreateOrUpdate(ctx, r.resourceGroup, *asg.Name, asg)
+	if err != nil {
+		return nil, fmt.Errorf("create or update ApplicationSecurityGroup: %w", err)
+	}
+	_ = r.cache.Delete(*asg.Name)
+
+	return rv, nil
+}
+
+func (r *repo) Delete(ctx context.Context, name string) error {
+	err := r.client.Delete(ctx, r.resourceGroup, name)
+	if _, err = errutils.CheckResourceExistsFromAzcoreError(err); err != nil {
+		return fmt.Errorf("delete ApplicationSecurityGroup: %w", err)
+	}
+	_ = r.cache.Delete(name)
+
+	return nil
+}
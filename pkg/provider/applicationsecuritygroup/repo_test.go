@@ -0,0 +1,177 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package applicationsecuritygroup
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v6"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"k8s.io/utils/ptr"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/cache"
+)
+
+const testResourceGroup = "testing-rg"
+
+func TestRepo_Get(t *testing.T) {
+	t.Parallel()
+
+	t.Run("found", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		cli := NewMockClient(ctrl)
+		repo, err := NewRepo(cli, testResourceGroup, time.Minute, false)
+		assert.NoError(t, err)
+
+		cli.EXPECT().Get(gomock.Any(), testResourceGroup, "asg").Return(&armnetwork.ApplicationSecurityGroup{
+			Name: ptr.To("asg"),
+		}, nil).Times(1)
+
+		for i := 0; i < 2; i++ {
+			asg, err := repo.Get(context.Background(), "asg", cache.CacheReadTypeDefault)
+			assert.NoError(t, err)
+			assert.Equal(t, "asg", *asg.Name)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		cli := NewMockClient(ctrl)
+		repo, err := NewRepo(cli, testResourceGroup, time.Minute, false)
+		assert.NoError(t, err)
+
+		cli.EXPECT().Get(gomock.Any(), testResourceGroup, "asg").Return(nil, &azcore.ResponseError{StatusCode: http.StatusNotFound})
+
+		asg, err := repo.Get(context.Background(), "asg", cache.CacheReadTypeDefault)
+		assert.NoError(t, err)
+		assert.Nil(t, asg)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		cli := NewMockClient(ctrl)
+		repo, err := NewRepo(cli, testResourceGroup, time.Minute, false)
+		assert.NoError(t, err)
+
+		cli.EXPECT().Get(gomock.Any(), testResourceGroup, "asg").Return(nil, &azcore.ResponseError{StatusCode: http.StatusInternalServerError})
+
+		_, err = repo.Get(context.Background(), "asg", cache.CacheReadTypeDefault)
+		assert.Error(t, err)
+	})
+}
+
+func TestRepo_CreateOrUpdate(t *testing.T) {
+	t.Parallel()
+
+	t.Run("missing name", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		repo, err := NewRepo(NewMockClient(ctrl), testResourceGroup, time.Minute, false)
+		assert.NoError(t, err)
+
+		_, err = repo.CreateOrUpdate(context.Background(), armnetwork.ApplicationSecurityGroup{})
+		assert.ErrorIs(t, err, ErrMissingApplicationSecurityGroupName)
+	})
+
+	t.Run("invalidate cache", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		cli := NewMockClient(ctrl)
+		repo, err := NewRepo(cli, testResourceGroup, time.Minute, false)
+		assert.NoError(t, err)
+		ctx := context.Background()
+
+		cli.EXPECT().Get(gomock.Any(), testResourceGroup, "asg").Return(nil, &azcore.ResponseError{StatusCode: http.StatusNotFound})
+		asg, err := repo.Get(ctx, "asg", cache.CacheReadTypeDefault)
+		assert.NoError(t, err)
+		assert.Nil(t, asg)
+
+		expected := armnetwork.ApplicationSecurityGroup{Name: ptr.To("asg"), Location: ptr.To("eastus")}
+		cli.EXPECT().CreateOrUpdate(gomock.Any(), testResourceGroup, "asg", expected).Return(&expected, nil)
+		_, err = repo.CreateOrUpdate(ctx, expected)
+		assert.NoError(t, err)
+
+		cli.EXPECT().Get(gomock.Any(), testResourceGroup, "asg").Return(&expected, nil)
+		asg, err = repo.Get(ctx, "asg", cache.CacheReadTypeDefault)
+		assert.NoError(t, err)
+		assert.Equal(t, "asg", *asg.Name)
+	})
+}
+
+func TestRepo_Delete(t *testing.T) {
+	t.Parallel()
+
+	t.Run("invalidate cache", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		cli := NewMockClient(ctrl)
+		repo, err := NewRepo(cli, testResourceGroup, time.Minute, false)
+		assert.NoError(t, err)
+		ctx := context.Background()
+
+		cli.EXPECT().Get(gomock.Any(), testResourceGroup, "asg").Return(&armnetwork.ApplicationSecurityGroup{Name: ptr.To("asg")}, nil)
+		asg, err := repo.Get(ctx, "asg", cache.CacheReadTypeDefault)
+		assert.NoError(t, err)
+		assert.NotNil(t, asg)
+
+		cli.EXPECT().Delete(gomock.Any(), testResourceGroup, "asg").Return(nil)
+		assert.NoError(t, repo.Delete(ctx, "asg"))
+
+		cli.EXPECT().Get(gomock.Any(), testResourceGroup, "asg").Return(nil, &azcore.ResponseError{StatusCode: http.StatusNotFound})
+		asg, err = repo.Get(ctx, "asg", cache.CacheReadTypeDefault)
+		assert.NoError(t, err)
+		assert.Nil(t, asg)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		cli := NewMockClient(ctrl)
+		repo, err := NewRepo(cli, testResourceGroup, time.Minute, false)
+		assert.NoError(t, err)
+
+		cli.EXPECT().Delete(gomock.Any(), testResourceGroup, "asg").Return(&azcore.ResponseError{StatusCode: http.StatusNotFound})
+		assert.NoError(t, repo.Delete(context.Background(), "asg"))
+	})
+
+	t.Run("error", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		cli := NewMockClient(ctrl)
+		repo, err := NewRepo(cli, testResourceGroup, time.Minute, false)
+		assert.NoError(t, err)
+
+		cli.EXPECT().Delete(gomock.Any(), testResourceGroup, "asg").Return(&azcore.ResponseError{StatusCode: http.StatusConflict})
+		assert.Error(t, repo.Delete(context.Background(), "asg"))
+	})
+}
@@ -27,11 +27,13 @@ import (
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
 	"github.com/Azure/go-autorest/autorest"
 	"github.com/Azure/go-autorest/autorest/adal"
 	"github.com/Azure/go-autorest/autorest/azure"
 
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/informers"
@@ -65,7 +67,9 @@ import (
 	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/vmsizeclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/vmssclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/vmssvmclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/provider/applicationsecuritygroup"
 	"sigs.k8s.io/cloud-provider-azure/pkg/provider/config"
+	"sigs.k8s.io/cloud-provider-azure/pkg/provider/dnsrecord"
 	"sigs.k8s.io/cloud-provider-azure/pkg/provider/privatelinkservice"
 	"sigs.k8s.io/cloud-provider-azure/pkg/provider/routetable"
 	"sigs.k8s.io/cloud-provider-azure/pkg/provider/securitygroup"
@@ -152,6 +156,11 @@ type Cloud struct {
 	excludeLoadBalancerNodes   *utilsets.IgnoreCaseSet
 	nodePrivateIPs             map[string]*utilsets.IgnoreCaseSet
 	nodePrivateIPToNodeNameMap map[string]string
+	// nodeLabels holds the labels of the nodes, which select the route tables of the nodes.
+	nodeLabels map[string]map[string]string
+	// nodeSubnetNames holds the subnet names of the primary network interfaces of the nodes,
+	// which select the route tables of the nodes. It is filled on demand.
+	nodeSubnetNames map[string]string
 	// nodeInformerSynced is for determining if the informer has synced.
 	nodeInformerSynced cache.InformerSynced
 
@@ -170,13 +179,18 @@ type Cloud struct {
 	routeUpdater       batchProcessor
 	backendPoolUpdater batchProcessor
 
-	vmCache        azcache.Resource
-	lbCache        azcache.Resource
-	nsgRepo        securitygroup.Repository
-	zoneRepo       zone.Repository
-	plsRepo        privatelinkservice.Repository
-	subnetRepo     subnet.Repository
-	routeTableRepo routetable.Repository
+	vmCache  azcache.Resource
+	lbCache  azcache.Resource
+	nsgRepo  securitygroup.Repository
+	zoneRepo zone.Repository
+	plsRepo  privatelinkservice.Repository
+	// plsConnectionClient approves or rejects the private endpoint connections of the private link services.
+	plsConnectionClient privatelinkservice.ConnectionClient
+	subnetRepo          subnet.Repository
+	routeTableRepo      routetable.Repository
+	asgRepo             applicationsecuritygroup.Repository
+	// dnsRecordClient manages the DNS records of the services in the Azure DNS zones and the Azure Private DNS zones.
+	dnsRecordClient dnsrecord.Client
 	// public ip cache
 	// key: [resourceGroupName]
 	// Value: sync.Map of [pipName]*PublicIPAddress
@@ -188,7 +202,9 @@ type Cloud struct {
 
 	// Add service lister to always get latest service
 	serviceLister corelisters.ServiceLister
-	// node-sync-loop routine and service-reconcile routine should not update LoadBalancer at the same time
+	// node-sync-loop routine and service-reconcile routine should not update LoadBalancer at the same time.
+	// The load balancer configurations generated by AutoScale are only added to
+	// MultipleStandardLoadBalancerConfigurations with the lock held.
 	serviceReconcileLock sync.Mutex
 
 	lockMap *lockmap.LockMap
@@ -201,6 +217,8 @@ type Cloud struct {
 	multipleStandardLoadBalancersActiveNodesLock    sync.Mutex
 	localServiceNameToServiceInfoMap                sync.Map
 	endpointSlicesCache                             sync.Map
+	// publicIPPrefixPoolLock serializes the allocations from the public IP prefix pool.
+	publicIPPrefixPoolLock sync.Mutex
 
 	azureResourceLocker *AzureResourceLocker
 }
@@ -211,6 +229,8 @@ func NewCloud(ctx context.Context, clientBuilder cloudprovider.ControllerClientB
 		nodeNames:                  utilsets.NewString(),
 		nodeZones:                  map[string]*utilsets.IgnoreCaseSet{},
 		nodeResourceGroups:         map[string]string{},
+		nodeLabels:                 map[string]map[string]string{},
+		nodeSubnetNames:            map[string]string{},
 		unmanagedNodes:             utilsets.NewString(),
 		routeCIDRs:                 map[string]string{},
 		excludeLoadBalancerNodes:   utilsets.NewString(),
@@ -302,74 +322,8 @@ func (az *Cloud) InitializeCloudFromConfig(ctx context.Context, config *config.C
 		return fmt.Errorf("InitializeCloudFromConfig: cannot initialize from nil config")
 	}
 
-	if config.RouteTableResourceGroup == "" {
-		config.RouteTableResourceGroup = config.ResourceGroup
-	}
-
-	if config.SecurityGroupResourceGroup == "" {
-		config.SecurityGroupResourceGroup = config.ResourceGroup
-	}
-
-	if config.PrivateLinkServiceResourceGroup == "" {
-		config.PrivateLinkServiceResourceGroup = config.ResourceGroup
-	}
-
-	if config.VMType == "" {
-		// default to vmss vmType if not set.
-		config.VMType = consts.VMTypeVMSS
-	}
-
-	if config.RouteUpdateWaitingInSeconds <= 0 {
-		config.RouteUpdateWaitingInSeconds = defaultRouteUpdateWaitingInSeconds
-	}
-
-	if config.DisableAvailabilitySetNodes && config.VMType != consts.VMTypeVMSS {
-		return fmt.Errorf("disableAvailabilitySetNodes %v is only supported when vmType is 'vmss'", config.DisableAvailabilitySetNodes)
-	}
-
-	if config.CloudConfigType == "" {
-		// The default cloud config type is cloudConfigTypeMerge.
-		config.CloudConfigType = configloader.CloudConfigTypeMerge
-	} else {
-		supportedCloudConfigTypes := utilsets.NewString(
-			string(configloader.CloudConfigTypeMerge),
-			string(configloader.CloudConfigTypeFile),
-			string(configloader.CloudConfigTypeSecret))
-		if !supportedCloudConfigTypes.Has(string(config.CloudConfigType)) {
-			return fmt.Errorf("cloudConfigType %v is not supported, supported values are %v", config.CloudConfigType, supportedCloudConfigTypes.UnsortedList())
-		}
-	}
-
-	if config.LoadBalancerBackendPoolConfigurationType == "" ||
-		// TODO(nilo19): support pod IP mode in the future
-		strings.EqualFold(config.LoadBalancerBackendPoolConfigurationType, consts.LoadBalancerBackendPoolConfigurationTypePODIP) {
-		config.LoadBalancerBackendPoolConfigurationType = consts.LoadBalancerBackendPoolConfigurationTypeNodeIPConfiguration
-	} else {
-		supportedLoadBalancerBackendPoolConfigurationTypes := utilsets.NewString(
-			strings.ToLower(consts.LoadBalancerBackendPoolConfigurationTypeNodeIPConfiguration),
-			strings.ToLower(consts.LoadBalancerBackendPoolConfigurationTypeNodeIP),
-			strings.ToLower(consts.LoadBalancerBackendPoolConfigurationTypePODIP))
-		if !supportedLoadBalancerBackendPoolConfigurationTypes.Has(strings.ToLower(config.LoadBalancerBackendPoolConfigurationType)) {
-			return fmt.Errorf("loadBalancerBackendPoolConfigurationType %s is not supported, supported values are %v", config.LoadBalancerBackendPoolConfigurationType, supportedLoadBalancerBackendPoolConfigurationTypes.UnsortedList())
-		}
-	}
-
-	if config.ClusterServiceLoadBalancerHealthProbeMode == "" {
-		config.ClusterServiceLoadBalancerHealthProbeMode = consts.ClusterServiceLoadBalancerHealthProbeModeServiceNodePort
-	} else {
-		supportedClusterServiceLoadBalancerHealthProbeModes := utilsets.NewString(
-			strings.ToLower(consts.ClusterServiceLoadBalancerHealthProbeModeServiceNodePort),
-			strings.ToLower(consts.ClusterServiceLoadBalancerHealthProbeModeShared),
-		)
-		if !supportedClusterServiceLoadBalancerHealthProbeModes.Has(strings.ToLower(config.ClusterServiceLoadBalancerHealthProbeMode)) {
-			return fmt.Errorf("clusterServiceLoadBalancerHealthProbeMode %s is not supported, supported values are %v", config.ClusterServiceLoadBalancerHealthProbeMode, supportedClusterServiceLoadBalancerHealthProbeModes.UnsortedList())
-		}
-	}
-	if config.ClusterServiceSharedLoadBalancerHealthProbePort == 0 {
-		config.ClusterServiceSharedLoadBalancerHealthProbePort = consts.ClusterServiceLoadBalancerHealthProbeDefaultPort
-	}
-	if config.ClusterServiceSharedLoadBalancerHealthProbePath == "" {
-		config.ClusterServiceSharedLoadBalancerHealthProbePath = consts.ClusterServiceLoadBalancerHealthProbeDefaultPath
+	if err := setConfigDefaults(config); err != nil {
+		return err
 	}
 
 	env, err := azureconfig.ParseAzureEnvironment(config.Cloud, config.ResourceManagerEndpoint, config.IdentitySystem)
@@ -417,11 +371,7 @@ func (az *Cloud) InitializeCloudFromConfig(ctx context.Context, config *config.C
 		}
 	}
 
-	if az.IsLBBackendPoolTypeNodeIPConfig() {
-		az.LoadBalancerBackendPool = newBackendPoolTypeNodeIPConfig(az)
-	} else if az.IsLBBackendPoolTypeNodeIP() {
-		az.LoadBalancerBackendPool = newBackendPoolTypeNodeIP(az)
-	}
+	az.initLoadBalancerBackendPool()
 
 	if az.UseMultipleStandardLoadBalancers() {
 		if err := az.checkEnableMultipleStandardLoadBalancers(); err != nil {
@@ -512,6 +462,18 @@ func (az *Cloud) InitializeCloudFromConfig(ctx context.Context, config *config.C
 		if err != nil {
 			return err
 		}
+		if az.PrivateLinkServiceConnectionApprovalIntervalInSeconds > 0 {
+			options, err := azclient.GetDefaultResourceClientOption(&az.ARMClientConfig, &azclient.ClientFactoryConfig{
+				SubscriptionID: az.SubscriptionID,
+			})
+			if err != nil {
+				return err
+			}
+			az.plsConnectionClient, err = privatelinkservice.NewConnectionClient(az.SubscriptionID, cred, options)
+			if err != nil {
+				return err
+			}
+		}
 
 		az.subnetRepo, err = subnet.NewRepo(networkClientFactory.GetSubnetClient())
 		if err != nil {
@@ -522,6 +484,30 @@ func (az *Cloud) InitializeCloudFromConfig(ctx context.Context, config *config.C
 		if err != nil {
 			return err
 		}
+
+		az.dnsRecordClient = dnsrecord.NewClient(networkClientFactory)
+
+		networkCred := cred
+		if authProvider.IsMultiTenantModeEnabled() {
+			networkCred = authProvider.GetNetworkAzIdentity()
+		}
+		networkClientOptions, err := azclient.GetDefaultResourceClientOption(&az.ARMClientConfig, &azclient.ClientFactoryConfig{
+			SubscriptionID: az.getNetworkResourceSubscriptionID(),
+		})
+		if err != nil {
+			return err
+		}
+
+		if az.UseApplicationSecurityGroups {
+			asgClient, err := applicationsecuritygroup.NewClient(az.getNetworkResourceSubscriptionID(), networkCred, networkClientOptions)
+			if err != nil {
+				return err
+			}
+			az.asgRepo, err = applicationsecuritygroup.NewRepo(asgClient, az.getLoadBalancerResourceGroup(), applicationsecuritygroup.DefaultCacheTTL, az.DisableAPICallCache)
+			if err != nil {
+				return err
+			}
+		}
 	}
 	err = az.initCaches()
 	if err != nil {
@@ -548,7 +534,7 @@ func (az *Cloud) InitializeCloudFromConfig(ctx context.Context, config *config.C
 		go az.routeUpdater.run(ctx)
 
 		// start backend pool updater.
-		if az.UseMultipleStandardLoadBalancers() {
+		if az.UseMultipleStandardLoadBalancers() || az.IsLBBackendPoolTypePodIP() {
 			az.backendPoolUpdater = newLoadBalancerBackendPoolUpdater(az, time.Duration(az.LoadBalancerBackendPoolUpdateIntervalInSeconds)*time.Second)
 			go az.backendPoolUpdater.run(ctx)
 		}
@@ -570,6 +556,171 @@ func (az *Cloud) InitializeCloudFromConfig(ctx context.Context, config *config.C
 	return nil
 }
 
+// setConfigDefaults sets the default values of the config and validates the enumerations.
+func setConfigDefaults(config *azureconfig.Config) error {
+	if config.RouteTableResourceGroup == "" {
+		config.RouteTableResourceGroup = config.ResourceGroup
+	}
+
+	if config.SecurityGroupResourceGroup == "" {
+		config.SecurityGroupResourceGroup = config.ResourceGroup
+	}
+
+	if config.PrivateLinkServiceResourceGroup == "" {
+		config.PrivateLinkServiceResourceGroup = config.ResourceGroup
+	}
+
+	if config.VMType == "" {
+		// default to vmss vmType if not set.
+		config.VMType = consts.VMTypeVMSS
+	}
+
+	if config.RouteUpdateWaitingInSeconds <= 0 {
+		config.RouteUpdateWaitingInSeconds = defaultRouteUpdateWaitingInSeconds
+	}
+
+	if config.DisableAvailabilitySetNodes && config.VMType != consts.VMTypeVMSS {
+		return fmt.Errorf("disableAvailabilitySetNodes %v is only supported when vmType is 'vmss'", config.DisableAvailabilitySetNodes)
+	}
+
+	if config.CloudConfigType == "" {
+		// The default cloud config type is cloudConfigTypeMerge.
+		config.CloudConfigType = configloader.CloudConfigTypeMerge
+	} else {
+		supportedCloudConfigTypes := utilsets.NewString(
+			string(configloader.CloudConfigTypeMerge),
+			string(configloader.CloudConfigTypeFile),
+			string(configloader.CloudConfigTypeSecret))
+		if !supportedCloudConfigTypes.Has(string(config.CloudConfigType)) {
+			return fmt.Errorf("cloudConfigType %v is not supported, supported values are %v", config.CloudConfigType, supportedCloudConfigTypes.UnsortedList())
+		}
+	}
+
+	if config.LoadBalancerBackendPoolConfigurationType == "" {
+		config.LoadBalancerBackendPoolConfigurationType = consts.LoadBalancerBackendPoolConfigurationTypeNodeIPConfiguration
+	} else {
+		supportedLoadBalancerBackendPoolConfigurationTypes := utilsets.NewString(
+			strings.ToLower(consts.LoadBalancerBackendPoolConfigurationTypeNodeIPConfiguration),
+			strings.ToLower(consts.LoadBalancerBackendPoolConfigurationTypeNodeIP),
+			strings.ToLower(consts.LoadBalancerBackendPoolConfigurationTypePODIP))
+		if !supportedLoadBalancerBackendPoolConfigurationTypes.Has(strings.ToLower(config.LoadBalancerBackendPoolConfigurationType)) {
+			return fmt.Errorf("loadBalancerBackendPoolConfigurationType %s is not supported, supported values are %v", config.LoadBalancerBackendPoolConfigurationType, supportedLoadBalancerBackendPoolConfigurationTypes.UnsortedList())
+		}
+	}
+	if config.UseApplicationSecurityGroups && !strings.EqualFold(config.LoadBalancerBackendPoolConfigurationType, consts.LoadBalancerBackendPoolConfigurationTypeNodeIPConfiguration) {
+		return fmt.Errorf("useApplicationSecurityGroups can only be used with backend pool type %s", consts.LoadBalancerBackendPoolConfigurationTypeNodeIPConfiguration)
+	}
+
+	if config.ClusterServiceLoadBalancerHealthProbeMode == "" {
+		config.ClusterServiceLoadBalancerHealthProbeMode = consts.ClusterServiceLoadBalancerHealthProbeModeServiceNodePort
+	} else {
+		supportedClusterServiceLoadBalancerHealthProbeModes := utilsets.NewString(
+			strings.ToLower(consts.ClusterServiceLoadBalancerHealthProbeModeServiceNodePort),
+			strings.ToLower(consts.ClusterServiceLoadBalancerHealthProbeModeShared),
+		)
+		if !supportedClusterServiceLoadBalancerHealthProbeModes.Has(strings.ToLower(config.ClusterServiceLoadBalancerHealthProbeMode)) {
+			return fmt.Errorf("clusterServiceLoadBalancerHealthProbeMode %s is not supported, supported values are %v", config.ClusterServiceLoadBalancerHealthProbeMode, supportedClusterServiceLoadBalancerHealthProbeModes.UnsortedList())
+		}
+	}
+	if config.OrphanedResourceGCMode == "" {
+		config.OrphanedResourceGCMode = consts.OrphanedResourceGCModeReport
+	} else {
+		supportedOrphanedResourceGCModes := utilsets.NewString(
+			strings.ToLower(consts.OrphanedResourceGCModeReport),
+			strings.ToLower(consts.OrphanedResourceGCModeEnforce),
+		)
+		if !supportedOrphanedResourceGCModes.Has(strings.ToLower(config.OrphanedResourceGCMode)) {
+			return fmt.Errorf("orphanedResourceGCMode %s is not supported, supported values are %v", config.OrphanedResourceGCMode, supportedOrphanedResourceGCModes.UnsortedList())
+		}
+	}
+	if config.RouteReconcileMode == "" {
+		config.RouteReconcileMode = consts.RouteReconcileModeNone
+	} else {
+		supportedRouteReconcileModes := utilsets.NewString(
+			consts.RouteReconcileModeNone,
+			consts.RouteReconcileModeReport,
+			consts.RouteReconcileModeRepair,
+		)
+		if !supportedRouteReconcileModes.Has(config.RouteReconcileMode) {
+			return fmt.Errorf("routeReconcileMode %s is not supported, supported values are %v", config.RouteReconcileMode, supportedRouteReconcileModes.UnsortedList())
+		}
+	}
+	if config.OrphanedResourceGCGracePeriodInSeconds <= 0 {
+		config.OrphanedResourceGCGracePeriodInSeconds = consts.DefaultOrphanedResourceGCGracePeriodInSeconds
+	}
+	if config.ClusterServiceSharedLoadBalancerHealthProbePort == 0 {
+		config.ClusterServiceSharedLoadBalancerHealthProbePort = consts.ClusterServiceLoadBalancerHealthProbeDefaultPort
+	}
+	if config.ClusterServiceSharedLoadBalancerHealthProbePath == "" {
+		config.ClusterServiceSharedLoadBalancerHealthProbePath = consts.ClusterServiceLoadBalancerHealthProbeDefaultPath
+	}
+	if config.PublicIPDdosProtectionMode != "" {
+		if _, err := parsePublicIPDdosProtectionMode(config.PublicIPDdosProtectionMode); err != nil {
+			return fmt.Errorf("publicIPDdosProtectionMode: %w", err)
+		}
+	}
+	if config.PublicIPRoutingPreference != "" {
+		if _, err := parsePublicIPRoutingPreference(config.PublicIPRoutingPreference); err != nil {
+			return fmt.Errorf("publicIPRoutingPreference: %w", err)
+		}
+	}
+	if pool := config.PublicIPPrefixPool; pool != nil {
+		for _, id := range pool.PrefixIDs {
+			if _, err := arm.ParseResourceID(id); err != nil {
+				return fmt.Errorf("invalid public IP prefix ID %s in publicIPPrefixPool: %w", id, err)
+			}
+		}
+		if pool.NameTemplate != "" {
+			if !strings.Contains(pool.NameTemplate, consts.PublicIPPrefixPoolIndexPlaceholder) {
+				return fmt.Errorf("the name template of publicIPPrefixPool must contain %s", consts.PublicIPPrefixPoolIndexPlaceholder)
+			}
+			if pool.MaxPrefixes <= 0 {
+				return fmt.Errorf("publicIPPrefixPool must have positive maxPrefixes with a name template")
+			}
+		}
+		if pool.ResourceGroup == "" {
+			pool.ResourceGroup = config.ResourceGroup
+		}
+		if pool.PrefixLength == 0 {
+			pool.PrefixLength = consts.DefaultPublicIPPrefixPoolPrefixLength
+		}
+		if pool.IPv6PrefixLength == 0 {
+			pool.IPv6PrefixLength = consts.DefaultPublicIPPrefixPoolIPv6PrefixLength
+		}
+		if pool.PrefixLength < 0 || pool.PrefixLength > 32 || pool.IPv6PrefixLength < 0 || pool.IPv6PrefixLength > 128 {
+			return fmt.Errorf("invalid prefix length %d or IPv6 prefix length %d of publicIPPrefixPool", pool.PrefixLength, pool.IPv6PrefixLength)
+		}
+	}
+	routeTableNames := utilsets.NewString(config.RouteTableName)
+	for _, routeTable := range config.RouteTables {
+		if routeTable.Name == "" {
+			return fmt.Errorf("the name of the route tables in routeTables must not be empty")
+		}
+		if routeTableNames.Has(routeTable.Name) {
+			return fmt.Errorf("duplicated route table name %s in routeTables", routeTable.Name)
+		}
+		routeTableNames.Insert(routeTable.Name)
+		if routeTable.NodeSelector == nil && routeTable.SubnetName == "" {
+			return fmt.Errorf("route table %s in routeTables must have nodeSelector or subnetName", routeTable.Name)
+		}
+		if _, err := metav1.LabelSelectorAsSelector(routeTable.NodeSelector); err != nil {
+			return fmt.Errorf("invalid nodeSelector of route table %s in routeTables: %w", routeTable.Name, err)
+		}
+	}
+	return nil
+}
+
+// initLoadBalancerBackendPool initializes the backend pool of the configured backend pool type.
+func (az *Cloud) initLoadBalancerBackendPool() {
+	if az.IsLBBackendPoolTypeNodeIPConfig() {
+		az.LoadBalancerBackendPool = newBackendPoolTypeNodeIPConfig(az)
+	} else if az.IsLBBackendPoolTypeNodeIP() {
+		az.LoadBalancerBackendPool = newBackendPoolTypeNodeIP(az)
+	} else if az.IsLBBackendPoolTypePodIP() {
+		az.LoadBalancerBackendPool = newBackendPoolTypePodIP(az)
+	}
+}
+
 // Multiple standard load balancer mode only supports IP-based load balancers.
 func (az *Cloud) checkEnableMultipleStandardLoadBalancers() error {
 	if az.IsLBBackendPoolTypeNodeIPConfig() {
@@ -593,9 +744,32 @@ func (az *Cloud) checkEnableMultipleStandardLoadBalancers() error {
 		primaryVMSets.Insert(multiSLBConfig.PrimaryVMSet)
 	}
 
+	for _, multiSLBConfig := range az.MultipleStandardLoadBalancerConfigurations {
+		if multiSLBConfig.AutoScale == nil {
+			continue
+		}
+		if multiSLBConfig.AutoScale.MaxCount <= 0 {
+			return fmt.Errorf("multiple standard load balancer configuration %s must have positive auto scale max count", multiSLBConfig.Name)
+		}
+		if multiSLBConfig.AutoScale.NameTemplate != "" &&
+			!strings.Contains(multiSLBConfig.AutoScale.NameTemplate, consts.AutoScaledLoadBalancerIndexPlaceholder) {
+			return fmt.Errorf("the auto scale name template of multiple standard load balancer configuration %s must contain %s", multiSLBConfig.Name, consts.AutoScaledLoadBalancerIndexPlaceholder)
+		}
+		for i := 1; i <= multiSLBConfig.AutoScale.MaxCount; i++ {
+			name := getAutoScaledLoadBalancerName(multiSLBConfig, i)
+			if names.Has(name) {
+				return fmt.Errorf("the auto scaled load balancer name %s of multiple standard load balancer configuration %s conflicts with another load balancer", name, multiSLBConfig.Name)
+			}
+			names.Insert(name)
+		}
+	}
+
 	if az.LoadBalancerBackendPoolUpdateIntervalInSeconds == 0 {
 		az.LoadBalancerBackendPoolUpdateIntervalInSeconds = consts.DefaultLoadBalancerBackendPoolUpdateIntervalInSeconds
 	}
+	if az.MaxConcurrentServiceMigrations <= 0 {
+		az.MaxConcurrentServiceMigrations = 1
+	}
 
 	return nil
 }
@@ -959,8 +1133,12 @@ func (az *Cloud) updateNodeCaches(prevNode, newNode *v1.Node) {
 			delete(az.nodePrivateIPToNodeNameMap, address)
 		}
 
+		// Remove from nodeLabels cache.
+		delete(az.nodeLabels, strings.ToLower(prevNode.Name))
+
 		// if the node is being deleted from the cluster, exclude it from load balancers
 		if newNode == nil {
+			delete(az.nodeSubnetNames, strings.ToLower(prevNode.Name))
 			az.excludeLoadBalancerNodes.Insert(prevNode.ObjectMeta.Name)
 			az.nodesWithCorrectLoadBalancerByPrimaryVMSet.Delete(strings.ToLower(prevNode.ObjectMeta.Name))
 			delete(az.nodePrivateIPs, strings.ToLower(prevNode.Name))
@@ -1008,6 +1186,12 @@ func (az *Cloud) updateNodeCaches(prevNode, newNode *v1.Node) {
 			az.excludeLoadBalancerNodes.Delete(newNode.ObjectMeta.Name)
 		}
 
+		// Add to nodeLabels cache.
+		if az.nodeLabels == nil {
+			az.nodeLabels = make(map[string]map[string]string)
+		}
+		az.nodeLabels[strings.ToLower(newNode.Name)] = newNode.Labels
+
 		// Add to nodePrivateIPs cache
 		for _, address := range getNodePrivateIPAddresses(newNode) {
 			if az.nodePrivateIPToNodeNameMap == nil {
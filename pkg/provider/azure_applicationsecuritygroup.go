@@ -0,0 +1,239 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v6"
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2022-08-01/compute"
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2022-07-01/network"
+
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+
+	azcache "sigs.k8s.io/cloud-provider-azure/pkg/cache"
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+	utilsets "sigs.k8s.io/cloud-provider-azure/pkg/util/sets"
+)
+
+// getApplicationSecurityGroupID returns the ID of the application security group of the load balancer.
+// The application security group is in the resource group of the load balancer and has the same name.
+func (az *Cloud) getApplicationSecurityGroupID(lbName string) string {
+	return fmt.Sprintf(
+		consts.ApplicationSecurityGroupIDTemplate,
+		az.getNetworkResourceSubscriptionID(),
+		az.getLoadBalancerResourceGroup(),
+		lbName)
+}
+
+// getApplicationSecurityGroupIDForBackendPool returns the ID of the application security group of the load balancer
+// the backend pool belongs to. It returns an empty string if the application security groups are not used.
+func (az *Cloud) getApplicationSecurityGroupIDForBackendPool(backendPoolID string) string {
+	if !az.UseApplicationSecurityGroups {
+		return ""
+	}
+	matches := backendPoolIDRE.FindStringSubmatch(backendPoolID)
+	if len(matches) != 3 {
+		klog.Warningf("getApplicationSecurityGroupIDForBackendPool: backendPoolID %q is in wrong format", backendPoolID)
+		return ""
+	}
+	return az.getApplicationSecurityGroupID(matches[1])
+}
+
+// ensureApplicationSecurityGroup creates the application security group of the load balancer if it does not exist.
+func (az *Cloud) ensureApplicationSecurityGroup(ctx context.Context, clusterName, lbName string) error {
+	asg, err := az.asgRepo.Get(ctx, lbName, azcache.CacheReadTypeDefault)
+	if err != nil {
+		return err
+	}
+	if asg != nil {
+		return nil
+	}
+
+	klog.V(2).Infof("ensureApplicationSecurityGroup: creating application security group %s", lbName)
+	_, err = az.asgRepo.CreateOrUpdate(ctx, armnetwork.ApplicationSecurityGroup{
+		Name:     ptr.To(lbName),
+		Location: ptr.To(az.Location),
+		Tags: map[string]*string{
+			consts.ClusterNameKey: ptr.To(clusterName),
+		},
+	})
+	return err
+}
+
+// deleteApplicationSecurityGroup deletes the application security group of the load balancer.
+// It must be called after the rules and the network interfaces referencing it are cleaned up.
+func (az *Cloud) deleteApplicationSecurityGroup(ctx context.Context, lbName string) error {
+	asg, err := az.asgRepo.Get(ctx, lbName, azcache.CacheReadTypeDefault)
+	if err != nil {
+		return err
+	}
+	if asg == nil {
+		return nil
+	}
+
+	klog.V(2).Infof("deleteApplicationSecurityGroup: deleting application security group %s", lbName)
+	return az.asgRepo.Delete(ctx, lbName)
+}
+
+// getApplicationSecurityGroupIDOfBackendPool returns the ID of the application security group of the load balancer
+// the backend pool belongs to, regardless of whether the application security groups are used, so the memberships
+// are still cleaned up after they are disabled. It returns an empty string if the backend pool ID is invalid.
+func getApplicationSecurityGroupIDOfBackendPool(backendPoolID string) string {
+	resourceID, err := arm.ParseResourceID(backendPoolID)
+	if err != nil || resourceID.Parent == nil || !strings.EqualFold(resourceID.Parent.ResourceType.Type, "loadBalancers") {
+		return ""
+	}
+	return fmt.Sprintf(
+		consts.ApplicationSecurityGroupIDTemplate,
+		resourceID.Parent.SubscriptionID,
+		resourceID.Parent.ResourceGroupName,
+		resourceID.Parent.Name)
+}
+
+// getApplicationSecurityGroupIDsToRemove returns the IDs of the application security groups of the load balancers
+// of the removed backend pools, except the ones of the load balancers the remaining backend pools belong to.
+func getApplicationSecurityGroupIDsToRemove(removedBackendPoolIDs, remainingBackendPoolIDs []string) *utilsets.IgnoreCaseSet {
+	remaining := utilsets.NewString()
+	for _, backendPoolID := range remainingBackendPoolIDs {
+		remaining.Insert(getApplicationSecurityGroupIDOfBackendPool(backendPoolID))
+	}
+	rv := utilsets.NewString()
+	for _, backendPoolID := range removedBackendPoolIDs {
+		if asgID := getApplicationSecurityGroupIDOfBackendPool(backendPoolID); asgID != "" && !remaining.Has(asgID) {
+			rv.Insert(asgID)
+		}
+	}
+	return rv
+}
+
+// isInterfaceIPConfigInApplicationSecurityGroup checks if the IP configuration of the network interface
+// is in the application security group. An empty asgID is treated as found.
+func isInterfaceIPConfigInApplicationSecurityGroup(ipConfig *network.InterfaceIPConfiguration, asgID string) bool {
+	if asgID == "" {
+		return true
+	}
+	if ipConfig.InterfaceIPConfigurationPropertiesFormat == nil || ipConfig.ApplicationSecurityGroups == nil {
+		return false
+	}
+	for _, asg := range *ipConfig.ApplicationSecurityGroups {
+		if strings.EqualFold(ptr.Deref(asg.ID, ""), asgID) {
+			return true
+		}
+	}
+	return false
+}
+
+// addApplicationSecurityGroupToInterfaceIPConfig adds the IP configuration of the network interface to the application security group.
+func addApplicationSecurityGroupToInterfaceIPConfig(ipConfig *network.InterfaceIPConfiguration, asgID string) {
+	if isInterfaceIPConfigInApplicationSecurityGroup(ipConfig, asgID) || ipConfig.InterfaceIPConfigurationPropertiesFormat == nil {
+		return
+	}
+	var asgs []network.ApplicationSecurityGroup
+	if ipConfig.ApplicationSecurityGroups != nil {
+		asgs = append(asgs, *ipConfig.ApplicationSecurityGroups...)
+	}
+	asgs = append(asgs, network.ApplicationSecurityGroup{ID: ptr.To(asgID)})
+	ipConfig.ApplicationSecurityGroups = &asgs
+}
+
+// removeApplicationSecurityGroupsFromInterfaceIPConfig removes the IP configuration of the network interface from the
+// application security groups of the removed backend pools. It is kept in the application security group of a load balancer
+// if it is still in another backend pool of the load balancer.
+func removeApplicationSecurityGroupsFromInterfaceIPConfig(ipConfig *network.InterfaceIPConfiguration, removedBackendPoolIDs []string) {
+	if ipConfig.InterfaceIPConfigurationPropertiesFormat == nil || ipConfig.ApplicationSecurityGroups == nil {
+		return
+	}
+	var remainingBackendPoolIDs []string
+	if ipConfig.LoadBalancerBackendAddressPools != nil {
+		for _, pool := range *ipConfig.LoadBalancerBackendAddressPools {
+			remainingBackendPoolIDs = append(remainingBackendPoolIDs, ptr.Deref(pool.ID, ""))
+		}
+	}
+	asgIDsToRemove := getApplicationSecurityGroupIDsToRemove(removedBackendPoolIDs, remainingBackendPoolIDs)
+	if asgIDsToRemove.Len() == 0 {
+		return
+	}
+	asgs := make([]network.ApplicationSecurityGroup, 0, len(*ipConfig.ApplicationSecurityGroups))
+	for _, asg := range *ipConfig.ApplicationSecurityGroups {
+		if !asgIDsToRemove.Has(ptr.Deref(asg.ID, "")) {
+			asgs = append(asgs, asg)
+		}
+	}
+	ipConfig.ApplicationSecurityGroups = &asgs
+}
+
+// isVMSSIPConfigInApplicationSecurityGroup checks if the IP configuration of the VMSS network interface
+// configuration is in the application security group. An empty asgID is treated as found.
+func isVMSSIPConfigInApplicationSecurityGroup(ipConfig *compute.VirtualMachineScaleSetIPConfiguration, asgID string) bool {
+	if asgID == "" {
+		return true
+	}
+	if ipConfig.VirtualMachineScaleSetIPConfigurationProperties == nil || ipConfig.ApplicationSecurityGroups == nil {
+		return false
+	}
+	for _, asg := range *ipConfig.ApplicationSecurityGroups {
+		if strings.EqualFold(ptr.Deref(asg.ID, ""), asgID) {
+			return true
+		}
+	}
+	return false
+}
+
+// addApplicationSecurityGroupToVMSSIPConfig adds the IP configuration of the VMSS network interface configuration
+// to the application security group.
+func addApplicationSecurityGroupToVMSSIPConfig(ipConfig *compute.VirtualMachineScaleSetIPConfiguration, asgID string) {
+	if isVMSSIPConfigInApplicationSecurityGroup(ipConfig, asgID) || ipConfig.VirtualMachineScaleSetIPConfigurationProperties == nil {
+		return
+	}
+	var asgs []compute.SubResource
+	if ipConfig.ApplicationSecurityGroups != nil {
+		asgs = append(asgs, *ipConfig.ApplicationSecurityGroups...)
+	}
+	asgs = append(asgs, compute.SubResource{ID: ptr.To(asgID)})
+	ipConfig.ApplicationSecurityGroups = &asgs
+}
+
+// removeApplicationSecurityGroupsFromVMSSIPConfig removes the IP configuration of the VMSS network interface configuration
+// from the application security groups of the removed backend pools. It is kept in the application security group of
+// a load balancer if it is still in another backend pool of the load balancer.
+func removeApplicationSecurityGroupsFromVMSSIPConfig(ipConfig *compute.VirtualMachineScaleSetIPConfiguration, removedBackendPoolIDs []string) {
+	if ipConfig.VirtualMachineScaleSetIPConfigurationProperties == nil || ipConfig.ApplicationSecurityGroups == nil {
+		return
+	}
+	var remainingBackendPoolIDs []string
+	if ipConfig.LoadBalancerBackendAddressPools != nil {
+		for _, pool := range *ipConfig.LoadBalancerBackendAddressPools {
+			remainingBackendPoolIDs = append(remainingBackendPoolIDs, ptr.Deref(pool.ID, ""))
+		}
+	}
+	asgIDsToRemove := getApplicationSecurityGroupIDsToRemove(removedBackendPoolIDs, remainingBackendPoolIDs)
+	if asgIDsToRemove.Len() == 0 {
+		return
+	}
+	asgs := make([]compute.SubResource, 0, len(*ipConfig.ApplicationSecurityGroups))
+	for _, asg := range *ipConfig.ApplicationSecurityGroups {
+		if !asgIDsToRemove.Has(ptr.Deref(asg.ID, "")) {
+			asgs = append(asgs, asg)
+		}
+	}
+	ipConfig.ApplicationSecurityGroups = &asgs
+}
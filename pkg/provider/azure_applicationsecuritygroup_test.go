@@ -0,0 +1,184 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v6"
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2022-08-01/compute"
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2022-07-01/network"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"k8s.io/utils/ptr"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+	"sigs.k8s.io/cloud-provider-azure/pkg/provider/applicationsecuritygroup"
+)
+
+func TestGetApplicationSecurityGroupIDForBackendPool(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	az := GetTestCloud(ctrl)
+	backendPoolID := "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/kubernetes/backendAddressPools/kubernetes"
+	assert.Empty(t, az.getApplicationSecurityGroupIDForBackendPool(backendPoolID))
+
+	az.UseApplicationSecurityGroups = true
+	assert.Equal(t, "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/applicationSecurityGroups/kubernetes",
+		az.getApplicationSecurityGroupIDForBackendPool(backendPoolID))
+	assert.Empty(t, az.getApplicationSecurityGroupIDForBackendPool("invalid"))
+}
+
+func TestEnsureApplicationSecurityGroup(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	az := GetTestCloud(ctrl)
+	az.UseApplicationSecurityGroups = true
+	mockASGRepo := az.asgRepo.(*applicationsecuritygroup.MockRepository)
+
+	// The application security group is created if it does not exist.
+	mockASGRepo.EXPECT().Get(gomock.Any(), "kubernetes", gomock.Any()).Return(nil, nil)
+	mockASGRepo.EXPECT().CreateOrUpdate(gomock.Any(), armnetwork.ApplicationSecurityGroup{
+		Name:     ptr.To("kubernetes"),
+		Location: ptr.To(az.Location),
+		Tags:     map[string]*string{consts.ClusterNameKey: ptr.To("cluster")},
+	}).Return(&armnetwork.ApplicationSecurityGroup{Name: ptr.To("kubernetes")}, nil)
+	assert.NoError(t, az.ensureApplicationSecurityGroup(context.TODO(), "cluster", "kubernetes"))
+
+	// The existing application security group is not updated.
+	mockASGRepo.EXPECT().Get(gomock.Any(), "kubernetes", gomock.Any()).Return(&armnetwork.ApplicationSecurityGroup{Name: ptr.To("kubernetes")}, nil)
+	assert.NoError(t, az.ensureApplicationSecurityGroup(context.TODO(), "cluster", "kubernetes"))
+}
+
+func TestAddApplicationSecurityGroupToIPConfig(t *testing.T) {
+	asgID := "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/applicationSecurityGroups/kubernetes"
+
+	interfaceIPConfig := &network.InterfaceIPConfiguration{
+		InterfaceIPConfigurationPropertiesFormat: &network.InterfaceIPConfigurationPropertiesFormat{
+			ApplicationSecurityGroups: &[]network.ApplicationSecurityGroup{{ID: ptr.To("other")}},
+		},
+	}
+	assert.True(t, isInterfaceIPConfigInApplicationSecurityGroup(interfaceIPConfig, ""))
+	assert.False(t, isInterfaceIPConfigInApplicationSecurityGroup(interfaceIPConfig, asgID))
+	addApplicationSecurityGroupToInterfaceIPConfig(interfaceIPConfig, asgID)
+	addApplicationSecurityGroupToInterfaceIPConfig(interfaceIPConfig, asgID)
+	assert.Len(t, *interfaceIPConfig.ApplicationSecurityGroups, 2)
+	assert.True(t, isInterfaceIPConfigInApplicationSecurityGroup(interfaceIPConfig, asgID))
+
+	vmssIPConfig := &compute.VirtualMachineScaleSetIPConfiguration{
+		VirtualMachineScaleSetIPConfigurationProperties: &compute.VirtualMachineScaleSetIPConfigurationProperties{},
+	}
+	assert.True(t, isVMSSIPConfigInApplicationSecurityGroup(vmssIPConfig, ""))
+	assert.False(t, isVMSSIPConfigInApplicationSecurityGroup(vmssIPConfig, asgID))
+	addApplicationSecurityGroupToVMSSIPConfig(vmssIPConfig, asgID)
+	addApplicationSecurityGroupToVMSSIPConfig(vmssIPConfig, asgID)
+	assert.Equal(t, []compute.SubResource{{ID: ptr.To(asgID)}}, *vmssIPConfig.ApplicationSecurityGroups)
+}
+
+func TestDeleteApplicationSecurityGroup(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	az := GetTestCloud(ctrl)
+	mockASGRepo := az.asgRepo.(*applicationsecuritygroup.MockRepository)
+
+	// Nothing is deleted if the application security group does not exist.
+	mockASGRepo.EXPECT().Get(gomock.Any(), "kubernetes", gomock.Any()).Return(nil, nil)
+	assert.NoError(t, az.deleteApplicationSecurityGroup(context.TODO(), "kubernetes"))
+
+	mockASGRepo.EXPECT().Get(gomock.Any(), "kubernetes", gomock.Any()).Return(&armnetwork.ApplicationSecurityGroup{Name: ptr.To("kubernetes")}, nil)
+	mockASGRepo.EXPECT().Delete(gomock.Any(), "kubernetes").Return(nil)
+	assert.NoError(t, az.deleteApplicationSecurityGroup(context.TODO(), "kubernetes"))
+}
+
+func TestRemoveApplicationSecurityGroupsFromIPConfig(t *testing.T) {
+	lbPrefix := "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/"
+	asgPrefix := "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/applicationSecurityGroups/"
+	assert.Equal(t, asgPrefix+"kubernetes", getApplicationSecurityGroupIDOfBackendPool(lbPrefix+"kubernetes/backendAddressPools/kubernetes"))
+	assert.Empty(t, getApplicationSecurityGroupIDOfBackendPool("invalid"))
+
+	for _, tc := range []struct {
+		desc          string
+		remainingPool string
+		removedPools  []string
+		expectedASGs  []string
+	}{
+		{
+			desc:         "the application security group of the removed backend pool is removed",
+			removedPools: []string{lbPrefix + "kubernetes/backendAddressPools/kubernetes"},
+			expectedASGs: []string{asgPrefix + "other"},
+		},
+		{
+			desc:          "the application security group is kept if another backend pool of the load balancer remains",
+			remainingPool: lbPrefix + "kubernetes/backendAddressPools/kubernetes-ipv6",
+			removedPools:  []string{lbPrefix + "kubernetes/backendAddressPools/kubernetes"},
+			expectedASGs:  []string{asgPrefix + "kubernetes", asgPrefix + "other"},
+		},
+		{
+			desc:         "the application security groups not created for load balancers are kept",
+			removedPools: []string{lbPrefix + "lb/backendAddressPools/lb"},
+			expectedASGs: []string{asgPrefix + "kubernetes", asgPrefix + "other"},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			var (
+				interfacePools []network.BackendAddressPool
+				interfaceASGs  []network.ApplicationSecurityGroup
+				vmssPools      []compute.SubResource
+				vmssASGs       []compute.SubResource
+			)
+			if tc.remainingPool != "" {
+				interfacePools = append(interfacePools, network.BackendAddressPool{ID: ptr.To(tc.remainingPool)})
+				vmssPools = append(vmssPools, compute.SubResource{ID: ptr.To(tc.remainingPool)})
+			}
+			for _, asgID := range []string{asgPrefix + "kubernetes", asgPrefix + "other"} {
+				interfaceASGs = append(interfaceASGs, network.ApplicationSecurityGroup{ID: ptr.To(asgID)})
+				vmssASGs = append(vmssASGs, compute.SubResource{ID: ptr.To(asgID)})
+			}
+
+			interfaceIPConfig := &network.InterfaceIPConfiguration{
+				InterfaceIPConfigurationPropertiesFormat: &network.InterfaceIPConfigurationPropertiesFormat{
+					LoadBalancerBackendAddressPools: &interfacePools,
+					ApplicationSecurityGroups:       &interfaceASGs,
+				},
+			}
+			removeApplicationSecurityGroupsFromInterfaceIPConfig(interfaceIPConfig, tc.removedPools)
+			var actual []string
+			for _, asg := range *interfaceIPConfig.ApplicationSecurityGroups {
+				actual = append(actual, ptr.Deref(asg.ID, ""))
+			}
+			assert.Equal(t, tc.expectedASGs, actual)
+
+			vmssIPConfig := &compute.VirtualMachineScaleSetIPConfiguration{
+				VirtualMachineScaleSetIPConfigurationProperties: &compute.VirtualMachineScaleSetIPConfigurationProperties{
+					LoadBalancerBackendAddressPools: &vmssPools,
+					ApplicationSecurityGroups:       &vmssASGs,
+				},
+			}
+			removeApplicationSecurityGroupsFromVMSSIPConfig(vmssIPConfig, tc.removedPools)
+			actual = nil
+			for _, asg := range *vmssIPConfig.ApplicationSecurityGroups {
+				actual = append(actual, ptr.Deref(asg.ID, ""))
+			}
+			assert.Equal(t, tc.expectedASGs, actual)
+		})
+	}
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package provider
 
 import (
+	"context"
 	"regexp"
 
 	"k8s.io/apimachinery/pkg/runtime"
@@ -41,9 +42,23 @@ func (az *Cloud) RequestBackoff() (resourceRequestBackoff wait.Backoff) {
 	return resourceRequestBackoff
 }
 
-// Event creates a event for the specified object.
-func (az *Cloud) Event(obj runtime.Object, eventType, reason, message string) {
+// Event creates a event for the specified object. The event is recorded as a note of the plan instead
+// when the reconciliation is planned, so that planning a service has no side effect on the cluster.
+func (az *Cloud) Event(ctx context.Context, obj runtime.Object, eventType, reason, message string) {
+	if planner := getServicePlanner(ctx); planner != nil {
+		if reason != "" {
+			planner.addNote("%s event %s: %s", eventType, reason, message)
+		}
+		return
+	}
 	if obj != nil && reason != "" {
 		az.eventRecorder.Event(obj, eventType, reason, message)
 	}
 }
+
+// eventEmitter returns a function creating the events with the context.
+func (az *Cloud) eventEmitter(ctx context.Context) func(obj runtime.Object, eventType, reason, message string) {
+	return func(obj runtime.Object, eventType, reason, message string) {
+		az.Event(ctx, obj, eventType, reason, message)
+	}
+}
@@ -0,0 +1,344 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	utilnet "k8s.io/utils/net"
+	"k8s.io/utils/ptr"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+	"sigs.k8s.io/cloud-provider-azure/pkg/provider/dnsrecord"
+)
+
+// serviceDNSRecordConfig is the DNS records of a service requested by the service annotations.
+type serviceDNSRecordConfig struct {
+	Zone       dnsrecord.Zone
+	RecordName string
+	TTL        int64
+}
+
+// getServiceDNSRecordConfig returns the DNS records requested by the service annotations,
+// or nil if the service does not request any.
+func getServiceDNSRecordConfig(service *v1.Service) (*serviceDNSRecordConfig, error) {
+	zoneID, found := service.Annotations[consts.ServiceAnnotationDNSZoneID]
+	if !found || strings.TrimSpace(zoneID) == "" {
+		return nil, nil
+	}
+	zone, err := dnsrecord.ParseZoneID(zoneID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse annotation %s: %w", consts.ServiceAnnotationDNSZoneID, err)
+	}
+	recordName := strings.TrimSpace(service.Annotations[consts.ServiceAnnotationDNSRecordName])
+	if recordName == "" {
+		return nil, fmt.Errorf("annotation %s is required if annotation %s is set", consts.ServiceAnnotationDNSRecordName, consts.ServiceAnnotationDNSZoneID)
+	}
+	config := &serviceDNSRecordConfig{
+		Zone:       zone,
+		RecordName: recordName,
+		TTL:        consts.DNSRecordDefaultTTL,
+	}
+	if ttl, found := service.Annotations[consts.ServiceAnnotationDNSRecordTTL]; found {
+		if config.TTL, err = strconv.ParseInt(strings.TrimSpace(ttl), 10, 64); err != nil || config.TTL <= 0 {
+			return nil, fmt.Errorf("failed to parse annotation %s: %q is not a positive integer", consts.ServiceAnnotationDNSRecordTTL, ttl)
+		}
+	}
+	return config, nil
+}
+
+// isDNSRecordSetOwnedByService returns true if the record set is tagged with the service and the cluster.
+func isDNSRecordSetOwnedByService(recordSet *dnsrecord.RecordSet, serviceName, clusterName string) bool {
+	return strings.EqualFold(ptr.Deref(recordSet.Metadata[consts.DNSRecordServiceKey], ""), serviceName) &&
+		strings.EqualFold(ptr.Deref(recordSet.Metadata[consts.DNSRecordClusterNameKey], ""), clusterName)
+}
+
+// isDNSRecordSetUpToDate returns true if the existing record set has the TTL, the IPs and the owner of the expected one.
+func isDNSRecordSetUpToDate(existing, expected *dnsrecord.RecordSet) bool {
+	if existing.TTL != expected.TTL || len(existing.IPs) != len(expected.IPs) {
+		return false
+	}
+	existingIPs := append([]string{}, existing.IPs...)
+	sort.Strings(existingIPs)
+	for i := range existingIPs {
+		if existingIPs[i] != expected.IPs[i] {
+			return false
+		}
+	}
+	for key, value := range expected.Metadata {
+		if !strings.EqualFold(ptr.Deref(existing.Metadata[key], ""), ptr.Deref(value, "")) {
+			return false
+		}
+	}
+	return true
+}
+
+// getLoadBalancerIngressIPsByDNSRecordType returns the sorted ingress IPs of the load balancer status by the DNS record type.
+func getLoadBalancerIngressIPsByDNSRecordType(lbStatus *v1.LoadBalancerStatus) map[dnsrecord.RecordType][]string {
+	ips := make(map[dnsrecord.RecordType][]string)
+	if lbStatus == nil {
+		return ips
+	}
+	for _, ingress := range lbStatus.Ingress {
+		if ingress.IP == "" {
+			continue
+		}
+		recordType := dnsrecord.RecordTypeA
+		if utilnet.IsIPv6String(ingress.IP) {
+			recordType = dnsrecord.RecordTypeAAAA
+		}
+		ips[recordType] = append(ips[recordType], ingress.IP)
+	}
+	for recordType := range ips {
+		sort.Strings(ips[recordType])
+	}
+	return ips
+}
+
+// getServiceOwnedDNSRecords returns the DNS records recorded in the owned annotation of the service,
+// or nil if the annotation is not set or invalid.
+func getServiceOwnedDNSRecords(service *v1.Service) *serviceDNSRecordConfig {
+	value := strings.TrimSpace(service.Annotations[consts.ServiceAnnotationDNSRecordOwned])
+	i := strings.LastIndex(value, "/")
+	if i <= 0 || i == len(value)-1 {
+		if value != "" {
+			klog.Warningf("getServiceOwnedDNSRecords: ignoring the invalid annotation %s %q of service %s", consts.ServiceAnnotationDNSRecordOwned, value, getServiceName(service))
+		}
+		return nil
+	}
+	zone, err := dnsrecord.ParseZoneID(value[:i])
+	if err != nil {
+		klog.Warningf("getServiceOwnedDNSRecords: ignoring the invalid annotation %s of service %s: %v", consts.ServiceAnnotationDNSRecordOwned, getServiceName(service), err)
+		return nil
+	}
+	return &serviceDNSRecordConfig{Zone: zone, RecordName: value[i+1:]}
+}
+
+// ownedAnnotationValue returns the value of the owned annotation for the DNS records.
+func (c *serviceDNSRecordConfig) ownedAnnotationValue() string {
+	if c == nil {
+		return ""
+	}
+	return c.Zone.String() + "/" + c.RecordName
+}
+
+// isSameRecords returns true if both configs point to the same records, regardless of the TTL.
+func (c *serviceDNSRecordConfig) isSameRecords(other *serviceDNSRecordConfig) bool {
+	return strings.EqualFold(c.ownedAnnotationValue(), other.ownedAnnotationValue())
+}
+
+// reconcileDNSRecords ensures the A and AAAA records of the service in the DNS zone requested by the service
+// annotations point to the ingress IPs of the load balancer, or deletes them if wantLb is false.
+// The records are tagged with the service and the cluster, and the records owned by others are never changed.
+// The zone and the name of the records are written back to the service, so the records in the previous zone
+// are cleaned up when the annotations are changed or removed.
+func (az *Cloud) reconcileDNSRecords(ctx context.Context, clusterName string, service *v1.Service, lbStatus *v1.LoadBalancerStatus, wantLb bool) error {
+	if p := getServicePlanner(ctx); p != nil {
+		if config, _ := getServiceDNSRecordConfig(service); config != nil || getServiceOwnedDNSRecords(service) != nil {
+			p.addNote("the DNS records of the service are not planned")
+		}
+		return nil
+	}
+
+	if !wantLb {
+		az.cleanupDNSRecords(ctx, clusterName, service)
+		return nil
+	}
+
+	config, err := getServiceDNSRecordConfig(service)
+	if err != nil {
+		return err
+	}
+	owned := getServiceOwnedDNSRecords(service)
+	if config == nil && owned == nil {
+		return nil
+	}
+	if az.dnsRecordClient == nil {
+		return errors.New("the DNS records of the service cannot be managed without the credentials of the cloud provider")
+	}
+
+	if owned != nil && (config == nil || !owned.isSameRecords(config)) {
+		if err := az.deleteServiceDNSRecords(ctx, clusterName, service, owned); err != nil {
+			return err
+		}
+	}
+	if config != nil {
+		if err := az.ensureServiceDNSRecords(ctx, clusterName, service, config, lbStatus); err != nil {
+			return err
+		}
+	}
+	return az.updateDNSRecordOwnedAnnotation(ctx, service, config.ownedAnnotationValue())
+}
+
+// cleanupDNSRecords deletes the DNS records owned by the service, both in the zone requested by the service
+// annotations and in the zone written back to the service. It is best-effort: the errors are only logged and
+// reported as events, so that an invalid annotation or an unavailable client never blocks the service deletion.
+func (az *Cloud) cleanupDNSRecords(ctx context.Context, clusterName string, service *v1.Service) {
+	serviceName := getServiceName(service)
+	var configs []*serviceDNSRecordConfig
+	config, err := getServiceDNSRecordConfig(service)
+	if err != nil {
+		klog.Warningf("cleanupDNSRecords for service(%s): skipping the DNS records requested by the annotations: %v", serviceName, err)
+	} else if config != nil {
+		configs = append(configs, config)
+	}
+	if owned := getServiceOwnedDNSRecords(service); owned != nil && (config == nil || !owned.isSameRecords(config)) {
+		configs = append(configs, owned)
+	}
+	if len(configs) == 0 {
+		return
+	}
+	if az.dnsRecordClient == nil {
+		klog.Warningf("cleanupDNSRecords for service(%s): skipping the DNS records because the cloud provider has no credentials to manage them", serviceName)
+		return
+	}
+
+	for _, config := range configs {
+		if err := az.deleteServiceDNSRecords(ctx, clusterName, service, config); err != nil {
+			klog.Errorf("cleanupDNSRecords for service(%s): failed to delete the DNS records: %v", serviceName, err)
+			az.Event(ctx, service, v1.EventTypeWarning, "DNSRecordCleanupFailed",
+				fmt.Sprintf("Failed to delete the DNS record %q in zone %s, and it must be deleted manually: %v", config.RecordName, config.Zone.Name, err))
+		}
+	}
+	if err := az.updateDNSRecordOwnedAnnotation(ctx, service, ""); err != nil {
+		klog.Errorf("cleanupDNSRecords for service(%s): %v", serviceName, err)
+	}
+}
+
+// deleteServiceDNSRecords deletes the A and AAAA records owned by the service. The records owned by others are skipped.
+func (az *Cloud) deleteServiceDNSRecords(ctx context.Context, clusterName string, service *v1.Service, config *serviceDNSRecordConfig) error {
+	serviceName := getServiceName(service)
+	for _, recordType := range []dnsrecord.RecordType{dnsrecord.RecordTypeA, dnsrecord.RecordTypeAAAA} {
+		existing, err := az.dnsRecordClient.Get(ctx, config.Zone, recordType, config.RecordName)
+		if err != nil {
+			return err
+		}
+		if existing == nil || !isDNSRecordSetOwnedByService(existing, serviceName, clusterName) {
+			continue
+		}
+		klog.V(2).Infof("deleteServiceDNSRecords for service(%s): deleting the %s record %q in zone %s", serviceName, recordType, config.RecordName, config.Zone)
+		if err := az.dnsRecordClient.Delete(ctx, config.Zone, recordType, config.RecordName, existing.Etag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureServiceDNSRecords ensures the A and AAAA records of the service point to the ingress IPs of the load balancer.
+// The records are created only if they do not exist, and updated only if they are not changed since they are read.
+func (az *Cloud) ensureServiceDNSRecords(ctx context.Context, clusterName string, service *v1.Service, config *serviceDNSRecordConfig, lbStatus *v1.LoadBalancerStatus) error {
+	serviceName := getServiceName(service)
+	ipsByType := getLoadBalancerIngressIPsByDNSRecordType(lbStatus)
+	for _, recordType := range []dnsrecord.RecordType{dnsrecord.RecordTypeA, dnsrecord.RecordTypeAAAA} {
+		ips := ipsByType[recordType]
+		existing, err := az.dnsRecordClient.Get(ctx, config.Zone, recordType, config.RecordName)
+		if err != nil {
+			return err
+		}
+		if existing != nil && !isDNSRecordSetOwnedByService(existing, serviceName, clusterName) {
+			if len(ips) == 0 {
+				continue
+			}
+			message := fmt.Sprintf("The %s record %q in the DNS zone %s is not owned by the service, and it will not be changed",
+				recordType, config.RecordName, config.Zone.Name)
+			az.Event(ctx, service, v1.EventTypeWarning, "DNSRecordConflict", message)
+			return errors.New(message)
+		}
+
+		if len(ips) == 0 {
+			if existing != nil {
+				klog.V(2).Infof("ensureServiceDNSRecords for service(%s): deleting the %s record %q in zone %s", serviceName, recordType, config.RecordName, config.Zone)
+				if err := az.dnsRecordClient.Delete(ctx, config.Zone, recordType, config.RecordName, existing.Etag); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		expected := &dnsrecord.RecordSet{
+			Name: config.RecordName,
+			Type: recordType,
+			TTL:  config.TTL,
+			IPs:  ips,
+			Metadata: map[string]*string{
+				consts.DNSRecordServiceKey:     ptr.To(serviceName),
+				consts.DNSRecordClusterNameKey: ptr.To(clusterName),
+			},
+		}
+		if existing != nil {
+			if isDNSRecordSetUpToDate(existing, expected) {
+				continue
+			}
+			// keep the metadata added by others
+			for key, value := range existing.Metadata {
+				if _, found := expected.Metadata[key]; !found {
+					expected.Metadata[key] = value
+				}
+			}
+			expected.Etag = existing.Etag
+		}
+		klog.V(2).Infof("ensureServiceDNSRecords for service(%s): updating the %s record %q in zone %s to %v", serviceName, recordType, config.RecordName, config.Zone, ips)
+		if err := az.dnsRecordClient.CreateOrUpdate(ctx, config.Zone, *expected); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateDNSRecordOwnedAnnotation patches the owned DNS records annotation of the service.
+// The annotation is removed if the value is empty.
+func (az *Cloud) updateDNSRecordOwnedAnnotation(ctx context.Context, service *v1.Service, value string) error {
+	if current, found := service.Annotations[consts.ServiceAnnotationDNSRecordOwned]; current == value && (found || value == "") {
+		return nil
+	}
+	if az.KubeClient == nil {
+		klog.V(2).Infof("updateDNSRecordOwnedAnnotation: az.KubeClient is nil, skip updating service %s", getServiceName(service))
+		return nil
+	}
+
+	var annotationValue interface{}
+	if value != "" {
+		annotationValue = value
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				consts.ServiceAnnotationDNSRecordOwned: annotationValue,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	klog.V(2).Infof("updateDNSRecordOwnedAnnotation: updating the owned DNS records of service %s to %q", getServiceName(service), value)
+	_, err = az.KubeClient.CoreV1().Services(service.Namespace).Patch(ctx, service.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to update the owned DNS records of service %s: %w", getServiceName(service), err)
+	}
+	return nil
+}
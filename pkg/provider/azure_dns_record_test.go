@@ -0,0 +1,326 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+	"sigs.k8s.io/cloud-provider-azure/pkg/provider/dnsrecord"
+)
+
+const (
+	testPrivateDNSZoneID = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/contoso.internal"
+	testPublicDNSZoneID  = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/dnszones/contoso.com"
+)
+
+func TestGetServiceDNSRecordConfig(t *testing.T) {
+	privateZone := dnsrecord.Zone{SubscriptionID: "sub", ResourceGroup: "rg", Name: "contoso.internal", Private: true}
+	for _, tc := range []struct {
+		desc           string
+		annotations    map[string]string
+		expectedConfig *serviceDNSRecordConfig
+		expectedErr    string
+	}{
+		{
+			desc: "no DNS zone",
+		},
+		{
+			desc: "default TTL",
+			annotations: map[string]string{
+				consts.ServiceAnnotationDNSZoneID:     testPrivateDNSZoneID,
+				consts.ServiceAnnotationDNSRecordName: "app",
+			},
+			expectedConfig: &serviceDNSRecordConfig{Zone: privateZone, RecordName: "app", TTL: consts.DNSRecordDefaultTTL},
+		},
+		{
+			desc: "custom TTL",
+			annotations: map[string]string{
+				consts.ServiceAnnotationDNSZoneID:     testPrivateDNSZoneID,
+				consts.ServiceAnnotationDNSRecordName: "app",
+				consts.ServiceAnnotationDNSRecordTTL:  "60",
+			},
+			expectedConfig: &serviceDNSRecordConfig{Zone: privateZone, RecordName: "app", TTL: 60},
+		},
+		{
+			desc: "missing record name",
+			annotations: map[string]string{
+				consts.ServiceAnnotationDNSZoneID: testPrivateDNSZoneID,
+			},
+			expectedErr: "is required",
+		},
+		{
+			desc: "invalid zone ID",
+			annotations: map[string]string{
+				consts.ServiceAnnotationDNSZoneID:     "contoso.internal",
+				consts.ServiceAnnotationDNSRecordName: "app",
+			},
+			expectedErr: "invalid DNS zone ID",
+		},
+		{
+			desc: "invalid TTL",
+			annotations: map[string]string{
+				consts.ServiceAnnotationDNSZoneID:     testPrivateDNSZoneID,
+				consts.ServiceAnnotationDNSRecordName: "app",
+				consts.ServiceAnnotationDNSRecordTTL:  "0",
+			},
+			expectedErr: "is not a positive integer",
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			service := getTestService("test", v1.ProtocolTCP, tc.annotations, false, 80)
+			config, err := getServiceDNSRecordConfig(&service)
+			if tc.expectedErr != "" {
+				assert.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedConfig, config)
+		})
+	}
+}
+
+func TestReconcileDNSRecords(t *testing.T) {
+	zone, _ := dnsrecord.ParseZoneID(testPrivateDNSZoneID)
+	oldZone, _ := dnsrecord.ParseZoneID(testPublicDNSZoneID)
+	owner := map[string]*string{
+		consts.DNSRecordServiceKey:     ptr.To("default/test"),
+		consts.DNSRecordClusterNameKey: ptr.To(testClusterName),
+	}
+	owned := testPrivateDNSZoneID + "/app"
+	dualStackStatus := &v1.LoadBalancerStatus{Ingress: []v1.LoadBalancerIngress{{IP: "10.0.0.5"}, {IP: "fd00::5"}}}
+	ipv4Status := &v1.LoadBalancerStatus{Ingress: []v1.LoadBalancerIngress{{IP: "10.0.0.5"}}}
+
+	for _, tc := range []struct {
+		desc          string
+		annotations   map[string]string
+		lbStatus      *v1.LoadBalancerStatus
+		wantLb        bool
+		nilClient     bool
+		setup         func(client *dnsrecord.MockClientMockRecorder)
+		expectedErr   string
+		expectedOwned string
+	}{
+		{
+			desc:     "the records are created",
+			lbStatus: dualStackStatus,
+			wantLb:   true,
+			setup: func(client *dnsrecord.MockClientMockRecorder) {
+				client.Get(gomock.Any(), zone, dnsrecord.RecordTypeA, "app").Return(nil, nil)
+				client.Get(gomock.Any(), zone, dnsrecord.RecordTypeAAAA, "app").Return(nil, nil)
+				client.CreateOrUpdate(gomock.Any(), zone, dnsrecord.RecordSet{
+					Name: "app", Type: dnsrecord.RecordTypeA, TTL: 300, IPs: []string{"10.0.0.5"}, Metadata: owner,
+				}).Return(nil)
+				client.CreateOrUpdate(gomock.Any(), zone, dnsrecord.RecordSet{
+					Name: "app", Type: dnsrecord.RecordTypeAAAA, TTL: 300, IPs: []string{"fd00::5"}, Metadata: owner,
+				}).Return(nil)
+			},
+			expectedOwned: owned,
+		},
+		{
+			desc:     "the records are up to date, and the stale AAAA record is deleted",
+			lbStatus: ipv4Status,
+			wantLb:   true,
+			setup: func(client *dnsrecord.MockClientMockRecorder) {
+				client.Get(gomock.Any(), zone, dnsrecord.RecordTypeA, "app").Return(&dnsrecord.RecordSet{
+					Name: "app", Type: dnsrecord.RecordTypeA, TTL: 300, IPs: []string{"10.0.0.5"}, Metadata: owner, Etag: "etag-a",
+				}, nil)
+				client.Get(gomock.Any(), zone, dnsrecord.RecordTypeAAAA, "app").Return(&dnsrecord.RecordSet{
+					Name: "app", Type: dnsrecord.RecordTypeAAAA, TTL: 300, IPs: []string{"fd00::5"}, Metadata: owner, Etag: "etag-aaaa",
+				}, nil)
+				client.Delete(gomock.Any(), zone, dnsrecord.RecordTypeAAAA, "app", "etag-aaaa").Return(nil)
+			},
+			expectedOwned: owned,
+		},
+		{
+			desc:     "the record is updated with its etag and keeps the metadata of others",
+			lbStatus: ipv4Status,
+			wantLb:   true,
+			setup: func(client *dnsrecord.MockClientMockRecorder) {
+				client.Get(gomock.Any(), zone, dnsrecord.RecordTypeA, "app").Return(&dnsrecord.RecordSet{
+					Name: "app", Type: dnsrecord.RecordTypeA, TTL: 300, IPs: []string{"10.0.0.4"}, Etag: "etag-a",
+					Metadata: map[string]*string{
+						consts.DNSRecordServiceKey:     ptr.To("default/test"),
+						consts.DNSRecordClusterNameKey: ptr.To(testClusterName),
+						"team":                         ptr.To("networking"),
+					},
+				}, nil)
+				client.Get(gomock.Any(), zone, dnsrecord.RecordTypeAAAA, "app").Return(nil, nil)
+				client.CreateOrUpdate(gomock.Any(), zone, dnsrecord.RecordSet{
+					Name: "app", Type: dnsrecord.RecordTypeA, TTL: 300, IPs: []string{"10.0.0.5"}, Etag: "etag-a",
+					Metadata: map[string]*string{
+						consts.DNSRecordServiceKey:     ptr.To("default/test"),
+						consts.DNSRecordClusterNameKey: ptr.To(testClusterName),
+						"team":                         ptr.To("networking"),
+					},
+				}).Return(nil)
+			},
+			expectedOwned: owned,
+		},
+		{
+			desc:     "the record owned by another service is not changed",
+			lbStatus: ipv4Status,
+			wantLb:   true,
+			setup: func(client *dnsrecord.MockClientMockRecorder) {
+				client.Get(gomock.Any(), zone, dnsrecord.RecordTypeA, "app").Return(&dnsrecord.RecordSet{
+					Name: "app", Type: dnsrecord.RecordTypeA, TTL: 300, IPs: []string{"10.0.0.4"},
+					Metadata: map[string]*string{
+						consts.DNSRecordServiceKey:     ptr.To("default/other"),
+						consts.DNSRecordClusterNameKey: ptr.To(testClusterName),
+					},
+				}, nil)
+			},
+			expectedErr: "is not owned by the service",
+		},
+		{
+			desc: "the owned records in the previous zone are deleted when the annotations are changed",
+			annotations: map[string]string{
+				consts.ServiceAnnotationDNSRecordOwned: testPublicDNSZoneID + "/old",
+			},
+			lbStatus: ipv4Status,
+			wantLb:   true,
+			setup: func(client *dnsrecord.MockClientMockRecorder) {
+				client.Get(gomock.Any(), oldZone, dnsrecord.RecordTypeA, "old").Return(&dnsrecord.RecordSet{
+					Name: "old", Type: dnsrecord.RecordTypeA, TTL: 300, IPs: []string{"10.0.0.5"}, Metadata: owner, Etag: "etag-old",
+				}, nil)
+				client.Get(gomock.Any(), oldZone, dnsrecord.RecordTypeAAAA, "old").Return(nil, nil)
+				client.Delete(gomock.Any(), oldZone, dnsrecord.RecordTypeA, "old", "etag-old").Return(nil)
+				client.Get(gomock.Any(), zone, dnsrecord.RecordTypeA, "app").Return(nil, nil)
+				client.Get(gomock.Any(), zone, dnsrecord.RecordTypeAAAA, "app").Return(nil, nil)
+				client.CreateOrUpdate(gomock.Any(), zone, dnsrecord.RecordSet{
+					Name: "app", Type: dnsrecord.RecordTypeA, TTL: 300, IPs: []string{"10.0.0.5"}, Metadata: owner,
+				}).Return(nil)
+			},
+			expectedOwned: owned,
+		},
+		{
+			desc: "the owned records are deleted when the annotations are removed",
+			annotations: map[string]string{
+				consts.ServiceAnnotationDNSZoneID:      "",
+				consts.ServiceAnnotationDNSRecordOwned: owned,
+			},
+			lbStatus: ipv4Status,
+			wantLb:   true,
+			setup: func(client *dnsrecord.MockClientMockRecorder) {
+				client.Get(gomock.Any(), zone, dnsrecord.RecordTypeA, "app").Return(&dnsrecord.RecordSet{
+					Name: "app", Type: dnsrecord.RecordTypeA, TTL: 300, IPs: []string{"10.0.0.5"}, Metadata: owner, Etag: "etag-a",
+				}, nil)
+				client.Get(gomock.Any(), zone, dnsrecord.RecordTypeAAAA, "app").Return(nil, nil)
+				client.Delete(gomock.Any(), zone, dnsrecord.RecordTypeA, "app", "etag-a").Return(nil)
+			},
+		},
+		{
+			desc: "the owned records are deleted with the service",
+			annotations: map[string]string{
+				consts.ServiceAnnotationDNSRecordOwned: owned,
+			},
+			setup: func(client *dnsrecord.MockClientMockRecorder) {
+				client.Get(gomock.Any(), zone, dnsrecord.RecordTypeA, "app").Return(&dnsrecord.RecordSet{
+					Name: "app", Type: dnsrecord.RecordTypeA, TTL: 300, IPs: []string{"10.0.0.5"}, Metadata: owner, Etag: "etag-a",
+				}, nil)
+				client.Get(gomock.Any(), zone, dnsrecord.RecordTypeAAAA, "app").Return(&dnsrecord.RecordSet{
+					Name: "app", Type: dnsrecord.RecordTypeAAAA, TTL: 300, IPs: []string{"fd00::5"},
+				}, nil)
+				client.Delete(gomock.Any(), zone, dnsrecord.RecordTypeA, "app", "etag-a").Return(nil)
+			},
+		},
+		{
+			desc: "the deletion of the service is not blocked by the failure of deleting the records",
+			annotations: map[string]string{
+				consts.ServiceAnnotationDNSRecordOwned: owned,
+			},
+			setup: func(client *dnsrecord.MockClientMockRecorder) {
+				client.Get(gomock.Any(), zone, dnsrecord.RecordTypeA, "app").Return(nil, errors.New("unavailable"))
+			},
+		},
+		{
+			desc: "the deletion of the service is not blocked by an invalid annotation",
+			annotations: map[string]string{
+				consts.ServiceAnnotationDNSZoneID: "invalid",
+			},
+			setup: func(_ *dnsrecord.MockClientMockRecorder) {},
+		},
+		{
+			desc:      "the deletion of the service is not blocked by the missing client",
+			nilClient: true,
+			setup:     func(_ *dnsrecord.MockClientMockRecorder) {},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			az := GetTestCloud(ctrl)
+			az.eventRecorder = record.NewFakeRecorder(10)
+			client := az.dnsRecordClient.(*dnsrecord.MockClient)
+			tc.setup(client.EXPECT())
+			if tc.nilClient {
+				az.dnsRecordClient = nil
+			}
+			annotations := map[string]string{
+				consts.ServiceAnnotationDNSZoneID:     testPrivateDNSZoneID,
+				consts.ServiceAnnotationDNSRecordName: "app",
+			}
+			for key, value := range tc.annotations {
+				annotations[key] = value
+			}
+			service := getTestService("test", v1.ProtocolTCP, annotations, false, 80)
+			az.KubeClient = fake.NewSimpleClientset(&service)
+
+			err := az.reconcileDNSRecords(context.TODO(), testClusterName, &service, tc.lbStatus, tc.wantLb)
+			if tc.expectedErr != "" {
+				assert.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			updated, err := az.KubeClient.CoreV1().Services(service.Namespace).Get(context.TODO(), service.Name, metav1.GetOptions{})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedOwned, updated.Annotations[consts.ServiceAnnotationDNSRecordOwned])
+		})
+	}
+}
+
+func TestGetServiceOwnedDNSRecords(t *testing.T) {
+	zone, _ := dnsrecord.ParseZoneID(testPrivateDNSZoneID)
+	for _, tc := range []struct {
+		value    string
+		expected *serviceDNSRecordConfig
+	}{
+		{value: ""},
+		{value: "app"},
+		{value: testPrivateDNSZoneID + "/"},
+		{value: "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/publicIPAddresses/pip/app"},
+		{value: testPrivateDNSZoneID + "/app", expected: &serviceDNSRecordConfig{Zone: zone, RecordName: "app"}},
+	} {
+		service := getTestService("test", v1.ProtocolTCP, map[string]string{consts.ServiceAnnotationDNSRecordOwned: tc.value}, false, 80)
+		owned := getServiceOwnedDNSRecords(&service)
+		assert.Equal(t, tc.expected, owned, tc.value)
+		if owned != nil {
+			assert.Equal(t, tc.value, owned.ownedAnnotationValue())
+		}
+	}
+}
@@ -0,0 +1,334 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2022-07-01/network"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+	"sigs.k8s.io/cloud-provider-azure/pkg/metrics"
+)
+
+// driftFieldWholeResource is the field of a drifted resource that is missing or unexpected as a whole.
+const driftFieldWholeResource = "*"
+
+// driftedField is a field of an Azure resource of a service that differs from the desired state.
+type driftedField struct {
+	ResourceType PlanResourceType
+	// Resource is the name of the resource, prefixed by the name of the parent resource for child resources.
+	Resource string
+	// Field is the JSON path of the field, or driftFieldWholeResource.
+	Field string
+}
+
+func (f driftedField) String() string {
+	return fmt.Sprintf("%s %s %s", f.ResourceType, f.Resource, f.Field)
+}
+
+// driftDetector periodically compares the Azure resources of the LoadBalancer services returned by the
+// caches with the desired state of the services, and reports the drifted fields by events and metrics.
+type driftDetector struct {
+	az          *Cloud
+	clusterName string
+	interval    time.Duration
+	// reconcile triggers the reconciliation of the drifted services.
+	reconcile bool
+	// fingerprints are the hashes of the drifted fields of the services in the last detection.
+	fingerprints map[string]string
+}
+
+// StartDriftDetection starts the drift detection of the LoadBalancer services if it is enabled,
+// and stops if the context exits.
+func (az *Cloud) StartDriftDetection(ctx context.Context, clusterName string) {
+	if az.DriftDetectionIntervalInSeconds <= 0 {
+		return
+	}
+	detector := newDriftDetector(az, clusterName, time.Duration(az.DriftDetectionIntervalInSeconds)*time.Second, az.EnableDriftReconciliation)
+	go detector.run(ctx)
+}
+
+func newDriftDetector(az *Cloud, clusterName string, interval time.Duration, reconcile bool) *driftDetector {
+	return &driftDetector{
+		az:           az,
+		clusterName:  clusterName,
+		interval:     interval,
+		reconcile:    reconcile,
+		fingerprints: make(map[string]string),
+	}
+}
+
+// run starts the driftDetector, and stops if the context exits.
+func (d *driftDetector) run(ctx context.Context) {
+	klog.V(2).Info("driftDetector.run: started")
+	err := wait.PollUntilContextCancel(ctx, d.interval, false, func(ctx context.Context) (bool, error) {
+		d.detect(ctx)
+		return false, nil
+	})
+	klog.Infof("driftDetector.run: stopped due to %s", err.Error())
+}
+
+// detect detects the drift of all LoadBalancer services once.
+func (d *driftDetector) detect(ctx context.Context) {
+	if d.az.serviceLister == nil {
+		klog.V(4).Info("driftDetector.detect: the service lister is not initialized, skip")
+		return
+	}
+	services, err := d.az.serviceLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("driftDetector.detect: failed to list services: %v", err)
+		return
+	}
+
+	// the load balancers are listed once for all the services
+	var (
+		lbs      []network.LoadBalancer
+		lbListed bool
+	)
+	detected := sets.New[string]()
+	for _, service := range services {
+		if !d.az.shouldDetectServiceDrift(service) {
+			continue
+		}
+		if !lbListed {
+			if lbs, err = d.az.listPlanLoadBalancers(ctx, nil, d.clusterName); err != nil {
+				klog.Errorf("driftDetector.detect: failed to list the load balancers: %v", err)
+				return
+			}
+			lbListed = true
+		}
+		serviceName := getServiceName(service)
+		detected.Insert(serviceName)
+		service = service.DeepCopy()
+
+		fields, err := d.detectService(ctx, service, lbs)
+		if err != nil {
+			klog.Warningf("driftDetector.detect: failed to detect the drift of service %s: %v", serviceName, err)
+			continue
+		}
+		d.report(ctx, service, fields)
+	}
+
+	// forget the services that are deleted or no longer LoadBalancer services
+	for serviceName := range d.fingerprints {
+		if !detected.Has(serviceName) {
+			metrics.ResetServiceResourceDrift(serviceName)
+			delete(d.fingerprints, serviceName)
+		}
+	}
+}
+
+// shouldDetectServiceDrift checks if the service is a LoadBalancer service owned by the cloud provider that has been reconciled.
+func (az *Cloud) shouldDetectServiceDrift(service *v1.Service) bool {
+	return service.Spec.Type == v1.ServiceTypeLoadBalancer &&
+		service.DeletionTimestamp == nil &&
+		az.OwnsLoadBalancerClass(service.Spec.LoadBalancerClass) &&
+		len(service.Status.LoadBalancer.Ingress) > 0
+}
+
+// detectService returns the drifted fields of the Azure resources of the service with the listed load balancers.
+// The other resources are read from the caches without blocking the reconciliation of services, and only the
+// planning holds serviceReconcileLock, because it dry-runs the reconciliation against the shared state of the cloud.
+func (d *driftDetector) detectService(ctx context.Context, service *v1.Service, lbs []network.LoadBalancer) ([]driftedField, error) {
+	inventory, err := d.az.getPlanInventory(ctx, service, lbs)
+	if err != nil {
+		return nil, err
+	}
+
+	d.az.serviceReconcileLock.Lock()
+	plan, err := d.az.PlanService(ctx, d.clusterName, service, inventory)
+	d.az.serviceReconcileLock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return getDriftedFieldsOfPlan(plan)
+}
+
+// report records the drifted fields of the service by metrics, and by events and the drift
+// annotation if they are changed since the last detection.
+func (d *driftDetector) report(ctx context.Context, service *v1.Service, fields []driftedField) {
+	serviceName := getServiceName(service)
+	metrics.ResetServiceResourceDrift(serviceName)
+	for _, field := range fields {
+		metrics.SetServiceResourceDrift(serviceName, string(field.ResourceType), field.Resource, field.Field)
+	}
+
+	fingerprint := getDriftFingerprint(fields)
+	if d.fingerprints[serviceName] == fingerprint {
+		return
+	}
+	d.fingerprints[serviceName] = fingerprint
+	if len(fields) == 0 {
+		klog.V(2).Infof("driftDetector.report: the Azure resources of service %s are not drifted", serviceName)
+		return
+	}
+
+	for _, field := range fields {
+		d.az.Event(ctx, service, v1.EventTypeWarning, "ResourceDriftDetected", fmt.Sprintf("The field %s of %s %s differs from the desired state", field.Field, field.ResourceType, field.Resource))
+	}
+	klog.Warningf("driftDetector.report: the Azure resources of service %s are drifted: %v", serviceName, fields)
+
+	if d.reconcile {
+		if err := d.triggerReconcile(ctx, service, fingerprint); err != nil {
+			klog.Errorf("driftDetector.report: failed to trigger the reconciliation of service %s: %v", serviceName, err)
+			// retry in the next detection
+			delete(d.fingerprints, serviceName)
+		}
+	}
+}
+
+// triggerReconcile updates the drift annotation of the service, which makes the service controller reconcile the service.
+func (d *driftDetector) triggerReconcile(ctx context.Context, service *v1.Service, fingerprint string) error {
+	if service.Annotations[consts.ServiceAnnotationLoadBalancerDrift] == fingerprint {
+		return nil
+	}
+	if d.az.KubeClient == nil {
+		return fmt.Errorf("az.KubeClient is nil")
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				consts.ServiceAnnotationLoadBalancerDrift: fingerprint,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	klog.V(2).Infof("driftDetector.triggerReconcile: triggering the reconciliation of service %s", getServiceName(service))
+	_, err = d.az.KubeClient.CoreV1().Services(service.Namespace).Patch(ctx, service.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// getDriftFingerprint returns the hash of the drifted fields, or an empty string if there is no drifted field.
+func getDriftFingerprint(fields []driftedField) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	h := sha256.New()
+	for _, field := range fields {
+		h.Write([]byte(field.String()))
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// getDriftedFieldsOfPlan returns the drifted fields of the planned changes, sorted by the resources and the fields.
+// The resources to be created or deleted are reported as a whole.
+func getDriftedFieldsOfPlan(plan *ServicePlan) ([]driftedField, error) {
+	var rv []driftedField
+	for _, change := range plan.Changes {
+		resource := change.Name
+		if change.Parent != "" {
+			resource = change.Parent + "/" + change.Name
+		}
+		if change.Action != PlanActionUpdate {
+			rv = append(rv, driftedField{ResourceType: change.ResourceType, Resource: resource, Field: driftFieldWholeResource})
+			continue
+		}
+
+		// The changes of the load balancer and the security group only contain the tags,
+		// and the changes of their child resources are reported separately.
+		var prefix string
+		if change.ResourceType == PlanResourceTypeLoadBalancer || change.ResourceType == PlanResourceTypeSecurityGroup {
+			if change.Before == nil && change.After == nil {
+				continue
+			}
+			prefix = "tags"
+		}
+		fields, err := diffJSONFields(prefix, change.Before, change.After)
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) == 0 {
+			fields = []string{driftFieldWholeResource}
+		}
+		for _, field := range fields {
+			rv = append(rv, driftedField{ResourceType: change.ResourceType, Resource: resource, Field: field})
+		}
+	}
+
+	sort.Slice(rv, func(i, j int) bool {
+		return rv[i].String() < rv[j].String()
+	})
+	return rv, nil
+}
+
+// diffJSONFields returns the JSON paths of the fields of the desired value that differ from the existing one.
+// Arrays are compared as a whole.
+func diffJSONFields(prefix string, before, after any) ([]string, error) {
+	toJSONValue := func(v any) (any, error) {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		var rv any
+		err = json.Unmarshal(data, &rv)
+		return rv, err
+	}
+	b, err := toJSONValue(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := toJSONValue(after)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []string
+	var walk func(path string, before, after any)
+	walk = func(path string, before, after any) {
+		bm, bok := before.(map[string]any)
+		am, aok := after.(map[string]any)
+		if bok && aok {
+			// The fields not in the desired state, such as the read-only ones, are ignored.
+			keys := make([]string, 0, len(am))
+			for key := range am {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				walk(strings.TrimPrefix(path+"."+key, "."), bm[key], am[key])
+			}
+			return
+		}
+		if !reflect.DeepEqual(before, after) {
+			if path == "" {
+				path = driftFieldWholeResource
+			}
+			fields = append(fields, path)
+		}
+	}
+	walk(prefix, b, a)
+	return fields, nil
+}
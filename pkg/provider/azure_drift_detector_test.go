@@ -0,0 +1,179 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v6"
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2022-07-01/network"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/azclient/securitygroupclient/mock_securitygroupclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/loadbalancerclient/mockloadbalancerclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/publicipclient/mockpublicipclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+)
+
+func TestGetDriftedFieldsOfPlan(t *testing.T) {
+	existingRule := network.LoadBalancingRule{
+		Name: ptr.To("asvc-TCP-80"),
+		ID:   ptr.To("rule-id"),
+		LoadBalancingRulePropertiesFormat: &network.LoadBalancingRulePropertiesFormat{
+			FrontendPort:         ptr.To(int32(80)),
+			BackendPort:          ptr.To(int32(8080)),
+			IdleTimeoutInMinutes: ptr.To(int32(4)),
+			ProvisioningState:    network.ProvisioningStateSucceeded,
+		},
+	}
+	expectedRule := network.LoadBalancingRule{
+		Name: ptr.To("asvc-TCP-80"),
+		LoadBalancingRulePropertiesFormat: &network.LoadBalancingRulePropertiesFormat{
+			FrontendPort:         ptr.To(int32(80)),
+			BackendPort:          ptr.To(int32(80)),
+			IdleTimeoutInMinutes: ptr.To(int32(10)),
+		},
+	}
+	plan := &ServicePlan{
+		Changes: []PlannedChange{
+			{ResourceType: PlanResourceTypeLoadBalancer, Name: "lb", Action: PlanActionUpdate},
+			{
+				ResourceType: PlanResourceTypeLoadBalancingRule,
+				Name:         "asvc-TCP-80",
+				Parent:       "lb",
+				Action:       PlanActionUpdate,
+				Before:       existingRule,
+				After:        expectedRule,
+			},
+			{ResourceType: PlanResourceTypeProbe, Name: "asvc-TCP-80", Parent: "lb", Action: PlanActionCreate},
+			{
+				ResourceType: PlanResourceTypeSecurityGroup,
+				Name:         "nsg",
+				Action:       PlanActionUpdate,
+				Before:       map[string]*string{"foo": ptr.To("bar")},
+				After:        map[string]*string{"foo": ptr.To("bar"), "k8s-azure-cluster-name": ptr.To("kubernetes")},
+			},
+			{ResourceType: PlanResourceTypeSecurityRule, Name: "rule", Parent: "nsg", Action: PlanActionDelete},
+		},
+	}
+
+	fields, err := getDriftedFieldsOfPlan(plan)
+	assert.NoError(t, err)
+	assert.Equal(t, []driftedField{
+		{ResourceType: PlanResourceTypeLoadBalancingRule, Resource: "lb/asvc-TCP-80", Field: "properties.backendPort"},
+		{ResourceType: PlanResourceTypeLoadBalancingRule, Resource: "lb/asvc-TCP-80", Field: "properties.idleTimeoutInMinutes"},
+		{ResourceType: PlanResourceTypeProbe, Resource: "lb/asvc-TCP-80", Field: driftFieldWholeResource},
+		{ResourceType: PlanResourceTypeSecurityGroup, Resource: "nsg", Field: "tags.k8s-azure-cluster-name"},
+		{ResourceType: PlanResourceTypeSecurityRule, Resource: "nsg/rule", Field: driftFieldWholeResource},
+	}, fields)
+}
+
+func TestShouldDetectServiceDrift(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	az := GetTestCloud(ctrl)
+	svc := getTestService("svc", v1.ProtocolTCP, nil, false, 80)
+	assert.False(t, az.shouldDetectServiceDrift(&svc))
+
+	svc.Status.LoadBalancer.Ingress = []v1.LoadBalancerIngress{{IP: "1.2.3.4"}}
+	assert.True(t, az.shouldDetectServiceDrift(&svc))
+
+	svc.Spec.LoadBalancerClass = ptr.To("other")
+	assert.False(t, az.shouldDetectServiceDrift(&svc))
+
+	svc.Spec.LoadBalancerClass = nil
+	az.OwnServicesWithoutLoadBalancerClass = ptr.To(false)
+	assert.False(t, az.shouldDetectServiceDrift(&svc))
+
+	az.OwnServicesWithoutLoadBalancerClass = nil
+	svc.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	assert.False(t, az.shouldDetectServiceDrift(&svc))
+}
+
+func TestDriftDetectorReport(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	az := GetTestCloud(ctrl)
+	recorder := record.NewFakeRecorder(10)
+	az.eventRecorder = recorder
+	svc := getTestService("svc", v1.ProtocolTCP, nil, false, 80)
+	az.KubeClient = fake.NewSimpleClientset(&svc)
+	detector := newDriftDetector(az, "kubernetes", time.Minute, true)
+
+	fields := []driftedField{
+		{ResourceType: PlanResourceTypeLoadBalancingRule, Resource: "lb/asvc-TCP-80", Field: "properties.backendPort"},
+		{ResourceType: PlanResourceTypeProbe, Resource: "lb/asvc-TCP-80", Field: driftFieldWholeResource},
+	}
+	detector.report(context.Background(), &svc, fields)
+	assert.Len(t, recorder.Events, 2)
+	updated, err := az.KubeClient.CoreV1().Services(svc.Namespace).Get(context.Background(), svc.Name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, getDriftFingerprint(fields), updated.Annotations[consts.ServiceAnnotationLoadBalancerDrift])
+
+	// The same drift is not reported again.
+	detector.report(context.Background(), updated, fields)
+	assert.Len(t, recorder.Events, 2)
+
+	// The drift is fixed.
+	detector.report(context.Background(), updated, nil)
+	assert.Len(t, recorder.Events, 2)
+	assert.Equal(t, "", detector.fingerprints[getServiceName(&svc)])
+}
+
+func TestDriftDetectorDetectListsLoadBalancersOnce(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	az := GetTestCloud(ctrl)
+	kubeClient := fake.NewSimpleClientset()
+	for _, name := range []string{"svc1", "svc2"} {
+		svc := getTestService(name, v1.ProtocolTCP, nil, false, 80)
+		svc.Status.LoadBalancer.Ingress = []v1.LoadBalancerIngress{{IP: "1.2.3.4"}}
+		_, _ = kubeClient.CoreV1().Services(svc.Namespace).Create(context.Background(), &svc, metav1.CreateOptions{})
+	}
+	informerFactory := informers.NewSharedInformerFactory(kubeClient, 0)
+	az.serviceLister = informerFactory.Core().V1().Services().Lister()
+	informerFactory.Start(wait.NeverStop)
+	informerFactory.WaitForCacheSync(wait.NeverStop)
+
+	mockLBsClient := az.LoadBalancerClient.(*mockloadbalancerclient.MockInterface)
+	mockLBsClient.EXPECT().List(gomock.Any(), az.Config.ResourceGroup).Return(nil, nil).Times(1)
+	mockPIPsClient := az.PublicIPAddressesClient.(*mockpublicipclient.MockInterface)
+	mockPIPsClient.EXPECT().List(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	securityGroupClient := az.NetworkClientFactory.GetSecurityGroupClient().(*mock_securitygroupclient.MockInterface)
+	securityGroupClient.EXPECT().Get(gomock.Any(), az.ResourceGroup, az.SecurityGroupName).Return(&armnetwork.SecurityGroup{
+		Name:       ptr.To("nsg"),
+		Properties: &armnetwork.SecurityGroupPropertiesFormat{},
+	}, nil).AnyTimes()
+
+	detector := newDriftDetector(az, "kubernetes", time.Minute, false)
+	detector.detect(context.Background())
+	assert.Len(t, detector.fingerprints, 2)
+}
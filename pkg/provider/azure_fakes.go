@@ -42,7 +42,9 @@ import (
 	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/vmssvmclient/mockvmssvmclient"
 	azcache "sigs.k8s.io/cloud-provider-azure/pkg/cache"
 	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+	"sigs.k8s.io/cloud-provider-azure/pkg/provider/applicationsecuritygroup"
 	"sigs.k8s.io/cloud-provider-azure/pkg/provider/config"
+	"sigs.k8s.io/cloud-provider-azure/pkg/provider/dnsrecord"
 	"sigs.k8s.io/cloud-provider-azure/pkg/provider/privatelinkservice"
 	"sigs.k8s.io/cloud-provider-azure/pkg/provider/routetable"
 	"sigs.k8s.io/cloud-provider-azure/pkg/provider/securitygroup"
@@ -106,6 +108,8 @@ func GetTestCloud(ctrl *gomock.Controller) (az *Cloud) {
 		nodeZones:                map[string]*utilsets.IgnoreCaseSet{},
 		nodeInformerSynced:       func() bool { return true },
 		nodeResourceGroups:       map[string]string{},
+		nodeLabels:               map[string]map[string]string{},
+		nodeSubnetNames:          map[string]string{},
 		unmanagedNodes:           utilsets.NewString(),
 		excludeLoadBalancerNodes: utilsets.NewString(),
 		nodePrivateIPs:           map[string]*utilsets.IgnoreCaseSet{},
@@ -144,7 +148,10 @@ func GetTestCloud(ctrl *gomock.Controller) (az *Cloud) {
 	az.LoadBalancerBackendPool = NewMockBackendPool(ctrl)
 
 	az.plsRepo = privatelinkservice.NewMockRepository(ctrl)
+	az.plsConnectionClient = privatelinkservice.NewMockConnectionClient(ctrl)
 	az.routeTableRepo = routetable.NewMockRepository(ctrl)
+	az.asgRepo = applicationsecuritygroup.NewMockRepository(ctrl)
+	az.dnsRecordClient = dnsrecord.NewMockClient(ctrl)
 
 	getter := func(_ context.Context, _ string) (interface{}, error) { return nil, nil }
 	az.storageAccountCache, _ = azcache.NewTimedCache(time.Minute, getter, az.Config.DisableAPICallCache)
@@ -30,7 +30,7 @@ func (az *Cloud) CreateOrUpdateInterface(ctx context.Context, service *v1.Servic
 	klog.V(10).Infof("InterfacesClient.CreateOrUpdate(%s): end", *nic.Name)
 	if rerr != nil {
 		klog.Errorf("InterfacesClient.CreateOrUpdate(%s) failed: %s", *nic.Name, rerr.Error().Error())
-		az.Event(service, v1.EventTypeWarning, "CreateOrUpdateInterface", rerr.Error().Error())
+		az.Event(ctx, service, v1.EventTypeWarning, "CreateOrUpdateInterface", rerr.Error().Error())
 		return rerr.Error()
 	}
 
@@ -36,6 +36,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/tools/cache"
 	cloudprovider "k8s.io/cloud-provider"
 	servicehelpers "k8s.io/cloud-provider/service/helpers"
 	"k8s.io/klog/v2"
@@ -47,6 +48,7 @@ import (
 	"sigs.k8s.io/cloud-provider-azure/pkg/metrics"
 	"sigs.k8s.io/cloud-provider-azure/pkg/provider/config"
 	"sigs.k8s.io/cloud-provider-azure/pkg/provider/loadbalancer"
+	"sigs.k8s.io/cloud-provider-azure/pkg/provider/securitygroup"
 	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
 	"sigs.k8s.io/cloud-provider-azure/pkg/trace"
 	"sigs.k8s.io/cloud-provider-azure/pkg/trace/attributes"
@@ -97,6 +99,11 @@ func (az *Cloud) GetLoadBalancer(ctx context.Context, clusterName string, servic
 	logger := log.FromContextOrBackground(ctx).WithName(Operation).WithValues("service", service.Name)
 	ctx = log.NewContext(ctx, logger)
 
+	if !az.OwnsLoadBalancerClass(service.Spec.LoadBalancerClass) {
+		logger.V(4).Info("The service is not owned by the cloud provider because of its load balancer class, skip")
+		return nil, false, nil
+	}
+
 	existingLBs, err := az.ListLB(ctx, service)
 	if err != nil {
 		return nil, az.existsPip(ctx, clusterName, service), err
@@ -130,6 +137,52 @@ func getPublicIPDomainNameLabel(service *v1.Service) (string, bool) {
 	return "", false
 }
 
+// getGatewayLoadBalancerFrontendIPConfigID returns the ID of the gateway load balancer frontend IP configuration
+// the frontend IP configurations of the service should be chained to, and whether the chaining is managed by the annotation.
+func (az *Cloud) getGatewayLoadBalancerFrontendIPConfigID(service *v1.Service) (string, bool, error) {
+	id, found := service.Annotations[consts.ServiceAnnotationGatewayLoadBalancerFrontendIPConfigID]
+	if !found {
+		return "", false, nil
+	}
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return "", true, nil
+	}
+	if requiresInternalLoadBalancer(service) {
+		return "", false, fmt.Errorf("annotation %s is not supported on internal load balancers", consts.ServiceAnnotationGatewayLoadBalancerFrontendIPConfigID)
+	}
+	if !az.UseStandardLoadBalancer() {
+		return "", false, fmt.Errorf("annotation %s is only supported on standard load balancers", consts.ServiceAnnotationGatewayLoadBalancerFrontendIPConfigID)
+	}
+	if !frontendIPConfigRE.MatchString(id) {
+		return "", false, fmt.Errorf("invalid gateway load balancer frontend IP configuration ID %q in annotation %s", id, consts.ServiceAnnotationGatewayLoadBalancerFrontendIPConfigID)
+	}
+	return id, true, nil
+}
+
+// reconcileFrontendGatewayLoadBalancer chains the frontend IP configuration to the gateway load balancer frontend IP
+// configuration gatewayLBFrontendIPConfigID, or removes the chaining if it is empty. It returns the ID it was chained to
+// before and whether the frontend IP configuration is changed.
+func reconcileFrontendGatewayLoadBalancer(fip *network.FrontendIPConfiguration, gatewayLBFrontendIPConfigID string) (string, bool) {
+	if fip.FrontendIPConfigurationPropertiesFormat == nil {
+		fip.FrontendIPConfigurationPropertiesFormat = &network.FrontendIPConfigurationPropertiesFormat{}
+	}
+	var previous string
+	if fip.GatewayLoadBalancer != nil {
+		previous = ptr.Deref(fip.GatewayLoadBalancer.ID, "")
+	}
+	if strings.EqualFold(previous, gatewayLBFrontendIPConfigID) {
+		return previous, false
+	}
+
+	if gatewayLBFrontendIPConfigID == "" {
+		fip.GatewayLoadBalancer = nil
+	} else {
+		fip.GatewayLoadBalancer = &network.SubResource{ID: ptr.To(gatewayLBFrontendIPConfigID)}
+	}
+	return previous, true
+}
+
 // reconcileService reconcile the LoadBalancer service. It returns LoadBalancerStatus on success.
 func (az *Cloud) reconcileService(ctx context.Context, clusterName string, service *v1.Service, nodes []*v1.Node) (*v1.LoadBalancerStatus, error) {
 	logger := log.FromContextOrBackground(ctx)
@@ -176,9 +229,25 @@ func (az *Cloud) reconcileService(ctx context.Context, clusterName string, servi
 		return nil, err
 	}
 
+	if err := az.reconcileInboundNatPortMappings(ctx, service, lb, true /* wantLb */); err != nil {
+		logger.Error(err, "Failed to reconcile inbound NAT port mappings")
+		return nil, err
+	}
+
+	if err := az.reconcileDNSRecords(ctx, clusterName, service, lbStatus, true /* wantLb */); err != nil {
+		logger.Error(err, "Failed to reconcile DNS records")
+		return nil, err
+	}
+
+	// The backend pools of local services follow the endpoint slices, which are not planned.
+	if p := getServicePlanner(ctx); p != nil {
+		p.loadBalancerName = ptr.Deref(lb.Name, "")
+		return lbStatus, nil
+	}
+
 	lbName := strings.ToLower(ptr.Deref(lb.Name, ""))
 	key := strings.ToLower(getServiceName(service))
-	if az.UseMultipleStandardLoadBalancers() && isLocalService(service) {
+	if az.useServiceBackendPool(service) {
 		az.localServiceNameToServiceInfoMap.Store(key, newServiceInfo(getServiceIPFamily(service), lbName))
 		// There are chances that the endpointslice changes after EnsureHostsInPool, so
 		// need to check endpointslice for a second time.
@@ -209,6 +278,11 @@ func (az *Cloud) EnsureLoadBalancer(ctx context.Context, clusterName string, ser
 	// Here we'll firstly ensure service do not lie in the opposite LB.
 	const Operation = "EnsureLoadBalancer"
 
+	if !az.OwnsLoadBalancerClass(service.Spec.LoadBalancerClass) {
+		klog.V(4).Infof("EnsureLoadBalancer: service %s is not owned by the cloud provider because of its load balancer class, skip", getServiceName(service))
+		return nil, cloudprovider.ImplementedElsewhere
+	}
+
 	ctx, span := trace.BeginReconcile(ctx, trace.DefaultTracer(), Operation, attributes.FeatureOfService(service)...)
 	defer func() { span.Observe(ctx, err) }()
 
@@ -303,6 +377,11 @@ func (az *Cloud) getLatestService(serviceName string, deepcopy bool) (*v1.Servic
 func (az *Cloud) UpdateLoadBalancer(ctx context.Context, clusterName string, service *v1.Service, nodes []*v1.Node) error {
 	const Operation = "UpdateLoadBalancer"
 
+	if !az.OwnsLoadBalancerClass(service.Spec.LoadBalancerClass) {
+		klog.V(4).Infof("UpdateLoadBalancer: service %s is not owned by the cloud provider because of its load balancer class, skip", getServiceName(service))
+		return cloudprovider.ImplementedElsewhere
+	}
+
 	var err error
 	ctx, span := trace.BeginReconcile(ctx, trace.DefaultTracer(), Operation, attributes.FeatureOfService(service)...)
 	defer func() { span.Observe(ctx, err) }()
@@ -405,6 +484,11 @@ func (az *Cloud) UpdateLoadBalancer(ctx context.Context, clusterName string, ser
 func (az *Cloud) EnsureLoadBalancerDeleted(ctx context.Context, clusterName string, service *v1.Service) (err error) {
 	const Operation = "EnsureLoadBalancerDeleted"
 
+	if !az.OwnsLoadBalancerClass(service.Spec.LoadBalancerClass) {
+		klog.V(4).Infof("EnsureLoadBalancerDeleted: service %s is not owned by the cloud provider because of its load balancer class, skip", getServiceName(service))
+		return cloudprovider.ImplementedElsewhere
+	}
+
 	ctx, span := trace.BeginReconcile(ctx, trace.DefaultTracer(), Operation, attributes.FeatureOfService(service)...)
 	defer func() { span.Observe(ctx, err) }()
 
@@ -469,11 +553,20 @@ func (az *Cloud) EnsureLoadBalancerDeleted(ctx context.Context, clusterName stri
 		return err
 	}
 
+	if err = az.reconcileDNSRecords(ctx, clusterName, service, nil, false /* wantLb */); err != nil {
+		return err
+	}
+
 	_, err = az.reconcileSecurityGroup(ctx, clusterName, service, ptr.Deref(lb.Name, ""), lbIPsPrimaryPIPs, false /* wantLb */)
 	if err != nil {
 		return err
 	}
 
+	// The service may have dropped the private link service annotation before it is deleted.
+	if err = az.unbindServiceFromPrivateLinkServices(ctx, clusterName, service, lb); err != nil {
+		return err
+	}
+
 	_, err = az.reconcileLoadBalancer(ctx, clusterName, service, nil, false /* wantLb */)
 	if err != nil && !retry.HasStatusForbiddenOrIgnoredError(err) {
 		return err
@@ -489,7 +582,11 @@ func (az *Cloud) EnsureLoadBalancerDeleted(ctx context.Context, clusterName stri
 		return err
 	}
 
-	if az.UseMultipleStandardLoadBalancers() && isLocalService(service) {
+	if err = az.reconcileInboundNatPortMappings(ctx, service, nil, false /* wantLb */); err != nil {
+		return err
+	}
+
+	if az.useServiceBackendPool(service) {
 		key := strings.ToLower(svcName)
 		az.localServiceNameToServiceInfoMap.Delete(key)
 	}
@@ -713,6 +810,11 @@ func (az *Cloud) cleanOrphanedLoadBalancer(ctx context.Context, lb *network.Load
 
 // safeDeleteLoadBalancer deletes the load balancer after decoupling it from the vmSet
 func (az *Cloud) safeDeleteLoadBalancer(ctx context.Context, lb network.LoadBalancer, _, vmSetName string, service *v1.Service) *retry.Error {
+	// The backend pools and the application security group of the load balancer are not planned.
+	if getServicePlanner(ctx) != nil {
+		return az.DeleteLB(ctx, service, ptr.Deref(lb.Name, ""))
+	}
+
 	lbBackendPoolIDsToDelete := []string{}
 	if lb.LoadBalancerPropertiesFormat != nil && lb.BackendAddressPools != nil {
 		for _, bp := range *lb.BackendAddressPools {
@@ -723,6 +825,14 @@ func (az *Cloud) safeDeleteLoadBalancer(ctx context.Context, lb network.LoadBala
 		return retry.NewError(false, fmt.Errorf("safeDeleteLoadBalancer: failed to EnsureBackendPoolDeleted: %w", err))
 	}
 
+	// The application security group is deleted before the load balancer, so it is retried with the load balancer
+	// if it is still referenced by the network interfaces.
+	if az.UseApplicationSecurityGroups {
+		if err := az.deleteApplicationSecurityGroup(ctx, ptr.Deref(lb.Name, "")); err != nil {
+			return retry.NewError(false, fmt.Errorf("safeDeleteLoadBalancer: failed to delete the application security group: %w", err))
+		}
+	}
+
 	klog.V(2).Infof("safeDeleteLoadBalancer: deleting LB %s", ptr.Deref(lb.Name, ""))
 	if rerr := az.DeleteLB(ctx, service, ptr.Deref(lb.Name, "")); rerr != nil {
 		return rerr
@@ -822,17 +932,18 @@ func (az *Cloud) getServiceLoadBalancer(
 				removeLBFromList(existingLBs, deletedLBName)
 			}
 			az.reconcileMultipleStandardLoadBalancerConfigurationStatus(
+				ctx,
 				false,
 				getServiceName(service),
 				ptr.Deref(existingLB.Name, ""),
 			)
 
-			if isLocalService(service) && az.UseMultipleStandardLoadBalancers() {
+			if az.useServiceBackendPool(service) {
 				// No need for the endpoint slice informer to update the backend pool
 				// for the service because the main loop will delete the old backend pool
 				// and create a new one in the new load balancer.
 				svcName := getServiceName(service)
-				if az.backendPoolUpdater != nil {
+				if az.backendPoolUpdater != nil && getServicePlanner(ctx) == nil {
 					az.backendPoolUpdater.removeOperation(svcName)
 				}
 
@@ -866,7 +977,9 @@ func (az *Cloud) getServiceLoadBalancer(
 	}
 
 	// If the service moves to a different load balancer, return the one
-	// instead of creating a new load balancer if it exists.
+	// instead of creating a new load balancer if it exists. The status of
+	// the previous load balancer is returned in both cases so that the
+	// private IP address of the internal service is kept.
 	if shouldChangeLB {
 		for _, existingLB := range *existingLBs {
 			if strings.EqualFold(ptr.Deref(existingLB.Name, ""), defaultLBName) {
@@ -898,8 +1011,11 @@ func (az *Cloud) getServiceLoadBalancer(
 			}
 		}
 	}
+	if !shouldChangeLB {
+		status, lbIPsPrimaryPIPs = nil, nil
+	}
 
-	return defaultLB, existingLBs, nil, nil, false, nil
+	return defaultLB, existingLBs, status, lbIPsPrimaryPIPs, false, nil
 }
 
 // selectLoadBalancer selects load balancer for the service in the cluster.
@@ -1120,21 +1236,123 @@ func (az *Cloud) ensurePublicIPExists(ctx context.Context, service *v1.Service,
 		return nil, err
 	}
 	serviceName := getServiceName(service)
+
+	if existsPip {
+		az.reportPublicIPSettingsRequiringRecreation(ctx, service, &pip, clusterName)
+	}
+
+	pip, changed, usingDNSLabel, err := az.getExpectedPublicIP(service, pip, existsPip, pipName, domainNameLabel, clusterName, shouldPIPExisted, foundDNSLabelAnnotation, isIPv6)
+	if err != nil {
+		return nil, err
+	}
+
+	if usingDNSLabel {
+		if changed {
+			klog.V(2).Infof("ensurePublicIPExists: updating the PIP %s for the incoming service %s", pipName, serviceName)
+			err = az.CreateOrUpdatePIP(ctx, service, pipResourceGroup, pip)
+			if err != nil {
+				return nil, err
+			}
+			pip, err = az.getUpdatedPublicIPAddress(ctx, pipResourceGroup, *pip.Name)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return &pip, nil
+	}
+
+	// skip adding zone info since edge zones doesn't support multiple availability zones.
+	if !existsPip && az.UseStandardLoadBalancer() && !az.HasExtendedLocation() {
+		// only add zone information for the new standard pips
+		zones, err := az.getRegionZonesBackoff(ctx, ptr.Deref(pip.Location, ""))
+		if err != nil {
+			return nil, err
+		}
+		if len(zones) > 0 {
+			pip.Zones = &zones
+		}
+	}
+
+	if !existsPip && pip.PublicIPPrefix == nil && az.usePublicIPPrefixPool() {
+		if p := getServicePlanner(ctx); p != nil {
+			p.addNote("the public IP prefix of the new public IP %s is allocated from the pool on creation, it is not planned", pipName)
+		} else {
+			prefix, err := az.allocatePublicIPPrefixFromPool(ctx, clusterName, isIPv6, pip.Zones)
+			if err != nil {
+				return nil, fmt.Errorf("ensurePublicIPExists for service(%s): failed to allocate pip(%s) from the public IP prefix pool: %w", serviceName, pipName, err)
+			}
+			klog.V(2).Infof("ensurePublicIPExists for service(%s): pip(%s) - allocating from public IP prefix %s", serviceName, pipName, ptr.Deref(prefix.ID, ""))
+			pip.PublicIPPrefix = &network.SubResource{ID: prefix.ID}
+			// the public IP must be in the zones of the prefix
+			pip.Zones = nil
+			if len(prefix.Zones) > 0 {
+				zones := make([]string, 0, len(prefix.Zones))
+				for _, zone := range prefix.Zones {
+					zones = append(zones, ptr.Deref(zone, ""))
+				}
+				pip.Zones = &zones
+			}
+		}
+	}
+
+	if changed {
+		klog.V(2).Infof("CreateOrUpdatePIP(%s, %q): start", pipResourceGroup, *pip.Name)
+		err = az.CreateOrUpdatePIP(ctx, service, pipResourceGroup, pip)
+		if err != nil {
+			klog.V(2).Infof("ensure(%s) abort backoff: pip(%s)", serviceName, *pip.Name)
+			return nil, err
+		}
+
+		klog.V(10).Infof("CreateOrUpdatePIP(%s, %q): end", pipResourceGroup, *pip.Name)
+	}
+
+	pip, err = az.getUpdatedPublicIPAddress(ctx, pipResourceGroup, *pip.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &pip, nil
+}
+
+// getExpectedPublicIP returns the public IP expected by the service based on the existing one
+// without sending any request to Azure. It returns whether the public IP needs to be created or updated,
+// and whether the existing public IP is already serving the DNS label of the service, in which case
+// only the service tags are reconciled. The zones of a new public IP are not resolved here.
+func (az *Cloud) getExpectedPublicIP(
+	service *v1.Service,
+	pip network.PublicIPAddress,
+	existsPip bool,
+	pipName, domainNameLabel, clusterName string,
+	shouldPIPExisted, foundDNSLabelAnnotation, isIPv6 bool,
+) (network.PublicIPAddress, bool, bool, error) {
+	serviceName := getServiceName(service)
 	ipVersion := network.IPv4
 	if isIPv6 {
 		ipVersion = network.IPv6
 	}
 
-	var changed, owns, isUserAssignedPIP bool
+	var (
+		changed, owns, isUserAssignedPIP bool
+		err                              error
+	)
+	settings, err := az.getServicePublicIPSettings(service)
+	if err != nil {
+		return pip, false, false, err
+	}
 	if existsPip {
 		// ensure that the service tag is good for managed pips
 		owns, isUserAssignedPIP = serviceOwnsPublicIP(service, &pip, clusterName)
 		if owns && !isUserAssignedPIP {
 			changed, err = bindServicesToPIP(&pip, []string{serviceName}, false)
 			if err != nil {
-				return nil, err
+				return pip, false, false, err
 			}
 		}
+		// the DDoS settings of the user-assigned public IPs are not managed
+		if !isUserAssignedPIP && reconcileDdosSettings(&pip, settings) {
+			klog.V(2).Infof("ensurePublicIPExists for service(%s): pip(%s) - updating the DDoS settings", serviceName, pipName)
+			changed = true
+		}
 
 		if pip.Tags == nil {
 			pip.Tags = make(map[string]*string)
@@ -1145,21 +1363,7 @@ func (az *Cloud) ensurePublicIPExists(ctx context.Context, service *v1.Service,
 			if existingServiceName := getServiceFromPIPDNSTags(pip.Tags); existingServiceName != "" && strings.EqualFold(existingServiceName, serviceName) {
 				klog.V(6).Infof("ensurePublicIPExists for service(%s): pip(%s) - "+
 					"the service is using the DNS label on the public IP", serviceName, pipName)
-
-				var rerr *retry.Error
-				if changed {
-					klog.V(2).Infof("ensurePublicIPExists: updating the PIP %s for the incoming service %s", pipName, serviceName)
-					err = az.CreateOrUpdatePIP(service, pipResourceGroup, pip)
-					if err != nil {
-						return nil, err
-					}
-					pip, rerr = az.PublicIPAddressesClient.Get(ctx, pipResourceGroup, *pip.Name, "")
-					if rerr != nil {
-						return nil, rerr.Error()
-					}
-				}
-
-				return &pip, nil
+				return pip, changed, true, nil
 			}
 		}
 
@@ -1173,7 +1377,7 @@ func (az *Cloud) ensurePublicIPExists(ctx context.Context, service *v1.Service,
 		}
 	} else {
 		if shouldPIPExisted {
-			return nil, fmt.Errorf("PublicIP from annotation azure-pip-name(-IPv6)=%s for service %s doesn't exist", pipName, serviceName)
+			return pip, false, false, fmt.Errorf("PublicIP from annotation azure-pip-name(-IPv6)=%s for service %s doesn't exist", pipName, serviceName)
 		}
 
 		changed = true
@@ -1194,14 +1398,14 @@ func (az *Cloud) ensurePublicIPExists(ctx context.Context, service *v1.Service,
 		pip.PublicIPAddressPropertiesFormat = &network.PublicIPAddressPropertiesFormat{
 			PublicIPAllocationMethod: network.Static,
 			PublicIPAddressVersion:   ipVersion,
-			IPTags:                   getServiceIPTagRequestForPublicIP(service).IPTags,
+			IPTags:                   withRoutingPreferenceIPTag(getServiceIPTagRequestForPublicIP(service).IPTags, settings.RoutingPreference),
 		}
 		pip.Tags = map[string]*string{
 			consts.ServiceTagKey:  ptr.To(""),
 			consts.ClusterNameKey: &clusterName,
 		}
 		if _, err = bindServicesToPIP(&pip, []string{serviceName}, false); err != nil {
-			return nil, err
+			return pip, false, false, err
 		}
 
 		if az.UseStandardLoadBalancer() {
@@ -1212,18 +1416,7 @@ func (az *Cloud) ensurePublicIPExists(ctx context.Context, service *v1.Service,
 			if id := getServicePIPPrefixID(service, isIPv6); id != "" {
 				pip.PublicIPPrefix = &network.SubResource{ID: ptr.To(id)}
 			}
-
-			// skip adding zone info since edge zones doesn't support multiple availability zones.
-			if !az.HasExtendedLocation() {
-				// only add zone information for the new standard pips
-				zones, err := az.getRegionZonesBackoff(ctx, ptr.Deref(pip.Location, ""))
-				if err != nil {
-					return nil, err
-				}
-				if len(zones) > 0 {
-					pip.Zones = &zones
-				}
-			}
+			reconcileDdosSettings(&pip, settings)
 		}
 		klog.V(2).Infof("ensurePublicIPExists for service(%s): pip(%s) - creating", serviceName, *pip.Name)
 	}
@@ -1234,7 +1427,7 @@ func (az *Cloud) ensurePublicIPExists(ctx context.Context, service *v1.Service,
 	if foundDNSLabelAnnotation {
 		updatedDNSSettings, err := reconcileDNSSettings(&pip, domainNameLabel, serviceName, pipName, isUserAssignedPIP)
 		if err != nil {
-			return nil, fmt.Errorf("ensurePublicIPExists for service(%s): failed to reconcileDNSSettings: %w", serviceName, err)
+			return pip, false, false, fmt.Errorf("ensurePublicIPExists for service(%s): failed to reconcileDNSSettings: %w", serviceName, err)
 		}
 
 		if updatedDNSSettings {
@@ -1249,22 +1442,7 @@ func (az *Cloud) ensurePublicIPExists(ctx context.Context, service *v1.Service,
 		changed = true
 	}
 
-	if changed {
-		klog.V(2).Infof("CreateOrUpdatePIP(%s, %q): start", pipResourceGroup, *pip.Name)
-		err = az.CreateOrUpdatePIP(service, pipResourceGroup, pip)
-		if err != nil {
-			klog.V(2).Infof("ensure(%s) abort backoff: pip(%s)", serviceName, *pip.Name)
-			return nil, err
-		}
-
-		klog.V(10).Infof("CreateOrUpdatePIP(%s, %q): end", pipResourceGroup, *pip.Name)
-	}
-
-	pip, rerr := az.PublicIPAddressesClient.Get(ctx, pipResourceGroup, *pip.Name, "")
-	if rerr != nil {
-		return nil, rerr.Error()
-	}
-	return &pip, nil
+	return pip, changed, false, nil
 }
 
 func (az *Cloud) reconcileIPSettings(pip *network.PublicIPAddress, service *v1.Service, isIPv6 bool) bool {
@@ -1543,6 +1721,7 @@ func (az *Cloud) isFrontendIPChanged(
 // loadBalancing resources, including loadBalancing rules, outbound rules, inbound NAT rules
 // and inbound NAT pools.
 func (az *Cloud) isFrontendIPConfigUnsafeToDelete(
+	ctx context.Context,
 	lb *network.LoadBalancer,
 	service *v1.Service,
 	fipConfigID *string,
@@ -1584,22 +1763,25 @@ func (az *Cloud) isFrontendIPConfigUnsafeToDelete(
 			if !az.serviceOwnsRule(service, *lbRule.Name) {
 				warningMsg := fmt.Sprintf("isFrontendIPConfigUnsafeToDelete: frontend IP configuration with ID %s on LB %s cannot be deleted because it is being referenced by load balancing rules of other services", *fipConfigID, *lb.Name)
 				klog.Warning(warningMsg)
-				az.Event(service, v1.EventTypeWarning, "DeletingFrontendIPConfiguration", warningMsg)
+				az.Event(ctx, service, v1.EventTypeWarning, "DeletingFrontendIPConfiguration", warningMsg)
 				unsafe = true
 				break
 			}
 		}
 	}
 
-	// check if there are outbound rules
-	// referencing this frontend IP configuration
+	// check if there are outbound rules referencing this frontend IP configuration,
+	// except the ones managed for this service which are removed together with it
 	for _, outboundRule := range outboundRules {
+		if az.serviceOwnsOutboundRule(service, ptr.Deref(outboundRule.Name, "")) {
+			continue
+		}
 		if outboundRule.OutboundRulePropertiesFormat != nil && outboundRule.FrontendIPConfigurations != nil {
 			outboundRuleFIPConfigs := *outboundRule.FrontendIPConfigurations
 			if found := findMatchedOutboundRuleFIPConfig(fipConfigID, outboundRuleFIPConfigs); found {
 				warningMsg := fmt.Sprintf("isFrontendIPConfigUnsafeToDelete: frontend IP configuration with ID %s on LB %s cannot be deleted because it is being referenced by the outbound rule %s", *fipConfigID, *lb.Name, *outboundRule.Name)
 				klog.Warning(warningMsg)
-				az.Event(service, v1.EventTypeWarning, "DeletingFrontendIPConfiguration", warningMsg)
+				az.Event(ctx, service, v1.EventTypeWarning, "DeletingFrontendIPConfiguration", warningMsg)
 				unsafe = true
 				break
 			}
@@ -1615,7 +1797,7 @@ func (az *Cloud) isFrontendIPConfigUnsafeToDelete(
 			strings.EqualFold(*inboundNatRule.FrontendIPConfiguration.ID, *fipConfigID) {
 			warningMsg := fmt.Sprintf("isFrontendIPConfigUnsafeToDelete: frontend IP configuration with ID %s on LB %s cannot be deleted because it is being referenced by the inbound NAT rule %s", *fipConfigID, *lb.Name, *inboundNatRule.Name)
 			klog.Warning(warningMsg)
-			az.Event(service, v1.EventTypeWarning, "DeletingFrontendIPConfiguration", warningMsg)
+			az.Event(ctx, service, v1.EventTypeWarning, "DeletingFrontendIPConfiguration", warningMsg)
 			unsafe = true
 			break
 		}
@@ -1630,7 +1812,7 @@ func (az *Cloud) isFrontendIPConfigUnsafeToDelete(
 			strings.EqualFold(*inboundNatPool.FrontendIPConfiguration.ID, *fipConfigID) {
 			warningMsg := fmt.Sprintf("isFrontendIPConfigUnsafeToDelete: frontend IP configuration with ID %s on LB %s cannot be deleted because it is being referenced by the inbound NAT pool %s", *fipConfigID, *lb.Name, *inboundNatPool.Name)
 			klog.Warning(warningMsg)
-			az.Event(service, v1.EventTypeWarning, "DeletingFrontendIPConfiguration", warningMsg)
+			az.Event(ctx, service, v1.EventTypeWarning, "DeletingFrontendIPConfiguration", warningMsg)
 			unsafe = true
 			break
 		}
@@ -1680,7 +1862,8 @@ func (az *Cloud) findFrontendIPConfigsOfService(
 // cloud controller manager restarts or reloads itself. It checks all existing
 // load balancer typed services and add service names to the ActiveServices queue
 // of the corresponding load balancer configuration. It also checks if there is a configuration
-// named <clustername>. If not, an error will be reported.
+// named <clustername>. If not, an error will be reported. The load balancer configurations
+// generated by AutoScale are restored from the ConfigMap.
 func (az *Cloud) reconcileMultipleStandardLoadBalancerConfigurations(
 	ctx context.Context,
 	lbs *[]network.LoadBalancer,
@@ -1693,6 +1876,11 @@ func (az *Cloud) reconcileMultipleStandardLoadBalancerConfigurations(
 		return nil
 	}
 
+	// The configurations are only read when planning.
+	if getServicePlanner(ctx) != nil {
+		return nil
+	}
+
 	if az.multipleStandardLoadBalancerConfigurationsSynced {
 		return nil
 	}
@@ -1713,6 +1901,11 @@ func (az *Cloud) reconcileMultipleStandardLoadBalancerConfigurations(
 		return fmt.Errorf("multiple standard load balancers are enabled but no configuration named %q is found", clusterName)
 	}
 
+	if err := az.recordAutoScaledLoadBalancerConfigurations(ctx, service, existingLBs); err != nil {
+		klog.Errorf("reconcileMultipleStandardLoadBalancerConfigurations: failed to record auto scaled load balancer configurations: %v", err)
+		return fmt.Errorf("failed to record auto scaled load balancer configurations: %w", err)
+	}
+
 	svcs, err := az.KubeClient.CoreV1().Services("").List(ctx, metav1.ListOptions{})
 	if err != nil {
 		klog.Errorf("reconcileMultipleStandardLoadBalancerConfigurations: failed to list all load balancer services: %V", err)
@@ -1778,7 +1971,8 @@ func (az *Cloud) reconcileLoadBalancer(ctx context.Context, clusterName string,
 	// Delete backend pools for local service if:
 	// 1. the cluster is migrating from multi-slb to single-slb,
 	// 2. the service is changed from local to cluster.
-	if !az.UseMultipleStandardLoadBalancers() || !isLocalService(service) {
+	// Services always have their own backend pools if the backend pool type is podIP.
+	if !az.useServiceBackendPool(service) {
 		existingLBs, err = az.cleanupLocalServiceBackendPool(ctx, service, nodes, existingLBs, clusterName)
 		if err != nil {
 			klog.Errorf("reconcileLoadBalancer: failed to cleanup local service backend pool for service %q, error: %s", serviceName, err.Error())
@@ -1791,6 +1985,13 @@ func (az *Cloud) reconcileLoadBalancer(ctx context.Context, clusterName string,
 		return nil, err
 	}
 
+	if wantLb {
+		if err := az.autoScaleMultipleStandardLoadBalancers(ctx, service, existingLBs); err != nil {
+			klog.Errorf("reconcileLoadBalancer: failed to auto scale multiple standard load balancers for service %q: %s", serviceName, err.Error())
+			return nil, err
+		}
+	}
+
 	lb, newLBs, lbStatus, _, _, err := az.getServiceLoadBalancer(ctx, service, clusterName, nodes, wantLb, existingLBs)
 	if err != nil {
 		klog.Errorf("reconcileLoadBalancer: failed to get load balancer for service %q, error: %v", serviceName, err)
@@ -1816,9 +2017,13 @@ func (az *Cloud) reconcileLoadBalancer(ctx context.Context, clusterName string,
 			preConfig, backendPoolsUpdated bool
 			err                            error
 		)
-		preConfig, backendPoolsUpdated, lb, err = az.LoadBalancerBackendPool.ReconcileBackendPools(ctx, clusterName, service, lb)
-		if err != nil {
-			return lb, err
+		if p := getServicePlanner(ctx); p != nil {
+			preConfig, backendPoolsUpdated = az.planBackendPools(p, clusterName, service, lb)
+		} else {
+			preConfig, backendPoolsUpdated, lb, err = az.LoadBalancerBackendPool.ReconcileBackendPools(ctx, clusterName, service, lb)
+			if err != nil {
+				return lb, err
+			}
 		}
 		if backendPoolsUpdated {
 			dirtyLb = true
@@ -1897,6 +2102,29 @@ func (az *Cloud) reconcileLoadBalancer(ctx context.Context, clusterName string,
 	if changed := az.reconcileLBRules(lb, service, serviceName, wantLb, expectedRules); changed {
 		dirtyLb = true
 	}
+
+	var expectedOutboundRules []network.OutboundRule
+	if wantLb {
+		if expectedOutboundRules, err = az.getExpectedOutboundRules(service, lb, lbFrontendIPConfigIDs); err != nil {
+			return nil, err
+		}
+	}
+	if changed := az.reconcileLBOutboundRules(lb, service, serviceName, wantLb, expectedOutboundRules); changed {
+		dirtyLb = true
+	}
+
+	var expectedInboundNatRules []network.InboundNatRule
+	if wantLb {
+		if expectedInboundNatRules, err = az.getExpectedInboundNatRules(service, lbFrontendIPConfigIDs, lbBackendPoolIDs); err != nil {
+			return nil, err
+		}
+		if err = az.checkInboundNatRulesConflicts(lb, service, expectedInboundNatRules); err != nil {
+			return nil, err
+		}
+	}
+	if changed := az.reconcileLBInboundNatRules(lb, service, serviceName, wantLb, expectedInboundNatRules); changed {
+		dirtyLb = true
+	}
 	if changed := az.ensureLoadBalancerTagged(lb); changed {
 		dirtyLb = true
 	}
@@ -1981,7 +2209,7 @@ func (az *Cloud) reconcileLoadBalancer(ctx context.Context, clusterName string,
 	}
 
 	if fipChanged {
-		az.reconcileMultipleStandardLoadBalancerConfigurationStatus(wantLb, serviceName, lbName)
+		az.reconcileMultipleStandardLoadBalancerConfigurationStatus(ctx, wantLb, serviceName, lbName)
 	}
 
 	klog.V(2).Infof("reconcileLoadBalancer for service(%s): lb(%s) finished", serviceName, lbName)
@@ -2139,6 +2367,7 @@ func (az *Cloud) accommodateNodesByPrimaryVMSet(
 
 // accommodateNodesByNodeSelector decides which load balancer configuration the node should be added to by node selector
 func (az *Cloud) accommodateNodesByNodeSelector(
+	ctx context.Context,
 	lbName string,
 	lbs *[]network.LoadBalancer,
 	service *v1.Service,
@@ -2219,7 +2448,7 @@ func (az *Cloud) accommodateNodesByNodeSelector(
 		// Emit a warning for the orphaned node.
 		if minNodesIDX == -1 {
 			warningMsg := fmt.Sprintf("failed to find a lb for node %s", node.Name)
-			az.Event(service, v1.EventTypeWarning, "FailedToFindLoadBalancerForNode", warningMsg)
+			az.Event(ctx, service, v1.EventTypeWarning, "FailedToFindLoadBalancerForNode", warningMsg)
 			continue
 		}
 
@@ -2291,7 +2520,7 @@ func (az *Cloud) reconcileMultipleStandardLoadBalancerBackendNodes(
 		return err
 	}
 
-	err = az.accommodateNodesByNodeSelector(lbName, lbs, service, nodes, nodeNameToLBConfigIDXMap)
+	err = az.accommodateNodesByNodeSelector(ctx, lbName, lbs, service, nodes, nodeNameToLBConfigIDXMap)
 	if err != nil {
 		return err
 	}
@@ -2330,7 +2559,11 @@ func (az *Cloud) recordExistingNodesOnLoadBalancers(clusterName string, lbs *[]n
 	return nil
 }
 
-func (az *Cloud) reconcileMultipleStandardLoadBalancerConfigurationStatus(wantLb bool, svcName, lbName string) {
+func (az *Cloud) reconcileMultipleStandardLoadBalancerConfigurationStatus(ctx context.Context, wantLb bool, svcName, lbName string) {
+	if getServicePlanner(ctx) != nil {
+		return
+	}
+
 	lbName = trimSuffixIgnoreCase(lbName, consts.InternalLoadBalancerNameSuffix)
 	for i := range az.MultipleStandardLoadBalancerConfigurations {
 		if strings.EqualFold(lbName, az.MultipleStandardLoadBalancerConfigurations[i].Name) {
@@ -2467,7 +2700,7 @@ func (az *Cloud) reconcileFrontendIPConfigs(
 			config := newConfigs[i]
 			isServiceOwnsFrontendIP, _, _ := az.serviceOwnsFrontendIP(ctx, config, service)
 			if isServiceOwnsFrontendIP {
-				unsafe, err := az.isFrontendIPConfigUnsafeToDelete(lb, service, config.ID)
+				unsafe, err := az.isFrontendIPConfigUnsafeToDelete(ctx, lb, service, config.ID)
 				if err != nil {
 					return nil, toDeleteConfigs, false, err
 				}
@@ -2499,14 +2732,26 @@ func (az *Cloud) reconcileFrontendIPConfigs(
 			isFipChanged bool
 			subnet       network.Subnet
 			existsSubnet bool
+
+			gatewayLBFrontendIPConfigID string
+			manageGatewayLB             bool
 		)
 
+		gatewayLBFrontendIPConfigID, manageGatewayLB, err = az.getGatewayLoadBalancerFrontendIPConfigID(service)
+		if err != nil {
+			return nil, toDeleteConfigs, false, err
+		}
+
 		if isInternal {
 			subnetName := getInternalSubnet(service)
 			if subnetName == nil {
 				subnetName = &az.SubnetName
 			}
-			subnet, existsSubnet, err = az.getSubnet("", az.VnetName, *subnetName)
+			if getServicePlanner(ctx) != nil {
+				subnet, existsSubnet = az.getSubnetForPlan(*subnetName), true
+			} else {
+				subnet, existsSubnet, err = az.getSubnet("", az.VnetName, *subnetName)
+			}
 			if err != nil {
 				return nil, toDeleteConfigs, false, err
 			}
@@ -2546,6 +2791,27 @@ func (az *Cloud) reconcileFrontendIPConfigs(
 			}
 		}
 
+		if manageGatewayLB {
+			for i := range newConfigs {
+				if isServiceOwnsFrontendIP, _, _ := az.serviceOwnsFrontendIP(ctx, newConfigs[i], service); !isServiceOwnsFrontendIP {
+					continue
+				}
+				previous, changed := reconcileFrontendGatewayLoadBalancer(&newConfigs[i], gatewayLBFrontendIPConfigID)
+				if !changed {
+					continue
+				}
+				if previous != "" && gatewayLBFrontendIPConfigID != "" {
+					warningMsg := fmt.Sprintf("the frontend IP configuration %s is chained to the gateway load balancer frontend %s, changing it to %s set by the annotation %s",
+						ptr.Deref(newConfigs[i].Name, ""), previous, gatewayLBFrontendIPConfigID, consts.ServiceAnnotationGatewayLoadBalancerFrontendIPConfigID)
+					klog.Warningf("reconcileFrontendIPConfigs for service (%s): %s", serviceName, warningMsg)
+					az.Event(ctx, service, v1.EventTypeWarning, "ConflictingGatewayLoadBalancer", warningMsg)
+				} else {
+					klog.V(2).Infof("reconcileFrontendIPConfigs for service (%s): lb frontendconfig(%s) - chaining to gateway load balancer frontend %q", serviceName, ptr.Deref(newConfigs[i].Name, ""), gatewayLBFrontendIPConfigID)
+				}
+				dirtyConfigs = true
+			}
+		}
+
 		ownedFIPConfigMap, err := az.findFrontendIPConfigsOfService(ctx, &newConfigs, service)
 		if err != nil {
 			return nil, toDeleteConfigs, false, err
@@ -2609,6 +2875,9 @@ func (az *Cloud) reconcileFrontendIPConfigs(
 				fipConfigurationProperties = &network.FrontendIPConfigurationPropertiesFormat{
 					PublicIPAddress: &network.PublicIPAddress{ID: pip.ID},
 				}
+				if gatewayLBFrontendIPConfigID != "" {
+					fipConfigurationProperties.GatewayLoadBalancer = &network.SubResource{ID: ptr.To(gatewayLBFrontendIPConfigID)}
+				}
 			}
 
 			newConfig := network.FrontendIPConfiguration{
@@ -2714,11 +2983,15 @@ func (az *Cloud) checkLoadBalancerResourcesConflicts(
 		if lb.InboundNatRules != nil {
 			for _, inboundNatRule := range *lb.InboundNatRules {
 				if inboundNatRuleConflictsWithPort(inboundNatRule, frontendIPConfigID, port) {
+					// the inbound NAT rules of the service are validated in getExpectedInboundNatRules
+					if inboundNatRule.Name != nil && az.serviceOwnsInboundNatRule(service, *inboundNatRule.Name) {
+						continue
+					}
 					return fmt.Errorf("checkLoadBalancerResourcesConflicts: service port %s is trying to "+
 						"consume the port %d which is being referenced by an existing inbound NAT rule %s with "+
 						"the same protocol %s and frontend IP config with ID %s",
 						port.Name,
-						*inboundNatRule.FrontendPort,
+						port.Port,
 						*inboundNatRule.Name,
 						inboundNatRule.Protocol,
 						*inboundNatRule.FrontendIPConfiguration.ID)
@@ -2765,8 +3038,9 @@ func inboundNatRuleConflictsWithPort(inboundNatRule network.InboundNatRule, fron
 		inboundNatRule.FrontendIPConfiguration.ID != nil &&
 		strings.EqualFold(*inboundNatRule.FrontendIPConfiguration.ID, frontendIPConfigID) &&
 		strings.EqualFold(string(inboundNatRule.Protocol), string(port.Protocol)) &&
-		inboundNatRule.FrontendPort != nil &&
-		*inboundNatRule.FrontendPort == port.Port
+		((inboundNatRule.FrontendPort != nil && *inboundNatRule.FrontendPort == port.Port) ||
+			(inboundNatRule.FrontendPortRangeStart != nil && inboundNatRule.FrontendPortRangeEnd != nil &&
+				*inboundNatRule.FrontendPortRangeStart <= port.Port && *inboundNatRule.FrontendPortRangeEnd >= port.Port))
 }
 
 func lbRuleConflictsWithPort(rule network.LoadBalancingRule, frontendIPConfigID string, port v1.ServicePort) bool {
@@ -2796,9 +3070,12 @@ func (az *Cloud) getExpectedLBRules(
 	// take precedence over user defined probe configuration
 	// healthcheck proxy server serves http requests
 	// https://github.com/kubernetes/kubernetes/blob/7c013c3f64db33cf19f38bb2fc8d9182e42b0b7b/pkg/proxy/healthcheck/service_health.go#L236
+	// Pods are probed directly when using podIP backend pool type, so neither the
+	// health check node port nor the shared probe is used.
 	var nodeEndpointHealthprobe *network.Probe
 	var nodeEndpointHealthprobeAdded bool
-	if servicehelpers.NeedsHealthCheck(service) && !(consts.IsPLSEnabled(service.Annotations) && consts.IsPLSProxyProtocolEnabled(service.Annotations)) {
+	if servicehelpers.NeedsHealthCheck(service) && !(consts.IsPLSEnabled(service.Annotations) && consts.IsPLSProxyProtocolEnabled(service.Annotations)) &&
+		!az.IsLBBackendPoolTypePodIP() {
 		podPresencePath, podPresencePort := servicehelpers.GetServiceHealthCheckPathPort(service)
 		lbRuleName := az.getLoadBalancerRuleName(service, v1.ProtocolTCP, podPresencePort, isIPv6)
 		probeInterval, numberOfProbes, err := az.getHealthProbeConfigProbeIntervalAndNumOfProbe(service, podPresencePort)
@@ -2819,7 +3096,8 @@ func (az *Cloud) getExpectedLBRules(
 
 	var useSharedProbe bool
 	if az.useSharedLoadBalancerHealthProbeMode() &&
-		!strings.EqualFold(string(service.Spec.ExternalTrafficPolicy), string(v1.ServiceExternalTrafficPolicyLocal)) {
+		!strings.EqualFold(string(service.Spec.ExternalTrafficPolicy), string(v1.ServiceExternalTrafficPolicyLocal)) &&
+		!az.IsLBBackendPoolTypePodIP() {
 		nodeEndpointHealthprobe = az.buildClusterServiceSharedProbe()
 		useSharedProbe = true
 	}
@@ -2924,7 +3202,7 @@ func (az *Cloud) getExpectedLBRules(
 					}
 				}
 			}
-			if consts.IsK8sServiceDisableLoadBalancerFloatingIP(service) {
+			if consts.IsK8sServiceDisableLoadBalancerFloatingIP(service) && !az.IsLBBackendPoolTypePodIP() {
 				props.BackendPort = ptr.To(port.NodePort)
 				props.EnableFloatingIP = ptr.To(false)
 			}
@@ -2967,11 +3245,12 @@ func (az *Cloud) getExpectedLoadBalancingRulePropertiesForPort(
 		lbIdleTimeout = ptr.To(int32(4))
 	}
 
+	// The outbound SNAT must be disabled if the frontend IP configuration is referenced by an outbound rule of the service.
 	props := &network.LoadBalancingRulePropertiesFormat{
 		Protocol:            transportProto,
 		FrontendPort:        ptr.To(servicePort.Port),
 		BackendPort:         ptr.To(servicePort.Port),
-		DisableOutboundSnat: ptr.To(az.DisableLoadBalancerOutboundSNAT()),
+		DisableOutboundSnat: ptr.To(az.DisableLoadBalancerOutboundSNAT() || hasServiceOutboundRules(service)),
 		EnableFloatingIP:    ptr.To(true),
 		LoadDistribution:    loadDistribution,
 		FrontendIPConfiguration: &network.SubResource{
@@ -2992,6 +3271,17 @@ func (az *Cloud) getExpectedLoadBalancingRulePropertiesForPort(
 		props.BackendPort = ptr.To(servicePort.NodePort)
 		props.EnableFloatingIP = ptr.To(false)
 	}
+
+	// The backend pool contains pod IPs when using podIP backend pool type, so the traffic
+	// should be sent to the target port of the pods without floating IP.
+	if az.IsLBBackendPoolTypePodIP() {
+		targetPort, err := az.getServicePortTargetPort(service, servicePort)
+		if err != nil {
+			return nil, err
+		}
+		props.BackendPort = ptr.To(targetPort)
+		props.EnableFloatingIP = ptr.To(false)
+	}
 	return props, nil
 }
 
@@ -3024,6 +3314,11 @@ func (az *Cloud) reconcileSecurityGroup(
 	logger.V(2).Info("Starting")
 	ctx = log.NewContext(ctx, logger)
 
+	planner := getServicePlanner(ctx)
+	if planner != nil && !planner.canPlanSecurityGroup(az, service, lbIPs) {
+		return nil, nil
+	}
+
 	if wantLb && len(lbIPs) == 0 {
 		return nil, fmt.Errorf("no load balancer IP for setting up security rules for service %s", service.Name)
 	}
@@ -3035,15 +3330,32 @@ func (az *Cloud) reconcileSecurityGroup(
 
 	var accessControl *loadbalancer.AccessControl
 	{
-		sg, err := az.nsgRepo.GetSecurityGroup(ctx)
-		if err != nil {
-			return nil, err
+		var sg *armnetwork.SecurityGroup
+		if planner != nil {
+			sg, _ = planner.getSecurityGroup()
+		} else {
+			sg, err = az.nsgRepo.GetSecurityGroup(ctx)
+			if err != nil {
+				return nil, err
+			}
 		}
 
 		var opts []loadbalancer.AccessControlOption
 		if !wantLb {
 			// When deleting LB, we don't need to validate the annotation
-			opts = append(opts, loadbalancer.WithEventEmitter(az.Event))
+			opts = append(opts, loadbalancer.WithEventEmitter(az.eventEmitter(ctx)))
+		}
+		if az.IsLBBackendPoolTypePodIP() {
+			// Traffic is sent to the target ports of the pods directly.
+			portsByProtocol, err := az.getSecurityRuleDestinationPortsOfPods(service)
+			if err != nil {
+				if wantLb {
+					return nil, err
+				}
+				logger.Error(err, "Failed to get the target ports of the pods, fall back to the service ports")
+			} else {
+				opts = append(opts, loadbalancer.WithSecurityRuleDestinationPortsByProtocol(portsByProtocol))
+			}
 		}
 		accessControl, err = loadbalancer.NewAccessControl(logger, service, sg, opts...)
 		if err != nil {
@@ -3053,7 +3365,7 @@ func (az *Cloud) reconcileSecurityGroup(
 	}
 
 	var (
-		disableFloatingIP                                = consts.IsK8sServiceDisableLoadBalancerFloatingIP(service)
+		disableFloatingIP                                = consts.IsK8sServiceDisableLoadBalancerFloatingIP(service) || az.IsLBBackendPoolTypePodIP()
 		lbIPAddresses, _                                 = iputil.ParseAddresses(lbIPs)
 		lbIPv4Addresses, lbIPv6Addresses                 = iputil.GroupAddressesByFamily(lbIPAddresses)
 		additionalIPv4Addresses, additionalIPv6Addresses = iputil.GroupAddressesByFamily(additionalIPs)
@@ -3072,7 +3384,8 @@ func (az *Cloud) reconcileSecurityGroup(
 			}
 		}
 		var backendIPv4List, backendIPv6List []string
-		if lbFound {
+		// The backend IPs are only targeted with floating IP disabled, so they are not resolved when planning otherwise.
+		if lbFound && (disableFloatingIP || planner == nil) {
 			backendIPv4List, backendIPv6List = az.LoadBalancerBackendPool.GetBackendPrivateIPs(ctx, clusterName, service, lb)
 		}
 		backendIPv4Addresses, _ = iputil.ParseAddresses(backendIPv4List)
@@ -3082,7 +3395,12 @@ func (az *Cloud) reconcileSecurityGroup(
 	var (
 		dstIPv4Addresses = additionalIPv4Addresses
 		dstIPv6Addresses = additionalIPv6Addresses
+		// The backend nodes are targeted by the application security group of the load balancer if enabled.
+		asgID string
 	)
+	if disableFloatingIP && az.UseApplicationSecurityGroups {
+		asgID = az.getApplicationSecurityGroupID(lbName)
+	}
 
 	if disableFloatingIP {
 		// use the backend node IPs
@@ -3105,27 +3423,62 @@ func (az *Cloud) reconcileSecurityGroup(
 			logger.Error(err, "Failed to clean security group")
 			return nil, err
 		}
+
+		if asgID != "" {
+			if err := accessControl.CleanSecurityGroupOnApplicationSecurityGroup(asgID, retainPortRanges); err != nil {
+				logger.Error(err, "Failed to clean security group on application security group")
+				return nil, err
+			}
+		}
+	}
+
+	if asgID != "" {
+		// The rules of the backend node IPs are replaced by the ones of the application security group.
+		dstIPv4Addresses, dstIPv6Addresses = additionalIPv4Addresses, additionalIPv6Addresses
 	}
 
 	if wantLb {
 		err := accessControl.PatchSecurityGroup(dstIPv4Addresses, dstIPv6Addresses)
 		if err != nil {
 			logger.Error(err, "Failed to patch security group")
+			az.emitSecurityGroupCapacityEvent(ctx, service, err)
 			return nil, err
 		}
+
+		if asgID != "" {
+			v4Enabled, v6Enabled := getIPFamiliesEnabled(service)
+			if err := accessControl.PatchSecurityGroupOnApplicationSecurityGroup(asgID, v4Enabled, v6Enabled); err != nil {
+				logger.Error(err, "Failed to patch security group on application security group")
+				az.emitSecurityGroupCapacityEvent(ctx, service, err)
+				return nil, err
+			}
+		}
+	}
+
+	if n := accessControl.CompactSecurityGroup(); n > 0 {
+		logger.V(2).Info("Compacted security group", "num-removed-rules", n)
+	}
+	if planner == nil {
+		usage := accessControl.SecurityGroupUsage()
+		metrics.SetSecurityGroupUsage(az.SecurityGroupName, metrics.SecurityGroupUsageRules, usage.Rules, securitygroup.MaxSecurityRulesPerGroup)
+		metrics.SetSecurityGroupUsage(az.SecurityGroupName, metrics.SecurityGroupUsageSourceAddresses, usage.SourceAddresses, securitygroup.MaxSecurityRuleSourceIPsPerGroup)
+		metrics.SetSecurityGroupUsage(az.SecurityGroupName, metrics.SecurityGroupUsageDestinationAddresses, usage.DestinationAddresses, securitygroup.MaxSecurityRuleDestinationIPsPerGroup)
 	}
 
 	rv, updated, err := accessControl.SecurityGroup()
 	if err != nil {
 		err = fmt.Errorf("unable to apply access control configuration to security group: %w", err)
 		logger.Error(err, "Failed to get security group after patching")
+		az.emitSecurityGroupCapacityEvent(ctx, service, err)
 		return nil, err
 	}
 	if az.ensureSecurityGroupTagged(rv) {
 		updated = true
 	}
 
-	if updated {
+	if updated && planner != nil {
+		planner.createOrUpdateSecurityGroup(rv)
+	} else if updated {
 		logger.V(2).Info("Preparing to update security group")
 		logger.V(5).Info("CreateOrUpdateSecurityGroup begin")
 		err := az.nsgRepo.CreateOrUpdateSecurityGroup(ctx, rv)
@@ -3138,6 +3491,16 @@ func (az *Cloud) reconcileSecurityGroup(
 	return rv, nil
 }
 
+// emitSecurityGroupCapacityEvent emits a warning event on the service if the security group cannot accommodate
+// the rules of the service, so it is visible to the users without checking the logs.
+func (az *Cloud) emitSecurityGroupCapacityEvent(ctx context.Context, service *v1.Service, err error) {
+	if !errors.Is(err, securitygroup.ErrSecurityGroupCapacityExceeded) && !errors.Is(err, securitygroup.ErrSecurityRulePriorityExhausted) {
+		return
+	}
+	az.Event(ctx, service, v1.EventTypeWarning, "SecurityGroupCapacityExceeded",
+		fmt.Sprintf("Security group %s cannot accommodate the rules of the service: %s", az.SecurityGroupName, err.Error()))
+}
+
 func (az *Cloud) shouldUpdateLoadBalancer(ctx context.Context, clusterName string, service *v1.Service, nodes []*v1.Node) (bool, error) {
 	existingManagedLBs, err := az.ListManagedLBs(ctx, service, nodes, clusterName)
 	if err != nil {
@@ -3171,6 +3534,11 @@ func shouldReleaseExistingOwnedPublicIP(
 	if pipPropertiesFormat != nil {
 		currentIPTags = (*pipPropertiesFormat).IPTags
 	}
+	// The routing preference set by the routing preference annotation is not compared, because
+	// changing it requires recreating the public IP, which is reported by an event instead.
+	if _, found := getRoutingPreferenceFromIPTags(ipTagRequest.IPTags); !found {
+		currentIPTags = withoutRoutingPreferenceIPTag(currentIPTags)
+	}
 
 	// Check whether the public IP is being referenced by other service.
 	// The owned public IP can be released only when there is not other service using it.
@@ -3322,7 +3690,7 @@ func (az *Cloud) reconcilePublicIP(ctx context.Context, pips []network.PublicIPA
 		pipCopy := *pip
 		updateFuncs = append(updateFuncs, func() error {
 			klog.V(2).Infof("reconcilePublicIP for service(%s): pip(%s), isIPv6(%v) - updating", serviceName, *pip.Name, isIPv6)
-			return az.CreateOrUpdatePIP(service, pipResourceGroup, pipCopy)
+			return az.CreateOrUpdatePIP(ctx, service, pipResourceGroup, pipCopy)
 		})
 	}
 	errs := utilerrors.AggregateGoroutines(updateFuncs...)
@@ -3413,7 +3781,8 @@ func (az *Cloud) getPublicIPUpdates(
 					dirtyPIP = true
 				}
 			}
-			if shouldReleaseExistingOwnedPublicIP(&pip, serviceReferences, wantLb, isInternal, isUserAssignedPIP, desiredPipName, serviceIPTagRequest) {
+			if shouldReleaseExistingOwnedPublicIP(&pip, serviceReferences, wantLb, isInternal, isUserAssignedPIP, desiredPipName, serviceIPTagRequest) &&
+				!az.isPublicIPReferencedByUnownedService(serviceReferences) {
 				// Then, release the public ip
 				pipsToBeDeleted = append(pipsToBeDeleted, &pip)
 
@@ -3441,6 +3810,29 @@ func (az *Cloud) getPublicIPUpdates(
 	return discoveredDesiredPublicIP, pipsToBeDeleted, deletedDesiredPublicIP, pipsToBeUpdated, err
 }
 
+// isPublicIPReferencedByUnownedService checks if any service referenced by the tags of the public IP is a LoadBalancer
+// service owned by other controllers according to its loadBalancerClass. Such public IPs are never released.
+func (az *Cloud) isPublicIPReferencedByUnownedService(serviceReferences []string) bool {
+	if az.serviceLister == nil {
+		return false
+	}
+	for _, serviceReference := range serviceReferences {
+		namespace, name, err := cache.SplitMetaNamespaceKey(serviceReference)
+		if err != nil {
+			continue
+		}
+		service, err := az.serviceLister.Services(namespace).Get(name)
+		if err != nil {
+			continue
+		}
+		if service.Spec.Type == v1.ServiceTypeLoadBalancer && !az.OwnsLoadBalancerClass(service.Spec.LoadBalancerClass) {
+			klog.V(2).Infof("isPublicIPReferencedByUnownedService: the public IP is referenced by service %s owned by other controllers", serviceReference)
+			return true
+		}
+	}
+	return false
+}
+
 // safeDeletePublicIP deletes public IP by removing its reference first.
 func (az *Cloud) safeDeletePublicIP(ctx context.Context, service *v1.Service, pipResourceGroup string, pip *network.PublicIPAddress, lb *network.LoadBalancer) error {
 	// Remove references if pip.IPConfiguration is not nil.
@@ -3522,7 +3914,7 @@ func (az *Cloud) safeDeletePublicIP(ctx context.Context, service *v1.Service, pi
 
 	pipName := ptr.Deref(pip.Name, "")
 	klog.V(10).Infof("DeletePublicIP(%s, %q): start", pipResourceGroup, pipName)
-	err := az.DeletePublicIP(service, pipResourceGroup, pipName)
+	err := az.DeletePublicIP(ctx, service, pipResourceGroup, pipName)
 	if err != nil {
 		return err
 	}
@@ -3924,6 +4316,9 @@ func (az *Cloud) getAzureLoadBalancerName(
 		}
 
 		currentLBName := az.getServiceCurrentLoadBalancerName(service)
+		if generated, found := az.getAutoScaledLoadBalancerConfiguration(service, currentLBName, eligibleLBs, existingLBs, requiresInternalLoadBalancer(service)); found {
+			eligibleLBs = append(eligibleLBs, generated.Name)
+		}
 		lbNamePrefix = getMostEligibleLBForService(currentLBName, eligibleLBs, existingLBs, requiresInternalLoadBalancer(service))
 	}
 
@@ -4021,14 +4416,15 @@ func (az *Cloud) getEligibleLoadBalancersForService(ctx context.Context, service
 		WithName("getEligibleLoadBalancersForService").
 		WithValues("service", service.Name)
 
-	// 1. Service selects LBs defined in the annotation.
+	// 1. Service selects LBs defined in the annotation, including the ones generated from them.
 	// If there is no annotation given, it selects all LBs.
 	lbsFromAnnotation := consts.GetLoadBalancerConfigurationsNames(service)
 	if len(lbsFromAnnotation) > 0 {
 		lbNamesSet := utilsets.NewString(lbsFromAnnotation...)
 		for i := range az.MultipleStandardLoadBalancerConfigurations {
 			multiSLBConfig := az.MultipleStandardLoadBalancerConfigurations[i]
-			if lbNamesSet.Has(multiSLBConfig.Name) {
+			if lbNamesSet.Has(multiSLBConfig.Name) ||
+				(multiSLBConfig.GeneratedFrom != "" && lbNamesSet.Has(multiSLBConfig.GeneratedFrom)) {
 				logger.V(4).Info("selects the load balancer by annotation",
 					"load balancer configuration name", multiSLBConfig.Name)
 				eligibleLBs = append(eligibleLBs, multiSLBConfig)
@@ -4050,14 +4446,14 @@ func (az *Cloud) getEligibleLoadBalancersForService(ctx context.Context, service
 	for i := len(eligibleLBs) - 1; i >= 0; i-- {
 		eligibleLB := eligibleLBs[i]
 
-		// 2. If the LB does not allow service placement, it is not eligible,
-		// unless the service is already using the LB.
-		if !ptr.Deref(eligibleLB.AllowServicePlacement, true) {
-			if az.isLoadBalancerInUseByService(service, eligibleLB) {
+		// 2. If the LB does not allow service placement or is migrating services to other LBs,
+		// it is not eligible, unless the service is already using the LB and is not being moved.
+		if !ptr.Deref(eligibleLB.AllowServicePlacement, true) || eligibleLB.MigrateServices {
+			if az.isLoadBalancerInUseByService(service, eligibleLB) && !az.isServiceMigratingFromLoadBalancer(service, eligibleLB) {
 				logger.V(4).Info("although the load balancer has AllowServicePlacement=false, service is allowed to be placed on load balancer because it is using the load balancer",
 					"load balancer configuration name", eligibleLB.Name)
 			} else {
-				logger.V(4).Info("the load balancer has AllowServicePlacement=false, service is not allowed to be placed on load balancer",
+				logger.V(4).Info("the load balancer has AllowServicePlacement=false or MigrateServices=true, service is not allowed to be placed on load balancer",
 					"load balancer configuration name", eligibleLB.Name)
 				eligibleLBs = append(eligibleLBs[:i], eligibleLBs[i+1:]...)
 				lbFailedPlacementFlag = append(lbFailedPlacementFlag, eligibleLB.Name)
@@ -4168,6 +4564,14 @@ func (az *Cloud) isLoadBalancerInUseByService(service *v1.Service, lbConfig conf
 	return lbConfig.ActiveServices.Has(serviceName)
 }
 
+func (az *Cloud) isServiceMigratingFromLoadBalancer(service *v1.Service, lbConfig config.MultipleStandardLoadBalancerConfiguration) bool {
+	az.multipleStandardLoadBalancersActiveServicesLock.Lock()
+	defer az.multipleStandardLoadBalancersActiveServicesLock.Unlock()
+
+	serviceName := getServiceName(service)
+	return lbConfig.MigratingServices.Has(serviceName)
+}
+
 // There are two cases when a service owns the frontend IP config:
 // 1. The primary service, which means the frontend IP config is created after the creation of the service.
 // This means the name of the config can be tracked by the service UID.
@@ -22,12 +22,14 @@ import (
 	"net/netip"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v6"
+	"github.com/go-logr/logr"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
 
 	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
 	"sigs.k8s.io/cloud-provider-azure/pkg/log"
 	"sigs.k8s.io/cloud-provider-azure/pkg/provider/loadbalancer"
+	"sigs.k8s.io/cloud-provider-azure/pkg/provider/securitygroup"
 	fnutil "sigs.k8s.io/cloud-provider-azure/pkg/util/collectionutil"
 )
 
@@ -58,30 +60,39 @@ func (az *Cloud) listSharedIPPortMapping(
 	svc *v1.Service,
 	ingressIPs []netip.Addr,
 ) (map[armnetwork.SecurityRuleProtocol][]int32, error) {
-	var (
-		logger = log.FromContextOrBackground(ctx).WithName("listSharedIPPortMapping")
-		rv     = make(map[armnetwork.SecurityRuleProtocol][]int32)
-	)
-
-	var services []*v1.Service
-	{
-		var err error
-		logger.V(5).Info("Listing all services")
-		services, err = az.serviceLister.List(labels.Everything())
-		if err != nil {
-			logger.Error(err, "Failed to list all services")
-			return nil, fmt.Errorf("list all services: %w", err)
-		}
-		logger.V(5).Info("Listed all services", "num-all-services", len(services))
-
-		// Filter services by ingress IPs or backend node pool IPs (when disable floating IP)
-		if consts.IsK8sServiceDisableLoadBalancerFloatingIP(svc) {
-			logger.V(5).Info("Filter service by disableFloatingIP")
-			services = filterServicesByDisableFloatingIP(services)
-		} else {
-			logger.V(5).Info("Filter service by external IPs")
-			services = filterServicesByIngressIPs(services, ingressIPs)
-		}
+	logger := log.FromContextOrBackground(ctx).WithName("listSharedIPPortMapping")
+
+	if p := getServicePlanner(ctx); p != nil {
+		return getSharedIPPortMapping(logger, svc, p.services, ingressIPs)
+	}
+
+	logger.V(5).Info("Listing all services")
+	services, err := az.serviceLister.List(labels.Everything())
+	if err != nil {
+		logger.Error(err, "Failed to list all services")
+		return nil, fmt.Errorf("list all services: %w", err)
+	}
+	logger.V(5).Info("Listed all services", "num-all-services", len(services))
+
+	return getSharedIPPortMapping(logger, svc, services, ingressIPs)
+}
+
+// getSharedIPPortMapping returns the port mapping of the given services sharing the IPs with the service.
+func getSharedIPPortMapping(
+	logger logr.Logger,
+	svc *v1.Service,
+	services []*v1.Service,
+	ingressIPs []netip.Addr,
+) (map[armnetwork.SecurityRuleProtocol][]int32, error) {
+	rv := make(map[armnetwork.SecurityRuleProtocol][]int32)
+
+	// Filter services by ingress IPs or backend node pool IPs (when disable floating IP)
+	if consts.IsK8sServiceDisableLoadBalancerFloatingIP(svc) {
+		logger.V(5).Info("Filter service by disableFloatingIP")
+		services = filterServicesByDisableFloatingIP(services)
+	} else {
+		logger.V(5).Info("Filter service by external IPs")
+		services = filterServicesByIngressIPs(services, ingressIPs)
 	}
 	logger.V(5).Info("Filtered services", "num-filtered-services", len(services))
 
@@ -106,3 +117,21 @@ func (az *Cloud) listSharedIPPortMapping(
 
 	return rv, nil
 }
+
+// getSecurityRuleDestinationPortsOfPods returns the target ports of the pods grouped by SecurityGroup protocol.
+// It is used when the backend pool type is podIP, where the traffic is sent to the pods without translation.
+func (az *Cloud) getSecurityRuleDestinationPortsOfPods(svc *v1.Service) (map[armnetwork.SecurityRuleProtocol][]int32, error) {
+	rv := make(map[armnetwork.SecurityRuleProtocol][]int32)
+	for _, port := range svc.Spec.Ports {
+		protocol, err := securitygroup.ProtocolFromKubernetes(port.Protocol)
+		if err != nil {
+			return nil, err
+		}
+		targetPort, err := az.getServicePortTargetPort(svc, port)
+		if err != nil {
+			return nil, err
+		}
+		rv[protocol] = append(rv[protocol], targetPort)
+	}
+	return rv, nil
+}
@@ -0,0 +1,237 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2022-07-01/network"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+	"sigs.k8s.io/cloud-provider-azure/pkg/provider/config"
+	utilsets "sigs.k8s.io/cloud-provider-azure/pkg/util/sets"
+)
+
+// getAutoScaledLoadBalancerName returns the name of the index-th load balancer generated
+// from the multiple standard load balancer configuration.
+func getAutoScaledLoadBalancerName(multiSLBConfig config.MultipleStandardLoadBalancerConfiguration, index int) string {
+	nameTemplate := consts.DefaultAutoScaledLoadBalancerNameTemplate
+	if multiSLBConfig.AutoScale != nil && multiSLBConfig.AutoScale.NameTemplate != "" {
+		nameTemplate = multiSLBConfig.AutoScale.NameTemplate
+	}
+	name := strings.ReplaceAll(nameTemplate, consts.AutoScaledLoadBalancerNamePlaceholder, multiSLBConfig.Name)
+	return strings.ReplaceAll(name, consts.AutoScaledLoadBalancerIndexPlaceholder, strconv.Itoa(index))
+}
+
+// newAutoScaledLoadBalancerConfiguration generates a multiple standard load balancer configuration
+// that inherits the selectors and the placement flag from the given one. The generated configuration
+// does not have a primary vmSet, so the nodes are added to it by the node selector.
+func newAutoScaledLoadBalancerConfiguration(source config.MultipleStandardLoadBalancerConfiguration, name string) config.MultipleStandardLoadBalancerConfiguration {
+	return config.MultipleStandardLoadBalancerConfiguration{
+		Name: name,
+		MultipleStandardLoadBalancerConfigurationSpec: config.MultipleStandardLoadBalancerConfigurationSpec{
+			AllowServicePlacement:    source.AllowServicePlacement,
+			ServiceLabelSelector:     source.ServiceLabelSelector.DeepCopy(),
+			ServiceNamespaceSelector: source.ServiceNamespaceSelector.DeepCopy(),
+			NodeSelector:             source.NodeSelector.DeepCopy(),
+		},
+		MultipleStandardLoadBalancerConfigurationStatus: config.MultipleStandardLoadBalancerConfigurationStatus{
+			GeneratedFrom: source.Name,
+		},
+	}
+}
+
+// getMultipleStandardLoadBalancerConfiguration returns the multiple standard load balancer configuration with the given name.
+func (az *Cloud) getMultipleStandardLoadBalancerConfiguration(name string) (config.MultipleStandardLoadBalancerConfiguration, bool) {
+	for _, multiSLBConfig := range az.MultipleStandardLoadBalancerConfigurations {
+		if strings.EqualFold(multiSLBConfig.Name, name) {
+			return multiSLBConfig, true
+		}
+	}
+	return config.MultipleStandardLoadBalancerConfiguration{}, false
+}
+
+// getServiceLoadBalancingRuleCount returns the number of load balancing rules the service needs on a load balancer.
+func getServiceLoadBalancingRuleCount(service *v1.Service) int {
+	v4Enabled, v6Enabled := getIPFamiliesEnabled(service)
+	count := len(service.Spec.Ports)
+	if v4Enabled && v6Enabled {
+		count *= 2
+	}
+	return count
+}
+
+// isLoadBalancerFullForService checks if the existing load balancer of the given configuration
+// cannot accommodate the load balancing rules of the service without exceeding MaximumLoadBalancerRuleCount.
+func (az *Cloud) isLoadBalancerFullForService(service *v1.Service, lbConfigName string, existingLBs *[]network.LoadBalancer, isInternal bool) bool {
+	if az.MaximumLoadBalancerRuleCount <= 0 || existingLBs == nil {
+		return false
+	}
+	for i := range *existingLBs {
+		existingLB := (*existingLBs)[i]
+		if !strings.EqualFold(trimSuffixIgnoreCase(ptr.Deref(existingLB.Name, ""), consts.InternalLoadBalancerNameSuffix), lbConfigName) ||
+			isInternalLoadBalancer(&existingLB) != isInternal {
+			continue
+		}
+		var ruleCount int
+		if existingLB.LoadBalancerPropertiesFormat != nil && existingLB.LoadBalancingRules != nil {
+			ruleCount = len(*existingLB.LoadBalancingRules)
+		}
+		return ruleCount+getServiceLoadBalancingRuleCount(service) > az.MaximumLoadBalancerRuleCount
+	}
+	return false
+}
+
+// getAutoScaledLoadBalancerConfiguration returns the load balancer configuration to be generated if none of the
+// eligible load balancers can accommodate the service and one of them has AutoScale enabled. It does not change
+// the load balancer configurations, which is done by autoScaleMultipleStandardLoadBalancers.
+// The services already using an eligible load balancer are not affected.
+func (az *Cloud) getAutoScaledLoadBalancerConfiguration(
+	service *v1.Service,
+	currentLBName string,
+	eligibleLBs []string,
+	existingLBs *[]network.LoadBalancer,
+	isInternal bool,
+) (config.MultipleStandardLoadBalancerConfiguration, bool) {
+	if StringInSlice(currentLBName, eligibleLBs) {
+		return config.MultipleStandardLoadBalancerConfiguration{}, false
+	}
+	for _, eligibleLB := range eligibleLBs {
+		if !az.isLoadBalancerFullForService(service, eligibleLB, existingLBs, isInternal) {
+			return config.MultipleStandardLoadBalancerConfiguration{}, false
+		}
+	}
+
+	serviceName := getServiceName(service)
+	for _, eligibleLB := range eligibleLBs {
+		source, found := az.getMultipleStandardLoadBalancerConfiguration(eligibleLB)
+		if !found {
+			continue
+		}
+		if source.GeneratedFrom != "" {
+			if source, found = az.getMultipleStandardLoadBalancerConfiguration(source.GeneratedFrom); !found {
+				continue
+			}
+		}
+		if source.AutoScale == nil {
+			continue
+		}
+
+		for i := 1; i <= source.AutoScale.MaxCount; i++ {
+			name := getAutoScaledLoadBalancerName(source, i)
+			if _, found := az.getMultipleStandardLoadBalancerConfiguration(name); found {
+				continue
+			}
+			return newAutoScaledLoadBalancerConfiguration(source, name), true
+		}
+		klog.Warningf("getAutoScaledLoadBalancerConfiguration: load balancer configuration %s has generated the maximum %d load balancers", source.Name, source.AutoScale.MaxCount)
+	}
+
+	klog.Warningf("getAutoScaledLoadBalancerConfiguration: all eligible load balancers %v of service %s have reached the maximum rule count %d", eligibleLBs, serviceName, az.MaximumLoadBalancerRuleCount)
+	return config.MultipleStandardLoadBalancerConfiguration{}, false
+}
+
+// autoScaleMultipleStandardLoadBalancers generates a new load balancer configuration if none of the eligible
+// load balancers can accommodate the service. The new configuration is persisted before it is added to
+// MultipleStandardLoadBalancerConfigurations, which is only changed with serviceReconcileLock held.
+func (az *Cloud) autoScaleMultipleStandardLoadBalancers(ctx context.Context, service *v1.Service, existingLBs *[]network.LoadBalancer) error {
+	if !az.UseMultipleStandardLoadBalancers() {
+		return nil
+	}
+	eligibleLBs, err := az.getEligibleLoadBalancersForService(ctx, service)
+	if err != nil {
+		return err
+	}
+	currentLBName := az.getServiceCurrentLoadBalancerName(service)
+	generated, found := az.getAutoScaledLoadBalancerConfiguration(service, currentLBName, eligibleLBs, existingLBs, requiresInternalLoadBalancer(service))
+	if !found {
+		return nil
+	}
+	if p := getServicePlanner(ctx); p != nil {
+		p.addNote("load balancer %s would be generated from %s, it is not planned", generated.Name, generated.GeneratedFrom)
+		return nil
+	}
+
+	klog.V(2).Infof("autoScaleMultipleStandardLoadBalancers: all eligible load balancers %v of service %s have reached the maximum rule count %d, generating load balancer %s from %s",
+		eligibleLBs, getServiceName(service), az.MaximumLoadBalancerRuleCount, generated.Name, generated.GeneratedFrom)
+	multiSLBConfigs := append(az.MultipleStandardLoadBalancerConfigurations[:len(az.MultipleStandardLoadBalancerConfigurations):len(az.MultipleStandardLoadBalancerConfigurations)], generated)
+	if err := az.updateMultipleStandardLoadBalancerState(ctx, func(state *multipleStandardLoadBalancerState) {
+		state.GeneratedConfigurations = getGeneratedLoadBalancerConfigurations(multiSLBConfigs)
+	}); err != nil {
+		return fmt.Errorf("failed to persist load balancer configuration %s: %w", generated.Name, err)
+	}
+	az.MultipleStandardLoadBalancerConfigurations = multiSLBConfigs
+	return nil
+}
+
+// recordAutoScaledLoadBalancerConfigurations restores the load balancer configurations generated by AutoScale from the
+// ConfigMap each time the cloud provider restarts. The existing load balancers of the restored configurations are
+// appended to existingLBs, because they are not listed as managed load balancers before.
+func (az *Cloud) recordAutoScaledLoadBalancerConfigurations(ctx context.Context, service *v1.Service, existingLBs *[]network.LoadBalancer) error {
+	state, err := az.getMultipleStandardLoadBalancerState(ctx)
+	if err != nil {
+		return err
+	}
+
+	restoredNames := utilsets.NewString()
+	for _, generated := range state.GeneratedConfigurations {
+		if _, found := az.getMultipleStandardLoadBalancerConfiguration(generated.Name); found {
+			continue
+		}
+		klog.V(2).Infof("recordAutoScaledLoadBalancerConfigurations: restoring load balancer configuration %s generated from %s", generated.Name, generated.GeneratedFrom)
+		az.MultipleStandardLoadBalancerConfigurations = append(az.MultipleStandardLoadBalancerConfigurations, config.MultipleStandardLoadBalancerConfiguration{
+			Name: generated.Name,
+			MultipleStandardLoadBalancerConfigurationSpec: generated.Spec,
+			MultipleStandardLoadBalancerConfigurationStatus: config.MultipleStandardLoadBalancerConfigurationStatus{
+				GeneratedFrom: generated.GeneratedFrom,
+			},
+		})
+		restoredNames.Insert(generated.Name)
+	}
+	if restoredNames.Len() == 0 || existingLBs == nil {
+		return nil
+	}
+
+	allLBs, err := az.ListLB(ctx, service)
+	if err != nil {
+		return err
+	}
+	for _, lb := range allLBs {
+		if restoredNames.Has(trimSuffixIgnoreCase(ptr.Deref(lb.Name, ""), consts.InternalLoadBalancerNameSuffix)) &&
+			!isLBInListByName(existingLBs, ptr.Deref(lb.Name, "")) {
+			*existingLBs = append(*existingLBs, lb)
+		}
+	}
+	return nil
+}
+
+// isLBInListByName checks if the lb is in the list by its exact name.
+func isLBInListByName(lbs *[]network.LoadBalancer, lbName string) bool {
+	for _, lb := range *lbs {
+		if strings.EqualFold(ptr.Deref(lb.Name, ""), lbName) {
+			return true
+		}
+	}
+	return false
+}
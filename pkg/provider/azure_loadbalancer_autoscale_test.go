@@ -0,0 +1,265 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2022-07-01/network"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/loadbalancerclient/mockloadbalancerclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+	"sigs.k8s.io/cloud-provider-azure/pkg/provider/config"
+	utilsets "sigs.k8s.io/cloud-provider-azure/pkg/util/sets"
+)
+
+func getTestLoadBalancerWithRuleCount(name string, ruleCount int) network.LoadBalancer {
+	rules := make([]network.LoadBalancingRule, ruleCount)
+	return network.LoadBalancer{
+		Name: ptr.To(name),
+		LoadBalancerPropertiesFormat: &network.LoadBalancerPropertiesFormat{
+			FrontendIPConfigurations: &[]network.FrontendIPConfiguration{
+				{
+					FrontendIPConfigurationPropertiesFormat: &network.FrontendIPConfigurationPropertiesFormat{
+						PublicIPAddress: &network.PublicIPAddress{ID: ptr.To("pip")},
+					},
+				},
+			},
+			LoadBalancingRules: &rules,
+		},
+	}
+}
+
+func TestGetAutoScaledLoadBalancerName(t *testing.T) {
+	multiSLBConfig := config.MultipleStandardLoadBalancerConfiguration{
+		Name: "lb",
+		MultipleStandardLoadBalancerConfigurationSpec: config.MultipleStandardLoadBalancerConfigurationSpec{
+			AutoScale: &config.MultipleStandardLoadBalancerAutoScale{MaxCount: 2},
+		},
+	}
+	assert.Equal(t, "lb-1", getAutoScaledLoadBalancerName(multiSLBConfig, 1))
+
+	multiSLBConfig.AutoScale.NameTemplate = "shard{index}-{name}"
+	assert.Equal(t, "shard2-lb", getAutoScaledLoadBalancerName(multiSLBConfig, 2))
+}
+
+func TestGetAutoScaledLoadBalancerConfiguration(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	svc := getTestService("svc", v1.ProtocolTCP, nil, false, 80)
+	labelSelector := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+	for _, tc := range []struct {
+		description       string
+		currentLBName     string
+		eligibleLBs       []string
+		existingLBs       []network.LoadBalancer
+		expectedGenerated string
+	}{
+		{
+			description:   "should not generate load balancers if the service is using an eligible one",
+			currentLBName: "kubernetes",
+			eligibleLBs:   []string{"kubernetes"},
+			existingLBs:   []network.LoadBalancer{getTestLoadBalancerWithRuleCount("kubernetes", 2)},
+		},
+		{
+			description: "should not generate load balancers if an eligible one is not full",
+			eligibleLBs: []string{"kubernetes", "lb1"},
+			existingLBs: []network.LoadBalancer{getTestLoadBalancerWithRuleCount("kubernetes", 2), getTestLoadBalancerWithRuleCount("lb1", 1)},
+		},
+		{
+			description:       "should generate a load balancer if all eligible ones are full",
+			eligibleLBs:       []string{"kubernetes"},
+			existingLBs:       []network.LoadBalancer{getTestLoadBalancerWithRuleCount("kubernetes", 2)},
+			expectedGenerated: "kubernetes-1",
+		},
+		{
+			description: "should not generate load balancers from the configuration without auto scale",
+			eligibleLBs: []string{"lb1"},
+			existingLBs: []network.LoadBalancer{getTestLoadBalancerWithRuleCount("lb1", 2)},
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			az := GetTestCloud(ctrl)
+			az.MaximumLoadBalancerRuleCount = 2
+			az.MultipleStandardLoadBalancerConfigurations = []config.MultipleStandardLoadBalancerConfiguration{
+				{
+					Name: "kubernetes",
+					MultipleStandardLoadBalancerConfigurationSpec: config.MultipleStandardLoadBalancerConfigurationSpec{
+						PrimaryVMSet:         "vmss-0",
+						ServiceLabelSelector: labelSelector,
+						AutoScale:            &config.MultipleStandardLoadBalancerAutoScale{MaxCount: 2},
+					},
+				},
+				{Name: "lb1"},
+			}
+
+			generated, found := az.getAutoScaledLoadBalancerConfiguration(&svc, tc.currentLBName, tc.eligibleLBs, &tc.existingLBs, false)
+			assert.Equal(t, tc.expectedGenerated != "", found)
+			assert.Equal(t, tc.expectedGenerated, generated.Name)
+			if found {
+				assert.Equal(t, "kubernetes", generated.GeneratedFrom)
+				assert.Equal(t, labelSelector, generated.ServiceLabelSelector)
+				assert.Empty(t, generated.PrimaryVMSet)
+				assert.Nil(t, generated.AutoScale)
+			}
+			// The load balancer configurations are not changed.
+			assert.Len(t, az.MultipleStandardLoadBalancerConfigurations, 2)
+		})
+	}
+}
+
+func TestAutoScaleMultipleStandardLoadBalancers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	az := GetTestCloud(ctrl)
+	az.LoadBalancerSku = consts.LoadBalancerSkuStandard
+	az.MaximumLoadBalancerRuleCount = 2
+	az.KubeClient = fake.NewSimpleClientset()
+	az.MultipleStandardLoadBalancerConfigurations = []config.MultipleStandardLoadBalancerConfiguration{
+		{
+			Name: "kubernetes",
+			MultipleStandardLoadBalancerConfigurationSpec: config.MultipleStandardLoadBalancerConfigurationSpec{
+				PrimaryVMSet: "vmss-0",
+				AutoScale:    &config.MultipleStandardLoadBalancerAutoScale{MaxCount: 1},
+			},
+		},
+	}
+	svc := getTestService("svc", v1.ProtocolTCP, nil, false, 80)
+	existingLBs := []network.LoadBalancer{getTestLoadBalancerWithRuleCount("kubernetes", 2)}
+
+	// The load balancer name is chosen without changing the load balancer configurations.
+	lbName, err := az.getAzureLoadBalancerName(context.TODO(), &svc, &existingLBs, "kubernetes", "vmss-0", false)
+	assert.NoError(t, err)
+	assert.Equal(t, "kubernetes-1", lbName)
+	assert.Len(t, az.MultipleStandardLoadBalancerConfigurations, 1)
+
+	err = az.autoScaleMultipleStandardLoadBalancers(context.TODO(), &svc, &existingLBs)
+	assert.NoError(t, err)
+	assert.Len(t, az.MultipleStandardLoadBalancerConfigurations, 2)
+	assert.Equal(t, "kubernetes-1", az.MultipleStandardLoadBalancerConfigurations[1].Name)
+	state, err := az.getMultipleStandardLoadBalancerState(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, []generatedLoadBalancerConfiguration{{Name: "kubernetes-1", GeneratedFrom: "kubernetes"}}, state.GeneratedConfigurations)
+
+	lbName, err = az.getAzureLoadBalancerName(context.TODO(), &svc, &existingLBs, "kubernetes", "vmss-0", false)
+	assert.NoError(t, err)
+	assert.Equal(t, "kubernetes-1", lbName)
+
+	// The maximum count is reached.
+	existingLBs = append(existingLBs, getTestLoadBalancerWithRuleCount("kubernetes-1", 2))
+	err = az.autoScaleMultipleStandardLoadBalancers(context.TODO(), &svc, &existingLBs)
+	assert.NoError(t, err)
+	assert.Len(t, az.MultipleStandardLoadBalancerConfigurations, 2)
+
+	// The load balancer configuration is not generated if it cannot be persisted.
+	az.KubeClient = nil
+	az.MultipleStandardLoadBalancerConfigurations = az.MultipleStandardLoadBalancerConfigurations[:1]
+	existingLBs = existingLBs[:1]
+	err = az.autoScaleMultipleStandardLoadBalancers(context.TODO(), &svc, &existingLBs)
+	assert.Error(t, err)
+	assert.Len(t, az.MultipleStandardLoadBalancerConfigurations, 1)
+}
+
+func TestGetEligibleLoadBalancersWithAutoScaledLoadBalancers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	az := GetTestCloud(ctrl)
+	az.MultipleStandardLoadBalancerConfigurations = []config.MultipleStandardLoadBalancerConfiguration{
+		{Name: "kubernetes"},
+		{Name: "lb1"},
+		{
+			Name: "lb1-1",
+			MultipleStandardLoadBalancerConfigurationStatus: config.MultipleStandardLoadBalancerConfigurationStatus{
+				GeneratedFrom: "lb1",
+			},
+		},
+	}
+	svc := getTestService("svc", v1.ProtocolTCP, map[string]string{consts.ServiceAnnotationLoadBalancerConfigurations: "lb1"}, false, 80)
+
+	eligibleLBs, err := az.getEligibleLoadBalancersForService(context.TODO(), &svc)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"lb1", "lb1-1"}, eligibleLBs)
+}
+
+func TestRecordAutoScaledLoadBalancerConfigurations(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	az := GetTestCloud(ctrl)
+	az.KubeClient = fake.NewSimpleClientset()
+	az.MultipleStandardLoadBalancerConfigurations = []config.MultipleStandardLoadBalancerConfiguration{
+		{
+			Name: "kubernetes",
+			MultipleStandardLoadBalancerConfigurationSpec: config.MultipleStandardLoadBalancerConfigurationSpec{
+				PrimaryVMSet: "vmss-0",
+				AutoScale:    &config.MultipleStandardLoadBalancerAutoScale{MaxCount: 3},
+			},
+			MultipleStandardLoadBalancerConfigurationStatus: config.MultipleStandardLoadBalancerConfigurationStatus{
+				ActiveServices: utilsets.NewString("default/svc"),
+			},
+		},
+	}
+	labelSelector := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+	err := az.updateMultipleStandardLoadBalancerState(context.TODO(), func(state *multipleStandardLoadBalancerState) {
+		state.GeneratedConfigurations = []generatedLoadBalancerConfiguration{
+			{Name: "kubernetes-1", GeneratedFrom: "kubernetes", Spec: config.MultipleStandardLoadBalancerConfigurationSpec{ServiceLabelSelector: labelSelector}},
+			{Name: "kubernetes-3", GeneratedFrom: "kubernetes"},
+		}
+	})
+	assert.NoError(t, err)
+
+	allLBs := []network.LoadBalancer{
+		getTestLoadBalancerWithRuleCount("kubernetes", 1),
+		getTestLoadBalancerWithRuleCount("kubernetes-1", 1),
+		getTestLoadBalancerWithRuleCount("kubernetes-2", 1),
+		getTestLoadBalancerWithRuleCount("kubernetes-3-internal", 1),
+		getTestLoadBalancerWithRuleCount("unmanaged", 1),
+	}
+	mockLBClient := az.LoadBalancerClient.(*mockloadbalancerclient.MockInterface)
+	mockLBClient.EXPECT().List(gomock.Any(), az.ResourceGroup).Return(allLBs, nil)
+
+	existingLBs := []network.LoadBalancer{allLBs[0]}
+	svc := getTestService("svc", v1.ProtocolTCP, nil, false, 80)
+	err = az.recordAutoScaledLoadBalancerConfigurations(context.TODO(), &svc, &existingLBs)
+	assert.NoError(t, err)
+
+	var configNames []string
+	for _, multiSLBConfig := range az.MultipleStandardLoadBalancerConfigurations {
+		configNames = append(configNames, multiSLBConfig.Name)
+	}
+	assert.Equal(t, []string{"kubernetes", "kubernetes-1", "kubernetes-3"}, configNames)
+	assert.Equal(t, labelSelector, az.MultipleStandardLoadBalancerConfigurations[1].ServiceLabelSelector)
+	assert.Equal(t, "kubernetes", az.MultipleStandardLoadBalancerConfigurations[1].GeneratedFrom)
+	assert.Equal(t, []network.LoadBalancer{allLBs[0], allLBs[1], allLBs[3]}, existingLBs)
+
+	// Restoring again does nothing.
+	err = az.recordAutoScaledLoadBalancerConfigurations(context.TODO(), &svc, &existingLBs)
+	assert.NoError(t, err)
+	assert.Len(t, az.MultipleStandardLoadBalancerConfigurations, 3)
+	assert.Len(t, existingLBs, 3)
+}
@@ -67,7 +67,14 @@ func newBackendPoolTypeNodeIPConfig(c *Cloud) BackendPool {
 	return &backendPoolTypeNodeIPConfig{c}
 }
 
-func (bc *backendPoolTypeNodeIPConfig) EnsureHostsInPool(ctx context.Context, service *v1.Service, nodes []*v1.Node, backendPoolID, vmSetName, _, _ string, _ network.BackendAddressPool) error {
+func (bc *backendPoolTypeNodeIPConfig) EnsureHostsInPool(ctx context.Context, service *v1.Service, nodes []*v1.Node, backendPoolID, vmSetName, clusterName, lbName string, _ network.BackendAddressPool) error {
+	if bc.UseApplicationSecurityGroups {
+		// The nodes join the application security group of the load balancer together with the backend pool.
+		if err := bc.ensureApplicationSecurityGroup(ctx, clusterName, lbName); err != nil {
+			klog.Errorf("bc.EnsureHostsInPool: failed to ensure application security group %s: %v", lbName, err)
+			return err
+		}
+	}
 	return bc.VMSet.EnsureHostsInPool(ctx, service, nodes, backendPoolID, vmSetName)
 }
 
@@ -791,6 +798,190 @@ func (bi *backendPoolTypeNodeIP) getBackendPoolNodeNames(bp *network.BackendAddr
 	return nodeNames.UnsortedList()
 }
 
+type backendPoolTypePodIP struct {
+	*Cloud
+}
+
+func newBackendPoolTypePodIP(c *Cloud) BackendPool {
+	return &backendPoolTypePodIP{c}
+}
+
+// EnsureHostsInPool ensures the ready pod IPs of the service join the backend pool of the service.
+// The pod IPs are taken from the EndpointSlices of the service, so the nodes are not used.
+func (bp *backendPoolTypePodIP) EnsureHostsInPool(ctx context.Context, service *v1.Service, _ []*v1.Node, _, _, clusterName, lbName string, backendPool network.BackendAddressPool) error {
+	isIPv6 := isBackendPoolIPv6(ptr.Deref(backendPool.Name, ""))
+	lbBackendPoolName := bp.getBackendPoolNameForService(service, clusterName, isIPv6)
+	if !strings.EqualFold(ptr.Deref(backendPool.Name, ""), lbBackendPoolName) {
+		return nil
+	}
+
+	key := strings.ToLower(getServiceName(service))
+	if si, found := bp.getLocalServiceInfo(key); found && !strings.EqualFold(si.lbName, lbName) {
+		klog.V(4).InfoS("bp.EnsureHostsInPool: the service is not on the load balancer",
+			"service", key,
+			"previous load balancer", lbName,
+			"current load balancer", si.lbName)
+		return nil
+	}
+
+	expectedIPs := utilsets.NewString()
+	for podIP := range bp.getServiceEndpointsPodIPs(service) {
+		if utilnet.IsIPv6String(podIP) == isIPv6 {
+			expectedIPs.Insert(podIP)
+		}
+	}
+
+	var (
+		changed                bool
+		numOfAdd, numOfDelete  int
+		podIPsToBeAdded        []string
+		retainedBackendAddress []network.LoadBalancerBackendAddress
+	)
+	existingIPs := utilsets.NewString()
+	if backendPool.BackendAddressPoolPropertiesFormat != nil && backendPool.LoadBalancerBackendAddresses != nil {
+		for _, address := range *backendPool.LoadBalancerBackendAddresses {
+			ip := ""
+			if address.LoadBalancerBackendAddressPropertiesFormat != nil {
+				ip = ptr.Deref(address.IPAddress, "")
+			}
+			if !expectedIPs.Has(ip) {
+				klog.V(4).Infof("bp.EnsureHostsInPool: removing IP %q from the backend pool %s because the pod is deleted or not ready", ip, lbBackendPoolName)
+				changed = true
+				numOfDelete++
+				continue
+			}
+			existingIPs.Insert(ip)
+			retainedBackendAddress = append(retainedBackendAddress, address)
+		}
+	}
+	for _, podIP := range expectedIPs.UnsortedList() {
+		if !existingIPs.Has(podIP) {
+			klog.V(6).Infof("bp.EnsureHostsInPool: adding pod IP %s", podIP)
+			podIPsToBeAdded = append(podIPsToBeAdded, podIP)
+			numOfAdd++
+		}
+	}
+
+	if backendPool.BackendAddressPoolPropertiesFormat == nil {
+		backendPool.BackendAddressPoolPropertiesFormat = &network.BackendAddressPoolPropertiesFormat{}
+	}
+	backendPool.LoadBalancerBackendAddresses = &retainedBackendAddress
+	if bp.addNodeIPAddressesToBackendPool(&backendPool, podIPsToBeAdded) {
+		changed = true
+	}
+
+	if changed {
+		klog.V(2).Infof("bp.EnsureHostsInPool: updating backend pool %s of load balancer %s to add %d pod IPs and remove %d pod IPs", lbBackendPoolName, lbName, numOfAdd, numOfDelete)
+		if err := bp.CreateOrUpdateLBBackendPool(ctx, lbName, backendPool); err != nil {
+			return fmt.Errorf("bp.EnsureHostsInPool: failed to update backend pool %s: %w", lbBackendPoolName, err)
+		}
+	}
+
+	return nil
+}
+
+// CleanupVMSetFromBackendPoolByCondition removes the pods running on the nodes of the unwanted vmSet
+// from the backend pools of the service.
+func (bp *backendPoolTypePodIP) CleanupVMSetFromBackendPoolByCondition(ctx context.Context, slb *network.LoadBalancer, service *v1.Service, nodes []*v1.Node, clusterName string, shouldRemoveVMSetFromSLB func(string) bool) (*network.LoadBalancer, error) {
+	if slb.LoadBalancerPropertiesFormat == nil || slb.BackendAddressPools == nil {
+		return slb, nil
+	}
+
+	nodeNamesToBeDeleted := utilsets.NewString()
+	for _, node := range nodes {
+		vmSetName, err := bp.VMSet.GetNodeVMSetName(ctx, node)
+		if err != nil {
+			return nil, err
+		}
+		if shouldRemoveVMSetFromSLB(vmSetName) {
+			nodeNamesToBeDeleted.Insert(node.Name)
+		}
+	}
+	if nodeNamesToBeDeleted.Len() == 0 {
+		return slb, nil
+	}
+
+	podIPsToBeDeleted := utilsets.NewString()
+	for podIP, nodeName := range bp.getServiceEndpointsPodIPs(service) {
+		if nodeNamesToBeDeleted.Has(nodeName) {
+			podIPsToBeDeleted.Insert(podIP)
+		}
+	}
+
+	lbBackendPoolNames := bp.getBackendPoolNamesForService(service, clusterName)
+	for i, backendPool := range *slb.BackendAddressPools {
+		if found, _ := isLBBackendPoolsExisting(lbBackendPoolNames, backendPool.Name); !found {
+			klog.V(10).Infof("bp.CleanupVMSetFromBackendPoolByCondition: found unmanaged backendpool %s from standard load balancer %q", ptr.Deref(backendPool.Name, ""), ptr.Deref(slb.Name, ""))
+			continue
+		}
+		if backendPool.BackendAddressPoolPropertiesFormat == nil || backendPool.LoadBalancerBackendAddresses == nil {
+			continue
+		}
+
+		var (
+			changed   bool
+			addresses []network.LoadBalancerBackendAddress
+		)
+		for _, address := range *backendPool.LoadBalancerBackendAddresses {
+			if address.LoadBalancerBackendAddressPropertiesFormat != nil &&
+				podIPsToBeDeleted.Has(ptr.Deref(address.IPAddress, "")) {
+				klog.V(4).Infof("bp.CleanupVMSetFromBackendPoolByCondition: removing pod IP %s from the backend pool %s", ptr.Deref(address.IPAddress, ""), ptr.Deref(backendPool.Name, ""))
+				changed = true
+				continue
+			}
+			addresses = append(addresses, address)
+		}
+		if changed {
+			backendPool.LoadBalancerBackendAddresses = &addresses
+			(*slb.BackendAddressPools)[i] = backendPool
+			klog.V(2).Infof("bp.CleanupVMSetFromBackendPoolByCondition: updating backend pool %s of load balancer %s", ptr.Deref(backendPool.Name, ""), ptr.Deref(slb.Name, ""))
+			if err := bp.CreateOrUpdateLBBackendPool(ctx, ptr.Deref(slb.Name, ""), backendPool); err != nil {
+				return nil, fmt.Errorf("bp.CleanupVMSetFromBackendPoolByCondition: "+
+					"failed to create or update backend pool %s: %w", ptr.Deref(backendPool.Name, ""), err)
+			}
+		}
+	}
+
+	return slb, nil
+}
+
+// ReconcileBackendPools creates the backend pools of the service if they do not exist.
+// Pre-configured backend pools are not supported because every service has its own backend pools.
+func (bp *backendPoolTypePodIP) ReconcileBackendPools(_ context.Context, clusterName string, service *v1.Service, lb *network.LoadBalancer) (bool, bool, *network.LoadBalancer, error) {
+	serviceName := getServiceName(service)
+	lbBackendPoolNames := bp.getBackendPoolNamesForService(service, clusterName)
+
+	foundBackendPools := map[bool]bool{}
+	if lb.LoadBalancerPropertiesFormat != nil && lb.BackendAddressPools != nil {
+		for _, backendPool := range *lb.BackendAddressPools {
+			if found, isIPv6 := isLBBackendPoolsExisting(lbBackendPoolNames, backendPool.Name); found {
+				klog.V(10).Infof("bp.ReconcileBackendPools for service (%s): found wanted backendpool. Not adding anything", serviceName)
+				foundBackendPools[isIPv6] = true
+			}
+		}
+	}
+
+	var backendPoolsUpdated bool
+	for _, ipFamily := range service.Spec.IPFamilies {
+		isIPv6 := ipFamily == v1.IPv6Protocol
+		if foundBackendPools[isIPv6] {
+			continue
+		}
+		klog.V(2).Infof("bp.ReconcileBackendPools for service (%s): creating backend pool %s", serviceName, lbBackendPoolNames[isIPv6])
+		_ = newBackendPool(lb, false, bp.PreConfiguredBackendPoolLoadBalancerTypes, serviceName, lbBackendPoolNames[isIPv6])
+		backendPoolsUpdated = true
+	}
+
+	return false, backendPoolsUpdated, lb, nil
+}
+
+// GetBackendPrivateIPs returns the pod IPs in the backend pools of the service.
+func (bp *backendPoolTypePodIP) GetBackendPrivateIPs(ctx context.Context, clusterName string, service *v1.Service, lb *network.LoadBalancer) ([]string, []string) {
+	// The backend pools are IP-based, which only differ from the nodeIP ones by
+	// the pool names, and those are resolved by getBackendPoolNamesForService.
+	return newBackendPoolTypeNodeIP(bp.Cloud).GetBackendPrivateIPs(ctx, clusterName, service, lb)
+}
+
 func newBackendPool(lb *network.LoadBalancer, isBackendPoolPreConfigured bool, preConfiguredBackendPoolLoadBalancerTypes, serviceName, lbBackendPoolName string) bool {
 	if isBackendPoolPreConfigured {
 		klog.V(2).Infof("newBackendPool for service (%s)(true): lb backendpool - PreConfiguredBackendPoolLoadBalancerTypes %s has been set but can not find corresponding backend pool %q, ignoring it",
@@ -839,6 +1030,10 @@ func (az *Cloud) addNodeIPAddressesToBackendPool(backendPool *network.BackendAdd
 	for _, ipAddress := range nodeIPAddresses {
 		if !hasIPAddressInBackendPool(backendPool, ipAddress) {
 			name := az.nodePrivateIPToNodeNameMap[ipAddress]
+			if name == "" && az.IsLBBackendPoolTypePodIP() {
+				// Pod IPs are not mapped to any node, so use the IP as the name of the backend address.
+				name = getBackendAddressNameForPodIP(ipAddress)
+			}
 			klog.V(4).Infof("bi.addNodeIPAddressesToBackendPool: adding %s to the backend pool %s", ipAddress, ptr.Deref(backendPool.Name, ""))
 			addresses = append(addresses, network.LoadBalancerBackendAddress{
 				Name: ptr.To(name),
@@ -922,3 +1117,9 @@ func removeNodeIPAddressesFromBackendPool(
 
 	return changed
 }
+
+// getBackendAddressNameForPodIP returns a valid backend address name for the pod IP.
+// Colons are not allowed in the name so they are replaced for IPv6 addresses.
+func getBackendAddressNameForPodIP(podIP string) string {
+	return strings.ReplaceAll(podIP, ":", "-")
+}
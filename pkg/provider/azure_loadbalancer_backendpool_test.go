@@ -29,6 +29,7 @@ import (
 	"go.uber.org/mock/gomock"
 
 	v1 "k8s.io/api/core/v1"
+	discovery_v1 "k8s.io/api/discovery/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
 	cloudprovider "k8s.io/cloud-provider"
@@ -1168,3 +1169,174 @@ func TestGetBackendIPConfigurationsToBeDeleted(t *testing.T) {
 		assert.Equal(t, tc.expected, actual)
 	}
 }
+
+func getTestPodIPEndpointSlice(name, namespace, svcName string, podIPsToNodeNames map[string]string) *discovery_v1.EndpointSlice {
+	es := getTestEndpointSlice(name, namespace, svcName)
+	for podIP, nodeName := range podIPsToNodeNames {
+		es.Endpoints = append(es.Endpoints, discovery_v1.Endpoint{
+			Addresses: []string{podIP},
+			NodeName:  ptr.To(nodeName),
+		})
+	}
+	return es
+}
+
+func TestEnsureHostsInPoolPodIP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	notReadyES := getTestPodIPEndpointSlice("eps2", "default", "test", map[string]string{"10.244.0.9": "node1"})
+	notReadyES.Endpoints[0].Conditions.Ready = ptr.To(false)
+
+	for _, tc := range []struct {
+		desc                string
+		backendPool         network.BackendAddressPool
+		endpointSlices      []*discovery_v1.EndpointSlice
+		serviceLBName       string
+		expectedBackendPool *network.BackendAddressPool
+	}{
+		{
+			desc:        "should add ready pod IPs and remove stale ones",
+			backendPool: getTestBackendAddressPoolWithIPs("lb1", "default-test", []string{"10.244.0.1", "10.244.0.2"}),
+			endpointSlices: []*discovery_v1.EndpointSlice{
+				getTestPodIPEndpointSlice("eps1", "default", "test", map[string]string{"10.244.0.2": "node1", "10.244.1.3": "node2", "fd00::3": "node2"}),
+				notReadyES,
+				getTestPodIPEndpointSlice("eps3", "default", "another", map[string]string{"10.244.1.4": "node2"}),
+			},
+			expectedBackendPool: func() *network.BackendAddressPool {
+				bp := getTestBackendAddressPoolWithIPs("lb1", "default-test", []string{"10.244.0.2"})
+				*bp.LoadBalancerBackendAddresses = append(*bp.LoadBalancerBackendAddresses, network.LoadBalancerBackendAddress{
+					Name: ptr.To("10.244.1.3"),
+					LoadBalancerBackendAddressPropertiesFormat: &network.LoadBalancerBackendAddressPropertiesFormat{
+						IPAddress: ptr.To("10.244.1.3"),
+					},
+				})
+				return &bp
+			}(),
+		},
+		{
+			desc:           "should not update the backend pool if nothing changes",
+			backendPool:    getTestBackendAddressPoolWithIPs("lb1", "default-test", []string{"10.244.0.2"}),
+			endpointSlices: []*discovery_v1.EndpointSlice{getTestPodIPEndpointSlice("eps1", "default", "test", map[string]string{"10.244.0.2": "node1"})},
+		},
+		{
+			desc:           "should skip backend pools of other services",
+			backendPool:    getTestBackendAddressPoolWithIPs("lb1", "default-another", []string{"10.244.0.2"}),
+			endpointSlices: []*discovery_v1.EndpointSlice{getTestPodIPEndpointSlice("eps1", "default", "test", map[string]string{"10.244.0.3": "node1"})},
+		},
+		{
+			desc:           "should skip if the service has been moved to another load balancer",
+			backendPool:    getTestBackendAddressPoolWithIPs("lb1", "default-test", []string{"10.244.0.2"}),
+			endpointSlices: []*discovery_v1.EndpointSlice{getTestPodIPEndpointSlice("eps1", "default", "test", map[string]string{"10.244.0.3": "node1"})},
+			serviceLBName:  "lb2",
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			az := GetTestCloud(ctrl)
+			az.LoadBalancerBackendPoolConfigurationType = consts.LoadBalancerBackendPoolConfigurationTypePODIP
+			for _, es := range tc.endpointSlices {
+				az.endpointSlicesCache.Store(strings.ToLower(fmt.Sprintf("%s/%s", es.Namespace, es.Name)), es)
+			}
+			if tc.serviceLBName != "" {
+				az.localServiceNameToServiceInfoMap.Store("default/test", newServiceInfo(consts.IPVersionIPv4String, tc.serviceLBName))
+			}
+			lbClient := mockloadbalancerclient.NewMockInterface(ctrl)
+			if tc.expectedBackendPool != nil {
+				lbClient.EXPECT().CreateOrUpdateBackendPools(gomock.Any(), gomock.Any(), "lb1", "default-test", *tc.expectedBackendPool, gomock.Any()).Return(nil)
+			}
+			az.LoadBalancerClient = lbClient
+
+			service := getTestService("test", v1.ProtocolTCP, nil, false, 80)
+			bp := newBackendPoolTypePodIP(az)
+			err := bp.EnsureHostsInPool(context.TODO(), &service, nil, "", "", "kubernetes", "lb1", tc.backendPool)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestReconcileBackendPoolsPodIP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	az := GetTestCloud(ctrl)
+	az.LoadBalancerBackendPoolConfigurationType = consts.LoadBalancerBackendPoolConfigurationTypePODIP
+	bp := newBackendPoolTypePodIP(az)
+
+	service := getTestServiceDualStack("test", v1.ProtocolTCP, nil, 80)
+	lb := buildLBWithVMIPs("kubernetes", []string{"10.0.0.1"})
+	*lb.BackendAddressPools = append(*lb.BackendAddressPools, network.BackendAddressPool{
+		Name:                               ptr.To("default-test"),
+		BackendAddressPoolPropertiesFormat: &network.BackendAddressPoolPropertiesFormat{},
+	})
+
+	preConfigured, updated, updatedLB, err := bp.ReconcileBackendPools(context.TODO(), "kubernetes", &service, lb)
+	assert.NoError(t, err)
+	assert.False(t, preConfigured)
+	assert.True(t, updated)
+	assert.Equal(t, 3, len(*updatedLB.BackendAddressPools))
+	assert.Equal(t, "default-test-ipv6", ptr.Deref((*updatedLB.BackendAddressPools)[2].Name, ""))
+
+	_, updated, _, err = bp.ReconcileBackendPools(context.TODO(), "kubernetes", &service, updatedLB)
+	assert.NoError(t, err)
+	assert.False(t, updated)
+}
+
+func TestCleanupVMSetFromBackendPoolByConditionPodIP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	az := GetTestCloud(ctrl)
+	az.LoadBalancerBackendPoolConfigurationType = consts.LoadBalancerBackendPoolConfigurationTypePODIP
+	es := getTestPodIPEndpointSlice("eps1", "default", "test", map[string]string{"10.244.0.1": "node1", "10.244.1.1": "node2"})
+	az.endpointSlicesCache.Store("default/eps1", es)
+
+	nodes := []*v1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node2"}},
+	}
+	mockVMSet := NewMockVMSet(ctrl)
+	mockVMSet.EXPECT().GetNodeVMSetName(gomock.Any(), nodes[0]).Return("vmss-1", nil)
+	mockVMSet.EXPECT().GetNodeVMSetName(gomock.Any(), nodes[1]).Return("vmss-2", nil)
+	az.VMSet = mockVMSet
+
+	lb := &network.LoadBalancer{
+		Name: ptr.To("lb1"),
+		LoadBalancerPropertiesFormat: &network.LoadBalancerPropertiesFormat{
+			BackendAddressPools: &[]network.BackendAddressPool{
+				getTestBackendAddressPoolWithIPs("lb1", "default-test", []string{"10.244.0.1", "10.244.1.1"}),
+			},
+		},
+	}
+	expectedBackendPool := getTestBackendAddressPoolWithIPs("lb1", "default-test", []string{"10.244.1.1"})
+	lbClient := mockloadbalancerclient.NewMockInterface(ctrl)
+	lbClient.EXPECT().CreateOrUpdateBackendPools(gomock.Any(), gomock.Any(), "lb1", "default-test", expectedBackendPool, gomock.Any()).Return(nil)
+	az.LoadBalancerClient = lbClient
+
+	service := getTestService("test", v1.ProtocolTCP, nil, false, 80)
+	bp := newBackendPoolTypePodIP(az)
+	cleanedLB, err := bp.CleanupVMSetFromBackendPoolByCondition(context.TODO(), lb, &service, nodes, "kubernetes", func(vmSetName string) bool {
+		return vmSetName == "vmss-1"
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, expectedBackendPool, (*cleanedLB.BackendAddressPools)[0])
+}
+
+func TestGetBackendPrivateIPsPodIP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	az := GetTestCloud(ctrl)
+	az.LoadBalancerBackendPoolConfigurationType = consts.LoadBalancerBackendPoolConfigurationTypePODIP
+	bp := newBackendPoolTypePodIP(az)
+
+	service := getTestServiceDualStack("test", v1.ProtocolTCP, nil, 80)
+	lb := buildLBWithVMIPs("kubernetes", []string{"10.0.0.1"})
+	*lb.BackendAddressPools = append(*lb.BackendAddressPools,
+		getTestBackendAddressPoolWithIPs("kubernetes", "default-test", []string{"10.244.0.1"}),
+		getTestBackendAddressPoolWithIPs("kubernetes", "default-test-ipv6", []string{"fd00::1"}),
+	)
+
+	ipv4, ipv6 := bp.GetBackendPrivateIPs(context.TODO(), "kubernetes", &service, lb)
+	assert.Equal(t, []string{"10.244.0.1"}, ipv4)
+	assert.Equal(t, []string{"fd00::1"}, ipv6)
+}
@@ -43,9 +43,18 @@ func (az *Cloud) buildClusterServiceSharedProbe() *network.Probe {
 	}
 }
 
+// getHealthProbeBackendPort returns the port on the backends that the health probe of the service port targets,
+// which is the node port by default, or the target port of the pods when using podIP backend pool type.
+func (az *Cloud) getHealthProbeBackendPort(service *v1.Service, port v1.ServicePort) (int32, error) {
+	if az.IsLBBackendPoolTypePodIP() {
+		return az.getServicePortTargetPort(service, port)
+	}
+	return port.NodePort, nil
+}
+
 // buildHealthProbeRulesForPort
 // for following sku: basic loadbalancer vs standard load balancer
-// for following protocols: TCP HTTP HTTPS(SLB only)
+// for following protocols: TCP HTTP HTTPS(SLB only) gRPC(SLB only, translated by the health-probe-proxy)
 // return nil if no new probe is added
 func (az *Cloud) buildHealthProbeRulesForPort(serviceManifest *v1.Service, port v1.ServicePort, lbrule string, healthCheckNodePortProbe *network.Probe, useSharedProbe bool) (*network.Probe, error) {
 	if useSharedProbe {
@@ -99,7 +108,11 @@ func (az *Cloud) buildHealthProbeRulesForPort(serviceManifest *v1.Service, port
 			for _, item := range serviceManifest.Spec.Ports {
 				if strings.EqualFold(item.Name, *probePort) {
 					//found the port
-					properties.Port = ptr.To(item.NodePort)
+					backendPort, err := az.getHealthProbeBackendPort(serviceManifest, item)
+					if err != nil {
+						return nil, err
+					}
+					properties.Port = ptr.To(backendPort)
 				}
 			}
 		} else {
@@ -109,7 +122,11 @@ func (az *Cloud) buildHealthProbeRulesForPort(serviceManifest *v1.Service, port
 				//nolint:gosec
 				if item.Port == int32(port) {
 					//found the port
-					properties.Port = ptr.To(item.NodePort)
+					backendPort, err := az.getHealthProbeBackendPort(serviceManifest, item)
+					if err != nil {
+						return nil, err
+					}
+					properties.Port = ptr.To(backendPort)
 					found = true
 					break
 				}
@@ -122,7 +139,11 @@ func (az *Cloud) buildHealthProbeRulesForPort(serviceManifest *v1.Service, port
 	} else if healthCheckNodePortProbe != nil {
 		return nil, nil
 	} else {
-		properties.Port = &port.NodePort
+		backendPort, err := az.getHealthProbeBackendPort(serviceManifest, port)
+		if err != nil {
+			return nil, err
+		}
+		properties.Port = ptr.To(backendPort)
 	}
 	// Select Protocol
 	//
@@ -134,6 +155,10 @@ func (az *Cloud) buildHealthProbeRulesForPort(serviceManifest *v1.Service, port
 		return nil, fmt.Errorf("failed to parse annotation %s: %w", consts.BuildHealthProbeAnnotationKeyForPort(port.Port, consts.HealthProbeParamsProtocol), err)
 	}
 
+	// gRPC probes can only be requested by the port-specific override, so that the existing services
+	// with the `grpc` appProtocol keep falling back to TCP probes.
+	useGRPC := protocol != nil && strings.EqualFold(strings.TrimSpace(*protocol), consts.HealthProbeProtocolGRPC)
+
 	// 2. If not specified, look up from AppProtocol
 	// Note - this order is to remain compatible with previous versions
 	if protocol == nil {
@@ -165,7 +190,7 @@ func (az *Cloud) buildHealthProbeRulesForPort(serviceManifest *v1.Service, port
 		} else {
 			properties.Protocol = network.ProbeProtocolHTTPS
 		}
-	case strings.EqualFold(*protocol, string(network.ProtocolHTTP)):
+	case strings.EqualFold(*protocol, string(network.ProtocolHTTP)), useGRPC:
 		properties.Protocol = network.ProbeProtocolHTTP
 	default:
 		//For backward compatibility,when unsupported protocol is used, fall back to tcp protocol in basic lb mode instead
@@ -190,6 +215,10 @@ func (az *Cloud) buildHealthProbeRulesForPort(serviceManifest *v1.Service, port
 		properties.RequestPath = path
 	}
 
+	if err := az.translateHealthProbeForPort(serviceManifest, port, properties, useGRPC); err != nil {
+		return nil, err
+	}
+
 	properties.IntervalInSeconds, properties.ProbeThreshold, err = az.getHealthProbeConfigProbeIntervalAndNumOfProbe(serviceManifest, port.Port)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse health probe config for port %d: %w", port.Port, err)
@@ -201,6 +230,101 @@ func (az *Cloud) buildHealthProbeRulesForPort(serviceManifest *v1.Service, port
 	return probe, nil
 }
 
+// translateHealthProbeForPort translates the gRPC health checks and the HTTP(S) checks with expected status codes
+// of the service port, which Azure load balancer does not support, into HTTP probes of the health-probe-proxy on
+// the nodes. The request path of the translated probe tells the proxy how to check the backend port:
+//   - /grpc/<port>[/<service>] checks the gRPC health service.
+//   - /http/<port>/<status codes><request path> and /https/<port>/<status codes><request path> check the
+//     request path and expect one of the status codes.
+func (az *Cloud) translateHealthProbeForPort(service *v1.Service, port v1.ServicePort, properties *network.ProbePropertiesFormat, useGRPC bool) error {
+	grpcService, err := consts.GetHealthProbeConfigOfPortFromK8sSvcAnnotation(service.Annotations, port.Port, consts.HealthProbeParamsGRPCService, validateGRPCServiceName)
+	if err != nil {
+		return fmt.Errorf("failed to parse annotation %s: %w", consts.BuildHealthProbeAnnotationKeyForPort(port.Port, consts.HealthProbeParamsGRPCService), err)
+	}
+	if grpcService != nil && !useGRPC {
+		return fmt.Errorf("annotation %s is only supported with the %s health probe protocol",
+			consts.BuildHealthProbeAnnotationKeyForPort(port.Port, consts.HealthProbeParamsGRPCService), consts.HealthProbeProtocolGRPC)
+	}
+	expectedStatusCodes, err := consts.GetHealthProbeConfigOfPortFromK8sSvcAnnotation(service.Annotations, port.Port, consts.HealthProbeParamsExpectedStatusCodes, validateExpectedStatusCodes)
+	if err != nil {
+		return fmt.Errorf("failed to parse annotation %s: %w", consts.BuildHealthProbeAnnotationKeyForPort(port.Port, consts.HealthProbeParamsExpectedStatusCodes), err)
+	}
+	if !useGRPC && expectedStatusCodes == nil {
+		return nil
+	}
+
+	// The basic load balancer falls back to TCP probes for HTTPS, so the translated probes are not supported either.
+	if !az.UseStandardLoadBalancer() {
+		return fmt.Errorf("the health probe of port %d needs the health-probe-proxy, which is only supported with the standard load balancer", port.Port)
+	}
+	if expectedStatusCodes != nil && (useGRPC || properties.Protocol == network.ProbeProtocolTCP) {
+		return fmt.Errorf("annotation %s is only supported with the %s and %s health probe protocols",
+			consts.BuildHealthProbeAnnotationKeyForPort(port.Port, consts.HealthProbeParamsExpectedStatusCodes), network.ProtocolHTTP, network.ProtocolHTTPS)
+	}
+	// The load balancer probes the pods directly with the podIP backend pool type, where the proxy is not running.
+	if az.IsLBBackendPoolTypePodIP() {
+		return fmt.Errorf("the health probe of port %d needs the health-probe-proxy, which is not supported with the %s backend pool type", port.Port, consts.LoadBalancerBackendPoolConfigurationTypePODIP)
+	}
+	if az.HealthProbeProxyPort == 0 {
+		return fmt.Errorf("the health probe of port %d needs the health-probe-proxy, but healthProbeProxyPort is not configured", port.Port)
+	}
+
+	backendPort := ptr.Deref(properties.Port, 0)
+	if useGRPC {
+		path := fmt.Sprintf("/%s/%d", consts.HealthProbeProtocolGRPC, backendPort)
+		if grpcService != nil && *grpcService != "" {
+			path += "/" + strings.TrimSpace(*grpcService)
+		}
+		properties.RequestPath = ptr.To(path)
+	} else {
+		properties.RequestPath = ptr.To(fmt.Sprintf("/%s/%d/%s%s",
+			strings.ToLower(string(properties.Protocol)), backendPort, strings.ReplaceAll(*expectedStatusCodes, " ", ""), ptr.Deref(properties.RequestPath, "")))
+	}
+	properties.Protocol = network.ProbeProtocolHTTP
+	properties.Port = ptr.To(az.HealthProbeProxyPort)
+	return nil
+}
+
+// validateGRPCServiceName validates the service name of the gRPC health check, which is a fully qualified
+// protobuf service name, e.g. `grpc.health.v1.Health`.
+func validateGRPCServiceName(s *string) error {
+	if s == nil {
+		return nil
+	}
+	for _, c := range strings.TrimSpace(*s) {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '_') {
+			return fmt.Errorf("invalid gRPC service name %q", *s)
+		}
+	}
+	return nil
+}
+
+// validateExpectedStatusCodes validates the comma separated status codes or ranges of status codes, e.g. `200-299,401`.
+func validateExpectedStatusCodes(s *string) error {
+	if s == nil {
+		return nil
+	}
+	for _, item := range strings.Split(*s, ",") {
+		item = strings.TrimSpace(item)
+		from, to, isRange := strings.Cut(item, "-")
+		if !isRange {
+			to = from
+		}
+		fromCode, err := strconv.Atoi(strings.TrimSpace(from))
+		if err != nil {
+			return fmt.Errorf("invalid status code %q", item)
+		}
+		toCode, err := strconv.Atoi(strings.TrimSpace(to))
+		if err != nil {
+			return fmt.Errorf("invalid status code %q", item)
+		}
+		if fromCode < 100 || toCode > 599 || fromCode > toCode {
+			return fmt.Errorf("invalid status code %q, the status codes must be in the range of 100-599", item)
+		}
+	}
+	return nil
+}
+
 // getHealthProbeConfigProbeIntervalAndNumOfProbe
 func (az *Cloud) getHealthProbeConfigProbeIntervalAndNumOfProbe(serviceManifest *v1.Service, port int32) (*int32, *int32, error) {
 
@@ -377,3 +377,149 @@ func TestShouldKeepSharedProbe(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildHealthProbeRulesForPortWithTranslation(t *testing.T) {
+	port := v1.ServicePort{Name: "grpc", Protocol: v1.ProtocolTCP, Port: 80, NodePort: 30080}
+	for _, tc := range []struct {
+		desc                string
+		annotations         map[string]string
+		sku                 string
+		backendPoolType     string
+		proxyPort           int32
+		expectedProtocol    network.ProbeProtocol
+		expectedPort        int32
+		expectedRequestPath string
+		expectedErr         string
+	}{
+		{
+			desc: "gRPC probe is translated to an HTTP probe of the proxy",
+			annotations: map[string]string{
+				consts.BuildHealthProbeAnnotationKeyForPort(80, consts.HealthProbeParamsProtocol): "gRPC",
+			},
+			expectedProtocol:    network.ProbeProtocolHTTP,
+			expectedPort:        10356,
+			expectedRequestPath: "/grpc/30080",
+		},
+		{
+			desc: "gRPC probe checks the service",
+			annotations: map[string]string{
+				consts.BuildHealthProbeAnnotationKeyForPort(80, consts.HealthProbeParamsProtocol):    "grpc",
+				consts.BuildHealthProbeAnnotationKeyForPort(80, consts.HealthProbeParamsGRPCService): "my.pkg.Greeter",
+			},
+			expectedProtocol:    network.ProbeProtocolHTTP,
+			expectedPort:        10356,
+			expectedRequestPath: "/grpc/30080/my.pkg.Greeter",
+		},
+		{
+			desc: "HTTPS probe with expected status codes is translated",
+			annotations: map[string]string{
+				consts.BuildHealthProbeAnnotationKeyForPort(80, consts.HealthProbeParamsProtocol):            "https",
+				consts.BuildHealthProbeAnnotationKeyForPort(80, consts.HealthProbeParamsRequestPath):         "/readyz",
+				consts.BuildHealthProbeAnnotationKeyForPort(80, consts.HealthProbeParamsExpectedStatusCodes): "200-299, 401",
+			},
+			expectedProtocol:    network.ProbeProtocolHTTP,
+			expectedPort:        10356,
+			expectedRequestPath: "/https/30080/200-299,401/readyz",
+		},
+		{
+			desc: "HTTP probe without expected status codes is not translated",
+			annotations: map[string]string{
+				consts.BuildHealthProbeAnnotationKeyForPort(80, consts.HealthProbeParamsProtocol): "http",
+			},
+			expectedProtocol:    network.ProbeProtocolHTTP,
+			expectedPort:        30080,
+			expectedRequestPath: "/",
+		},
+		{
+			desc: "gRPC protocol of the global annotation still falls back to TCP",
+			annotations: map[string]string{
+				consts.ServiceAnnotationLoadBalancerHealthProbeProtocol: "grpc",
+			},
+			expectedProtocol: network.ProbeProtocolTCP,
+			expectedPort:     30080,
+		},
+		{
+			desc: "gRPC service without the gRPC protocol",
+			annotations: map[string]string{
+				consts.BuildHealthProbeAnnotationKeyForPort(80, consts.HealthProbeParamsGRPCService): "my.pkg.Greeter",
+			},
+			expectedErr: "is only supported with the grpc health probe protocol",
+		},
+		{
+			desc: "invalid gRPC service",
+			annotations: map[string]string{
+				consts.BuildHealthProbeAnnotationKeyForPort(80, consts.HealthProbeParamsProtocol):    "grpc",
+				consts.BuildHealthProbeAnnotationKeyForPort(80, consts.HealthProbeParamsGRPCService): "my/Greeter",
+			},
+			expectedErr: "invalid gRPC service name",
+		},
+		{
+			desc: "expected status codes with TCP",
+			annotations: map[string]string{
+				consts.BuildHealthProbeAnnotationKeyForPort(80, consts.HealthProbeParamsExpectedStatusCodes): "200",
+			},
+			expectedErr: "is only supported with the Http and Https health probe protocols",
+		},
+		{
+			desc: "invalid expected status codes",
+			annotations: map[string]string{
+				consts.BuildHealthProbeAnnotationKeyForPort(80, consts.HealthProbeParamsProtocol):            "http",
+				consts.BuildHealthProbeAnnotationKeyForPort(80, consts.HealthProbeParamsExpectedStatusCodes): "299-200",
+			},
+			expectedErr: "invalid status code",
+		},
+		{
+			desc: "translated probe with the basic load balancer",
+			annotations: map[string]string{
+				consts.BuildHealthProbeAnnotationKeyForPort(80, consts.HealthProbeParamsProtocol): "grpc",
+			},
+			sku:         consts.LoadBalancerSkuBasic,
+			expectedErr: "only supported with the standard load balancer",
+		},
+		{
+			desc: "translated probe with the podIP backend pool type",
+			annotations: map[string]string{
+				consts.BuildHealthProbeAnnotationKeyForPort(80, consts.HealthProbeParamsProtocol): "grpc",
+			},
+			backendPoolType: consts.LoadBalancerBackendPoolConfigurationTypePODIP,
+			expectedErr:     "not supported with the podIP backend pool type",
+		},
+		{
+			desc: "translated probe without the proxy port",
+			annotations: map[string]string{
+				consts.BuildHealthProbeAnnotationKeyForPort(80, consts.HealthProbeParamsProtocol): "grpc",
+			},
+			proxyPort:   -1,
+			expectedErr: "healthProbeProxyPort is not configured",
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			az := GetTestCloud(ctrl)
+			az.LoadBalancerSku = consts.LoadBalancerSkuStandard
+			if tc.sku != "" {
+				az.LoadBalancerSku = tc.sku
+			}
+			if tc.backendPoolType != "" {
+				az.LoadBalancerBackendPoolConfigurationType = tc.backendPoolType
+			}
+			az.HealthProbeProxyPort = 10356
+			if tc.proxyPort < 0 {
+				az.HealthProbeProxyPort = 0
+			}
+			svc := getTestService("test1", v1.ProtocolTCP, tc.annotations, false, 80)
+			svc.Spec.Ports = []v1.ServicePort{port}
+
+			probe, err := az.buildHealthProbeRulesForPort(&svc, port, "rule", nil, false)
+			if tc.expectedErr != "" {
+				assert.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedProtocol, probe.Protocol)
+			assert.Equal(t, tc.expectedPort, ptr.Deref(probe.Port, 0))
+			assert.Equal(t, tc.expectedRequestPath, ptr.Deref(probe.RequestPath, ""))
+		})
+	}
+}
@@ -0,0 +1,448 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2022-07-01/network"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+)
+
+// getInboundNatFrontendPortRange parses the inbound NAT frontend port range annotation of the port.
+// It returns false if the annotation is not set.
+func getInboundNatFrontendPortRange(annotations map[string]string, port int32) (int32, int32, bool, error) {
+	key := consts.BuildAnnotationKeyForPort(port, consts.PortAnnotationInboundNATFrontendPortRange)
+	val, found := annotations[key]
+	if !found {
+		return 0, 0, false, nil
+	}
+
+	const (
+		min = 1
+		max = 65534
+	)
+	parts := strings.Split(strings.TrimSpace(val), "-")
+	if len(parts) != 2 {
+		return 0, 0, false, fmt.Errorf("invalid value %q of annotation %s: the frontend port range must be in the form of <start>-<end>", val, key)
+	}
+	start, err := strconv.ParseInt(strings.TrimSpace(parts[0]), 10, 32)
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("invalid value %q of annotation %s: %w", val, key, err)
+	}
+	end, err := strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 32)
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("invalid value %q of annotation %s: %w", val, key, err)
+	}
+	if start < min || end > max || start > end {
+		return 0, 0, false, fmt.Errorf("invalid value %q of annotation %s: the frontend ports must be between %d and %d, and the start port must not be greater than the end port", val, key, min, max)
+	}
+	return int32(start), int32(end), true, nil
+}
+
+// hasServiceInboundNatRules checks if any port of the service has an inbound NAT frontend port range.
+func hasServiceInboundNatRules(service *v1.Service) bool {
+	for _, port := range service.Spec.Ports {
+		if _, found := service.Annotations[consts.BuildAnnotationKeyForPort(port.Port, consts.PortAnnotationInboundNATFrontendPortRange)]; found {
+			return true
+		}
+	}
+	return false
+}
+
+func (az *Cloud) getInboundNatRuleName(service *v1.Service, protocol v1.Protocol, port int32, isIPv6 bool) string {
+	ruleName := fmt.Sprintf("%s-nat-%s-%d", az.getRulePrefix(service), protocol, port)
+	return getResourceByIPFamily(ruleName, isServiceDualStack(service), isIPv6)
+}
+
+// serviceOwnsInboundNatRule checks if the inbound NAT rule is created by the cloud provider for the service.
+func (az *Cloud) serviceOwnsInboundNatRule(service *v1.Service, rule string) bool {
+	prefix := fmt.Sprintf("%s-nat-", az.getRulePrefix(service))
+	return strings.HasPrefix(strings.ToUpper(rule), strings.ToUpper(prefix))
+}
+
+// getInboundNatPortMappingKey returns the key of the port in the inbound NAT port mappings annotation.
+func getInboundNatPortMappingKey(service *v1.Service, protocol v1.Protocol, port int32, isIPv6 bool) string {
+	return getResourceByIPFamily(fmt.Sprintf("%s/%d", protocol, port), isServiceDualStack(service), isIPv6)
+}
+
+// getExpectedInboundNatRules returns the inbound NAT rules of the service on the load balancer. There is one
+// inbound NAT rule for each port with a frontend port range and each IP family, which maps one frontend port
+// in the range to each backend of the backend pool of the service.
+func (az *Cloud) getExpectedInboundNatRules(
+	service *v1.Service,
+	lbFrontendIPConfigIDs map[bool]string,
+	lbBackendPoolIDs map[bool]string,
+) ([]network.InboundNatRule, error) {
+	if !hasServiceInboundNatRules(service) {
+		return nil, nil
+	}
+	if !az.UseStandardLoadBalancer() {
+		return nil, fmt.Errorf("inbound NAT frontend port ranges are only supported on standard load balancers")
+	}
+	// The inbound NAT rules send the traffic to the backend IPs, which is only allowed by the
+	// security rules of the service when floating IP is disabled.
+	if !consts.IsK8sServiceDisableLoadBalancerFloatingIP(service) && !az.IsLBBackendPoolTypePodIP() {
+		return nil, fmt.Errorf("inbound NAT frontend port ranges require annotation %s to be %s",
+			consts.ServiceAnnotationDisableLoadBalancerFloatingIP, consts.TrueAnnotationValue)
+	}
+
+	var rules []network.InboundNatRule
+	v4Enabled, v6Enabled := getIPFamiliesEnabled(service)
+	for _, port := range service.Spec.Ports {
+		start, end, found, err := getInboundNatFrontendPortRange(service.Annotations, port.Port)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			continue
+		}
+		if port.Protocol == v1.ProtocolSCTP {
+			return nil, fmt.Errorf("inbound NAT rules are not supported on SCTP port %d", port.Port)
+		}
+		for _, other := range service.Spec.Ports {
+			if other.Protocol == port.Protocol && other.Port >= start && other.Port <= end {
+				return nil, fmt.Errorf("the inbound NAT frontend port range %d-%d of port %d overlaps with the service port %d", start, end, port.Port, other.Port)
+			}
+		}
+		for _, rule := range rules {
+			if strings.EqualFold(string(rule.Protocol), string(port.Protocol)) &&
+				*rule.FrontendPortRangeStart <= end && *rule.FrontendPortRangeEnd >= start {
+				return nil, fmt.Errorf("the inbound NAT frontend port range %d-%d of port %d overlaps with the range %d-%d of another port",
+					start, end, port.Port, *rule.FrontendPortRangeStart, *rule.FrontendPortRangeEnd)
+			}
+		}
+
+		transportProto, _, _, err := getProtocolsFromKubernetesProtocol(port.Protocol)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse transport protocol: %w", err)
+		}
+		// The backend pool contains pod IPs when using podIP backend pool type, otherwise the
+		// traffic is sent to the node port of the nodes.
+		backendPort := port.NodePort
+		if az.IsLBBackendPoolTypePodIP() {
+			if backendPort, err = az.getServicePortTargetPort(service, port); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, isIPv6 := range []bool{consts.IPVersionIPv4, consts.IPVersionIPv6} {
+			if (!isIPv6 && !v4Enabled) || (isIPv6 && !v6Enabled) {
+				continue
+			}
+			props := &network.InboundNatRulePropertiesFormat{
+				FrontendIPConfiguration: &network.SubResource{ID: ptr.To(lbFrontendIPConfigIDs[isIPv6])},
+				BackendAddressPool:      &network.SubResource{ID: ptr.To(lbBackendPoolIDs[isIPv6])},
+				Protocol:                *transportProto,
+				FrontendPortRangeStart:  ptr.To(start),
+				FrontendPortRangeEnd:    ptr.To(end),
+				BackendPort:             ptr.To(backendPort),
+				EnableFloatingIP:        ptr.To(false),
+			}
+			if strings.EqualFold(string(*transportProto), string(network.TransportProtocolTCP)) {
+				props.EnableTCPReset = ptr.To(!consts.IsTCPResetDisabled(service.Annotations))
+			}
+			rules = append(rules, network.InboundNatRule{
+				Name:                           ptr.To(az.getInboundNatRuleName(service, port.Protocol, port.Port, isIPv6)),
+				InboundNatRulePropertiesFormat: props,
+			})
+		}
+	}
+	return rules, nil
+}
+
+// checkInboundNatRulesConflicts checks if the frontend port ranges of the inbound NAT rules of the service are
+// consuming the frontend ports of other load balancing rules, inbound NAT rules or inbound NAT pools.
+func (az *Cloud) checkInboundNatRulesConflicts(lb *network.LoadBalancer, service *v1.Service, expectedRules []network.InboundNatRule) error {
+	if lb.LoadBalancerPropertiesFormat == nil {
+		return nil
+	}
+	for _, expectedRule := range expectedRules {
+		fipID := *expectedRule.FrontendIPConfiguration.ID
+		start, end := *expectedRule.FrontendPortRangeStart, *expectedRule.FrontendPortRangeEnd
+		sameFrontend := func(fip *network.SubResource, protocol network.TransportProtocol) bool {
+			return fip != nil && strings.EqualFold(ptr.Deref(fip.ID, ""), fipID) &&
+				strings.EqualFold(string(protocol), string(expectedRule.Protocol))
+		}
+
+		if lb.LoadBalancingRules != nil {
+			for _, rule := range *lb.LoadBalancingRules {
+				if rule.LoadBalancingRulePropertiesFormat == nil || az.serviceOwnsRule(service, ptr.Deref(rule.Name, "")) {
+					continue
+				}
+				if sameFrontend(rule.FrontendIPConfiguration, rule.Protocol) && rule.FrontendPort != nil &&
+					*rule.FrontendPort >= start && *rule.FrontendPort <= end {
+					return fmt.Errorf("checkInboundNatRulesConflicts: the frontend port range %d-%d of inbound NAT rule %s overlaps with "+
+						"the port %d of the existing loadBalancing rule %s", start, end, *expectedRule.Name, *rule.FrontendPort, ptr.Deref(rule.Name, ""))
+				}
+			}
+		}
+
+		if lb.InboundNatRules != nil {
+			for _, rule := range *lb.InboundNatRules {
+				if rule.InboundNatRulePropertiesFormat == nil || az.serviceOwnsInboundNatRule(service, ptr.Deref(rule.Name, "")) ||
+					!sameFrontend(rule.FrontendIPConfiguration, rule.Protocol) {
+					continue
+				}
+				ruleStart, ruleEnd := ptr.Deref(rule.FrontendPortRangeStart, 0), ptr.Deref(rule.FrontendPortRangeEnd, 0)
+				if rule.FrontendPort != nil {
+					ruleStart, ruleEnd = *rule.FrontendPort, *rule.FrontendPort
+				}
+				if ruleStart != 0 && ruleStart <= end && ruleEnd >= start {
+					return fmt.Errorf("checkInboundNatRulesConflicts: the frontend port range %d-%d of inbound NAT rule %s overlaps with "+
+						"the ports %d-%d of the existing inbound NAT rule %s", start, end, *expectedRule.Name, ruleStart, ruleEnd, ptr.Deref(rule.Name, ""))
+				}
+			}
+		}
+
+		if lb.InboundNatPools != nil {
+			for _, pool := range *lb.InboundNatPools {
+				if pool.InboundNatPoolPropertiesFormat == nil || !sameFrontend(pool.FrontendIPConfiguration, pool.Protocol) ||
+					pool.FrontendPortRangeStart == nil || pool.FrontendPortRangeEnd == nil {
+					continue
+				}
+				if *pool.FrontendPortRangeStart <= end && *pool.FrontendPortRangeEnd >= start {
+					return fmt.Errorf("checkInboundNatRulesConflicts: the frontend port range %d-%d of inbound NAT rule %s overlaps with "+
+						"the range %d-%d of the existing inbound NAT pool %s", start, end, *expectedRule.Name,
+						*pool.FrontendPortRangeStart, *pool.FrontendPortRangeEnd, ptr.Deref(pool.Name, ""))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// reconcileLBInboundNatRules reconciles the inbound NAT rules owned by the service, and returns true if they are changed.
+// The inbound NAT rules of other services and the ones not created by the cloud provider are kept.
+func (az *Cloud) reconcileLBInboundNatRules(lb *network.LoadBalancer, service *v1.Service, serviceName string, wantLb bool, expectedRules []network.InboundNatRule) bool {
+	if lb.LoadBalancerPropertiesFormat == nil {
+		return false
+	}
+
+	dirtyRules := false
+	var updatedRules []network.InboundNatRule
+	if lb.InboundNatRules != nil {
+		updatedRules = *lb.InboundNatRules
+	}
+
+	// update rules: remove unwanted
+	for i := len(updatedRules) - 1; i >= 0; i-- {
+		existingRule := updatedRules[i]
+		if az.serviceOwnsInboundNatRule(service, ptr.Deref(existingRule.Name, "")) && !findInboundNatRule(expectedRules, existingRule) {
+			klog.V(2).Infof("reconcileLoadBalancer for service (%s)(%t): lb inbound NAT rule(%s) - dropping", serviceName, wantLb, ptr.Deref(existingRule.Name, ""))
+			updatedRules = append(updatedRules[:i], updatedRules[i+1:]...)
+			dirtyRules = true
+		}
+	}
+	// update rules: add needed
+	for _, expectedRule := range expectedRules {
+		if !findInboundNatRule(updatedRules, expectedRule) {
+			klog.V(2).Infof("reconcileLoadBalancer for service (%s)(%t): lb inbound NAT rule(%s) - adding", serviceName, wantLb, ptr.Deref(expectedRule.Name, ""))
+			updatedRules = append(updatedRules, expectedRule)
+			dirtyRules = true
+		}
+	}
+	if dirtyRules {
+		lb.InboundNatRules = &updatedRules
+	}
+	return dirtyRules
+}
+
+func findInboundNatRule(rules []network.InboundNatRule, rule network.InboundNatRule) bool {
+	for _, existingRule := range rules {
+		if strings.EqualFold(ptr.Deref(existingRule.Name, ""), ptr.Deref(rule.Name, "")) &&
+			equalInboundNatRulePropertiesFormat(existingRule.InboundNatRulePropertiesFormat, rule.InboundNatRulePropertiesFormat) {
+			return true
+		}
+	}
+	return false
+}
+
+// equalInboundNatRulePropertiesFormat checks whether the provided InboundNatRulePropertiesFormat are equal.
+// Note: only fields set in getExpectedInboundNatRules are considered.
+// s: existing, t: target
+func equalInboundNatRulePropertiesFormat(s, t *network.InboundNatRulePropertiesFormat) bool {
+	if s == nil || t == nil {
+		return false
+	}
+	return equalSubResource(s.FrontendIPConfiguration, t.FrontendIPConfiguration) &&
+		equalSubResource(s.BackendAddressPool, t.BackendAddressPool) &&
+		reflect.DeepEqual(s.Protocol, t.Protocol) &&
+		ptr.Deref(s.FrontendPortRangeStart, 0) == ptr.Deref(t.FrontendPortRangeStart, 0) &&
+		ptr.Deref(s.FrontendPortRangeEnd, 0) == ptr.Deref(t.FrontendPortRangeEnd, 0) &&
+		ptr.Deref(s.BackendPort, 0) == ptr.Deref(t.BackendPort, 0) &&
+		ptr.Deref(s.EnableFloatingIP, false) == ptr.Deref(t.EnableFloatingIP, false) &&
+		ptr.Deref(s.EnableTCPReset, false) == ptr.Deref(t.EnableTCPReset, false)
+}
+
+// getInboundNatPortMappings returns the frontend ports of the backends of the inbound NAT rules of the service,
+// keyed by the protocol and port of the service. The port mappings are read from the backend pools referenced
+// by the inbound NAT rules, because they are only allocated after the backends are added to the pools.
+func (az *Cloud) getInboundNatPortMappings(ctx context.Context, service *v1.Service, lb *network.LoadBalancer) (map[string]map[string]int32, error) {
+	if lb == nil || lb.LoadBalancerPropertiesFormat == nil || lb.InboundNatRules == nil {
+		return nil, nil
+	}
+
+	ruleNameToKey := make(map[string]string)
+	for _, port := range service.Spec.Ports {
+		for _, isIPv6 := range []bool{consts.IPVersionIPv4, consts.IPVersionIPv6} {
+			ruleName := az.getInboundNatRuleName(service, port.Protocol, port.Port, isIPv6)
+			ruleNameToKey[strings.ToLower(ruleName)] = getInboundNatPortMappingKey(service, port.Protocol, port.Port, isIPv6)
+		}
+	}
+
+	lbName := ptr.Deref(lb.Name, "")
+	poolNames := make(map[string]bool)
+	for _, rule := range *lb.InboundNatRules {
+		if _, found := ruleNameToKey[strings.ToLower(ptr.Deref(rule.Name, ""))]; !found ||
+			rule.InboundNatRulePropertiesFormat == nil || rule.BackendAddressPool == nil {
+			continue
+		}
+		poolName, err := getLastSegment(ptr.Deref(rule.BackendAddressPool.ID, ""), "/")
+		if err != nil {
+			return nil, err
+		}
+		poolNames[poolName] = true
+	}
+
+	var mappings map[string]map[string]int32
+	for poolName := range poolNames {
+		bp, rerr := az.LoadBalancerClient.GetLBBackendPool(ctx, az.getLoadBalancerResourceGroup(), lbName, poolName, "")
+		if rerr != nil {
+			return nil, fmt.Errorf("failed to get backend pool %s of load balancer %s: %w", poolName, lbName, rerr.Error())
+		}
+		if bp.BackendAddressPoolPropertiesFormat == nil || bp.LoadBalancerBackendAddresses == nil {
+			continue
+		}
+		for _, address := range *bp.LoadBalancerBackendAddresses {
+			if address.LoadBalancerBackendAddressPropertiesFormat == nil || address.InboundNatRulesPortMapping == nil {
+				continue
+			}
+			backend := az.getInboundNatBackendName(ctx, address)
+			for _, mapping := range *address.InboundNatRulesPortMapping {
+				key, found := ruleNameToKey[strings.ToLower(ptr.Deref(mapping.InboundNatRuleName, ""))]
+				if !found || mapping.FrontendPort == nil {
+					continue
+				}
+				if mappings == nil {
+					mappings = make(map[string]map[string]int32)
+				}
+				if mappings[key] == nil {
+					mappings[key] = make(map[string]int32)
+				}
+				mappings[key][backend] = *mapping.FrontendPort
+			}
+		}
+	}
+	return mappings, nil
+}
+
+// getInboundNatBackendName returns the node name of the backend address, or the pod IP
+// for the podIP backend pool type.
+func (az *Cloud) getInboundNatBackendName(ctx context.Context, address network.LoadBalancerBackendAddress) string {
+	if ipAddress := ptr.Deref(address.IPAddress, ""); ipAddress != "" {
+		if az.IsLBBackendPoolTypePodIP() {
+			return ipAddress
+		}
+		if name := ptr.Deref(address.Name, ""); name != "" {
+			return name
+		}
+		return ipAddress
+	}
+	if address.NetworkInterfaceIPConfiguration != nil {
+		ipConfigID := ptr.Deref(address.NetworkInterfaceIPConfiguration.ID, "")
+		nodeName, _, err := az.VMSet.GetNodeNameByIPConfigurationID(ctx, ipConfigID)
+		if err != nil || nodeName == "" {
+			klog.Warningf("getInboundNatBackendName: failed to get the node name of ip configuration %s: %v", ipConfigID, err)
+			return ipConfigID
+		}
+		return nodeName
+	}
+	return ptr.Deref(address.Name, "")
+}
+
+// reconcileInboundNatPortMappings writes the inbound NAT port mappings of the service back to the service annotation.
+func (az *Cloud) reconcileInboundNatPortMappings(ctx context.Context, service *v1.Service, lb *network.LoadBalancer, wantLb bool) error {
+	if p := getServicePlanner(ctx); p != nil {
+		if wantLb && hasServiceInboundNatRules(service) {
+			p.addNote("the frontend ports of the inbound NAT rules are allocated by Azure and are not planned")
+		}
+		return nil
+	}
+
+	var value string
+	if wantLb && hasServiceInboundNatRules(service) {
+		mappings, err := az.getInboundNatPortMappings(ctx, service, lb)
+		if err != nil {
+			return err
+		}
+		if len(mappings) > 0 {
+			data, err := json.Marshal(mappings)
+			if err != nil {
+				return err
+			}
+			value = string(data)
+		}
+	}
+	return az.updateInboundNatPortMappingsAnnotation(ctx, service, value)
+}
+
+// updateInboundNatPortMappingsAnnotation patches the inbound NAT port mappings annotation of the service.
+// The annotation is removed if the value is empty.
+func (az *Cloud) updateInboundNatPortMappingsAnnotation(ctx context.Context, service *v1.Service, value string) error {
+	if current, found := service.Annotations[consts.ServiceAnnotationLoadBalancerInboundNATPortMappings]; current == value && (found || value == "") {
+		return nil
+	}
+	if az.KubeClient == nil {
+		klog.V(2).Infof("updateInboundNatPortMappingsAnnotation: az.KubeClient is nil, skip updating service %s", getServiceName(service))
+		return nil
+	}
+
+	var annotationValue interface{}
+	if value != "" {
+		annotationValue = value
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				consts.ServiceAnnotationLoadBalancerInboundNATPortMappings: annotationValue,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	klog.V(2).Infof("updateInboundNatPortMappingsAnnotation: updating the inbound NAT port mappings of service %s to %q", getServiceName(service), value)
+	_, err = az.KubeClient.CoreV1().Services(service.Namespace).Patch(ctx, service.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to update the inbound NAT port mappings of service %s: %w", getServiceName(service), err)
+	}
+	return nil
+}
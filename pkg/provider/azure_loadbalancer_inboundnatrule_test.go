@@ -0,0 +1,409 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2022-07-01/network"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/loadbalancerclient/mockloadbalancerclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+)
+
+func TestGetInboundNatFrontendPortRange(t *testing.T) {
+	key := consts.BuildAnnotationKeyForPort(80, consts.PortAnnotationInboundNATFrontendPortRange)
+	for _, tc := range []struct {
+		desc          string
+		annotations   map[string]string
+		expectedStart int32
+		expectedEnd   int32
+		expectedFound bool
+		expectedErr   bool
+	}{
+		{
+			desc: "not set",
+		},
+		{
+			desc:          "valid range",
+			annotations:   map[string]string{key: "50000 - 50099"},
+			expectedStart: 50000,
+			expectedEnd:   50099,
+			expectedFound: true,
+		},
+		{
+			desc:          "single port range",
+			annotations:   map[string]string{key: "50000-50000"},
+			expectedStart: 50000,
+			expectedEnd:   50000,
+			expectedFound: true,
+		},
+		{
+			desc:        "single port",
+			annotations: map[string]string{key: "50000"},
+			expectedErr: true,
+		},
+		{
+			desc:        "not a number",
+			annotations: map[string]string{key: "a-b"},
+			expectedErr: true,
+		},
+		{
+			desc:        "start greater than end",
+			annotations: map[string]string{key: "50099-50000"},
+			expectedErr: true,
+		},
+		{
+			desc:        "out of range",
+			annotations: map[string]string{key: "65000-65535"},
+			expectedErr: true,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			start, end, found, err := getInboundNatFrontendPortRange(tc.annotations, 80)
+			assert.Equal(t, tc.expectedErr, err != nil)
+			assert.Equal(t, tc.expectedStart, start)
+			assert.Equal(t, tc.expectedEnd, end)
+			assert.Equal(t, tc.expectedFound, found)
+		})
+	}
+}
+
+func TestGetExpectedInboundNatRules(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	az := GetTestCloud(ctrl)
+	az.LoadBalancerSku = consts.LoadBalancerSkuStandard
+	fipIDs := map[bool]string{
+		consts.IPVersionIPv4: az.getFrontendIPConfigID("lb", "fip"),
+		consts.IPVersionIPv6: az.getFrontendIPConfigID("lb", "fip-IPv6"),
+	}
+	poolIDs := map[bool]string{
+		consts.IPVersionIPv4: az.getBackendPoolID("lb", "kubernetes"),
+		consts.IPVersionIPv6: az.getBackendPoolID("lb", "kubernetes-IPv6"),
+	}
+	portRangeKey := func(port int32) string {
+		return consts.BuildAnnotationKeyForPort(port, consts.PortAnnotationInboundNATFrontendPortRange)
+	}
+
+	t.Run("no inbound NAT rule without the annotation", func(t *testing.T) {
+		svc := getTestService("svc", v1.ProtocolTCP, nil, false, 80)
+		rules, err := az.getExpectedInboundNatRules(&svc, fipIDs, poolIDs)
+		assert.NoError(t, err)
+		assert.Empty(t, rules)
+	})
+
+	t.Run("inbound NAT rules of dual-stack service", func(t *testing.T) {
+		svc := getTestServiceDualStack("svc", v1.ProtocolTCP, map[string]string{
+			consts.ServiceAnnotationDisableLoadBalancerFloatingIP: consts.TrueAnnotationValue,
+			portRangeKey(80): "50000-50099",
+		}, 80, 443)
+		rules, err := az.getExpectedInboundNatRules(&svc, fipIDs, poolIDs)
+		assert.NoError(t, err)
+		expectedRule := func(name string, isIPv6 bool) network.InboundNatRule {
+			return network.InboundNatRule{
+				Name: ptr.To(name),
+				InboundNatRulePropertiesFormat: &network.InboundNatRulePropertiesFormat{
+					FrontendIPConfiguration: &network.SubResource{ID: ptr.To(fipIDs[isIPv6])},
+					BackendAddressPool:      &network.SubResource{ID: ptr.To(poolIDs[isIPv6])},
+					Protocol:                network.TransportProtocolTCP,
+					FrontendPortRangeStart:  ptr.To(int32(50000)),
+					FrontendPortRangeEnd:    ptr.To(int32(50099)),
+					BackendPort:             ptr.To(getBackendPort(80)),
+					EnableFloatingIP:        ptr.To(false),
+					EnableTCPReset:          ptr.To(true),
+				},
+			}
+		}
+		assert.Equal(t, []network.InboundNatRule{
+			expectedRule("asvc-nat-TCP-80", consts.IPVersionIPv4),
+			expectedRule("asvc-nat-TCP-80-IPv6", consts.IPVersionIPv6),
+		}, rules)
+	})
+
+	for _, tc := range []struct {
+		desc        string
+		annotations map[string]string
+		basicSKU    bool
+	}{
+		{
+			desc:        "basic load balancer",
+			annotations: map[string]string{consts.ServiceAnnotationDisableLoadBalancerFloatingIP: consts.TrueAnnotationValue, portRangeKey(80): "50000-50099"},
+			basicSKU:    true,
+		},
+		{
+			desc:        "floating IP enabled",
+			annotations: map[string]string{portRangeKey(80): "50000-50099"},
+		},
+		{
+			desc:        "invalid range",
+			annotations: map[string]string{consts.ServiceAnnotationDisableLoadBalancerFloatingIP: consts.TrueAnnotationValue, portRangeKey(80): "50099-50000"},
+		},
+		{
+			desc:        "range overlaps with a service port",
+			annotations: map[string]string{consts.ServiceAnnotationDisableLoadBalancerFloatingIP: consts.TrueAnnotationValue, portRangeKey(80): "400-500"},
+		},
+		{
+			desc: "ranges overlap with each other",
+			annotations: map[string]string{
+				consts.ServiceAnnotationDisableLoadBalancerFloatingIP: consts.TrueAnnotationValue,
+				portRangeKey(80):  "50000-50099",
+				portRangeKey(443): "50050-50149",
+			},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			az := GetTestCloud(ctrl)
+			if !tc.basicSKU {
+				az.LoadBalancerSku = consts.LoadBalancerSkuStandard
+			}
+			svc := getTestService("svc", v1.ProtocolTCP, tc.annotations, false, 80, 443)
+			_, err := az.getExpectedInboundNatRules(&svc, fipIDs, poolIDs)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestCheckInboundNatRulesConflicts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	az := GetTestCloud(ctrl)
+	svc := getTestService("svc", v1.ProtocolTCP, nil, false, 80)
+	expectedRules := []network.InboundNatRule{
+		{
+			Name: ptr.To("asvc-nat-TCP-80"),
+			InboundNatRulePropertiesFormat: &network.InboundNatRulePropertiesFormat{
+				FrontendIPConfiguration: &network.SubResource{ID: ptr.To("fip")},
+				Protocol:                network.TransportProtocolTCP,
+				FrontendPortRangeStart:  ptr.To(int32(50000)),
+				FrontendPortRangeEnd:    ptr.To(int32(50099)),
+			},
+		},
+	}
+
+	for _, tc := range []struct {
+		desc        string
+		props       network.LoadBalancerPropertiesFormat
+		expectedErr bool
+	}{
+		{
+			desc: "no conflict with the rules of other frontends, other protocols, or the service",
+			props: network.LoadBalancerPropertiesFormat{
+				LoadBalancingRules: &[]network.LoadBalancingRule{
+					{
+						Name: ptr.To("aother-TCP-50000"),
+						LoadBalancingRulePropertiesFormat: &network.LoadBalancingRulePropertiesFormat{
+							FrontendIPConfiguration: &network.SubResource{ID: ptr.To("other-fip")},
+							FrontendPort:            ptr.To(int32(50000)),
+							Protocol:                network.TransportProtocolTCP,
+						},
+					},
+				},
+				InboundNatRules: &[]network.InboundNatRule{
+					{
+						Name: ptr.To("aother-nat-UDP-53"),
+						InboundNatRulePropertiesFormat: &network.InboundNatRulePropertiesFormat{
+							FrontendIPConfiguration: &network.SubResource{ID: ptr.To("fip")},
+							FrontendPortRangeStart:  ptr.To(int32(50000)),
+							FrontendPortRangeEnd:    ptr.To(int32(50099)),
+							Protocol:                network.TransportProtocolUDP,
+						},
+					},
+					{
+						Name: ptr.To("asvc-nat-TCP-443"),
+						InboundNatRulePropertiesFormat: &network.InboundNatRulePropertiesFormat{
+							FrontendIPConfiguration: &network.SubResource{ID: ptr.To("fip")},
+							FrontendPortRangeStart:  ptr.To(int32(50000)),
+							FrontendPortRangeEnd:    ptr.To(int32(50099)),
+							Protocol:                network.TransportProtocolTCP,
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "conflict with a load balancing rule",
+			props: network.LoadBalancerPropertiesFormat{
+				LoadBalancingRules: &[]network.LoadBalancingRule{
+					{
+						Name: ptr.To("aother-TCP-50010"),
+						LoadBalancingRulePropertiesFormat: &network.LoadBalancingRulePropertiesFormat{
+							FrontendIPConfiguration: &network.SubResource{ID: ptr.To("fip")},
+							FrontendPort:            ptr.To(int32(50010)),
+							Protocol:                network.TransportProtocolTCP,
+						},
+					},
+				},
+			},
+			expectedErr: true,
+		},
+		{
+			desc: "conflict with a single port inbound NAT rule",
+			props: network.LoadBalancerPropertiesFormat{
+				InboundNatRules: &[]network.InboundNatRule{
+					{
+						Name: ptr.To("ssh"),
+						InboundNatRulePropertiesFormat: &network.InboundNatRulePropertiesFormat{
+							FrontendIPConfiguration: &network.SubResource{ID: ptr.To("fip")},
+							FrontendPort:            ptr.To(int32(50099)),
+							Protocol:                network.TransportProtocolTCP,
+						},
+					},
+				},
+			},
+			expectedErr: true,
+		},
+		{
+			desc: "conflict with an inbound NAT pool",
+			props: network.LoadBalancerPropertiesFormat{
+				InboundNatPools: &[]network.InboundNatPool{
+					{
+						Name: ptr.To("pool"),
+						InboundNatPoolPropertiesFormat: &network.InboundNatPoolPropertiesFormat{
+							FrontendIPConfiguration: &network.SubResource{ID: ptr.To("fip")},
+							FrontendPortRangeStart:  ptr.To(int32(49990)),
+							FrontendPortRangeEnd:    ptr.To(int32(50000)),
+							Protocol:                network.TransportProtocolTCP,
+						},
+					},
+				},
+			},
+			expectedErr: true,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			lb := &network.LoadBalancer{Name: ptr.To("lb"), LoadBalancerPropertiesFormat: &tc.props}
+			err := az.checkInboundNatRulesConflicts(lb, &svc, expectedRules)
+			assert.Equal(t, tc.expectedErr, err != nil)
+		})
+	}
+}
+
+func TestReconcileLBInboundNatRules(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	az := GetTestCloud(ctrl)
+	az.LoadBalancerSku = consts.LoadBalancerSkuStandard
+	portRangeKey := consts.BuildAnnotationKeyForPort(80, consts.PortAnnotationInboundNATFrontendPortRange)
+	svc := getTestService("svc", v1.ProtocolTCP, map[string]string{
+		consts.ServiceAnnotationDisableLoadBalancerFloatingIP: consts.TrueAnnotationValue,
+		portRangeKey: "50000-50099",
+	}, false, 80)
+	fipIDs := map[bool]string{consts.IPVersionIPv4: az.getFrontendIPConfigID("lb", "asvc")}
+	poolIDs := map[bool]string{consts.IPVersionIPv4: az.getBackendPoolID("lb", "kubernetes")}
+
+	otherRule := network.InboundNatRule{
+		Name: ptr.To("ssh"),
+		InboundNatRulePropertiesFormat: &network.InboundNatRulePropertiesFormat{
+			FrontendIPConfiguration: &network.SubResource{ID: ptr.To(fipIDs[consts.IPVersionIPv4])},
+			FrontendPort:            ptr.To(int32(22)),
+		},
+	}
+	lb := getTestLoadBalancerWithBackendPools(az, "kubernetes")
+	lb.InboundNatRules = &[]network.InboundNatRule{otherRule}
+
+	expectedRules, err := az.getExpectedInboundNatRules(&svc, fipIDs, poolIDs)
+	assert.NoError(t, err)
+	assert.True(t, az.reconcileLBInboundNatRules(lb, &svc, getServiceName(&svc), true, expectedRules))
+	assert.Equal(t, append([]network.InboundNatRule{otherRule}, expectedRules...), *lb.InboundNatRules)
+
+	// The inbound NAT rule is up to date.
+	assert.False(t, az.reconcileLBInboundNatRules(lb, &svc, getServiceName(&svc), true, expectedRules))
+
+	// The inbound NAT rule is updated with the new frontend port range.
+	svc.Annotations[portRangeKey] = "50000-50199"
+	expectedRules, err = az.getExpectedInboundNatRules(&svc, fipIDs, poolIDs)
+	assert.NoError(t, err)
+	assert.True(t, az.reconcileLBInboundNatRules(lb, &svc, getServiceName(&svc), true, expectedRules))
+	assert.Len(t, *lb.InboundNatRules, 2)
+	assert.Equal(t, int32(50199), ptr.Deref((*lb.InboundNatRules)[1].FrontendPortRangeEnd, 0))
+
+	// The inbound NAT rule of the service is removed, and the others are kept.
+	assert.True(t, az.reconcileLBInboundNatRules(lb, &svc, getServiceName(&svc), false, nil))
+	assert.Equal(t, []network.InboundNatRule{otherRule}, *lb.InboundNatRules)
+}
+
+func TestReconcileInboundNatPortMappings(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	az := GetTestCloud(ctrl)
+	az.LoadBalancerSku = consts.LoadBalancerSkuStandard
+	svc := getTestService("svc", v1.ProtocolTCP, map[string]string{
+		consts.ServiceAnnotationDisableLoadBalancerFloatingIP:                                  consts.TrueAnnotationValue,
+		consts.BuildAnnotationKeyForPort(80, consts.PortAnnotationInboundNATFrontendPortRange): "50000-50099",
+	}, false, 80)
+	az.KubeClient = fake.NewSimpleClientset(&svc)
+
+	lb := getTestLoadBalancerWithBackendPools(az, "kubernetes")
+	lb.InboundNatRules = &[]network.InboundNatRule{
+		{
+			Name: ptr.To("asvc-nat-TCP-80"),
+			InboundNatRulePropertiesFormat: &network.InboundNatRulePropertiesFormat{
+				BackendAddressPool: &network.SubResource{ID: ptr.To(az.getBackendPoolID("lb", "kubernetes"))},
+			},
+		},
+	}
+	mockLBClient := az.LoadBalancerClient.(*mockloadbalancerclient.MockInterface)
+	mockLBClient.EXPECT().GetLBBackendPool(gomock.Any(), "rg", "lb", "kubernetes", "").Return(network.BackendAddressPool{
+		BackendAddressPoolPropertiesFormat: &network.BackendAddressPoolPropertiesFormat{
+			LoadBalancerBackendAddresses: &[]network.LoadBalancerBackendAddress{
+				{
+					Name: ptr.To("node-0"),
+					LoadBalancerBackendAddressPropertiesFormat: &network.LoadBalancerBackendAddressPropertiesFormat{
+						IPAddress: ptr.To("10.0.0.4"),
+						InboundNatRulesPortMapping: &[]network.NatRulePortMapping{
+							{InboundNatRuleName: ptr.To("asvc-nat-TCP-80"), FrontendPort: ptr.To(int32(50000)), BackendPort: ptr.To(getBackendPort(80))},
+							{InboundNatRuleName: ptr.To("ssh"), FrontendPort: ptr.To(int32(22)), BackendPort: ptr.To(int32(22))},
+						},
+					},
+				},
+				{
+					Name: ptr.To("node-1"),
+					LoadBalancerBackendAddressPropertiesFormat: &network.LoadBalancerBackendAddressPropertiesFormat{
+						IPAddress: ptr.To("10.0.0.5"),
+						InboundNatRulesPortMapping: &[]network.NatRulePortMapping{
+							{InboundNatRuleName: ptr.To("asvc-nat-TCP-80"), FrontendPort: ptr.To(int32(50001)), BackendPort: ptr.To(getBackendPort(80))},
+						},
+					},
+				},
+			},
+		},
+	}, nil)
+
+	assert.NoError(t, az.reconcileInboundNatPortMappings(context.Background(), &svc, lb, true))
+	updated, err := az.KubeClient.CoreV1().Services(svc.Namespace).Get(context.Background(), svc.Name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"TCP/80":{"node-0":50000,"node-1":50001}}`, updated.Annotations[consts.ServiceAnnotationLoadBalancerInboundNATPortMappings])
+
+	// The annotation is removed after the load balancer of the service is deleted.
+	assert.NoError(t, az.reconcileInboundNatPortMappings(context.Background(), updated, nil, false))
+	updated, err = az.KubeClient.CoreV1().Services(svc.Namespace).Get(context.Background(), svc.Name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.NotContains(t, updated.Annotations, consts.ServiceAnnotationLoadBalancerInboundNATPortMappings)
+}
@@ -0,0 +1,376 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+	"sigs.k8s.io/cloud-provider-azure/pkg/provider/config"
+	utilsets "sigs.k8s.io/cloud-provider-azure/pkg/util/sets"
+)
+
+// serviceMigrationPhase is the phase of the migration of a service.
+type serviceMigrationPhase string
+
+const (
+	serviceMigrationPhaseMigrating serviceMigrationPhase = "Migrating"
+	serviceMigrationPhaseMigrated  serviceMigrationPhase = "Migrated"
+	serviceMigrationPhaseFailed    serviceMigrationPhase = "Failed"
+)
+
+// serviceMigration is the migration of a service off a load balancer, which is persisted in the
+// multiple standard load balancer state so that it is resumed after the cloud provider restarts.
+type serviceMigration struct {
+	// LoadBalancer is the name of the load balancer configuration that the service is moved off.
+	LoadBalancer string `json:"loadBalancer"`
+	// Phase is the phase of the migration.
+	Phase serviceMigrationPhase `json:"phase"`
+	// LastTransitionTime is the last time the phase changed. It is the start time of the migration in progress.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+	// Attempts is the number of times the service has been moved.
+	Attempts int `json:"attempts"`
+}
+
+// serviceMigrator moves the services off the multiple standard load balancers with MigrateServices one at a time.
+// The service being moved is marked in the MigratingServices of the load balancer configuration, which makes the
+// load balancer not eligible for the service, and then the reconciliation of the service is triggered. The frontend
+// IP configuration of the service is moved to another load balancer with the same public IP, which is not deleted
+// with the previous frontend, or with the same private IP address, which is taken from the previous status.
+// The migrator changes the load balancer configurations with serviceReconcileLock held like the reconciliations.
+type serviceMigrator struct {
+	az            *Cloud
+	interval      time.Duration
+	timeout       time.Duration
+	retryInterval time.Duration
+	// migrations are the migrations of the services, which are restored from the state at the first run.
+	migrations map[string]*serviceMigration
+	// dirty is true if the migrations have not been persisted.
+	dirty bool
+}
+
+// StartServiceMigration starts the service migration of multiple standard load balancers if it is enabled,
+// and stops if the context exits.
+func (az *Cloud) StartServiceMigration(ctx context.Context) {
+	if !az.UseMultipleStandardLoadBalancers() {
+		return
+	}
+	migrator := newServiceMigrator(az, consts.ServiceMigrationInterval, consts.ServiceMigrationTimeout, consts.ServiceMigrationRetryInterval)
+	go migrator.run(ctx)
+}
+
+func newServiceMigrator(az *Cloud, interval, timeout, retryInterval time.Duration) *serviceMigrator {
+	return &serviceMigrator{
+		az:            az,
+		interval:      interval,
+		timeout:       timeout,
+		retryInterval: retryInterval,
+	}
+}
+
+// run starts the serviceMigrator, and stops if the context exits.
+func (m *serviceMigrator) run(ctx context.Context) {
+	klog.V(2).Info("serviceMigrator.run: started")
+	err := wait.PollUntilContextCancel(ctx, m.interval, false, func(ctx context.Context) (bool, error) {
+		m.migrate(ctx)
+		return false, nil
+	})
+	klog.Infof("serviceMigrator.run: stopped due to %s", err.Error())
+}
+
+// migrate records the progress of the migrations in progress, starts new ones within the budget and persists the migrations.
+func (m *serviceMigrator) migrate(ctx context.Context) {
+	m.az.serviceReconcileLock.Lock()
+	defer m.az.serviceReconcileLock.Unlock()
+
+	if m.migrations == nil {
+		if err := m.restoreMigrations(ctx); err != nil {
+			klog.Errorf("serviceMigrator.migrate: failed to restore the service migrations: %v", err)
+			return
+		}
+	}
+	defer m.saveMigrations(ctx)
+
+	inProgress := m.updateMigrationStatus()
+
+	budget := m.az.MaxConcurrentServiceMigrations
+	if budget <= 0 {
+		budget = 1
+	}
+	budget -= inProgress
+	if budget <= 0 {
+		klog.V(4).Infof("serviceMigrator.migrate: %d services are being moved, skip", inProgress)
+		return
+	}
+
+	for _, lbName := range m.getMigratingLoadBalancers() {
+		for _, serviceName := range m.getServicesToMigrate(lbName) {
+			if budget <= 0 {
+				return
+			}
+			started, err := m.startMigration(ctx, lbName, serviceName)
+			if err != nil {
+				klog.Errorf("serviceMigrator.migrate: failed to move service %s off load balancer %s: %v", serviceName, lbName, err)
+				continue
+			}
+			if started {
+				budget--
+			}
+		}
+	}
+}
+
+// restoreMigrations restores the migrations from the state, and marks the services being moved or moved
+// in the load balancer configurations.
+func (m *serviceMigrator) restoreMigrations(ctx context.Context) error {
+	state, err := m.az.getMultipleStandardLoadBalancerState(ctx)
+	if err != nil {
+		return err
+	}
+	m.migrations = make(map[string]*serviceMigration)
+	for serviceName, migration := range state.ServiceMigrations {
+		if migration == nil {
+			continue
+		}
+		klog.V(2).Infof("serviceMigrator: restoring the migration of service %s off load balancer %s in phase %s", serviceName, migration.LoadBalancer, migration.Phase)
+		m.migrations[serviceName] = migration
+		m.setServiceMigrationPhase(migration.LoadBalancer, serviceName, migration.Phase)
+	}
+	return nil
+}
+
+// saveMigrations persists the migrations if they are changed. The migrations are persisted again in the next run if it fails.
+func (m *serviceMigrator) saveMigrations(ctx context.Context) {
+	if !m.dirty {
+		return
+	}
+	migrations := make(map[string]*serviceMigration, len(m.migrations))
+	for serviceName, migration := range m.migrations {
+		migration := *migration
+		migrations[serviceName] = &migration
+	}
+	if err := m.az.updateMultipleStandardLoadBalancerState(ctx, func(state *multipleStandardLoadBalancerState) {
+		state.ServiceMigrations = migrations
+	}); err != nil {
+		klog.Errorf("serviceMigrator.saveMigrations: failed to persist the service migrations: %v", err)
+		return
+	}
+	m.dirty = false
+}
+
+// getLoadBalancerConfiguration returns the load balancer configuration with the given name. The returned pointer
+// is only used with serviceReconcileLock held, because the configurations generated by AutoScale are appended with it.
+func (m *serviceMigrator) getLoadBalancerConfiguration(lbName string) *config.MultipleStandardLoadBalancerConfiguration {
+	for i := range m.az.MultipleStandardLoadBalancerConfigurations {
+		if strings.EqualFold(m.az.MultipleStandardLoadBalancerConfigurations[i].Name, lbName) {
+			return &m.az.MultipleStandardLoadBalancerConfigurations[i]
+		}
+	}
+	return nil
+}
+
+// setMigrationPhase changes the phase of the migration of the service and marks it in the load balancer configuration.
+func (m *serviceMigrator) setMigrationPhase(serviceName string, migration *serviceMigration, phase serviceMigrationPhase) {
+	migration.Phase = phase
+	migration.LastTransitionTime = metav1.Now()
+	m.setServiceMigrationPhase(migration.LoadBalancer, serviceName, phase)
+	m.dirty = true
+}
+
+// deleteMigration forgets the migration of the service and unmarks it in the load balancer configuration.
+func (m *serviceMigrator) deleteMigration(serviceName string, migration *serviceMigration) {
+	delete(m.migrations, serviceName)
+	m.setServiceMigrationPhase(migration.LoadBalancer, serviceName, "")
+	m.dirty = true
+}
+
+// updateMigrationStatus marks the migrations of the services that are not using the load balancers any more as migrated,
+// marks the migrations that time out as failed, and forgets the migrations off the load balancers that do not migrate
+// services any more. It returns the number of migrations in progress.
+func (m *serviceMigrator) updateMigrationStatus() int {
+	var inProgress int
+	for serviceName, migration := range m.migrations {
+		multiSLBConfig := m.getLoadBalancerConfiguration(migration.LoadBalancer)
+		if multiSLBConfig == nil || !multiSLBConfig.MigrateServices {
+			klog.V(2).Infof("serviceMigrator: load balancer %s does not migrate services any more, stop moving service %s", migration.LoadBalancer, serviceName)
+			m.deleteMigration(serviceName, migration)
+			continue
+		}
+		if migration.Phase != serviceMigrationPhaseMigrating {
+			continue
+		}
+
+		m.az.multipleStandardLoadBalancersActiveServicesLock.Lock()
+		active := multiSLBConfig.ActiveServices.Has(serviceName)
+		m.az.multipleStandardLoadBalancersActiveServicesLock.Unlock()
+		switch {
+		case !active:
+			klog.V(2).Infof("serviceMigrator: service %s has been moved off load balancer %s", serviceName, migration.LoadBalancer)
+			m.setMigrationPhase(serviceName, migration, serviceMigrationPhaseMigrated)
+		case time.Since(migration.LastTransitionTime.Time) > m.timeout:
+			klog.Warningf("serviceMigrator: failed to move service %s off load balancer %s in %s, will retry in %s", serviceName, migration.LoadBalancer, m.timeout, m.retryInterval)
+			m.setMigrationPhase(serviceName, migration, serviceMigrationPhaseFailed)
+		default:
+			inProgress++
+		}
+	}
+	return inProgress
+}
+
+// getMigratingLoadBalancers returns the names of the load balancers with MigrateServices.
+func (m *serviceMigrator) getMigratingLoadBalancers() []string {
+	var lbNames []string
+	for _, multiSLBConfig := range m.az.MultipleStandardLoadBalancerConfigurations {
+		if multiSLBConfig.MigrateServices {
+			lbNames = append(lbNames, multiSLBConfig.Name)
+		}
+	}
+	return lbNames
+}
+
+// getServicesToMigrate returns the sorted services using the load balancer that are not being moved. The services
+// that failed to be moved are retried after the retry interval.
+func (m *serviceMigrator) getServicesToMigrate(lbName string) []string {
+	multiSLBConfig := m.getLoadBalancerConfiguration(lbName)
+	if multiSLBConfig == nil {
+		return nil
+	}
+	m.az.multipleStandardLoadBalancersActiveServicesLock.Lock()
+	activeServices := multiSLBConfig.ActiveServices.UnsortedList()
+	m.az.multipleStandardLoadBalancersActiveServicesLock.Unlock()
+
+	var serviceNames []string
+	for _, serviceName := range activeServices {
+		if migration, found := m.migrations[serviceName]; found && strings.EqualFold(migration.LoadBalancer, lbName) {
+			if migration.Phase == serviceMigrationPhaseMigrating ||
+				(migration.Phase == serviceMigrationPhaseFailed && time.Since(migration.LastTransitionTime.Time) < m.retryInterval) {
+				continue
+			}
+		}
+		serviceNames = append(serviceNames, serviceName)
+	}
+	sort.Strings(serviceNames)
+	return serviceNames
+}
+
+// startMigration starts to move the service off the load balancer if the service is not pinned and there is
+// another eligible load balancer for it. It returns true if the migration is started.
+func (m *serviceMigrator) startMigration(ctx context.Context, lbName, serviceName string) (bool, error) {
+	if m.az.serviceLister == nil {
+		return false, fmt.Errorf("the service lister is not initialized")
+	}
+	namespace, name, err := cache.SplitMetaNamespaceKey(serviceName)
+	if err != nil {
+		return false, err
+	}
+	service, err := m.az.serviceLister.Services(namespace).Get(name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			klog.V(4).Infof("serviceMigrator.startMigration: service %s is not found, skip", serviceName)
+			return false, nil
+		}
+		return false, err
+	}
+	if strings.EqualFold(service.Annotations[consts.ServiceAnnotationLoadBalancerPinned], consts.TrueAnnotationValue) {
+		klog.V(4).Infof("serviceMigrator.startMigration: service %s is pinned to load balancer %s, skip", serviceName, lbName)
+		return false, nil
+	}
+
+	previous := m.migrations[serviceName]
+	m.setServiceMigrationPhase(lbName, serviceName, serviceMigrationPhaseMigrating)
+	restore := func() {
+		if previous != nil {
+			m.setServiceMigrationPhase(previous.LoadBalancer, serviceName, previous.Phase)
+		} else {
+			m.setServiceMigrationPhase(lbName, serviceName, "")
+		}
+	}
+	eligibleLBs, err := m.az.getEligibleLoadBalancersForService(ctx, service)
+	if err != nil || len(eligibleLBs) == 0 {
+		restore()
+		klog.V(4).Infof("serviceMigrator.startMigration: there is no other eligible load balancer for service %s: %v", serviceName, err)
+		return false, nil
+	}
+
+	klog.V(2).Infof("serviceMigrator.startMigration: moving service %s off load balancer %s, eligible load balancers: %v", serviceName, lbName, eligibleLBs)
+	if err := m.triggerReconcile(ctx, service); err != nil {
+		restore()
+		return false, err
+	}
+	migration := &serviceMigration{LoadBalancer: lbName}
+	if previous != nil && strings.EqualFold(previous.LoadBalancer, lbName) {
+		migration.Attempts = previous.Attempts
+	}
+	migration.Attempts++
+	m.migrations[serviceName] = migration
+	m.setMigrationPhase(serviceName, migration, serviceMigrationPhaseMigrating)
+	m.az.Event(ctx, service, v1.EventTypeNormal, "MigratingLoadBalancer", fmt.Sprintf("Moving the service off load balancer %s", lbName))
+	return true, nil
+}
+
+// setServiceMigrationPhase marks the service in the MigratingServices or MigratedServices of the load balancer configuration
+// by the phase of its migration. The service is unmarked if the migration failed or the phase is empty.
+func (m *serviceMigrator) setServiceMigrationPhase(lbName, serviceName string, phase serviceMigrationPhase) {
+	multiSLBConfig := m.getLoadBalancerConfiguration(lbName)
+	if multiSLBConfig == nil {
+		return
+	}
+
+	m.az.multipleStandardLoadBalancersActiveServicesLock.Lock()
+	defer m.az.multipleStandardLoadBalancersActiveServicesLock.Unlock()
+
+	multiSLBConfig.MigratingServices.Delete(serviceName)
+	multiSLBConfig.MigratedServices.Delete(serviceName)
+	switch phase {
+	case serviceMigrationPhaseMigrating:
+		multiSLBConfig.MigratingServices = utilsets.SafeInsert(multiSLBConfig.MigratingServices, serviceName)
+	case serviceMigrationPhaseMigrated:
+		multiSLBConfig.MigratedServices = utilsets.SafeInsert(multiSLBConfig.MigratedServices, serviceName)
+	}
+}
+
+// triggerReconcile updates the migration annotation of the service, which makes the service controller reconcile the service.
+func (m *serviceMigrator) triggerReconcile(ctx context.Context, service *v1.Service) error {
+	if m.az.KubeClient == nil {
+		return fmt.Errorf("az.KubeClient is nil")
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				consts.ServiceAnnotationLoadBalancerMigration: time.Now().UTC().Format(time.RFC3339),
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = m.az.KubeClient.CoreV1().Services(service.Namespace).Patch(ctx, service.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
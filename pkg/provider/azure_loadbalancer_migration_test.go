@@ -0,0 +1,179 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+	"sigs.k8s.io/cloud-provider-azure/pkg/provider/config"
+	utilsets "sigs.k8s.io/cloud-provider-azure/pkg/util/sets"
+)
+
+func getTestCloudWithServiceMigration(ctrl *gomock.Controller, services ...*v1.Service) *Cloud {
+	az := GetTestCloud(ctrl)
+	az.MaxConcurrentServiceMigrations = 1
+	az.MultipleStandardLoadBalancerConfigurations = []config.MultipleStandardLoadBalancerConfiguration{
+		{
+			Name: "kubernetes",
+			MultipleStandardLoadBalancerConfigurationSpec: config.MultipleStandardLoadBalancerConfigurationSpec{
+				MigrateServices: true,
+			},
+			MultipleStandardLoadBalancerConfigurationStatus: config.MultipleStandardLoadBalancerConfigurationStatus{
+				ActiveServices: utilsets.NewString(),
+			},
+		},
+		{Name: "lb1"},
+	}
+
+	kubeClient := fake.NewSimpleClientset()
+	for _, service := range services {
+		_, _ = kubeClient.CoreV1().Services(service.Namespace).Create(context.Background(), service, metav1.CreateOptions{})
+		az.MultipleStandardLoadBalancerConfigurations[0].ActiveServices.Insert(getServiceName(service))
+	}
+	az.KubeClient = kubeClient
+	informerFactory := informers.NewSharedInformerFactory(kubeClient, 0)
+	az.serviceLister = informerFactory.Core().V1().Services().Lister()
+	informerFactory.Start(wait.NeverStop)
+	informerFactory.WaitForCacheSync(wait.NeverStop)
+	return az
+}
+
+func TestGetEligibleLoadBalancersWithServiceMigration(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	svc := getTestService("svc", v1.ProtocolTCP, nil, false, 80)
+	newSvc := getTestService("new", v1.ProtocolTCP, nil, false, 80)
+	az := getTestCloudWithServiceMigration(ctrl, &svc)
+
+	// The service using the load balancer stays until it is being moved.
+	eligibleLBs, err := az.getEligibleLoadBalancersForService(context.TODO(), &svc)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"kubernetes", "lb1"}, eligibleLBs)
+
+	// The new services are not placed on the load balancer.
+	eligibleLBs, err = az.getEligibleLoadBalancersForService(context.TODO(), &newSvc)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"lb1"}, eligibleLBs)
+
+	az.MultipleStandardLoadBalancerConfigurations[0].MigratingServices = utilsets.NewString(getServiceName(&svc))
+	eligibleLBs, err = az.getEligibleLoadBalancersForService(context.TODO(), &svc)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"lb1"}, eligibleLBs)
+}
+
+func TestServiceMigratorMigrate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pinned := getTestService("pinned", v1.ProtocolTCP, map[string]string{consts.ServiceAnnotationLoadBalancerPinned: "true"}, false, 80)
+	svc1 := getTestService("svc1", v1.ProtocolTCP, nil, false, 80)
+	svc2 := getTestService("svc2", v1.ProtocolTCP, nil, false, 80)
+	az := getTestCloudWithServiceMigration(ctrl, &pinned, &svc1, &svc2)
+	migrator := newServiceMigrator(az, time.Minute, time.Hour, time.Hour)
+	lbConfig := func() config.MultipleStandardLoadBalancerConfiguration {
+		return az.MultipleStandardLoadBalancerConfigurations[0]
+	}
+	getMigrationAnnotation := func(service v1.Service) string {
+		updated, err := az.KubeClient.CoreV1().Services(service.Namespace).Get(context.TODO(), service.Name, metav1.GetOptions{})
+		assert.NoError(t, err)
+		return updated.Annotations[consts.ServiceAnnotationLoadBalancerMigration]
+	}
+
+	// The pinned service is skipped and only one service is moved at a time.
+	migrator.migrate(context.TODO())
+	assert.Equal(t, utilsets.NewString("default/svc1"), lbConfig().MigratingServices)
+	assert.NotEmpty(t, getMigrationAnnotation(svc1))
+	assert.Empty(t, getMigrationAnnotation(svc2))
+	assert.Empty(t, getMigrationAnnotation(pinned))
+
+	migrator.migrate(context.TODO())
+	assert.Equal(t, utilsets.NewString("default/svc1"), lbConfig().MigratingServices)
+	assert.Empty(t, getMigrationAnnotation(svc2))
+
+	// The service is moved off the load balancer by the reconciliation.
+	az.reconcileMultipleStandardLoadBalancerConfigurationStatus(context.TODO(), false, "default/svc1", "kubernetes")
+	migrator.migrate(context.TODO())
+	assert.Equal(t, utilsets.NewString("default/svc1"), lbConfig().MigratedServices)
+	assert.Equal(t, utilsets.NewString("default/svc2"), lbConfig().MigratingServices)
+	assert.NotEmpty(t, getMigrationAnnotation(svc2))
+
+	// The migration times out, and is retried after the retry interval.
+	migrator.timeout = 0
+	migrator.migrate(context.TODO())
+	assert.Equal(t, 0, lbConfig().MigratingServices.Len())
+	assert.Equal(t, serviceMigrationPhaseFailed, migrator.migrations["default/svc2"].Phase)
+	assert.True(t, lbConfig().ActiveServices.Has("default/pinned"))
+
+	migrator.timeout = time.Hour
+	migrator.retryInterval = 0
+	migrator.migrate(context.TODO())
+	assert.Equal(t, utilsets.NewString("default/svc2"), lbConfig().MigratingServices)
+	assert.Equal(t, serviceMigrationPhaseMigrating, migrator.migrations["default/svc2"].Phase)
+	assert.Equal(t, 2, migrator.migrations["default/svc2"].Attempts)
+
+	// The migrations are persisted and restored after restarts.
+	state, err := az.getMultipleStandardLoadBalancerState(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, serviceMigrationPhaseMigrated, state.ServiceMigrations["default/svc1"].Phase)
+	assert.Equal(t, serviceMigrationPhaseMigrating, state.ServiceMigrations["default/svc2"].Phase)
+
+	az.MultipleStandardLoadBalancerConfigurations[0].MigratingServices = nil
+	az.MultipleStandardLoadBalancerConfigurations[0].MigratedServices = nil
+	restarted := newServiceMigrator(az, time.Minute, time.Hour, time.Hour)
+	restarted.migrate(context.TODO())
+	assert.Equal(t, utilsets.NewString("default/svc1"), lbConfig().MigratedServices)
+	assert.Equal(t, utilsets.NewString("default/svc2"), lbConfig().MigratingServices)
+	assert.Equal(t, 2, restarted.migrations["default/svc2"].Attempts)
+
+	// The migrations are forgotten when the load balancer does not migrate services any more.
+	az.MultipleStandardLoadBalancerConfigurations[0].MigrateServices = false
+	restarted.migrate(context.TODO())
+	assert.Equal(t, 0, lbConfig().MigratingServices.Len())
+	assert.Empty(t, restarted.migrations)
+	state, err = az.getMultipleStandardLoadBalancerState(context.TODO())
+	assert.NoError(t, err)
+	assert.Empty(t, state.ServiceMigrations)
+}
+
+func TestServiceMigratorMigrateWithoutEligibleLoadBalancers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	svc := getTestService("svc", v1.ProtocolTCP, nil, false, 80)
+	az := getTestCloudWithServiceMigration(ctrl, &svc)
+	az.MultipleStandardLoadBalancerConfigurations[1].AllowServicePlacement = new(bool)
+	migrator := newServiceMigrator(az, time.Minute, time.Hour, time.Hour)
+
+	migrator.migrate(context.TODO())
+	assert.Equal(t, 0, az.MultipleStandardLoadBalancerConfigurations[0].MigratingServices.Len())
+	updated, err := az.KubeClient.CoreV1().Services(svc.Namespace).Get(context.TODO(), svc.Name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, updated.Annotations[consts.ServiceAnnotationLoadBalancerMigration])
+}
@@ -0,0 +1,218 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2022-07-01/network"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+)
+
+// getServiceOutboundBackendPoolName returns the name of the backend pool the outbound rules
+// of the service target, or an empty string if the service has no outbound rules.
+func getServiceOutboundBackendPoolName(service *v1.Service) string {
+	return strings.TrimSpace(service.Annotations[consts.ServiceAnnotationLoadBalancerOutboundBackendPool])
+}
+
+// hasServiceOutboundRules checks if the outbound rules of the service are managed by the cloud provider.
+func hasServiceOutboundRules(service *v1.Service) bool {
+	return getServiceOutboundBackendPoolName(service) != ""
+}
+
+func (az *Cloud) getOutboundRuleName(service *v1.Service, isIPv6 bool) string {
+	ruleName := fmt.Sprintf("%s-outbound", az.getRulePrefix(service))
+	return getResourceByIPFamily(ruleName, isServiceDualStack(service), isIPv6)
+}
+
+// serviceOwnsOutboundRule checks if the outbound rule is created by the cloud provider for the service.
+// Both the single-stack and dual-stack names are checked, so the rules are cleaned up after the IP families change.
+func (az *Cloud) serviceOwnsOutboundRule(service *v1.Service, rule string) bool {
+	ruleName := fmt.Sprintf("%s-outbound", az.getRulePrefix(service))
+	return strings.EqualFold(rule, ruleName) ||
+		strings.EqualFold(rule, getResourceByIPFamily(ruleName, true, consts.IPVersionIPv6))
+}
+
+// getExpectedOutboundRules returns the outbound rules of the service on the load balancer. There is one
+// outbound rule for each IP family, from the frontend IP configuration of the service to the outbound backend pool.
+func (az *Cloud) getExpectedOutboundRules(
+	service *v1.Service,
+	lb *network.LoadBalancer,
+	lbFrontendIPConfigIDs map[bool]string,
+) ([]network.OutboundRule, error) {
+	backendPoolName := getServiceOutboundBackendPoolName(service)
+	if backendPoolName == "" {
+		return nil, nil
+	}
+	if requiresInternalLoadBalancer(service) {
+		return nil, fmt.Errorf("annotation %s is not supported on internal load balancers", consts.ServiceAnnotationLoadBalancerOutboundBackendPool)
+	}
+	if !az.UseStandardLoadBalancer() {
+		return nil, fmt.Errorf("annotation %s is only supported on standard load balancers", consts.ServiceAnnotationLoadBalancerOutboundBackendPool)
+	}
+
+	allocatedPorts, err := consts.Getint32ValueFromK8sSvcAnnotation(service.Annotations, consts.ServiceAnnotationLoadBalancerOutboundAllocatedPorts, func(val *int32) error {
+		const (
+			min = 0
+			max = 64000
+		)
+		if *val < min || *val > max || *val%8 != 0 {
+			return fmt.Errorf("allocated outbound ports must be a multiple of 8 between %d and %d, actual value: %d", min, max, *val)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error parsing allocated outbound ports key: %s, err: %w", consts.ServiceAnnotationLoadBalancerOutboundAllocatedPorts, err)
+	} else if allocatedPorts == nil {
+		allocatedPorts = ptr.To(int32(0))
+	}
+
+	idleTimeout, err := consts.Getint32ValueFromK8sSvcAnnotation(service.Annotations, consts.ServiceAnnotationLoadBalancerOutboundIdleTimeout, func(val *int32) error {
+		const (
+			min = 4
+			max = 120
+		)
+		if *val < min || *val > max {
+			return fmt.Errorf("idle timeout value must be a whole number representing minutes between %d and %d, actual value: %d", min, max, *val)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error parsing outbound idle timeout key: %s, err: %w", consts.ServiceAnnotationLoadBalancerOutboundIdleTimeout, err)
+	} else if idleTimeout == nil {
+		idleTimeout = ptr.To(int32(4))
+	}
+
+	var rules []network.OutboundRule
+	isDualStack := isServiceDualStack(service)
+	v4Enabled, v6Enabled := getIPFamiliesEnabled(service)
+	for _, isIPv6 := range []bool{consts.IPVersionIPv4, consts.IPVersionIPv6} {
+		if (!isIPv6 && !v4Enabled) || (isIPv6 && !v6Enabled) {
+			continue
+		}
+
+		poolName := getResourceByIPFamily(backendPoolName, isDualStack, isIPv6)
+		poolID := az.getBackendPoolID(ptr.Deref(lb.Name, ""), poolName)
+		if !hasBackendPool(lb, poolID) {
+			return nil, fmt.Errorf("the outbound backend pool %s of service %s is not found on load balancer %s", poolName, getServiceName(service), ptr.Deref(lb.Name, ""))
+		}
+
+		rules = append(rules, network.OutboundRule{
+			Name: ptr.To(az.getOutboundRuleName(service, isIPv6)),
+			OutboundRulePropertiesFormat: &network.OutboundRulePropertiesFormat{
+				AllocatedOutboundPorts: allocatedPorts,
+				FrontendIPConfigurations: &[]network.SubResource{
+					{ID: ptr.To(lbFrontendIPConfigIDs[isIPv6])},
+				},
+				BackendAddressPool:   &network.SubResource{ID: ptr.To(poolID)},
+				Protocol:             network.LoadBalancerOutboundRuleProtocolAll,
+				EnableTCPReset:       ptr.To(!consts.IsTCPResetDisabled(service.Annotations)),
+				IdleTimeoutInMinutes: idleTimeout,
+			},
+		})
+	}
+	return rules, nil
+}
+
+func hasBackendPool(lb *network.LoadBalancer, poolID string) bool {
+	if lb.LoadBalancerPropertiesFormat == nil || lb.BackendAddressPools == nil {
+		return false
+	}
+	for _, pool := range *lb.BackendAddressPools {
+		if strings.EqualFold(ptr.Deref(pool.ID, ""), poolID) {
+			return true
+		}
+	}
+	return false
+}
+
+// reconcileLBOutboundRules reconciles the outbound rules owned by the service, and returns true if they are changed.
+// The outbound rules of other services and the ones not created by the cloud provider are kept.
+func (az *Cloud) reconcileLBOutboundRules(lb *network.LoadBalancer, service *v1.Service, serviceName string, wantLb bool, expectedRules []network.OutboundRule) bool {
+	if lb.LoadBalancerPropertiesFormat == nil {
+		return false
+	}
+
+	dirtyRules := false
+	var updatedRules []network.OutboundRule
+	if lb.OutboundRules != nil {
+		updatedRules = *lb.OutboundRules
+	}
+
+	// update rules: remove unwanted
+	for i := len(updatedRules) - 1; i >= 0; i-- {
+		existingRule := updatedRules[i]
+		if az.serviceOwnsOutboundRule(service, ptr.Deref(existingRule.Name, "")) && !findOutboundRule(expectedRules, existingRule) {
+			klog.V(2).Infof("reconcileLoadBalancer for service (%s)(%t): lb outbound rule(%s) - dropping", serviceName, wantLb, ptr.Deref(existingRule.Name, ""))
+			updatedRules = append(updatedRules[:i], updatedRules[i+1:]...)
+			dirtyRules = true
+		}
+	}
+	// update rules: add needed
+	for _, expectedRule := range expectedRules {
+		if !findOutboundRule(updatedRules, expectedRule) {
+			klog.V(2).Infof("reconcileLoadBalancer for service (%s)(%t): lb outbound rule(%s) - adding", serviceName, wantLb, ptr.Deref(expectedRule.Name, ""))
+			updatedRules = append(updatedRules, expectedRule)
+			dirtyRules = true
+		}
+	}
+	if dirtyRules {
+		lb.OutboundRules = &updatedRules
+	}
+	return dirtyRules
+}
+
+func findOutboundRule(rules []network.OutboundRule, rule network.OutboundRule) bool {
+	for _, existingRule := range rules {
+		if strings.EqualFold(ptr.Deref(existingRule.Name, ""), ptr.Deref(rule.Name, "")) &&
+			equalOutboundRulePropertiesFormat(existingRule.OutboundRulePropertiesFormat, rule.OutboundRulePropertiesFormat) {
+			return true
+		}
+	}
+	return false
+}
+
+// equalOutboundRulePropertiesFormat checks whether the provided OutboundRulePropertiesFormat are equal.
+// Note: only fields set in getExpectedOutboundRules are considered.
+// s: existing, t: target
+func equalOutboundRulePropertiesFormat(s, t *network.OutboundRulePropertiesFormat) bool {
+	if s == nil || t == nil {
+		return false
+	}
+	if s.FrontendIPConfigurations == nil || t.FrontendIPConfigurations == nil ||
+		len(*s.FrontendIPConfigurations) != len(*t.FrontendIPConfigurations) {
+		return false
+	}
+	for i := range *s.FrontendIPConfigurations {
+		if !equalSubResource(&(*s.FrontendIPConfigurations)[i], &(*t.FrontendIPConfigurations)[i]) {
+			return false
+		}
+	}
+
+	return equalSubResource(s.BackendAddressPool, t.BackendAddressPool) &&
+		reflect.DeepEqual(s.Protocol, t.Protocol) &&
+		ptr.Deref(s.AllocatedOutboundPorts, 0) == ptr.Deref(t.AllocatedOutboundPorts, 0) &&
+		ptr.Deref(s.EnableTCPReset, false) == ptr.Deref(t.EnableTCPReset, false) &&
+		ptr.Deref(s.IdleTimeoutInMinutes, 0) == ptr.Deref(t.IdleTimeoutInMinutes, 0)
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2022-07-01/network"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+)
+
+func getTestLoadBalancerWithBackendPools(az *Cloud, poolNames ...string) *network.LoadBalancer {
+	var pools []network.BackendAddressPool
+	for _, poolName := range poolNames {
+		pools = append(pools, network.BackendAddressPool{
+			Name: ptr.To(poolName),
+			ID:   ptr.To(az.getBackendPoolID("lb", poolName)),
+		})
+	}
+	return &network.LoadBalancer{
+		Name: ptr.To("lb"),
+		LoadBalancerPropertiesFormat: &network.LoadBalancerPropertiesFormat{
+			BackendAddressPools: &pools,
+		},
+	}
+}
+
+func TestGetExpectedOutboundRules(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	az := GetTestCloud(ctrl)
+	az.LoadBalancerSku = consts.LoadBalancerSkuStandard
+	fipIDs := map[bool]string{
+		consts.IPVersionIPv4: az.getFrontendIPConfigID("lb", "fip"),
+		consts.IPVersionIPv6: az.getFrontendIPConfigID("lb", "fip-IPv6"),
+	}
+
+	t.Run("no outbound rule without the annotation", func(t *testing.T) {
+		svc := getTestService("svc", v1.ProtocolTCP, nil, false, 80)
+		rules, err := az.getExpectedOutboundRules(&svc, getTestLoadBalancerWithBackendPools(az, "egress"), fipIDs)
+		assert.NoError(t, err)
+		assert.Empty(t, rules)
+	})
+
+	t.Run("outbound rules of dual-stack service", func(t *testing.T) {
+		svc := getTestServiceDualStack("svc", v1.ProtocolTCP, map[string]string{
+			consts.ServiceAnnotationLoadBalancerOutboundBackendPool:    "egress",
+			consts.ServiceAnnotationLoadBalancerOutboundAllocatedPorts: "1024",
+			consts.ServiceAnnotationLoadBalancerOutboundIdleTimeout:    "30",
+		}, 80)
+		rules, err := az.getExpectedOutboundRules(&svc, getTestLoadBalancerWithBackendPools(az, "egress", "egress-IPv6"), fipIDs)
+		assert.NoError(t, err)
+		assert.Equal(t, []network.OutboundRule{
+			{
+				Name: ptr.To("asvc-outbound"),
+				OutboundRulePropertiesFormat: &network.OutboundRulePropertiesFormat{
+					AllocatedOutboundPorts:   ptr.To(int32(1024)),
+					FrontendIPConfigurations: &[]network.SubResource{{ID: ptr.To(fipIDs[consts.IPVersionIPv4])}},
+					BackendAddressPool:       &network.SubResource{ID: ptr.To(az.getBackendPoolID("lb", "egress"))},
+					Protocol:                 network.LoadBalancerOutboundRuleProtocolAll,
+					EnableTCPReset:           ptr.To(true),
+					IdleTimeoutInMinutes:     ptr.To(int32(30)),
+				},
+			},
+			{
+				Name: ptr.To("asvc-outbound-IPv6"),
+				OutboundRulePropertiesFormat: &network.OutboundRulePropertiesFormat{
+					AllocatedOutboundPorts:   ptr.To(int32(1024)),
+					FrontendIPConfigurations: &[]network.SubResource{{ID: ptr.To(fipIDs[consts.IPVersionIPv6])}},
+					BackendAddressPool:       &network.SubResource{ID: ptr.To(az.getBackendPoolID("lb", "egress-IPv6"))},
+					Protocol:                 network.LoadBalancerOutboundRuleProtocolAll,
+					EnableTCPReset:           ptr.To(true),
+					IdleTimeoutInMinutes:     ptr.To(int32(30)),
+				},
+			},
+		}, rules)
+	})
+
+	for _, tc := range []struct {
+		desc        string
+		annotations map[string]string
+		poolNames   []string
+	}{
+		{
+			desc: "internal service",
+			annotations: map[string]string{
+				consts.ServiceAnnotationLoadBalancerOutboundBackendPool: "egress",
+				consts.ServiceAnnotationLoadBalancerInternal:            consts.TrueAnnotationValue,
+			},
+			poolNames: []string{"egress"},
+		},
+		{
+			desc:        "backend pool not found",
+			annotations: map[string]string{consts.ServiceAnnotationLoadBalancerOutboundBackendPool: "egress"},
+			poolNames:   []string{"kubernetes"},
+		},
+		{
+			desc: "allocated ports not a multiple of 8",
+			annotations: map[string]string{
+				consts.ServiceAnnotationLoadBalancerOutboundBackendPool:    "egress",
+				consts.ServiceAnnotationLoadBalancerOutboundAllocatedPorts: "1004",
+			},
+			poolNames: []string{"egress"},
+		},
+		{
+			desc: "idle timeout out of range",
+			annotations: map[string]string{
+				consts.ServiceAnnotationLoadBalancerOutboundBackendPool: "egress",
+				consts.ServiceAnnotationLoadBalancerOutboundIdleTimeout: "121",
+			},
+			poolNames: []string{"egress"},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			svc := getTestService("svc", v1.ProtocolTCP, tc.annotations, false, 80)
+			_, err := az.getExpectedOutboundRules(&svc, getTestLoadBalancerWithBackendPools(az, tc.poolNames...), fipIDs)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestReconcileLBOutboundRules(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	az := GetTestCloud(ctrl)
+	az.LoadBalancerSku = consts.LoadBalancerSkuStandard
+	svc := getTestService("svc", v1.ProtocolTCP, map[string]string{
+		consts.ServiceAnnotationLoadBalancerOutboundBackendPool: "egress",
+	}, false, 80)
+	fipIDs := map[bool]string{consts.IPVersionIPv4: az.getFrontendIPConfigID("lb", "asvc")}
+
+	otherRule := network.OutboundRule{
+		Name: ptr.To("aksOutboundRule"),
+		OutboundRulePropertiesFormat: &network.OutboundRulePropertiesFormat{
+			FrontendIPConfigurations: &[]network.SubResource{{ID: ptr.To(az.getFrontendIPConfigID("lb", "outbound"))}},
+			BackendAddressPool:       &network.SubResource{ID: ptr.To(az.getBackendPoolID("lb", "kubernetes"))},
+		},
+	}
+	lb := getTestLoadBalancerWithBackendPools(az, "kubernetes", "egress")
+	lb.OutboundRules = &[]network.OutboundRule{otherRule}
+
+	expectedRules, err := az.getExpectedOutboundRules(&svc, lb, fipIDs)
+	assert.NoError(t, err)
+	assert.True(t, az.reconcileLBOutboundRules(lb, &svc, getServiceName(&svc), true, expectedRules))
+	assert.Equal(t, append([]network.OutboundRule{otherRule}, expectedRules...), *lb.OutboundRules)
+
+	// The outbound rule is up to date.
+	assert.False(t, az.reconcileLBOutboundRules(lb, &svc, getServiceName(&svc), true, expectedRules))
+
+	// The outbound rule is updated with the new idle timeout.
+	svc.Annotations[consts.ServiceAnnotationLoadBalancerOutboundIdleTimeout] = "10"
+	expectedRules, err = az.getExpectedOutboundRules(&svc, lb, fipIDs)
+	assert.NoError(t, err)
+	assert.True(t, az.reconcileLBOutboundRules(lb, &svc, getServiceName(&svc), true, expectedRules))
+	assert.Len(t, *lb.OutboundRules, 2)
+	assert.Equal(t, int32(10), ptr.Deref((*lb.OutboundRules)[1].IdleTimeoutInMinutes, 0))
+
+	// The outbound rule of the service is removed, and the others are kept.
+	assert.True(t, az.reconcileLBOutboundRules(lb, &svc, getServiceName(&svc), false, nil))
+	assert.Equal(t, []network.OutboundRule{otherRule}, *lb.OutboundRules)
+}
@@ -0,0 +1,603 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v6"
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2022-07-01/network"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+	azureconfig "sigs.k8s.io/cloud-provider-azure/pkg/provider/config"
+	"sigs.k8s.io/cloud-provider-azure/pkg/util/deepcopy"
+)
+
+// PlanAction is the action that the reconciliation would take on an Azure resource.
+type PlanAction string
+
+const (
+	// PlanActionCreate means the resource would be created.
+	PlanActionCreate PlanAction = "Create"
+	// PlanActionUpdate means the resource would be updated.
+	PlanActionUpdate PlanAction = "Update"
+	// PlanActionDelete means the resource would be deleted.
+	PlanActionDelete PlanAction = "Delete"
+)
+
+// PlanResourceType is the type of the Azure resource in a plan.
+type PlanResourceType string
+
+const (
+	PlanResourceTypeLoadBalancer            PlanResourceType = "LoadBalancer"
+	PlanResourceTypeFrontendIPConfiguration PlanResourceType = "FrontendIPConfiguration"
+	PlanResourceTypeBackendAddressPool      PlanResourceType = "BackendAddressPool"
+	PlanResourceTypeProbe                   PlanResourceType = "Probe"
+	PlanResourceTypeLoadBalancingRule       PlanResourceType = "LoadBalancingRule"
+	PlanResourceTypeOutboundRule            PlanResourceType = "OutboundRule"
+	PlanResourceTypeInboundNatRule          PlanResourceType = "InboundNatRule"
+	PlanResourceTypePublicIPAddress         PlanResourceType = "PublicIPAddress"
+	PlanResourceTypeSecurityGroup           PlanResourceType = "SecurityGroup"
+	PlanResourceTypeSecurityRule            PlanResourceType = "SecurityRule"
+	PlanResourceTypePrivateLinkService      PlanResourceType = "PrivateLinkService"
+)
+
+// PlannedChange is a change that the reconciliation would make to an Azure resource.
+type PlannedChange struct {
+	ResourceType PlanResourceType `json:"resourceType"`
+	Name         string           `json:"name"`
+	// Parent is the name of the load balancer or the security group of a child resource.
+	Parent string     `json:"parent,omitempty"`
+	Action PlanAction `json:"action"`
+	Before any        `json:"before,omitempty"`
+	After  any        `json:"after,omitempty"`
+}
+
+// ServicePlan is the desired-vs-existing diff of the Azure resources of a service.
+type ServicePlan struct {
+	Service      string          `json:"service"`
+	LoadBalancer string          `json:"loadBalancer"`
+	Changes      []PlannedChange `json:"changes"`
+	// Notes are the parts of the reconciliation that cannot be planned.
+	Notes []string `json:"notes,omitempty"`
+}
+
+// PlanInventory is a snapshot of the Azure resources that a plan is computed against.
+type PlanInventory struct {
+	LoadBalancers     []network.LoadBalancer    `json:"loadBalancers,omitempty"`
+	PublicIPAddresses []network.PublicIPAddress `json:"publicIPAddresses,omitempty"`
+	SecurityGroup     *armnetwork.SecurityGroup `json:"securityGroup,omitempty"`
+	// Services are the other services in the cluster. They are used to retain the
+	// security rules of the IPs shared with the service.
+	Services []*v1.Service `json:"services,omitempty"`
+}
+
+// NewCloudForPlan creates a Cloud that can only be used to plan the reconciliation of services
+// against a snapshot of the Azure resources. No Azure client is initialized.
+func NewCloudForPlan(config *azureconfig.Config) (*Cloud, error) {
+	if config == nil {
+		config = &azureconfig.Config{}
+	}
+	if err := setConfigDefaults(config); err != nil {
+		return nil, err
+	}
+
+	az := &Cloud{}
+	if err := az.setLBDefaults(config); err != nil {
+		return nil, err
+	}
+	az.Config = *config
+	if az.MaximumLoadBalancerRuleCount == 0 {
+		az.MaximumLoadBalancerRuleCount = consts.MaximumLoadBalancerRuleCount
+	}
+	az.eventRecorder = &record.FakeRecorder{}
+
+	// The caches are only invalidated in plan mode, the resources are read from the inventory.
+	var err error
+	if az.lbCache, err = az.newLBCache(); err != nil {
+		return nil, err
+	}
+	if az.pipCache, err = az.newPIPCache(); err != nil {
+		return nil, err
+	}
+
+	// Only the name of the primary VMSet is needed to resolve the load balancer name.
+	az.VMSet, err = newAvailabilitySet(az)
+	if err != nil {
+		return nil, err
+	}
+	az.initLoadBalancerBackendPool()
+
+	return az, nil
+}
+
+// PlanService computes the changes that reconcileService would make to the load balancer, public IPs
+// and security group of the service against the inventory. The reconciliation runs in plan mode, in which
+// the resources are read from and written to copies of the inventory instead of Azure. The backend pool
+// membership, private link services, DNS records and the values allocated by Azure are not planned.
+func (az *Cloud) PlanService(ctx context.Context, clusterName string, service *v1.Service, inventory *PlanInventory) (*ServicePlan, error) {
+	if service.Spec.Type != v1.ServiceTypeLoadBalancer {
+		return nil, fmt.Errorf("service %s/%s is not of type LoadBalancer", service.Namespace, service.Name)
+	}
+	if inventory == nil {
+		inventory = &PlanInventory{}
+	}
+
+	p := newServicePlanner(inventory)
+	if _, err := az.reconcileService(withServicePlanner(ctx, p), clusterName, service, nil /* nodes */); err != nil {
+		return nil, err
+	}
+
+	plan := &ServicePlan{
+		Service:      getServiceName(service),
+		LoadBalancer: p.loadBalancerName,
+		Notes:        p.notes,
+	}
+	plan.Changes = append(plan.Changes, getLoadBalancerChanges(inventory.LoadBalancers, p.loadBalancers)...)
+	plan.Changes = append(plan.Changes, getPublicIPChanges(inventory.PublicIPAddresses, p.publicIPs)...)
+	plan.Changes = append(plan.Changes, getSecurityGroupChanges(inventory.SecurityGroup, p.securityGroup)...)
+	return plan, nil
+}
+
+type servicePlannerKey struct{}
+
+// servicePlanner holds the Azure resources that the reconciliation reads and writes in plan mode,
+// and the notes on the parts of the reconciliation that cannot be planned.
+type servicePlanner struct {
+	// lock guards the resources, which are written concurrently by reconcilePublicIP.
+	lock          sync.Mutex
+	loadBalancers []network.LoadBalancer
+	publicIPs     []network.PublicIPAddress
+	securityGroup *armnetwork.SecurityGroup
+	services      []*v1.Service
+	// loadBalancerName is the name of the load balancer of the service, which is set by reconcileService.
+	loadBalancerName string
+	notes            []string
+}
+
+// newServicePlanner creates a servicePlanner with copies of the resources in the inventory,
+// so that the inventory is not changed by the plan.
+func newServicePlanner(inventory *PlanInventory) *servicePlanner {
+	p := &servicePlanner{services: inventory.Services}
+	for i := range inventory.LoadBalancers {
+		p.loadBalancers = append(p.loadBalancers, copyForPlan(&inventory.LoadBalancers[i]))
+	}
+	for i := range inventory.PublicIPAddresses {
+		p.publicIPs = append(p.publicIPs, copyForPlan(&inventory.PublicIPAddresses[i]))
+	}
+	if inventory.SecurityGroup != nil {
+		sg := copyForPlan(inventory.SecurityGroup)
+		p.securityGroup = &sg
+	}
+	return p
+}
+
+// withServicePlanner returns a context that runs the reconciliation in plan mode.
+func withServicePlanner(ctx context.Context, p *servicePlanner) context.Context {
+	return context.WithValue(ctx, servicePlannerKey{}, p)
+}
+
+// getServicePlanner returns the servicePlanner if the reconciliation runs in plan mode, or nil.
+func getServicePlanner(ctx context.Context) *servicePlanner {
+	p, _ := ctx.Value(servicePlannerKey{}).(*servicePlanner)
+	return p
+}
+
+func (p *servicePlanner) addNote(format string, args ...any) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	note := fmt.Sprintf(format, args...)
+	if !slices.Contains(p.notes, note) {
+		p.notes = append(p.notes, note)
+	}
+}
+
+func (p *servicePlanner) listLoadBalancers() []network.LoadBalancer {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	rv := make([]network.LoadBalancer, 0, len(p.loadBalancers))
+	for i := range p.loadBalancers {
+		rv = append(rv, copyForPlan(&p.loadBalancers[i]))
+	}
+	return rv
+}
+
+func (p *servicePlanner) getLoadBalancer(name string) (*network.LoadBalancer, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if lb := findLoadBalancerByName(p.loadBalancers, name); lb != nil {
+		rv := copyForPlan(lb)
+		return &rv, true
+	}
+	return nil, false
+}
+
+func (p *servicePlanner) createOrUpdateLoadBalancer(lb network.LoadBalancer) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	lb = copyForPlan(&lb)
+	if existing := findLoadBalancerByName(p.loadBalancers, ptr.Deref(lb.Name, "")); existing != nil {
+		*existing = lb
+		return
+	}
+	p.loadBalancers = append(p.loadBalancers, lb)
+}
+
+func (p *servicePlanner) deleteLoadBalancer(name string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.loadBalancers = slices.DeleteFunc(p.loadBalancers, func(lb network.LoadBalancer) bool {
+		return strings.EqualFold(ptr.Deref(lb.Name, ""), name)
+	})
+}
+
+func (p *servicePlanner) createOrUpdateBackendPool(lbName string, backendPool network.BackendAddressPool) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	lb := findLoadBalancerByName(p.loadBalancers, lbName)
+	if lb == nil {
+		return fmt.Errorf("load balancer %s not found", lbName)
+	}
+	if lb.LoadBalancerPropertiesFormat == nil {
+		lb.LoadBalancerPropertiesFormat = &network.LoadBalancerPropertiesFormat{}
+	}
+	backendPool = copyForPlan(&backendPool)
+	var pools []network.BackendAddressPool
+	if lb.BackendAddressPools != nil {
+		pools = *lb.BackendAddressPools
+	}
+	if i := slices.IndexFunc(pools, func(bp network.BackendAddressPool) bool {
+		return strings.EqualFold(ptr.Deref(bp.Name, ""), ptr.Deref(backendPool.Name, ""))
+	}); i >= 0 {
+		pools[i] = backendPool
+	} else {
+		pools = append(pools, backendPool)
+	}
+	lb.BackendAddressPools = &pools
+	return nil
+}
+
+func (p *servicePlanner) deleteBackendPool(lbName, backendPoolName string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	lb := findLoadBalancerByName(p.loadBalancers, lbName)
+	if lb == nil || lb.LoadBalancerPropertiesFormat == nil || lb.BackendAddressPools == nil {
+		return
+	}
+	pools := slices.DeleteFunc(*lb.BackendAddressPools, func(bp network.BackendAddressPool) bool {
+		return strings.EqualFold(ptr.Deref(bp.Name, ""), backendPoolName)
+	})
+	lb.BackendAddressPools = &pools
+}
+
+func (p *servicePlanner) listPublicIPs() []network.PublicIPAddress {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	rv := make([]network.PublicIPAddress, 0, len(p.publicIPs))
+	for i := range p.publicIPs {
+		rv = append(rv, copyForPlan(&p.publicIPs[i]))
+	}
+	return rv
+}
+
+func (p *servicePlanner) getPublicIP(name string) (network.PublicIPAddress, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if pip := findPublicIPByName(p.publicIPs, name); pip != nil {
+		return copyForPlan(pip), true
+	}
+	return network.PublicIPAddress{}, false
+}
+
+// createOrUpdatePublicIP saves the public IP. The ID of a new public IP is generated as Azure would do.
+func (p *servicePlanner) createOrUpdatePublicIP(subscriptionID, pipResourceGroup string, pip network.PublicIPAddress) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	pip = copyForPlan(&pip)
+	if pip.ID == nil {
+		pip.ID = ptr.To(fmt.Sprintf(consts.PublicIPAddressIDTemplate, subscriptionID, pipResourceGroup, ptr.Deref(pip.Name, "")))
+	}
+	if existing := findPublicIPByName(p.publicIPs, ptr.Deref(pip.Name, "")); existing != nil {
+		*existing = pip
+		return
+	}
+	p.publicIPs = append(p.publicIPs, pip)
+}
+
+func (p *servicePlanner) deletePublicIP(name string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.publicIPs = slices.DeleteFunc(p.publicIPs, func(pip network.PublicIPAddress) bool {
+		return strings.EqualFold(ptr.Deref(pip.Name, ""), name)
+	})
+}
+
+func (p *servicePlanner) getSecurityGroup() (*armnetwork.SecurityGroup, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.securityGroup == nil {
+		return nil, false
+	}
+	rv := copyForPlan(p.securityGroup)
+	return &rv, true
+}
+
+func (p *servicePlanner) createOrUpdateSecurityGroup(sg *armnetwork.SecurityGroup) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	rv := copyForPlan(sg)
+	p.securityGroup = &rv
+}
+
+// canPlanSecurityGroup checks if the security rules of the service can be planned with the IPs
+// of the load balancer, and adds a note if they cannot.
+func (p *servicePlanner) canPlanSecurityGroup(az *Cloud, service *v1.Service, lbIPs []string) bool {
+	p.lock.Lock()
+	hasSecurityGroup := p.securityGroup != nil
+	p.lock.Unlock()
+
+	disableFloatingIP := consts.IsK8sServiceDisableLoadBalancerFloatingIP(service) || az.IsLBBackendPoolTypePodIP()
+	switch {
+	case !hasSecurityGroup:
+		p.addNote("the security group is not in the inventory, the security rules are not planned")
+		return false
+	case disableFloatingIP && az.IsLBBackendPoolTypeNodeIPConfig():
+		p.addNote("the backend IPs of the service are resolved from the network interfaces, the security rules are not planned")
+		return false
+	case !disableFloatingIP && (len(lbIPs) == 0 || slices.Contains(lbIPs, "")):
+		p.addNote("the frontend IPs of the service are allocated on creation, the security rules are not planned")
+		return false
+	}
+	return true
+}
+
+// planBackendPools adds the missing backend pools of the service to the load balancer in plan mode. The membership
+// of the backend pools is not planned, because the nodes and their network interfaces are not in the inventory.
+func (az *Cloud) planBackendPools(p *servicePlanner, clusterName string, service *v1.Service, lb *network.LoadBalancer) (bool, bool) {
+	serviceName := getServiceName(service)
+	isBackendPoolPreConfigured := az.isBackendPoolPreConfigured(service)
+	lbBackendPoolNames := az.getBackendPoolNamesForService(service, clusterName)
+
+	foundBackendPools := map[bool]bool{}
+	if lb.LoadBalancerPropertiesFormat != nil && lb.BackendAddressPools != nil {
+		for _, backendPool := range *lb.BackendAddressPools {
+			if found, isIPv6 := isLBBackendPoolsExisting(lbBackendPoolNames, backendPool.Name); found {
+				foundBackendPools[isIPv6] = true
+			}
+		}
+	}
+
+	var backendPoolsUpdated bool
+	for _, ipFamily := range service.Spec.IPFamilies {
+		isIPv6 := ipFamily == v1.IPv6Protocol
+		if foundBackendPools[isIPv6] {
+			continue
+		}
+		isBackendPoolPreConfigured = newBackendPool(lb, isBackendPoolPreConfigured, az.PreConfiguredBackendPoolLoadBalancerTypes, serviceName, lbBackendPoolNames[isIPv6])
+		backendPoolsUpdated = true
+	}
+	p.addNote("the membership of the backend pools is not planned")
+
+	return isBackendPoolPreConfigured, backendPoolsUpdated
+}
+
+// getSubnetForPlan returns the subnet by its ID, because the subnets are not in the inventory.
+// Without the address prefixes of the subnet, the private IP of an internal service moving to
+// another load balancer is only kept if it is set in the service.
+func (az *Cloud) getSubnetForPlan(subnetName string) network.Subnet {
+	vnetResourceGroup := az.ResourceGroup
+	if len(az.VnetResourceGroup) > 0 {
+		vnetResourceGroup = az.VnetResourceGroup
+	}
+	return network.Subnet{
+		Name: ptr.To(subnetName),
+		ID:   ptr.To(fmt.Sprintf(consts.SubnetIDTemplate, az.getNetworkResourceSubscriptionID(), vnetResourceGroup, az.VnetName, subnetName)),
+	}
+}
+
+// getLoadBalancerChanges returns the changes between the load balancers in the inventory and the ones after the reconciliation.
+func getLoadBalancerChanges(before, after []network.LoadBalancer) []PlannedChange {
+	var rv []PlannedChange
+	for i := range before {
+		rv = append(rv, diffLoadBalancer(&before[i], findLoadBalancerByName(after, ptr.Deref(before[i].Name, "")))...)
+	}
+	for i := range after {
+		if findLoadBalancerByName(before, ptr.Deref(after[i].Name, "")) == nil {
+			rv = append(rv, diffLoadBalancer(nil, &after[i])...)
+		}
+	}
+	return rv
+}
+
+// diffLoadBalancer returns the changes of the load balancer, followed by the ones of its child resources.
+// The change of the load balancer itself only contains the tags.
+func diffLoadBalancer(before, after *network.LoadBalancer) []PlannedChange {
+	var beforeProps, afterProps network.LoadBalancerPropertiesFormat
+	if before != nil && before.LoadBalancerPropertiesFormat != nil {
+		beforeProps = *before.LoadBalancerPropertiesFormat
+	}
+	if after != nil && after.LoadBalancerPropertiesFormat != nil {
+		afterProps = *after.LoadBalancerPropertiesFormat
+	}
+	change := PlannedChange{ResourceType: PlanResourceTypeLoadBalancer, Action: PlanActionUpdate}
+	switch {
+	case before == nil:
+		change.Name, change.Action = ptr.Deref(after.Name, ""), PlanActionCreate
+	case after == nil:
+		change.Name, change.Action = ptr.Deref(before.Name, ""), PlanActionDelete
+	default:
+		change.Name = ptr.Deref(after.Name, "")
+		if !reflect.DeepEqual(before.Tags, after.Tags) {
+			change.Before, change.After = before.Tags, after.Tags
+		}
+	}
+
+	lbName := change.Name
+	var children []PlannedChange
+	children = append(children, diffPlanSubResources(PlanResourceTypeFrontendIPConfiguration, lbName,
+		beforeProps.FrontendIPConfigurations, afterProps.FrontendIPConfigurations,
+		func(fip network.FrontendIPConfiguration) string { return ptr.Deref(fip.Name, "") })...)
+	children = append(children, diffPlanSubResources(PlanResourceTypeBackendAddressPool, lbName,
+		beforeProps.BackendAddressPools, afterProps.BackendAddressPools,
+		func(pool network.BackendAddressPool) string { return ptr.Deref(pool.Name, "") })...)
+	children = append(children, diffPlanSubResources(PlanResourceTypeProbe, lbName,
+		beforeProps.Probes, afterProps.Probes,
+		func(probe network.Probe) string { return ptr.Deref(probe.Name, "") })...)
+	children = append(children, diffPlanSubResources(PlanResourceTypeLoadBalancingRule, lbName,
+		beforeProps.LoadBalancingRules, afterProps.LoadBalancingRules,
+		func(rule network.LoadBalancingRule) string { return ptr.Deref(rule.Name, "") })...)
+	children = append(children, diffPlanSubResources(PlanResourceTypeOutboundRule, lbName,
+		beforeProps.OutboundRules, afterProps.OutboundRules,
+		func(rule network.OutboundRule) string { return ptr.Deref(rule.Name, "") })...)
+	children = append(children, diffPlanSubResources(PlanResourceTypeInboundNatRule, lbName,
+		beforeProps.InboundNatRules, afterProps.InboundNatRules,
+		func(rule network.InboundNatRule) string { return ptr.Deref(rule.Name, "") })...)
+
+	if change.Action == PlanActionUpdate && change.Before == nil && len(children) == 0 {
+		return nil
+	}
+	return append([]PlannedChange{change}, children...)
+}
+
+// getPublicIPChanges returns the changes between the public IPs in the inventory and the ones after the reconciliation.
+func getPublicIPChanges(before, after []network.PublicIPAddress) []PlannedChange {
+	var rv []PlannedChange
+	for _, pip := range before {
+		name := ptr.Deref(pip.Name, "")
+		switch updated := findPublicIPByName(after, name); {
+		case updated == nil:
+			rv = append(rv, PlannedChange{ResourceType: PlanResourceTypePublicIPAddress, Name: name, Action: PlanActionDelete, Before: pip})
+		case !reflect.DeepEqual(pip, *updated):
+			rv = append(rv, PlannedChange{ResourceType: PlanResourceTypePublicIPAddress, Name: name, Action: PlanActionUpdate, Before: pip, After: *updated})
+		}
+	}
+	for _, pip := range after {
+		if name := ptr.Deref(pip.Name, ""); findPublicIPByName(before, name) == nil {
+			rv = append(rv, PlannedChange{ResourceType: PlanResourceTypePublicIPAddress, Name: name, Action: PlanActionCreate, After: pip})
+		}
+	}
+	return rv
+}
+
+// getSecurityGroupChanges returns the changes of the security group, followed by the ones of its rules.
+// The change of the security group itself only contains the tags.
+func getSecurityGroupChanges(before, after *armnetwork.SecurityGroup) []PlannedChange {
+	if before == nil || after == nil {
+		return nil
+	}
+	sgName := ptr.Deref(after.Name, "")
+
+	var beforeRules, afterRules []armnetwork.SecurityRule
+	if before.Properties != nil {
+		for _, rule := range before.Properties.SecurityRules {
+			beforeRules = append(beforeRules, *rule)
+		}
+	}
+	if after.Properties != nil {
+		for _, rule := range after.Properties.SecurityRules {
+			afterRules = append(afterRules, *rule)
+		}
+	}
+	rules := diffPlanSubResources(PlanResourceTypeSecurityRule, sgName, &beforeRules, &afterRules,
+		func(rule armnetwork.SecurityRule) string { return ptr.Deref(rule.Name, "") })
+
+	change := PlannedChange{ResourceType: PlanResourceTypeSecurityGroup, Name: sgName, Action: PlanActionUpdate}
+	if !reflect.DeepEqual(before.Tags, after.Tags) {
+		change.Before, change.After = before.Tags, after.Tags
+	} else if len(rules) == 0 {
+		return nil
+	}
+	return append([]PlannedChange{change}, rules...)
+}
+
+// diffPlanSubResources returns the changes between the existing and the expected child resources.
+func diffPlanSubResources[T any](resourceType PlanResourceType, parent string, before, after *[]T, nameOf func(T) string) []PlannedChange {
+	existing := make(map[string]T)
+	if before != nil {
+		for _, r := range *before {
+			existing[strings.ToLower(nameOf(r))] = r
+		}
+	}
+
+	var rv []PlannedChange
+	expected := make(map[string]bool)
+	if after != nil {
+		for _, r := range *after {
+			key := strings.ToLower(nameOf(r))
+			expected[key] = true
+			old, ok := existing[key]
+			switch {
+			case !ok:
+				rv = append(rv, PlannedChange{ResourceType: resourceType, Name: nameOf(r), Parent: parent, Action: PlanActionCreate, After: r})
+			case !reflect.DeepEqual(old, r):
+				rv = append(rv, PlannedChange{ResourceType: resourceType, Name: nameOf(r), Parent: parent, Action: PlanActionUpdate, Before: old, After: r})
+			}
+		}
+	}
+	if before != nil {
+		for _, r := range *before {
+			if !expected[strings.ToLower(nameOf(r))] {
+				rv = append(rv, PlannedChange{ResourceType: resourceType, Name: nameOf(r), Parent: parent, Action: PlanActionDelete, Before: r})
+			}
+		}
+	}
+	return rv
+}
+
+// copyForPlan deep copies the resource, so that the resources of the plan never share fields with the inventory.
+func copyForPlan[T any](v *T) T {
+	return *(deepcopy.Copy(v).(*T))
+}
+
+func findLoadBalancerByName(lbs []network.LoadBalancer, name string) *network.LoadBalancer {
+	for i := range lbs {
+		if strings.EqualFold(ptr.Deref(lbs[i].Name, ""), name) {
+			return &lbs[i]
+		}
+	}
+	return nil
+}
+
+func findPublicIPByName(pips []network.PublicIPAddress, name string) *network.PublicIPAddress {
+	for i := range pips {
+		if strings.EqualFold(ptr.Deref(pips[i].Name, ""), name) {
+			return &pips[i]
+		}
+	}
+	return nil
+}
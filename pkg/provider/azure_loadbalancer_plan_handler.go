@@ -0,0 +1,153 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2022-07-01/network"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	azcache "sigs.k8s.io/cloud-provider-azure/pkg/cache"
+)
+
+// ServicePlanPath is the path of the debug endpoint serving the plans of services.
+const ServicePlanPath = "/debug/loadbalancer/plan"
+
+// ServicePlanHandler serves the plan of a service at ServicePlanPath?namespace=<namespace>&name=<name>.
+// The load balancers are listed from ARM, the other existing Azure resources are read from the caches of the
+// cloud, and nothing is written to Azure.
+// The plan is computed with serviceReconcileLock held, because the reconciliation of services is dry-run
+// against the shared state of the cloud.
+type ServicePlanHandler struct {
+	lock        sync.RWMutex
+	az          *Cloud
+	clusterName string
+}
+
+// NewServicePlanHandler creates a ServicePlanHandler. It serves 503 until the cloud is set.
+func NewServicePlanHandler() *ServicePlanHandler {
+	return &ServicePlanHandler{}
+}
+
+// SetCloud sets the cloud and the cluster name used to compute the plans.
+func (h *ServicePlanHandler) SetCloud(az *Cloud, clusterName string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.az = az
+	h.clusterName = clusterName
+}
+
+// ServeHTTP implements http.Handler.
+func (h *ServicePlanHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.lock.RLock()
+	az, clusterName := h.az, h.clusterName
+	h.lock.RUnlock()
+
+	if az == nil || az.serviceLister == nil {
+		http.Error(w, "the cloud provider is not initialized", http.StatusServiceUnavailable)
+		return
+	}
+
+	namespace, name := r.URL.Query().Get("namespace"), r.URL.Query().Get("name")
+	if namespace == "" {
+		namespace = v1.NamespaceDefault
+	}
+	if name == "" {
+		http.Error(w, "the name of the service is required", http.StatusBadRequest)
+		return
+	}
+
+	service, err := az.serviceLister.Services(namespace).Get(name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	service = service.DeepCopy()
+
+	lbs, err := az.listPlanLoadBalancers(r.Context(), service, clusterName)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to list the Azure resources: %v", err), http.StatusInternalServerError)
+		return
+	}
+	inventory, err := az.getPlanInventory(r.Context(), service, lbs)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to list the Azure resources: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	az.serviceReconcileLock.Lock()
+	plan, err := az.PlanService(r.Context(), clusterName, service, inventory)
+	az.serviceReconcileLock.Unlock()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to plan the service: %v", err), http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(plan); err != nil {
+		klog.Errorf("ServicePlanHandler: failed to write the plan of service %s: %v", getServiceName(service), err)
+	}
+}
+
+// listPlanLoadBalancers lists the load balancers managed by the cluster. The load balancer cache is keyed by name,
+// so the load balancers are listed from ARM, and the callers planning several services list them only once.
+func (az *Cloud) listPlanLoadBalancers(ctx context.Context, service *v1.Service, clusterName string) ([]network.LoadBalancer, error) {
+	lbs, err := az.ListManagedLBs(ctx, service, nil, clusterName)
+	if err != nil || lbs == nil {
+		return nil, err
+	}
+	return *lbs, nil
+}
+
+// getPlanInventory returns the existing Azure resources of the service with the listed load balancers.
+// The public IPs and the security group are read from the caches.
+func (az *Cloud) getPlanInventory(ctx context.Context, service *v1.Service, lbs []network.LoadBalancer) (*PlanInventory, error) {
+	inventory := &PlanInventory{LoadBalancers: lbs}
+
+	var err error
+	inventory.PublicIPAddresses, err = az.listPIP(ctx, az.getPublicIPAddressResourceGroup(service), azcache.CacheReadTypeDefault)
+	if err != nil {
+		return nil, err
+	}
+
+	inventory.SecurityGroup, err = az.nsgRepo.GetSecurityGroup(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	inventory.Services, err = az.serviceLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	return inventory, nil
+}
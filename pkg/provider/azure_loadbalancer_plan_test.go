@@ -0,0 +1,291 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v6"
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2022-07-01/network"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+	azureconfig "sigs.k8s.io/cloud-provider-azure/pkg/provider/config"
+)
+
+func getTestCloudForPlan(t *testing.T) *Cloud {
+	az, err := NewCloudForPlan(&azureconfig.Config{
+		AzureClientConfig: azureconfig.AzureClientConfig{
+			SubscriptionID: "subscription",
+		},
+		ResourceGroup:     "rg",
+		Location:          "westus",
+		VnetName:          "vnet",
+		SubnetName:        "subnet",
+		SecurityGroupName: "nsg",
+	})
+	assert.NoError(t, err)
+	return az
+}
+
+func getTestSecurityGroupForPlan() *armnetwork.SecurityGroup {
+	return &armnetwork.SecurityGroup{
+		Name: ptr.To("nsg"),
+		Properties: &armnetwork.SecurityGroupPropertiesFormat{
+			SecurityRules: []*armnetwork.SecurityRule{},
+		},
+	}
+}
+
+func getPlannedChanges(plan *ServicePlan, resourceType PlanResourceType) map[string]PlanAction {
+	rv := make(map[string]PlanAction)
+	for _, change := range plan.Changes {
+		if change.ResourceType == resourceType {
+			rv[change.Name] = change.Action
+		}
+	}
+	return rv
+}
+
+func TestNewCloudForPlan(t *testing.T) {
+	az, err := NewCloudForPlan(nil)
+	assert.NoError(t, err)
+	assert.True(t, az.UseStandardLoadBalancer())
+	assert.Equal(t, consts.LoadBalancerBackendPoolConfigurationTypeNodeIPConfiguration, az.LoadBalancerBackendPoolConfigurationType)
+	assert.NotNil(t, az.VMSet)
+	assert.NotNil(t, az.LoadBalancerBackendPool)
+
+	_, err = NewCloudForPlan(&azureconfig.Config{LoadBalancerBackendPoolConfigurationType: "invalid"})
+	assert.Error(t, err)
+}
+
+func TestPlanServiceNewPublicService(t *testing.T) {
+	az := getTestCloudForPlan(t)
+	svc := getTestService("svc", v1.ProtocolTCP, nil, false, 80)
+
+	plan, err := az.PlanService(context.Background(), testClusterName, &svc, &PlanInventory{SecurityGroup: getTestSecurityGroupForPlan()})
+	assert.NoError(t, err)
+
+	assert.Equal(t, testClusterName, plan.LoadBalancer)
+	assert.Equal(t, map[string]PlanAction{testClusterName: PlanActionCreate}, getPlannedChanges(plan, PlanResourceTypeLoadBalancer))
+	assert.Equal(t, map[string]PlanAction{az.getDefaultFrontendIPConfigName(&svc): PlanActionCreate}, getPlannedChanges(plan, PlanResourceTypeFrontendIPConfiguration))
+	assert.Equal(t, map[string]PlanAction{testClusterName: PlanActionCreate}, getPlannedChanges(plan, PlanResourceTypeBackendAddressPool))
+	assert.Len(t, getPlannedChanges(plan, PlanResourceTypeProbe), 1)
+	assert.Len(t, getPlannedChanges(plan, PlanResourceTypeLoadBalancingRule), 1)
+
+	pipName, err := az.getPublicIPName(testClusterName, &svc, false)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]PlanAction{pipName: PlanActionCreate}, getPlannedChanges(plan, PlanResourceTypePublicIPAddress))
+
+	// The address of the new public IP is unknown, so are the security rules.
+	assert.Empty(t, getPlannedChanges(plan, PlanResourceTypeSecurityRule))
+	assert.Contains(t, plan.Notes, "the frontend IPs of the service are allocated on creation, the security rules are not planned")
+}
+
+func TestPlanServiceExistingService(t *testing.T) {
+	az := getTestCloudForPlan(t)
+	svc := getTestService("svc", v1.ProtocolTCP, nil, false, 80)
+
+	// Build the resources as if the service has been reconciled.
+	p := newServicePlanner(&PlanInventory{})
+	_, err := az.reconcileService(withServicePlanner(context.Background(), p), testClusterName, &svc, nil)
+	assert.NoError(t, err)
+	assert.Len(t, p.loadBalancers, 1)
+	assert.Len(t, p.publicIPs, 1)
+	lb, pip := p.loadBalancers[0], p.publicIPs[0]
+	pip.IPAddress = ptr.To("1.2.3.4")
+
+	inventory := &PlanInventory{
+		LoadBalancers:     []network.LoadBalancer{lb},
+		PublicIPAddresses: []network.PublicIPAddress{pip},
+		SecurityGroup:     getTestSecurityGroupForPlan(),
+	}
+
+	t.Run("no change to the up-to-date load balancer and public IP", func(t *testing.T) {
+		plan, err := az.PlanService(context.Background(), testClusterName, &svc, inventory)
+		assert.NoError(t, err)
+
+		assert.Empty(t, getPlannedChanges(plan, PlanResourceTypeLoadBalancer))
+		assert.Empty(t, getPlannedChanges(plan, PlanResourceTypeLoadBalancingRule))
+		assert.Empty(t, getPlannedChanges(plan, PlanResourceTypePublicIPAddress))
+		assert.Equal(t, map[string]PlanAction{"nsg": PlanActionUpdate}, getPlannedChanges(plan, PlanResourceTypeSecurityGroup))
+		assert.Len(t, getPlannedChanges(plan, PlanResourceTypeSecurityRule), 1)
+		for _, change := range plan.Changes {
+			if change.ResourceType == PlanResourceTypeSecurityRule {
+				rule := change.After.(armnetwork.SecurityRule)
+				assert.Equal(t, "1.2.3.4", ptr.Deref(rule.Properties.DestinationAddressPrefix, ""))
+			}
+		}
+
+		// The inventory is not changed by the plan.
+		assert.Empty(t, inventory.SecurityGroup.Properties.SecurityRules)
+	})
+
+	t.Run("the rule is updated when the port changes", func(t *testing.T) {
+		updatedSvc := svc.DeepCopy()
+		updatedSvc.Spec.Ports[0].Port = 8080
+
+		plan, err := az.PlanService(context.Background(), testClusterName, updatedSvc, inventory)
+		assert.NoError(t, err)
+
+		assert.Equal(t, map[string]PlanAction{testClusterName: PlanActionUpdate}, getPlannedChanges(plan, PlanResourceTypeLoadBalancer))
+		rules := getPlannedChanges(plan, PlanResourceTypeLoadBalancingRule)
+		assert.Len(t, rules, 2)
+		assert.ElementsMatch(t, []PlanAction{PlanActionCreate, PlanActionDelete}, []PlanAction{rules[az.getLoadBalancerRuleName(updatedSvc, v1.ProtocolTCP, 8080, false)], rules[az.getLoadBalancerRuleName(&svc, v1.ProtocolTCP, 80, false)]})
+		assert.Empty(t, getPlannedChanges(plan, PlanResourceTypePublicIPAddress))
+		assert.Len(t, *lb.LoadBalancingRules, 1, "the load balancer in the inventory should not be changed")
+	})
+
+	t.Run("the service is moved to the internal load balancer", func(t *testing.T) {
+		internalSvc := svc.DeepCopy()
+		internalSvc.Annotations = map[string]string{consts.ServiceAnnotationLoadBalancerInternal: consts.TrueAnnotationValue}
+
+		plan, err := az.PlanService(context.Background(), testClusterName, internalSvc, inventory)
+		assert.NoError(t, err)
+
+		internalLBName := testClusterName + consts.InternalLoadBalancerNameSuffix
+		assert.Equal(t, internalLBName, plan.LoadBalancer)
+		assert.Equal(t, map[string]PlanAction{
+			testClusterName: PlanActionDelete,
+			internalLBName:  PlanActionCreate,
+		}, getPlannedChanges(plan, PlanResourceTypeLoadBalancer))
+		assert.Equal(t, map[string]PlanAction{ptr.Deref(pip.Name, ""): PlanActionDelete}, getPlannedChanges(plan, PlanResourceTypePublicIPAddress))
+
+		for _, change := range plan.Changes {
+			if change.ResourceType == PlanResourceTypeFrontendIPConfiguration && change.Action == PlanActionCreate {
+				fip := change.After.(network.FrontendIPConfiguration)
+				assert.Equal(t, "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/subnet", ptr.Deref(fip.Subnet.ID, ""))
+				assert.Equal(t, network.Dynamic, fip.PrivateIPAllocationMethod)
+			}
+		}
+	})
+
+	t.Run("no event is recorded in plan mode", func(t *testing.T) {
+		recorder := record.NewFakeRecorder(10)
+		az.eventRecorder = recorder
+		defer func() { az.eventRecorder = &record.FakeRecorder{} }()
+		internetSvc := svc.DeepCopy()
+		internetSvc.Annotations = map[string]string{consts.ServiceAnnotationPIPRoutingPreference: consts.PublicIPRoutingPreferenceInternet}
+
+		plan, err := az.PlanService(context.Background(), testClusterName, internetSvc, inventory)
+		assert.NoError(t, err)
+
+		// The public IP must be recreated to change the routing preference, which is noted instead of recorded.
+		var found bool
+		for _, note := range plan.Notes {
+			found = found || strings.Contains(note, "PublicIPRecreationRequired")
+		}
+		assert.True(t, found, "the event should be noted in the plan")
+		assert.Empty(t, recorder.Events)
+	})
+
+	t.Run("the frontend is chained to the gateway load balancer", func(t *testing.T) {
+		chainedSvc := svc.DeepCopy()
+		gatewayLBFrontendIPConfigID := "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/gwlb/frontendIPConfigurations/gw"
+		chainedSvc.Annotations = map[string]string{consts.ServiceAnnotationGatewayLoadBalancerFrontendIPConfigID: gatewayLBFrontendIPConfigID}
+
+		plan, err := az.PlanService(context.Background(), testClusterName, chainedSvc, inventory)
+		assert.NoError(t, err)
+
+		fipName := az.getDefaultFrontendIPConfigName(chainedSvc)
+		assert.Equal(t, map[string]PlanAction{fipName: PlanActionUpdate}, getPlannedChanges(plan, PlanResourceTypeFrontendIPConfiguration))
+		for _, change := range plan.Changes {
+			if change.ResourceType == PlanResourceTypeFrontendIPConfiguration {
+				fip := change.After.(network.FrontendIPConfiguration)
+				assert.Equal(t, gatewayLBFrontendIPConfigID, ptr.Deref(fip.GatewayLoadBalancer.ID, ""))
+			}
+		}
+		for _, fip := range *lb.FrontendIPConfigurations {
+			assert.Nil(t, fip.GatewayLoadBalancer, "the load balancer in the inventory should not be changed")
+		}
+	})
+}
+
+func TestPlanServiceApplicationSecurityGroup(t *testing.T) {
+	az, err := NewCloudForPlan(&azureconfig.Config{
+		AzureClientConfig: azureconfig.AzureClientConfig{
+			SubscriptionID: "subscription",
+		},
+		ResourceGroup:                            "rg",
+		Location:                                 "westus",
+		VnetName:                                 "vnet",
+		SubnetName:                               "subnet",
+		SecurityGroupName:                        "nsg",
+		LoadBalancerBackendPoolConfigurationType: consts.LoadBalancerBackendPoolConfigurationTypeNodeIP,
+	})
+	assert.NoError(t, err)
+	az.UseApplicationSecurityGroups = true
+	svc := getTestService("svc", v1.ProtocolTCP, map[string]string{consts.ServiceAnnotationDisableLoadBalancerFloatingIP: consts.TrueAnnotationValue}, false, 80)
+
+	p := newServicePlanner(&PlanInventory{SecurityGroup: getTestSecurityGroupForPlan()})
+	_, err = az.reconcileService(withServicePlanner(context.Background(), p), testClusterName, &svc, nil)
+	assert.NoError(t, err)
+	assert.NotNil(t, p.securityGroup)
+	assert.Len(t, p.securityGroup.Properties.SecurityRules, 1)
+	rule := p.securityGroup.Properties.SecurityRules[0]
+	assert.Len(t, rule.Properties.DestinationApplicationSecurityGroups, 1)
+	assert.Equal(t, az.getApplicationSecurityGroupID(testClusterName), ptr.Deref(rule.Properties.DestinationApplicationSecurityGroups[0].ID, ""))
+
+	// The security group patched on the application security group is up-to-date.
+	plan, err := az.PlanService(context.Background(), testClusterName, &svc, &PlanInventory{
+		LoadBalancers:     p.loadBalancers,
+		PublicIPAddresses: p.publicIPs,
+		SecurityGroup:     p.securityGroup,
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, getPlannedChanges(plan, PlanResourceTypeSecurityGroup))
+	assert.Empty(t, getPlannedChanges(plan, PlanResourceTypeSecurityRule))
+}
+
+func TestPlanServiceErrors(t *testing.T) {
+	az := getTestCloudForPlan(t)
+
+	svc := getTestService("svc", v1.ProtocolTCP, nil, false, 80)
+	svc.Spec.Type = v1.ServiceTypeClusterIP
+	_, err := az.PlanService(context.Background(), testClusterName, &svc, nil)
+	assert.Error(t, err)
+
+	svc = getTestService("svc", v1.ProtocolTCP, map[string]string{consts.ServiceAnnotationPIPNameDualStack[false]: "pip"}, false, 80)
+	_, err = az.PlanService(context.Background(), testClusterName, &svc, nil)
+	assert.ErrorContains(t, err, "azure-pip-name(-IPv6)=pip for service default/svc doesn't exist")
+
+	svc = getTestService("svc", v1.ProtocolTCP, nil, false, 80)
+	svc.Spec.LoadBalancerIP = "1.2.3.4"
+	_, err = az.PlanService(context.Background(), testClusterName, &svc, nil)
+	assert.ErrorContains(t, err, "cannot find public IP with IP address 1.2.3.4")
+}
+
+func TestServicePlanHandler(t *testing.T) {
+	handler := NewServicePlanHandler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ServicePlanPath+"?name=svc", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	az := getTestCloudForPlan(t)
+	handler.SetCloud(az, testClusterName)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ServicePlanPath+"?name=svc", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code, "the service lister is not set")
+}
@@ -40,6 +40,11 @@ import (
 
 // DeleteLB invokes az.LoadBalancerClient.Delete with exponential backoff retry
 func (az *Cloud) DeleteLB(ctx context.Context, service *v1.Service, lbName string) *retry.Error {
+	if p := getServicePlanner(ctx); p != nil {
+		p.deleteLoadBalancer(lbName)
+		return nil
+	}
+
 	rgName := az.getLoadBalancerResourceGroup()
 	rerr := az.LoadBalancerClient.Delete(ctx, rgName, lbName)
 	if rerr == nil {
@@ -49,19 +54,23 @@ func (az *Cloud) DeleteLB(ctx context.Context, service *v1.Service, lbName strin
 	}
 
 	klog.Errorf("LoadBalancerClient.Delete(%s) failed: %s", lbName, rerr.Error().Error())
-	az.Event(service, v1.EventTypeWarning, "DeleteLoadBalancer", rerr.Error().Error())
+	az.Event(ctx, service, v1.EventTypeWarning, "DeleteLoadBalancer", rerr.Error().Error())
 	return rerr
 }
 
 // ListLB invokes az.LoadBalancerClient.List with exponential backoff retry
 func (az *Cloud) ListLB(ctx context.Context, service *v1.Service) ([]network.LoadBalancer, error) {
+	if p := getServicePlanner(ctx); p != nil {
+		return p.listLoadBalancers(), nil
+	}
+
 	rgName := az.getLoadBalancerResourceGroup()
 	allLBs, rerr := az.LoadBalancerClient.List(ctx, rgName)
 	if rerr != nil {
 		if rerr.IsNotFound() {
 			return nil, nil
 		}
-		az.Event(service, v1.EventTypeWarning, "ListLoadBalancers", rerr.Error().Error())
+		az.Event(ctx, service, v1.EventTypeWarning, "ListLoadBalancers", rerr.Error().Error())
 		klog.Errorf("LoadBalancerClient.List(%v) failure with err=%v", rgName, rerr)
 		return nil, rerr.Error()
 	}
@@ -128,6 +137,10 @@ func (az *Cloud) ListManagedLBs(ctx context.Context, service *v1.Service, nodes
 // CreateOrUpdateLB invokes az.LoadBalancerClient.CreateOrUpdate with exponential backoff retry
 func (az *Cloud) CreateOrUpdateLB(ctx context.Context, service *v1.Service, lb network.LoadBalancer) error {
 	lb = cleanupSubnetInFrontendIPConfigurations(&lb)
+	if p := getServicePlanner(ctx); p != nil {
+		p.createOrUpdateLoadBalancer(lb)
+		return nil
+	}
 
 	rgName := az.getLoadBalancerResourceGroup()
 	rerr := az.LoadBalancerClient.CreateOrUpdate(ctx, rgName, ptr.Deref(lb.Name, ""), lb, ptr.Deref(lb.Etag, ""))
@@ -169,7 +182,7 @@ func (az *Cloud) CreateOrUpdateLB(ctx context.Context, service *v1.Service, lb n
 			return rerr.Error()
 		}
 		// Perform a dummy update to fix the provisioning state
-		err = az.CreateOrUpdatePIP(service, pipRG, pip)
+		err = az.CreateOrUpdatePIP(ctx, service, pipRG, pip)
 		if err != nil {
 			klog.Errorf("Failed to update the public IP %s in resource group %s: %v", pipName, pipRG, err)
 			return rerr.Error()
@@ -184,6 +197,9 @@ func (az *Cloud) CreateOrUpdateLB(ctx context.Context, service *v1.Service, lb n
 
 func (az *Cloud) CreateOrUpdateLBBackendPool(ctx context.Context, lbName string, backendPool network.BackendAddressPool) error {
 	klog.V(4).Infof("CreateOrUpdateLBBackendPool: updating backend pool %s in LB %s", ptr.Deref(backendPool.Name, ""), lbName)
+	if p := getServicePlanner(ctx); p != nil {
+		return p.createOrUpdateBackendPool(lbName, backendPool)
+	}
 	rerr := az.LoadBalancerClient.CreateOrUpdateBackendPools(ctx, az.getLoadBalancerResourceGroup(), lbName, ptr.Deref(backendPool.Name, ""), backendPool, ptr.Deref(backendPool.Etag, ""))
 	if rerr == nil {
 		// Invalidate the cache right after updating
@@ -209,6 +225,10 @@ func (az *Cloud) CreateOrUpdateLBBackendPool(ctx context.Context, lbName string,
 
 func (az *Cloud) DeleteLBBackendPool(ctx context.Context, lbName, backendPoolName string) error {
 	klog.V(4).Infof("DeleteLBBackendPool: deleting backend pool %s in LB %s", backendPoolName, lbName)
+	if p := getServicePlanner(ctx); p != nil {
+		p.deleteBackendPool(lbName, backendPoolName)
+		return nil
+	}
 	rerr := az.LoadBalancerClient.DeleteLBBackendPool(ctx, az.getLoadBalancerResourceGroup(), lbName, backendPoolName)
 	if rerr == nil {
 		// Invalidate the cache right after updating
@@ -333,6 +353,11 @@ func (az *Cloud) newLBCache() (azcache.Resource, error) {
 }
 
 func (az *Cloud) getAzureLoadBalancer(ctx context.Context, name string, crt azcache.AzureCacheReadType) (lb *network.LoadBalancer, exists bool, err error) {
+	if p := getServicePlanner(ctx); p != nil {
+		lb, exists = p.getLoadBalancer(name)
+		return lb, exists, nil
+	}
+
 	cachedLB, err := az.lbCache.GetWithDeepCopy(ctx, name, crt)
 	if err != nil {
 		return lb, false, err
@@ -0,0 +1,139 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+	"sigs.k8s.io/cloud-provider-azure/pkg/provider/config"
+)
+
+// multipleStandardLoadBalancerState is the state of the multiple standard load balancers that is not in the cloud
+// provider configuration. It is persisted in a ConfigMap so that it is not lost when the cloud provider restarts.
+type multipleStandardLoadBalancerState struct {
+	// GeneratedConfigurations are the load balancer configurations generated by AutoScale.
+	GeneratedConfigurations []generatedLoadBalancerConfiguration `json:"generatedConfigurations,omitempty"`
+	// ServiceMigrations are the migrations of the services moved off the load balancers with MigrateServices,
+	// keyed by the names of the services.
+	ServiceMigrations map[string]*serviceMigration `json:"serviceMigrations,omitempty"`
+}
+
+// generatedLoadBalancerConfiguration is a load balancer configuration generated by AutoScale.
+type generatedLoadBalancerConfiguration struct {
+	Name          string                                               `json:"name"`
+	GeneratedFrom string                                               `json:"generatedFrom"`
+	Spec          config.MultipleStandardLoadBalancerConfigurationSpec `json:"spec"`
+}
+
+// getMultipleStandardLoadBalancerState reads the state from the ConfigMap. It returns an empty state if the ConfigMap does not exist.
+func (az *Cloud) getMultipleStandardLoadBalancerState(ctx context.Context) (*multipleStandardLoadBalancerState, error) {
+	if az.KubeClient == nil {
+		return nil, fmt.Errorf("az.KubeClient is nil")
+	}
+	configMap, err := az.KubeClient.CoreV1().ConfigMaps(consts.MultipleStandardLoadBalancerStateConfigMapNamespace).Get(ctx, consts.MultipleStandardLoadBalancerStateConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			klog.V(4).Infof("getMultipleStandardLoadBalancerState: ConfigMap %s/%s not found", consts.MultipleStandardLoadBalancerStateConfigMapNamespace, consts.MultipleStandardLoadBalancerStateConfigMapName)
+			return &multipleStandardLoadBalancerState{}, nil
+		}
+		return nil, fmt.Errorf("failed to get ConfigMap %s/%s: %w", consts.MultipleStandardLoadBalancerStateConfigMapNamespace, consts.MultipleStandardLoadBalancerStateConfigMapName, err)
+	}
+	return parseMultipleStandardLoadBalancerState(configMap)
+}
+
+// parseMultipleStandardLoadBalancerState returns the state in the ConfigMap.
+func parseMultipleStandardLoadBalancerState(configMap *v1.ConfigMap) (*multipleStandardLoadBalancerState, error) {
+	state := &multipleStandardLoadBalancerState{}
+	data := configMap.Data[consts.MultipleStandardLoadBalancerStateConfigMapKey]
+	if data == "" {
+		return state, nil
+	}
+	if err := json.Unmarshal([]byte(data), state); err != nil {
+		return nil, fmt.Errorf("failed to parse ConfigMap %s/%s: %w", configMap.Namespace, configMap.Name, err)
+	}
+	return state, nil
+}
+
+// updateMultipleStandardLoadBalancerState changes the state in the ConfigMap by the given function, and creates
+// the ConfigMap if it does not exist. The other parts of the state are kept as they are.
+func (az *Cloud) updateMultipleStandardLoadBalancerState(ctx context.Context, update func(state *multipleStandardLoadBalancerState)) error {
+	if az.KubeClient == nil {
+		return fmt.Errorf("az.KubeClient is nil")
+	}
+
+	configMaps := az.KubeClient.CoreV1().ConfigMaps(consts.MultipleStandardLoadBalancerStateConfigMapNamespace)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		state := &multipleStandardLoadBalancerState{}
+		configMap, err := configMaps.Get(ctx, consts.MultipleStandardLoadBalancerStateConfigMapName, metav1.GetOptions{})
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				return err
+			}
+			configMap = nil
+		} else if state, err = parseMultipleStandardLoadBalancerState(configMap); err != nil {
+			return err
+		}
+
+		update(state)
+		data, err := json.Marshal(state)
+		if err != nil {
+			return err
+		}
+		if configMap == nil {
+			configMap = &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      consts.MultipleStandardLoadBalancerStateConfigMapName,
+					Namespace: consts.MultipleStandardLoadBalancerStateConfigMapNamespace,
+				},
+				Data: map[string]string{consts.MultipleStandardLoadBalancerStateConfigMapKey: string(data)},
+			}
+			_, err = configMaps.Create(ctx, configMap, metav1.CreateOptions{})
+			return err
+		}
+		if configMap.Data == nil {
+			configMap.Data = make(map[string]string)
+		}
+		configMap.Data[consts.MultipleStandardLoadBalancerStateConfigMapKey] = string(data)
+		_, err = configMaps.Update(ctx, configMap, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// getGeneratedLoadBalancerConfigurations returns the load balancer configurations generated by AutoScale in the state format.
+func getGeneratedLoadBalancerConfigurations(multiSLBConfigs []config.MultipleStandardLoadBalancerConfiguration) []generatedLoadBalancerConfiguration {
+	var generated []generatedLoadBalancerConfiguration
+	for _, multiSLBConfig := range multiSLBConfigs {
+		if multiSLBConfig.GeneratedFrom == "" {
+			continue
+		}
+		generated = append(generated, generatedLoadBalancerConfiguration{
+			Name:          multiSLBConfig.Name,
+			GeneratedFrom: multiSLBConfig.GeneratedFrom,
+			Spec:          multiSLBConfig.MultipleStandardLoadBalancerConfigurationSpec,
+		})
+	}
+	return generated
+}
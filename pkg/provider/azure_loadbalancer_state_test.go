@@ -0,0 +1,84 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+	"sigs.k8s.io/cloud-provider-azure/pkg/provider/config"
+)
+
+func TestMultipleStandardLoadBalancerState(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	az := GetTestCloud(ctrl)
+	az.KubeClient = fake.NewSimpleClientset()
+
+	// An empty state is returned if the ConfigMap does not exist.
+	state, err := az.getMultipleStandardLoadBalancerState(context.TODO())
+	assert.NoError(t, err)
+	assert.Empty(t, state.GeneratedConfigurations)
+
+	// The ConfigMap is created and then updated.
+	for _, generated := range [][]generatedLoadBalancerConfiguration{
+		{{Name: "lb-1", GeneratedFrom: "lb"}},
+		{{Name: "lb-1", GeneratedFrom: "lb"}, {Name: "lb-2", GeneratedFrom: "lb", Spec: config.MultipleStandardLoadBalancerConfigurationSpec{PrimaryVMSet: "vmss"}}},
+	} {
+		err = az.updateMultipleStandardLoadBalancerState(context.TODO(), func(state *multipleStandardLoadBalancerState) {
+			state.GeneratedConfigurations = generated
+		})
+		assert.NoError(t, err)
+		state, err = az.getMultipleStandardLoadBalancerState(context.TODO())
+		assert.NoError(t, err)
+		assert.Equal(t, generated, state.GeneratedConfigurations)
+	}
+
+	// The other parts of the state are kept.
+	migrations := map[string]*serviceMigration{"default/svc": {LoadBalancer: "lb", Phase: serviceMigrationPhaseMigrated, Attempts: 1}}
+	err = az.updateMultipleStandardLoadBalancerState(context.TODO(), func(state *multipleStandardLoadBalancerState) {
+		state.ServiceMigrations = migrations
+	})
+	assert.NoError(t, err)
+	state, err = az.getMultipleStandardLoadBalancerState(context.TODO())
+	assert.NoError(t, err)
+	assert.Len(t, state.GeneratedConfigurations, 2)
+	assert.Equal(t, serviceMigrationPhaseMigrated, state.ServiceMigrations["default/svc"].Phase)
+
+	// An invalid state is reported.
+	_, err = az.KubeClient.CoreV1().ConfigMaps(consts.MultipleStandardLoadBalancerStateConfigMapNamespace).Update(context.TODO(), &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      consts.MultipleStandardLoadBalancerStateConfigMapName,
+			Namespace: consts.MultipleStandardLoadBalancerStateConfigMapNamespace,
+		},
+		Data: map[string]string{consts.MultipleStandardLoadBalancerStateConfigMapKey: "invalid"},
+	}, metav1.UpdateOptions{})
+	assert.NoError(t, err)
+	_, err = az.getMultipleStandardLoadBalancerState(context.TODO())
+	assert.Error(t, err)
+	err = az.updateMultipleStandardLoadBalancerState(context.TODO(), func(*multipleStandardLoadBalancerState) {})
+	assert.Error(t, err)
+}
@@ -41,9 +41,14 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes/fake"
 	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+	cloudprovider "k8s.io/cloud-provider"
 	"k8s.io/utils/ptr"
 
 	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/loadbalancerclient/mockloadbalancerclient"
@@ -51,8 +56,10 @@ import (
 	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/subnetclient/mocksubnetclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/vmssclient/mockvmssclient"
 	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+	"sigs.k8s.io/cloud-provider-azure/pkg/provider/applicationsecuritygroup"
 	"sigs.k8s.io/cloud-provider-azure/pkg/provider/config"
 	"sigs.k8s.io/cloud-provider-azure/pkg/provider/privatelinkservice"
+	"sigs.k8s.io/cloud-provider-azure/pkg/provider/securitygroup"
 	"sigs.k8s.io/cloud-provider-azure/pkg/provider/zone"
 	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
 	utilsets "sigs.k8s.io/cloud-provider-azure/pkg/util/sets"
@@ -1204,6 +1211,24 @@ func TestShouldReleaseExistingOwnedPublicIP(t *testing.T) {
 	}
 	existingPipWithTagIPv6Suffix := existingPipWithTag
 	existingPipWithTagIPv6Suffix.Name = ptr.To("testPIP-IPv6")
+	existingPipWithRoutingPreference := network.PublicIPAddress{
+		ID:   ptr.To("/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/publicIPAddresses/testPIP"),
+		Name: ptr.To("testPIP"),
+		PublicIPAddressPropertiesFormat: &network.PublicIPAddressPropertiesFormat{
+			PublicIPAddressVersion:   network.IPv4,
+			PublicIPAllocationMethod: network.Static,
+			IPTags: &[]network.IPTag{
+				{
+					IPTagType: ptr.To("tag1"),
+					Tag:       ptr.To("tag1value"),
+				},
+				{
+					IPTagType: ptr.To(consts.IPTagTypeRoutingPreference),
+					Tag:       ptr.To(consts.PublicIPRoutingPreferenceInternet),
+				},
+			},
+		},
+	}
 
 	existingPipWithNoPublicIPAddressFormatProperties := network.PublicIPAddress{
 		ID:                              ptr.To("/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/publicIPAddresses/testPIP"),
@@ -1235,6 +1260,18 @@ func TestShouldReleaseExistingOwnedPublicIP(t *testing.T) {
 			},
 			expectedShouldRelease: false,
 		},
+		{
+			desc:           "routing preference tag is not requested by the ip tags annotation, no release",
+			existingPip:    existingPipWithRoutingPreference,
+			lbShouldExist:  true,
+			lbIsInternal:   false,
+			desiredPipName: *existingPipWithRoutingPreference.Name,
+			ipTagRequest: serviceIPTagRequest{
+				IPTagsRequestedByAnnotation: true,
+				IPTags:                      existingPipWithTag.PublicIPAddressPropertiesFormat.IPTags,
+			},
+			expectedShouldRelease: false,
+		},
 		{
 			desc:           "nil tags (none-specified by annotation, some are present on object), no release",
 			existingPip:    existingPipWithTag,
@@ -1733,6 +1770,7 @@ func TestGetServiceLoadBalancerMultiSLB(t *testing.T) {
 		multiSLBConfigs []config.MultipleStandardLoadBalancerConfiguration
 		expectedLB      *network.LoadBalancer
 		expectedLBs     *[]network.LoadBalancer
+		expectedStatus  *v1.LoadBalancerStatus
 		expectedError   error
 	}{
 		{
@@ -1775,6 +1813,49 @@ func TestGetServiceLoadBalancerMultiSLB(t *testing.T) {
 				{Name: ptr.To("lb2-internal")},
 			},
 		},
+		{
+			description: "should return the status of the previous lb if the service is moved to a new lb",
+			existingLBs: []network.LoadBalancer{
+				{
+					Name: ptr.To("lb1-internal"),
+					LoadBalancerPropertiesFormat: &network.LoadBalancerPropertiesFormat{
+						FrontendIPConfigurations: &[]network.FrontendIPConfiguration{
+							{
+								Name: ptr.To("atest1"),
+								ID:   ptr.To("atest1"),
+								FrontendIPConfigurationPropertiesFormat: &network.FrontendIPConfigurationPropertiesFormat{
+									PrivateIPAddress: ptr.To("1.2.3.4"),
+								},
+							},
+						},
+					},
+				},
+			},
+			service: getInternalTestService("test1"),
+			multiSLBConfigs: []config.MultipleStandardLoadBalancerConfiguration{
+				{
+					Name: "lb1",
+				},
+				{
+					Name: "lb2",
+					MultipleStandardLoadBalancerConfigurationStatus: config.MultipleStandardLoadBalancerConfigurationStatus{
+						ActiveServices: utilsets.NewString("default/test1"),
+					},
+				},
+			},
+			expectedLB: &network.LoadBalancer{
+				Name:     ptr.To("lb2-internal"),
+				Location: ptr.To("westus"),
+				Sku: &network.LoadBalancerSku{
+					Name: network.LoadBalancerSkuNameStandard,
+				},
+				LoadBalancerPropertiesFormat: &network.LoadBalancerPropertiesFormat{},
+			},
+			expectedLBs: &[]network.LoadBalancer{},
+			expectedStatus: &v1.LoadBalancerStatus{
+				Ingress: []v1.LoadBalancerIngress{{IP: "1.2.3.4"}},
+			},
+		},
 		{
 			description: "remove backend pool when a local service changes its load balancer",
 			existingLBs: []network.LoadBalancer{
@@ -1906,11 +1987,14 @@ func TestGetServiceLoadBalancerMultiSLB(t *testing.T) {
 				tc.service.Spec.ExternalTrafficPolicy = v1.ServiceExternalTrafficPolicyLocal
 			}
 
-			lb, lbs, _, _, _, err := cloud.getServiceLoadBalancer(context.TODO(), &tc.service, testClusterName,
+			lb, lbs, status, _, _, err := cloud.getServiceLoadBalancer(context.TODO(), &tc.service, testClusterName,
 				[]*v1.Node{}, true, &tc.existingLBs)
 			assert.Equal(t, tc.expectedError, err)
 			assert.Equal(t, tc.expectedLB, lb)
 			assert.Equal(t, tc.expectedLBs, lbs)
+			if tc.expectedStatus != nil {
+				assert.Equal(t, tc.expectedStatus, status)
+			}
 		})
 	}
 }
@@ -3104,6 +3188,28 @@ func TestGetExpectedLBRulesSharedProbe(t *testing.T) {
 	}
 }
 
+func TestGetExpectedLBRulesPodIP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	az := GetTestCloud(ctrl)
+	az.LoadBalancerBackendPoolConfigurationType = consts.LoadBalancerBackendPoolConfigurationTypePODIP
+	az.ClusterServiceLoadBalancerHealthProbeMode = consts.ClusterServiceLoadBalancerHealthProbeModeShared
+	svc := getTestService("test1", v1.ProtocolTCP, nil, false, 80)
+	svc.Spec.Ports[0].TargetPort = intstr.FromInt32(8080)
+	svc.Spec.ExternalTrafficPolicy = v1.ServiceExternalTrafficPolicyLocal
+	svc.Spec.HealthCheckNodePort = 32000
+
+	probes, rules, err := az.getExpectedLBRules(&svc, "frontendIPConfigID", "backendPoolID", "lbname", consts.IPVersionIPv4)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(rules))
+	assert.Equal(t, int32(8080), ptr.Deref(rules[0].BackendPort, 0))
+	assert.False(t, ptr.Deref(rules[0].EnableFloatingIP, true))
+	assert.Equal(t, 1, len(probes))
+	assert.Equal(t, network.ProbeProtocolTCP, probes[0].Protocol)
+	assert.Equal(t, int32(8080), ptr.Deref(probes[0].Port, 0))
+}
+
 // getDefaultTestRules returns dualstack rules.
 func getDefaultTestRules(enableTCPReset bool) map[bool][]network.LoadBalancingRule {
 	return map[bool][]network.LoadBalancingRule{
@@ -5747,6 +5853,25 @@ func TestIsFrontendIPConfigIsUnsafeToDelete(t *testing.T) {
 			},
 			unsafe: true,
 		},
+		{
+			desc: "isFrontendIPConfigUnsafeToDelete should return false if there is a " +
+				"outbound rule managed for this service referencing the frontend IP config",
+			existingLB: &network.LoadBalancer{
+				Name: ptr.To("lb"),
+				LoadBalancerPropertiesFormat: &network.LoadBalancerPropertiesFormat{
+					OutboundRules: &[]network.OutboundRule{
+						{
+							Name: ptr.To("aservice1-outbound"),
+							OutboundRulePropertiesFormat: &network.OutboundRulePropertiesFormat{
+								FrontendIPConfigurations: &[]network.SubResource{
+									{ID: ptr.To("fip")},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 		{
 			desc: "isFrontendIPConfigUnsafeToDelete should return false if there is a " +
 				"loadBalancing rule from this service referencing the frontend IP config",
@@ -5803,7 +5928,7 @@ func TestIsFrontendIPConfigIsUnsafeToDelete(t *testing.T) {
 	}
 
 	for _, testCase := range testCases {
-		unsafe, _ := az.isFrontendIPConfigUnsafeToDelete(testCase.existingLB, &service, fipID)
+		unsafe, _ := az.isFrontendIPConfigUnsafeToDelete(context.TODO(), testCase.existingLB, &service, fipID)
 		assert.Equal(t, testCase.unsafe, unsafe, testCase.desc)
 	}
 }
@@ -5900,6 +6025,28 @@ func TestCheckLoadBalancerResourcesConflicted(t *testing.T) {
 			},
 			expectedErr: true,
 		},
+		{
+			desc: "checkLoadBalancerResourcesConflicts should report the conflict error if " +
+				"there is an inbound NAT rule with a conflicted frontend port range",
+			fipID: "fip",
+			existingLB: &network.LoadBalancer{
+				Name: ptr.To("lb"),
+				LoadBalancerPropertiesFormat: &network.LoadBalancerPropertiesFormat{
+					InboundNatRules: &[]network.InboundNatRule{
+						{
+							Name: ptr.To("aservice2-nat-TCP-8080"),
+							InboundNatRulePropertiesFormat: &network.InboundNatRulePropertiesFormat{
+								FrontendIPConfiguration: &network.SubResource{ID: ptr.To("fip")},
+								FrontendPortRangeStart:  ptr.To(int32(50)),
+								FrontendPortRangeEnd:    ptr.To(int32(99)),
+								Protocol:                network.TransportProtocol(v1.ProtocolTCP),
+							},
+						},
+					},
+				},
+			},
+			expectedErr: true,
+		},
 		{
 			desc: "checkLoadBalancerResourcesConflicts should report the conflict error if " +
 				"there is a conflicted inbound NAT pool",
@@ -6683,6 +6830,184 @@ func TestReconcileFrontendIPConfigs(t *testing.T) {
 				},
 			},
 		},
+		{
+			desc: "Service with gateway load balancer annotation chains the existing FIP, dirty",
+			service: getTestService("test", v1.ProtocolTCP, map[string]string{
+				consts.ServiceAnnotationGatewayLoadBalancerFrontendIPConfigID: "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/gwlb/frontendIPConfigurations/gw-new",
+			}, false, 80),
+			existingFIPs: []network.FrontendIPConfiguration{
+				{
+					Name: ptr.To("atest"),
+					ID:   ptr.To("/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/lb/frontendIPConfigurations/atest"),
+					FrontendIPConfigurationPropertiesFormat: &network.FrontendIPConfigurationPropertiesFormat{
+						PublicIPAddress: &network.PublicIPAddress{
+							ID: ptr.To("testCluster-atest-id"),
+						},
+						GatewayLoadBalancer: &network.SubResource{
+							ID: ptr.To("/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/gwlb/frontendIPConfigurations/gw-old"),
+						},
+					},
+				},
+			},
+			existingPIPs: []network.PublicIPAddress{
+				{
+					Name: ptr.To("testCluster-atest"),
+					ID:   ptr.To("testCluster-atest-id"),
+					PublicIPAddressPropertiesFormat: &network.PublicIPAddressPropertiesFormat{
+						PublicIPAddressVersion:   network.IPv4,
+						PublicIPAllocationMethod: network.Static,
+						IPAddress:                ptr.To("1.2.3.5"),
+					},
+				},
+			},
+			wantLB:        true,
+			expectedDirty: true,
+			expectedFIPs: []network.FrontendIPConfiguration{
+				{
+					Name: ptr.To("atest"),
+					ID:   ptr.To("/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/lb/frontendIPConfigurations/atest"),
+					FrontendIPConfigurationPropertiesFormat: &network.FrontendIPConfigurationPropertiesFormat{
+						PublicIPAddress: &network.PublicIPAddress{
+							ID: ptr.To("testCluster-atest-id"),
+						},
+						GatewayLoadBalancer: &network.SubResource{
+							ID: ptr.To("/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/gwlb/frontendIPConfigurations/gw-new"),
+						},
+					},
+				},
+			},
+		},
+		{
+			desc:    "Service without gateway load balancer annotation keeps the chaining of the existing FIP, not dirty",
+			service: getTestService("test", v1.ProtocolTCP, nil, false, 80),
+			existingFIPs: []network.FrontendIPConfiguration{
+				{
+					Name: ptr.To("atest"),
+					ID:   ptr.To("/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/lb/frontendIPConfigurations/atest"),
+					FrontendIPConfigurationPropertiesFormat: &network.FrontendIPConfigurationPropertiesFormat{
+						PublicIPAddress: &network.PublicIPAddress{
+							ID: ptr.To("testCluster-atest-id"),
+						},
+						GatewayLoadBalancer: &network.SubResource{
+							ID: ptr.To("/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/gwlb/frontendIPConfigurations/gw-old"),
+						},
+					},
+				},
+			},
+			existingPIPs: []network.PublicIPAddress{
+				{
+					Name: ptr.To("testCluster-atest"),
+					ID:   ptr.To("testCluster-atest-id"),
+					PublicIPAddressPropertiesFormat: &network.PublicIPAddressPropertiesFormat{
+						PublicIPAddressVersion:   network.IPv4,
+						PublicIPAllocationMethod: network.Static,
+						IPAddress:                ptr.To("1.2.3.5"),
+					},
+				},
+			},
+			wantLB:        true,
+			expectedDirty: false,
+			expectedFIPs: []network.FrontendIPConfiguration{
+				{
+					Name: ptr.To("atest"),
+					ID:   ptr.To("/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/lb/frontendIPConfigurations/atest"),
+					FrontendIPConfigurationPropertiesFormat: &network.FrontendIPConfigurationPropertiesFormat{
+						PublicIPAddress: &network.PublicIPAddress{
+							ID: ptr.To("testCluster-atest-id"),
+						},
+						GatewayLoadBalancer: &network.SubResource{
+							ID: ptr.To("/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/gwlb/frontendIPConfigurations/gw-old"),
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "Service with empty gateway load balancer annotation removes the chaining of the existing FIP, dirty",
+			service: getTestService("test", v1.ProtocolTCP, map[string]string{
+				consts.ServiceAnnotationGatewayLoadBalancerFrontendIPConfigID: "",
+			}, false, 80),
+			existingFIPs: []network.FrontendIPConfiguration{
+				{
+					Name: ptr.To("atest"),
+					ID:   ptr.To("/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/lb/frontendIPConfigurations/atest"),
+					FrontendIPConfigurationPropertiesFormat: &network.FrontendIPConfigurationPropertiesFormat{
+						PublicIPAddress: &network.PublicIPAddress{
+							ID: ptr.To("testCluster-atest-id"),
+						},
+						GatewayLoadBalancer: &network.SubResource{
+							ID: ptr.To("/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/gwlb/frontendIPConfigurations/gw-old"),
+						},
+					},
+				},
+			},
+			existingPIPs: []network.PublicIPAddress{
+				{
+					Name: ptr.To("testCluster-atest"),
+					ID:   ptr.To("testCluster-atest-id"),
+					PublicIPAddressPropertiesFormat: &network.PublicIPAddressPropertiesFormat{
+						PublicIPAddressVersion:   network.IPv4,
+						PublicIPAllocationMethod: network.Static,
+						IPAddress:                ptr.To("1.2.3.5"),
+					},
+				},
+			},
+			wantLB:        true,
+			expectedDirty: true,
+			expectedFIPs: []network.FrontendIPConfiguration{
+				{
+					Name: ptr.To("atest"),
+					ID:   ptr.To("/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/lb/frontendIPConfigurations/atest"),
+					FrontendIPConfigurationPropertiesFormat: &network.FrontendIPConfigurationPropertiesFormat{
+						PublicIPAddress: &network.PublicIPAddress{
+							ID: ptr.To("testCluster-atest-id"),
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "Service with gateway load balancer annotation creates the chained FIP, dirty",
+			service: getTestService("test", v1.ProtocolTCP, map[string]string{
+				consts.ServiceAnnotationGatewayLoadBalancerFrontendIPConfigID: "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/gwlb/frontendIPConfigurations/gw-new",
+			}, false, 80),
+			existingPIPs: []network.PublicIPAddress{
+				{
+					Name: ptr.To("testCluster-atest"),
+					ID:   ptr.To("testCluster-atest-id"),
+					PublicIPAddressPropertiesFormat: &network.PublicIPAddressPropertiesFormat{
+						PublicIPAddressVersion:   network.IPv4,
+						PublicIPAllocationMethod: network.Static,
+						IPAddress:                ptr.To("1.2.3.5"),
+					},
+				},
+			},
+			wantLB:        true,
+			expectedDirty: true,
+			expectedFIPs: []network.FrontendIPConfiguration{
+				{
+					Name: ptr.To("atest"),
+					ID:   ptr.To("/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/lb/frontendIPConfigurations/atest"),
+					FrontendIPConfigurationPropertiesFormat: &network.FrontendIPConfigurationPropertiesFormat{
+						PublicIPAddress: &network.PublicIPAddress{
+							ID: ptr.To("testCluster-atest-id"),
+						},
+						GatewayLoadBalancer: &network.SubResource{
+							ID: ptr.To("/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/gwlb/frontendIPConfigurations/gw-new"),
+						},
+					},
+				},
+			},
+		},
+		{
+			desc: "Internal service with gateway load balancer annotation should return error",
+			service: getTestService("test", v1.ProtocolTCP, map[string]string{
+				consts.ServiceAnnotationLoadBalancerInternal:                  consts.TrueAnnotationValue,
+				consts.ServiceAnnotationGatewayLoadBalancerFrontendIPConfigID: "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/gwlb/frontendIPConfigurations/gw-new",
+			}, false, 80),
+			wantLB:      true,
+			expectedErr: fmt.Errorf("annotation %s is not supported on internal load balancers", consts.ServiceAnnotationGatewayLoadBalancerFrontendIPConfigID),
+		},
 	}
 
 	for _, tc := range testcases {
@@ -6719,6 +7044,71 @@ func TestReconcileFrontendIPConfigs(t *testing.T) {
 	}
 }
 
+func TestGetGatewayLoadBalancerFrontendIPConfigID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	const gatewayLBFrontendIPConfigID = "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/gwlb/frontendIPConfigurations/gw"
+	for _, tc := range []struct {
+		desc          string
+		annotations   map[string]string
+		sku           string
+		expectedID    string
+		expectedFound bool
+		expectedErr   bool
+	}{
+		{
+			desc: "no annotation",
+			sku:  consts.LoadBalancerSkuStandard,
+		},
+		{
+			desc:          "empty annotation",
+			annotations:   map[string]string{consts.ServiceAnnotationGatewayLoadBalancerFrontendIPConfigID: " "},
+			sku:           consts.LoadBalancerSkuStandard,
+			expectedFound: true,
+		},
+		{
+			desc:          "valid annotation",
+			annotations:   map[string]string{consts.ServiceAnnotationGatewayLoadBalancerFrontendIPConfigID: gatewayLBFrontendIPConfigID},
+			sku:           consts.LoadBalancerSkuStandard,
+			expectedID:    gatewayLBFrontendIPConfigID,
+			expectedFound: true,
+		},
+		{
+			desc:        "invalid ID",
+			annotations: map[string]string{consts.ServiceAnnotationGatewayLoadBalancerFrontendIPConfigID: "/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/gwlb"},
+			sku:         consts.LoadBalancerSkuStandard,
+			expectedErr: true,
+		},
+		{
+			desc:        "basic load balancer",
+			annotations: map[string]string{consts.ServiceAnnotationGatewayLoadBalancerFrontendIPConfigID: gatewayLBFrontendIPConfigID},
+			sku:         consts.LoadBalancerSkuBasic,
+			expectedErr: true,
+		},
+		{
+			desc: "internal load balancer",
+			annotations: map[string]string{
+				consts.ServiceAnnotationGatewayLoadBalancerFrontendIPConfigID: gatewayLBFrontendIPConfigID,
+				consts.ServiceAnnotationLoadBalancerInternal:                  consts.TrueAnnotationValue,
+			},
+			sku:         consts.LoadBalancerSkuStandard,
+			expectedErr: true,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			az := GetTestCloud(ctrl)
+			az.LoadBalancerSku = tc.sku
+			service := getTestService("test", v1.ProtocolTCP, tc.annotations, false, 80)
+
+			id, found, err := az.getGatewayLoadBalancerFrontendIPConfigID(&service)
+			assert.Equal(t, tc.expectedErr, err != nil)
+			assert.Equal(t, tc.expectedID, id)
+			assert.Equal(t, tc.expectedFound, found)
+		})
+	}
+}
+
 func TestReconcileIPSettings(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -6911,6 +7301,8 @@ func TestSafeDeleteLoadBalancer(t *testing.T) {
 		nodesWithCorrectVMSet         *utilsets.IgnoreCaseSet
 		expectedMultiSLBConfigs       []config.MultipleStandardLoadBalancerConfiguration
 		expectedNodesWithCorrectVMSet *utilsets.IgnoreCaseSet
+		useApplicationSecurityGroups  bool
+		asgDeleteErr                  error
 		expectedErr                   *retry.Error
 	}{
 		{
@@ -6918,6 +7310,20 @@ func TestSafeDeleteLoadBalancer(t *testing.T) {
 			expectedDeleteCall: true,
 			expectedErr:        nil,
 		},
+		{
+			desc:                         "should delete the application security group with the load balancer",
+			expectedDeleteCall:           true,
+			useApplicationSecurityGroups: true,
+		},
+		{
+			desc:                         "should not delete the load balancer if failed to delete the application security group",
+			useApplicationSecurityGroups: true,
+			asgDeleteErr:                 errors.New("in use"),
+			expectedErr: retry.NewError(
+				false,
+				fmt.Errorf("safeDeleteLoadBalancer: failed to delete the application security group: %w", errors.New("in use")),
+			),
+		},
 		{
 			desc:                "Standard SKU: should not delete the load balancer if failed to ensure backend pool deleted",
 			expectedDeleteCall:  false,
@@ -6980,6 +7386,12 @@ func TestSafeDeleteLoadBalancer(t *testing.T) {
 			).Return(false, tc.expectedDecoupleErr)
 			cloud.VMSet = mockVMSet
 			cloud.LoadBalancerClient = mockLBClient
+			cloud.UseApplicationSecurityGroups = tc.useApplicationSecurityGroups
+			if tc.useApplicationSecurityGroups {
+				mockASGRepo := cloud.asgRepo.(*applicationsecuritygroup.MockRepository)
+				mockASGRepo.EXPECT().Get(gomock.Any(), "test", gomock.Any()).Return(&armnetwork.ApplicationSecurityGroup{Name: ptr.To("test")}, nil)
+				mockASGRepo.EXPECT().Delete(gomock.Any(), "test").Return(tc.asgDeleteErr)
+			}
 			if len(tc.multiSLBConfigs) > 0 {
 				cloud.MultipleStandardLoadBalancerConfigurations = tc.multiSLBConfigs
 				for _, nodeName := range tc.nodesWithCorrectVMSet.UnsortedList() {
@@ -8857,3 +9269,90 @@ func fakeEnsureHostsInPool() func(context.Context, *v1.Service, []*v1.Node, stri
 		return nil
 	}
 }
+
+func TestLoadBalancerClassOwnership(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	az := GetTestCloud(ctrl)
+	assert.True(t, az.OwnsLoadBalancerClass(nil))
+	assert.False(t, az.OwnsLoadBalancerClass(ptr.To("other")))
+
+	az.OwnServicesWithoutLoadBalancerClass = ptr.To(false)
+	assert.False(t, az.OwnsLoadBalancerClass(nil))
+	assert.False(t, az.OwnsLoadBalancerClass(ptr.To("other")))
+
+	// The services owned by other controllers are skipped without calling Azure APIs.
+	for _, loadBalancerClass := range []*string{nil, ptr.To("other")} {
+		svc := getTestService("svc", v1.ProtocolTCP, nil, false, 80)
+		svc.Spec.LoadBalancerClass = loadBalancerClass
+
+		status, err := az.EnsureLoadBalancer(context.TODO(), testClusterName, &svc, nil)
+		assert.Nil(t, status)
+		assert.Equal(t, cloudprovider.ImplementedElsewhere, err)
+		assert.Equal(t, cloudprovider.ImplementedElsewhere, az.UpdateLoadBalancer(context.TODO(), testClusterName, &svc, nil))
+		assert.Equal(t, cloudprovider.ImplementedElsewhere, az.EnsureLoadBalancerDeleted(context.TODO(), testClusterName, &svc))
+		status, exists, err := az.GetLoadBalancer(context.TODO(), testClusterName, &svc)
+		assert.NoError(t, err)
+		assert.False(t, exists)
+		assert.Nil(t, status)
+	}
+}
+
+func TestLoadBalancerClassNotDeletedByServiceController(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// The service controller never ensures the load balancer of a service with a loadBalancerClass, and it
+	// calls EnsureLoadBalancerDeleted for such services if GetLoadBalancer reports that the load balancer exists.
+	// The load balancer of the service, e.g. created before the class was set, must be left alone.
+	az := GetTestCloud(ctrl)
+	svc := getTestService("svc", v1.ProtocolTCP, nil, false, 80)
+	svc.Spec.LoadBalancerClass = ptr.To("service.k8s.io/azure")
+	svc.Status.LoadBalancer.Ingress = []v1.LoadBalancerIngress{{IP: "1.2.3.4"}}
+
+	// No LB or public IP is listed or deleted, which would fail the test with unexpected calls to the mocks.
+	_, exists, err := az.GetLoadBalancer(context.TODO(), testClusterName, &svc)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	if exists {
+		assert.NoError(t, az.EnsureLoadBalancerDeleted(context.TODO(), testClusterName, &svc))
+	}
+}
+
+func TestIsPublicIPReferencedByUnownedService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	az := GetTestCloud(ctrl)
+	owned := getTestService("owned", v1.ProtocolTCP, nil, false, 80)
+	unowned := getTestService("unowned", v1.ProtocolTCP, nil, false, 80)
+	unowned.Spec.LoadBalancerClass = ptr.To("other")
+	kubeClient := fake.NewSimpleClientset(&owned, &unowned)
+	informerFactory := informers.NewSharedInformerFactory(kubeClient, 0)
+	az.serviceLister = informerFactory.Core().V1().Services().Lister()
+	informerFactory.Start(wait.NeverStop)
+	informerFactory.WaitForCacheSync(wait.NeverStop)
+
+	assert.False(t, az.isPublicIPReferencedByUnownedService(nil))
+	assert.False(t, az.isPublicIPReferencedByUnownedService([]string{"default/owned", "default/deleted"}))
+	assert.True(t, az.isPublicIPReferencedByUnownedService([]string{"default/owned", "default/unowned"}))
+}
+
+func TestEmitSecurityGroupCapacityEvent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	az := GetTestCloud(ctrl)
+	recorder := record.NewFakeRecorder(10)
+	az.eventRecorder = recorder
+	svc := getTestService("svc", v1.ProtocolTCP, nil, false, 80)
+
+	az.emitSecurityGroupCapacityEvent(context.Background(), &svc, errors.New("unrelated error"))
+	assert.Len(t, recorder.Events, 0)
+
+	az.emitSecurityGroupCapacityEvent(context.Background(), &svc, fmt.Errorf("unable to apply: %w", securitygroup.ErrSecurityGroupCapacityExceeded))
+	az.emitSecurityGroupCapacityEvent(context.Background(), &svc, fmt.Errorf("add rule: %w", securitygroup.ErrSecurityRulePriorityExhausted))
+	assert.Len(t, recorder.Events, 2)
+	assert.Contains(t, <-recorder.Events, "SecurityGroupCapacityExceeded")
+}
@@ -27,6 +27,7 @@ import (
 
 	v1 "k8s.io/api/core/v1"
 	discovery_v1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/informers"
@@ -200,7 +201,7 @@ func (updater *loadBalancerBackendPoolUpdater) process(ctx context.Context) {
 		operationName := fmt.Sprintf("%s/%s", lbName, poolName)
 		bp, rerr := updater.az.LoadBalancerClient.GetLBBackendPool(ctx, updater.az.ResourceGroup, lbName, poolName, "")
 		if rerr != nil {
-			updater.processError(rerr, operationName, ops...)
+			updater.processError(ctx, rerr, operationName, ops...)
 			continue
 		}
 
@@ -224,17 +225,18 @@ func (updater *loadBalancerBackendPoolUpdater) process(ctx context.Context) {
 			klog.V(2).Infof("loadBalancerBackendPoolUpdater.process: updating backend pool %s/%s", lbName, poolName)
 			rerr = updater.az.LoadBalancerClient.CreateOrUpdateBackendPools(ctx, updater.az.ResourceGroup, lbName, poolName, bp, ptr.Deref(bp.Etag, ""))
 			if rerr != nil {
-				updater.processError(rerr, operationName, ops...)
+				updater.processError(ctx, rerr, operationName, ops...)
 				continue
 			}
 		}
-		updater.notify(newBatchOperationResult(operationName, true, nil), ops...)
+		updater.notify(ctx, newBatchOperationResult(operationName, true, nil), ops...)
 	}
 }
 
 // processError mark the operations as retriable if the error is retriable,
 // and fail all operations if the error is not retriable.
 func (updater *loadBalancerBackendPoolUpdater) processError(
+	ctx context.Context,
 	rerr *retry.Error,
 	operationName string,
 	operations ...batchOperation,
@@ -249,14 +251,14 @@ func (updater *loadBalancerBackendPoolUpdater) processError(
 		updater.operations = append(updater.operations, operations...)
 	} else {
 		// Fail all operations if not retriable.
-		updater.notify(newBatchOperationResult(operationName, false, rerr.Error()), operations...)
+		updater.notify(ctx, newBatchOperationResult(operationName, false, rerr.Error()), operations...)
 	}
 }
 
 // notify notifies the operations with the result.
-func (updater *loadBalancerBackendPoolUpdater) notify(res batchOperationResult, operations ...batchOperation) {
+func (updater *loadBalancerBackendPoolUpdater) notify(ctx context.Context, res batchOperationResult, operations ...batchOperation) {
 	for _, op := range operations {
-		updater.az.processBatchOperationResult(op, res)
+		updater.az.processBatchOperationResult(ctx, op, res)
 		break
 	}
 }
@@ -294,6 +296,12 @@ func (az *Cloud) setUpEndpointSlicesInformer(informerFactory informers.SharedInf
 			AddFunc: func(obj interface{}) {
 				es := obj.(*discovery_v1.EndpointSlice)
 				az.endpointSlicesCache.Store(strings.ToLower(fmt.Sprintf("%s/%s", es.Namespace, es.Name)), es)
+
+				// Pod IPs of a newly created EndpointSlice need to join the backend pool,
+				// while node IP based local service backend pools are reconciled by the main loop.
+				if svcName := getServiceNameOfEndpointSlice(es); svcName != "" && az.IsLBBackendPoolTypePodIP() {
+					az.applyEndpointSliceChangesToBackendPools(svcName, nil, es)
+				}
 			},
 			UpdateFunc: func(oldObj, newObj interface{}) {
 				previousES := oldObj.(*discovery_v1.EndpointSlice)
@@ -308,61 +316,92 @@ func (az *Cloud) setUpEndpointSlicesInformer(informerFactory informers.SharedInf
 				klog.V(4).Infof("Detecting EndpointSlice %s/%s update", newES.Namespace, newES.Name)
 				az.endpointSlicesCache.Store(strings.ToLower(fmt.Sprintf("%s/%s", newES.Namespace, newES.Name)), newES)
 
-				key := strings.ToLower(fmt.Sprintf("%s/%s", newES.Namespace, svcName))
-				si, found := az.getLocalServiceInfo(key)
-				if !found {
-					klog.V(4).Infof("EndpointSlice %s/%s belongs to service %s, but the service is not a local service, or has not finished the initial reconciliation loop. Skip updating load balancer backend pool", newES.Namespace, newES.Name, key)
-					return
-				}
-				lbName, ipFamily := si.lbName, si.ipFamily
-
-				var previousIPs, currentIPs, previousNodeNames, currentNodeNames []string
-				if previousES != nil {
-					for _, ep := range previousES.Endpoints {
-						previousNodeNames = append(previousNodeNames, ptr.Deref(ep.NodeName, ""))
+				az.applyEndpointSliceChangesToBackendPools(svcName, previousES, newES)
+			},
+			DeleteFunc: func(obj interface{}) {
+				es, ok := obj.(*discovery_v1.EndpointSlice)
+				if !ok {
+					tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+					if !ok {
+						return
 					}
-				}
-				if newES != nil {
-					for _, ep := range newES.Endpoints {
-						currentNodeNames = append(currentNodeNames, ptr.Deref(ep.NodeName, ""))
+					if es, ok = tombstone.Obj.(*discovery_v1.EndpointSlice); !ok {
+						return
 					}
 				}
-				for _, previousNodeName := range previousNodeNames {
-					nodeIPsSet := az.nodePrivateIPs[strings.ToLower(previousNodeName)]
-					previousIPs = append(previousIPs, nodeIPsSet.UnsortedList()...)
-				}
-				for _, currentNodeName := range currentNodeNames {
-					nodeIPsSet := az.nodePrivateIPs[strings.ToLower(currentNodeName)]
-					currentIPs = append(currentIPs, nodeIPsSet.UnsortedList()...)
-				}
+				az.endpointSlicesCache.Delete(strings.ToLower(fmt.Sprintf("%s/%s", es.Namespace, es.Name)))
 
-				if az.backendPoolUpdater != nil {
-					var bpNames []string
-					bpNameIPv4 := getLocalServiceBackendPoolName(key, false)
-					bpNameIPv6 := getLocalServiceBackendPoolName(key, true)
-					switch strings.ToLower(ipFamily) {
-					case strings.ToLower(consts.IPVersionIPv4String):
-						bpNames = append(bpNames, bpNameIPv4)
-					case strings.ToLower(consts.IPVersionIPv6String):
-						bpNames = append(bpNames, bpNameIPv6)
-					default:
-						bpNames = append(bpNames, bpNameIPv4, bpNameIPv6)
-					}
-					currentIPsInBackendPools := make(map[string][]string)
-					for _, bpName := range bpNames {
-						currentIPsInBackendPools[bpName] = previousIPs
-					}
-					az.applyIPChangesAmongLocalServiceBackendPoolsByIPFamily(lbName, key, currentIPsInBackendPools, currentIPs)
+				if svcName := getServiceNameOfEndpointSlice(es); svcName != "" && az.IsLBBackendPoolTypePodIP() {
+					az.applyEndpointSliceChangesToBackendPools(svcName, es, nil)
 				}
 			},
-			DeleteFunc: func(obj interface{}) {
-				es := obj.(*discovery_v1.EndpointSlice)
-				az.endpointSlicesCache.Delete(strings.ToLower(fmt.Sprintf("%s/%s", es.Namespace, es.Name)))
-			},
 		})
 }
 
-func (az *Cloud) processBatchOperationResult(op batchOperation, res batchOperationResult) {
+// applyEndpointSliceChangesToBackendPools sends backend pool update operations to the batch
+// updater according to the difference between the previous and the current EndpointSlice.
+// Node IPs of the endpoints are used for local services, and pod IPs are used if the
+// backend pool type is podIP. Either of the EndpointSlices can be nil.
+func (az *Cloud) applyEndpointSliceChangesToBackendPools(svcName string, previousES, newES *discovery_v1.EndpointSlice) {
+	es := newES
+	if es == nil {
+		es = previousES
+	}
+	key := strings.ToLower(fmt.Sprintf("%s/%s", es.Namespace, svcName))
+	si, found := az.getLocalServiceInfo(key)
+	if !found {
+		klog.V(4).Infof("EndpointSlice %s/%s belongs to service %s, but the service is not a local service, or has not finished the initial reconciliation loop. Skip updating load balancer backend pool", es.Namespace, es.Name, key)
+		return
+	}
+	lbName, ipFamily := si.lbName, si.ipFamily
+
+	var previousIPs, currentIPs []string
+	if az.IsLBBackendPoolTypePodIP() {
+		previousIPs = getEndpointSlicePodIPs(previousES)
+		currentIPs = getEndpointSlicePodIPs(newES)
+	} else {
+		var previousNodeNames, currentNodeNames []string
+		if previousES != nil {
+			for _, ep := range previousES.Endpoints {
+				previousNodeNames = append(previousNodeNames, ptr.Deref(ep.NodeName, ""))
+			}
+		}
+		if newES != nil {
+			for _, ep := range newES.Endpoints {
+				currentNodeNames = append(currentNodeNames, ptr.Deref(ep.NodeName, ""))
+			}
+		}
+		for _, previousNodeName := range previousNodeNames {
+			nodeIPsSet := az.nodePrivateIPs[strings.ToLower(previousNodeName)]
+			previousIPs = append(previousIPs, nodeIPsSet.UnsortedList()...)
+		}
+		for _, currentNodeName := range currentNodeNames {
+			nodeIPsSet := az.nodePrivateIPs[strings.ToLower(currentNodeName)]
+			currentIPs = append(currentIPs, nodeIPsSet.UnsortedList()...)
+		}
+	}
+
+	if az.backendPoolUpdater != nil {
+		var bpNames []string
+		bpNameIPv4 := getLocalServiceBackendPoolName(key, false)
+		bpNameIPv6 := getLocalServiceBackendPoolName(key, true)
+		switch strings.ToLower(ipFamily) {
+		case strings.ToLower(consts.IPVersionIPv4String):
+			bpNames = append(bpNames, bpNameIPv4)
+		case strings.ToLower(consts.IPVersionIPv6String):
+			bpNames = append(bpNames, bpNameIPv6)
+		default:
+			bpNames = append(bpNames, bpNameIPv4, bpNameIPv6)
+		}
+		currentIPsInBackendPools := make(map[string][]string)
+		for _, bpName := range bpNames {
+			currentIPsInBackendPools[bpName] = previousIPs
+		}
+		az.applyIPChangesAmongLocalServiceBackendPoolsByIPFamily(lbName, key, currentIPsInBackendPools, currentIPs)
+	}
+}
+
+func (az *Cloud) processBatchOperationResult(ctx context.Context, op batchOperation, res batchOperationResult) {
 	lbOp := op.(*loadBalancerBackendPoolUpdateOperation)
 	var svc *v1.Service
 	svc, _, _ = az.getLatestService(lbOp.serviceName, false)
@@ -375,9 +414,9 @@ func (az *Cloud) processBatchOperationResult(op batchOperation, res batchOperati
 		if res.err != nil {
 			errStr = res.err.Error()
 		}
-		az.Event(svc, v1.EventTypeWarning, "LoadBalancerBackendPoolUpdateFailed", errStr)
+		az.Event(ctx, svc, v1.EventTypeWarning, "LoadBalancerBackendPoolUpdateFailed", errStr)
 	} else {
-		az.Event(svc, v1.EventTypeNormal, "LoadBalancerBackendPoolUpdated", "Load balancer backend pool updated successfully")
+		az.Event(ctx, svc, v1.EventTypeNormal, "LoadBalancerBackendPoolUpdated", "Load balancer backend pool updated successfully")
 	}
 }
 
@@ -405,10 +444,18 @@ func getLocalServiceBackendPoolName(serviceName string, ipv6 bool) string {
 	return serviceName
 }
 
+// useServiceBackendPool returns true if the service has a dedicated backend pool
+// instead of sharing the cluster backend pool. This is the case for local services
+// when using multiple standard load balancers, and for all services when the
+// backend pool type is podIP.
+func (az *Cloud) useServiceBackendPool(service *v1.Service) bool {
+	return az.IsLBBackendPoolTypePodIP() || (isLocalService(service) && az.UseMultipleStandardLoadBalancers())
+}
+
 // getBackendPoolNameForService determine the expected backend pool name
 // by checking the external traffic policy of the service.
 func (az *Cloud) getBackendPoolNameForService(service *v1.Service, clusterName string, ipv6 bool) string {
-	if !isLocalService(service) || !az.UseMultipleStandardLoadBalancers() {
+	if !az.useServiceBackendPool(service) {
 		return getBackendPoolName(clusterName, ipv6)
 	}
 	return getLocalServiceBackendPoolName(getServiceName(service), ipv6)
@@ -417,7 +464,7 @@ func (az *Cloud) getBackendPoolNameForService(service *v1.Service, clusterName s
 // getBackendPoolNamesForService determine the expected backend pool names
 // by checking the external traffic policy of the service.
 func (az *Cloud) getBackendPoolNamesForService(service *v1.Service, clusterName string) map[bool]string {
-	if !isLocalService(service) || !az.UseMultipleStandardLoadBalancers() {
+	if !az.useServiceBackendPool(service) {
 		return getBackendPoolNames(clusterName)
 	}
 	return map[bool]string{
@@ -429,7 +476,7 @@ func (az *Cloud) getBackendPoolNamesForService(service *v1.Service, clusterName
 // getBackendPoolIDsForService determine the expected backend pool IDs
 // by checking the external traffic policy of the service.
 func (az *Cloud) getBackendPoolIDsForService(service *v1.Service, clusterName, lbName string) map[bool]string {
-	if !isLocalService(service) || !az.UseMultipleStandardLoadBalancers() {
+	if !az.useServiceBackendPool(service) {
 		return az.getBackendPoolIDs(clusterName, lbName)
 	}
 	return map[bool]string{
@@ -463,8 +510,8 @@ func newServiceInfo(ipFamily, lbName string) *serviceInfo {
 	}
 }
 
-// getLocalServiceEndpointsNodeNames gets the node names that host all endpoints of the local service.
-func (az *Cloud) getLocalServiceEndpointsNodeNames(service *v1.Service) *utilsets.IgnoreCaseSet {
+// getServiceEndpointSlices gets all cached EndpointSlices of the service.
+func (az *Cloud) getServiceEndpointSlices(service *v1.Service) []*discovery_v1.EndpointSlice {
 	var eps []*discovery_v1.EndpointSlice
 	az.endpointSlicesCache.Range(func(_, value interface{}) bool {
 		endpointSlice := value.(*discovery_v1.EndpointSlice)
@@ -474,6 +521,12 @@ func (az *Cloud) getLocalServiceEndpointsNodeNames(service *v1.Service) *utilset
 		}
 		return true
 	})
+	return eps
+}
+
+// getLocalServiceEndpointsNodeNames gets the node names that host all endpoints of the local service.
+func (az *Cloud) getLocalServiceEndpointsNodeNames(service *v1.Service) *utilsets.IgnoreCaseSet {
+	eps := az.getServiceEndpointSlices(service)
 	if len(eps) == 0 {
 		klog.Warningf("getLocalServiceEndpointsNodeNames: failed to find EndpointSlice for service %s/%s", service.Namespace, service.Name)
 		return nil
@@ -490,6 +543,66 @@ func (az *Cloud) getLocalServiceEndpointsNodeNames(service *v1.Service) *utilset
 	return utilsets.NewString(nodeNames...)
 }
 
+// getServiceEndpointsPodIPs gets the IPs of all ready endpoints of the service,
+// mapped to the names of the nodes hosting them.
+func (az *Cloud) getServiceEndpointsPodIPs(service *v1.Service) map[string]string {
+	podIPToNodeName := make(map[string]string)
+	for _, es := range az.getServiceEndpointSlices(service) {
+		for _, endpoint := range es.Endpoints {
+			if !isEndpointReady(endpoint) {
+				continue
+			}
+			for _, address := range endpoint.Addresses {
+				podIPToNodeName[address] = ptr.Deref(endpoint.NodeName, "")
+			}
+		}
+	}
+	return podIPToNodeName
+}
+
+// getEndpointSlicePodIPs gets the IPs of all ready endpoints in the EndpointSlice.
+func getEndpointSlicePodIPs(es *discovery_v1.EndpointSlice) []string {
+	if es == nil {
+		return nil
+	}
+	var podIPs []string
+	for _, endpoint := range es.Endpoints {
+		if isEndpointReady(endpoint) {
+			podIPs = append(podIPs, endpoint.Addresses...)
+		}
+	}
+	return podIPs
+}
+
+// isEndpointReady checks if the endpoint is ready to serve traffic.
+// An unknown state is interpreted as ready.
+func isEndpointReady(endpoint discovery_v1.Endpoint) bool {
+	return ptr.Deref(endpoint.Conditions.Ready, true)
+}
+
+// getServicePortTargetPort gets the port on the pods that a service port targets.
+// Named target ports are resolved from the EndpointSlices of the service.
+func (az *Cloud) getServicePortTargetPort(service *v1.Service, port v1.ServicePort) (int32, error) {
+	switch {
+	case port.TargetPort.Type == intstr.Int && port.TargetPort.IntVal != 0:
+		return port.TargetPort.IntVal, nil
+	case port.TargetPort.Type == intstr.Int:
+		// The target port defaults to the service port.
+		return port.Port, nil
+	}
+
+	for _, es := range az.getServiceEndpointSlices(service) {
+		for _, esPort := range es.Ports {
+			if ptr.Deref(esPort.Name, "") == port.Name &&
+				ptr.Deref(esPort.Protocol, v1.ProtocolTCP) == port.Protocol &&
+				esPort.Port != nil {
+				return *esPort.Port, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("failed to resolve the named target port %q of service port %d from the EndpointSlices of service %s", port.TargetPort.StrVal, port.Port, getServiceName(service))
+}
+
 // cleanupLocalServiceBackendPool cleans up the backend pool of
 // a local service among given load balancers.
 func (az *Cloud) cleanupLocalServiceBackendPool(
@@ -531,15 +644,21 @@ func (az *Cloud) cleanupLocalServiceBackendPool(
 // with the corresponding endpointslice, and update the backend pool if necessary.
 func (az *Cloud) checkAndApplyLocalServiceBackendPoolUpdates(lb network.LoadBalancer, service *v1.Service) error {
 	serviceName := getServiceName(service)
-	endpointsNodeNames := az.getLocalServiceEndpointsNodeNames(service)
-	if endpointsNodeNames == nil {
-		return nil
-	}
 
 	var expectedIPs []string
-	for _, nodeName := range endpointsNodeNames.UnsortedList() {
-		ips := az.nodePrivateIPs[strings.ToLower(nodeName)]
-		expectedIPs = append(expectedIPs, ips.UnsortedList()...)
+	if az.IsLBBackendPoolTypePodIP() {
+		for podIP := range az.getServiceEndpointsPodIPs(service) {
+			expectedIPs = append(expectedIPs, podIP)
+		}
+	} else {
+		endpointsNodeNames := az.getLocalServiceEndpointsNodeNames(service)
+		if endpointsNodeNames == nil {
+			return nil
+		}
+		for _, nodeName := range endpointsNodeNames.UnsortedList() {
+			ips := az.nodePrivateIPs[strings.ToLower(nodeName)]
+			expectedIPs = append(expectedIPs, ips.UnsortedList()...)
+		}
 	}
 	currentIPsInBackendPools := make(map[string][]string)
 	for _, bp := range *lb.BackendAddressPools {
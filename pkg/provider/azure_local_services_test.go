@@ -33,6 +33,7 @@ import (
 	v1 "k8s.io/api/core/v1"
 	discovery_v1 "k8s.io/api/discovery/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes/fake"
 	"k8s.io/utils/ptr"
@@ -617,3 +618,75 @@ func TestCheckAndApplyLocalServiceBackendPoolUpdates(t *testing.T) {
 		})
 	}
 }
+
+func TestApplyEndpointSliceChangesToBackendPoolsPodIP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cloud := GetTestCloud(ctrl)
+	cloud.LoadBalancerBackendPoolConfigurationType = consts.LoadBalancerBackendPoolConfigurationTypePODIP
+	cloud.localServiceNameToServiceInfoMap.Store("test/svc1", newServiceInfo(consts.IPVersionIPv4String, "lb1"))
+	u := newLoadBalancerBackendPoolUpdater(cloud, time.Second)
+	cloud.backendPoolUpdater = u
+
+	previousES := getTestPodIPEndpointSlice("eps1", "test", "svc1", map[string]string{"10.244.0.1": "node1"})
+	currentES := getTestPodIPEndpointSlice("eps1", "test", "svc1", map[string]string{"10.244.0.2": "node1"})
+	cloud.applyEndpointSliceChangesToBackendPools("svc1", previousES, currentES)
+	assert.Equal(t, []batchOperation{
+		getRemoveIPsFromBackendPoolOperation("test/svc1", "lb1", "test-svc1", []string{"10.244.0.1"}),
+		getAddIPsToBackendPoolOperation("test/svc1", "lb1", "test-svc1", []string{"10.244.0.2"}),
+	}, u.operations)
+
+	u.operations = nil
+	cloud.applyEndpointSliceChangesToBackendPools("svc1", currentES, nil)
+	assert.Equal(t, []batchOperation{
+		getRemoveIPsFromBackendPoolOperation("test/svc1", "lb1", "test-svc1", []string{"10.244.0.2"}),
+	}, u.operations)
+}
+
+func TestGetServicePortTargetPort(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cloud := GetTestCloud(ctrl)
+	es := getTestEndpointSlice("eps1", "default", "svc1")
+	es.Ports = []discovery_v1.EndpointPort{
+		{Name: ptr.To("http"), Protocol: ptr.To(v1.ProtocolTCP), Port: ptr.To(int32(8080))},
+	}
+	cloud.endpointSlicesCache.Store("default/eps1", es)
+	svc := getTestService("svc1", v1.ProtocolTCP, nil, false)
+
+	for _, tc := range []struct {
+		desc         string
+		port         v1.ServicePort
+		expectedPort int32
+		expectedErr  bool
+	}{
+		{
+			desc:         "numeric target port",
+			port:         v1.ServicePort{Port: 80, TargetPort: intstr.FromInt32(8000), Protocol: v1.ProtocolTCP},
+			expectedPort: 8000,
+		},
+		{
+			desc:         "target port defaults to the service port",
+			port:         v1.ServicePort{Port: 80, Protocol: v1.ProtocolTCP},
+			expectedPort: 80,
+		},
+		{
+			desc:         "named target port is resolved from the EndpointSlices",
+			port:         v1.ServicePort{Name: "http", Port: 80, TargetPort: intstr.FromString("web"), Protocol: v1.ProtocolTCP},
+			expectedPort: 8080,
+		},
+		{
+			desc:        "named target port cannot be resolved",
+			port:        v1.ServicePort{Name: "https", Port: 443, TargetPort: intstr.FromString("web-tls"), Protocol: v1.ProtocolTCP},
+			expectedErr: true,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			port, err := cloud.getServicePortTargetPort(&svc, tc.port)
+			assert.Equal(t, tc.expectedErr, err != nil)
+			assert.Equal(t, tc.expectedPort, port)
+		})
+	}
+}
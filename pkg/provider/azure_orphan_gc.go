@@ -0,0 +1,775 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v6"
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2022-07-01/network"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	cloudprovider "k8s.io/cloud-provider"
+	servicehelpers "k8s.io/cloud-provider/service/helpers"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+
+	azcache "sigs.k8s.io/cloud-provider-azure/pkg/cache"
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+	"sigs.k8s.io/cloud-provider-azure/pkg/metrics"
+	"sigs.k8s.io/cloud-provider-azure/pkg/provider/loadbalancer"
+	"sigs.k8s.io/cloud-provider-azure/pkg/provider/securitygroup"
+	utilsets "sigs.k8s.io/cloud-provider-azure/pkg/util/sets"
+)
+
+// loadBalancerResourceOwnerRE matches the default load balancer name of a service, which prefixes the names of
+// the frontend IP configurations, rules and probes created for the service.
+var loadBalancerResourceOwnerRE = regexp.MustCompile(`^a[0-9a-f]{31}`)
+
+// orphanedResource is an Azure resource created for a service that no longer exists.
+type orphanedResource struct {
+	ResourceType PlanResourceType
+	// Resource is the name of the resource, prefixed by the resource group, or by the name of the parent resource
+	// for child resources. For security rules, it is the destination address prefixed by the security group name.
+	Resource string
+	// Service is the namespaced names of the owning services split by comma, or empty if they are unknown.
+	Service string
+}
+
+func (r orphanedResource) String() string {
+	return fmt.Sprintf("%s %s", r.ResourceType, r.Resource)
+}
+
+// serviceOwners are the services that may own Azure resources.
+type serviceOwners struct {
+	// services are the namespaced names of the LoadBalancer services and the services being cleaned up.
+	services *utilsets.IgnoreCaseSet
+	// loadBalancerNames are the default load balancer names of the services.
+	loadBalancerNames *utilsets.IgnoreCaseSet
+	// addresses are the ingress IPs of all services and the additional public IPs of the services.
+	addresses sets.Set[string]
+	// publicIPResourceGroups are the resource groups of the public IPs of the services.
+	publicIPResourceGroups *utilsets.IgnoreCaseSet
+	// privateLinkServiceResourceGroups are the resource groups of the private link services of the services.
+	privateLinkServiceResourceGroups *utilsets.IgnoreCaseSet
+}
+
+// ownsAny returns true if any of the services exists.
+func (o *serviceOwners) ownsAny(services []string) bool {
+	for _, service := range services {
+		if o.services.Has(service) {
+			return true
+		}
+	}
+	return false
+}
+
+// ownsLoadBalancerResource returns true if the name of the load balancer child resource is not prefixed by the default
+// load balancer name of a service, or if the service exists.
+func (o *serviceOwners) ownsLoadBalancerResource(name string) bool {
+	owner := loadBalancerResourceOwnerRE.FindString(strings.ToLower(name))
+	return owner == "" || o.loadBalancerNames.Has(owner)
+}
+
+// orphanedResourceGC periodically looks for the public IPs, load balancer child resources, private link services
+// and security rule destinations created by the cloud provider whose owning service no longer exists. The orphaned
+// resources are reported by events and metrics, and deleted after a grace period if enforce is set.
+type orphanedResourceGC struct {
+	az          *Cloud
+	clusterName string
+	interval    time.Duration
+	enforce     bool
+	gracePeriod time.Duration
+	// firstSeen is when the orphaned resources were found for the first time.
+	firstSeen map[string]time.Time
+	now       func() time.Time
+}
+
+// StartOrphanedResourceGC starts the garbage collection of the orphaned resources if it is enabled,
+// and stops if the context exits.
+func (az *Cloud) StartOrphanedResourceGC(ctx context.Context, clusterName string) {
+	if az.OrphanedResourceGCIntervalInSeconds <= 0 {
+		return
+	}
+	gc := newOrphanedResourceGC(
+		az,
+		clusterName,
+		time.Duration(az.OrphanedResourceGCIntervalInSeconds)*time.Second,
+		strings.EqualFold(az.OrphanedResourceGCMode, consts.OrphanedResourceGCModeEnforce),
+		time.Duration(az.OrphanedResourceGCGracePeriodInSeconds)*time.Second,
+	)
+	go gc.run(ctx)
+}
+
+func newOrphanedResourceGC(az *Cloud, clusterName string, interval time.Duration, enforce bool, gracePeriod time.Duration) *orphanedResourceGC {
+	return &orphanedResourceGC{
+		az:          az,
+		clusterName: clusterName,
+		interval:    interval,
+		enforce:     enforce,
+		gracePeriod: gracePeriod,
+		firstSeen:   make(map[string]time.Time),
+		now:         time.Now,
+	}
+}
+
+// run starts the orphanedResourceGC, and stops if the context exits.
+func (gc *orphanedResourceGC) run(ctx context.Context) {
+	klog.V(2).Infof("orphanedResourceGC.run: started, enforce: %t", gc.enforce)
+	err := wait.PollUntilContextCancel(ctx, gc.interval, false, func(ctx context.Context) (bool, error) {
+		gc.collect(ctx)
+		return false, nil
+	})
+	klog.Infof("orphanedResourceGC.run: stopped due to %s", err.Error())
+}
+
+// orphanedResourceGCSnapshot is the state of the services and the Azure resources taken at once.
+type orphanedResourceGCSnapshot struct {
+	owners *serviceOwners
+	// lbs are all load balancers in the resource group, and loadBalancerNames are the names of the load
+	// balancers of the cluster without the internal suffix.
+	lbs               []network.LoadBalancer
+	loadBalancerNames *utilsets.IgnoreCaseSet
+	pips              map[string][]network.PublicIPAddress
+	// plsLists are the private link services by resource group, or nil with plsErr if they cannot be listed.
+	plsLists map[string][]*armnetwork.PrivateLinkService
+	plsErr   error
+	sg       *armnetwork.SecurityGroup
+	sgErr    error
+}
+
+// snapshot lists the services and the Azure resources. The reconciliation of services is blocked while the snapshot
+// is taken, so the resources being created are not seen without their services. The Azure resources are deleted
+// after the lock is released, and a load balancer or security group updated in between fails the update by its etag.
+func (gc *orphanedResourceGC) snapshot(ctx context.Context) (*orphanedResourceGCSnapshot, error) {
+	gc.az.serviceReconcileLock.Lock()
+	defer gc.az.serviceReconcileLock.Unlock()
+
+	owners, err := gc.listServiceOwners()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services: %w", err)
+	}
+	lbs, rerr := gc.az.LoadBalancerClient.List(ctx, gc.az.getLoadBalancerResourceGroup())
+	if rerr != nil && !rerr.IsNotFound() {
+		return nil, fmt.Errorf("failed to list load balancers: %w", rerr.Error())
+	}
+	rv := &orphanedResourceGCSnapshot{
+		owners:            owners,
+		lbs:               lbs,
+		loadBalancerNames: utilsets.NewString(gc.clusterName),
+		pips:              make(map[string][]network.PublicIPAddress),
+		plsLists:          make(map[string][]*armnetwork.PrivateLinkService),
+	}
+	for _, multiSLBConfig := range gc.az.MultipleStandardLoadBalancerConfigurations {
+		rv.loadBalancerNames.Insert(multiSLBConfig.Name)
+	}
+	for _, rg := range sortedList(owners.publicIPResourceGroups) {
+		rv.pips[rg], err = gc.az.listPIP(ctx, rg, azcache.CacheReadTypeDefault)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list public IPs in resource group %s: %w", rg, err)
+		}
+	}
+	for _, rg := range sortedList(owners.privateLinkServiceResourceGroups) {
+		rv.plsLists[rg], err = gc.az.plsRepo.List(ctx, rg)
+		if err != nil {
+			rv.plsLists, rv.plsErr = nil, fmt.Errorf("failed to list private link services in resource group %s: %w", rg, err)
+			break
+		}
+	}
+	if rv.sg, err = gc.az.nsgRepo.GetSecurityGroup(ctx); err != nil {
+		rv.sgErr = fmt.Errorf("failed to get security group: %w", err)
+	}
+	return rv, nil
+}
+
+// collect looks for the orphaned resources once from a snapshot. The private link services are collected before
+// the load balancer frontends they reference, and the frontends before the public IPs they reference.
+func (gc *orphanedResourceGC) collect(ctx context.Context) {
+	if gc.az.serviceLister == nil {
+		klog.V(4).Info("orphanedResourceGC.collect: the service lister is not initialized, skip")
+		return
+	}
+
+	snapshot, err := gc.snapshot(ctx)
+	if err != nil {
+		klog.Errorf("orphanedResourceGC.collect: %v", err)
+		return
+	}
+
+	var (
+		found    []orphanedResource
+		complete = true
+	)
+	collected := func(resources []orphanedResource, err error) {
+		if err != nil {
+			klog.Errorf("orphanedResourceGC.collect: %v", err)
+			complete = false
+		}
+		found = append(found, resources...)
+	}
+	resources, plsFrontendIDs, err := gc.collectPrivateLinkServices(ctx, snapshot)
+	collected(resources, err)
+	// the frontends referenced by the private link services are unknown if they cannot be listed
+	if plsFrontendIDs != nil {
+		collected(gc.collectLoadBalancerResources(ctx, snapshot, plsFrontendIDs))
+	}
+	collected(gc.collectPublicIPs(ctx, snapshot.owners, snapshot.pips))
+	collected(gc.collectSecurityRules(ctx, snapshot))
+
+	metrics.ResetOrphanedResources()
+	for _, r := range found {
+		metrics.SetOrphanedResource(string(r.ResourceType), r.Resource, r.Service)
+	}
+
+	// forget the resources that are deleted or owned again, unless some resources could not be listed
+	if !complete {
+		return
+	}
+	keys := sets.New[string]()
+	for _, r := range found {
+		keys.Insert(strings.ToLower(r.String()))
+	}
+	for key := range gc.firstSeen {
+		if !keys.Has(key) {
+			delete(gc.firstSeen, key)
+		}
+	}
+}
+
+// listServiceOwners returns the services that may own Azure resources.
+func (gc *orphanedResourceGC) listServiceOwners() (*serviceOwners, error) {
+	services, err := gc.az.serviceLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	owners := &serviceOwners{
+		services:                         utilsets.NewString(),
+		loadBalancerNames:                utilsets.NewString(),
+		addresses:                        sets.New[string](),
+		publicIPResourceGroups:           utilsets.NewString(gc.az.ResourceGroup),
+		privateLinkServiceResourceGroups: utilsets.NewString(gc.az.PrivateLinkServiceResourceGroup),
+	}
+	for _, service := range services {
+		for _, ingress := range service.Status.LoadBalancer.Ingress {
+			if addr, err := netip.ParseAddr(ingress.IP); err == nil {
+				owners.addresses.Insert(addr.String())
+			}
+		}
+		if !gc.az.mayOwnResources(service) {
+			continue
+		}
+		owners.services.Insert(getServiceName(service))
+		owners.loadBalancerNames.Insert(cloudprovider.DefaultLoadBalancerName(service))
+		owners.publicIPResourceGroups.Insert(gc.az.getPublicIPAddressResourceGroup(service))
+		owners.privateLinkServiceResourceGroups.Insert(gc.az.getPLSResourceGroup(service))
+		if addrs, err := loadbalancer.AdditionalPublicIPs(service); err == nil {
+			for _, addr := range addrs {
+				owners.addresses.Insert(addr.String())
+			}
+		}
+	}
+	return owners, nil
+}
+
+// mayOwnResources checks if the service is a LoadBalancer service owned by the cloud provider,
+// or if the Azure resources of the service are still being cleaned up.
+func (az *Cloud) mayOwnResources(service *v1.Service) bool {
+	if service.DeletionTimestamp != nil || servicehelpers.HasLBFinalizer(service) {
+		return true
+	}
+	return service.Spec.Type == v1.ServiceTypeLoadBalancer && az.OwnsLoadBalancerClass(service.Spec.LoadBalancerClass)
+}
+
+// observe records the orphaned resource, reports it if it is found for the first time,
+// and returns true if it should be deleted.
+func (gc *orphanedResourceGC) observe(ctx context.Context, r orphanedResource) bool {
+	key := strings.ToLower(r.String())
+	firstSeen, found := gc.firstSeen[key]
+	if !found {
+		firstSeen = gc.now()
+		gc.firstSeen[key] = firstSeen
+		klog.Warningf("orphanedResourceGC.observe: found orphaned %s of service %q", r, r.Service)
+		gc.event(ctx, r, v1.EventTypeWarning, "OrphanedResourceDetected", fmt.Sprintf("The %s is orphaned", r))
+	}
+	return gc.enforce && gc.now().Sub(firstSeen) >= gc.gracePeriod
+}
+
+// deleted forgets the deleted orphaned resource and reports it.
+func (gc *orphanedResourceGC) deleted(ctx context.Context, r orphanedResource) {
+	delete(gc.firstSeen, strings.ToLower(r.String()))
+	klog.V(2).Infof("orphanedResourceGC.deleted: deleted orphaned %s of service %q", r, r.Service)
+	gc.event(ctx, r, v1.EventTypeNormal, "OrphanedResourceDeleted", fmt.Sprintf("The orphaned %s is deleted", r))
+}
+
+// event records an event on each deleted owning service of the orphaned resource.
+func (gc *orphanedResourceGC) event(ctx context.Context, r orphanedResource, eventType, reason, message string) {
+	for _, serviceName := range parsePIPServiceTag(&r.Service) {
+		namespace, name, found := strings.Cut(serviceName, "/")
+		if !found {
+			continue
+		}
+		gc.az.Event(ctx, &v1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}, eventType, reason, message)
+	}
+}
+
+// collectPrivateLinkServices returns the orphaned private link services of the cluster, and deletes them if they
+// should be. The private endpoint connections are deleted before the private link services. It also returns the IDs
+// of the load balancer frontend IP configurations referenced by the private link services that are not deleted.
+func (gc *orphanedResourceGC) collectPrivateLinkServices(ctx context.Context, snapshot *orphanedResourceGCSnapshot) ([]orphanedResource, sets.Set[string], error) {
+	if snapshot.plsErr != nil {
+		return nil, nil, snapshot.plsErr
+	}
+	var (
+		rv             []orphanedResource
+		plsFrontendIDs = sets.New[string]()
+		owners         = snapshot.owners
+	)
+	for _, rg := range sortedKeys(snapshot.plsLists) {
+		for _, pls := range snapshot.plsLists[rg] {
+			if pls == nil {
+				continue
+			}
+			retain := func() {
+				if pls.Properties == nil {
+					return
+				}
+				for _, fip := range pls.Properties.LoadBalancerFrontendIPConfigurations {
+					if fip != nil && fip.ID != nil {
+						plsFrontendIDs.Insert(strings.ToLower(*fip.ID))
+					}
+				}
+			}
+			owner := getPrivateLinkServiceOwner(pls)
+			if !isManagedPrivateLinkSerivce(pls, gc.clusterName) || owner == "" || owners.ownsAny(getPrivateLinkServiceOwners(pls)) {
+				retain()
+				continue
+			}
+			r := orphanedResource{
+				ResourceType: PlanResourceTypePrivateLinkService,
+				Resource:     rg + "/" + ptr.Deref(pls.Name, ""),
+				Service:      owner,
+			}
+			if !gc.observe(ctx, r) {
+				rv = append(rv, r)
+				retain()
+				continue
+			}
+			if err := gc.deletePrivateLinkService(ctx, rg, pls); err != nil {
+				klog.Errorf("orphanedResourceGC.collectPrivateLinkServices: failed to delete %s: %v", r, err)
+				rv = append(rv, r)
+				retain()
+				continue
+			}
+			gc.deleted(ctx, r)
+		}
+	}
+	return rv, plsFrontendIDs, nil
+}
+
+func (gc *orphanedResourceGC) deletePrivateLinkService(ctx context.Context, rg string, pls *armnetwork.PrivateLinkService) error {
+	plsName := ptr.Deref(pls.Name, "")
+	var lbFrontendID string
+	if pls.Properties != nil {
+		for _, peConn := range pls.Properties.PrivateEndpointConnections {
+			if err := gc.az.plsRepo.DeletePEConnection(ctx, rg, plsName, ptr.Deref(peConn.Name, "")); err != nil {
+				return err
+			}
+		}
+		if len(pls.Properties.LoadBalancerFrontendIPConfigurations) > 0 {
+			lbFrontendID = ptr.Deref(pls.Properties.LoadBalancerFrontendIPConfigurations[0].ID, "")
+		}
+	}
+	return gc.az.plsRepo.Delete(ctx, rg, plsName, lbFrontendID)
+}
+
+// collectLoadBalancerResources returns the orphaned frontend IP configurations, rules and probes of the load balancers
+// of the cluster, and deletes them if they should be. The load balancers of other clusters in the resource group are
+// never scanned, because the names of their child resources are prefixed by the services of those clusters.
+// The load balancers are updated in place. A frontend IP configuration or a probe is orphaned only if it is not
+// referenced by any rule that is not orphaned, and the frontend IP configurations referenced by private link services
+// are kept until the private link services are deleted.
+func (gc *orphanedResourceGC) collectLoadBalancerResources(
+	ctx context.Context,
+	snapshot *orphanedResourceGCSnapshot,
+	plsFrontendIDs sets.Set[string],
+) ([]orphanedResource, error) {
+	var (
+		rv     []orphanedResource
+		errs   []string
+		owners = snapshot.owners
+	)
+	for i := range snapshot.lbs {
+		lb := &snapshot.lbs[i]
+		lbName := ptr.Deref(lb.Name, "")
+		if lb.LoadBalancerPropertiesFormat == nil || !snapshot.loadBalancerNames.Has(trimSuffixIgnoreCase(lbName, consts.InternalLoadBalancerNameSuffix)) {
+			continue
+		}
+		var (
+			// orphaned are the names of the orphaned child resources, and deleted are the ones to be deleted.
+			orphaned = sets.New[string]()
+			deleted  = make(map[string]orphanedResource)
+			// referenced and retained are the IDs of the frontends and probes referenced by the rules that are
+			// not orphaned and by the ones that are not deleted.
+			referenced = sets.New[string]()
+			retained   = sets.New[string]()
+		)
+		observe := func(resourceType PlanResourceType, name string, isOrphaned bool, id string) {
+			if !isOrphaned {
+				return
+			}
+			orphaned.Insert(strings.ToLower(name))
+			r := orphanedResource{ResourceType: resourceType, Resource: lbName + "/" + name}
+			if gc.observe(ctx, r) && !retained.Has(id) {
+				deleted[strings.ToLower(name)] = r
+				return
+			}
+			rv = append(rv, r)
+		}
+		reference := func(name string, ids ...*string) {
+			for _, id := range ids {
+				if ptr.Deref(id, "") == "" {
+					continue
+				}
+				if !orphaned.Has(strings.ToLower(name)) {
+					referenced.Insert(strings.ToLower(*id))
+				}
+				if _, found := deleted[strings.ToLower(name)]; !found {
+					retained.Insert(strings.ToLower(*id))
+				}
+			}
+		}
+
+		for _, rule := range ptr.Deref(lb.LoadBalancingRules, nil) {
+			name := ptr.Deref(rule.Name, "")
+			observe(PlanResourceTypeLoadBalancingRule, name, !owners.ownsLoadBalancerResource(name), "")
+			if rule.LoadBalancingRulePropertiesFormat != nil {
+				if rule.FrontendIPConfiguration != nil {
+					reference(name, rule.FrontendIPConfiguration.ID)
+				}
+				if rule.Probe != nil {
+					reference(name, rule.Probe.ID)
+				}
+			}
+		}
+		for _, rule := range ptr.Deref(lb.InboundNatRules, nil) {
+			name := ptr.Deref(rule.Name, "")
+			observe(PlanResourceTypeInboundNatRule, name, !owners.ownsLoadBalancerResource(name), "")
+			if rule.InboundNatRulePropertiesFormat != nil && rule.FrontendIPConfiguration != nil {
+				reference(name, rule.FrontendIPConfiguration.ID)
+			}
+		}
+		for _, rule := range ptr.Deref(lb.OutboundRules, nil) {
+			if rule.OutboundRulePropertiesFormat != nil {
+				for _, fip := range ptr.Deref(rule.FrontendIPConfigurations, nil) {
+					reference(ptr.Deref(rule.Name, ""), fip.ID)
+				}
+			}
+		}
+		for _, probe := range ptr.Deref(lb.Probes, nil) {
+			name := ptr.Deref(probe.Name, "")
+			id := strings.ToLower(ptr.Deref(probe.ID, ""))
+			observe(PlanResourceTypeProbe, name, !owners.ownsLoadBalancerResource(name) && !referenced.Has(id), id)
+		}
+		for _, fip := range ptr.Deref(lb.FrontendIPConfigurations, nil) {
+			name := ptr.Deref(fip.Name, "")
+			id := strings.ToLower(ptr.Deref(fip.ID, ""))
+			observe(PlanResourceTypeFrontendIPConfiguration, name, !owners.ownsLoadBalancerResource(name) && !referenced.Has(id) && !plsFrontendIDs.Has(id), id)
+		}
+
+		if len(deleted) == 0 {
+			continue
+		}
+		updated := *lb
+		updated.LoadBalancerPropertiesFormat = ptr.To(*lb.LoadBalancerPropertiesFormat)
+		keep := func(name *string) bool {
+			_, found := deleted[strings.ToLower(ptr.Deref(name, ""))]
+			return !found
+		}
+		updated.LoadBalancingRules = filterOrphanedLoadBalancerResources(lb.LoadBalancingRules, func(r network.LoadBalancingRule) bool { return keep(r.Name) })
+		updated.InboundNatRules = filterOrphanedLoadBalancerResources(lb.InboundNatRules, func(r network.InboundNatRule) bool { return keep(r.Name) })
+		updated.Probes = filterOrphanedLoadBalancerResources(lb.Probes, func(p network.Probe) bool { return keep(p.Name) })
+		updated.FrontendIPConfigurations = filterOrphanedLoadBalancerResources(lb.FrontendIPConfigurations, func(f network.FrontendIPConfiguration) bool { return keep(f.Name) })
+		if err := gc.updateLoadBalancer(ctx, updated); err != nil {
+			errs = append(errs, fmt.Sprintf("failed to update load balancer %s: %v", lbName, err))
+			for _, name := range sortedKeys(deleted) {
+				rv = append(rv, deleted[name])
+			}
+			continue
+		}
+		for _, name := range sortedKeys(deleted) {
+			gc.deleted(ctx, deleted[name])
+		}
+		*lb = updated
+	}
+	if len(errs) > 0 {
+		return rv, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return rv, nil
+}
+
+// filterOrphanedLoadBalancerResources returns the child resources to keep, or nil if none is kept.
+func filterOrphanedLoadBalancerResources[T any](resources *[]T, keep func(T) bool) *[]T {
+	if resources == nil {
+		return nil
+	}
+	rv := make([]T, 0, len(*resources))
+	for _, r := range *resources {
+		if keep(r) {
+			rv = append(rv, r)
+		}
+	}
+	return &rv
+}
+
+func (gc *orphanedResourceGC) updateLoadBalancer(ctx context.Context, lb network.LoadBalancer) error {
+	lb = cleanupSubnetInFrontendIPConfigurations(&lb)
+	lbName := ptr.Deref(lb.Name, "")
+	rerr := gc.az.LoadBalancerClient.CreateOrUpdate(ctx, gc.az.getLoadBalancerResourceGroup(), lbName, lb, ptr.Deref(lb.Etag, ""))
+	// Invalidate the cache no matter the update succeeds or not
+	_ = gc.az.lbCache.Delete(lbName)
+	if rerr != nil {
+		return rerr.Error()
+	}
+	return nil
+}
+
+// collectPublicIPs returns the orphaned public IPs of the cluster, and deletes them if they should be. The public IPs
+// are managed if they are tagged with the services and the cluster name, and are not created by the CSI drivers.
+// The public IPs still referenced by other resources are kept, and deleted once the references are removed.
+func (gc *orphanedResourceGC) collectPublicIPs(ctx context.Context, owners *serviceOwners, pips map[string][]network.PublicIPAddress) ([]orphanedResource, error) {
+	var (
+		rv   []orphanedResource
+		errs []string
+	)
+	for _, rg := range sortedKeys(pips) {
+		for _, pip := range pips[rg] {
+			serviceTag := getServiceFromPIPServiceTags(pip.Tags)
+			services := parsePIPServiceTag(&serviceTag)
+			if len(services) == 0 || owners.ownsAny(services) {
+				continue
+			}
+			if _, found := pip.Tags[consts.CreatedByTag]; found {
+				continue
+			}
+			if !strings.EqualFold(getClusterFromPIPClusterTags(pip.Tags), gc.clusterName) {
+				continue
+			}
+			pipName := ptr.Deref(pip.Name, "")
+			r := orphanedResource{
+				ResourceType: PlanResourceTypePublicIPAddress,
+				Resource:     rg + "/" + pipName,
+				Service:      serviceTag,
+			}
+			isReferenced := pip.PublicIPAddressPropertiesFormat != nil && pip.IPConfiguration != nil
+			if !gc.observe(ctx, r) || isReferenced {
+				rv = append(rv, r)
+				continue
+			}
+			rerr := gc.az.PublicIPAddressesClient.Delete(ctx, rg, pipName)
+			if rerr != nil {
+				errs = append(errs, fmt.Sprintf("failed to delete %s: %v", r, rerr.Error()))
+				rv = append(rv, r)
+				continue
+			}
+			_ = gc.az.pipCache.Delete(rg)
+			gc.deleted(ctx, r)
+		}
+	}
+	if len(errs) > 0 {
+		return rv, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return rv, nil
+}
+
+// collectSecurityRules returns the destination addresses of the managed security rules that are not the IPs of any
+// service, load balancer frontend or node, and removes them from the rules if they should be. The security rules
+// are not collected if the backend pool type is podIP, because the destinations may be the IPs of the pods, or if
+// the node cache is not synced yet, because the destinations may be the IPs of the nodes not in the cache.
+func (gc *orphanedResourceGC) collectSecurityRules(ctx context.Context, snapshot *orphanedResourceGCSnapshot) ([]orphanedResource, error) {
+	if gc.az.IsLBBackendPoolTypePodIP() {
+		return nil, nil
+	}
+	if gc.az.nodeInformerSynced == nil || !gc.az.nodeInformerSynced() {
+		return nil, fmt.Errorf("the node cache is not synced, skip the security rules")
+	}
+
+	if snapshot.sgErr != nil {
+		return nil, snapshot.sgErr
+	}
+	sg := snapshot.sg
+	if sg == nil || sg.Properties == nil {
+		return nil, nil
+	}
+	sgName := ptr.Deref(sg.Name, "")
+
+	known := gc.knownAddresses(snapshot.owners, snapshot.lbs, snapshot.pips)
+	var (
+		// orphaned are the orphaned destinations as they are in the rules, and protocols are the protocols of their rules.
+		orphaned  = sets.New[string]()
+		protocols = sets.New[armnetwork.SecurityRuleProtocol]()
+	)
+	for _, rule := range sg.Properties.SecurityRules {
+		if !isManagedSecurityRule(rule) {
+			continue
+		}
+		for _, dst := range securitygroup.ListDestinationPrefixes(rule) {
+			addr, err := netip.ParseAddr(dst)
+			if err != nil || known.Has(addr.String()) {
+				continue
+			}
+			orphaned.Insert(dst)
+			if rule.Properties.Protocol != nil {
+				protocols.Insert(*rule.Properties.Protocol)
+			}
+		}
+	}
+
+	var (
+		rv       []orphanedResource
+		toRemove []orphanedResource
+		dsts     []string
+	)
+	for _, dst := range sets.List(orphaned) {
+		r := orphanedResource{ResourceType: PlanResourceTypeSecurityRule, Resource: sgName + "/" + dst}
+		if gc.observe(ctx, r) {
+			toRemove = append(toRemove, r)
+			dsts = append(dsts, dst)
+			continue
+		}
+		rv = append(rv, r)
+	}
+	if len(dsts) == 0 {
+		return rv, nil
+	}
+
+	// the security group is read again, so that the rules of the services reconciled after the snapshot are kept
+	sg, err := gc.az.nsgRepo.GetSecurityGroup(ctx)
+	if err != nil {
+		return append(rv, toRemove...), fmt.Errorf("failed to get security group: %w", err)
+	}
+	updated, err := removeSecurityRuleDestinations(sg, dsts, sets.List(protocols))
+	if err == nil && updated != nil {
+		err = gc.az.nsgRepo.CreateOrUpdateSecurityGroup(ctx, updated)
+	}
+	if err != nil {
+		return append(rv, toRemove...), fmt.Errorf("failed to remove orphaned destinations from security group %s: %w", sgName, err)
+	}
+	for _, r := range toRemove {
+		gc.deleted(ctx, r)
+	}
+	return rv, nil
+}
+
+// knownAddresses returns the ingress IPs and the additional public IPs of the services, the frontend IPs
+// of the load balancers and the IPs of the nodes.
+func (gc *orphanedResourceGC) knownAddresses(owners *serviceOwners, lbs []network.LoadBalancer, pips map[string][]network.PublicIPAddress) sets.Set[string] {
+	known := owners.addresses.Clone()
+	insert := func(ip string) {
+		if addr, err := netip.ParseAddr(ip); err == nil {
+			known.Insert(addr.String())
+		}
+	}
+
+	pipAddresses := make(map[string]string)
+	for _, rgPIPs := range pips {
+		for _, pip := range rgPIPs {
+			if pip.PublicIPAddressPropertiesFormat != nil {
+				pipAddresses[strings.ToLower(ptr.Deref(pip.ID, ""))] = ptr.Deref(pip.IPAddress, "")
+			}
+		}
+	}
+	for _, lb := range lbs {
+		if lb.LoadBalancerPropertiesFormat == nil {
+			continue
+		}
+		for _, fip := range ptr.Deref(lb.FrontendIPConfigurations, nil) {
+			if fip.FrontendIPConfigurationPropertiesFormat == nil {
+				continue
+			}
+			insert(ptr.Deref(fip.PrivateIPAddress, ""))
+			if fip.PublicIPAddress != nil {
+				insert(pipAddresses[strings.ToLower(ptr.Deref(fip.PublicIPAddress.ID, ""))])
+			}
+		}
+	}
+
+	gc.az.nodeCachesLock.RLock()
+	defer gc.az.nodeCachesLock.RUnlock()
+	for _, ips := range gc.az.nodePrivateIPs {
+		for _, ip := range ips.UnsortedList() {
+			insert(ip)
+		}
+	}
+	return known
+}
+
+// isManagedSecurityRule checks if the security rule is created by the cloud provider.
+func isManagedSecurityRule(rule *armnetwork.SecurityRule) bool {
+	if rule == nil || rule.Properties == nil || rule.Properties.Priority == nil {
+		return false
+	}
+	priority := *rule.Properties.Priority
+	return strings.HasPrefix(ptr.Deref(rule.Name, ""), securitygroup.SecurityRuleNamePrefix+securitygroup.SecurityRuleNameSep) &&
+		consts.LoadBalancerMinimumPriority <= priority && priority <= consts.LoadBalancerMaximumPriority
+}
+
+// removeSecurityRuleDestinations removes the destinations from the managed security rules of the given protocols,
+// and returns the updated security group, or nil if it is not changed.
+func removeSecurityRuleDestinations(sg *armnetwork.SecurityGroup, dsts []string, protocols []armnetwork.SecurityRuleProtocol) (*armnetwork.SecurityGroup, error) {
+	helper, err := securitygroup.NewSecurityGroupHelper(klog.Background(), sg)
+	if err != nil {
+		return nil, err
+	}
+	for _, protocol := range protocols {
+		if err := helper.RemoveDestinationFromRules(protocol, dsts, nil); err != nil {
+			return nil, err
+		}
+	}
+	helper.Compact()
+	rv, updated, err := helper.SecurityGroup()
+	if err != nil || !updated {
+		return nil, err
+	}
+	return rv, nil
+}
+
+// sortedList returns the items of the set in order.
+func sortedList(s *utilsets.IgnoreCaseSet) []string {
+	rv := s.UnsortedList()
+	sort.Strings(rv)
+	return rv
+}
+
+// sortedKeys returns the keys of the map in order.
+func sortedKeys[T any](m map[string]T) []string {
+	rv := make([]string, 0, len(m))
+	for key := range m {
+		rv = append(rv, key)
+	}
+	sort.Strings(rv)
+	return rv
+}
@@ -0,0 +1,366 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v6"
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2022-07-01/network"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	cloudprovider "k8s.io/cloud-provider"
+	"k8s.io/utils/ptr"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/loadbalancerclient/mockloadbalancerclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/azureclients/publicipclient/mockpublicipclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+	"sigs.k8s.io/cloud-provider-azure/pkg/provider/privatelinkservice"
+	"sigs.k8s.io/cloud-provider-azure/pkg/provider/securitygroup"
+	"sigs.k8s.io/cloud-provider-azure/pkg/retry"
+)
+
+func TestMayOwnResources(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	az := GetTestCloud(ctrl)
+	svc := getTestService("svc", v1.ProtocolTCP, nil, false, 80)
+	assert.True(t, az.mayOwnResources(&svc))
+
+	svc.Spec.LoadBalancerClass = ptr.To("other")
+	assert.False(t, az.mayOwnResources(&svc))
+
+	svc.Finalizers = []string{"service.kubernetes.io/load-balancer-cleanup"}
+	assert.True(t, az.mayOwnResources(&svc))
+
+	svc = getTestService("svc", v1.ProtocolTCP, nil, false, 80)
+	svc.Spec.Type = v1.ServiceTypeClusterIP
+	assert.False(t, az.mayOwnResources(&svc))
+
+	svc.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	assert.True(t, az.mayOwnResources(&svc))
+}
+
+func TestOrphanedResourceGCObserve(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	az := GetTestCloud(ctrl)
+	recorder := record.NewFakeRecorder(10)
+	az.eventRecorder = recorder
+	now := time.Now()
+	gc := newOrphanedResourceGC(az, "kubernetes", time.Minute, false, time.Hour)
+	gc.now = func() time.Time { return now }
+
+	r := orphanedResource{ResourceType: PlanResourceTypePublicIPAddress, Resource: "rg/pip", Service: "default/svc1,default/svc2"}
+	assert.False(t, gc.observe(context.Background(), r))
+	assert.Len(t, recorder.Events, 2)
+
+	// The same resource is not reported again, and is never deleted in the report mode.
+	now = now.Add(2 * time.Hour)
+	assert.False(t, gc.observe(context.Background(), r))
+	assert.Len(t, recorder.Events, 2)
+
+	gc.enforce = true
+	assert.True(t, gc.observe(context.Background(), r))
+
+	gc.deleted(context.Background(), r)
+	assert.Len(t, recorder.Events, 4)
+	assert.Empty(t, gc.firstSeen)
+
+	// The grace period starts again if the resource is found again.
+	assert.False(t, gc.observe(context.Background(), r))
+}
+
+func TestOrphanedResourceGCCollect(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	az := GetTestCloud(ctrl)
+	recorder := record.NewFakeRecorder(20)
+	az.eventRecorder = recorder
+
+	svc := getTestService("svc", v1.ProtocolTCP, nil, false, 80)
+	svc.UID = "1c5a3e7c-0000-4000-8000-00000000abcd"
+	svc.Status.LoadBalancer.Ingress = []v1.LoadBalancerIngress{{IP: "1.2.3.4"}}
+	kubeClient := fake.NewSimpleClientset(&svc)
+	informerFactory := informers.NewSharedInformerFactory(kubeClient, 0)
+	az.serviceLister = informerFactory.Core().V1().Services().Lister()
+	informerFactory.Start(wait.NeverStop)
+	informerFactory.WaitForCacheSync(wait.NeverStop)
+
+	var (
+		living = cloudprovider.DefaultLoadBalancerName(&svc)
+		gone   = "a0000000000000000000000000000fff"
+	)
+	lb := network.LoadBalancer{
+		Name: ptr.To("kubernetes"),
+		LoadBalancerPropertiesFormat: &network.LoadBalancerPropertiesFormat{
+			FrontendIPConfigurations: &[]network.FrontendIPConfiguration{
+				{
+					Name: ptr.To(living),
+					ID:   ptr.To("fip-living"),
+					FrontendIPConfigurationPropertiesFormat: &network.FrontendIPConfigurationPropertiesFormat{
+						PublicIPAddress: &network.PublicIPAddress{ID: ptr.To("pip-living")},
+					},
+				},
+				{
+					// The frontend is shared with the living service.
+					Name: ptr.To(gone + "-shared"),
+					ID:   ptr.To("fip-shared"),
+					FrontendIPConfigurationPropertiesFormat: &network.FrontendIPConfigurationPropertiesFormat{
+						PrivateIPAddress: ptr.To("10.0.0.4"),
+					},
+				},
+				{
+					Name: ptr.To(gone),
+					ID:   ptr.To("fip-gone"),
+					FrontendIPConfigurationPropertiesFormat: &network.FrontendIPConfigurationPropertiesFormat{
+						PrivateIPAddress: ptr.To("10.0.0.5"),
+					},
+				},
+				{
+					// The frontend is referenced by a private link service.
+					Name: ptr.To(gone + "-pls"),
+					ID:   ptr.To("fip-pls"),
+					FrontendIPConfigurationPropertiesFormat: &network.FrontendIPConfigurationPropertiesFormat{
+						PrivateIPAddress: ptr.To("10.0.0.6"),
+					},
+				},
+			},
+			LoadBalancingRules: &[]network.LoadBalancingRule{
+				{
+					Name: ptr.To(living + "-TCP-80"),
+					LoadBalancingRulePropertiesFormat: &network.LoadBalancingRulePropertiesFormat{
+						FrontendIPConfiguration: &network.SubResource{ID: ptr.To("fip-living")},
+						Probe:                   &network.SubResource{ID: ptr.To("probe-living")},
+					},
+				},
+				{
+					Name: ptr.To(living + "-TCP-81"),
+					LoadBalancingRulePropertiesFormat: &network.LoadBalancingRulePropertiesFormat{
+						FrontendIPConfiguration: &network.SubResource{ID: ptr.To("fip-shared")},
+					},
+				},
+				{
+					Name: ptr.To(gone + "-TCP-80"),
+					LoadBalancingRulePropertiesFormat: &network.LoadBalancingRulePropertiesFormat{
+						FrontendIPConfiguration: &network.SubResource{ID: ptr.To("fip-gone")},
+						Probe:                   &network.SubResource{ID: ptr.To("probe-gone")},
+					},
+				},
+			},
+			Probes: &[]network.Probe{
+				{Name: ptr.To(living + "-TCP-80"), ID: ptr.To("probe-living")},
+				{Name: ptr.To(gone + "-TCP-80"), ID: ptr.To("probe-gone")},
+				{Name: ptr.To(consts.SharedProbeName), ID: ptr.To("probe-shared")},
+			},
+		},
+	}
+	clusterTags := func(service string) map[string]*string {
+		return map[string]*string{
+			consts.ServiceTagKey:  ptr.To(service),
+			consts.ClusterNameKey: ptr.To("kubernetes"),
+		}
+	}
+	pips := []network.PublicIPAddress{
+		{
+			Name: ptr.To("pip-living"),
+			ID:   ptr.To("pip-living"),
+			Tags: clusterTags("default/svc"),
+			PublicIPAddressPropertiesFormat: &network.PublicIPAddressPropertiesFormat{
+				IPAddress:       ptr.To("1.2.3.4"),
+				IPConfiguration: &network.IPConfiguration{ID: ptr.To("fip-living")},
+			},
+		},
+		{
+			Name:                            ptr.To("pip-gone"),
+			Tags:                            clusterTags("default/gone"),
+			PublicIPAddressPropertiesFormat: &network.PublicIPAddressPropertiesFormat{IPAddress: ptr.To("5.6.7.8")},
+		},
+		{
+			Name: ptr.To("pip-other-cluster"),
+			Tags: map[string]*string{
+				consts.ServiceTagKey:  ptr.To("default/gone"),
+				consts.ClusterNameKey: ptr.To("other"),
+			},
+		},
+		{
+			Name: ptr.To("pip-csi"),
+			Tags: map[string]*string{
+				consts.ServiceTagKey:  ptr.To("default/gone"),
+				consts.ClusterNameKey: ptr.To("kubernetes"),
+				consts.CreatedByTag:   ptr.To("azure"),
+			},
+		},
+	}
+	pls := &armnetwork.PrivateLinkService{
+		Name: ptr.To("pls-gone"),
+		Tags: map[string]*string{
+			consts.ClusterNameTagKey:  ptr.To("kubernetes"),
+			consts.OwnerServiceTagKey: ptr.To("default/gone"),
+		},
+		Properties: &armnetwork.PrivateLinkServiceProperties{
+			LoadBalancerFrontendIPConfigurations: []*armnetwork.FrontendIPConfiguration{{ID: ptr.To("fip-pls")}},
+		},
+	}
+	newSecurityGroup := func(dsts ...string) *armnetwork.SecurityGroup {
+		return &armnetwork.SecurityGroup{
+			Name: ptr.To("nsg"),
+			Properties: &armnetwork.SecurityGroupPropertiesFormat{
+				SecurityRules: []*armnetwork.SecurityRule{
+					{
+						Name: ptr.To("k8s-azure-lb_allow_IPv4_rule"),
+						Properties: &armnetwork.SecurityRulePropertiesFormat{
+							Protocol:                   ptr.To(armnetwork.SecurityRuleProtocolTCP),
+							Access:                     ptr.To(armnetwork.SecurityRuleAccessAllow),
+							Direction:                  ptr.To(armnetwork.SecurityRuleDirectionInbound),
+							SourceAddressPrefix:        ptr.To("Internet"),
+							SourcePortRange:            ptr.To("*"),
+							DestinationAddressPrefixes: to.SliceOfPtrs(dsts...),
+							DestinationPortRanges:      to.SliceOfPtrs("80"),
+							Priority:                   ptr.To(int32(consts.LoadBalancerMinimumPriority)),
+						},
+					},
+					{
+						// The rule is not created by the cloud provider.
+						Name: ptr.To("user-rule"),
+						Properties: &armnetwork.SecurityRulePropertiesFormat{
+							Protocol:                   ptr.To(armnetwork.SecurityRuleProtocolTCP),
+							DestinationAddressPrefixes: to.SliceOfPtrs("9.9.9.9"),
+							Priority:                   ptr.To(int32(100)),
+						},
+					},
+				},
+			},
+		}
+	}
+
+	// The load balancer of another cluster in the resource group is never scanned.
+	otherClusterLB := network.LoadBalancer{
+		Name: ptr.To("other-internal"),
+		LoadBalancerPropertiesFormat: &network.LoadBalancerPropertiesFormat{
+			FrontendIPConfigurations: &[]network.FrontendIPConfiguration{{Name: ptr.To(gone), ID: ptr.To("fip-other-cluster")}},
+			Probes:                   &[]network.Probe{{Name: ptr.To(gone + "-TCP-80"), ID: ptr.To("probe-other-cluster")}},
+		},
+	}
+
+	lbClient := az.LoadBalancerClient.(*mockloadbalancerclient.MockInterface)
+	lbClient.EXPECT().List(gomock.Any(), "rg").DoAndReturn(func(_ context.Context, _ string) ([]network.LoadBalancer, *retry.Error) {
+		return []network.LoadBalancer{lb, otherClusterLB}, nil
+	}).Times(2)
+	pipClient := az.PublicIPAddressesClient.(*mockpublicipclient.MockInterface)
+	pipClient.EXPECT().List(gomock.Any(), "rg").Return(pips, nil).MaxTimes(2)
+	plsRepo := az.plsRepo.(*privatelinkservice.MockRepository)
+	plsRepo.EXPECT().List(gomock.Any(), "rg").Return([]*armnetwork.PrivateLinkService{pls}, nil).Times(2)
+	sgRepo := securitygroup.NewMockRepository(ctrl)
+	az.nsgRepo = sgRepo
+	sgRepo.EXPECT().GetSecurityGroup(gomock.Any()).DoAndReturn(func(_ context.Context) (*armnetwork.SecurityGroup, error) {
+		return newSecurityGroup("1.2.3.4", "5.6.7.8", "10.0.0.5"), nil
+	}).Times(3)
+
+	// The orphaned resources are only reported in the report mode.
+	gc := newOrphanedResourceGC(az, "kubernetes", time.Minute, false, 0)
+	gc.collect(context.Background())
+	assert.Equal(t, sets.New(
+		"privatelinkservice rg/pls-gone",
+		"loadbalancingrule kubernetes/"+gone+"-tcp-80",
+		"probe kubernetes/"+gone+"-tcp-80",
+		"frontendipconfiguration kubernetes/"+gone,
+		"publicipaddress rg/pip-gone",
+		"securityrule nsg/5.6.7.8",
+	), sets.KeySet(gc.firstSeen))
+	assert.Len(t, recorder.Events, 2)
+
+	// The orphaned resources are deleted in the enforce mode, the private link service before its frontend,
+	// and the frontend before the security rule destination of its IP.
+	gc.enforce = true
+	plsRepo.EXPECT().Delete(gomock.Any(), "rg", "pls-gone", "fip-pls").Return(nil)
+	lbClient.EXPECT().CreateOrUpdate(gomock.Any(), "rg", "kubernetes", gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, _, _ string, updated network.LoadBalancer, _ string) *retry.Error {
+			// the services are not blocked while the orphaned resources are deleted
+			assert.True(t, az.serviceReconcileLock.TryLock())
+			az.serviceReconcileLock.Unlock()
+			var names []string
+			for _, fip := range *updated.FrontendIPConfigurations {
+				names = append(names, *fip.Name)
+			}
+			assert.Equal(t, []string{living, gone + "-shared"}, names)
+			assert.Len(t, *updated.LoadBalancingRules, 2)
+			assert.Len(t, *updated.Probes, 2)
+			return nil
+		})
+	pipClient.EXPECT().Delete(gomock.Any(), "rg", "pip-gone").Return(nil)
+	sgRepo.EXPECT().CreateOrUpdateSecurityGroup(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, sg *armnetwork.SecurityGroup) error {
+		for _, rule := range sg.Properties.SecurityRules {
+			if *rule.Name == "user-rule" {
+				assert.Equal(t, []string{"9.9.9.9"}, securitygroup.ListDestinationPrefixes(rule))
+				continue
+			}
+			assert.Equal(t, []string{"1.2.3.4"}, securitygroup.ListDestinationPrefixes(rule))
+		}
+		return nil
+	})
+	gc.collect(context.Background())
+	assert.Empty(t, gc.firstSeen)
+	assert.Len(t, recorder.Events, 4)
+}
+
+func TestOrphanedResourceGCSkipSecurityRulesWithoutNodeCache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	az := GetTestCloud(ctrl)
+	az.nodeInformerSynced = func() bool { return false }
+	gc := newOrphanedResourceGC(az, "kubernetes", time.Minute, true, 0)
+
+	// The destinations of the nodes not in the cache yet would be orphaned, so the collection is incomplete.
+	snapshot := &orphanedResourceGCSnapshot{
+		owners: &serviceOwners{addresses: sets.New[string]()},
+		sg: &armnetwork.SecurityGroup{
+			Name: ptr.To("nsg"),
+			Properties: &armnetwork.SecurityGroupPropertiesFormat{
+				SecurityRules: []*armnetwork.SecurityRule{
+					{
+						Name: ptr.To("k8s-azure-lb_allow_IPv4_rule"),
+						Properties: &armnetwork.SecurityRulePropertiesFormat{
+							Protocol:                   ptr.To(armnetwork.SecurityRuleProtocolTCP),
+							DestinationAddressPrefixes: to.SliceOfPtrs("10.0.0.4"),
+							Priority:                   ptr.To(int32(consts.LoadBalancerMinimumPriority)),
+						},
+					},
+				},
+			},
+		},
+	}
+	found, err := gc.collectSecurityRules(context.Background(), snapshot)
+	assert.Error(t, err)
+	assert.Empty(t, found)
+	assert.Empty(t, gc.firstSeen)
+}
@@ -30,6 +30,8 @@ import (
 	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2022-07-01/network"
 
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/ptr"
 
@@ -48,6 +50,13 @@ func (az *Cloud) reconcilePrivateLinkService(
 	fipConfig *network.FrontendIPConfiguration,
 	wantPLS bool,
 ) error {
+	if p := getServicePlanner(ctx); p != nil {
+		if wantPLS && serviceRequiresPLS(service) {
+			p.addNote("the private link service of the service is not planned")
+		}
+		return nil
+	}
+
 	isinternal := requiresInternalLoadBalancer(service)
 	_, _, fipIPVersion := az.serviceOwnsFrontendIP(ctx, *fipConfig, service)
 	serviceName := getServiceName(service)
@@ -98,6 +107,10 @@ func (az *Cloud) reconcilePrivateLinkService(
 			return err
 		}
 
+		var (
+			plsService    = service
+			ownersChanged bool
+		)
 		exists := !strings.EqualFold(ptr.Deref(existingPLS.ID, ""), consts.PrivateLinkServiceNotExistID)
 		if exists {
 			klog.V(4).Infof("reconcilePrivateLinkService for service(%s): found existing private link service attached(%s)", serviceName, ptr.Deref(existingPLS.Name, ""))
@@ -109,25 +122,32 @@ func (az *Cloud) reconcilePrivateLinkService(
 					ptr.Deref(existingPLS.ID, ""),
 				)
 			}
-			// If there is an existing private link service, only owner service can update its properties
-			ownerService := getPrivateLinkServiceOwner(existingPLS)
-			if !strings.EqualFold(ownerService, serviceName) {
-				if serviceHasAdditionalConfigs(service) {
-					return fmt.Errorf(
-						"reconcilePrivateLinkService for service(%s) failed: LB frontend(%s) already has existing private link service(%s) owned by service(%s)",
-						serviceName,
-						ptr.Deref(fipConfigID, ""),
-						ptr.Deref(existingPLS.Name, ""),
-						ownerService,
-					)
-				}
+			// The private link service is shared by the services using the same LB frontend. Its properties are
+			// merged from the configurations of all the owner services, which must not conflict with each other.
+			ownerServices, owners := az.getPrivateLinkServiceOwnerServices(service, getPrivateLinkServiceOwners(existingPLS))
+			configs, err := mergePLSConfigs(ownerServices)
+			if err != nil {
+				return fmt.Errorf(
+					"reconcilePrivateLinkService for service(%s) failed: LB frontend(%s) already has existing private link service(%s) owned by services(%s): %w",
+					serviceName,
+					ptr.Deref(fipConfigID, ""),
+					ptr.Deref(existingPLS.Name, ""),
+					strings.Join(owners, ","),
+					err,
+				)
+			}
+			if len(owners) > 1 {
 				klog.V(2).Infof(
-					"reconcilePrivateLinkService for service(%s): automatically share private link service(%s) owned by service(%s)",
+					"reconcilePrivateLinkService for service(%s): share private link service(%s) with services(%s)",
 					serviceName,
 					ptr.Deref(existingPLS.Name, ""),
-					ownerService,
+					strings.Join(owners, ","),
 				)
-				return nil
+			}
+			plsService = withPLSConfigs(service, configs)
+			if ownersChanged, err = setPrivateLinkServiceOwners(existingPLS, owners); err != nil {
+				return fmt.Errorf("reconcilePrivateLinkService for service(%s) failed to share private link service(%s): %w",
+					serviceName, ptr.Deref(existingPLS.Name, ""), err)
 			}
 		} else {
 			existingPLS.ID = nil
@@ -141,15 +161,18 @@ func (az *Cloud) reconcilePrivateLinkService(
 			}
 		}
 
-		plsName, err := az.getPrivateLinkServiceName(existingPLS, service, fipConfig)
+		plsName, err := az.getPrivateLinkServiceName(existingPLS, plsService, fipConfig)
 		if err != nil {
 			return err
 		}
 
-		dirtyPLS, err := az.getExpectedPrivateLinkService(ctx, existingPLS, &plsName, &clusterName, service, fipConfig)
+		dirtyPLS, err := az.getExpectedPrivateLinkService(ctx, existingPLS, &plsName, &clusterName, plsService, fipConfig)
 		if err != nil {
 			return err
 		}
+		if ownersChanged {
+			dirtyPLS = true
+		}
 
 		if dirtyPLS {
 			klog.V(2).Infof("reconcilePrivateLinkService for service(%s): pls(%s) - updating", serviceName, plsName)
@@ -165,7 +188,14 @@ func (az *Cloud) reconcilePrivateLinkService(
 				return err
 			}
 		}
-	} else if !wantPLS {
+	} else if wantPLS {
+		// The service no longer requires a private link service, e.g. the annotation is removed,
+		// so it must not stay in the owners of the one shared with other services.
+		if err := az.unbindServiceFromPrivateLinkService(ctx, clusterName, service, fipConfig); err != nil {
+			klog.Errorf("reconcilePrivateLinkService for service(%s): failed to unbind from the private link service of frontEnd(%s): %v", serviceName, ptr.Deref(fipConfigID, ""), err)
+			return err
+		}
+	} else {
 		existingPLS, err := az.plsRepo.Get(ctx, az.getPLSResourceGroup(service), *fipConfigID, azcache.CacheReadTypeDefault)
 		if err != nil {
 			klog.Errorf("reconcilePrivateLinkService for service(%s): getPrivateLinkService(%s) failed: %v", serviceName, ptr.Deref(fipConfigID, ""), err)
@@ -749,21 +779,189 @@ func getPrivateLinkServiceOwner(existingPLS *armnetwork.PrivateLinkService) stri
 	return ""
 }
 
-// Return true if service has private link service config annotations
-func serviceHasAdditionalConfigs(service *v1.Service) bool {
-	tagKeyList := []string{
-		consts.ServiceAnnotationPLSName,
-		consts.ServiceAnnotationPLSIpConfigurationSubnet,
-		consts.ServiceAnnotationPLSIpConfigurationIPAddressCount,
-		consts.ServiceAnnotationPLSIpConfigurationIPAddress,
-		consts.ServiceAnnotationPLSFqdns,
-		consts.ServiceAnnotationPLSProxyProtocol,
-		consts.ServiceAnnotationPLSVisibility,
-		consts.ServiceAnnotationPLSAutoApproval}
-	for _, k := range tagKeyList {
-		if _, found := service.Annotations[k]; found {
-			return true
+// getPrivateLinkServiceOwners returns the owner services of an existing private link service from its tags.
+// A private link service shared by the services using the same LB frontend has the comma separated owners in the tag.
+func getPrivateLinkServiceOwners(existingPLS *armnetwork.PrivateLinkService) []string {
+	owner := getPrivateLinkServiceOwner(existingPLS)
+	return parsePIPServiceTag(&owner)
+}
+
+// setPrivateLinkServiceOwners sets the owner services of the private link service to its tags,
+// and returns true if the owners are changed. The tags are copied to avoid changing the pls cache.
+// An error is returned if the owners do not fit in the tag value.
+func setPrivateLinkServiceOwners(existingPLS *armnetwork.PrivateLinkService, owners []string) (bool, error) {
+	value := strings.Join(owners, ",")
+	if v, ok := existingPLS.Tags[consts.OwnerServiceTagKey]; ok && v != nil && *v == value {
+		return false, nil
+	}
+	if len(value) > consts.MaxTagValueLength {
+		return false, fmt.Errorf("the owner services(%s) of private link service(%s) exceed the maximum length %d of the tag %s",
+			value, ptr.Deref(existingPLS.Name, ""), consts.MaxTagValueLength, consts.OwnerServiceTagKey)
+	}
+	tags := make(map[string]*string, len(existingPLS.Tags)+1)
+	for k, v := range existingPLS.Tags {
+		tags[k] = v
+	}
+	tags[consts.OwnerServiceTagKey] = &value
+	existingPLS.Tags = tags
+	return true, nil
+}
+
+// getPrivateLinkServiceOwnerServices returns the owner services of the private link service that still require it,
+// and their names in the order of the owners with the given service at the end if it is a new owner. The owners that
+// are deleted or no longer require the private link service are dropped, and the owners that cannot be looked up
+// are kept without their configurations.
+func (az *Cloud) getPrivateLinkServiceOwnerServices(service *v1.Service, owners []string) ([]*v1.Service, []string) {
+	var (
+		serviceName   = getServiceName(service)
+		ownerServices = []*v1.Service{service}
+		ownerNames    []string
+		found         bool
+	)
+	for _, owner := range owners {
+		if strings.EqualFold(owner, serviceName) {
+			if !found {
+				ownerNames = append(ownerNames, serviceName)
+				found = true
+			}
+			continue
+		}
+		if az.serviceLister == nil {
+			ownerNames = append(ownerNames, owner)
+			continue
+		}
+		namespace, name, err := cache.SplitMetaNamespaceKey(owner)
+		if err != nil {
+			klog.Warningf("getPrivateLinkServiceOwnerServices for service(%s): dropping invalid owner %q: %v", serviceName, owner, err)
+			continue
+		}
+		ownerService, err := az.serviceLister.Services(namespace).Get(name)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				klog.V(2).Infof("getPrivateLinkServiceOwnerServices for service(%s): dropping deleted owner service(%s)", serviceName, owner)
+				continue
+			}
+			klog.Warningf("getPrivateLinkServiceOwnerServices for service(%s): failed to get owner service(%s): %v", serviceName, owner, err)
+			ownerNames = append(ownerNames, owner)
+			continue
+		}
+		if ownerService.DeletionTimestamp != nil || !serviceRequiresPLS(ownerService) {
+			klog.V(2).Infof("getPrivateLinkServiceOwnerServices for service(%s): dropping owner service(%s) which no longer requires private link service", serviceName, owner)
+			continue
+		}
+		ownerServices = append(ownerServices, ownerService)
+		ownerNames = append(ownerNames, owner)
+	}
+	if !found {
+		ownerNames = append(ownerNames, serviceName)
+	}
+	return ownerServices, ownerNames
+}
+
+// plsConfigAnnotations are the service annotations configuring the private link service.
+var plsConfigAnnotations = []string{
+	consts.ServiceAnnotationPLSName,
+	consts.ServiceAnnotationPLSIpConfigurationSubnet,
+	consts.ServiceAnnotationPLSIpConfigurationIPAddressCount,
+	consts.ServiceAnnotationPLSIpConfigurationIPAddress,
+	consts.ServiceAnnotationPLSFqdns,
+	consts.ServiceAnnotationPLSProxyProtocol,
+	consts.ServiceAnnotationPLSVisibility,
+	consts.ServiceAnnotationPLSAutoApproval,
+}
+
+// mergePLSConfigs merges the private link service configurations of the services sharing the private link service.
+// An annotation set by several services must have the same value on all of them, ignoring the extra whitespaces.
+func mergePLSConfigs(services []*v1.Service) (map[string]string, error) {
+	configs := make(map[string]string)
+	configuredBy := make(map[string]string)
+	for _, service := range services {
+		for _, key := range plsConfigAnnotations {
+			value, found := service.Annotations[key]
+			if !found {
+				continue
+			}
+			value = strings.Join(strings.Fields(value), " ")
+			if existing, ok := configs[key]; ok {
+				if !strings.EqualFold(existing, value) {
+					return nil, fmt.Errorf("annotation %s of service(%s) %q conflicts with %q of service(%s)",
+						key, getServiceName(service), value, existing, configuredBy[key])
+				}
+				continue
+			}
+			configs[key] = value
+			configuredBy[key] = getServiceName(service)
+		}
+	}
+	return configs, nil
+}
+
+// withPLSConfigs returns a copy of the service with the private link service configurations replaced by the given ones.
+func withPLSConfigs(service *v1.Service, configs map[string]string) *v1.Service {
+	merged := service.DeepCopy()
+	if merged.Annotations == nil {
+		merged.Annotations = make(map[string]string)
+	}
+	for _, key := range plsConfigAnnotations {
+		delete(merged.Annotations, key)
+	}
+	for key, value := range configs {
+		merged.Annotations[key] = value
+	}
+	return merged
+}
+
+// unbindServiceFromPrivateLinkServices removes the service from the owners of the private link services shared with
+// other services on the frontend IP configurations of the load balancer, which are kept for the other owners.
+// The private link services owned by the service only are deleted with the frontend IP configurations.
+func (az *Cloud) unbindServiceFromPrivateLinkServices(ctx context.Context, clusterName string, service *v1.Service, lb *network.LoadBalancer) error {
+	if lb == nil || lb.LoadBalancerPropertiesFormat == nil || lb.FrontendIPConfigurations == nil {
+		return nil
+	}
+	for i := range *lb.FrontendIPConfigurations {
+		fipConfig := &(*lb.FrontendIPConfigurations)[i]
+		if fipConfig.ID == nil {
+			continue
 		}
+		if owns, _, _ := az.serviceOwnsFrontendIP(ctx, *fipConfig, service); !owns {
+			continue
+		}
+		if err := az.unbindServiceFromPrivateLinkService(ctx, clusterName, service, fipConfig); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unbindServiceFromPrivateLinkService removes the service from the owners of the private link service on the
+// frontend IP configuration if other services still own it. The private link service owned by the service only
+// is left as it is.
+func (az *Cloud) unbindServiceFromPrivateLinkService(ctx context.Context, clusterName string, service *v1.Service, fipConfig *network.FrontendIPConfiguration) error {
+	serviceName := getServiceName(service)
+	resourceGroup := az.getPLSResourceGroup(service)
+	existingPLS, err := az.plsRepo.Get(ctx, resourceGroup, *fipConfig.ID, azcache.CacheReadTypeDefault)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(ptr.Deref(existingPLS.ID, ""), consts.PrivateLinkServiceNotExistID) || !isManagedPrivateLinkSerivce(existingPLS, clusterName) {
+		return nil
+	}
+	owners := getPrivateLinkServiceOwners(existingPLS)
+	remainingOwners := make([]string, 0, len(owners))
+	for _, owner := range owners {
+		if !strings.EqualFold(owner, serviceName) {
+			remainingOwners = append(remainingOwners, owner)
+		}
+	}
+	if len(remainingOwners) == len(owners) || len(remainingOwners) == 0 {
+		return nil
+	}
+	klog.V(2).Infof("unbindServiceFromPrivateLinkService for service(%s): pls(%s) - keeping it for services(%s)",
+		serviceName, ptr.Deref(existingPLS.Name, ""), strings.Join(remainingOwners, ","))
+	if _, err := setPrivateLinkServiceOwners(existingPLS, remainingOwners); err != nil {
+		return err
 	}
-	return false
+	existingPLS.Etag = ptr.To("")
+	_, err = az.plsRepo.CreateOrUpdate(ctx, resourceGroup, *existingPLS)
+	return err
 }
@@ -0,0 +1,313 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v6"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+	utilsets "sigs.k8s.io/cloud-provider-azure/pkg/util/sets"
+)
+
+// plsConnectionApprover periodically approves or rejects the pending private endpoint connections to the private link
+// services of the services by the approval policies, and reports the states of the connections by events and the
+// connection states annotation of the services. The connections that are not pending are never changed.
+type plsConnectionApprover struct {
+	az          *Cloud
+	clusterName string
+	interval    time.Duration
+	// approvedSubscriptions are the subscriptions whose pending connections are approved for all services.
+	approvedSubscriptions *utilsets.IgnoreCaseSet
+	// rejectUnapproved rejects the pending connections that are not approved, unless the service overrides it.
+	rejectUnapproved bool
+	// states are the states of the connections in the last approval, keyed by the connection IDs.
+	states map[string]string
+}
+
+// StartPrivateLinkServiceConnectionApproval starts the approval of the private endpoint connections if it is enabled,
+// and stops if the context exits.
+func (az *Cloud) StartPrivateLinkServiceConnectionApproval(ctx context.Context, clusterName string) {
+	if az.PrivateLinkServiceConnectionApprovalIntervalInSeconds <= 0 {
+		return
+	}
+	approver := newPLSConnectionApprover(
+		az,
+		clusterName,
+		time.Duration(az.PrivateLinkServiceConnectionApprovalIntervalInSeconds)*time.Second,
+		az.PrivateLinkServiceConnectionApprovedSubscriptions,
+		az.RejectUnapprovedPrivateLinkServiceConnections,
+	)
+	go approver.run(ctx)
+}
+
+func newPLSConnectionApprover(az *Cloud, clusterName string, interval time.Duration, approvedSubscriptions []string, rejectUnapproved bool) *plsConnectionApprover {
+	return &plsConnectionApprover{
+		az:                    az,
+		clusterName:           clusterName,
+		interval:              interval,
+		approvedSubscriptions: utilsets.NewString(approvedSubscriptions...),
+		rejectUnapproved:      rejectUnapproved,
+		states:                make(map[string]string),
+	}
+}
+
+// run starts the plsConnectionApprover, and stops if the context exits.
+func (a *plsConnectionApprover) run(ctx context.Context) {
+	klog.V(2).Infof("plsConnectionApprover.run: started, reject unapproved: %t", a.rejectUnapproved)
+	err := wait.PollUntilContextCancel(ctx, a.interval, false, func(ctx context.Context) (bool, error) {
+		a.approve(ctx)
+		return false, nil
+	})
+	klog.Infof("plsConnectionApprover.run: stopped due to %s", err.Error())
+}
+
+// approve approves or rejects the pending connections to the private link services of all services once.
+func (a *plsConnectionApprover) approve(ctx context.Context) {
+	if a.az.serviceLister == nil {
+		klog.V(4).Info("plsConnectionApprover.approve: the service lister is not initialized, skip")
+		return
+	}
+	services, err := a.az.serviceLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("plsConnectionApprover.approve: failed to list services: %v", err)
+		return
+	}
+
+	servicesByName := make(map[string]*v1.Service)
+	resourceGroups := utilsets.NewString()
+	for _, service := range services {
+		if service.Spec.Type != v1.ServiceTypeLoadBalancer || service.DeletionTimestamp != nil ||
+			!a.az.OwnsLoadBalancerClass(service.Spec.LoadBalancerClass) || !serviceRequiresPLS(service) {
+			continue
+		}
+		servicesByName[strings.ToLower(getServiceName(service))] = service
+		resourceGroups.Insert(a.az.getPLSResourceGroup(service))
+	}
+
+	var (
+		seen     = sets.New[string]()
+		complete = true
+		// connectionStates are the states of the connections of the services keyed by the private endpoint IDs.
+		connectionStates = make(map[string]map[string]string)
+	)
+	for _, rg := range sortedList(resourceGroups) {
+		plsList, err := a.az.plsRepo.List(ctx, rg)
+		if err != nil {
+			klog.Errorf("plsConnectionApprover.approve: failed to list private link services in resource group %s: %v", rg, err)
+			complete = false
+			continue
+		}
+		for _, pls := range plsList {
+			if pls == nil || pls.Properties == nil || !isManagedPrivateLinkSerivce(pls, a.clusterName) {
+				continue
+			}
+			// the private link service shared by several services is approved by their merged policies,
+			// and its connection states are reported to all of them
+			var ownerServices []*v1.Service
+			for _, owner := range getPrivateLinkServiceOwners(pls) {
+				if service, found := servicesByName[strings.ToLower(owner)]; found {
+					ownerServices = append(ownerServices, service)
+				}
+			}
+			if len(ownerServices) == 0 {
+				continue
+			}
+			for _, service := range ownerServices {
+				if connectionStates[strings.ToLower(getServiceName(service))] == nil {
+					connectionStates[strings.ToLower(getServiceName(service))] = make(map[string]string)
+				}
+			}
+			for _, peConn := range pls.Properties.PrivateEndpointConnections {
+				if peConn == nil || peConn.ID == nil || peConn.Properties == nil {
+					continue
+				}
+				status := a.approveConnection(ctx, rg, pls, peConn, ownerServices)
+				seen.Insert(strings.ToLower(*peConn.ID))
+				for _, service := range ownerServices {
+					connectionStates[strings.ToLower(getServiceName(service))][getPrivateEndpointID(peConn)] = status
+				}
+			}
+		}
+	}
+
+	for serviceName, states := range connectionStates {
+		if err := a.updateConnectionStates(ctx, servicesByName[serviceName], states); err != nil {
+			klog.Errorf("plsConnectionApprover.approve: failed to update the connection states of service %s: %v", serviceName, err)
+		}
+	}
+
+	// forget the connections that are deleted, unless some private link services could not be listed
+	if !complete {
+		return
+	}
+	for id := range a.states {
+		if !seen.Has(id) {
+			delete(a.states, id)
+		}
+	}
+}
+
+// approveConnection approves or rejects the connection if it is pending and matches a policy, reports the state
+// of the connection if it is changed, and returns the state.
+func (a *plsConnectionApprover) approveConnection(
+	ctx context.Context,
+	resourceGroup string,
+	pls *armnetwork.PrivateLinkService,
+	peConn *armnetwork.PrivateEndpointConnection,
+	services []*v1.Service,
+) string {
+	var (
+		key         = strings.ToLower(*peConn.ID)
+		plsName     = ptr.Deref(pls.Name, "")
+		peID        = getPrivateEndpointID(peConn)
+		status      string
+		changedByUs bool
+	)
+	if state := peConn.Properties.PrivateLinkServiceConnectionState; state != nil {
+		status = ptr.Deref(state.Status, "")
+	}
+
+	if strings.EqualFold(status, consts.PrivateEndpointConnectionStatusPending) {
+		if decision, description := a.decide(services, peID); decision != "" {
+			klog.V(2).Infof("plsConnectionApprover.approveConnection: updating the connection of private endpoint %s to private link service %s to %s", peID, plsName, decision)
+			_, err := a.az.plsConnectionClient.UpdatePrivateEndpointConnection(ctx, resourceGroup, plsName, ptr.Deref(peConn.Name, ""), armnetwork.PrivateEndpointConnection{
+				Name: peConn.Name,
+				Properties: &armnetwork.PrivateEndpointConnectionProperties{
+					PrivateLinkServiceConnectionState: &armnetwork.PrivateLinkServiceConnectionState{
+						Status:      ptr.To(decision),
+						Description: ptr.To(description),
+					},
+				},
+			})
+			if err != nil {
+				klog.Errorf("plsConnectionApprover.approveConnection: failed to update the connection of private endpoint %s to private link service %s: %v", peID, plsName, err)
+			} else {
+				status = decision
+				changedByUs = true
+			}
+		}
+	}
+
+	// the states of the connections found for the first time are not reported unless they need attention,
+	// so the restart of the cloud provider does not report all connections again
+	lastStatus, found := a.states[key]
+	a.states[key] = status
+	if (found && !strings.EqualFold(lastStatus, status)) ||
+		(!found && (changedByUs || strings.EqualFold(status, consts.PrivateEndpointConnectionStatusPending))) {
+		for _, service := range services {
+			a.az.Event(ctx, service, v1.EventTypeNormal, "PrivateEndpointConnection"+status,
+				fmt.Sprintf("The connection of private endpoint %s to private link service %s is %s", peID, plsName, status))
+		}
+	}
+	return status
+}
+
+// decide returns the state that the pending connection of the private endpoint should be updated to and
+// the description of the decision, or an empty state if the connection should stay pending. The private endpoint
+// approved by any of the services sharing the private link service is approved, and the unapproved one is rejected
+// if any of them requests so.
+func (a *plsConnectionApprover) decide(services []*v1.Service, peID string) (string, string) {
+	for _, service := range services {
+		if getPLSApprovedEndpoints(service).Has(peID) {
+			return consts.PrivateEndpointConnectionStatusApproved,
+				fmt.Sprintf("Approved by annotation %s of service %s", consts.ServiceAnnotationPLSApprovedEndpoints, getServiceName(service))
+		}
+	}
+	if resourceID, err := arm.ParseResourceID(peID); err == nil && a.approvedSubscriptions.Has(resourceID.SubscriptionID) {
+		return consts.PrivateEndpointConnectionStatusApproved,
+			fmt.Sprintf("Approved because subscription %s is allowed by the cluster", resourceID.SubscriptionID)
+	}
+	rejectUnapproved := false
+	for _, service := range services {
+		if v, found := service.Annotations[consts.ServiceAnnotationPLSRejectUnapprovedConnections]; found {
+			rejectUnapproved = rejectUnapproved || strings.EqualFold(strings.TrimSpace(v), consts.TrueAnnotationValue)
+		} else {
+			rejectUnapproved = rejectUnapproved || a.rejectUnapproved
+		}
+	}
+	if rejectUnapproved {
+		return consts.PrivateEndpointConnectionStatusRejected, "Rejected because it is not approved by any approval policy of the cluster"
+	}
+	return "", ""
+}
+
+// updateConnectionStates writes the states of the connections to the connection states annotation of the service,
+// or removes the annotation if there is no connection.
+func (a *plsConnectionApprover) updateConnectionStates(ctx context.Context, service *v1.Service, states map[string]string) error {
+	var value *string
+	if len(states) > 0 {
+		data, err := json.Marshal(states)
+		if err != nil {
+			return err
+		}
+		value = ptr.To(string(data))
+	}
+	current, found := service.Annotations[consts.ServiceAnnotationPLSConnectionStates]
+	if (value == nil && !found) || (value != nil && found && current == *value) {
+		return nil
+	}
+	if a.az.KubeClient == nil {
+		return fmt.Errorf("az.KubeClient is nil")
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]*string{
+				consts.ServiceAnnotationPLSConnectionStates: value,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = a.az.KubeClient.CoreV1().Services(service.Namespace).Patch(ctx, service.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// getPrivateEndpointID returns the ID of the private endpoint of the connection.
+func getPrivateEndpointID(peConn *armnetwork.PrivateEndpointConnection) string {
+	if peConn.Properties == nil || peConn.Properties.PrivateEndpoint == nil {
+		return ""
+	}
+	return ptr.Deref(peConn.Properties.PrivateEndpoint.ID, "")
+}
+
+func getPLSApprovedEndpoints(service *v1.Service) *utilsets.IgnoreCaseSet {
+	endpoints := utilsets.NewString()
+	if val, ok := service.Annotations[consts.ServiceAnnotationPLSApprovedEndpoints]; ok {
+		for _, endpoint := range strings.Split(strings.TrimSpace(val), " ") {
+			if endpoint = strings.TrimSpace(endpoint); endpoint != "" {
+				endpoints.Insert(endpoint)
+			}
+		}
+	}
+	return endpoints
+}
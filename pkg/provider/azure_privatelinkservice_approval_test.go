@@ -0,0 +1,262 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v6"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+	"sigs.k8s.io/cloud-provider-azure/pkg/provider/privatelinkservice"
+)
+
+func getTestPrivateEndpointConnection(name, peID, status string) *armnetwork.PrivateEndpointConnection {
+	return &armnetwork.PrivateEndpointConnection{
+		Name: ptr.To(name),
+		ID:   ptr.To("/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/privateLinkServices/pls/privateEndpointConnections/" + name),
+		Properties: &armnetwork.PrivateEndpointConnectionProperties{
+			PrivateEndpoint: &armnetwork.PrivateEndpoint{ID: ptr.To(peID)},
+			PrivateLinkServiceConnectionState: &armnetwork.PrivateLinkServiceConnectionState{
+				Status: ptr.To(status),
+			},
+		},
+	}
+}
+
+func newTestServiceLister(t *testing.T, services ...*v1.Service) corelisters.ServiceLister {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, service := range services {
+		assert.NoError(t, indexer.Add(service))
+	}
+	return corelisters.NewServiceLister(indexer)
+}
+
+func TestPLSConnectionApproverDecide(t *testing.T) {
+	const (
+		peInAllowedSubscription = "/subscriptions/allowed/resourceGroups/rg/providers/Microsoft.Network/privateEndpoints/pe"
+		peInOtherSubscription   = "/subscriptions/other/resourceGroups/rg/providers/Microsoft.Network/privateEndpoints/pe"
+	)
+	for _, tc := range []struct {
+		desc             string
+		annotations      map[string]string
+		rejectUnapproved bool
+		peID             string
+		expected         string
+	}{
+		{
+			desc:     "should approve the private endpoint in the approved subscriptions",
+			peID:     peInAllowedSubscription,
+			expected: consts.PrivateEndpointConnectionStatusApproved,
+		},
+		{
+			desc: "should approve the private endpoint listed in the annotation case-insensitively",
+			annotations: map[string]string{
+				consts.ServiceAnnotationPLSApprovedEndpoints: "/subscriptions/x/pe " + "/SUBSCRIPTIONS/OTHER/resourceGroups/rg/providers/Microsoft.Network/privateEndpoints/pe",
+			},
+			rejectUnapproved: true,
+			peID:             peInOtherSubscription,
+			expected:         consts.PrivateEndpointConnectionStatusApproved,
+		},
+		{
+			desc:     "should leave the unapproved private endpoint pending by default",
+			peID:     peInOtherSubscription,
+			expected: "",
+		},
+		{
+			desc:             "should reject the unapproved private endpoint if configured",
+			rejectUnapproved: true,
+			peID:             peInOtherSubscription,
+			expected:         consts.PrivateEndpointConnectionStatusRejected,
+		},
+		{
+			desc:        "should reject the unapproved private endpoint if the annotation overrides the config",
+			annotations: map[string]string{consts.ServiceAnnotationPLSRejectUnapprovedConnections: "true"},
+			peID:        peInOtherSubscription,
+			expected:    consts.PrivateEndpointConnectionStatusRejected,
+		},
+		{
+			desc:             "should leave the unapproved private endpoint pending if the annotation overrides the config",
+			annotations:      map[string]string{consts.ServiceAnnotationPLSRejectUnapprovedConnections: "false"},
+			rejectUnapproved: true,
+			peID:             peInOtherSubscription,
+			expected:         "",
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			approver := newPLSConnectionApprover(nil, "kubernetes", 0, []string{"ALLOWED"}, tc.rejectUnapproved)
+			svc := getTestService("svc", v1.ProtocolTCP, tc.annotations, false, 80)
+			status, _ := approver.decide([]*v1.Service{&svc}, tc.peID)
+			assert.Equal(t, tc.expected, status)
+		})
+	}
+}
+
+func TestPLSConnectionApproverApprove(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	az := GetTestCloud(ctrl)
+	recorder := record.NewFakeRecorder(20)
+	az.eventRecorder = recorder
+
+	const (
+		peApprovedByAnnotation = "/subscriptions/other/resourceGroups/rg/providers/Microsoft.Network/privateEndpoints/pe-annotation"
+		peApprovedBySub        = "/subscriptions/allowed/resourceGroups/rg/providers/Microsoft.Network/privateEndpoints/pe-sub"
+		peUnapproved           = "/subscriptions/other/resourceGroups/rg/providers/Microsoft.Network/privateEndpoints/pe-unapproved"
+		peManual               = "/subscriptions/other/resourceGroups/rg/providers/Microsoft.Network/privateEndpoints/pe-manual"
+	)
+	svc := getTestService("svc", v1.ProtocolTCP, map[string]string{
+		consts.ServiceAnnotationPLSCreation:                    "true",
+		consts.ServiceAnnotationPLSApprovedEndpoints:           peApprovedByAnnotation,
+		consts.ServiceAnnotationPLSRejectUnapprovedConnections: "true",
+	}, false, 80)
+	otherSvc := getTestService("other", v1.ProtocolTCP, nil, false, 80)
+	kubeClient := fake.NewSimpleClientset(&svc, &otherSvc)
+	az.KubeClient = kubeClient
+	az.serviceLister = newTestServiceLister(t, &svc, &otherSvc)
+
+	pls := &armnetwork.PrivateLinkService{
+		Name: ptr.To("pls"),
+		Tags: map[string]*string{
+			consts.ClusterNameTagKey:  ptr.To("kubernetes"),
+			consts.OwnerServiceTagKey: ptr.To("default/svc"),
+		},
+		Properties: &armnetwork.PrivateLinkServiceProperties{
+			PrivateEndpointConnections: []*armnetwork.PrivateEndpointConnection{
+				getTestPrivateEndpointConnection("conn-annotation", peApprovedByAnnotation, consts.PrivateEndpointConnectionStatusPending),
+				getTestPrivateEndpointConnection("conn-sub", peApprovedBySub, consts.PrivateEndpointConnectionStatusPending),
+				getTestPrivateEndpointConnection("conn-unapproved", peUnapproved, consts.PrivateEndpointConnectionStatusPending),
+				getTestPrivateEndpointConnection("conn-manual", peManual, consts.PrivateEndpointConnectionStatusApproved),
+			},
+		},
+	}
+	// The private link services of other clusters are ignored.
+	otherPLS := &armnetwork.PrivateLinkService{
+		Name: ptr.To("pls-other"),
+		Tags: map[string]*string{
+			consts.ClusterNameTagKey:  ptr.To("other"),
+			consts.OwnerServiceTagKey: ptr.To("default/svc"),
+		},
+		Properties: &armnetwork.PrivateLinkServiceProperties{
+			PrivateEndpointConnections: []*armnetwork.PrivateEndpointConnection{
+				getTestPrivateEndpointConnection("conn-other", peUnapproved, consts.PrivateEndpointConnectionStatusPending),
+			},
+		},
+	}
+	plsRepo := az.plsRepo.(*privatelinkservice.MockRepository)
+	plsRepo.EXPECT().List(gomock.Any(), "rg").Return([]*armnetwork.PrivateLinkService{pls, otherPLS}, nil)
+
+	connectionClient := az.plsConnectionClient.(*privatelinkservice.MockConnectionClient)
+	for name, status := range map[string]string{
+		"conn-annotation": consts.PrivateEndpointConnectionStatusApproved,
+		"conn-sub":        consts.PrivateEndpointConnectionStatusApproved,
+		"conn-unapproved": consts.PrivateEndpointConnectionStatusRejected,
+	} {
+		status := status
+		connectionClient.EXPECT().UpdatePrivateEndpointConnection(gomock.Any(), "rg", "pls", name, gomock.Any()).
+			DoAndReturn(func(_ context.Context, _, _, _ string, peConn armnetwork.PrivateEndpointConnection) (*armnetwork.PrivateEndpointConnection, error) {
+				assert.Equal(t, status, *peConn.Properties.PrivateLinkServiceConnectionState.Status)
+				return &peConn, nil
+			})
+	}
+
+	approver := newPLSConnectionApprover(az, "kubernetes", 0, []string{"allowed"}, false)
+	approver.approve(context.Background())
+
+	assert.Len(t, recorder.Events, 3)
+	for i := 0; i < 3; i++ {
+		event := <-recorder.Events
+		assert.Contains(t, event, "Normal PrivateEndpointConnection")
+	}
+
+	updated, err := kubeClient.CoreV1().Services("default").Get(context.Background(), "svc", metav1.GetOptions{})
+	assert.NoError(t, err)
+	states := map[string]string{}
+	assert.NoError(t, json.Unmarshal([]byte(updated.Annotations[consts.ServiceAnnotationPLSConnectionStates]), &states))
+	assert.Equal(t, map[string]string{
+		peApprovedByAnnotation: consts.PrivateEndpointConnectionStatusApproved,
+		peApprovedBySub:        consts.PrivateEndpointConnectionStatusApproved,
+		peUnapproved:           consts.PrivateEndpointConnectionStatusRejected,
+		peManual:               consts.PrivateEndpointConnectionStatusApproved,
+	}, states)
+
+	// The connection removed by the customer is forgotten, and the annotation is removed
+	// when there is no connection.
+	pls.Properties.PrivateEndpointConnections = nil
+	plsRepo.EXPECT().List(gomock.Any(), "rg").Return([]*armnetwork.PrivateLinkService{pls}, nil)
+	az.serviceLister = newTestServiceLister(t, updated)
+	approver.approve(context.Background())
+	assert.Empty(t, approver.states)
+	assert.Len(t, recorder.Events, 0)
+
+	updated, err = kubeClient.CoreV1().Services("default").Get(context.Background(), "svc", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.NotContains(t, updated.Annotations, consts.ServiceAnnotationPLSConnectionStates)
+}
+
+func TestPLSConnectionApproverReportStateChanges(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	az := GetTestCloud(ctrl)
+	recorder := record.NewFakeRecorder(20)
+	az.eventRecorder = recorder
+
+	const peID = "/subscriptions/other/resourceGroups/rg/providers/Microsoft.Network/privateEndpoints/pe"
+	svc := getTestService("svc", v1.ProtocolTCP, map[string]string{consts.ServiceAnnotationPLSCreation: "true"}, false, 80)
+	az.KubeClient = fake.NewSimpleClientset(&svc)
+	az.serviceLister = newTestServiceLister(t, &svc)
+
+	pls := &armnetwork.PrivateLinkService{
+		Name: ptr.To("pls"),
+		Tags: map[string]*string{
+			consts.ClusterNameTagKey:  ptr.To("kubernetes"),
+			consts.OwnerServiceTagKey: ptr.To("default/svc"),
+		},
+		Properties: &armnetwork.PrivateLinkServiceProperties{
+			PrivateEndpointConnections: []*armnetwork.PrivateEndpointConnection{
+				getTestPrivateEndpointConnection("conn", peID, consts.PrivateEndpointConnectionStatusApproved),
+			},
+		},
+	}
+	plsRepo := az.plsRepo.(*privatelinkservice.MockRepository)
+	plsRepo.EXPECT().List(gomock.Any(), "rg").Return([]*armnetwork.PrivateLinkService{pls}, nil).Times(2)
+
+	approver := newPLSConnectionApprover(az, "kubernetes", 0, nil, false)
+	// The existing connections that need no attention are not reported.
+	approver.approve(context.Background())
+	assert.Len(t, recorder.Events, 0)
+
+	// The connections rejected manually are reported, but not changed.
+	pls.Properties.PrivateEndpointConnections[0].Properties.PrivateLinkServiceConnectionState.Status = ptr.To(consts.PrivateEndpointConnectionStatusRejected)
+	approver.approve(context.Background())
+	assert.Len(t, recorder.Events, 1)
+	assert.Contains(t, <-recorder.Events, "Normal PrivateEndpointConnectionRejected")
+}
@@ -18,6 +18,7 @@ package provider
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"strings"
 	"testing"
@@ -53,11 +54,56 @@ func TestReconcilePrivateLinkService(t *testing.T) {
 		expectedPLSCreate bool
 		expectedPLS       *armnetwork.PrivateLinkService
 		expectedPLSDelete bool
+		expectedOwners    string
+		existingServices  []*v1.Service
 		expectedError     bool
 	}{
 		{
-			desc:    "reconcilePrivateLinkService should do nothing if service does not create any PLS",
+			desc:            "reconcilePrivateLinkService should do nothing if service does not create any PLS",
+			wantPLS:         true,
+			expectedPLSList: true,
+		},
+		{
+			desc:    "reconcilePrivateLinkService should remove the service no longer requiring PLS from the owners of the shared PLS",
 			wantPLS: true,
+			existingServices: []*v1.Service{
+				ptr.To(getTestServiceWithAnnotation("test1", map[string]string{
+					consts.ServiceAnnotationPLSCreation:          "true",
+					consts.ServiceAnnotationLoadBalancerInternal: "true",
+				}, false, 80)),
+			},
+			expectedPLSCreate: true,
+			expectedOwners:    "default/test1",
+			expectedPLSList:   true,
+			existingPLSList: []*armnetwork.PrivateLinkService{
+				{
+					Name: ptr.To("testpls"),
+					Properties: &armnetwork.PrivateLinkServiceProperties{
+						LoadBalancerFrontendIPConfigurations: []*armnetwork.FrontendIPConfiguration{{ID: ptr.To("fipConfigID")}},
+					},
+					Tags: map[string]*string{
+						consts.ClusterNameTagKey:  ptr.To(testClusterName),
+						consts.OwnerServiceTagKey: ptr.To("default/test1,default/test"),
+					},
+				},
+			},
+		},
+		{
+			desc:            "reconcilePrivateLinkService should keep the PLS owned by the service only if it no longer requires PLS",
+			wantPLS:         true,
+			expectedPLSList: true,
+			existingPLSList: []*armnetwork.PrivateLinkService{
+				{
+					Name: ptr.To("testpls"),
+					Properties: &armnetwork.PrivateLinkServiceProperties{
+						LoadBalancerFrontendIPConfigurations: []*armnetwork.FrontendIPConfiguration{{ID: ptr.To("fipConfigID")}},
+					},
+					Tags: map[string]*string{
+						consts.ClusterNameTagKey:  ptr.To(testClusterName),
+						consts.OwnerServiceTagKey: ptr.To("default/test"),
+					},
+				},
+			},
 		},
 		{
 			desc: "reconcilePrivateLinkService should return error if service requires PLS but needs external LB and floating ip enabled",
@@ -138,11 +184,19 @@ func TestReconcilePrivateLinkService(t *testing.T) {
 			expectedError: true,
 		},
 		{
-			desc: "reconcilePrivateLinkService should report error if service tries to update pls without being an owner",
+			desc: "reconcilePrivateLinkService should report error if the configurations of the owner services conflict",
 			annotations: map[string]string{
 				consts.ServiceAnnotationPLSCreation:          "true",
 				consts.ServiceAnnotationLoadBalancerInternal: "true",
 				consts.ServiceAnnotationPLSName:              "testpls",
+				consts.ServiceAnnotationPLSProxyProtocol:     "true",
+			},
+			existingServices: []*v1.Service{
+				ptr.To(getTestServiceWithAnnotation("test1", map[string]string{
+					consts.ServiceAnnotationPLSCreation:          "true",
+					consts.ServiceAnnotationLoadBalancerInternal: "true",
+					consts.ServiceAnnotationPLSProxyProtocol:     "false",
+				}, false, 80)),
 			},
 			wantPLS:         true,
 			expectedPLSList: true,
@@ -171,13 +225,31 @@ func TestReconcilePrivateLinkService(t *testing.T) {
 			expectedError: true,
 		},
 		{
-			desc: "reconcilePrivateLinkService should share existing pls to a service using the same LB frontEnd without any changes",
+			desc: "reconcilePrivateLinkService should share existing pls to a service using the same LB frontEnd with the merged configurations",
 			annotations: map[string]string{
 				consts.ServiceAnnotationPLSCreation:          "true",
 				consts.ServiceAnnotationLoadBalancerInternal: "true",
+				consts.ServiceAnnotationPLSName:              "testpls",
 			},
-			wantPLS:         true,
-			expectedPLSList: true,
+			existingServices: []*v1.Service{
+				ptr.To(getTestServiceWithAnnotation("test1", map[string]string{
+					consts.ServiceAnnotationPLSCreation:                 "true",
+					consts.ServiceAnnotationLoadBalancerInternal:        "true",
+					consts.ServiceAnnotationPLSIpConfigurationIPAddress: "10.2.0.4",
+				}, false, 80)),
+			},
+			wantPLS:           true,
+			expectedSubnetGet: true,
+			existingSubnet: &armnetwork.Subnet{
+				Name: ptr.To("subnet"),
+				ID:   ptr.To("subnetID"),
+				Properties: &armnetwork.SubnetPropertiesFormat{
+					PrivateLinkServiceNetworkPolicies: to.Ptr(armnetwork.VirtualNetworkPrivateLinkServiceNetworkPoliciesDisabled),
+				},
+			},
+			expectedPLSCreate: true,
+			expectedOwners:    "default/test1,default/test",
+			expectedPLSList:   true,
 			existingPLSList: []*armnetwork.PrivateLinkService{
 				{
 					Name: ptr.To("testpls"),
@@ -325,6 +397,7 @@ func TestReconcilePrivateLinkService(t *testing.T) {
 		test := test
 		t.Run(test.desc, func(t *testing.T) {
 			az := GetTestCloud(ctrl)
+			az.serviceLister = newTestServiceLister(t, test.existingServices...)
 			service := getTestServiceWithAnnotation("test", test.annotations, false, 80)
 			fipConfig := &network.FrontendIPConfiguration{
 				Name: ptr.To("fipConfig"),
@@ -358,7 +431,12 @@ func TestReconcilePrivateLinkService(t *testing.T) {
 				mockPLSRepo.EXPECT().List(gomock.Any(), "rg").Return(test.existingPLSList, nil).MaxTimes(1)
 			}
 			if test.expectedPLSCreate {
-				mockPLSRepo.EXPECT().CreateOrUpdate(gomock.Any(), "rg", gomock.Any()).Return(nil, nil).Times(1)
+				mockPLSRepo.EXPECT().CreateOrUpdate(gomock.Any(), "rg", gomock.Any()).DoAndReturn(func(_ context.Context, _ string, pls armnetwork.PrivateLinkService) (*armnetwork.PrivateLinkService, error) {
+					if test.expectedOwners != "" {
+						assert.Equal(t, test.expectedOwners, ptr.Deref(pls.Tags[consts.OwnerServiceTagKey], ""))
+					}
+					return nil, nil
+				}).Times(1)
 			}
 			if test.expectedPLSDelete {
 				mockPLSRepo.EXPECT().Delete(gomock.Any(), "rg", "testpls", *fipConfig.ID).Return(nil).Times(1)
@@ -1909,66 +1987,143 @@ func TestGetPrivateLinkServiceOwner(t *testing.T) {
 	}
 }
 
-func TestServiceHasAdditionalConfigs(t *testing.T) {
-	tests := []struct {
-		desc        string
-		annotations map[string]string
-		expected    bool
+func TestGetPrivateLinkServiceOwners(t *testing.T) {
+	assert.Empty(t, getPrivateLinkServiceOwners(&armnetwork.PrivateLinkService{}))
+	assert.Equal(t, []string{"default/svc1", "default/svc2"}, getPrivateLinkServiceOwners(&armnetwork.PrivateLinkService{
+		Tags: map[string]*string{consts.OwnerServiceTagKey: ptr.To("default/svc1, default/svc2")},
+	}))
+
+	tags := map[string]*string{consts.OwnerServiceTagKey: ptr.To("default/svc1"), "foo": ptr.To("bar")}
+	pls := &armnetwork.PrivateLinkService{Tags: tags}
+	changed, err := setPrivateLinkServiceOwners(pls, []string{"default/svc1"})
+	assert.NoError(t, err)
+	assert.False(t, changed)
+	changed, err = setPrivateLinkServiceOwners(pls, []string{"default/svc1", "default/svc2"})
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "default/svc1,default/svc2", *pls.Tags[consts.OwnerServiceTagKey])
+	assert.Equal(t, "bar", *pls.Tags["foo"])
+	assert.Equal(t, "default/svc1", *tags[consts.OwnerServiceTagKey], "the original tags should not be changed")
+
+	// the owners exceeding the limit of the tag value are rejected
+	owners := []string{}
+	for i := 0; i < 20; i++ {
+		owners = append(owners, fmt.Sprintf("default/service-%d", i))
+	}
+	changed, err = setPrivateLinkServiceOwners(pls, owners)
+	assert.Error(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, "default/svc1,default/svc2", *pls.Tags[consts.OwnerServiceTagKey])
+}
+
+func TestGetPrivateLinkServiceOwnerServices(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	az := GetTestCloud(ctrl)
+	plsAnnotations := map[string]string{consts.ServiceAnnotationPLSCreation: "true"}
+	service := getTestServiceWithAnnotation("svc", plsAnnotations, false, 80)
+	sharing := getTestServiceWithAnnotation("sharing", plsAnnotations, false, 80)
+	notRequiring := getTestServiceWithAnnotation("not-requiring", nil, false, 80)
+	deleting := getTestServiceWithAnnotation("deleting", plsAnnotations, false, 80)
+	deleting.DeletionTimestamp = &metav1.Time{}
+	az.serviceLister = newTestServiceLister(t, &service, &sharing, &notRequiring, &deleting)
+
+	services, owners := az.getPrivateLinkServiceOwnerServices(&service,
+		[]string{"default/sharing", "default/deleted", "default/not-requiring", "default/deleting"})
+	assert.Equal(t, []string{"default/sharing", "default/svc"}, owners)
+	assert.Equal(t, []*v1.Service{&service, &sharing}, services)
+
+	services, owners = az.getPrivateLinkServiceOwnerServices(&service, []string{"default/svc", "default/sharing"})
+	assert.Equal(t, []string{"default/svc", "default/sharing"}, owners)
+	assert.Equal(t, []*v1.Service{&service, &sharing}, services)
+}
+
+func TestMergePLSConfigs(t *testing.T) {
+	for _, tc := range []struct {
+		desc          string
+		annotations   []map[string]string
+		expected      map[string]string
+		expectedError bool
 	}{
 		{
-			desc:     "Service without any annotations should return false",
-			expected: false,
-		},
-		{
-			desc:        "Service with only pls-create annotation should return false",
-			annotations: map[string]string{consts.ServiceAnnotationPLSCreation: "true"},
-			expected:    false,
-		},
-		{
-			desc:        "Service with pls-name annotation should return true",
-			annotations: map[string]string{consts.ServiceAnnotationPLSName: "test"},
-			expected:    true,
-		},
-		{
-			desc:        "Service with pls-subnet annotation should return true",
-			annotations: map[string]string{consts.ServiceAnnotationPLSIpConfigurationSubnet: "test"},
-			expected:    true,
-		},
-		{
-			desc:        "Service with pls-ip-address-count annotation should return true",
-			annotations: map[string]string{consts.ServiceAnnotationPLSIpConfigurationIPAddressCount: "test"},
-			expected:    true,
-		},
-		{
-			desc:        "Service with pls-ip-address annotation should return true",
-			annotations: map[string]string{consts.ServiceAnnotationPLSIpConfigurationIPAddress: "test"},
-			expected:    true,
-		},
-		{
-			desc:        "Service with pls-fqdns annotation should return true",
-			annotations: map[string]string{consts.ServiceAnnotationPLSFqdns: "test"},
-			expected:    true,
+			desc: "should merge the configurations set by different services",
+			annotations: []map[string]string{
+				{consts.ServiceAnnotationPLSCreation: "true", consts.ServiceAnnotationPLSName: "pls"},
+				{consts.ServiceAnnotationPLSCreation: "true", consts.ServiceAnnotationPLSVisibility: "*"},
+			},
+			expected: map[string]string{consts.ServiceAnnotationPLSName: "pls", consts.ServiceAnnotationPLSVisibility: "*"},
 		},
 		{
-			desc:        "Service with pls-proxy-protocol annotation should return true",
-			annotations: map[string]string{consts.ServiceAnnotationPLSProxyProtocol: "test"},
-			expected:    true,
+			desc: "should ignore the extra whitespaces",
+			annotations: []map[string]string{
+				{consts.ServiceAnnotationPLSFqdns: "a.com b.com"},
+				{consts.ServiceAnnotationPLSFqdns: " a.com  b.com "},
+			},
+			expected: map[string]string{consts.ServiceAnnotationPLSFqdns: "a.com b.com"},
 		},
 		{
-			desc:        "Service with pls-visibility annotation should return true",
-			annotations: map[string]string{consts.ServiceAnnotationPLSVisibility: "test"},
-			expected:    true,
+			desc: "should report error if the configurations conflict",
+			annotations: []map[string]string{
+				{consts.ServiceAnnotationPLSIpConfigurationIPAddressCount: "2"},
+				{consts.ServiceAnnotationPLSIpConfigurationIPAddressCount: "3"},
+			},
+			expectedError: true,
 		},
-		{
-			desc:        "Service with pls-auto-approval annotation should return true",
-			annotations: map[string]string{consts.ServiceAnnotationPLSAutoApproval: "test"},
-			expected:    true,
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			var services []*v1.Service
+			for i, annotations := range tc.annotations {
+				services = append(services, ptr.To(getTestServiceWithAnnotation(fmt.Sprintf("svc%d", i), annotations, false, 80)))
+			}
+			configs, err := mergePLSConfigs(services)
+			assert.Equal(t, tc.expectedError, err != nil, "error: %v", err)
+			if !tc.expectedError {
+				assert.Equal(t, tc.expected, configs)
+				merged := withPLSConfigs(services[0], configs)
+				assert.Equal(t, services[0].Annotations[consts.ServiceAnnotationPLSCreation], merged.Annotations[consts.ServiceAnnotationPLSCreation])
+				for key, value := range tc.expected {
+					assert.Equal(t, value, merged.Annotations[key])
+				}
+			}
+		})
+	}
+}
+
+func TestUnbindServiceFromPrivateLinkServices(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	az := GetTestCloud(ctrl)
+	// the service is unbound even if it has dropped the annotation before it is deleted
+	service := getTestServiceWithAnnotation("svc", nil, false, 80)
+	lb := &network.LoadBalancer{
+		LoadBalancerPropertiesFormat: &network.LoadBalancerPropertiesFormat{
+			FrontendIPConfigurations: &[]network.FrontendIPConfiguration{
+				{Name: ptr.To(az.GetLoadBalancerName(context.TODO(), "", &service)), ID: ptr.To("fip-shared")},
+				{Name: ptr.To(az.GetLoadBalancerName(context.TODO(), "", &service) + "-only"), ID: ptr.To("fip-only")},
+				{Name: ptr.To("other"), ID: ptr.To("fip-other")},
+			},
 		},
 	}
-	for i, c := range tests {
-		s := &v1.Service{}
-		s.Annotations = c.annotations
-		actual := serviceHasAdditionalConfigs(s)
-		assert.Equal(t, actual, c.expected, "TestCase[%d]: %s", i, c.desc)
+	newPLS := func(name, owners string) *armnetwork.PrivateLinkService {
+		return &armnetwork.PrivateLinkService{
+			ID:   ptr.To(name + "-id"),
+			Name: ptr.To(name),
+			Tags: map[string]*string{
+				consts.ClusterNameTagKey:  ptr.To(testClusterName),
+				consts.OwnerServiceTagKey: ptr.To(owners),
+			},
+		}
 	}
+	mockPLSRepo := az.plsRepo.(*privatelinkservice.MockRepository)
+	mockPLSRepo.EXPECT().Get(gomock.Any(), "rg", "fip-shared", gomock.Any()).Return(newPLS("pls-shared", "default/svc,default/sharing"), nil)
+	mockPLSRepo.EXPECT().Get(gomock.Any(), "rg", "fip-only", gomock.Any()).Return(newPLS("pls-only", "default/svc"), nil)
+	mockPLSRepo.EXPECT().CreateOrUpdate(gomock.Any(), "rg", gomock.Any()).DoAndReturn(func(_ context.Context, _ string, pls armnetwork.PrivateLinkService) (*armnetwork.PrivateLinkService, error) {
+		assert.Equal(t, "pls-shared", *pls.Name)
+		assert.Equal(t, "default/sharing", *pls.Tags[consts.OwnerServiceTagKey])
+		return &pls, nil
+	})
+
+	assert.NoError(t, az.unbindServiceFromPrivateLinkServices(context.TODO(), testClusterName, &service, lb))
 }
@@ -0,0 +1,191 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v6"
+
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/azclient/publicipprefixclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+	"sigs.k8s.io/cloud-provider-azure/pkg/metrics"
+	"sigs.k8s.io/cloud-provider-azure/pkg/provider/config"
+	"sigs.k8s.io/cloud-provider-azure/pkg/util/errutils"
+)
+
+// usePublicIPPrefixPool returns true if the new public IPs of the services are allocated from the public IP prefix pool.
+func (az *Cloud) usePublicIPPrefixPool() bool {
+	return az.PublicIPPrefixPool != nil && az.UseStandardLoadBalancer() && !az.HasExtendedLocation()
+}
+
+func (az *Cloud) getPublicIPPrefixClient() publicipprefixclient.Interface {
+	clientFactory := az.NetworkClientFactory
+	if clientFactory == nil {
+		// multi-tenant support
+		clientFactory = az.ComputeClientFactory
+	}
+	return clientFactory.GetPublicIPPrefixClient()
+}
+
+// getPublicIPPrefixPoolName returns the name of the index-th public IP prefix of the IP family created for the pool.
+func getPublicIPPrefixPoolName(pool *config.PublicIPPrefixPool, index int, isIPv6 bool) string {
+	name := strings.ReplaceAll(pool.NameTemplate, consts.PublicIPPrefixPoolIndexPlaceholder, strconv.Itoa(index))
+	return getResourceByIPFamily(name, true, isIPv6)
+}
+
+func isPublicIPPrefixIPv6(prefix *armnetwork.PublicIPPrefix) bool {
+	return prefix.Properties != nil && ptr.Deref(prefix.Properties.PublicIPAddressVersion, "") == armnetwork.IPVersionIPv6
+}
+
+// getPublicIPPrefixUtilization returns the number of public IPs allocated from the prefix and the number of its addresses.
+func getPublicIPPrefixUtilization(prefix *armnetwork.PublicIPPrefix) (allocated, capacity int) {
+	if prefix.Properties == nil {
+		return 0, 0
+	}
+	bits := int32(32)
+	if isPublicIPPrefixIPv6(prefix) {
+		bits = 128
+	}
+	// The public IP prefixes have at most 2^16 addresses.
+	hostBits := min(bits-ptr.Deref(prefix.Properties.PrefixLength, bits), 16)
+	return len(prefix.Properties.PublicIPAddresses), 1 << max(hostBits, 0)
+}
+
+// listPublicIPPrefixPool returns the public IP prefixes of the IP family in the pool, the configured ones first
+// and then the created ones by index, and the smallest index of the prefixes that are not created yet.
+func (az *Cloud) listPublicIPPrefixPool(ctx context.Context, isIPv6 bool) ([]*armnetwork.PublicIPPrefix, int, error) {
+	pool := az.PublicIPPrefixPool
+	client := az.getPublicIPPrefixClient()
+
+	var prefixes []*armnetwork.PublicIPPrefix
+	for _, id := range pool.PrefixIDs {
+		resourceID, err := arm.ParseResourceID(id)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid public IP prefix ID %s: %w", id, err)
+		}
+		prefix, err := client.Get(ctx, resourceID.ResourceGroupName, resourceID.Name, nil)
+		if exists, err := errutils.CheckResourceExistsFromAzcoreError(err); err != nil {
+			return nil, 0, fmt.Errorf("failed to get public IP prefix %s: %w", id, err)
+		} else if !exists {
+			klog.Warningf("listPublicIPPrefixPool: public IP prefix %s in the pool is not found", id)
+			continue
+		}
+		if isPublicIPPrefixIPv6(prefix) == isIPv6 {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+
+	nextIndex := 0
+	if pool.NameTemplate != "" {
+		existing, err := client.List(ctx, pool.ResourceGroup)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to list public IP prefixes in resource group %s: %w", pool.ResourceGroup, err)
+		}
+		prefixByName := make(map[string]*armnetwork.PublicIPPrefix, len(existing))
+		for _, prefix := range existing {
+			prefixByName[strings.ToLower(ptr.Deref(prefix.Name, ""))] = prefix
+		}
+		for i := 1; i <= pool.MaxPrefixes; i++ {
+			prefix, ok := prefixByName[strings.ToLower(getPublicIPPrefixPoolName(pool, i, isIPv6))]
+			if !ok {
+				if nextIndex == 0 {
+					nextIndex = i
+				}
+				continue
+			}
+			if !pool.HasPrefixID(ptr.Deref(prefix.ID, "")) {
+				prefixes = append(prefixes, prefix)
+			}
+		}
+	}
+
+	ipVersion := string(armnetwork.IPVersionIPv4)
+	if isIPv6 {
+		ipVersion = string(armnetwork.IPVersionIPv6)
+	}
+	for _, prefix := range prefixes {
+		allocated, capacity := getPublicIPPrefixUtilization(prefix)
+		metrics.SetPublicIPPrefixUtilization(ptr.Deref(prefix.ID, ""), ipVersion, allocated, capacity)
+	}
+	return prefixes, nextIndex, nil
+}
+
+// allocatePublicIPPrefixFromPool returns a public IP prefix in the pool that has free addresses for a new public IP
+// of the IP family. The prefixes are used in order, so that the public IPs fill up the existing prefixes before a new
+// prefix is created in the zones of the public IP. Concurrent allocations may pick the last free address of a prefix
+// at the same time, in which case creating one of the public IPs fails, and it is retried in the next reconciliation
+// of the service, when the prefix is seen as exhausted.
+func (az *Cloud) allocatePublicIPPrefixFromPool(ctx context.Context, clusterName string, isIPv6 bool, zones *[]string) (*armnetwork.PublicIPPrefix, error) {
+	az.publicIPPrefixPoolLock.Lock()
+	defer az.publicIPPrefixPoolLock.Unlock()
+
+	prefixes, nextIndex, err := az.listPublicIPPrefixPool(ctx, isIPv6)
+	if err != nil {
+		return nil, err
+	}
+	for _, prefix := range prefixes {
+		if allocated, capacity := getPublicIPPrefixUtilization(prefix); allocated < capacity {
+			return prefix, nil
+		}
+	}
+
+	pool := az.PublicIPPrefixPool
+	if pool.NameTemplate == "" || nextIndex == 0 || len(prefixes) >= pool.MaxPrefixes {
+		return nil, fmt.Errorf("all %d public IP prefixes in the public IP prefix pool are exhausted", len(prefixes))
+	}
+
+	name := getPublicIPPrefixPoolName(pool, nextIndex, isIPv6)
+	prefixLength, ipVersion := pool.PrefixLength, armnetwork.IPVersionIPv4
+	if isIPv6 {
+		prefixLength, ipVersion = pool.IPv6PrefixLength, armnetwork.IPVersionIPv6
+	}
+	prefix := armnetwork.PublicIPPrefix{
+		Name:     ptr.To(name),
+		Location: ptr.To(az.Location),
+		SKU: &armnetwork.PublicIPPrefixSKU{
+			Name: ptr.To(armnetwork.PublicIPPrefixSKUNameStandard),
+		},
+		Properties: &armnetwork.PublicIPPrefixPropertiesFormat{
+			PrefixLength:           ptr.To(prefixLength),
+			PublicIPAddressVersion: ptr.To(ipVersion),
+		},
+		Tags: map[string]*string{
+			consts.ClusterNameKey: ptr.To(clusterName),
+		},
+	}
+	if zones != nil {
+		for _, zone := range *zones {
+			prefix.Zones = append(prefix.Zones, ptr.To(zone))
+		}
+	}
+	klog.V(2).Infof("allocatePublicIPPrefixFromPool: creating public IP prefix %s/%s with prefix length %d", pool.ResourceGroup, name, prefixLength)
+	created, err := az.getPublicIPPrefixClient().CreateOrUpdate(ctx, pool.ResourceGroup, name, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create public IP prefix %s/%s: %w", pool.ResourceGroup, name, err)
+	}
+	allocated, capacity := getPublicIPPrefixUtilization(created)
+	metrics.SetPublicIPPrefixUtilization(ptr.Deref(created.ID, ""), string(ipVersion), allocated, capacity)
+	return created, nil
+}
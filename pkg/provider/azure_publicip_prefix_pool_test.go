@@ -0,0 +1,186 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v6"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"k8s.io/utils/ptr"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/azclient/mock_azclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/azclient/publicipprefixclient/mock_publicipprefixclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+	"sigs.k8s.io/cloud-provider-azure/pkg/provider/config"
+)
+
+func getTestPublicIPPrefix(name string, prefixLength int32, allocated int, isIPv6 bool) *armnetwork.PublicIPPrefix {
+	ipVersion := armnetwork.IPVersionIPv4
+	if isIPv6 {
+		ipVersion = armnetwork.IPVersionIPv6
+	}
+	prefix := &armnetwork.PublicIPPrefix{
+		ID:   ptr.To(fmt.Sprintf("/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/publicIPPrefixes/%s", name)),
+		Name: ptr.To(name),
+		Properties: &armnetwork.PublicIPPrefixPropertiesFormat{
+			PrefixLength:           ptr.To(prefixLength),
+			PublicIPAddressVersion: ptr.To(ipVersion),
+		},
+	}
+	for i := 0; i < allocated; i++ {
+		prefix.Properties.PublicIPAddresses = append(prefix.Properties.PublicIPAddresses, &armnetwork.ReferencedPublicIPAddress{
+			ID: ptr.To(fmt.Sprintf("pip-%d", i)),
+		})
+	}
+	return prefix
+}
+
+func TestGetPublicIPPrefixUtilization(t *testing.T) {
+	for _, tc := range []struct {
+		prefix                      *armnetwork.PublicIPPrefix
+		expectedAllocated, capacity int
+	}{
+		{prefix: getTestPublicIPPrefix("p", 28, 3, false), expectedAllocated: 3, capacity: 16},
+		{prefix: getTestPublicIPPrefix("p", 31, 0, false), expectedAllocated: 0, capacity: 2},
+		{prefix: getTestPublicIPPrefix("p", 124, 16, true), expectedAllocated: 16, capacity: 16},
+		{prefix: getTestPublicIPPrefix("p", 64, 1, true), expectedAllocated: 1, capacity: 1 << 16},
+		{prefix: &armnetwork.PublicIPPrefix{}, expectedAllocated: 0, capacity: 0},
+	} {
+		allocated, capacity := getPublicIPPrefixUtilization(tc.prefix)
+		assert.Equal(t, tc.expectedAllocated, allocated)
+		assert.Equal(t, tc.capacity, capacity)
+	}
+}
+
+func TestAllocatePublicIPPrefixFromPool(t *testing.T) {
+	notFound := &azcore.ResponseError{StatusCode: http.StatusNotFound}
+	for _, tc := range []struct {
+		desc           string
+		pool           config.PublicIPPrefixPool
+		isIPv6         bool
+		configured     map[string]*armnetwork.PublicIPPrefix
+		listed         []*armnetwork.PublicIPPrefix
+		expectedCreate string
+		expectedPrefix string
+		expectedErr    string
+	}{
+		{
+			desc:           "the first configured prefix with free addresses is used",
+			pool:           config.PublicIPPrefixPool{PrefixIDs: []string{"full", "free", "missing"}},
+			configured:     map[string]*armnetwork.PublicIPPrefix{"full": getTestPublicIPPrefix("full", 31, 2, false), "free": getTestPublicIPPrefix("free", 28, 15, false)},
+			expectedPrefix: "free",
+		},
+		{
+			desc:           "prefixes of the other IP family are not used",
+			pool:           config.PublicIPPrefixPool{PrefixIDs: []string{"v4", "v6"}},
+			isIPv6:         true,
+			configured:     map[string]*armnetwork.PublicIPPrefix{"v4": getTestPublicIPPrefix("v4", 28, 0, false), "v6": getTestPublicIPPrefix("v6", 124, 0, true)},
+			expectedPrefix: "v6",
+		},
+		{
+			desc:           "a new prefix is created when all prefixes are exhausted",
+			pool:           config.PublicIPPrefixPool{PrefixIDs: []string{"full"}, NameTemplate: "pool-{index}", MaxPrefixes: 4},
+			configured:     map[string]*armnetwork.PublicIPPrefix{"full": getTestPublicIPPrefix("full", 31, 2, false)},
+			listed:         []*armnetwork.PublicIPPrefix{getTestPublicIPPrefix("pool-1", 31, 2, false), getTestPublicIPPrefix("pool-3", 31, 2, false)},
+			expectedCreate: "pool-2",
+			expectedPrefix: "pool-2",
+		},
+		{
+			desc:           "a new IPv6 prefix has the IPv6 suffix",
+			pool:           config.PublicIPPrefixPool{NameTemplate: "pool-{index}", MaxPrefixes: 1},
+			isIPv6:         true,
+			listed:         []*armnetwork.PublicIPPrefix{getTestPublicIPPrefix("pool-1", 31, 0, false)},
+			expectedCreate: "pool-1-IPv6",
+			expectedPrefix: "pool-1-IPv6",
+		},
+		{
+			desc:           "the created prefix with free addresses is used",
+			pool:           config.PublicIPPrefixPool{NameTemplate: "pool-{index}", MaxPrefixes: 2},
+			listed:         []*armnetwork.PublicIPPrefix{getTestPublicIPPrefix("pool-1", 31, 2, false), getTestPublicIPPrefix("pool-2", 31, 1, false)},
+			expectedPrefix: "pool-2",
+		},
+		{
+			desc:        "the pool is exhausted without a name template",
+			pool:        config.PublicIPPrefixPool{PrefixIDs: []string{"full"}},
+			configured:  map[string]*armnetwork.PublicIPPrefix{"full": getTestPublicIPPrefix("full", 31, 2, false)},
+			expectedErr: "all 1 public IP prefixes in the public IP prefix pool are exhausted",
+		},
+		{
+			desc:        "the pool is exhausted with max prefixes",
+			pool:        config.PublicIPPrefixPool{PrefixIDs: []string{"full"}, NameTemplate: "pool-{index}", MaxPrefixes: 2},
+			configured:  map[string]*armnetwork.PublicIPPrefix{"full": getTestPublicIPPrefix("full", 31, 2, false)},
+			listed:      []*armnetwork.PublicIPPrefix{getTestPublicIPPrefix("pool-1", 31, 2, false)},
+			expectedErr: "all 2 public IP prefixes in the public IP prefix pool are exhausted",
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			az := GetTestCloud(ctrl)
+			pool := tc.pool
+			for i, name := range pool.PrefixIDs {
+				pool.PrefixIDs[i] = fmt.Sprintf("/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/publicIPPrefixes/%s", name)
+			}
+			pool.ResourceGroup = "rg"
+			pool.PrefixLength = 30
+			pool.IPv6PrefixLength = 126
+			az.PublicIPPrefixPool = &pool
+
+			prefixClient := mock_publicipprefixclient.NewMockInterface(ctrl)
+			az.NetworkClientFactory.(*mock_azclient.MockClientFactory).EXPECT().GetPublicIPPrefixClient().Return(prefixClient).AnyTimes()
+			prefixClient.EXPECT().Get(gomock.Any(), "rg", gomock.Any(), nil).DoAndReturn(
+				func(_ context.Context, _, name string, _ *string) (*armnetwork.PublicIPPrefix, error) {
+					if prefix, ok := tc.configured[name]; ok {
+						return prefix, nil
+					}
+					return nil, notFound
+				}).Times(len(pool.PrefixIDs))
+			if pool.NameTemplate != "" {
+				prefixClient.EXPECT().List(gomock.Any(), "rg").Return(tc.listed, nil)
+			}
+			if tc.expectedCreate != "" {
+				prefixClient.EXPECT().CreateOrUpdate(gomock.Any(), "rg", tc.expectedCreate, gomock.Any()).DoAndReturn(
+					func(_ context.Context, _, name string, prefix armnetwork.PublicIPPrefix) (*armnetwork.PublicIPPrefix, error) {
+						assert.Equal(t, []*string{ptr.To("1"), ptr.To("2")}, prefix.Zones)
+						assert.Equal(t, "kubernetes", ptr.Deref(prefix.Tags[consts.ClusterNameKey], ""))
+						if tc.isIPv6 {
+							assert.Equal(t, int32(126), ptr.Deref(prefix.Properties.PrefixLength, 0))
+						} else {
+							assert.Equal(t, int32(30), ptr.Deref(prefix.Properties.PrefixLength, 0))
+						}
+						prefix.ID = ptr.To("/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/publicIPPrefixes/" + name)
+						return &prefix, nil
+					})
+			}
+
+			prefix, err := az.allocatePublicIPPrefixFromPool(context.Background(), "kubernetes", tc.isIPv6, &[]string{"1", "2"})
+			if tc.expectedErr != "" {
+				assert.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedPrefix, ptr.Deref(prefix.Name, ""))
+		})
+	}
+}
@@ -37,9 +37,11 @@ import (
 )
 
 // CreateOrUpdatePIP invokes az.PublicIPAddressesClient.CreateOrUpdate with exponential backoff retry
-func (az *Cloud) CreateOrUpdatePIP(service *v1.Service, pipResourceGroup string, pip network.PublicIPAddress) error {
-	ctx, cancel := getContextWithCancel()
-	defer cancel()
+func (az *Cloud) CreateOrUpdatePIP(ctx context.Context, service *v1.Service, pipResourceGroup string, pip network.PublicIPAddress) error {
+	if p := getServicePlanner(ctx); p != nil {
+		p.createOrUpdatePublicIP(az.getNetworkResourceSubscriptionID(), pipResourceGroup, pip)
+		return nil
+	}
 
 	rerr := az.PublicIPAddressesClient.CreateOrUpdate(ctx, pipResourceGroup, ptr.Deref(pip.Name, ""), pip)
 	klog.V(10).Infof("PublicIPAddressesClient.CreateOrUpdate(%s, %s): end", pipResourceGroup, ptr.Deref(pip.Name, ""))
@@ -51,7 +53,7 @@ func (az *Cloud) CreateOrUpdatePIP(service *v1.Service, pipResourceGroup string,
 
 	pipJSON, _ := json.Marshal(pip)
 	klog.Warningf("PublicIPAddressesClient.CreateOrUpdate(%s, %s) failed: %s, PublicIP request: %s", pipResourceGroup, ptr.Deref(pip.Name, ""), rerr.Error().Error(), string(pipJSON))
-	az.Event(service, v1.EventTypeWarning, "CreateOrUpdatePublicIPAddress", rerr.Error().Error())
+	az.Event(ctx, service, v1.EventTypeWarning, "CreateOrUpdatePublicIPAddress", rerr.Error().Error())
 
 	// Invalidate the cache because ETAG precondition mismatch.
 	if rerr.HTTPStatusCode == http.StatusPreconditionFailed {
@@ -70,14 +72,16 @@ func (az *Cloud) CreateOrUpdatePIP(service *v1.Service, pipResourceGroup string,
 }
 
 // DeletePublicIP invokes az.PublicIPAddressesClient.Delete with exponential backoff retry
-func (az *Cloud) DeletePublicIP(service *v1.Service, pipResourceGroup string, pipName string) error {
-	ctx, cancel := getContextWithCancel()
-	defer cancel()
+func (az *Cloud) DeletePublicIP(ctx context.Context, service *v1.Service, pipResourceGroup string, pipName string) error {
+	if p := getServicePlanner(ctx); p != nil {
+		p.deletePublicIP(pipName)
+		return nil
+	}
 
 	rerr := az.PublicIPAddressesClient.Delete(ctx, pipResourceGroup, pipName)
 	if rerr != nil {
 		klog.Errorf("PublicIPAddressesClient.Delete(%s) failed: %s", pipName, rerr.Error().Error())
-		az.Event(service, v1.EventTypeWarning, "DeletePublicIPAddress", rerr.Error().Error())
+		az.Event(ctx, service, v1.EventTypeWarning, "DeletePublicIPAddress", rerr.Error().Error())
 
 		if strings.Contains(rerr.Error().Error(), consts.CannotDeletePublicIPErrorMessageCode) {
 			klog.Warningf("DeletePublicIP for public IP %s failed with error %v, this is because other resources are referencing the public IP. The deletion of the service will continue.", pipName, rerr.Error())
@@ -114,6 +118,11 @@ func (az *Cloud) newPIPCache() (azcache.Resource, error) {
 }
 
 func (az *Cloud) getPublicIPAddress(ctx context.Context, pipResourceGroup string, pipName string, crt azcache.AzureCacheReadType) (network.PublicIPAddress, bool, error) {
+	if p := getServicePlanner(ctx); p != nil {
+		pip, exists := p.getPublicIP(pipName)
+		return pip, exists, nil
+	}
+
 	cached, err := az.pipCache.Get(ctx, pipResourceGroup, crt)
 	if err != nil {
 		return network.PublicIPAddress{}, false, err
@@ -138,7 +147,26 @@ func (az *Cloud) getPublicIPAddress(ctx context.Context, pipResourceGroup string
 	return *(deepcopy.Copy(pip).(*network.PublicIPAddress)), true, nil
 }
 
+// getUpdatedPublicIPAddress gets the public IP from Azure bypassing the cache, so that the
+// properties allocated by Azure on creation are returned.
+func (az *Cloud) getUpdatedPublicIPAddress(ctx context.Context, pipResourceGroup, pipName string) (network.PublicIPAddress, error) {
+	if p := getServicePlanner(ctx); p != nil {
+		pip, _ := p.getPublicIP(pipName)
+		return pip, nil
+	}
+
+	pip, rerr := az.PublicIPAddressesClient.Get(ctx, pipResourceGroup, pipName, "")
+	if rerr != nil {
+		return network.PublicIPAddress{}, rerr.Error()
+	}
+	return pip, nil
+}
+
 func (az *Cloud) listPIP(ctx context.Context, pipResourceGroup string, crt azcache.AzureCacheReadType) ([]network.PublicIPAddress, error) {
+	if p := getServicePlanner(ctx); p != nil {
+		return p.listPublicIPs(), nil
+	}
+
 	cached, err := az.pipCache.Get(ctx, pipResourceGroup, crt)
 	if err != nil {
 		return nil, err
@@ -74,7 +74,7 @@ func TestCreateOrUpdatePIP(t *testing.T) {
 			mockPIPClient.EXPECT().List(gomock.Any(), az.ResourceGroup).Return([]network.PublicIPAddress{}, nil)
 		}
 
-		err := az.CreateOrUpdatePIP(&v1.Service{}, az.ResourceGroup, network.PublicIPAddress{Name: ptr.To("nic")})
+		err := az.CreateOrUpdatePIP(context.TODO(), &v1.Service{}, az.ResourceGroup, network.PublicIPAddress{Name: ptr.To("nic")})
 		assert.EqualError(t, test.expectedErr, err.Error())
 
 		cachedPIP, err := az.pipCache.GetWithDeepCopy(context.TODO(), az.ResourceGroup, cache.CacheReadTypeDefault)
@@ -95,7 +95,7 @@ func TestDeletePublicIP(t *testing.T) {
 	mockPIPClient := az.PublicIPAddressesClient.(*mockpublicipclient.MockInterface)
 	mockPIPClient.EXPECT().Delete(gomock.Any(), az.ResourceGroup, "pip").Return(&retry.Error{HTTPStatusCode: http.StatusInternalServerError})
 
-	err := az.DeletePublicIP(&v1.Service{}, az.ResourceGroup, "pip")
+	err := az.DeletePublicIP(context.TODO(), &v1.Service{}, az.ResourceGroup, "pip")
 	assert.EqualError(t, fmt.Errorf("Retriable: false, RetryAfter: 0s, HTTPStatusCode: 500, RawError: %w", error(nil)), err.Error())
 }
 
@@ -0,0 +1,219 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2022-07-01/network"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+)
+
+// publicIPSettings are the DDoS protection and routing preference settings of the managed public IPs of a service.
+// The empty values mean that the settings are not requested, and the existing ones are kept.
+type publicIPSettings struct {
+	DdosProtectionMode   network.DdosSettingsProtectionMode
+	DdosProtectionPlanID string
+	RoutingPreference    string
+}
+
+func parsePublicIPDdosProtectionMode(mode string) (network.DdosSettingsProtectionMode, error) {
+	for _, m := range network.PossibleDdosSettingsProtectionModeValues() {
+		if strings.EqualFold(strings.TrimSpace(mode), string(m)) {
+			return m, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported DDoS protection mode %q, supported values are %v", mode, network.PossibleDdosSettingsProtectionModeValues())
+}
+
+func parsePublicIPRoutingPreference(preference string) (string, error) {
+	for _, p := range []string{consts.PublicIPRoutingPreferenceInternet, consts.PublicIPRoutingPreferenceMicrosoft} {
+		if strings.EqualFold(strings.TrimSpace(preference), p) {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported routing preference %q, supported values are %q and %q", preference, consts.PublicIPRoutingPreferenceInternet, consts.PublicIPRoutingPreferenceMicrosoft)
+}
+
+// getServicePublicIPSettings returns the settings of the managed public IPs of the service from the service
+// annotations and the defaults in the cloud config. The DDoS protection plan implies the `Enabled` mode.
+func (az *Cloud) getServicePublicIPSettings(service *v1.Service) (publicIPSettings, error) {
+	var (
+		settings publicIPSettings
+		err      error
+	)
+	if mode := az.PublicIPDdosProtectionMode; mode != "" {
+		if settings.DdosProtectionMode, err = parsePublicIPDdosProtectionMode(mode); err != nil {
+			return settings, err
+		}
+		if settings.DdosProtectionMode == network.DdosSettingsProtectionModeEnabled {
+			settings.DdosProtectionPlanID = az.PublicIPDdosProtectionPlanID
+		}
+	} else if az.PublicIPDdosProtectionPlanID != "" {
+		settings.DdosProtectionMode = network.DdosSettingsProtectionModeEnabled
+		settings.DdosProtectionPlanID = az.PublicIPDdosProtectionPlanID
+	}
+
+	if mode, found := service.Annotations[consts.ServiceAnnotationPIPDdosProtectionMode]; found {
+		if settings.DdosProtectionMode, err = parsePublicIPDdosProtectionMode(mode); err != nil {
+			return settings, fmt.Errorf("failed to parse annotation %s: %w", consts.ServiceAnnotationPIPDdosProtectionMode, err)
+		}
+		if settings.DdosProtectionMode != network.DdosSettingsProtectionModeEnabled {
+			settings.DdosProtectionPlanID = ""
+		}
+	}
+	if planID, found := service.Annotations[consts.ServiceAnnotationPIPDdosProtectionPlanID]; found {
+		if _, modeFound := service.Annotations[consts.ServiceAnnotationPIPDdosProtectionMode]; !modeFound {
+			settings.DdosProtectionMode = network.DdosSettingsProtectionModeEnabled
+		}
+		if settings.DdosProtectionMode != network.DdosSettingsProtectionModeEnabled {
+			return settings, fmt.Errorf("annotation %s is only supported with the %s DDoS protection mode",
+				consts.ServiceAnnotationPIPDdosProtectionPlanID, network.DdosSettingsProtectionModeEnabled)
+		}
+		settings.DdosProtectionPlanID = strings.TrimSpace(planID)
+	}
+
+	preference := az.PublicIPRoutingPreference
+	if p, found := service.Annotations[consts.ServiceAnnotationPIPRoutingPreference]; found {
+		preference = p
+	}
+	if preference != "" {
+		if settings.RoutingPreference, err = parsePublicIPRoutingPreference(preference); err != nil {
+			return settings, fmt.Errorf("failed to parse annotation %s: %w", consts.ServiceAnnotationPIPRoutingPreference, err)
+		}
+	}
+	return settings, nil
+}
+
+// getRoutingPreferenceFromIPTags returns the routing preference set by the ip tags, if any.
+func getRoutingPreferenceFromIPTags(ipTags *[]network.IPTag) (string, bool) {
+	if ipTags != nil {
+		for _, ipTag := range *ipTags {
+			if strings.EqualFold(ptr.Deref(ipTag.IPTagType, ""), consts.IPTagTypeRoutingPreference) {
+				return ptr.Deref(ipTag.Tag, ""), true
+			}
+		}
+	}
+	return "", false
+}
+
+// getPublicIPRoutingPreference returns the routing preference of the public IP, which is set by an ip tag.
+func getPublicIPRoutingPreference(pip *network.PublicIPAddress) string {
+	if pip.PublicIPAddressPropertiesFormat != nil {
+		if routingPreference, found := getRoutingPreferenceFromIPTags(pip.IPTags); found {
+			return routingPreference
+		}
+	}
+	return consts.PublicIPRoutingPreferenceMicrosoft
+}
+
+// withRoutingPreferenceIPTag returns the ip tags with the routing preference, unless the ip tags already set one.
+func withRoutingPreferenceIPTag(ipTags *[]network.IPTag, routingPreference string) *[]network.IPTag {
+	if routingPreference != consts.PublicIPRoutingPreferenceInternet {
+		return ipTags
+	}
+	if _, found := getRoutingPreferenceFromIPTags(ipTags); found {
+		return ipTags
+	}
+	result := []network.IPTag{}
+	if ipTags != nil {
+		result = append(result, *ipTags...)
+	}
+	result = append(result, network.IPTag{
+		IPTagType: ptr.To(consts.IPTagTypeRoutingPreference),
+		Tag:       ptr.To(routingPreference),
+	})
+	return &result
+}
+
+// withoutRoutingPreferenceIPTag returns the ip tags without the routing preference.
+func withoutRoutingPreferenceIPTag(ipTags *[]network.IPTag) *[]network.IPTag {
+	if ipTags == nil {
+		return nil
+	}
+	result := []network.IPTag{}
+	for _, ipTag := range *ipTags {
+		if !strings.EqualFold(ptr.Deref(ipTag.IPTagType, ""), consts.IPTagTypeRoutingPreference) {
+			result = append(result, ipTag)
+		}
+	}
+	return &result
+}
+
+// reconcileDdosSettings updates the DDoS settings of the standard public IP if they are requested and different
+// from the existing ones, which can be changed in place. The basic public IPs do not support the DDoS settings.
+func reconcileDdosSettings(pip *network.PublicIPAddress, settings publicIPSettings) bool {
+	if settings.DdosProtectionMode == "" || pip.Sku == nil || pip.Sku.Name != network.PublicIPAddressSkuNameStandard {
+		return false
+	}
+	current := pip.DdosSettings
+	if current != nil && current.ProtectionMode == settings.DdosProtectionMode &&
+		strings.EqualFold(getDdosProtectionPlanID(current), settings.DdosProtectionPlanID) {
+		return false
+	}
+	pip.DdosSettings = &network.DdosSettings{ProtectionMode: settings.DdosProtectionMode}
+	if settings.DdosProtectionPlanID != "" {
+		pip.DdosSettings.DdosProtectionPlan = &network.SubResource{ID: ptr.To(settings.DdosProtectionPlanID)}
+	}
+	return true
+}
+
+func getDdosProtectionPlanID(ddosSettings *network.DdosSettings) string {
+	if ddosSettings.DdosProtectionPlan == nil {
+		return ""
+	}
+	return ptr.Deref(ddosSettings.DdosProtectionPlan.ID, "")
+}
+
+// getPublicIPSettingsRequiringRecreation returns the requested settings that differ from the existing public IP
+// but cannot be changed in place, so the public IP must be recreated to apply them.
+func getPublicIPSettingsRequiringRecreation(pip *network.PublicIPAddress, settings publicIPSettings) []string {
+	var settingsRequiringRecreation []string
+	if settings.RoutingPreference != "" {
+		if current := getPublicIPRoutingPreference(pip); !strings.EqualFold(current, settings.RoutingPreference) {
+			settingsRequiringRecreation = append(settingsRequiringRecreation,
+				fmt.Sprintf("routing preference %s (current: %s)", settings.RoutingPreference, current))
+		}
+	}
+	return settingsRequiringRecreation
+}
+
+// reportPublicIPSettingsRequiringRecreation emits an event if the existing managed public IP of the service
+// must be recreated to apply the requested settings.
+func (az *Cloud) reportPublicIPSettingsRequiringRecreation(ctx context.Context, service *v1.Service, pip *network.PublicIPAddress, clusterName string) {
+	if _, isUserAssignedPIP := serviceOwnsPublicIP(service, pip, clusterName); isUserAssignedPIP {
+		return
+	}
+	// the invalid settings are reported by the reconciliation of the public IP
+	settings, err := az.getServicePublicIPSettings(service)
+	if err != nil {
+		return
+	}
+	if settingsRequiringRecreation := getPublicIPSettingsRequiringRecreation(pip, settings); len(settingsRequiringRecreation) > 0 {
+		message := fmt.Sprintf("The public IP %s must be recreated to apply %s, which cannot be changed in place. "+
+			"Delete the public IP or recreate the service to apply the settings.", ptr.Deref(pip.Name, ""), strings.Join(settingsRequiringRecreation, ", "))
+		klog.Warningf("service(%s): %s", getServiceName(service), message)
+		az.Event(ctx, service, v1.EventTypeWarning, "PublicIPRecreationRequired", message)
+	}
+}
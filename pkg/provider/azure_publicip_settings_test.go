@@ -0,0 +1,243 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2022-07-01/network"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+)
+
+func TestGetServicePublicIPSettings(t *testing.T) {
+	const planID = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/ddosProtectionPlans/plan"
+	for _, tc := range []struct {
+		desc             string
+		ddosMode         string
+		ddosPlanID       string
+		routing          string
+		annotations      map[string]string
+		expectedSettings publicIPSettings
+		expectedErr      string
+	}{
+		{
+			desc: "no settings are requested",
+		},
+		{
+			desc:       "the defaults of the cloud config are used",
+			ddosMode:   "enabled",
+			ddosPlanID: planID,
+			routing:    "internet",
+			expectedSettings: publicIPSettings{
+				DdosProtectionMode:   network.DdosSettingsProtectionModeEnabled,
+				DdosProtectionPlanID: planID,
+				RoutingPreference:    consts.PublicIPRoutingPreferenceInternet,
+			},
+		},
+		{
+			desc:       "the DDoS protection plan implies the enabled mode",
+			ddosPlanID: planID,
+			expectedSettings: publicIPSettings{
+				DdosProtectionMode:   network.DdosSettingsProtectionModeEnabled,
+				DdosProtectionPlanID: planID,
+			},
+		},
+		{
+			desc:       "the annotations override the cloud config",
+			ddosMode:   "Enabled",
+			ddosPlanID: planID,
+			routing:    "Internet",
+			annotations: map[string]string{
+				consts.ServiceAnnotationPIPDdosProtectionMode: "VirtualNetworkInherited",
+				consts.ServiceAnnotationPIPRoutingPreference:  "Microsoft",
+			},
+			expectedSettings: publicIPSettings{
+				DdosProtectionMode: network.DdosSettingsProtectionModeVirtualNetworkInherited,
+				RoutingPreference:  consts.PublicIPRoutingPreferenceMicrosoft,
+			},
+		},
+		{
+			desc: "the DDoS protection plan annotation implies the enabled mode",
+			annotations: map[string]string{
+				consts.ServiceAnnotationPIPDdosProtectionPlanID: planID,
+			},
+			expectedSettings: publicIPSettings{
+				DdosProtectionMode:   network.DdosSettingsProtectionModeEnabled,
+				DdosProtectionPlanID: planID,
+			},
+		},
+		{
+			desc: "the DDoS protection plan annotation with the disabled mode",
+			annotations: map[string]string{
+				consts.ServiceAnnotationPIPDdosProtectionMode:   "Disabled",
+				consts.ServiceAnnotationPIPDdosProtectionPlanID: planID,
+			},
+			expectedErr: "is only supported with the Enabled DDoS protection mode",
+		},
+		{
+			desc: "invalid DDoS protection mode",
+			annotations: map[string]string{
+				consts.ServiceAnnotationPIPDdosProtectionMode: "on",
+			},
+			expectedErr: "unsupported DDoS protection mode",
+		},
+		{
+			desc: "invalid routing preference",
+			annotations: map[string]string{
+				consts.ServiceAnnotationPIPRoutingPreference: "fast",
+			},
+			expectedErr: "unsupported routing preference",
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			az := GetTestCloud(ctrl)
+			az.PublicIPDdosProtectionMode = tc.ddosMode
+			az.PublicIPDdosProtectionPlanID = tc.ddosPlanID
+			az.PublicIPRoutingPreference = tc.routing
+			service := getTestService("test", v1.ProtocolTCP, tc.annotations, false, 80)
+
+			settings, err := az.getServicePublicIPSettings(&service)
+			if tc.expectedErr != "" {
+				assert.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedSettings, settings)
+		})
+	}
+}
+
+func TestReconcileDdosSettings(t *testing.T) {
+	const planID = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/ddosProtectionPlans/plan"
+	standardPIP := func(ddosSettings *network.DdosSettings) *network.PublicIPAddress {
+		return &network.PublicIPAddress{
+			Sku: &network.PublicIPAddressSku{Name: network.PublicIPAddressSkuNameStandard},
+			PublicIPAddressPropertiesFormat: &network.PublicIPAddressPropertiesFormat{
+				DdosSettings: ddosSettings,
+			},
+		}
+	}
+	enabled := publicIPSettings{DdosProtectionMode: network.DdosSettingsProtectionModeEnabled, DdosProtectionPlanID: planID}
+
+	for _, tc := range []struct {
+		desc            string
+		pip             *network.PublicIPAddress
+		settings        publicIPSettings
+		expectedChanged bool
+		expectedDdos    *network.DdosSettings
+	}{
+		{
+			desc:         "the DDoS settings are not requested",
+			pip:          standardPIP(&network.DdosSettings{ProtectionMode: network.DdosSettingsProtectionModeDisabled}),
+			expectedDdos: &network.DdosSettings{ProtectionMode: network.DdosSettingsProtectionModeDisabled},
+		},
+		{
+			desc:            "the DDoS settings are set",
+			pip:             standardPIP(nil),
+			settings:        enabled,
+			expectedChanged: true,
+			expectedDdos: &network.DdosSettings{
+				ProtectionMode:     network.DdosSettingsProtectionModeEnabled,
+				DdosProtectionPlan: &network.SubResource{ID: ptr.To(planID)},
+			},
+		},
+		{
+			desc: "the DDoS settings are up to date",
+			pip: standardPIP(&network.DdosSettings{
+				ProtectionMode:     network.DdosSettingsProtectionModeEnabled,
+				DdosProtectionPlan: &network.SubResource{ID: ptr.To(planID)},
+			}),
+			settings: enabled,
+			expectedDdos: &network.DdosSettings{
+				ProtectionMode:     network.DdosSettingsProtectionModeEnabled,
+				DdosProtectionPlan: &network.SubResource{ID: ptr.To(planID)},
+			},
+		},
+		{
+			desc: "the DDoS protection plan is removed",
+			pip: standardPIP(&network.DdosSettings{
+				ProtectionMode:     network.DdosSettingsProtectionModeEnabled,
+				DdosProtectionPlan: &network.SubResource{ID: ptr.To(planID)},
+			}),
+			settings:        publicIPSettings{DdosProtectionMode: network.DdosSettingsProtectionModeVirtualNetworkInherited},
+			expectedChanged: true,
+			expectedDdos:    &network.DdosSettings{ProtectionMode: network.DdosSettingsProtectionModeVirtualNetworkInherited},
+		},
+		{
+			desc: "the basic public IP is not changed",
+			pip: &network.PublicIPAddress{
+				Sku:                             &network.PublicIPAddressSku{Name: network.PublicIPAddressSkuNameBasic},
+				PublicIPAddressPropertiesFormat: &network.PublicIPAddressPropertiesFormat{},
+			},
+			settings: enabled,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, tc.expectedChanged, reconcileDdosSettings(tc.pip, tc.settings))
+			assert.Equal(t, tc.expectedDdos, tc.pip.DdosSettings)
+		})
+	}
+}
+
+func TestWithRoutingPreferenceIPTag(t *testing.T) {
+	routingPreferenceTag := network.IPTag{IPTagType: ptr.To(consts.IPTagTypeRoutingPreference), Tag: ptr.To(consts.PublicIPRoutingPreferenceInternet)}
+	otherTag := network.IPTag{IPTagType: ptr.To("FirstPartyUsage"), Tag: ptr.To("/Sql")}
+
+	assert.Nil(t, withRoutingPreferenceIPTag(nil, ""))
+	assert.Nil(t, withRoutingPreferenceIPTag(nil, consts.PublicIPRoutingPreferenceMicrosoft))
+	assert.Equal(t, &[]network.IPTag{routingPreferenceTag}, withRoutingPreferenceIPTag(nil, consts.PublicIPRoutingPreferenceInternet))
+	assert.Equal(t, &[]network.IPTag{otherTag, routingPreferenceTag}, withRoutingPreferenceIPTag(&[]network.IPTag{otherTag}, consts.PublicIPRoutingPreferenceInternet))
+	assert.Equal(t, &[]network.IPTag{routingPreferenceTag}, withRoutingPreferenceIPTag(&[]network.IPTag{routingPreferenceTag}, consts.PublicIPRoutingPreferenceInternet))
+	assert.Equal(t, &[]network.IPTag{otherTag}, withoutRoutingPreferenceIPTag(&[]network.IPTag{otherTag, routingPreferenceTag}))
+}
+
+func TestReportPublicIPSettingsRequiringRecreation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	az := GetTestCloud(ctrl)
+	recorder := record.NewFakeRecorder(10)
+	az.eventRecorder = recorder
+	service := getTestService("test", v1.ProtocolTCP, map[string]string{
+		consts.ServiceAnnotationPIPRoutingPreference: "Internet",
+	}, false, 80)
+	pip := &network.PublicIPAddress{
+		Name: ptr.To("pip"),
+		Tags: map[string]*string{
+			consts.ServiceTagKey:  ptr.To("default/test"),
+			consts.ClusterNameKey: ptr.To(testClusterName),
+		},
+		PublicIPAddressPropertiesFormat: &network.PublicIPAddressPropertiesFormat{},
+	}
+
+	az.reportPublicIPSettingsRequiringRecreation(context.Background(), &service, pip, testClusterName)
+	assert.Len(t, recorder.Events, 1)
+	assert.Contains(t, <-recorder.Events, "PublicIPRecreationRequired")
+
+	pip.IPTags = &[]network.IPTag{{IPTagType: ptr.To(consts.IPTagTypeRoutingPreference), Tag: ptr.To(consts.PublicIPRoutingPreferenceInternet)}}
+	az.reportPublicIPSettingsRequiringRecreation(context.Background(), &service, pip, testClusterName)
+	assert.Len(t, recorder.Events, 0)
+}
@@ -24,7 +24,10 @@ import (
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v6"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/wait"
 	cloudprovider "k8s.io/cloud-provider"
 	"k8s.io/klog/v2"
@@ -52,6 +55,9 @@ const (
 
 // delayedRouteOperation defines a delayed route operation which is used in delayedRouteUpdater.
 type delayedRouteOperation struct {
+	routeTableName string
+	// clusterName is the cluster owning the route, which records the route in the tags of the route table if set.
+	clusterName    string
 	route          *armnetwork.Route
 	routeTableTags map[string]*string
 	operation      routeOperation
@@ -106,38 +112,50 @@ func (d *delayedRouteUpdater) updateRoutes(ctx context.Context) {
 		return
 	}
 
-	var err error
-	defer func() {
-		// Notify all the goroutines.
-		for _, op := range d.routesToUpdate {
-			rt := op.(*delayedRouteOperation)
+	// Group the operations by route table, and update the route tables in the order of their first operations.
+	var routeTableNames []string
+	operationsByRouteTable := make(map[string][]*delayedRouteOperation)
+	for _, op := range d.routesToUpdate {
+		rt := op.(*delayedRouteOperation)
+		key := strings.ToLower(rt.routeTableName)
+		if _, found := operationsByRouteTable[key]; !found {
+			routeTableNames = append(routeTableNames, rt.routeTableName)
+		}
+		operationsByRouteTable[key] = append(operationsByRouteTable[key], rt)
+	}
+	// Clear all the jobs.
+	d.routesToUpdate = make([]batchOperation, 0)
+
+	for _, routeTableName := range routeTableNames {
+		operations := operationsByRouteTable[strings.ToLower(routeTableName)]
+		err := d.updateRouteTable(ctx, routeTableName, operations)
+		// Notify the goroutines of the route table.
+		for _, rt := range operations {
 			rt.result <- newBatchOperationResult("", false, err)
 		}
-		// Clear all the jobs.
-		d.routesToUpdate = make([]batchOperation, 0)
-	}()
+	}
+}
 
-	var (
-		routeTable *armnetwork.RouteTable
-	)
-	routeTable, err = d.az.routeTableRepo.Get(ctx, d.az.RouteTableName, azcache.CacheReadTypeDefault)
+// updateRouteTable applies the operations to the route table, and creates the route table if it doesn't exist yet.
+func (d *delayedRouteUpdater) updateRouteTable(ctx context.Context, routeTableName string, operations []*delayedRouteOperation) error {
+	routeTable, err := d.az.routeTableRepo.Get(ctx, routeTableName, azcache.CacheReadTypeDefault)
 	if err != nil {
-		klog.Errorf("getRouteTable() failed with error: %v", err)
-		return
+		klog.Errorf("getRouteTable(%s) failed with error: %v", routeTableName, err)
+		return err
 	}
 
 	// create route table if it doesn't exists yet.
 	if routeTable == nil {
-		err = d.az.createRouteTable(ctx)
+		err = d.az.createRouteTable(ctx, routeTableName)
 		if err != nil {
-			klog.Errorf("createRouteTable() failed with error: %v", err)
-			return
+			klog.Errorf("createRouteTable(%s) failed with error: %v", routeTableName, err)
+			return err
 		}
 
-		routeTable, err = d.az.routeTableRepo.Get(ctx, d.az.RouteTableName, azcache.CacheReadTypeDefault)
+		routeTable, err = d.az.routeTableRepo.Get(ctx, routeTableName, azcache.CacheReadTypeDefault)
 		if err != nil {
-			klog.Errorf("getRouteTable() failed with error: %v", err)
-			return
+			klog.Errorf("getRouteTable(%s) failed with error: %v", routeTableName, err)
+			return err
 		}
 	}
 
@@ -153,8 +171,7 @@ func (d *delayedRouteUpdater) updateRoutes(ctx context.Context) {
 		onlyUpdateTags = false
 	}
 
-	for _, op := range d.routesToUpdate {
-		rt := op.(*delayedRouteOperation)
+	for _, rt := range operations {
 		if rt.operation == routeTableOperationUpdateTags {
 			routeTable.Tags = rt.routeTableTags
 			dirty = true
@@ -193,20 +210,28 @@ func (d *delayedRouteUpdater) updateRoutes(ctx context.Context) {
 		}
 	}
 
+	if d.az.isRouteReconcileEnabled() {
+		if tags, changed := recordOwnedRoutes(routeTable, routes, operations); changed {
+			routeTable.Tags = tags
+			dirty = true
+		}
+	}
+
 	if dirty {
 		if !onlyUpdateTags {
-			klog.V(2).Infof("updateRoutes: updating routes")
+			klog.V(2).Infof("updateRoutes: updating routes of route table %s", routeTableName)
 			routeTable.Properties.Routes = routes
 		}
 		_, err := d.az.routeTableRepo.CreateOrUpdate(ctx, *routeTable)
 		if err != nil {
-			klog.Errorf("CreateOrUpdateRouteTable() failed with error: %v", err)
-			return
+			klog.Errorf("CreateOrUpdateRouteTable(%s) failed with error: %v", routeTableName, err)
+			return err
 		}
 
 		// wait a while for route updates to take effect.
 		time.Sleep(time.Duration(d.az.Config.RouteUpdateWaitingInSeconds) * time.Second)
 	}
+	return nil
 }
 
 // cleanupOutdatedRoutes deletes all non-dualstack routes when dualstack is enabled,
@@ -239,24 +264,29 @@ func (d *delayedRouteUpdater) cleanupOutdatedRoutes(existingRoutes []*armnetwork
 	return existingRoutes, changed
 }
 
-func getAddRouteOperation(route *armnetwork.Route) batchOperation {
+func getAddRouteOperation(routeTableName, clusterName string, route *armnetwork.Route) batchOperation {
 	return &delayedRouteOperation{
-		route:     route,
-		operation: routeOperationAdd,
-		result:    make(chan batchOperationResult),
+		routeTableName: routeTableName,
+		clusterName:    clusterName,
+		route:          route,
+		operation:      routeOperationAdd,
+		result:         make(chan batchOperationResult),
 	}
 }
 
-func getDeleteRouteOperation(route *armnetwork.Route) batchOperation {
+func getDeleteRouteOperation(routeTableName, clusterName string, route *armnetwork.Route) batchOperation {
 	return &delayedRouteOperation{
-		route:     route,
-		operation: routeOperationDelete,
-		result:    make(chan batchOperationResult),
+		routeTableName: routeTableName,
+		clusterName:    clusterName,
+		route:          route,
+		operation:      routeOperationDelete,
+		result:         make(chan batchOperationResult),
 	}
 }
 
-func getUpdateRouteTableTagsOperation(tags map[string]*string) batchOperation {
+func getUpdateRouteTableTagsOperation(routeTableName string, tags map[string]*string) batchOperation {
 	return &delayedRouteOperation{
+		routeTableName: routeTableName,
 		routeTableTags: tags,
 		operation:      routeTableOperationUpdateTags,
 		result:         make(chan batchOperationResult),
@@ -278,10 +308,53 @@ func (d *delayedRouteUpdater) removeOperation(_ string) {}
 // implements cloudprovider.Routes.ListRoutes
 func (az *Cloud) ListRoutes(ctx context.Context, clusterName string) ([]*cloudprovider.Route, error) {
 	klog.V(10).Infof("ListRoutes: START clusterName=%q", clusterName)
-	routeTable, err := az.routeTableRepo.Get(ctx, az.RouteTableName, azcache.CacheReadTypeDefault)
-	routes, err := processRoutes(az.ipv6DualStackEnabled, routeTable, err)
-	if err != nil {
-		return nil, err
+	routeTables := make(map[string]*armnetwork.RouteTable)
+	routesByRouteTable := make(map[string][]*cloudprovider.Route)
+	for _, routeTableName := range az.getRouteTableNames() {
+		routeTable, err := az.routeTableRepo.Get(ctx, routeTableName, azcache.CacheReadTypeDefault)
+		routeTableRoutes, err := processRoutes(az.ipv6DualStackEnabled, routeTable, err)
+		if err != nil {
+			return nil, err
+		}
+		routeTables[routeTableName] = routeTable
+		routesByRouteTable[routeTableName] = routeTableRoutes
+	}
+
+	// ensure the route tables are tagged as configured, before the routes are filtered by the cluster name tag.
+	for _, routeTableName := range az.getRouteTableNames() {
+		routeTable := routeTables[routeTableName]
+		if routeTable == nil {
+			continue
+		}
+		tags, changed := az.ensureRouteTableTagged(routeTable)
+		if az.isRouteReconcileEnabled() {
+			if clusterNameTags, clusterNameChanged := ensureRouteTableClusterNameTagged(routeTable, clusterName); clusterNameChanged {
+				tags, changed = clusterNameTags, true
+			}
+		}
+		if changed {
+			klog.V(2).Infof("ListRoutes: updating tags on route table %s", ptr.Deref(routeTable.Name, ""))
+			op := az.routeUpdater.addOperation(getUpdateRouteTableTagsOperation(routeTableName, tags))
+
+			// Wait for operation complete.
+			if err := op.wait().err; err != nil {
+				klog.Errorf("ListRoutes: failed to update route table tags with error: %v", err)
+				return nil, err
+			}
+		}
+	}
+
+	var routes []*cloudprovider.Route
+	for _, routeTableName := range az.getRouteTableNames() {
+		routeTableRoutes := routesByRouteTable[routeTableName]
+		if az.isRouteReconcileEnabled() {
+			routeTableRoutes = az.filterProtectedRoutes(routeTables[routeTableName], clusterName, routeTableRoutes)
+		}
+		if routes == nil {
+			routes = routeTableRoutes
+		} else {
+			routes = append(routes, routeTableRoutes...)
+		}
 	}
 
 	// Compose routes for unmanaged routes so that node controller won't retry creating routes for them.
@@ -301,17 +374,11 @@ func (az *Cloud) ListRoutes(ctx context.Context, clusterName string) ([]*cloudpr
 		}
 	}
 
-	// ensure the route table is tagged as configured
-	tags, changed := az.ensureRouteTableTagged(routeTable)
-	if changed {
-		klog.V(2).Infof("ListRoutes: updating tags on route table %s", ptr.Deref(routeTable.Name, ""))
-		op := az.routeUpdater.addOperation(getUpdateRouteTableTagsOperation(tags))
-
-		// Wait for operation complete.
-		err = op.wait().err
-		if err != nil {
-			klog.Errorf("ListRoutes: failed to update route table tags with error: %v", err)
-			return nil, err
+	if az.isRouteReconcileEnabled() {
+		for _, routeTableName := range az.getRouteTableNames() {
+			if routeTable := routeTables[routeTableName]; routeTable != nil {
+				az.reconcileRouteTable(ctx, clusterName, routeTableName, routeTable)
+			}
 		}
 	}
 
@@ -347,18 +414,141 @@ func processRoutes(ipv6DualStackEnabled bool, routeTable *armnetwork.RouteTable,
 	return kubeRoutes, nil
 }
 
-func (az *Cloud) createRouteTable(ctx context.Context) error {
+func (az *Cloud) createRouteTable(ctx context.Context, routeTableName string) error {
 	routeTable := armnetwork.RouteTable{
-		Name:       ptr.To(az.RouteTableName),
+		Name:       ptr.To(routeTableName),
 		Location:   ptr.To(az.Location),
 		Properties: &armnetwork.RouteTablePropertiesFormat{},
 	}
 
-	klog.V(3).Infof("createRouteTableIfNotExists: creating routetable. routeTableName=%q", az.RouteTableName)
+	klog.V(3).Infof("createRouteTableIfNotExists: creating routetable. routeTableName=%q", routeTableName)
 	_, err := az.routeTableRepo.CreateOrUpdate(ctx, routeTable)
 	return err
 }
 
+// getRouteTableNames returns the names of all route tables of the cluster, starting with RouteTableName.
+func (az *Cloud) getRouteTableNames() []string {
+	routeTableNames := []string{az.RouteTableName}
+	for _, routeTable := range az.RouteTables {
+		routeTableNames = append(routeTableNames, routeTable.Name)
+	}
+	return routeTableNames
+}
+
+// getRouteTableNameForNode returns the name of the route table of the node, which is the first route table
+// in RouteTables that the node matches by labels and subnet, or RouteTableName if it matches none of them.
+func (az *Cloud) getRouteTableNameForNode(ctx context.Context, nodeName types.NodeName) (string, error) {
+	if len(az.RouteTables) == 0 {
+		return az.RouteTableName, nil
+	}
+
+	az.nodeCachesLock.RLock()
+	nodeLabels := az.nodeLabels[strings.ToLower(string(nodeName))]
+	az.nodeCachesLock.RUnlock()
+
+	var subnetName string
+	for _, routeTable := range az.RouteTables {
+		if routeTable.NodeSelector != nil {
+			nodeSelector, err := metav1.LabelSelectorAsSelector(routeTable.NodeSelector)
+			if err != nil {
+				return "", fmt.Errorf("invalid nodeSelector of route table %s: %w", routeTable.Name, err)
+			}
+			if !nodeSelector.Matches(labels.Set(nodeLabels)) {
+				continue
+			}
+		}
+		if routeTable.SubnetName != "" {
+			if subnetName == "" {
+				var err error
+				if subnetName, err = az.getNodeSubnetName(ctx, nodeName); err != nil {
+					return "", err
+				}
+			}
+			if !strings.EqualFold(subnetName, routeTable.SubnetName) {
+				continue
+			}
+		}
+		return routeTable.Name, nil
+	}
+	return az.RouteTableName, nil
+}
+
+// getNodeSubnetName returns the subnet name of the primary IP configuration of the node's primary network interface.
+func (az *Cloud) getNodeSubnetName(ctx context.Context, nodeName types.NodeName) (string, error) {
+	key := strings.ToLower(string(nodeName))
+	az.nodeCachesLock.RLock()
+	subnetName, found := az.nodeSubnetNames[key]
+	az.nodeCachesLock.RUnlock()
+	if found {
+		return subnetName, nil
+	}
+
+	nic, err := az.VMSet.GetPrimaryInterface(ctx, string(nodeName))
+	if err != nil {
+		return "", err
+	}
+	ipConfig, err := getPrimaryIPConfig(nic)
+	if err != nil {
+		return "", err
+	}
+	if ipConfig.Subnet == nil || ipConfig.Subnet.ID == nil {
+		return "", fmt.Errorf("the primary IP configuration of node %s has no subnet", nodeName)
+	}
+	if subnetName, err = getLastSegment(*ipConfig.Subnet.ID, "/"); err != nil {
+		return "", err
+	}
+
+	az.nodeCachesLock.Lock()
+	defer az.nodeCachesLock.Unlock()
+	if az.nodeSubnetNames == nil {
+		az.nodeSubnetNames = make(map[string]string)
+	}
+	az.nodeSubnetNames[key] = subnetName
+	return subnetName, nil
+}
+
+// deleteRouteFromOtherRouteTables deletes the route from the route tables other than routeTableName that contain it,
+// e.g. the previous route table of a node whose labels are changed.
+func (az *Cloud) deleteRouteFromOtherRouteTables(ctx context.Context, clusterName, routeTableName, routeName string) error {
+	var routeTableNames []string
+	for _, name := range az.getRouteTableNames() {
+		if strings.EqualFold(name, routeTableName) {
+			continue
+		}
+		routeTable, err := az.routeTableRepo.Get(ctx, name, azcache.CacheReadTypeDefault)
+		if err != nil {
+			return err
+		}
+		if routeTable == nil || routeTable.Properties == nil {
+			continue
+		}
+		for _, route := range routeTable.Properties.Routes {
+			if strings.EqualFold(ptr.Deref(route.Name, ""), routeName) {
+				routeTableNames = append(routeTableNames, name)
+				break
+			}
+		}
+	}
+
+	// Add all the operations before waiting for them, so that they are done in the same batch.
+	ops := make([]batchOperation, 0, len(routeTableNames))
+	for _, name := range routeTableNames {
+		klog.V(2).Infof("deleteRouteFromOtherRouteTables: deleting route %q from route table %q", routeName, name)
+		route := &armnetwork.Route{
+			Name:       ptr.To(routeName),
+			Properties: &armnetwork.RoutePropertiesFormat{},
+		}
+		ops = append(ops, az.routeUpdater.addOperation(getDeleteRouteOperation(name, clusterName, route)))
+	}
+	var errs []error
+	for _, op := range ops {
+		if err := op.wait().err; err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
 // CreateRoute creates the described managed route
 // route.Name will be ignored, although the cloud-provider may use nameHint
 // to create a more user-meaningful name.
@@ -371,7 +561,6 @@ func (az *Cloud) CreateRoute(ctx context.Context, clusterName string, _ string,
 	}()
 
 	// Returns  for unmanaged nodes because azure cloud provider couldn't fetch information for them.
-	var targetIP string
 	nodeName := string(kubeRoute.TargetNode)
 	unmanaged, err := az.IsNodeUnmanaged(nodeName)
 	if err != nil {
@@ -385,30 +574,14 @@ func (az *Cloud) CreateRoute(ctx context.Context, clusterName string, _ string,
 		return nil
 	}
 
-	CIDRv6 := utilnet.IsIPv6CIDRString(kubeRoute.DestinationCIDR)
-	// if single stack IPv4 then get the IP for the primary ip config
-	// single stack IPv6 is supported on dual stack host. So the IPv6 IP is secondary IP for both single stack IPv6 and dual stack
-	// Get all private IPs for the machine and find the first one that matches the IPv6 family
-	if !az.ipv6DualStackEnabled && !CIDRv6 {
-		targetIP, _, err = az.getIPForMachine(ctx, kubeRoute.TargetNode)
-		if err != nil {
-			return err
-		}
-	} else {
-		// for dual stack and single stack IPv6 we need to select
-		// a private ip that matches family of the cidr
-		klog.V(4).Infof("CreateRoute: create route instance=%q cidr=%q is in dual stack mode", kubeRoute.TargetNode, kubeRoute.DestinationCIDR)
-		nodePrivateIPs, err := az.getPrivateIPsForMachine(ctx, kubeRoute.TargetNode)
-		if nil != err {
-			klog.V(3).Infof("CreateRoute: create route: failed(GetPrivateIPsByNodeName) instance=%q cidr=%q with error=%v", kubeRoute.TargetNode, kubeRoute.DestinationCIDR, err)
-			return err
-		}
-
-		targetIP, err = findFirstIPByFamily(nodePrivateIPs, CIDRv6)
-		if nil != err {
-			klog.V(3).Infof("CreateRoute: create route: failed(findFirstIpByFamily) instance=%q cidr=%q with error=%v", kubeRoute.TargetNode, kubeRoute.DestinationCIDR, err)
-			return err
-		}
+	targetIP, err := az.getRouteTargetIP(ctx, kubeRoute.TargetNode, kubeRoute.DestinationCIDR)
+	if err != nil {
+		return err
+	}
+	routeTableName, err := az.getRouteTableNameForNode(ctx, kubeRoute.TargetNode)
+	if err != nil {
+		klog.Errorf("CreateRoute: failed to get the route table of node %q with error: %v", kubeRoute.TargetNode, err)
+		return err
 	}
 	routeName := mapNodeNameToRouteName(az.ipv6DualStackEnabled, kubeRoute.TargetNode, kubeRoute.DestinationCIDR)
 	route := &armnetwork.Route{
@@ -420,8 +593,8 @@ func (az *Cloud) CreateRoute(ctx context.Context, clusterName string, _ string,
 		},
 	}
 
-	klog.V(2).Infof("CreateRoute: creating route for clusterName=%q instance=%q cidr=%q", clusterName, kubeRoute.TargetNode, kubeRoute.DestinationCIDR)
-	op := az.routeUpdater.addOperation(getAddRouteOperation(route))
+	klog.V(2).Infof("CreateRoute: creating route for clusterName=%q instance=%q cidr=%q routeTable=%q", clusterName, kubeRoute.TargetNode, kubeRoute.DestinationCIDR, routeTableName)
+	op := az.routeUpdater.addOperation(getAddRouteOperation(routeTableName, clusterName, route))
 
 	// Wait for operation complete.
 	err = op.wait().err
@@ -430,16 +603,50 @@ func (az *Cloud) CreateRoute(ctx context.Context, clusterName string, _ string,
 		return err
 	}
 
+	// Remove the route from the previous route table of the node.
+	if err := az.deleteRouteFromOtherRouteTables(ctx, clusterName, routeTableName, routeName); err != nil {
+		klog.Errorf("CreateRoute failed to delete route %q of node %q from other route tables with error: %v", routeName, kubeRoute.TargetNode, err)
+		return err
+	}
+
 	klog.V(2).Infof("CreateRoute: route created. clusterName=%q instance=%q cidr=%q", clusterName, kubeRoute.TargetNode, kubeRoute.DestinationCIDR)
 	isOperationSucceeded = true
 
 	return nil
 }
 
+// getRouteTargetIP returns the private IP of the node that is the next hop of the route to the CIDR.
+func (az *Cloud) getRouteTargetIP(ctx context.Context, nodeName types.NodeName, cidr string) (string, error) {
+	CIDRv6 := utilnet.IsIPv6CIDRString(cidr)
+	// if single stack IPv4 then get the IP for the primary ip config
+	// single stack IPv6 is supported on dual stack host. So the IPv6 IP is secondary IP for both single stack IPv6 and dual stack
+	// Get all private IPs for the machine and find the first one that matches the IPv6 family
+	if !az.ipv6DualStackEnabled && !CIDRv6 {
+		targetIP, _, err := az.getIPForMachine(ctx, nodeName)
+		return targetIP, err
+	}
+
+	// for dual stack and single stack IPv6 we need to select
+	// a private ip that matches family of the cidr
+	klog.V(4).Infof("getRouteTargetIP: route instance=%q cidr=%q is in dual stack mode", nodeName, cidr)
+	nodePrivateIPs, err := az.getPrivateIPsForMachine(ctx, nodeName)
+	if nil != err {
+		klog.V(3).Infof("getRouteTargetIP: failed(GetPrivateIPsByNodeName) instance=%q cidr=%q with error=%v", nodeName, cidr, err)
+		return "", err
+	}
+
+	targetIP, err := findFirstIPByFamily(nodePrivateIPs, CIDRv6)
+	if nil != err {
+		klog.V(3).Infof("getRouteTargetIP: failed(findFirstIpByFamily) instance=%q cidr=%q with error=%v", nodeName, cidr, err)
+		return "", err
+	}
+	return targetIP, nil
+}
+
 // DeleteRoute deletes the specified managed route
 // Route should be as returned by ListRoutes
 // implements cloudprovider.Routes.DeleteRoute
-func (az *Cloud) DeleteRoute(_ context.Context, clusterName string, kubeRoute *cloudprovider.Route) error {
+func (az *Cloud) DeleteRoute(ctx context.Context, clusterName string, kubeRoute *cloudprovider.Route) error {
 	mc := metrics.NewMetricContext("routes", "delete_route", az.ResourceGroup, az.getNetworkResourceSubscriptionID(), string(kubeRoute.TargetNode))
 	isOperationSucceeded := false
 	defer func() {
@@ -460,34 +667,37 @@ func (az *Cloud) DeleteRoute(_ context.Context, clusterName string, kubeRoute *c
 		return nil
 	}
 
-	routeName := mapNodeNameToRouteName(az.ipv6DualStackEnabled, kubeRoute.TargetNode, kubeRoute.DestinationCIDR)
-	klog.V(2).Infof("DeleteRoute: deleting route. clusterName=%q instance=%q cidr=%q routeName=%q", clusterName, kubeRoute.TargetNode, kubeRoute.DestinationCIDR, routeName)
-	route := &armnetwork.Route{
-		Name:       ptr.To(routeName),
-		Properties: &armnetwork.RoutePropertiesFormat{},
-	}
-	op := az.routeUpdater.addOperation(getDeleteRouteOperation(route))
-
-	// Wait for operation complete.
-	err = op.wait().err
+	// The route table of a deleted node may be unknown, so the route is deleted from all route tables containing it.
+	routeTableName, err := az.getRouteTableNameForNode(ctx, kubeRoute.TargetNode)
 	if err != nil {
-		klog.Errorf("DeleteRoute failed for node %q with error: %v", kubeRoute.TargetNode, err)
-		return err
+		klog.Warningf("DeleteRoute: failed to get the route table of node %q with error: %v", kubeRoute.TargetNode, err)
+		routeTableName = ""
 	}
 
+	routeName := mapNodeNameToRouteName(az.ipv6DualStackEnabled, kubeRoute.TargetNode, kubeRoute.DestinationCIDR)
+	routeNames := []string{routeName}
 	// Remove outdated ipv4 routes as well
 	if az.ipv6DualStackEnabled {
-		routeNameWithoutIPV6Suffix := strings.Split(routeName, consts.RouteNameSeparator)[0]
-		klog.V(2).Infof("DeleteRoute: deleting route. clusterName=%q instance=%q cidr=%q routeName=%q", clusterName, kubeRoute.TargetNode, kubeRoute.DestinationCIDR, routeNameWithoutIPV6Suffix)
-		route := &armnetwork.Route{
-			Name:       ptr.To(routeNameWithoutIPV6Suffix),
-			Properties: &armnetwork.RoutePropertiesFormat{},
+		routeNames = append(routeNames, strings.Split(routeName, consts.RouteNameSeparator)[0])
+	}
+	for _, routeName := range routeNames {
+		klog.V(2).Infof("DeleteRoute: deleting route. clusterName=%q instance=%q cidr=%q routeName=%q", clusterName, kubeRoute.TargetNode, kubeRoute.DestinationCIDR, routeName)
+		if routeTableName != "" {
+			route := &armnetwork.Route{
+				Name:       ptr.To(routeName),
+				Properties: &armnetwork.RoutePropertiesFormat{},
+			}
+			op := az.routeUpdater.addOperation(getDeleteRouteOperation(routeTableName, clusterName, route))
+
+			// Wait for operation complete.
+			err = op.wait().err
+			if err != nil {
+				klog.Errorf("DeleteRoute failed for node %q with error: %v", kubeRoute.TargetNode, err)
+				return err
+			}
 		}
-		op := az.routeUpdater.addOperation(getDeleteRouteOperation(route))
 
-		// Wait for operation complete.
-		err = op.wait().err
-		if err != nil {
+		if err := az.deleteRouteFromOtherRouteTables(ctx, clusterName, routeTableName, routeName); err != nil {
 			klog.Errorf("DeleteRoute failed for node %q with error: %v", kubeRoute.TargetNode, err)
 			return err
 		}
@@ -0,0 +1,331 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v6"
+	cloudprovider "k8s.io/cloud-provider"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+	"sigs.k8s.io/cloud-provider-azure/pkg/metrics"
+	utilsets "sigs.k8s.io/cloud-provider-azure/pkg/util/sets"
+)
+
+const (
+	routeDriftFieldNextHopType      = "next_hop_type"
+	routeDriftFieldNextHopIPAddress = "next_hop_ip_address"
+)
+
+// isRouteReconcileEnabled returns true if the routes are compared with the nodes when listing the routes.
+func (az *Cloud) isRouteReconcileEnabled() bool {
+	return az.RouteReconcileMode != "" && !strings.EqualFold(az.RouteReconcileMode, consts.RouteReconcileModeNone)
+}
+
+// isRouteTableOwnedByCluster returns true if the cluster name tag of the route table contains the cluster,
+// which means the routes named after the nodes of the cluster in the route table are owned by the cluster.
+func isRouteTableOwnedByCluster(routeTable *armnetwork.RouteTable, clusterName string) bool {
+	for _, name := range parsePIPServiceTag(routeTable.Tags[consts.ClusterNameKey]) {
+		if strings.EqualFold(name, clusterName) {
+			return true
+		}
+	}
+	return false
+}
+
+// ensureRouteTableClusterNameTagged adds the cluster to the cluster name tag of the route table.
+// The tag is a comma separated list, because a route table can be shared by the nodes of several clusters.
+func ensureRouteTableClusterNameTagged(routeTable *armnetwork.RouteTable, clusterName string) (map[string]*string, bool) {
+	if isRouteTableOwnedByCluster(routeTable, clusterName) {
+		return routeTable.Tags, false
+	}
+	if routeTable.Tags == nil {
+		routeTable.Tags = make(map[string]*string)
+	}
+	clusterNames := append(parsePIPServiceTag(routeTable.Tags[consts.ClusterNameKey]), clusterName)
+	routeTable.Tags[consts.ClusterNameKey] = ptr.To(strings.Join(clusterNames, ","))
+	return routeTable.Tags, true
+}
+
+// getRouteTableOwnedRouteTagKey returns the key of the i-th tag that records the routes owned by the cluster.
+func getRouteTableOwnedRouteTagKey(clusterName string, i int) string {
+	return fmt.Sprintf("%s%s-%d", consts.RouteTableOwnedRoutesKeyPrefix, clusterName, i)
+}
+
+// isRouteTableOwnedRouteTagKey returns true if the key is one of the tags that record the routes owned by the cluster.
+func isRouteTableOwnedRouteTagKey(key, clusterName string) bool {
+	prefix := consts.RouteTableOwnedRoutesKeyPrefix + clusterName + "-"
+	if len(key) <= len(prefix) || !strings.EqualFold(key[:len(prefix)], prefix) {
+		return false
+	}
+	_, err := strconv.Atoi(key[len(prefix):])
+	return err == nil
+}
+
+// getRouteTableOwnedRoutes returns the names of the routes recorded as owned by the cluster in the tags of the route table.
+func getRouteTableOwnedRoutes(routeTable *armnetwork.RouteTable, clusterName string) *utilsets.IgnoreCaseSet {
+	ownedRoutes := utilsets.NewString()
+	if routeTable == nil || clusterName == "" {
+		return ownedRoutes
+	}
+	for i := 0; ; i++ {
+		found, key := findKeyInMapCaseInsensitive(routeTable.Tags, getRouteTableOwnedRouteTagKey(clusterName, i))
+		if !found {
+			return ownedRoutes
+		}
+		ownedRoutes.Insert(parsePIPServiceTag(routeTable.Tags[key])...)
+	}
+}
+
+// setRouteTableOwnedRoutes records the routes owned by the cluster in the tags of the route table, and returns the
+// updated tags and true if they are changed. The comma separated route names are split across as many tags as needed
+// to fit into the tag value limit, and the tags are copied to avoid changing the route table cache.
+func setRouteTableOwnedRoutes(routeTable *armnetwork.RouteTable, clusterName string, ownedRoutes *utilsets.IgnoreCaseSet) (map[string]*string, bool, error) {
+	routeNames := ownedRoutes.UnsortedList()
+	sort.Strings(routeNames)
+	var values []string
+	for _, routeName := range routeNames {
+		if last := len(values) - 1; last >= 0 && len(values[last])+1+len(routeName) <= consts.MaxTagValueLength {
+			values[last] += "," + routeName
+			continue
+		}
+		values = append(values, routeName)
+	}
+
+	tags := make(map[string]*string, len(routeTable.Tags)+len(values))
+	existing := make(map[string]string)
+	for key, value := range routeTable.Tags {
+		if isRouteTableOwnedRouteTagKey(key, clusterName) {
+			existing[strings.ToLower(key)] = ptr.Deref(value, "")
+			continue
+		}
+		tags[key] = value
+	}
+	changed := len(existing) != len(values)
+	for i, value := range values {
+		key := getRouteTableOwnedRouteTagKey(clusterName, i)
+		if v, ok := existing[strings.ToLower(key)]; !ok || v != value {
+			changed = true
+		}
+		tags[key] = ptr.To(value)
+	}
+	if !changed {
+		return routeTable.Tags, false, nil
+	}
+	if len(tags) > consts.MaxTagCount {
+		return nil, false, fmt.Errorf("the %d routes owned by cluster %s cannot be recorded in the tags of route table %s, which would exceed the limit of %d tags",
+			len(routeNames), clusterName, ptr.Deref(routeTable.Name, ""), consts.MaxTagCount)
+	}
+	return tags, true, nil
+}
+
+// recordOwnedRoutes records the routes added by the operations of the cluster as owned by it in the tags of the route
+// table, and drops the routes deleted by the operations or gone from the route table from the record. It returns the
+// updated tags and true if they are changed.
+func recordOwnedRoutes(routeTable *armnetwork.RouteTable, routes []*armnetwork.Route, operations []*delayedRouteOperation) (map[string]*string, bool) {
+	var clusterName string
+	for _, op := range operations {
+		if op.clusterName != "" {
+			clusterName = op.clusterName
+			break
+		}
+	}
+	if clusterName == "" {
+		return routeTable.Tags, false
+	}
+
+	ownedRoutes := getRouteTableOwnedRoutes(routeTable, clusterName)
+	for _, op := range operations {
+		if op.route == nil || !strings.EqualFold(op.clusterName, clusterName) {
+			continue
+		}
+		switch op.operation {
+		case routeOperationAdd:
+			ownedRoutes.Insert(ptr.Deref(op.route.Name, ""))
+		case routeOperationDelete:
+			ownedRoutes.Delete(ptr.Deref(op.route.Name, ""))
+		}
+	}
+	existingRoutes := utilsets.NewString()
+	for _, route := range routes {
+		existingRoutes.Insert(ptr.Deref(route.Name, ""))
+	}
+	for _, routeName := range ownedRoutes.UnsortedList() {
+		if !existingRoutes.Has(routeName) {
+			ownedRoutes.Delete(routeName)
+		}
+	}
+
+	tags, changed, err := setRouteTableOwnedRoutes(routeTable, clusterName, ownedRoutes)
+	if err != nil {
+		klog.Warningf("recordOwnedRoutes: %v", err)
+		return routeTable.Tags, false
+	}
+	return tags, changed
+}
+
+// isRouteNamedAfterNode returns true if the route follows the naming convention of the routes of a node in the cluster.
+func (az *Cloud) isRouteNamedAfterNode(route *armnetwork.Route) bool {
+	routeName := ptr.Deref(route.Name, "")
+	nodeName := MapRouteNameToNodeName(az.ipv6DualStackEnabled, routeName)
+	az.nodeCachesLock.RLock()
+	isNode := az.nodeNames != nil && az.nodeNames.Has(string(nodeName))
+	az.nodeCachesLock.RUnlock()
+	if !isNode {
+		return false
+	}
+	if route.Properties == nil || route.Properties.AddressPrefix == nil {
+		return true
+	}
+	return strings.EqualFold(routeName, mapNodeNameToRouteName(az.ipv6DualStackEnabled, nodeName, *route.Properties.AddressPrefix))
+}
+
+// isRouteOwnedByCluster returns true if the route is recorded as owned by the cluster in the tags of the route table,
+// or if the route table is tagged for the cluster and the route follows the naming convention of the routes of a node.
+// The record keeps the routes of the nodes deleted from the cluster owned, so that the route controller removes them.
+func (az *Cloud) isRouteOwnedByCluster(routeTable *armnetwork.RouteTable, clusterName string, route *armnetwork.Route) bool {
+	if getRouteTableOwnedRoutes(routeTable, clusterName).Has(ptr.Deref(route.Name, "")) {
+		return true
+	}
+	return isRouteTableOwnedByCluster(routeTable, clusterName) && az.isRouteNamedAfterNode(route)
+}
+
+// isRouteProtected returns true if the route is not owned by the cluster, whatever its next hop type.
+// Such routes, e.g. a default route to a firewall appliance, are never deleted by the route controller.
+func (az *Cloud) isRouteProtected(routeTable *armnetwork.RouteTable, clusterName string, route *armnetwork.Route) bool {
+	return !az.isRouteOwnedByCluster(routeTable, clusterName, route)
+}
+
+// getRouteDriftedFields returns the fields of the route of a node that differ from the desired state.
+func (az *Cloud) getRouteDriftedFields(route *armnetwork.Route) []string {
+	if route.Properties == nil {
+		return nil
+	}
+	var fields []string
+	if ptr.Deref(route.Properties.NextHopType, "") != armnetwork.RouteNextHopTypeVirtualAppliance {
+		fields = append(fields, routeDriftFieldNextHopType)
+	}
+	nodeName := strings.ToLower(string(MapRouteNameToNodeName(az.ipv6DualStackEnabled, ptr.Deref(route.Name, ""))))
+	az.nodeCachesLock.RLock()
+	nodeIPs := az.nodePrivateIPs[nodeName]
+	isNodeIP := nodeIPs == nil || nodeIPs.Len() == 0 || nodeIPs.Has(ptr.Deref(route.Properties.NextHopIPAddress, ""))
+	az.nodeCachesLock.RUnlock()
+	if !isNodeIP {
+		fields = append(fields, routeDriftFieldNextHopIPAddress)
+	}
+	return fields
+}
+
+// reconcileRouteTable reports the utilization of the route table and the drifted routes of the nodes by metrics,
+// and restores the drifted routes in the repair mode. The routes owned by others are left alone.
+func (az *Cloud) reconcileRouteTable(ctx context.Context, clusterName, routeTableName string, routeTable *armnetwork.RouteTable) {
+	metrics.ResetRouteDrift(routeTableName)
+	if routeTable == nil {
+		return
+	}
+
+	var routes []*armnetwork.Route
+	if routeTable.Properties != nil {
+		routes = routeTable.Properties.Routes
+	}
+	repair := strings.EqualFold(az.RouteReconcileMode, consts.RouteReconcileModeRepair)
+	owned, foreign := 0, 0
+	var ops []batchOperation
+	for _, route := range routes {
+		routeName := ptr.Deref(route.Name, "")
+		if !az.isRouteOwnedByCluster(routeTable, clusterName, route) {
+			klog.V(4).Infof("reconcileRouteTable: route %s in route table %s is not owned by the cluster", routeName, routeTableName)
+			foreign++
+			continue
+		}
+		owned++
+		if !az.isRouteNamedAfterNode(route) {
+			// the node of the route is deleted, and the route is going to be deleted by the route controller.
+			continue
+		}
+
+		fields := az.getRouteDriftedFields(route)
+		if len(fields) == 0 {
+			continue
+		}
+		for _, field := range fields {
+			klog.Warningf("reconcileRouteTable: the field %s of route %s in route table %s differs from the desired state", field, routeName, routeTableName)
+			metrics.SetRouteDrift(routeTableName, routeName, field)
+		}
+		if !repair {
+			continue
+		}
+
+		nodeName := MapRouteNameToNodeName(az.ipv6DualStackEnabled, routeName)
+		cidr := ptr.Deref(route.Properties.AddressPrefix, "")
+		targetIP, err := az.getRouteTargetIP(ctx, nodeName, cidr)
+		if err != nil {
+			klog.Errorf("reconcileRouteTable: failed to get the next hop of route %s in route table %s: %v", routeName, routeTableName, err)
+			continue
+		}
+		klog.V(2).Infof("reconcileRouteTable: restoring route %s in route table %s to next hop %s", routeName, routeTableName, targetIP)
+		ops = append(ops, az.routeUpdater.addOperation(getAddRouteOperation(routeTableName, clusterName, &armnetwork.Route{
+			Name: ptr.To(routeName),
+			Properties: &armnetwork.RoutePropertiesFormat{
+				AddressPrefix:    ptr.To(cidr),
+				NextHopType:      ptr.To(armnetwork.RouteNextHopTypeVirtualAppliance),
+				NextHopIPAddress: ptr.To(targetIP),
+			},
+		})))
+	}
+	metrics.SetRouteTableUsage(routeTableName, owned, foreign, consts.RouteTableRouteLimit)
+
+	// Wait for operation complete.
+	for _, op := range ops {
+		if err := op.wait().err; err != nil {
+			klog.Errorf("reconcileRouteTable: failed to restore the routes in route table %s: %v", routeTableName, err)
+		}
+	}
+}
+
+// filterProtectedRoutes removes the routes that are owned by others for sure from the listed routes,
+// so that the route controller never deletes them.
+func (az *Cloud) filterProtectedRoutes(routeTable *armnetwork.RouteTable, clusterName string, kubeRoutes []*cloudprovider.Route) []*cloudprovider.Route {
+	if routeTable == nil || routeTable.Properties == nil {
+		return kubeRoutes
+	}
+	protected := utilsets.NewString()
+	for _, route := range routeTable.Properties.Routes {
+		if az.isRouteProtected(routeTable, clusterName, route) {
+			protected.Insert(ptr.Deref(route.Name, ""))
+		}
+	}
+	if protected.Len() == 0 {
+		return kubeRoutes
+	}
+	filtered := make([]*cloudprovider.Route, 0, len(kubeRoutes))
+	for _, kubeRoute := range kubeRoutes {
+		if protected.Has(kubeRoute.Name) {
+			klog.V(4).Infof("filterProtectedRoutes: omitting route %s in route table %s owned by others", kubeRoute.Name, ptr.Deref(routeTable.Name, ""))
+			continue
+		}
+		filtered = append(filtered, kubeRoute)
+	}
+	return filtered
+}
@@ -0,0 +1,379 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v6"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	cloudprovider "k8s.io/cloud-provider"
+	"k8s.io/utils/ptr"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/consts"
+	"sigs.k8s.io/cloud-provider-azure/pkg/provider/routetable"
+	utilsets "sigs.k8s.io/cloud-provider-azure/pkg/util/sets"
+)
+
+func newTestRoute(name, prefix string, nextHopType armnetwork.RouteNextHopType, nextHopIP string) *armnetwork.Route {
+	return &armnetwork.Route{
+		Name: ptr.To(name),
+		Properties: &armnetwork.RoutePropertiesFormat{
+			AddressPrefix:    ptr.To(prefix),
+			NextHopType:      ptr.To(nextHopType),
+			NextHopIPAddress: ptr.To(nextHopIP),
+		},
+	}
+}
+
+func TestEnsureRouteTableClusterNameTagged(t *testing.T) {
+	testCases := []struct {
+		desc            string
+		tags            map[string]*string
+		expectedTag     string
+		expectedChanged bool
+	}{
+		{
+			desc:            "should add the cluster name tag",
+			expectedTag:     "kubernetes",
+			expectedChanged: true,
+		},
+		{
+			desc:            "should append the cluster to the cluster name tag",
+			tags:            map[string]*string{consts.ClusterNameKey: ptr.To("other")},
+			expectedTag:     "other,kubernetes",
+			expectedChanged: true,
+		},
+		{
+			desc:        "should not change the tag if it contains the cluster",
+			tags:        map[string]*string{consts.ClusterNameKey: ptr.To("other, Kubernetes")},
+			expectedTag: "other, Kubernetes",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			routeTable := &armnetwork.RouteTable{Tags: tc.tags}
+			tags, changed := ensureRouteTableClusterNameTagged(routeTable, "kubernetes")
+			assert.Equal(t, tc.expectedChanged, changed)
+			assert.Equal(t, tc.expectedTag, ptr.Deref(tags[consts.ClusterNameKey], ""))
+			assert.True(t, isRouteTableOwnedByCluster(routeTable, "kubernetes"))
+		})
+	}
+}
+
+func TestIsRouteOwnedByCluster(t *testing.T) {
+	testCases := []struct {
+		desc                 string
+		ipv6DualStackEnabled bool
+		tags                 map[string]*string
+		route                *armnetwork.Route
+		expectedOwned        bool
+		expectedProtected    bool
+	}{
+		{
+			desc:          "should own the route named after a node",
+			route:         newTestRoute("Node1", "10.244.0.0/24", armnetwork.RouteNextHopTypeVirtualAppliance, "10.0.0.4"),
+			expectedOwned: true,
+		},
+		{
+			desc:                 "should own the dual-stack route named after a node and its CIDR",
+			ipv6DualStackEnabled: true,
+			route:                newTestRoute("node1____102440024", "10.244.0.0/24", armnetwork.RouteNextHopTypeVirtualAppliance, "10.0.0.4"),
+			expectedOwned:        true,
+		},
+		{
+			desc:                 "should not own the dual-stack route whose name does not match its CIDR",
+			ipv6DualStackEnabled: true,
+			route:                newTestRoute("node1____102441024", "10.244.0.0/24", armnetwork.RouteNextHopTypeVirtualAppliance, "10.0.0.4"),
+			expectedProtected:    true,
+		},
+		{
+			desc:              "should protect the route to a virtual appliance which is not named after a node",
+			route:             newTestRoute("firewall", "0.0.0.0/0", armnetwork.RouteNextHopTypeVirtualAppliance, "10.1.0.4"),
+			expectedProtected: true,
+		},
+		{
+			desc:              "should protect the route of other next hop type",
+			route:             newTestRoute("internet", "0.0.0.0/0", armnetwork.RouteNextHopTypeInternet, ""),
+			expectedProtected: true,
+		},
+		{
+			desc:          "should not protect the route of a node whose next hop type is changed",
+			route:         newTestRoute("node1", "10.244.0.0/24", armnetwork.RouteNextHopTypeInternet, ""),
+			expectedOwned: true,
+		},
+		{
+			desc: "should own the recorded route of a deleted node",
+			tags: map[string]*string{
+				consts.ClusterNameKey: ptr.To("kubernetes"),
+				consts.RouteTableOwnedRoutesKeyPrefix + "kubernetes-0": ptr.To("node1,node2"),
+			},
+			route:         newTestRoute("node2", "10.244.1.0/24", armnetwork.RouteNextHopTypeVirtualAppliance, "10.0.0.5"),
+			expectedOwned: true,
+		},
+		{
+			desc: "should protect the route recorded by another cluster",
+			tags: map[string]*string{
+				consts.ClusterNameKey:                             ptr.To("kubernetes,other"),
+				consts.RouteTableOwnedRoutesKeyPrefix + "other-0": ptr.To("node2"),
+			},
+			route:             newTestRoute("node2", "10.244.1.0/24", armnetwork.RouteNextHopTypeVirtualAppliance, "10.0.0.5"),
+			expectedProtected: true,
+		},
+		{
+			desc:              "should protect the route named after a node in the route table not tagged for the cluster",
+			tags:              map[string]*string{consts.ClusterNameKey: ptr.To("other")},
+			route:             newTestRoute("node1", "10.244.0.0/24", armnetwork.RouteNextHopTypeVirtualAppliance, "10.0.0.4"),
+			expectedProtected: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			az := &Cloud{
+				nodeNames:            utilsets.NewString("node1"),
+				ipv6DualStackEnabled: tc.ipv6DualStackEnabled,
+			}
+			routeTable := &armnetwork.RouteTable{Tags: tc.tags}
+			if routeTable.Tags == nil {
+				routeTable.Tags = map[string]*string{consts.ClusterNameKey: ptr.To("kubernetes")}
+			}
+			assert.Equal(t, tc.expectedOwned, az.isRouteOwnedByCluster(routeTable, "kubernetes", tc.route))
+			assert.Equal(t, tc.expectedProtected, az.isRouteProtected(routeTable, "kubernetes", tc.route))
+		})
+	}
+}
+
+func TestSetRouteTableOwnedRoutes(t *testing.T) {
+	routeTable := &armnetwork.RouteTable{
+		Name: ptr.To("rt"),
+		Tags: map[string]*string{
+			consts.ClusterNameKey: ptr.To("kubernetes"),
+			consts.RouteTableOwnedRoutesKeyPrefix + "kubernetes-0": ptr.To("node0"),
+			consts.RouteTableOwnedRoutesKeyPrefix + "kubernetes-1": ptr.To("node1"),
+			// the tag of cluster kubernetes-1 is kept.
+			consts.RouteTableOwnedRoutesKeyPrefix + "kubernetes-1-0": ptr.To("node2"),
+		},
+	}
+
+	// the route names are split across the tags to fit into the tag value limit.
+	ownedRoutes := utilsets.NewString()
+	for i := 0; i < 30; i++ {
+		ownedRoutes.Insert(fmt.Sprintf("aks-nodepool1-12345678-vmss%06d", i))
+	}
+	tags, changed, err := setRouteTableOwnedRoutes(routeTable, "kubernetes", ownedRoutes)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Len(t, tags, 7)
+	for i := 0; i < 5; i++ {
+		assert.LessOrEqual(t, len(ptr.Deref(tags[getRouteTableOwnedRouteTagKey("kubernetes", i)], "")), consts.MaxTagValueLength)
+	}
+	assert.Equal(t, "node0", ptr.Deref(routeTable.Tags[getRouteTableOwnedRouteTagKey("kubernetes", 0)], ""), "the route table cache should not be changed")
+	routeTable.Tags = tags
+	assert.Equal(t, ownedRoutes.Len(), getRouteTableOwnedRoutes(routeTable, "kubernetes").Len())
+
+	_, changed, err = setRouteTableOwnedRoutes(routeTable, "kubernetes", ownedRoutes)
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	// the outdated tags are removed.
+	tags, changed, err = setRouteTableOwnedRoutes(routeTable, "kubernetes", utilsets.NewString())
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, map[string]*string{
+		consts.ClusterNameKey: ptr.To("kubernetes"),
+		consts.RouteTableOwnedRoutesKeyPrefix + "kubernetes-1-0": ptr.To("node2"),
+	}, tags)
+
+	// the routes cannot be recorded beyond the tag count limit.
+	for i := 0; i < 1000; i++ {
+		ownedRoutes.Insert(fmt.Sprintf("aks-nodepool2-12345678-vmss%06d", i))
+	}
+	_, _, err = setRouteTableOwnedRoutes(routeTable, "kubernetes", ownedRoutes)
+	assert.Error(t, err)
+}
+
+func TestRecordOwnedRoutes(t *testing.T) {
+	routeTable := &armnetwork.RouteTable{
+		Tags: map[string]*string{consts.RouteTableOwnedRoutesKeyPrefix + "kubernetes-0": ptr.To("node1,node2,node3")},
+	}
+	routes := []*armnetwork.Route{
+		newTestRoute("node1", "10.244.0.0/24", armnetwork.RouteNextHopTypeVirtualAppliance, "10.0.0.4"),
+		newTestRoute("node4", "10.244.3.0/24", armnetwork.RouteNextHopTypeVirtualAppliance, "10.0.0.7"),
+		newTestRoute("firewall", "0.0.0.0/0", armnetwork.RouteNextHopTypeVirtualAppliance, "10.1.0.4"),
+	}
+	operations := []*delayedRouteOperation{
+		getAddRouteOperation("rt", "kubernetes", routes[1]).(*delayedRouteOperation),
+		getDeleteRouteOperation("rt", "kubernetes", &armnetwork.Route{Name: ptr.To("node2")}).(*delayedRouteOperation),
+	}
+
+	// node2 is deleted by the operation and node3 is gone from the route table.
+	tags, changed := recordOwnedRoutes(routeTable, routes, operations)
+	assert.True(t, changed)
+	assert.Equal(t, "node1,node4", ptr.Deref(tags[consts.RouteTableOwnedRoutesKeyPrefix+"kubernetes-0"], ""))
+
+	// the routes are not recorded without the cluster name.
+	operations = []*delayedRouteOperation{getAddRouteOperation("rt", "", routes[2]).(*delayedRouteOperation)}
+	tags, changed = recordOwnedRoutes(routeTable, routes, operations)
+	assert.False(t, changed)
+	assert.Equal(t, routeTable.Tags, tags)
+}
+
+func TestGetRouteDriftedFields(t *testing.T) {
+	az := &Cloud{
+		nodePrivateIPs: map[string]*utilsets.IgnoreCaseSet{
+			"node1": utilsets.NewString("10.0.0.4"),
+		},
+	}
+	assert.Empty(t, az.getRouteDriftedFields(newTestRoute("node1", "10.244.0.0/24", armnetwork.RouteNextHopTypeVirtualAppliance, "10.0.0.4")))
+	assert.Empty(t, az.getRouteDriftedFields(newTestRoute("node2", "10.244.1.0/24", armnetwork.RouteNextHopTypeVirtualAppliance, "10.0.0.5")))
+	assert.Equal(t, []string{routeDriftFieldNextHopIPAddress},
+		az.getRouteDriftedFields(newTestRoute("node1", "10.244.0.0/24", armnetwork.RouteNextHopTypeVirtualAppliance, "10.1.0.4")))
+	assert.Equal(t, []string{routeDriftFieldNextHopType, routeDriftFieldNextHopIPAddress},
+		az.getRouteDriftedFields(newTestRoute("node1", "10.244.0.0/24", armnetwork.RouteNextHopTypeInternet, "")))
+}
+
+func TestListRoutesReconcile(t *testing.T) {
+	for _, mode := range []string{consts.RouteReconcileModeReport, consts.RouteReconcileModeRepair} {
+		t.Run(mode, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			az := GetTestCloud(ctrl)
+			az.RouteReconcileMode = mode
+			az.RouteTableResourceGroup = az.ResourceGroup
+			az.nodeNames = utilsets.NewString("node1", "node2")
+			az.nodePrivateIPs = map[string]*utilsets.IgnoreCaseSet{
+				"node1": utilsets.NewString("10.0.0.4"),
+				"node2": utilsets.NewString("10.0.0.5"),
+			}
+			az.routeUpdater = newDelayedRouteUpdater(az, 10*time.Millisecond)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go az.routeUpdater.run(ctx)
+
+			routeTable := &armnetwork.RouteTable{
+				Name: ptr.To(az.RouteTableName),
+				Properties: &armnetwork.RouteTablePropertiesFormat{
+					Routes: []*armnetwork.Route{
+						newTestRoute("node1", "10.244.0.0/24", armnetwork.RouteNextHopTypeVirtualAppliance, "10.0.0.4"),
+						newTestRoute("node2", "10.244.1.0/24", armnetwork.RouteNextHopTypeVirtualAppliance, "10.1.0.4"),
+						newTestRoute("firewall", "0.0.0.0/0", armnetwork.RouteNextHopTypeVirtualAppliance, "10.1.0.4"),
+						newTestRoute("hub", "10.1.0.0/16", armnetwork.RouteNextHopTypeVirtualNetworkGateway, ""),
+					},
+				},
+			}
+			mockRTRepo := az.routeTableRepo.(*routetable.MockRepository)
+			mockRTRepo.EXPECT().Get(gomock.Any(), az.RouteTableName, gomock.Any()).DoAndReturn(
+				func(_ context.Context, _ string, _ interface{}) (*armnetwork.RouteTable, error) {
+					copied := *routeTable
+					copied.Tags = map[string]*string{}
+					for key, value := range routeTable.Tags {
+						copied.Tags[key] = value
+					}
+					return &copied, nil
+				}).AnyTimes()
+			// the cluster name tag is added before reconciling the routes
+			mockRTRepo.EXPECT().CreateOrUpdate(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, rt armnetwork.RouteTable) (*armnetwork.RouteTable, error) {
+					assert.Equal(t, "kubernetes", ptr.Deref(rt.Tags[consts.ClusterNameKey], ""))
+					assert.Len(t, rt.Properties.Routes, 4)
+					return &rt, nil
+				})
+			if mode == consts.RouteReconcileModeRepair {
+				mockVMSet := NewMockVMSet(ctrl)
+				az.VMSet = mockVMSet
+				mockVMSet.EXPECT().GetIPByNodeName(gomock.Any(), "node2").Return("10.0.0.5", "", nil)
+				mockRTRepo.EXPECT().CreateOrUpdate(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, rt armnetwork.RouteTable) (*armnetwork.RouteTable, error) {
+						assert.Contains(t, rt.Properties.Routes, newTestRoute("node2", "10.244.1.0/24", armnetwork.RouteNextHopTypeVirtualAppliance, "10.0.0.5"))
+						assert.Contains(t, rt.Properties.Routes, routeTable.Properties.Routes[2])
+						assert.Contains(t, rt.Properties.Routes, routeTable.Properties.Routes[3])
+						return &rt, nil
+					})
+			}
+
+			routes, err := az.ListRoutes(context.Background(), "kubernetes")
+			assert.NoError(t, err)
+			// the routes to the firewall appliance and the virtual network gateway are protected
+			assert.Equal(t, []*cloudprovider.Route{
+				{Name: "node1", TargetNode: "node1", DestinationCIDR: "10.244.0.0/24"},
+				{Name: "node2", TargetNode: "node2", DestinationCIDR: "10.244.1.0/24"},
+			}, routes)
+		})
+	}
+}
+
+func TestListRoutesDeletedNode(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	az := GetTestCloud(ctrl)
+	az.RouteReconcileMode = consts.RouteReconcileModeReport
+	// node2 is deleted from the cluster.
+	az.nodeNames = utilsets.NewString("node1")
+	az.routeUpdater = newDelayedRouteUpdater(az, 10*time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go az.routeUpdater.run(ctx)
+
+	routeTable := &armnetwork.RouteTable{
+		Name: ptr.To(az.RouteTableName),
+		Tags: map[string]*string{
+			consts.ClusterNameKey:                          ptr.To("kubernetes"),
+			getRouteTableOwnedRouteTagKey("kubernetes", 0): ptr.To("node1,node2"),
+		},
+		Properties: &armnetwork.RouteTablePropertiesFormat{
+			Routes: []*armnetwork.Route{
+				newTestRoute("node1", "10.244.0.0/24", armnetwork.RouteNextHopTypeVirtualAppliance, "10.0.0.4"),
+				newTestRoute("node2", "10.244.1.0/24", armnetwork.RouteNextHopTypeVirtualAppliance, "10.0.0.5"),
+				newTestRoute("firewall", "0.0.0.0/0", armnetwork.RouteNextHopTypeVirtualAppliance, "10.1.0.4"),
+			},
+		},
+	}
+	mockRTRepo := az.routeTableRepo.(*routetable.MockRepository)
+	mockRTRepo.EXPECT().Get(gomock.Any(), az.RouteTableName, gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ string, _ interface{}) (*armnetwork.RouteTable, error) {
+			copied := *routeTable
+			copied.Properties = &armnetwork.RouteTablePropertiesFormat{
+				Routes: append([]*armnetwork.Route{}, routeTable.Properties.Routes...),
+			}
+			return &copied, nil
+		}).AnyTimes()
+
+	// the route of the deleted node is still listed because it is recorded as owned by the cluster.
+	routes, err := az.ListRoutes(context.Background(), "kubernetes")
+	assert.NoError(t, err)
+	assert.Equal(t, []*cloudprovider.Route{
+		{Name: "node1", TargetNode: "node1", DestinationCIDR: "10.244.0.0/24"},
+		{Name: "node2", TargetNode: "node2", DestinationCIDR: "10.244.1.0/24"},
+	}, routes)
+
+	// the route controller deletes the route of the deleted node, which is removed from the record as well.
+	mockRTRepo.EXPECT().CreateOrUpdate(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, rt armnetwork.RouteTable) (*armnetwork.RouteTable, error) {
+			assert.Equal(t, []*armnetwork.Route{routeTable.Properties.Routes[0], routeTable.Properties.Routes[2]}, rt.Properties.Routes)
+			assert.Equal(t, "node1", ptr.Deref(rt.Tags[getRouteTableOwnedRouteTagKey("kubernetes", 0)], ""))
+			return &rt, nil
+		})
+	err = az.DeleteRoute(context.Background(), "kubernetes", routes[1])
+	assert.NoError(t, err)
+}
@@ -25,9 +25,11 @@ import (
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v6"
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2022-07-01/network"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	cloudprovider "k8s.io/cloud-provider"
 	"k8s.io/utils/ptr"
@@ -411,7 +413,7 @@ func TestCreateRouteTable(t *testing.T) {
 		Properties: &armnetwork.RouteTablePropertiesFormat{},
 	}
 	mockRTRepo.EXPECT().CreateOrUpdate(gomock.Any(), expectedTable).Return(nil, nil)
-	err := cloud.createRouteTable(context.Background())
+	err := cloud.createRouteTable(context.Background(), cloud.RouteTableName)
 	if err != nil {
 		t.Errorf("unexpected error in creating route table: %v", err)
 		t.FailNow()
@@ -836,3 +838,274 @@ func TestEnsureRouteTableTagged(t *testing.T) {
 	assert.Nil(t, tags)
 	assert.False(t, changed)
 }
+
+func TestGetRouteTableNameForNode(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	routeTables := []config.RouteTableConfiguration{
+		{
+			Name:         "rt-pool1",
+			NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"agentpool": "pool1"}},
+		},
+		{
+			Name:       "rt-subnet1",
+			SubnetName: "subnet1",
+		},
+		{
+			Name:         "rt-pool2-subnet2",
+			NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"agentpool": "pool2"}},
+			SubnetName:   "subnet2",
+		},
+	}
+	testCases := []struct {
+		desc               string
+		routeTables        []config.RouteTableConfiguration
+		nodeLabels         map[string]string
+		nodeSubnetName     string
+		nicErr             error
+		expectedRouteTable string
+		expectedErr        bool
+	}{
+		{
+			desc:               "should return the route table name if there are no route tables",
+			nodeLabels:         map[string]string{"agentpool": "pool1"},
+			expectedRouteTable: "bar",
+		},
+		{
+			desc:               "should return the route table matching the node labels",
+			routeTables:        routeTables,
+			nodeLabels:         map[string]string{"agentpool": "pool1"},
+			expectedRouteTable: "rt-pool1",
+		},
+		{
+			desc:               "should return the route table matching the node subnet",
+			routeTables:        routeTables,
+			nodeLabels:         map[string]string{"agentpool": "pool3"},
+			nodeSubnetName:     "Subnet1",
+			expectedRouteTable: "rt-subnet1",
+		},
+		{
+			desc:               "should return the route table matching both the node labels and subnet",
+			routeTables:        routeTables,
+			nodeLabels:         map[string]string{"agentpool": "pool2"},
+			nodeSubnetName:     "subnet2",
+			expectedRouteTable: "rt-pool2-subnet2",
+		},
+		{
+			desc:               "should return the route table name if the node matches no route table",
+			routeTables:        routeTables,
+			nodeLabels:         map[string]string{"agentpool": "pool3"},
+			nodeSubnetName:     "subnet2",
+			expectedRouteTable: "bar",
+		},
+		{
+			desc:        "should return an error if the node subnet cannot be found",
+			routeTables: routeTables,
+			nicErr:      cloudprovider.InstanceNotFound,
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			mockVMSet := NewMockVMSet(ctrl)
+			cloud := &Cloud{
+				VMSet: mockVMSet,
+				Config: config.Config{
+					RouteTableName: "bar",
+					RouteTables:    tc.routeTables,
+				},
+				nodeLabels: map[string]map[string]string{"node": tc.nodeLabels},
+			}
+			if tc.nicErr != nil {
+				mockVMSet.EXPECT().GetPrimaryInterface(gomock.Any(), "node").Return(network.Interface{}, tc.nicErr)
+			} else if tc.nodeSubnetName != "" {
+				mockVMSet.EXPECT().GetPrimaryInterface(gomock.Any(), "node").Return(network.Interface{
+					Name: ptr.To("nic"),
+					InterfacePropertiesFormat: &network.InterfacePropertiesFormat{
+						IPConfigurations: &[]network.InterfaceIPConfiguration{
+							{
+								InterfaceIPConfigurationPropertiesFormat: &network.InterfaceIPConfigurationPropertiesFormat{
+									Subnet: &network.Subnet{ID: ptr.To("/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/" + tc.nodeSubnetName)},
+								},
+							},
+						},
+					},
+				}, nil).MaxTimes(1)
+			}
+
+			routeTableName, err := cloud.getRouteTableNameForNode(context.Background(), "node")
+			assert.Equal(t, tc.expectedErr, err != nil)
+			assert.Equal(t, tc.expectedRouteTable, routeTableName)
+			if tc.nodeSubnetName != "" {
+				// the subnet name is cached
+				routeTableName, err = cloud.getRouteTableNameForNode(context.Background(), "node")
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedRouteTable, routeTableName)
+			}
+		})
+	}
+}
+
+func TestUpdateRoutesMultipleRouteTables(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRTRepo := routetable.NewMockRepository(ctrl)
+	cloud := &Cloud{
+		routeTableRepo: mockRTRepo,
+		Config: config.Config{
+			RouteTableResourceGroup: "foo",
+			RouteTableName:          "bar",
+			Location:                "location",
+			RouteTables: []config.RouteTableConfiguration{
+				{Name: "rt1", SubnetName: "subnet1"},
+			},
+		},
+		nodeNames: utilsets.NewString(),
+	}
+	updater := newDelayedRouteUpdater(cloud, 100*time.Millisecond).(*delayedRouteUpdater)
+
+	route1 := &armnetwork.Route{
+		Name: ptr.To("node1"),
+		Properties: &armnetwork.RoutePropertiesFormat{
+			AddressPrefix:    ptr.To("10.244.0.0/24"),
+			NextHopIPAddress: ptr.To("10.0.0.4"),
+		},
+	}
+	route2 := &armnetwork.Route{
+		Name: ptr.To("node2"),
+		Properties: &armnetwork.RoutePropertiesFormat{
+			AddressPrefix:    ptr.To("10.244.1.0/24"),
+			NextHopIPAddress: ptr.To("10.0.0.5"),
+		},
+	}
+	mockRTRepo.EXPECT().Get(gomock.Any(), "bar", gomock.Any()).Return(&armnetwork.RouteTable{
+		Name:       ptr.To("bar"),
+		Properties: &armnetwork.RouteTablePropertiesFormat{},
+	}, nil)
+	mockRTRepo.EXPECT().Get(gomock.Any(), "rt1", gomock.Any()).Return(nil, nil)
+	mockRTRepo.EXPECT().CreateOrUpdate(gomock.Any(), armnetwork.RouteTable{
+		Name:       ptr.To("rt1"),
+		Location:   ptr.To("location"),
+		Properties: &armnetwork.RouteTablePropertiesFormat{},
+	}).Return(nil, nil)
+	mockRTRepo.EXPECT().Get(gomock.Any(), "rt1", gomock.Any()).Return(&armnetwork.RouteTable{
+		Name:       ptr.To("rt1"),
+		Properties: &armnetwork.RouteTablePropertiesFormat{},
+	}, nil)
+	mockRTRepo.EXPECT().CreateOrUpdate(gomock.Any(), armnetwork.RouteTable{
+		Name:       ptr.To("bar"),
+		Properties: &armnetwork.RouteTablePropertiesFormat{Routes: []*armnetwork.Route{route1}},
+	}).Return(nil, nil)
+	mockRTRepo.EXPECT().CreateOrUpdate(gomock.Any(), armnetwork.RouteTable{
+		Name:       ptr.To("rt1"),
+		Properties: &armnetwork.RouteTablePropertiesFormat{Routes: []*armnetwork.Route{route2}},
+	}).Return(nil, nil)
+
+	op1 := updater.addOperation(getAddRouteOperation("bar", "", route1))
+	op2 := updater.addOperation(getAddRouteOperation("rt1", "", route2))
+	go updater.updateRoutes(context.Background())
+	assert.NoError(t, op1.wait().err)
+	assert.NoError(t, op2.wait().err)
+}
+
+func TestListRoutesMultipleRouteTables(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRTRepo := routetable.NewMockRepository(ctrl)
+	cloud := &Cloud{
+		routeTableRepo: mockRTRepo,
+		Config: config.Config{
+			RouteTableResourceGroup: "foo",
+			RouteTableName:          "bar",
+			Location:                "location",
+			RouteTables: []config.RouteTableConfiguration{
+				{Name: "rt1", SubnetName: "subnet1"},
+				{Name: "rt2", SubnetName: "subnet2"},
+			},
+		},
+		unmanagedNodes:     utilsets.NewString(),
+		nodeInformerSynced: func() bool { return true },
+	}
+
+	newRouteTable := func(name string, routeNames ...string) *armnetwork.RouteTable {
+		routeTable := &armnetwork.RouteTable{
+			Name:       ptr.To(name),
+			Properties: &armnetwork.RouteTablePropertiesFormat{},
+		}
+		for i, routeName := range routeNames {
+			routeTable.Properties.Routes = append(routeTable.Properties.Routes, &armnetwork.Route{
+				Name: ptr.To(routeName),
+				Properties: &armnetwork.RoutePropertiesFormat{
+					AddressPrefix: ptr.To(fmt.Sprintf("10.244.%d.0/24", i)),
+				},
+			})
+		}
+		return routeTable
+	}
+	mockRTRepo.EXPECT().Get(gomock.Any(), "bar", gomock.Any()).Return(newRouteTable("bar", "node1"), nil)
+	mockRTRepo.EXPECT().Get(gomock.Any(), "rt1", gomock.Any()).Return(newRouteTable("rt1", "node2", "node3"), nil)
+	mockRTRepo.EXPECT().Get(gomock.Any(), "rt2", gomock.Any()).Return(nil, nil)
+
+	routes, err := cloud.ListRoutes(context.Background(), "cluster")
+	assert.NoError(t, err)
+	assert.Equal(t, []*cloudprovider.Route{
+		{Name: "node1", TargetNode: "node1", DestinationCIDR: "10.244.0.0/24"},
+		{Name: "node2", TargetNode: "node2", DestinationCIDR: "10.244.0.0/24"},
+		{Name: "node3", TargetNode: "node3", DestinationCIDR: "10.244.1.0/24"},
+	}, routes)
+}
+
+func TestDeleteRouteMultipleRouteTables(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRTRepo := routetable.NewMockRepository(ctrl)
+	mockVMSet := NewMockVMSet(ctrl)
+	cloud := &Cloud{
+		routeTableRepo: mockRTRepo,
+		VMSet:          mockVMSet,
+		Config: config.Config{
+			RouteTableResourceGroup: "foo",
+			RouteTableName:          "bar",
+			Location:                "location",
+			RouteTables: []config.RouteTableConfiguration{
+				{Name: "rt1", SubnetName: "subnet1"},
+				{Name: "rt2", SubnetName: "subnet2"},
+			},
+		},
+		unmanagedNodes:     utilsets.NewString(),
+		nodeNames:          utilsets.NewString(),
+		nodeInformerSynced: func() bool { return true },
+	}
+	cloud.routeUpdater = newDelayedRouteUpdater(cloud, 100*time.Millisecond)
+	go cloud.routeUpdater.run(context.Background())
+
+	// the node is deleted, so its route is deleted from the route tables containing it.
+	mockVMSet.EXPECT().GetPrimaryInterface(gomock.Any(), "node").Return(network.Interface{}, cloudprovider.InstanceNotFound)
+	mockRTRepo.EXPECT().Get(gomock.Any(), "bar", gomock.Any()).Return(&armnetwork.RouteTable{
+		Name:       ptr.To("bar"),
+		Properties: &armnetwork.RouteTablePropertiesFormat{},
+	}, nil)
+	mockRTRepo.EXPECT().Get(gomock.Any(), "rt1", gomock.Any()).Return(&armnetwork.RouteTable{
+		Name: ptr.To("rt1"),
+		Properties: &armnetwork.RouteTablePropertiesFormat{
+			Routes: []*armnetwork.Route{{Name: ptr.To("node")}, {Name: ptr.To("node1")}},
+		},
+	}, nil).Times(2)
+	mockRTRepo.EXPECT().Get(gomock.Any(), "rt2", gomock.Any()).Return(nil, nil)
+	mockRTRepo.EXPECT().CreateOrUpdate(gomock.Any(), armnetwork.RouteTable{
+		Name: ptr.To("rt1"),
+		Properties: &armnetwork.RouteTablePropertiesFormat{
+			Routes: []*armnetwork.Route{{Name: ptr.To("node1")}},
+		},
+	}).Return(nil, nil)
+
+	err := cloud.DeleteRoute(context.Background(), "cluster", &cloudprovider.Route{
+		TargetNode:      "node",
+		DestinationCIDR: "10.244.0.0/24",
+	})
+	assert.NoError(t, err)
+}
@@ -53,6 +53,7 @@ var (
 	nicIDRE            = regexp.MustCompile(`(?i)/subscriptions/(?:.*)/resourceGroups/(.+)/providers/Microsoft.Network/networkInterfaces/(.+)/ipConfigurations/(?:.*)`)
 	vmIDRE             = regexp.MustCompile(`(?i)/subscriptions/(?:.*)/resourceGroups/(?:.*)/providers/Microsoft.Compute/virtualMachines/(.+)`)
 	vmasIDRE           = regexp.MustCompile(`/subscriptions/(?:.*)/resourceGroups/(?:.*)/providers/Microsoft.Compute/availabilitySets/(.+)`)
+	frontendIPConfigRE = regexp.MustCompile(`(?i)^/subscriptions/[^/]+/resourceGroups/[^/]+/providers/Microsoft.Network/loadBalancers/[^/]+/frontendIPConfigurations/[^/]+$`)
 )
 
 // returns the full identifier of an availabilitySet
@@ -903,8 +904,9 @@ func (as *availabilitySet) EnsureHostInPool(ctx context.Context, service *v1.Ser
 			break
 		}
 	}
-	if !foundPool {
-		if as.UseStandardLoadBalancer() && len(newBackendPools) > 0 {
+	asgID := as.getApplicationSecurityGroupIDForBackendPool(backendPoolID)
+	if !foundPool || !isInterfaceIPConfigInApplicationSecurityGroup(primaryIPConfig, asgID) {
+		if !foundPool && as.UseStandardLoadBalancer() && len(newBackendPools) > 0 {
 			// Although standard load balancer supports backends from multiple availability
 			// sets, the same network interface couldn't be added to more than one load balancer of
 			// the same type. Omit those nodes (e.g. masters) so Azure ARM won't complain
@@ -925,12 +927,15 @@ func (as *availabilitySet) EnsureHostInPool(ctx context.Context, service *v1.Ser
 			}
 		}
 
-		newBackendPools = append(newBackendPools,
-			network.BackendAddressPool{
-				ID: ptr.To(backendPoolID),
-			})
+		if !foundPool {
+			newBackendPools = append(newBackendPools,
+				network.BackendAddressPool{
+					ID: ptr.To(backendPoolID),
+				})
 
-		primaryIPConfig.LoadBalancerBackendAddressPools = &newBackendPools
+			primaryIPConfig.LoadBalancerBackendAddressPools = &newBackendPools
+		}
+		addApplicationSecurityGroupToInterfaceIPConfig(primaryIPConfig, asgID)
 
 		nicName := *nic.Name
 		klog.V(3).Infof("nicupdate(%s): nic(%s) - updating", serviceName, nicName)
@@ -1096,6 +1101,7 @@ func (as *availabilitySet) EnsureBackendPoolDeleted(ctx context.Context, service
 				}
 				newIPConfigs[j].LoadBalancerBackendAddressPools = &newLBAddressPools
 			}
+			removeApplicationSecurityGroupsFromInterfaceIPConfig(&newIPConfigs[j], backendPoolIDs)
 		}
 		nic.IPConfigurations = &newIPConfigs
 		nicUpdaters = append(nicUpdaters, func() error {
@@ -1652,6 +1652,75 @@ func TestStandardEnsureBackendPoolDeleted(t *testing.T) {
 	}
 }
 
+func TestStandardEnsureBackendPoolDeletedFromApplicationSecurityGroup(t *testing.T) {
+	service := getTestService("test", v1.ProtocolTCP, nil, false, 80)
+	lbPrefix := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/"
+	asgPrefix := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/applicationSecurityGroups/"
+	backendPoolID := lbPrefix + "kubernetes/backendAddressPools/kubernetes"
+	backendAddressPools := &[]network.BackendAddressPool{
+		{
+			ID: ptr.To(backendPoolID),
+			BackendAddressPoolPropertiesFormat: &network.BackendAddressPoolPropertiesFormat{
+				BackendIPConfigurations: &[]network.InterfaceIPConfiguration{
+					{
+						ID: ptr.To("/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/networkInterfaces/k8s-agentpool1-00000000-nic-1/ipConfigurations/ipconfig1"),
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range []struct {
+		desc         string
+		backendPools []string
+		expectedASGs []network.ApplicationSecurityGroup
+	}{
+		{
+			desc:         "the nic leaves the application security group of the load balancer with the backend pool",
+			backendPools: []string{backendPoolID, lbPrefix + "other/backendAddressPools/other"},
+			expectedASGs: []network.ApplicationSecurityGroup{{ID: ptr.To(asgPrefix + "other")}, {ID: ptr.To(asgPrefix + "user")}},
+		},
+		{
+			desc:         "the nic stays in the application security group if it is in another backend pool of the load balancer",
+			backendPools: []string{backendPoolID, lbPrefix + "kubernetes/backendAddressPools/kubernetes-ipv6"},
+			expectedASGs: []network.ApplicationSecurityGroup{{ID: ptr.To(asgPrefix + "kubernetes")}, {ID: ptr.To(asgPrefix + "other")}, {ID: ptr.To(asgPrefix + "user")}},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			cloud := GetTestCloud(ctrl)
+			existingVM := buildDefaultTestVirtualMachine("/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Compute/availabilitySets/as", []string{
+				"/subscriptions/subscription/resourceGroups/rg/providers/Microsoft.Network/networkInterfaces/k8s-agentpool1-00000000-nic-1",
+			})
+			existingNIC := buildDefaultTestInterface(true, tc.backendPools)
+			existingNIC.Name = ptr.To("k8s-agentpool1-00000000-nic-1")
+			existingNIC.VirtualMachine = &network.SubResource{
+				ID: ptr.To("/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/k8s-agentpool1-00000000-1"),
+			}
+			(*existingNIC.IPConfigurations)[0].ApplicationSecurityGroups = &[]network.ApplicationSecurityGroup{
+				{ID: ptr.To(asgPrefix + "kubernetes")}, {ID: ptr.To(asgPrefix + "other")}, {ID: ptr.To(asgPrefix + "user")},
+			}
+
+			mockVMClient := mockvmclient.NewMockInterface(ctrl)
+			mockVMClient.EXPECT().Get(gomock.Any(), cloud.ResourceGroup, "k8s-agentpool1-00000000-1", gomock.Any()).Return(existingVM, nil)
+			cloud.VirtualMachinesClient = mockVMClient
+			mockNICClient := mockinterfaceclient.NewMockInterface(ctrl)
+			mockNICClient.EXPECT().Get(gomock.Any(), "rg", "k8s-agentpool1-00000000-nic-1", gomock.Any()).Return(existingNIC, nil).Times(2)
+			mockNICClient.EXPECT().CreateOrUpdate(gomock.Any(), "rg", "k8s-agentpool1-00000000-nic-1", gomock.Any()).DoAndReturn(
+				func(_ context.Context, _, _ string, nic network.Interface) *retry.Error {
+					assert.Equal(t, tc.expectedASGs, *(*nic.IPConfigurations)[0].ApplicationSecurityGroups)
+					return nil
+				})
+			cloud.InterfacesClient = mockNICClient
+
+			nicUpdated, err := cloud.VMSet.EnsureBackendPoolDeleted(context.TODO(), &service, []string{backendPoolID}, "AS", backendAddressPools, true)
+			assert.NoError(t, err)
+			assert.True(t, nicUpdated)
+		})
+	}
+}
+
 func buildDefaultTestInterface(isPrimary bool, lbBackendpoolIDs []string) network.Interface {
 	expectedNIC := network.Interface{
 		InterfacePropertiesFormat: &network.InterfacePropertiesFormat{
@@ -38,7 +38,7 @@ func (az *Cloud) CreateOrUpdateSubnet(service *v1.Service, subnet network.Subnet
 	klog.V(10).Infof("SubnetClient.CreateOrUpdate(%s): end", *subnet.Name)
 	if rerr != nil {
 		klog.Errorf("SubnetClient.CreateOrUpdate(%s) failed: %s", *subnet.Name, rerr.Error().Error())
-		az.Event(service, v1.EventTypeWarning, "CreateOrUpdateSubnet", rerr.Error().Error())
+		az.Event(ctx, service, v1.EventTypeWarning, "CreateOrUpdateSubnet", rerr.Error().Error())
 		return rerr.Error()
 	}
 
@@ -673,6 +673,7 @@ func testLoadBalancerServicesSpecifiedSelection(t *testing.T, isInternal bool) {
 		expectedPLS := make([]*armnetwork.PrivateLinkService, 0)
 		mockPLSRepo := privatelinkservice.NewMockRepository(ctrl)
 		mockPLSRepo.EXPECT().List(gomock.Any(), az.Config.ResourceGroup).Return(expectedPLS, nil).MinTimes(1).MaxTimes(1)
+		mockPLSRepo.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(&armnetwork.PrivateLinkService{ID: to.Ptr(consts.PrivateLinkServiceNotExistID)}, nil).AnyTimes()
 		az.plsRepo = mockPLSRepo
 
 		lbStatus, err := az.EnsureLoadBalancer(context.TODO(), testClusterName, &svc, clusterResources.nodes)
@@ -732,6 +733,7 @@ func testLoadBalancerMaxRulesServices(t *testing.T, isInternal bool) {
 		expectedPLS := make([]*armnetwork.PrivateLinkService, 0)
 		mockPLSRepo := privatelinkservice.NewMockRepository(ctrl)
 		mockPLSRepo.EXPECT().List(gomock.Any(), az.Config.ResourceGroup).Return(expectedPLS, nil).MinTimes(1).MaxTimes(1)
+		mockPLSRepo.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(&armnetwork.PrivateLinkService{ID: to.Ptr(consts.PrivateLinkServiceNotExistID)}, nil).AnyTimes()
 		az.plsRepo = mockPLSRepo
 
 		lbStatus, err := az.EnsureLoadBalancer(context.TODO(), testClusterName, &svc, clusterResources.nodes)
@@ -2819,6 +2821,15 @@ func TestInitializeCloudFromConfig(t *testing.T) {
 	err = az.InitializeCloudFromConfig(context.Background(), &azureconfig, false, true)
 	assert.NoError(t, err)
 	assert.Equal(t, az.Config.LoadBalancerBackendPoolConfigurationType, consts.LoadBalancerBackendPoolConfigurationTypeNodeIPConfiguration)
+
+	azureconfig = config.Config{
+		LoadBalancerBackendPoolConfigurationType: consts.LoadBalancerBackendPoolConfigurationTypePODIP,
+	}
+	err = az.InitializeCloudFromConfig(context.Background(), &azureconfig, false, true)
+	assert.NoError(t, err)
+	assert.Equal(t, az.Config.LoadBalancerBackendPoolConfigurationType, consts.LoadBalancerBackendPoolConfigurationTypePODIP)
+	_, ok := az.LoadBalancerBackendPool.(*backendPoolTypePodIP)
+	assert.True(t, ok)
 }
 
 func TestSetLBDefaults(t *testing.T) {
@@ -2899,6 +2910,45 @@ func TestCheckEnableMultipleStandardLoadBalancers(t *testing.T) {
 
 	err = az.checkEnableMultipleStandardLoadBalancers()
 	assert.Equal(t, "duplicated primary VMSet vmss-2 in multiple standard load balancer configurations lb2", err.Error())
+
+	az.MultipleStandardLoadBalancerConfigurations = []config.MultipleStandardLoadBalancerConfiguration{
+		{
+			Name: "kubernetes",
+			MultipleStandardLoadBalancerConfigurationSpec: config.MultipleStandardLoadBalancerConfigurationSpec{
+				PrimaryVMSet: "vmss-0",
+				AutoScale:    &config.MultipleStandardLoadBalancerAutoScale{MaxCount: 0},
+			},
+		},
+	}
+
+	err = az.checkEnableMultipleStandardLoadBalancers()
+	assert.Equal(t, "multiple standard load balancer configuration kubernetes must have positive auto scale max count", err.Error())
+
+	az.MultipleStandardLoadBalancerConfigurations[0].AutoScale = &config.MultipleStandardLoadBalancerAutoScale{NameTemplate: "{name}", MaxCount: 2}
+	err = az.checkEnableMultipleStandardLoadBalancers()
+	assert.Equal(t, "the auto scale name template of multiple standard load balancer configuration kubernetes must contain {index}", err.Error())
+
+	az.MultipleStandardLoadBalancerConfigurations = []config.MultipleStandardLoadBalancerConfiguration{
+		{
+			Name: "kubernetes",
+			MultipleStandardLoadBalancerConfigurationSpec: config.MultipleStandardLoadBalancerConfigurationSpec{
+				PrimaryVMSet: "vmss-0",
+				AutoScale:    &config.MultipleStandardLoadBalancerAutoScale{NameTemplate: "lb{index}", MaxCount: 2},
+			},
+		},
+		{
+			Name: "lb2",
+			MultipleStandardLoadBalancerConfigurationSpec: config.MultipleStandardLoadBalancerConfigurationSpec{
+				PrimaryVMSet: "vmss-2",
+			},
+		},
+	}
+
+	err = az.checkEnableMultipleStandardLoadBalancers()
+	assert.Equal(t, "the auto scaled load balancer name lb2 of multiple standard load balancer configuration kubernetes conflicts with another load balancer", err.Error())
+
+	az.MultipleStandardLoadBalancerConfigurations[0].AutoScale.MaxCount = 1
+	assert.NoError(t, az.checkEnableMultipleStandardLoadBalancers())
 }
 
 func TestIsNodeReady(t *testing.T) {
@@ -1111,12 +1111,14 @@ func (ss *ScaleSet) EnsureHostInPool(ctx context.Context, _ *v1.Service, nodeNam
 		}
 	}
 
-	// The backendPoolID has already been found from existing LoadBalancerBackendAddressPools.
-	if foundPool {
+	// The backendPoolID has already been found from existing LoadBalancerBackendAddressPools,
+	// and the primary IP configuration has joined the application security group if needed.
+	asgID := ss.getApplicationSecurityGroupIDForBackendPool(backendPoolID)
+	if foundPool && isVMSSIPConfigInApplicationSecurityGroup(primaryIPConfiguration, asgID) {
 		return "", "", "", nil, nil
 	}
 
-	if ss.UseStandardLoadBalancer() && len(newBackendPools) > 0 {
+	if !foundPool && ss.UseStandardLoadBalancer() && len(newBackendPools) > 0 {
 		// Although standard load balancer supports backends from multiple scale
 		// sets, the same network interface couldn't be added to more than one load balancer of
 		// the same type. Omit those nodes (e.g. masters) so Azure ARM won't complain
@@ -1138,11 +1140,14 @@ func (ss *ScaleSet) EnsureHostInPool(ctx context.Context, _ *v1.Service, nodeNam
 	}
 
 	// Compose a new vmssVM with added backendPoolID.
-	newBackendPools = append(newBackendPools,
-		compute.SubResource{
-			ID: ptr.To(backendPoolID),
-		})
-	primaryIPConfiguration.LoadBalancerBackendAddressPools = &newBackendPools
+	if !foundPool {
+		newBackendPools = append(newBackendPools,
+			compute.SubResource{
+				ID: ptr.To(backendPoolID),
+			})
+		primaryIPConfiguration.LoadBalancerBackendAddressPools = &newBackendPools
+	}
+	addApplicationSecurityGroupToVMSSIPConfig(primaryIPConfiguration, asgID)
 	newVM := &compute.VirtualMachineScaleSetVM{
 		Location: &vm.Location,
 		VirtualMachineScaleSetVMProperties: &compute.VirtualMachineScaleSetVMProperties{
@@ -1279,11 +1284,12 @@ func (ss *ScaleSet) ensureVMSSInPool(ctx context.Context, _ *v1.Service, nodes [
 				break
 			}
 		}
-		if found {
+		asgID := ss.getApplicationSecurityGroupIDForBackendPool(backendPoolID)
+		if found && isVMSSIPConfigInApplicationSecurityGroup(primaryIPConfig, asgID) {
 			continue
 		}
 
-		if ss.UseStandardLoadBalancer() && len(loadBalancerBackendAddressPools) > 0 {
+		if !found && ss.UseStandardLoadBalancer() && len(loadBalancerBackendAddressPools) > 0 {
 			// Although standard load balancer supports backends from multiple scale
 			// sets, the same network interface couldn't be added to more than one load balancer of
 			// the same type. Omit those nodes (e.g. masters) so Azure ARM won't complain
@@ -1305,11 +1311,14 @@ func (ss *ScaleSet) ensureVMSSInPool(ctx context.Context, _ *v1.Service, nodes [
 		}
 
 		// Compose a new vmss with added backendPoolID.
-		loadBalancerBackendAddressPools = append(loadBalancerBackendAddressPools,
-			compute.SubResource{
-				ID: ptr.To(backendPoolID),
-			})
-		primaryIPConfig.LoadBalancerBackendAddressPools = &loadBalancerBackendAddressPools
+		if !found {
+			loadBalancerBackendAddressPools = append(loadBalancerBackendAddressPools,
+				compute.SubResource{
+					ID: ptr.To(backendPoolID),
+				})
+			primaryIPConfig.LoadBalancerBackendAddressPools = &loadBalancerBackendAddressPools
+		}
+		addApplicationSecurityGroupToVMSSIPConfig(primaryIPConfig, asgID)
 		newVMSS := compute.VirtualMachineScaleSet{
 			Location: vmss.Location,
 			VirtualMachineScaleSetProperties: &compute.VirtualMachineScaleSetProperties{
@@ -2141,6 +2150,7 @@ func deleteBackendPoolFromIPConfig(msg, backendPoolID, resource string, primaryN
 		return false, nil
 	}
 	primaryIPConfig.LoadBalancerBackendAddressPools = &newBackendPools
+	removeApplicationSecurityGroupsFromVMSSIPConfig(primaryIPConfig, []string{backendPoolID})
 	return true, nil
 }
 
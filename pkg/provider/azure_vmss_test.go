@@ -2882,6 +2882,68 @@ func TestEnsureBackendPoolDeletedFromVMSS(t *testing.T) {
 	}
 }
 
+func TestEnsureBackendPoolDeletedFromApplicationSecurityGroupVMSS(t *testing.T) {
+	lbASGID := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/applicationSecurityGroups/lb"
+	userASGID := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/applicationSecurityGroups/user"
+
+	t.Run("the VMSS leaves the application security group of the load balancer with the backend pool", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ss, err := NewTestScaleSet(ctrl)
+		assert.NoError(t, err)
+		ss.LoadBalancerSku = consts.LoadBalancerSkuStandard
+
+		expectedVMSS := buildTestVMSSWithLB(testVMSSName, "vmss-vm-", []string{testLBBackendpoolID0}, false)
+		ipConfigs := *(*expectedVMSS.VirtualMachineProfile.NetworkProfile.NetworkInterfaceConfigurations)[0].IPConfigurations
+		ipConfigs[0].ApplicationSecurityGroups = &[]compute.SubResource{{ID: ptr.To(lbASGID)}, {ID: ptr.To(userASGID)}}
+		mockVMSSClient := ss.VirtualMachineScaleSetsClient.(*mockvmssclient.MockInterface)
+		mockVMSSClient.EXPECT().List(gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
+		mockVMSSClient.EXPECT().Get(gomock.Any(), ss.ResourceGroup, testVMSSName).Return(expectedVMSS, nil)
+		mockVMSSClient.EXPECT().CreateOrUpdate(gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).DoAndReturn(
+			func(_ context.Context, _, _ string, vmss compute.VirtualMachineScaleSet) *retry.Error {
+				ipConfig := (*(*vmss.VirtualMachineProfile.NetworkProfile.NetworkInterfaceConfigurations)[0].IPConfigurations)[0]
+				assert.Empty(t, *ipConfig.LoadBalancerBackendAddressPools)
+				assert.Equal(t, []compute.SubResource{{ID: ptr.To(userASGID)}}, *ipConfig.ApplicationSecurityGroups)
+				return nil
+			})
+		expectedVMSSVMs, _, _ := buildTestVirtualMachineEnv(ss.Cloud, testVMSSName, "", 0, []string{"vmss-vm-000000"}, "", false)
+		mockVMSSVMClient := ss.VirtualMachineScaleSetVMsClient.(*mockvmssvmclient.MockInterface)
+		mockVMSSVMClient.EXPECT().List(gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
+
+		assert.NoError(t, ss.ensureBackendPoolDeletedFromVMSS(context.TODO(), []string{testLBBackendpoolID0}, testVMSSName))
+	})
+
+	t.Run("the VMSS VM leaves the application security group of the load balancer with the backend pools", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ss, err := NewTestScaleSet(ctrl)
+		assert.NoError(t, err)
+
+		expectedVMSS := buildTestVMSS(testVMSSName, "vmss-vm-")
+		mockVMSSClient := ss.VirtualMachineScaleSetsClient.(*mockvmssclient.MockInterface)
+		mockVMSSClient.EXPECT().List(gomock.Any(), ss.ResourceGroup).Return([]compute.VirtualMachineScaleSet{expectedVMSS}, nil).AnyTimes()
+		expectedVMSSVMs, _, _ := buildTestVirtualMachineEnv(ss.Cloud, testVMSSName, "", 0, []string{"vmss-vm-000000"}, "", true)
+		for _, ipConfig := range *(*expectedVMSSVMs[0].NetworkProfileConfiguration.NetworkInterfaceConfigurations)[0].IPConfigurations {
+			if ptr.Deref(ipConfig.Primary, false) {
+				ipConfig.ApplicationSecurityGroups = &[]compute.SubResource{{ID: ptr.To(lbASGID)}, {ID: ptr.To(userASGID)}}
+			}
+		}
+		mockVMSSVMClient := ss.VirtualMachineScaleSetVMsClient.(*mockvmssvmclient.MockInterface)
+		mockVMSSVMClient.EXPECT().List(gomock.Any(), ss.ResourceGroup, testVMSSName, gomock.Any()).Return(expectedVMSSVMs, nil).AnyTimes()
+
+		// The VM stays in the application security group while it is in another backend pool of the load balancer.
+		_, _, _, vm, err := ss.ensureBackendPoolDeletedFromNode(context.TODO(), "vmss-vm-000000", []string{testLBBackendpoolID0v6})
+		assert.NoError(t, err)
+		primaryIPConfig := (*(*vm.NetworkProfileConfiguration.NetworkInterfaceConfigurations)[0].IPConfigurations)[0]
+		assert.Equal(t, []compute.SubResource{{ID: ptr.To(lbASGID)}, {ID: ptr.To(userASGID)}}, *primaryIPConfig.ApplicationSecurityGroups)
+
+		_, _, _, vm, err = ss.ensureBackendPoolDeletedFromNode(context.TODO(), "vmss-vm-000000", []string{testLBBackendpoolID0, testLBBackendpoolID0v6})
+		assert.NoError(t, err)
+		primaryIPConfig = (*(*vm.NetworkProfileConfiguration.NetworkInterfaceConfigurations)[0].IPConfigurations)[0]
+		assert.Equal(t, []compute.SubResource{{ID: ptr.To(userASGID)}}, *primaryIPConfig.ApplicationSecurityGroups)
+	})
+}
+
 func TestEnsureBackendPoolDeleted(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -504,12 +504,14 @@ func (fs *FlexScaleSet) EnsureHostInPool(ctx context.Context, service *v1.Servic
 			break
 		}
 	}
-	// The backendPoolID has already been found from existing LoadBalancerBackendAddressPools.
-	if foundPool {
+	// The backendPoolID has already been found from existing LoadBalancerBackendAddressPools,
+	// and the primary IP configuration has joined the application security group if needed.
+	asgID := fs.getApplicationSecurityGroupIDForBackendPool(backendPoolID)
+	if foundPool && isInterfaceIPConfigInApplicationSecurityGroup(primaryIPConfig, asgID) {
 		return "", "", "", nil, nil
 	}
 
-	if fs.UseStandardLoadBalancer() && len(newBackendPools) > 0 {
+	if !foundPool && fs.UseStandardLoadBalancer() && len(newBackendPools) > 0 {
 		// Although standard load balancer supports backends from multiple availability
 		// sets, the same network interface couldn't be added to more than one load balancer of
 		// the same type. Omit those nodes (e.g. masters) so Azure ARM won't complain
@@ -530,12 +532,15 @@ func (fs *FlexScaleSet) EnsureHostInPool(ctx context.Context, service *v1.Servic
 		}
 	}
 
-	newBackendPools = append(newBackendPools,
-		network.BackendAddressPool{
-			ID: ptr.To(backendPoolID),
-		})
+	if !foundPool {
+		newBackendPools = append(newBackendPools,
+			network.BackendAddressPool{
+				ID: ptr.To(backendPoolID),
+			})
 
-	primaryIPConfig.LoadBalancerBackendAddressPools = &newBackendPools
+		primaryIPConfig.LoadBalancerBackendAddressPools = &newBackendPools
+	}
+	addApplicationSecurityGroupToInterfaceIPConfig(primaryIPConfig, asgID)
 
 	nicName := *nic.Name
 	klog.V(3).Infof("nicupdate(%s): nic(%s) - updating", serviceName, nicName)
@@ -646,11 +651,12 @@ func (fs *FlexScaleSet) ensureVMSSFlexInPool(ctx context.Context, _ *v1.Service,
 				break
 			}
 		}
-		if found {
+		asgID := fs.getApplicationSecurityGroupIDForBackendPool(backendPoolID)
+		if found && isVMSSIPConfigInApplicationSecurityGroup(primaryIPConfig, asgID) {
 			continue
 		}
 
-		if fs.UseStandardLoadBalancer() && len(loadBalancerBackendAddressPools) > 0 {
+		if !found && fs.UseStandardLoadBalancer() && len(loadBalancerBackendAddressPools) > 0 {
 			// Although standard load balancer supports backends from multiple scale
 			// sets, the same network interface couldn't be added to more than one load balancer of
 			// the same type. Omit those nodes (e.g. masters) so Azure ARM won't complain
@@ -672,11 +678,14 @@ func (fs *FlexScaleSet) ensureVMSSFlexInPool(ctx context.Context, _ *v1.Service,
 		}
 
 		// Compose a new vmss with added backendPoolID.
-		loadBalancerBackendAddressPools = append(loadBalancerBackendAddressPools,
-			compute.SubResource{
-				ID: ptr.To(backendPoolID),
-			})
-		primaryIPConfig.LoadBalancerBackendAddressPools = &loadBalancerBackendAddressPools
+		if !found {
+			loadBalancerBackendAddressPools = append(loadBalancerBackendAddressPools,
+				compute.SubResource{
+					ID: ptr.To(backendPoolID),
+				})
+			primaryIPConfig.LoadBalancerBackendAddressPools = &loadBalancerBackendAddressPools
+		}
+		addApplicationSecurityGroupToVMSSIPConfig(primaryIPConfig, asgID)
 		newVMSS := compute.VirtualMachineScaleSet{
 			Location: vmssFlex.Location,
 			VirtualMachineScaleSetProperties: &compute.VirtualMachineScaleSetProperties{
@@ -987,6 +996,7 @@ func (fs *FlexScaleSet) ensureBackendPoolDeletedFromNode(ctx context.Context, vm
 				}
 				newIPConfigs[j].LoadBalancerBackendAddressPools = &newLBAddressPools
 			}
+			removeApplicationSecurityGroupsFromInterfaceIPConfig(&newIPConfigs[j], backendPoolIDs)
 		}
 		nic.IPConfigurations = &newIPConfigs
 
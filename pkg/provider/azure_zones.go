@@ -87,6 +87,11 @@ func (az *Cloud) getRegionZonesBackoff(ctx context.Context, region string) ([]st
 		return az.regionZonesMap[region], nil
 	}
 
+	if p := getServicePlanner(ctx); p != nil {
+		p.addNote("the zones of the region %s are not known, the zones of new resources are resolved on creation", region)
+		return nil, nil
+	}
+
 	klog.V(2).Infof("getRegionZonesMapWrapper: the region-zones map is not initialized successfully, retrying immediately")
 
 	var (
@@ -60,6 +60,15 @@ type Config struct {
 	RouteTableName string `json:"routeTableName,omitempty" yaml:"routeTableName,omitempty"`
 	// The name of the resource group that the RouteTable is deployed in
 	RouteTableResourceGroup string `json:"routeTableResourceGroup,omitempty" yaml:"routeTableResourceGroup,omitempty"`
+	// (Optional) The additional route tables of the nodes. A node uses the first route table that it matches,
+	// or RouteTableName if it matches none of them. All route tables are in RouteTableResourceGroup.
+	RouteTables []RouteTableConfiguration `json:"routeTables,omitempty" yaml:"routeTables,omitempty"`
+	// RouteReconcileMode determines how the routes in the route tables are compared with the nodes when listing the routes.
+	// Supported values are `none`, `report` and `repair`.
+	// `none`: the routes are not compared (default).
+	// `report`: the drifted routes of the nodes, e.g. with a changed next hop IP, and the route table utilization are reported by metrics.
+	// `repair`: the drifted routes of the nodes are also restored. The routes owned by others are never changed in any mode.
+	RouteReconcileMode string `json:"routeReconcileMode,omitempty" yaml:"routeReconcileMode,omitempty"`
 	// (Optional) The name of the availability set that should be used as the load balancer backend
 	// If this is set, the Azure cloudprovider will only add nodes from that availability set to the load
 	// balancer backend pool. If this is not set, and multiple agent pools (availability sets) are used, then
@@ -126,6 +135,11 @@ type Config struct {
 	// DisableOutboundSNAT disables the outbound SNAT for public load balancer rules.
 	// It should only be set when loadBalancerSku is standard. If not set, it will be default to false.
 	DisableOutboundSNAT *bool `json:"disableOutboundSNAT,omitempty" yaml:"disableOutboundSNAT,omitempty"`
+	// OwnServicesWithoutLoadBalancerClass determines if the Azure cloud provider owns the LoadBalancer services
+	// without loadBalancerClass, so that another controller can own them instead. The services with a
+	// loadBalancerClass are never owned, because the service controller does not reconcile them.
+	// If not set, it will be default to true.
+	OwnServicesWithoutLoadBalancerClass *bool `json:"ownServicesWithoutLoadBalancerClass,omitempty" yaml:"ownServicesWithoutLoadBalancerClass,omitempty"`
 
 	// Maximum allowed LoadBalancer Rule Count is the limit enforced by Azure Load balancer
 	MaximumLoadBalancerRuleCount int `json:"maximumLoadBalancerRuleCount,omitempty" yaml:"maximumLoadBalancerRuleCount,omitempty"`
@@ -134,8 +148,16 @@ type Config struct {
 	// are `nodeIPConfiguration`, `nodeIP` and `podIP`.
 	// `nodeIPConfiguration`: vm network interfaces will be attached to the inbound backend pool of the load balancer (default);
 	// `nodeIP`: vm private IPs will be attached to the inbound backend pool of the load balancer;
-	// `podIP`: pod IPs will be attached to the inbound backend pool of the load balancer (not supported yet).
+	// `podIP`: pod IPs will be attached to the inbound backend pool of the load balancer. Each service has its own backend pool
+	// whose members are taken from the EndpointSlices of the service.
 	LoadBalancerBackendPoolConfigurationType string `json:"loadBalancerBackendPoolConfigurationType,omitempty" yaml:"loadBalancerBackendPoolConfigurationType,omitempty"`
+	// UseApplicationSecurityGroups makes the security rules of the services with floating IP disabled target
+	// an application security group per load balancer instead of the private IP addresses of the backend nodes,
+	// so the security group is not updated when the nodes change. The primary IP configurations of the nodes join
+	// the application security group together with the backend pool of the load balancer and leave it together with
+	// the backend pool, so it can only be used with the `nodeIPConfiguration` backend pool type. The application
+	// security group is deleted with the load balancer.
+	UseApplicationSecurityGroups bool `json:"useApplicationSecurityGroups,omitempty" yaml:"useApplicationSecurityGroups,omitempty"`
 	// PutVMSSVMBatchSize defines how many requests the client send concurrently when putting the VMSS VMs.
 	// If it is smaller than or equal to zero, the request will be sent one by one in sequence (default).
 	PutVMSSVMBatchSize int `json:"putVMSSVMBatchSize" yaml:"putVMSSVMBatchSize"`
@@ -154,10 +176,38 @@ type Config struct {
 	// there must be one configuration named "<clustername>" or an error will be reported.
 	MultipleStandardLoadBalancerConfigurations []MultipleStandardLoadBalancerConfiguration `json:"multipleStandardLoadBalancerConfigurations,omitempty" yaml:"multipleStandardLoadBalancerConfigurations,omitempty"`
 
+	// MaxConcurrentServiceMigrations is the maximum number of services that are being moved off the multiple
+	// standard load balancers with MigrateServices at the same time. Default is 1.
+	MaxConcurrentServiceMigrations int `json:"maxConcurrentServiceMigrations,omitempty" yaml:"maxConcurrentServiceMigrations,omitempty"`
+
 	// RouteUpdateIntervalInSeconds is the interval for updating routes. Default is 30 seconds.
 	RouteUpdateIntervalInSeconds int `json:"routeUpdateIntervalInSeconds,omitempty" yaml:"routeUpdateIntervalInSeconds,omitempty"`
 	// LoadBalancerBackendPoolUpdateIntervalInSeconds is the interval for updating load balancer backend pool of local services. Default is 30 seconds.
 	LoadBalancerBackendPoolUpdateIntervalInSeconds int `json:"loadBalancerBackendPoolUpdateIntervalInSeconds,omitempty" yaml:"loadBalancerBackendPoolUpdateIntervalInSeconds,omitempty"`
+	// DriftDetectionIntervalInSeconds is the interval for comparing the load balancers, public IPs and the security group
+	// with the desired state of the LoadBalancer services. The drift detection is disabled if it is 0.
+	DriftDetectionIntervalInSeconds int `json:"driftDetectionIntervalInSeconds,omitempty" yaml:"driftDetectionIntervalInSeconds,omitempty"`
+	// EnableDriftReconciliation triggers the reconciliation of the services whose Azure resources are drifted.
+	EnableDriftReconciliation bool `json:"enableDriftReconciliation,omitempty" yaml:"enableDriftReconciliation,omitempty"`
+	// OrphanedResourceGCIntervalInSeconds is the interval for finding the public IPs, load balancer frontends, rules and probes,
+	// private link services and security rules whose owning service no longer exists. Only the load balancers of the cluster are
+	// scanned. The garbage collection is disabled if it is 0.
+	OrphanedResourceGCIntervalInSeconds int `json:"orphanedResourceGCIntervalInSeconds,omitempty" yaml:"orphanedResourceGCIntervalInSeconds,omitempty"`
+	// OrphanedResourceGCMode determines what to do with the orphaned resources. Supported values are `report` and `enforce`.
+	// `report`: the orphaned resources are reported by events and metrics only (default).
+	// `enforce`: the orphaned resources are also deleted once they have been orphaned for the grace period.
+	OrphanedResourceGCMode string `json:"orphanedResourceGCMode,omitempty" yaml:"orphanedResourceGCMode,omitempty"`
+	// OrphanedResourceGCGracePeriodInSeconds is how long a resource must stay orphaned before it is deleted in the `enforce` mode. Default is 3600 seconds.
+	OrphanedResourceGCGracePeriodInSeconds int `json:"orphanedResourceGCGracePeriodInSeconds,omitempty" yaml:"orphanedResourceGCGracePeriodInSeconds,omitempty"`
+	// PrivateLinkServiceConnectionApprovalIntervalInSeconds is the interval for approving or rejecting the pending private endpoint
+	// connections to the private link services of the services. The approval is disabled if it is 0.
+	PrivateLinkServiceConnectionApprovalIntervalInSeconds int `json:"privateLinkServiceConnectionApprovalIntervalInSeconds,omitempty" yaml:"privateLinkServiceConnectionApprovalIntervalInSeconds,omitempty"`
+	// PrivateLinkServiceConnectionApprovedSubscriptions are the subscriptions whose pending private endpoint connections to the
+	// private link services of all services are approved.
+	PrivateLinkServiceConnectionApprovedSubscriptions []string `json:"privateLinkServiceConnectionApprovedSubscriptions,omitempty" yaml:"privateLinkServiceConnectionApprovedSubscriptions,omitempty"`
+	// RejectUnapprovedPrivateLinkServiceConnections rejects the pending private endpoint connections that are not approved by
+	// any policy, instead of leaving them pending for the manual approval.
+	RejectUnapprovedPrivateLinkServiceConnections bool `json:"rejectUnapprovedPrivateLinkServiceConnections,omitempty" yaml:"rejectUnapprovedPrivateLinkServiceConnections,omitempty"`
 
 	// ClusterServiceLoadBalancerHealthProbeMode determines the health probe mode for cluster service load balancer.
 	// Supported values are `shared` and `servicenodeport`.
@@ -168,6 +218,24 @@ type Config struct {
 	ClusterServiceSharedLoadBalancerHealthProbePort int32 `json:"clusterServiceSharedLoadBalancerHealthProbePort,omitempty" yaml:"clusterServiceSharedLoadBalancerHealthProbePort,omitempty"`
 	// ClusterServiceSharedLoadBalancerHealthProbePath defines the target path of the shared health probe. Default to `/healthz`.
 	ClusterServiceSharedLoadBalancerHealthProbePath string `json:"clusterServiceSharedLoadBalancerHealthProbePath,omitempty" yaml:"clusterServiceSharedLoadBalancerHealthProbePath,omitempty"`
+	// HealthProbeProxyPort is the port of the health-probe-proxy on the nodes, which translates the gRPC health checks
+	// and the HTTP(S) checks with expected status codes into HTTP probes of the load balancer. The probes that need
+	// the translation are rejected if it is not set. The proxy must run with --enable-probe-translation.
+	HealthProbeProxyPort int32 `json:"healthProbeProxyPort,omitempty" yaml:"healthProbeProxyPort,omitempty"`
+
+	// PublicIPPrefixPool is the pool of public IP prefixes that the new public IPs of the services are allocated
+	// from with the standard load balancer. New prefixes are created when all prefixes are exhausted.
+	PublicIPPrefixPool *PublicIPPrefixPool `json:"publicIPPrefixPool,omitempty" yaml:"publicIPPrefixPool,omitempty"`
+
+	// PublicIPDdosProtectionMode is the default DDoS protection mode of the managed public IPs, which is `Enabled`,
+	// `Disabled` or `VirtualNetworkInherited`. The DDoS settings of the public IPs are not reconciled if it is empty.
+	PublicIPDdosProtectionMode string `json:"publicIPDdosProtectionMode,omitempty" yaml:"publicIPDdosProtectionMode,omitempty"`
+	// PublicIPDdosProtectionPlanID is the resource ID of the default DDoS protection plan of the managed public IPs,
+	// which is only associated when the DDoS protection mode is `Enabled`.
+	PublicIPDdosProtectionPlanID string `json:"publicIPDdosProtectionPlanID,omitempty" yaml:"publicIPDdosProtectionPlanID,omitempty"`
+	// PublicIPRoutingPreference is the default routing preference of the managed public IPs, which is `Internet`
+	// or `Microsoft`. It only applies to the new public IPs.
+	PublicIPRoutingPreference string `json:"publicIPRoutingPreference,omitempty" yaml:"publicIPRoutingPreference,omitempty"`
 }
 
 // HasExtendedLocation returns true if extendedlocation prop are specified.
@@ -183,6 +251,10 @@ func (az *Config) IsLBBackendPoolTypeNodeIP() bool {
 	return strings.EqualFold(az.LoadBalancerBackendPoolConfigurationType, consts.LoadBalancerBackendPoolConfigurationTypeNodeIP)
 }
 
+func (az *Config) IsLBBackendPoolTypePodIP() bool {
+	return strings.EqualFold(az.LoadBalancerBackendPoolConfigurationType, consts.LoadBalancerBackendPoolConfigurationTypePODIP)
+}
+
 func (az *Config) GetPutVMSSVMBatchSize() int {
 	return az.PutVMSSVMBatchSize
 }
@@ -203,6 +275,14 @@ func (az *Config) DisableLoadBalancerOutboundSNAT() bool {
 	return *az.DisableOutboundSNAT
 }
 
+// OwnsLoadBalancerClass checks if the LoadBalancer services with the given loadBalancerClass are owned by the Azure cloud provider.
+func (az *Config) OwnsLoadBalancerClass(loadBalancerClass *string) bool {
+	if loadBalancerClass != nil {
+		return false
+	}
+	return az.OwnServicesWithoutLoadBalancerClass == nil || *az.OwnServicesWithoutLoadBalancerClass
+}
+
 func (az *Config) UseMultipleStandardLoadBalancers() bool {
 	return az.UseStandardLoadBalancer() && len(az.MultipleStandardLoadBalancerConfigurations) > 0
 }
@@ -60,6 +60,30 @@ type MultipleStandardLoadBalancerConfigurationSpec struct {
 	// Nodes matching this selector will be preferentially added to the load balancers that
 	// they match selectors for. NodeSelector does not override primaryAgentPool for node allocation.
 	NodeSelector *metav1.LabelSelector `json:"nodeSelector" yaml:"nodeSelector"`
+
+	// MigrateServices moves the services that are using this load balancer to other eligible load balancers,
+	// at most MaxConcurrentServiceMigrations services at a time. New services are not placed on the load
+	// balancer either. The services pinned by annotation are not moved.
+	MigrateServices bool `json:"migrateServices,omitempty" yaml:"migrateServices,omitempty"`
+
+	// AutoScale enables creating new load balancers from this configuration at runtime
+	// when the eligible load balancers of a service reach MaximumLoadBalancerRuleCount.
+	// The generated load balancers inherit the selectors and the placement flag of this
+	// configuration when they are generated, and are persisted in a ConfigMap in kube-system
+	// so that they are restored after the cloud provider restarts.
+	AutoScale *MultipleStandardLoadBalancerAutoScale `json:"autoScale,omitempty" yaml:"autoScale,omitempty"`
+}
+
+// MultipleStandardLoadBalancerAutoScale stores the properties regarding the load balancers
+// generated from a multiple standard load balancer configuration.
+type MultipleStandardLoadBalancerAutoScale struct {
+	// NameTemplate is the template of the names of the generated load balancers. The "{name}"
+	// placeholder is replaced by the name of the configuration, and the "{index}" placeholder
+	// by the index of the generated load balancer starting from 1. Defaults to "{name}-{index}".
+	NameTemplate string `json:"nameTemplate,omitempty" yaml:"nameTemplate,omitempty"`
+
+	// MaxCount is the maximum number of load balancers generated from the configuration.
+	MaxCount int `json:"maxCount" yaml:"maxCount"`
 }
 
 // MultipleStandardLoadBalancerConfigurationStatus stores the properties regarding multiple standard load balancers.
@@ -70,4 +94,14 @@ type MultipleStandardLoadBalancerConfigurationStatus struct {
 	// ActiveNodes stores the nodes that are supposed to be in the load balancer.
 	// It will be used in EnsureHostsInPool to make sure the given ones are in the backend pool.
 	ActiveNodes *utilsets.IgnoreCaseSet `json:"activeNodes" yaml:"activeNodes"`
+
+	// MigratingServices stores the services that are being moved off the load balancer.
+	MigratingServices *utilsets.IgnoreCaseSet `json:"-" yaml:"-"`
+
+	// MigratedServices stores the services that have been moved off the load balancer.
+	MigratedServices *utilsets.IgnoreCaseSet `json:"-" yaml:"-"`
+
+	// GeneratedFrom is the name of the configuration with AutoScale that the load balancer is
+	// generated from. It is empty for the load balancers in the cloud provider configuration.
+	GeneratedFrom string `json:"-" yaml:"-"`
 }
@@ -0,0 +1,55 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import "strings"
+
+// PublicIPPrefixPool stores the properties regarding the pool of public IP prefixes that the new public IPs
+// of the services are allocated from, so that the public IPs of the cluster stay in a small set of CIDRs.
+// The public IPs of the services with the pip-prefix-id annotation are not allocated from the pool.
+type PublicIPPrefixPool struct {
+	// PrefixIDs are the IDs of the existing public IP prefixes in the pool, which are used in order.
+	PrefixIDs []string `json:"prefixIDs,omitempty" yaml:"prefixIDs,omitempty"`
+
+	// NameTemplate is the template of the names of the public IP prefixes created when all prefixes in the pool
+	// are exhausted. The "{index}" placeholder is replaced by the index of the created prefix starting from 1,
+	// and the names of the IPv6 prefixes have the "-IPv6" suffix. No prefix is created if it is empty.
+	NameTemplate string `json:"nameTemplate,omitempty" yaml:"nameTemplate,omitempty"`
+
+	// ResourceGroup is the resource group of the created public IP prefixes. Defaults to the resource group of the cluster.
+	ResourceGroup string `json:"resourceGroup,omitempty" yaml:"resourceGroup,omitempty"`
+
+	// PrefixLength is the length of the created IPv4 public IP prefixes. Defaults to 28, which has 16 addresses.
+	PrefixLength int32 `json:"prefixLength,omitempty" yaml:"prefixLength,omitempty"`
+
+	// IPv6PrefixLength is the length of the created IPv6 public IP prefixes. Defaults to 124, which has 16 addresses.
+	IPv6PrefixLength int32 `json:"ipv6PrefixLength,omitempty" yaml:"ipv6PrefixLength,omitempty"`
+
+	// MaxPrefixes is the maximum number of public IP prefixes of each IP family in the pool, including PrefixIDs.
+	// It is required if NameTemplate is set.
+	MaxPrefixes int `json:"maxPrefixes,omitempty" yaml:"maxPrefixes,omitempty"`
+}
+
+// HasPrefixID returns true if the prefix ID is in PrefixIDs.
+func (pool *PublicIPPrefixPool) HasPrefixID(id string) bool {
+	for _, prefixID := range pool.PrefixIDs {
+		if strings.EqualFold(prefixID, id) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RouteTableConfiguration stores the properties regarding an additional route table that the pod CIDR routes
+// of a set of nodes, e.g. a node pool or the nodes in a subnet, are written to. Azure limits the number of
+// routes in a route table, so a large kubenet cluster needs a route table per subnet or node pool.
+type RouteTableConfiguration struct {
+	// Name of the route table in RouteTableResourceGroup. It is created if it does not exist.
+	Name string `json:"name" yaml:"name"`
+
+	// Nodes matching this selector use the route table.
+	NodeSelector *metav1.LabelSelector `json:"nodeSelector,omitempty" yaml:"nodeSelector,omitempty"`
+
+	// Nodes whose primary network interface is in the subnet with this name use the route table.
+	// If both NodeSelector and SubnetName are set, a node must match both of them.
+	SubnetName string `json:"subnetName,omitempty" yaml:"subnetName,omitempty"`
+}
@@ -0,0 +1,263 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dnsrecord
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/privatedns/armprivatedns"
+	"k8s.io/utils/ptr"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/azclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/azclient/privaterecordsetclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/azclient/recordsetclient"
+	"sigs.k8s.io/cloud-provider-azure/pkg/util/errutils"
+)
+
+// Generate mocks for the client interface
+//go:generate mockgen -destination=./mock_client.go -package=dnsrecord -copyright_file ../../../hack/boilerplate/boilerplate.generatego.txt -source=client.go Client
+
+// RecordType is the type of the DNS records that are managed for the services.
+type RecordType string
+
+const (
+	RecordTypeA    RecordType = "A"
+	RecordTypeAAAA RecordType = "AAAA"
+)
+
+const (
+	resourceTypeDNSZone        = "Microsoft.Network/dnszones"
+	resourceTypePrivateDNSZone = "Microsoft.Network/privateDnsZones"
+)
+
+// Zone is an Azure DNS zone or an Azure Private DNS zone.
+type Zone struct {
+	SubscriptionID string
+	ResourceGroup  string
+	Name           string
+	Private        bool
+}
+
+// ParseZoneID parses the resource ID of an Azure DNS zone or an Azure Private DNS zone.
+func ParseZoneID(id string) (Zone, error) {
+	resourceID, err := arm.ParseResourceID(strings.TrimSpace(id))
+	if err != nil {
+		return Zone{}, fmt.Errorf("invalid DNS zone ID %q: %w", id, err)
+	}
+	zone := Zone{
+		SubscriptionID: resourceID.SubscriptionID,
+		ResourceGroup:  resourceID.ResourceGroupName,
+		Name:           resourceID.Name,
+	}
+	switch {
+	case strings.EqualFold(resourceID.ResourceType.String(), resourceTypeDNSZone):
+	case strings.EqualFold(resourceID.ResourceType.String(), resourceTypePrivateDNSZone):
+		zone.Private = true
+	default:
+		return Zone{}, fmt.Errorf("invalid DNS zone ID %q: the resource type must be %s or %s", id, resourceTypeDNSZone, resourceTypePrivateDNSZone)
+	}
+	return zone, nil
+}
+
+func (z Zone) String() string {
+	resourceType := resourceTypeDNSZone
+	if z.Private {
+		resourceType = resourceTypePrivateDNSZone
+	}
+	return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/%s/%s", z.SubscriptionID, z.ResourceGroup, resourceType, z.Name)
+}
+
+// RecordSet is a set of A or AAAA records with the same name.
+type RecordSet struct {
+	Name     string
+	Type     RecordType
+	TTL      int64
+	IPs      []string
+	Metadata map[string]*string
+	// Etag is the etag of the existing record set, and is empty for a record set to be created.
+	Etag string
+}
+
+// Client manages the A and AAAA record sets in the Azure DNS zones and the Azure Private DNS zones.
+type Client interface {
+	// Get returns the record set, or nil if it is not found.
+	Get(ctx context.Context, zone Zone, recordType RecordType, name string) (*RecordSet, error)
+	// CreateOrUpdate creates the record set if its etag is empty, or updates it if its etag matches.
+	CreateOrUpdate(ctx context.Context, zone Zone, recordSet RecordSet) error
+	// Delete deletes the record set if the etag is empty or matches, and succeeds if it is not found.
+	Delete(ctx context.Context, zone Zone, recordType RecordType, name, etag string) error
+}
+
+type client struct {
+	factory azclient.ClientFactory
+}
+
+// NewClient returns a Client of the DNS record sets. The record set clients are taken from the client
+// factory per subscription, because the zones can be in any subscription.
+func NewClient(factory azclient.ClientFactory) Client {
+	return &client{factory: factory}
+}
+
+func (c *client) Get(ctx context.Context, zone Zone, recordType RecordType, name string) (*RecordSet, error) {
+	var (
+		recordSet *RecordSet
+		err       error
+	)
+	if zone.Private {
+		var rc privaterecordsetclient.Interface
+		if rc, err = c.factory.GetPrivateRecordSetClientForSub(zone.SubscriptionID); err != nil {
+			return nil, err
+		}
+		var rs *armprivatedns.RecordSet
+		if rs, err = rc.Get(ctx, zone.ResourceGroup, zone.Name, armprivatedns.RecordType(recordType), name); err == nil && rs != nil {
+			recordSet = fromPrivateRecordSet(recordType, name, *rs)
+		}
+	} else {
+		var rc recordsetclient.Interface
+		if rc, err = c.factory.GetRecordSetClientForSub(zone.SubscriptionID); err != nil {
+			return nil, err
+		}
+		var rs *armdns.RecordSet
+		if rs, err = rc.Get(ctx, zone.ResourceGroup, zone.Name, armdns.RecordType(recordType), name); err == nil && rs != nil {
+			recordSet = fromPublicRecordSet(recordType, name, *rs)
+		}
+	}
+	found, err := errutils.CheckResourceExistsFromAzcoreError(err)
+	if err != nil {
+		return nil, fmt.Errorf("get %s record set %q in zone %s: %w", recordType, name, zone, err)
+	}
+	if !found {
+		return nil, nil
+	}
+	return recordSet, nil
+}
+
+func (c *client) CreateOrUpdate(ctx context.Context, zone Zone, recordSet RecordSet) error {
+	var err error
+	if zone.Private {
+		var rc privaterecordsetclient.Interface
+		if rc, err = c.factory.GetPrivateRecordSetClientForSub(zone.SubscriptionID); err != nil {
+			return err
+		}
+		_, err = rc.CreateOrUpdate(ctx, zone.ResourceGroup, zone.Name, armprivatedns.RecordType(recordSet.Type), recordSet.Name, toPrivateRecordSet(recordSet), recordSet.Etag)
+	} else {
+		var rc recordsetclient.Interface
+		if rc, err = c.factory.GetRecordSetClientForSub(zone.SubscriptionID); err != nil {
+			return err
+		}
+		_, err = rc.CreateOrUpdate(ctx, zone.ResourceGroup, zone.Name, armdns.RecordType(recordSet.Type), recordSet.Name, toPublicRecordSet(recordSet), recordSet.Etag)
+	}
+	if err != nil {
+		return fmt.Errorf("create or update %s record set %q in zone %s: %w", recordSet.Type, recordSet.Name, zone, err)
+	}
+	return nil
+}
+
+func (c *client) Delete(ctx context.Context, zone Zone, recordType RecordType, name, etag string) error {
+	var err error
+	if zone.Private {
+		var rc privaterecordsetclient.Interface
+		if rc, err = c.factory.GetPrivateRecordSetClientForSub(zone.SubscriptionID); err != nil {
+			return err
+		}
+		err = rc.Delete(ctx, zone.ResourceGroup, zone.Name, armprivatedns.RecordType(recordType), name, etag)
+	} else {
+		var rc recordsetclient.Interface
+		if rc, err = c.factory.GetRecordSetClientForSub(zone.SubscriptionID); err != nil {
+			return err
+		}
+		err = rc.Delete(ctx, zone.ResourceGroup, zone.Name, armdns.RecordType(recordType), name, etag)
+	}
+	if _, err = errutils.CheckResourceExistsFromAzcoreError(err); err != nil {
+		return fmt.Errorf("delete %s record set %q in zone %s: %w", recordType, name, zone, err)
+	}
+	return nil
+}
+
+func fromPublicRecordSet(recordType RecordType, name string, rs armdns.RecordSet) *RecordSet {
+	recordSet := &RecordSet{Name: name, Type: recordType, Etag: ptr.Deref(rs.Etag, "")}
+	if rs.Properties == nil {
+		return recordSet
+	}
+	recordSet.TTL = ptr.Deref(rs.Properties.TTL, 0)
+	recordSet.Metadata = rs.Properties.Metadata
+	for _, r := range rs.Properties.ARecords {
+		if r != nil && r.IPv4Address != nil {
+			recordSet.IPs = append(recordSet.IPs, *r.IPv4Address)
+		}
+	}
+	for _, r := range rs.Properties.AaaaRecords {
+		if r != nil && r.IPv6Address != nil {
+			recordSet.IPs = append(recordSet.IPs, *r.IPv6Address)
+		}
+	}
+	return recordSet
+}
+
+func toPublicRecordSet(recordSet RecordSet) armdns.RecordSet {
+	properties := &armdns.RecordSetProperties{
+		TTL:      ptr.To(recordSet.TTL),
+		Metadata: recordSet.Metadata,
+	}
+	for _, ip := range recordSet.IPs {
+		if recordSet.Type == RecordTypeAAAA {
+			properties.AaaaRecords = append(properties.AaaaRecords, &armdns.AaaaRecord{IPv6Address: ptr.To(ip)})
+		} else {
+			properties.ARecords = append(properties.ARecords, &armdns.ARecord{IPv4Address: ptr.To(ip)})
+		}
+	}
+	return armdns.RecordSet{Properties: properties}
+}
+
+func fromPrivateRecordSet(recordType RecordType, name string, rs armprivatedns.RecordSet) *RecordSet {
+	recordSet := &RecordSet{Name: name, Type: recordType, Etag: ptr.Deref(rs.Etag, "")}
+	if rs.Properties == nil {
+		return recordSet
+	}
+	recordSet.TTL = ptr.Deref(rs.Properties.TTL, 0)
+	recordSet.Metadata = rs.Properties.Metadata
+	for _, r := range rs.Properties.ARecords {
+		if r != nil && r.IPv4Address != nil {
+			recordSet.IPs = append(recordSet.IPs, *r.IPv4Address)
+		}
+	}
+	for _, r := range rs.Properties.AaaaRecords {
+		if r != nil && r.IPv6Address != nil {
+			recordSet.IPs = append(recordSet.IPs, *r.IPv6Address)
+		}
+	}
+	return recordSet
+}
+
+func toPrivateRecordSet(recordSet RecordSet) armprivatedns.RecordSet {
+	properties := &armprivatedns.RecordSetProperties{
+		TTL:      ptr.To(recordSet.TTL),
+		Metadata: recordSet.Metadata,
+	}
+	for _, ip := range recordSet.IPs {
+		if recordSet.Type == RecordTypeAAAA {
+			properties.AaaaRecords = append(properties.AaaaRecords, &armprivatedns.AaaaRecord{IPv6Address: ptr.To(ip)})
+		} else {
+			properties.ARecords = append(properties.ARecords, &armprivatedns.ARecord{IPv4Address: ptr.To(ip)})
+		}
+	}
+	return armprivatedns.RecordSet{Properties: properties}
+}
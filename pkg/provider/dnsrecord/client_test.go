@@ -0,0 +1,98 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dnsrecord
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/privatedns/armprivatedns"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/utils/ptr"
+)
+
+func TestParseZoneID(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		id           string
+		expectedZone Zone
+		expectedErr  bool
+	}{
+		{
+			id:           "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/dnszones/contoso.com",
+			expectedZone: Zone{SubscriptionID: "sub", ResourceGroup: "rg", Name: "contoso.com"},
+		},
+		{
+			id:           "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/privateDnsZones/contoso.internal",
+			expectedZone: Zone{SubscriptionID: "sub", ResourceGroup: "rg", Name: "contoso.internal", Private: true},
+		},
+		{
+			id:           "/subscriptions/sub/resourceGroups/rg/providers/microsoft.network/privatednszones/contoso.internal",
+			expectedZone: Zone{SubscriptionID: "sub", ResourceGroup: "rg", Name: "contoso.internal", Private: true},
+		},
+		{
+			id:          "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/publicIPAddresses/pip",
+			expectedErr: true,
+		},
+		{
+			id:          "contoso.internal",
+			expectedErr: true,
+		},
+	} {
+		zone, err := ParseZoneID(tc.id)
+		if tc.expectedErr {
+			assert.Error(t, err, tc.id)
+			continue
+		}
+		assert.NoError(t, err, tc.id)
+		assert.Equal(t, tc.expectedZone, zone)
+	}
+
+	zone := Zone{SubscriptionID: "sub", ResourceGroup: "rg", Name: "contoso.internal", Private: true}
+	parsed, err := ParseZoneID(zone.String())
+	assert.NoError(t, err)
+	assert.Equal(t, zone, parsed)
+}
+
+func TestRecordSetConversion(t *testing.T) {
+	t.Parallel()
+
+	for _, recordSet := range []RecordSet{
+		{
+			Name:     "app",
+			Type:     RecordTypeA,
+			TTL:      300,
+			IPs:      []string{"10.0.0.4", "10.0.0.5"},
+			Metadata: map[string]*string{"k8s_azure_service": ptr.To("default/app")},
+		},
+		{
+			Name: "app",
+			Type: RecordTypeAAAA,
+			TTL:  60,
+			IPs:  []string{"fd00::4"},
+		},
+	} {
+		public := toPublicRecordSet(recordSet)
+		assert.Equal(t, &recordSet, fromPublicRecordSet(recordSet.Type, recordSet.Name, public))
+		private := toPrivateRecordSet(recordSet)
+		assert.Equal(t, &recordSet, fromPrivateRecordSet(recordSet.Type, recordSet.Name, private))
+	}
+
+	assert.Equal(t, &RecordSet{Name: "app", Type: RecordTypeA}, fromPublicRecordSet(RecordTypeA, "app", armdns.RecordSet{}))
+	assert.Equal(t, &RecordSet{Name: "app", Type: RecordTypeA}, fromPrivateRecordSet(RecordTypeA, "app", armprivatedns.RecordSet{}))
+}
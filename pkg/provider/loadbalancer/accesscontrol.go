@@ -19,6 +19,7 @@ package loadbalancer
 import (
 	"fmt"
 	"net/netip"
+	"sort"
 	"strings"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v6"
@@ -49,10 +50,38 @@ type AccessControl struct {
 	AllowedServiceTags                     []string
 	invalidRanges                          []string
 	securityRuleDestinationPortsByProtocol map[armnetwork.SecurityRuleProtocol][]int32
+	// portAccessControls are the access control of the service ports configured by the port annotations,
+	// keyed by the protocol and the destination port of the security rules.
+	portAccessControls map[armnetwork.SecurityRuleProtocol]map[int32]*portAccessControl
+}
+
+// portAccessControl is the access control of a service port configured by the port annotations
+// service.beta.kubernetes.io/port_{port}_allowed_ip_ranges and service.beta.kubernetes.io/port_{port}_allowed_service_tags.
+// It takes the place of the allowed IP ranges and service tags of the service for the port.
+type portAccessControl struct {
+	AllowedIPRanges    []netip.Prefix
+	AllowedServiceTags []string
+	invalidRanges      []string
+}
+
+// isAllowFromInternet returns true if the port is allowed to be accessed from internet,
+// following the same rules as AccessControl.IsAllowFromInternet.
+func (pac *portAccessControl) isAllowFromInternet(isInternal bool) bool {
+	if len(pac.AllowedServiceTags) > 0 || len(pac.invalidRanges) > 0 {
+		return false
+	}
+	if len(pac.AllowedIPRanges) > 0 && !iputil.IsPrefixesAllowAll(pac.AllowedIPRanges) {
+		return false
+	}
+	if !isInternal {
+		return true
+	}
+	return len(pac.AllowedIPRanges) > 0
 }
 
 type accessControlOptions struct {
-	EventEmitter K8sEventEmitter
+	EventEmitter                           K8sEventEmitter
+	SecurityRuleDestinationPortsByProtocol map[armnetwork.SecurityRuleProtocol][]int32
 }
 
 var defaultAccessControlOptions = accessControlOptions{
@@ -61,6 +90,14 @@ var defaultAccessControlOptions = accessControlOptions{
 
 type AccessControlOption func(*accessControlOptions)
 
+// WithSecurityRuleDestinationPortsByProtocol overrides the destination ports of the security rules,
+// which are derived from the service ports by default.
+func WithSecurityRuleDestinationPortsByProtocol(portsByProtocol map[armnetwork.SecurityRuleProtocol][]int32) AccessControlOption {
+	return func(o *accessControlOptions) {
+		o.SecurityRuleDestinationPortsByProtocol = portsByProtocol
+	}
+}
+
 func WithEventEmitter(emitter K8sEventEmitter) AccessControlOption {
 	return func(o *accessControlOptions) {
 		o.EventEmitter = emitter
@@ -96,11 +133,15 @@ func NewAccessControl(logger logr.Logger, svc *v1.Service, sg *armnetwork.Securi
 		eventEmitter(svc, v1.EventTypeWarning, "InvalidAllowedIPRanges", EventMessageOfInvalidAllowedIPRanges(invalidAllowedIPRanges))
 	}
 	allowedServiceTags := AllowedServiceTags(svc)
-	securityRuleDestinationPortsByProtocol, err := SecurityRuleDestinationPortsByProtocol(svc)
-	if err != nil {
-		logger.Error(err, "Failed to parse service spec.Ports")
-		return nil, err
+	securityRuleDestinationPortsByProtocol := options.SecurityRuleDestinationPortsByProtocol
+	if securityRuleDestinationPortsByProtocol == nil {
+		securityRuleDestinationPortsByProtocol, err = SecurityRuleDestinationPortsByProtocol(svc)
+		if err != nil {
+			logger.Error(err, "Failed to parse service spec.Ports")
+			return nil, err
+		}
 	}
+	portAccessControls := newPortAccessControls(logger, svc, options.SecurityRuleDestinationPortsByProtocol != nil, eventEmitter)
 	if len(sourceRanges) > 0 && len(allowedIPRanges) > 0 {
 		logger.Error(ErrSetBothLoadBalancerSourceRangesAndAllowedIPRanges, "Forbidden configuration")
 		return nil, ErrSetBothLoadBalancerSourceRangesAndAllowedIPRanges
@@ -124,9 +165,55 @@ func NewAccessControl(logger logr.Logger, svc *v1.Service, sg *armnetwork.Securi
 		AllowedServiceTags:                     allowedServiceTags,
 		invalidRanges:                          append(invalidSourceRanges, invalidAllowedIPRanges...),
 		securityRuleDestinationPortsByProtocol: securityRuleDestinationPortsByProtocol,
+		portAccessControls:                     portAccessControls,
 	}, nil
 }
 
+// newPortAccessControls parses the port annotations of the access control. The port annotations are ignored
+// if the destination ports of the security rules are overridden, because they are not the ports of the service.
+func newPortAccessControls(
+	logger logr.Logger,
+	svc *v1.Service,
+	dstPortsOverridden bool,
+	eventEmitter K8sEventEmitter,
+) map[armnetwork.SecurityRuleProtocol]map[int32]*portAccessControl {
+	rv := make(map[armnetwork.SecurityRuleProtocol]map[int32]*portAccessControl)
+	for _, port := range svc.Spec.Ports {
+		var (
+			allowedServiceTags, tagsFound                    = PortAllowedServiceTags(svc, port.Port)
+			allowedIPRanges, invalidRanges, rangesFound, err = PortAllowedIPRanges(svc, port.Port)
+		)
+		if !tagsFound && !rangesFound {
+			continue
+		}
+		if dstPortsOverridden {
+			logger.Info("Ignoring the access control of the port because the destination ports are overridden", "port", port.Port)
+			eventEmitter(svc, v1.EventTypeWarning, "UnsupportedPortAccessControl", EventMessageOfUnsupportedPortAccessControl(port.Port))
+			continue
+		}
+		if err != nil {
+			logger.Error(err, "Failed to parse AllowedIPRanges configuration of the port", "port", port.Port)
+
+			// Same as the allowed IP ranges of the service: no error but emit a warning event.
+			eventEmitter(svc, v1.EventTypeWarning, "InvalidAllowedIPRanges", EventMessageOfInvalidPortAllowedIPRanges(port.Port, invalidRanges))
+		}
+		protocol, err := securitygroup.ProtocolFromKubernetes(port.Protocol)
+		if err != nil {
+			// It has been validated by SecurityRuleDestinationPortsByProtocol.
+			continue
+		}
+		if rv[protocol] == nil {
+			rv[protocol] = make(map[int32]*portAccessControl)
+		}
+		rv[protocol][securityRuleDestinationPort(svc, port)] = &portAccessControl{
+			AllowedIPRanges:    allowedIPRanges,
+			AllowedServiceTags: allowedServiceTags,
+			invalidRanges:      invalidRanges,
+		}
+	}
+	return rv
+}
+
 // IsAllowFromInternet returns true if the given service is allowed to be accessed from internet.
 // To be specific,
 // 1. For all types of LB, it returns false if the given service is specified with `service tags` or `not allowed all IP ranges`, including invalid IP ranges.
@@ -159,45 +246,27 @@ func (ac *AccessControl) DenyAllExceptSourceRanges() bool {
 		sourceRangeSpecified   = len(ac.SourceRanges) > 0 || len(ac.AllowedIPRanges) > 0
 		invalidRangesSpecified = len(ac.invalidRanges) > 0
 	)
-	return (annotationEnabled && sourceRangeSpecified) || invalidRangesSpecified
-}
-
-// AllowedIPv4Ranges returns the IPv4 ranges that are allowed to access the LoadBalancer.
-func (ac *AccessControl) AllowedIPv4Ranges() []netip.Prefix {
-	var rv []netip.Prefix
-	for _, cidr := range ac.SourceRanges {
-		if cidr.Addr().Is4() {
-			rv = append(rv, cidr)
+	for _, pacs := range ac.portAccessControls {
+		for _, pac := range pacs {
+			sourceRangeSpecified = sourceRangeSpecified || len(pac.AllowedIPRanges) > 0
+			invalidRangesSpecified = invalidRangesSpecified || len(pac.invalidRanges) > 0
 		}
 	}
-	for _, cidr := range ac.AllowedIPRanges {
-		if cidr.Addr().Is4() {
-			rv = append(rv, cidr)
-		}
-	}
-	return rv
+	return (annotationEnabled && sourceRangeSpecified) || invalidRangesSpecified
 }
 
-// AllowedIPv6Ranges returns the IPv6 ranges that are allowed to access the LoadBalancer.
-func (ac *AccessControl) AllowedIPv6Ranges() []netip.Prefix {
-	var rv []netip.Prefix
-	for _, cidr := range ac.SourceRanges {
-		if cidr.Addr().Is6() {
-			rv = append(rv, cidr)
-		}
-	}
-	for _, cidr := range ac.AllowedIPRanges {
-		if cidr.Addr().Is6() {
-			rv = append(rv, cidr)
-		}
-	}
-	return rv
+// allowPolicy is the sources allowed to access the destination ports of a protocol.
+type allowPolicy struct {
+	Protocol           armnetwork.SecurityRuleProtocol
+	DstPorts           []int32
+	AllowedIPv4Ranges  []netip.Prefix
+	AllowedIPv6Ranges  []netip.Prefix
+	AllowedServiceTags []string
 }
 
-// PatchSecurityGroup checks and adds rules for the given destination IP addresses.
-func (ac *AccessControl) PatchSecurityGroup(dstIPv4Addresses, dstIPv6Addresses []netip.Addr) error {
-	logger := ac.logger.WithName("PatchSecurityGroup")
-
+// allowPolicies returns the allow policies of the service. The ports with their own access control
+// configured by the port annotations have one policy each, and the rest of the ports share the policy of the service.
+func (ac *AccessControl) allowPolicies(logger logr.Logger) []allowPolicy {
 	var (
 		allowedIPRanges    = append(ac.AllowedIPv4Ranges(), ac.AllowedIPv6Ranges()...)
 		allowedServiceTags = ac.AllowedServiceTags
@@ -220,6 +289,7 @@ func (ac *AccessControl) PatchSecurityGroup(dstIPv4Addresses, dstIPv6Addresses [
 		"num-allowed-ipv4-ranges", len(allowedIPv4Ranges),
 		"num-allowed-ipv6-ranges", len(allowedIPv6Ranges),
 		"num-allowed-service-tags", len(allowedServiceTags),
+		"num-ports-with-access-control", len(ac.portAccessControls),
 	)
 
 	protocols := []armnetwork.SecurityRuleProtocol{
@@ -228,36 +298,121 @@ func (ac *AccessControl) PatchSecurityGroup(dstIPv4Addresses, dstIPv6Addresses [
 		armnetwork.SecurityRuleProtocolAsterisk,
 	}
 
+	var (
+		rv         []allowPolicy
+		isInternal = IsInternal(ac.svc)
+	)
 	for _, protocol := range protocols {
 		dstPorts, found := ac.securityRuleDestinationPortsByProtocol[protocol]
 		if !found {
 			continue
 		}
+
+		var servicePorts, portsWithAccessControl []int32
+		for _, port := range dstPorts {
+			if _, found := ac.portAccessControls[protocol][port]; found {
+				portsWithAccessControl = append(portsWithAccessControl, port)
+			} else {
+				servicePorts = append(servicePorts, port)
+			}
+		}
+
+		if len(servicePorts) > 0 {
+			rv = append(rv, allowPolicy{
+				Protocol:           protocol,
+				DstPorts:           servicePorts,
+				AllowedIPv4Ranges:  allowedIPv4Ranges,
+				AllowedIPv6Ranges:  allowedIPv6Ranges,
+				AllowedServiceTags: allowedServiceTags,
+			})
+		}
+
+		sort.Slice(portsWithAccessControl, func(i, j int) bool { return portsWithAccessControl[i] < portsWithAccessControl[j] })
+		for _, port := range portsWithAccessControl {
+			var (
+				pac            = ac.portAccessControls[protocol][port]
+				portTags       = pac.AllowedServiceTags
+				portIPv4Ranges []netip.Prefix
+				portIPv6Ranges []netip.Prefix
+			)
+			if pac.isAllowFromInternet(isInternal) {
+				portTags = append(portTags, securitygroup.ServiceTagInternet)
+			}
+			portIPv4Ranges, portIPv6Ranges = iputil.GroupPrefixesByFamily(iputil.AggregatePrefixes(pac.AllowedIPRanges))
+			rv = append(rv, allowPolicy{
+				Protocol:           protocol,
+				DstPorts:           []int32{port},
+				AllowedIPv4Ranges:  portIPv4Ranges,
+				AllowedIPv6Ranges:  portIPv6Ranges,
+				AllowedServiceTags: portTags,
+			})
+		}
+	}
+	return rv
+}
+
+// AllowedIPv4Ranges returns the IPv4 ranges that are allowed to access the LoadBalancer.
+func (ac *AccessControl) AllowedIPv4Ranges() []netip.Prefix {
+	var rv []netip.Prefix
+	for _, cidr := range ac.SourceRanges {
+		if cidr.Addr().Is4() {
+			rv = append(rv, cidr)
+		}
+	}
+	for _, cidr := range ac.AllowedIPRanges {
+		if cidr.Addr().Is4() {
+			rv = append(rv, cidr)
+		}
+	}
+	return rv
+}
+
+// AllowedIPv6Ranges returns the IPv6 ranges that are allowed to access the LoadBalancer.
+func (ac *AccessControl) AllowedIPv6Ranges() []netip.Prefix {
+	var rv []netip.Prefix
+	for _, cidr := range ac.SourceRanges {
+		if cidr.Addr().Is6() {
+			rv = append(rv, cidr)
+		}
+	}
+	for _, cidr := range ac.AllowedIPRanges {
+		if cidr.Addr().Is6() {
+			rv = append(rv, cidr)
+		}
+	}
+	return rv
+}
+
+// PatchSecurityGroup checks and adds rules for the given destination IP addresses.
+func (ac *AccessControl) PatchSecurityGroup(dstIPv4Addresses, dstIPv6Addresses []netip.Addr) error {
+	logger := ac.logger.WithName("PatchSecurityGroup")
+
+	for _, policy := range ac.allowPolicies(logger) {
 		if len(dstIPv4Addresses) > 0 {
-			for _, tag := range allowedServiceTags {
-				err := ac.sgHelper.AddRuleForAllowedServiceTag(tag, protocol, dstIPv4Addresses, dstPorts)
+			for _, tag := range policy.AllowedServiceTags {
+				err := ac.sgHelper.AddRuleForAllowedServiceTag(tag, policy.Protocol, dstIPv4Addresses, policy.DstPorts)
 				if err != nil {
 					return fmt.Errorf("add rule for allowed service tag on IPv4: %w", err)
 				}
 			}
 
-			if len(allowedIPv4Ranges) > 0 {
-				err := ac.sgHelper.AddRuleForAllowedIPRanges(allowedIPv4Ranges, protocol, dstIPv4Addresses, dstPorts)
+			if len(policy.AllowedIPv4Ranges) > 0 {
+				err := ac.sgHelper.AddRuleForAllowedIPRanges(policy.AllowedIPv4Ranges, policy.Protocol, dstIPv4Addresses, policy.DstPorts)
 				if err != nil {
 					return fmt.Errorf("add rule for allowed IP ranges on IPv4: %w", err)
 				}
 			}
 		}
 		if len(dstIPv6Addresses) > 0 {
-			for _, tag := range allowedServiceTags {
-				err := ac.sgHelper.AddRuleForAllowedServiceTag(tag, protocol, dstIPv6Addresses, dstPorts)
+			for _, tag := range policy.AllowedServiceTags {
+				err := ac.sgHelper.AddRuleForAllowedServiceTag(tag, policy.Protocol, dstIPv6Addresses, policy.DstPorts)
 				if err != nil {
 					return fmt.Errorf("add rule for allowed service tag on IPv6: %w", err)
 				}
 			}
 
-			if len(allowedIPv6Ranges) > 0 {
-				err := ac.sgHelper.AddRuleForAllowedIPRanges(allowedIPv6Ranges, protocol, dstIPv6Addresses, dstPorts)
+			if len(policy.AllowedIPv6Ranges) > 0 {
+				err := ac.sgHelper.AddRuleForAllowedIPRanges(policy.AllowedIPv6Ranges, policy.Protocol, dstIPv6Addresses, policy.DstPorts)
 				if err != nil {
 					return fmt.Errorf("add rule for allowed IP ranges on IPv6: %w", err)
 				}
@@ -283,6 +438,63 @@ func (ac *AccessControl) PatchSecurityGroup(dstIPv4Addresses, dstIPv6Addresses [
 	return nil
 }
 
+// PatchSecurityGroupOnApplicationSecurityGroup checks and adds rules for the given application security group
+// for the enabled IP families. The rules target the application security group instead of the destination IP addresses,
+// so they don't change with the IP addresses of the members.
+func (ac *AccessControl) PatchSecurityGroupOnApplicationSecurityGroup(asgID string, ipv4Enabled, ipv6Enabled bool) error {
+	logger := ac.logger.WithName("PatchSecurityGroupOnApplicationSecurityGroup").WithValues("application-security-group", asgID)
+
+	for _, policy := range ac.allowPolicies(logger) {
+		if ipv4Enabled {
+			for _, tag := range policy.AllowedServiceTags {
+				err := ac.sgHelper.AddRuleForAllowedServiceTagOnApplicationSecurityGroup(tag, policy.Protocol, iputil.IPv4, asgID, policy.DstPorts)
+				if err != nil {
+					return fmt.Errorf("add rule for allowed service tag on IPv4: %w", err)
+				}
+			}
+
+			if len(policy.AllowedIPv4Ranges) > 0 {
+				err := ac.sgHelper.AddRuleForAllowedIPRangesOnApplicationSecurityGroup(policy.AllowedIPv4Ranges, policy.Protocol, asgID, policy.DstPorts)
+				if err != nil {
+					return fmt.Errorf("add rule for allowed IP ranges on IPv4: %w", err)
+				}
+			}
+		}
+		if ipv6Enabled {
+			for _, tag := range policy.AllowedServiceTags {
+				err := ac.sgHelper.AddRuleForAllowedServiceTagOnApplicationSecurityGroup(tag, policy.Protocol, iputil.IPv6, asgID, policy.DstPorts)
+				if err != nil {
+					return fmt.Errorf("add rule for allowed service tag on IPv6: %w", err)
+				}
+			}
+
+			if len(policy.AllowedIPv6Ranges) > 0 {
+				err := ac.sgHelper.AddRuleForAllowedIPRangesOnApplicationSecurityGroup(policy.AllowedIPv6Ranges, policy.Protocol, asgID, policy.DstPorts)
+				if err != nil {
+					return fmt.Errorf("add rule for allowed IP ranges on IPv6: %w", err)
+				}
+			}
+		}
+	}
+
+	if ac.DenyAllExceptSourceRanges() {
+		if ipv4Enabled {
+			if err := ac.sgHelper.AddRuleForDenyAllOnApplicationSecurityGroup(iputil.IPv4, asgID); err != nil {
+				return fmt.Errorf("add rule for deny all on IPv4: %w", err)
+			}
+		}
+		if ipv6Enabled {
+			if err := ac.sgHelper.AddRuleForDenyAllOnApplicationSecurityGroup(iputil.IPv6, asgID); err != nil {
+				return fmt.Errorf("add rule for deny all on IPv6: %w", err)
+			}
+		}
+	}
+
+	logger.V(10).Info("Completed patching")
+
+	return nil
+}
+
 // CleanSecurityGroup removes the given IP addresses from the SecurityGroup.
 func (ac *AccessControl) CleanSecurityGroup(
 	dstIPv4Addresses, dstIPv6Addresses []netip.Addr,
@@ -321,6 +533,42 @@ func (ac *AccessControl) CleanSecurityGroup(
 	return nil
 }
 
+// CleanSecurityGroupOnApplicationSecurityGroup removes the given application security group from the SecurityGroup.
+func (ac *AccessControl) CleanSecurityGroupOnApplicationSecurityGroup(
+	asgID string,
+	retainPortRanges map[armnetwork.SecurityRuleProtocol][]int32,
+) error {
+	logger := ac.logger.WithName("CleanSecurityGroupOnApplicationSecurityGroup").WithValues("application-security-group", asgID)
+	logger.V(10).Info("Start cleaning")
+
+	protocols := []armnetwork.SecurityRuleProtocol{
+		armnetwork.SecurityRuleProtocolTCP,
+		armnetwork.SecurityRuleProtocolUDP,
+		armnetwork.SecurityRuleProtocolAsterisk,
+	}
+
+	for _, protocol := range protocols {
+		if err := ac.sgHelper.RemoveApplicationSecurityGroupFromRules(protocol, []string{asgID}, retainPortRanges[protocol]); err != nil {
+			logger.Error(err, "Failed to remove application security group from rules")
+			return err
+		}
+	}
+
+	logger.V(10).Info("Completed cleaning")
+	return nil
+}
+
+// CompactSecurityGroup merges the rules that only differ in the destination ports or destinations,
+// and reclaims the priorities of the removed rules. It returns the number of removed rules.
+func (ac *AccessControl) CompactSecurityGroup() int {
+	return ac.sgHelper.Compact()
+}
+
+// SecurityGroupUsage returns the number of rules and IP addresses of the SecurityGroup with patched rules.
+func (ac *AccessControl) SecurityGroupUsage() securitygroup.Usage {
+	return ac.sgHelper.Usage()
+}
+
 // SecurityGroup returns the SecurityGroup object with patched rules and indicates if the rules had been changed.
 // There are mainly two operations to alter the SecurityGroup:
 // 1. `PatchSecurityGroup`: Add rules for the given destination IP addresses.
@@ -339,14 +587,15 @@ func SecurityRuleDestinationPortsByProtocol(svc *v1.Service) (map[armnetwork.Sec
 			return nil, err
 		}
 
-		var p int32
-		if consts.IsK8sServiceDisableLoadBalancerFloatingIP(svc) {
-			p = port.NodePort
-		} else {
-			p = port.Port
-		}
-
-		rv[protocol] = append(rv[protocol], p)
+		rv[protocol] = append(rv[protocol], securityRuleDestinationPort(svc, port))
 	}
 	return rv, nil
 }
+
+// securityRuleDestinationPort returns the destination port of the security rules for the service port.
+func securityRuleDestinationPort(svc *v1.Service, port v1.ServicePort) int32 {
+	if consts.IsK8sServiceDisableLoadBalancerFloatingIP(svc) {
+		return port.NodePort
+	}
+	return port.Port
+}
@@ -165,6 +165,48 @@ func TestNewAccessControl(t *testing.T) {
 		assert.Equal(t, 1, called)
 	})
 
+	t.Run("it should emit warning event if invalid allowed IP ranges of port", func(t *testing.T) {
+		svc := k8sFx.Service().
+			WithPortAllowedIPRanges(443, "foo", "10.0.0.1/32").
+			Build()
+
+		called := 0
+		eventEmitter := func(obj runtime.Object, eventType, reason, message string) {
+			called++
+			assert.Equal(t, v1.EventTypeWarning, eventType)
+			assert.Equal(t, "InvalidAllowedIPRanges", reason)
+			assert.Equal(t, EventMessageOfInvalidPortAllowedIPRanges(443, []string{"foo"}), message)
+		}
+
+		ac, err := NewAccessControl(log.Noop(), &svc, sg, WithEventEmitter(eventEmitter))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, called)
+		assert.True(t, ac.DenyAllExceptSourceRanges())
+	})
+
+	t.Run("it should ignore the access control of ports if the destination ports are overridden", func(t *testing.T) {
+		svc := k8sFx.Service().
+			WithPortAllowedServiceTags(443, "AzureFrontDoor.Backend").
+			Build()
+
+		called := 0
+		eventEmitter := func(obj runtime.Object, eventType, reason, message string) {
+			called++
+			assert.Equal(t, "UnsupportedPortAccessControl", reason)
+			assert.Equal(t, EventMessageOfUnsupportedPortAccessControl(443), message)
+		}
+
+		ac, err := NewAccessControl(log.Noop(), &svc, sg,
+			WithEventEmitter(eventEmitter),
+			WithSecurityRuleDestinationPortsByProtocol(map[armnetwork.SecurityRuleProtocol][]int32{
+				armnetwork.SecurityRuleProtocolTCP: {8443},
+			}),
+		)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, called)
+		assert.Empty(t, ac.portAccessControls)
+	})
+
 	t.Run("it should emit warning event if invalid azure-allowed-ip-ranges", func(t *testing.T) {
 		svc := k8sFx.Service().
 			WithAllowedIPRanges("foo", "10.0.0.1/32", "bar").
@@ -1054,6 +1096,86 @@ func TestAccessControl_PatchSecurityGroup(t *testing.T) {
 		runTest(t, svc, originalRules, dstIPv4Addresses, dstIPv6Addresses, true, expectedRules)
 	})
 
+	t.Run("patch service with allowedIPRanges and allowedServiceTags of ports", func(t *testing.T) {
+		var (
+			k8sFx           = fixture.NewFixture().Kubernetes()
+			allowedIPRanges = []string{"192.168.0.0/16"}
+			portIPRanges    = []string{"10.10.0.0/16"}
+			portServiceTags = []string{"AzureFrontDoor.Backend"}
+			svc             = k8sFx.Service().
+					WithAllowedIPRanges(allowedIPRanges...).
+					WithPortAllowedIPRanges(443, portIPRanges...).
+					WithPortAllowedServiceTags(53, portServiceTags...).
+					Build()
+			originalRules    = azureFx.NoiseSecurityRules()
+			dstIPv4Addresses = []string{
+				"10.0.0.1",
+				"10.0.0.2",
+			}
+			expectedRules = testutil.CloneInJSON(originalRules)
+		)
+		expectedRules = append(expectedRules,
+			// TCP ports without access control of the port
+			azureFx.
+				AllowSecurityRule(armnetwork.SecurityRuleProtocolTCP, iputil.IPv4, allowedIPRanges, []int32{80}).
+				WithPriority(500).
+				WithDestination(dstIPv4Addresses...).
+				Build(),
+			// TCP 53 for the service tags of the port
+			azureFx.
+				AllowSecurityRule(armnetwork.SecurityRuleProtocolTCP, iputil.IPv4, portServiceTags, []int32{53}).
+				WithPriority(501).
+				WithDestination(dstIPv4Addresses...).
+				Build(),
+			// TCP 443 for the IP ranges of the port
+			azureFx.
+				AllowSecurityRule(armnetwork.SecurityRuleProtocolTCP, iputil.IPv4, portIPRanges, []int32{443}).
+				WithPriority(502).
+				WithDestination(dstIPv4Addresses...).
+				Build(),
+			// UDP 53 for the service tags of the port
+			azureFx.
+				AllowSecurityRule(armnetwork.SecurityRuleProtocolUDP, iputil.IPv4, portServiceTags, []int32{53}).
+				WithPriority(503).
+				WithDestination(dstIPv4Addresses...).
+				Build(),
+		)
+		runTest(t, svc, originalRules, dstIPv4Addresses, nil, true, expectedRules)
+	})
+
+	t.Run("patch service with invalid allowedIPRanges of ports", func(t *testing.T) {
+		var (
+			k8sFx = fixture.NewFixture().Kubernetes()
+			svc   = k8sFx.Service().
+				WithPortAllowedIPRanges(443, "foo").
+				Build()
+			originalRules    = azureFx.NoiseSecurityRules()
+			dstIPv4Addresses = []string{
+				"10.0.0.1",
+			}
+			expectedRules = testutil.CloneInJSON(originalRules)
+		)
+		expectedRules = append(expectedRules,
+			// The other ports are still allowed from Internet
+			azureFx.
+				AllowSecurityRule(armnetwork.SecurityRuleProtocolTCP, iputil.IPv4, []string{securitygroup.ServiceTagInternet}, []int32{80, 53}).
+				WithPriority(500).
+				WithDestination(dstIPv4Addresses...).
+				Build(),
+			azureFx.
+				AllowSecurityRule(armnetwork.SecurityRuleProtocolUDP, iputil.IPv4, []string{securitygroup.ServiceTagInternet}, []int32{53}).
+				WithPriority(501).
+				WithDestination(dstIPv4Addresses...).
+				Build(),
+			azureFx.
+				DenyAllSecurityRule(iputil.IPv4).
+				WithPriority(4095).
+				WithDestination(dstIPv4Addresses...).
+				Build(),
+		)
+		runTest(t, svc, originalRules, dstIPv4Addresses, nil, true, expectedRules)
+	})
+
 	t.Run("patch service with invalid allowedIPRanges", func(t *testing.T) {
 		var (
 			k8sFx           = fixture.NewFixture().Kubernetes()
@@ -1525,3 +1647,104 @@ func TestAccessControl_CleanSecurityGroup(t *testing.T) {
 		}, outputSG.Properties.SecurityRules)
 	})
 }
+
+func TestAccessControl_PatchSecurityGroupOnApplicationSecurityGroup(t *testing.T) {
+	var (
+		fx      = fixture.NewFixture()
+		azureFx = fx.Azure()
+		asgID   = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/applicationSecurityGroups/kubernetes"
+	)
+
+	t.Run("it should add rules targeting the application security group", func(t *testing.T) {
+		var (
+			k8sFx           = fx.Kubernetes()
+			allowedIPRanges = []string{"192.168.0.0/16", "20.0.0.1/32"}
+			svc             = k8sFx.Service().
+					WithAllowedIPRanges(allowedIPRanges...).
+					WithDenyAllExceptLoadBalancerSourceRanges().
+					Build()
+			sg      = azureFx.SecurityGroup().WithRules(azureFx.NoiseSecurityRules()).Build()
+			ac, err = NewAccessControl(log.Noop(), &svc, sg)
+		)
+		assert.NoError(t, err)
+
+		assert.NoError(t, ac.PatchSecurityGroupOnApplicationSecurityGroup(asgID, true, false))
+		actualSG, updated, err := ac.SecurityGroup()
+		assert.NoError(t, err)
+		assert.True(t, updated)
+
+		expectedRuleNames := []string{
+			securitygroup.GenerateAllowApplicationSecurityGroupRuleName(armnetwork.SecurityRuleProtocolTCP, iputil.IPv4, allowedIPRanges, k8sFx.Service().TCPPorts()),
+			securitygroup.GenerateAllowApplicationSecurityGroupRuleName(armnetwork.SecurityRuleProtocolUDP, iputil.IPv4, allowedIPRanges, k8sFx.Service().UDPPorts()),
+			securitygroup.GenerateDenyAllApplicationSecurityGroupRuleName(iputil.IPv4),
+		}
+		for _, name := range expectedRuleNames {
+			var found bool
+			for _, rule := range actualSG.Properties.SecurityRules {
+				if ptr.Deref(rule.Name, "") != name {
+					continue
+				}
+				found = true
+				assert.Equal(t, []string{asgID}, securitygroup.ListDestinationApplicationSecurityGroupIDs(rule))
+				assert.Empty(t, securitygroup.ListDestinationPrefixes(rule))
+			}
+			assert.True(t, found, "rule %s is not found", name)
+		}
+	})
+
+	t.Run("it should not add rules for the disabled IP family", func(t *testing.T) {
+		var (
+			svc     = fx.Kubernetes().Service().Build()
+			sg      = azureFx.SecurityGroup().Build()
+			ac, err = NewAccessControl(log.Noop(), &svc, sg)
+		)
+		assert.NoError(t, err)
+
+		assert.NoError(t, ac.PatchSecurityGroupOnApplicationSecurityGroup(asgID, false, false))
+		_, updated, err := ac.SecurityGroup()
+		assert.NoError(t, err)
+		assert.False(t, updated)
+	})
+}
+
+func TestAccessControl_CleanSecurityGroupOnApplicationSecurityGroup(t *testing.T) {
+	var (
+		fx      = fixture.NewFixture()
+		azureFx = fx.Azure()
+		asgID   = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/applicationSecurityGroups/kubernetes"
+		svc     = fx.Kubernetes().Service().Build()
+	)
+
+	t.Run("it should remove the rules only targeting the application security group", func(t *testing.T) {
+		var (
+			patchSG      = azureFx.SecurityGroup().Build()
+			patchAC, err = NewAccessControl(log.Noop(), &svc, patchSG)
+		)
+		assert.NoError(t, err)
+		assert.NoError(t, patchAC.PatchSecurityGroupOnApplicationSecurityGroup(asgID, true, true))
+		patchedSG, _, err := patchAC.SecurityGroup()
+		assert.NoError(t, err)
+		assert.NotEmpty(t, patchedSG.Properties.SecurityRules)
+
+		ac, err := NewAccessControl(log.Noop(), &svc, patchedSG)
+		assert.NoError(t, err)
+		assert.NoError(t, ac.CleanSecurityGroupOnApplicationSecurityGroup(asgID, make(map[armnetwork.SecurityRuleProtocol][]int32)))
+		actualSG, updated, err := ac.SecurityGroup()
+		assert.NoError(t, err)
+		assert.True(t, updated)
+		assert.Empty(t, actualSG.Properties.SecurityRules)
+	})
+
+	t.Run("it should not patch rules if no rules match", func(t *testing.T) {
+		var (
+			sg      = azureFx.SecurityGroup().WithRules(azureFx.NoiseSecurityRules()).Build()
+			ac, err = NewAccessControl(log.Noop(), &svc, sg)
+		)
+		assert.NoError(t, err)
+
+		assert.NoError(t, ac.CleanSecurityGroupOnApplicationSecurityGroup(asgID, make(map[armnetwork.SecurityRuleProtocol][]int32)))
+		_, updated, err := ac.SecurityGroup()
+		assert.NoError(t, err)
+		assert.False(t, updated)
+	})
+}
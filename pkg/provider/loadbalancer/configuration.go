@@ -53,6 +53,57 @@ func AllowedServiceTags(svc *v1.Service) []string {
 	return tags
 }
 
+// PortAllowedServiceTags returns the allowed service tags of the service port configured by user through
+// the port annotation service.beta.kubernetes.io/port_{port}_allowed_service_tags, and whether it is set.
+func PortAllowedServiceTags(svc *v1.Service, port int32) ([]string, bool) {
+	const Sep = ","
+
+	value, found := svc.Annotations[consts.BuildAnnotationKeyForPort(port, consts.PortAnnotationAllowedServiceTags)]
+	if !found {
+		return nil, false
+	}
+
+	var tags []string
+	for _, tag := range strings.Split(strings.TrimSpace(value), Sep) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, true
+}
+
+// PortAllowedIPRanges returns the allowed IP ranges of the service port configured by user through
+// the port annotation service.beta.kubernetes.io/port_{port}_allowed_ip_ranges, and whether it is set.
+func PortAllowedIPRanges(svc *v1.Service, port int32) ([]netip.Prefix, []string, bool, error) {
+	const Sep = ","
+
+	key := consts.BuildAnnotationKeyForPort(port, consts.PortAnnotationAllowedIPRanges)
+	value, found := svc.Annotations[key]
+	if !found {
+		return nil, nil, false, nil
+	}
+
+	var (
+		errs          []error
+		validRanges   []netip.Prefix
+		invalidRanges []string
+	)
+	for _, p := range strings.Split(strings.TrimSpace(value), Sep) {
+		p = strings.TrimSpace(p)
+		prefix, err := iputil.ParsePrefix(p)
+		if err != nil {
+			errs = append(errs, err)
+			invalidRanges = append(invalidRanges, p)
+		} else {
+			validRanges = append(validRanges, prefix)
+		}
+	}
+	if len(errs) > 0 {
+		return validRanges, invalidRanges, true, NewErrAnnotationValue(key, value, errors.Join(errs...))
+	}
+	return validRanges, invalidRanges, true, nil
+}
+
 // AllowedIPRanges returns the allowed IP ranges configured by user through AKS custom annotations:
 // service.beta.kubernetes.io/azure-allowed-ip-ranges and service.beta.kubernetes.io/load-balancer-source-ranges
 func AllowedIPRanges(svc *v1.Service) ([]netip.Prefix, []string, error) {
@@ -108,6 +108,62 @@ func TestAllowedServiceTags(t *testing.T) {
 	})
 }
 
+func TestPortAllowedServiceTags(t *testing.T) {
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				"service.beta.kubernetes.io/port_443_allowed_service_tags":  " AzureFrontDoor.Backend, foo ,",
+				"service.beta.kubernetes.io/port_8443_allowed_service_tags": "",
+			},
+		},
+	}
+
+	tags, found := PortAllowedServiceTags(svc, 80)
+	assert.False(t, found)
+	assert.Empty(t, tags)
+
+	tags, found = PortAllowedServiceTags(svc, 443)
+	assert.True(t, found)
+	assert.Equal(t, []string{"AzureFrontDoor.Backend", "foo"}, tags)
+
+	// An empty annotation denies all traffic to the port.
+	tags, found = PortAllowedServiceTags(svc, 8443)
+	assert.True(t, found)
+	assert.Empty(t, tags)
+}
+
+func TestPortAllowedIPRanges(t *testing.T) {
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				"service.beta.kubernetes.io/port_443_allowed_ip_ranges":  "10.10.0.0/16, 2001:db8::/32",
+				"service.beta.kubernetes.io/port_8443_allowed_ip_ranges": "10.0.0.1/32,foo",
+			},
+		},
+	}
+
+	ranges, invalidRanges, found, err := PortAllowedIPRanges(svc, 80)
+	assert.NoError(t, err)
+	assert.False(t, found)
+	assert.Empty(t, ranges)
+	assert.Empty(t, invalidRanges)
+
+	ranges, invalidRanges, found, err = PortAllowedIPRanges(svc, 443)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.10.0.0/16"), netip.MustParsePrefix("2001:db8::/32")}, ranges)
+	assert.Empty(t, invalidRanges)
+
+	ranges, invalidRanges, found, err = PortAllowedIPRanges(svc, 8443)
+	assert.Error(t, err)
+	var expectedErr *ErrAnnotationValue
+	assert.ErrorAs(t, err, &expectedErr)
+	assert.Equal(t, "service.beta.kubernetes.io/port_8443_allowed_ip_ranges", expectedErr.AnnotationKey)
+	assert.True(t, found)
+	assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.0.0.1/32")}, ranges)
+	assert.Equal(t, []string{"foo"}, invalidRanges)
+}
+
 func TestAllowedIPRanges(t *testing.T) {
 	t.Run("no annotation", func(t *testing.T) {
 		actual, invalid, err := AllowedIPRanges(&v1.Service{
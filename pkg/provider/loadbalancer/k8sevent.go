@@ -42,6 +42,20 @@ func EventMessageOfInvalidAllowedIPRanges(allowedIPRanges []string) string {
 	)
 }
 
+func EventMessageOfInvalidPortAllowedIPRanges(port int32, allowedIPRanges []string) string {
+	return fmt.Sprintf("Found invalid %s %q, ignoring and adding a default DenyAll rule in security group.",
+		consts.BuildAnnotationKeyForPort(port, consts.PortAnnotationAllowedIPRanges),
+		allowedIPRanges,
+	)
+}
+
+func EventMessageOfUnsupportedPortAccessControl(port int32) string {
+	return fmt.Sprintf("Ignoring %s and %s, because the destination ports of the security rules are the target ports of the pods.",
+		consts.BuildAnnotationKeyForPort(port, consts.PortAnnotationAllowedIPRanges),
+		consts.BuildAnnotationKeyForPort(port, consts.PortAnnotationAllowedServiceTags),
+	)
+}
+
 func EventMessageOfConflictLoadBalancerSourceRangesAndAllowedIPRanges() string {
 	return fmt.Sprintf(
 		"Please use annotation %s instead of spec.loadBalancerSourceRanges while using %s annotation at the same time.",
@@ -0,0 +1,64 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package privatelinkservice
+
+// Generate mocks for the connection client interface
+//go:generate mockgen -destination=./mock_connection_client.go -package=privatelinkservice -copyright_file ../../../hack/boilerplate/boilerplate.generatego.txt -source=connection_client.go ConnectionClient
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v6"
+
+	"sigs.k8s.io/cloud-provider-azure/pkg/azclient/utils"
+)
+
+// ConnectionClient updates the private endpoint connections of the private link services,
+// which is not supported by the private link service client.
+type ConnectionClient interface {
+	UpdatePrivateEndpointConnection(ctx context.Context, resourceGroup, plsName, peConnName string, peConn armnetwork.PrivateEndpointConnection) (*armnetwork.PrivateEndpointConnection, error)
+}
+
+type connectionClient struct {
+	*armnetwork.PrivateLinkServicesClient
+}
+
+// NewConnectionClient returns a ConnectionClient of the private link services in the given subscription.
+func NewConnectionClient(subscriptionID string, credential azcore.TokenCredential, options *arm.ClientOptions) (ConnectionClient, error) {
+	if options == nil {
+		options = utils.GetDefaultOption()
+	}
+	c, err := armnetwork.NewPrivateLinkServicesClient(subscriptionID, credential, options)
+	if err != nil {
+		return nil, err
+	}
+	return &connectionClient{PrivateLinkServicesClient: c}, nil
+}
+
+func (c *connectionClient) UpdatePrivateEndpointConnection(ctx context.Context, resourceGroup, plsName, peConnName string, peConn armnetwork.PrivateEndpointConnection) (*armnetwork.PrivateEndpointConnection, error) {
+	if plsName == "" {
+		return nil, ErrMissingPLSName
+	}
+	resp, err := c.PrivateLinkServicesClient.UpdatePrivateEndpointConnection(ctx, resourceGroup, plsName, peConnName, peConn, nil)
+	if err != nil {
+		return nil, fmt.Errorf("update PLS PE connection: %w", err)
+	}
+	return &resp.PrivateEndpointConnection, nil
+}
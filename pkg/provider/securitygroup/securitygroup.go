@@ -19,10 +19,12 @@ package securitygroup
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/netip"
 	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v6"
@@ -53,6 +55,7 @@ const (
 var (
 	ErrInvalidSecurityGroup                                = fmt.Errorf("invalid SecurityGroup object")
 	ErrSecurityRulePriorityExhausted                       = fmt.Errorf("security rule priority exhausted")
+	ErrSecurityGroupCapacityExceeded                       = fmt.Errorf("security group capacity exceeded")
 	ErrSecurityRuleSourceAddressesNotFromSameIPFamily      = fmt.Errorf("security rule source addresses must be from the same IP family")
 	ErrSecurityRuleDestinationAddressesNotFromSameIPFamily = fmt.Errorf("security rule destination addresses must be from the same IP family")
 	ErrSecurityRuleSourceAndDestinationNotFromSameIPFamily = fmt.Errorf("security rule source addresses and destination addresses must be from the same IP family")
@@ -141,9 +144,12 @@ func (helper *RuleHelper) getOrCreateRule(name string, priorityPrefer rulePriori
 	}
 
 	priority, err := helper.nextRulePriority(priorityPrefer)
+	if errors.Is(err, ErrSecurityRulePriorityExhausted) {
+		// Reclaim the priorities of the rules without destination, and try again.
+		logger.V(4).Info("Reclaiming rule priorities", "num-reclaimed", helper.reclaimRulePriorities())
+		priority, err = helper.nextRulePriority(priorityPrefer)
+	}
 	if err != nil {
-		// NOTE: right now it won't happen because the number of rules is limited.
-		//       maxPriority[4096] - minPriority[500] > maxSecurityRulesPerGroup[1000]
 		helper.logger.Error(err, "Failed to get an available rule priority")
 		return nil, err
 	}
@@ -293,6 +299,112 @@ func (helper *RuleHelper) AddRuleForDenyAll(dstAddresses []netip.Addr) error {
 	return nil
 }
 
+// addAllowRuleForApplicationSecurityGroups adds a rule that allows certain traffic to the given application security groups.
+func (helper *RuleHelper) addAllowRuleForApplicationSecurityGroups(
+	protocol armnetwork.SecurityRuleProtocol,
+	ipFamily iputil.Family,
+	srcPrefixes []string,
+	asgIDs []string,
+	dstPorts []int32,
+) error {
+	name := GenerateAllowApplicationSecurityGroupRuleName(protocol, ipFamily, srcPrefixes, dstPorts)
+	rule, err := helper.getOrCreateRule(name, rulePriorityPreferFromStart)
+	if err != nil {
+		return err
+	}
+	dstPortRanges := fnutil.Map(func(p int32) string { return strconv.FormatInt(int64(p), 10) }, dstPorts)
+	sort.Strings(dstPortRanges)
+
+	rule.Properties.Protocol = to.Ptr(protocol)
+	rule.Properties.Access = to.Ptr(armnetwork.SecurityRuleAccessAllow)
+	rule.Properties.Direction = to.Ptr(armnetwork.SecurityRuleDirectionInbound)
+	{
+		// Source
+		if len(srcPrefixes) == 1 {
+			rule.Properties.SourceAddressPrefix = to.Ptr(srcPrefixes[0])
+		} else {
+			rule.Properties.SourceAddressPrefixes = to.SliceOfPtrs(srcPrefixes...)
+		}
+		rule.Properties.SourcePortRange = ptr.To("*")
+	}
+	{
+		// Destination
+		ids := append(ListDestinationApplicationSecurityGroupIDs(rule), asgIDs...)
+		SetDestinationApplicationSecurityGroupIDs(rule, ids)
+		rule.Properties.DestinationPortRanges = to.SliceOfPtrs(dstPortRanges...)
+	}
+
+	helper.logger.V(4).Info("Patched a rule for allow on application security groups", "rule-name", name)
+
+	return nil
+}
+
+// AddRuleForAllowedServiceTagOnApplicationSecurityGroup adds a rule for traffic from a certain service tag
+// to the given application security group.
+func (helper *RuleHelper) AddRuleForAllowedServiceTagOnApplicationSecurityGroup(
+	serviceTag string,
+	protocol armnetwork.SecurityRuleProtocol,
+	ipFamily iputil.Family,
+	asgID string,
+	dstPorts []int32,
+) error {
+	helper.logger.V(4).Info("Patching a rule for allowed service tag on application security group", "ip-family", ipFamily)
+
+	return helper.addAllowRuleForApplicationSecurityGroups(protocol, ipFamily, []string{serviceTag}, []string{asgID}, dstPorts)
+}
+
+// AddRuleForAllowedIPRangesOnApplicationSecurityGroup adds a rule for traffic from certain IP ranges
+// to the given application security group.
+func (helper *RuleHelper) AddRuleForAllowedIPRangesOnApplicationSecurityGroup(
+	ipRanges []netip.Prefix,
+	protocol armnetwork.SecurityRuleProtocol,
+	asgID string,
+	dstPorts []int32,
+) error {
+	if !iputil.ArePrefixesFromSameFamily(ipRanges) {
+		return ErrSecurityRuleSourceAddressesNotFromSameIPFamily
+	}
+
+	var (
+		ipFamily    = iputil.FamilyOfAddr(ipRanges[0].Addr())
+		srcPrefixes = fnutil.Map(func(ip netip.Prefix) string { return ip.String() }, ipRanges)
+	)
+
+	helper.logger.V(4).Info("Patching a rule for allowed IP ranges on application security group", "ip-family", ipFamily)
+
+	return helper.addAllowRuleForApplicationSecurityGroups(protocol, ipFamily, srcPrefixes, []string{asgID}, dstPorts)
+}
+
+// AddRuleForDenyAllOnApplicationSecurityGroup adds a rule to deny all traffic to the given application security group.
+func (helper *RuleHelper) AddRuleForDenyAllOnApplicationSecurityGroup(ipFamily iputil.Family, asgID string) error {
+	ruleName := GenerateDenyAllApplicationSecurityGroupRuleName(ipFamily)
+
+	helper.logger.V(4).Info("Patching a rule for deny all on application security group", "ip-family", ipFamily)
+
+	rule, err := helper.getOrCreateRule(ruleName, rulePriorityPreferFromEnd)
+	if err != nil {
+		return err
+	}
+	rule.Properties.Protocol = to.Ptr(armnetwork.SecurityRuleProtocolAsterisk)
+	rule.Properties.Access = to.Ptr(armnetwork.SecurityRuleAccessDeny)
+	rule.Properties.Direction = to.Ptr(armnetwork.SecurityRuleDirectionInbound)
+	{
+		// Source
+		rule.Properties.SourceAddressPrefix = ptr.To("*")
+		rule.Properties.SourcePortRange = ptr.To("*")
+	}
+	{
+		// Destination
+		ids := append(ListDestinationApplicationSecurityGroupIDs(rule), asgID)
+		SetDestinationApplicationSecurityGroupIDs(rule, ids)
+		rule.Properties.DestinationPortRange = ptr.To("*")
+	}
+
+	helper.logger.V(4).Info("Patched a rule for deny all on application security group", "rule-name", ptr.To(rule.Name))
+
+	return nil
+}
+
 // RemoveDestinationFromRules removes the given destination addresses from rules that match the given protocol and ports is in the retainDstPorts list.
 // It may add a new rule if the original rule needs to be split.
 func (helper *RuleHelper) RemoveDestinationFromRules(
@@ -317,6 +429,11 @@ func (helper *RuleHelper) RemoveDestinationFromRules(
 			continue
 		}
 
+		if len(rule.Properties.DestinationApplicationSecurityGroups) > 0 {
+			// The rule targeting application security groups does not have destination addresses.
+			continue
+		}
+
 		if err := helper.removeDestinationFromRule(rule, dstPrefixes, retainDstPorts); err != nil {
 			logger.Error(err, "Failed to remove destination from rule", "rule-name", *rule.Name)
 		}
@@ -325,6 +442,82 @@ func (helper *RuleHelper) RemoveDestinationFromRules(
 	return nil
 }
 
+// RemoveApplicationSecurityGroupFromRules removes the given application security groups from rules that match the given protocol
+// and ports is in the retainDstPorts list. It may add a new rule if the original rule needs to be split.
+func (helper *RuleHelper) RemoveApplicationSecurityGroupFromRules(
+	protocol armnetwork.SecurityRuleProtocol,
+	asgIDs []string,
+	retainDstPorts []int32,
+) error {
+	logger := helper.logger.WithName("RemoveApplicationSecurityGroupFromRules").WithValues("protocol", protocol, "num-asgs", len(asgIDs))
+	logger.V(10).Info("Cleaning application security groups from SecurityGroup")
+
+	for _, rule := range helper.rules {
+		if rule.Properties.Priority == nil {
+			continue
+		}
+		priority := *rule.Properties.Priority
+		if priority < consts.LoadBalancerMinimumPriority || consts.LoadBalancerMaximumPriority < priority {
+			logger.V(4).Info("Skip rule with not-in-range priority", "rule-name", *rule.Name, "priority", priority)
+			continue
+		}
+
+		if *rule.Properties.Protocol != protocol || len(rule.Properties.DestinationApplicationSecurityGroups) == 0 {
+			continue
+		}
+
+		if err := helper.removeApplicationSecurityGroupFromRule(rule, asgIDs, retainDstPorts); err != nil {
+			logger.Error(err, "Failed to remove application security groups from rule", "rule-name", *rule.Name)
+		}
+	}
+
+	return nil
+}
+
+func (helper *RuleHelper) removeApplicationSecurityGroupFromRule(rule *armnetwork.SecurityRule, asgIDs []string, retainDstPorts []int32) error {
+	logger := helper.logger.WithName("removeApplicationSecurityGroupFromRule").
+		WithValues("security-rule-name", rule.Name)
+
+	var (
+		idIndex    = fnutil.IndexSet(asgIDs)
+		currentIDs = ListDestinationApplicationSecurityGroupIDs(rule)
+
+		expectedIDs = idIndex.SubtractedBy(currentIDs)        // The ASGs to keep.
+		targetIDs   = fnutil.Intersection(currentIDs, asgIDs) // The ASGs to remove.
+	)
+
+	// Clean DenyAll rule
+	if *rule.Properties.Access == armnetwork.SecurityRuleAccessDeny && len(retainDstPorts) == 0 {
+		SetDestinationApplicationSecurityGroupIDs(rule, expectedIDs)
+		return nil
+	}
+
+	// Clean Allow rule
+	currentPorts, err := ListDestinationPortRanges(rule)
+	if err != nil {
+		logger.Info("Skip because it contains `*` or port-ranges as destination port ranges.")
+		return nil
+	}
+	expectedPorts := fnutil.Intersection(currentPorts, retainDstPorts) // The ports to keep.
+
+	if len(targetIDs) == 0 || len(currentPorts) == len(expectedPorts) {
+		return nil
+	}
+
+	SetDestinationApplicationSecurityGroupIDs(rule, expectedIDs)
+
+	if len(expectedPorts) == 0 {
+		return nil
+	}
+
+	// There are additional ports are expected, need to create a new rule for them.
+	ipFamily, ok := ipFamilyOfRuleName(*rule.Name)
+	if !ok {
+		return fmt.Errorf("unable to get IP family from rule name %q", *rule.Name)
+	}
+	return helper.addAllowRuleForApplicationSecurityGroups(*rule.Properties.Protocol, ipFamily, ListSourcePrefixes(rule), targetIDs, expectedPorts)
+}
+
 func (helper *RuleHelper) removeDestinationFromRule(rule *armnetwork.SecurityRule, prefixes []string, retainDstPorts []int32) error {
 	logger := helper.logger.WithName("removeDestinationFromRule").
 		WithValues("security-rule-name", rule.Name)
@@ -403,37 +596,21 @@ func (helper *RuleHelper) SecurityGroup() (*armnetwork.SecurityGroup, bool, erro
 	var (
 		snapshot = makeSecurityGroupSnapshot(rv)
 		updated  = !bytes.Equal(helper.snapshot, snapshot)
-		nRules   int
-		nSrcIPs  int
-		nDstIPs  int
+		usage    = usageOfSecurityRules(rv.Properties.SecurityRules)
 	)
-	{
-		// Check whether the SecurityGroup exceeds the limit.
-		for _, rule := range rv.Properties.SecurityRules {
-			nRules++
-			if rule.Properties.SourceAddressPrefixes != nil {
-				nSrcIPs += len(rule.Properties.SourceAddressPrefixes)
-			}
-			if rule.Properties.SourceAddressPrefix != nil {
-				nSrcIPs++
-			}
-			if rule.Properties.DestinationAddressPrefixes != nil {
-				nDstIPs += len(rule.Properties.DestinationAddressPrefixes)
-			}
-			if rule.Properties.DestinationAddressPrefix != nil {
-				nDstIPs++
-			}
-		}
-		helper.logger.V(10).Info("Checking the number of rules and IP addresses", "num-rules", nRules, "num-src-ips", nSrcIPs, "num-dst-ips", nDstIPs)
-		if nRules > MaxSecurityRulesPerGroup {
-			return nil, false, fmt.Errorf("exceeds the maximum number of rules (%d > %d)", nRules, MaxSecurityRulesPerGroup)
-		}
-		if nSrcIPs > MaxSecurityRuleSourceIPsPerGroup {
-			return nil, false, fmt.Errorf("exceeds the maximum number of source IP addresses (%d > %d)", nSrcIPs, MaxSecurityRuleSourceIPsPerGroup)
-		}
-		if nDstIPs > MaxSecurityRuleDestinationIPsPerGroup {
-			return nil, false, fmt.Errorf("exceeds the maximum number of destination IP addresses (%d > %d)", nDstIPs, MaxSecurityRuleDestinationIPsPerGroup)
-		}
+	helper.logger.V(10).Info("Checking the number of rules and IP addresses",
+		"num-rules", usage.Rules, "num-src-ips", usage.SourceAddresses, "num-dst-ips", usage.DestinationAddresses)
+	if usage.Rules > MaxSecurityRulesPerGroup {
+		return nil, false, fmt.Errorf("%w: exceeds the maximum number of rules (%d > %d)",
+			ErrSecurityGroupCapacityExceeded, usage.Rules, MaxSecurityRulesPerGroup)
+	}
+	if usage.SourceAddresses > MaxSecurityRuleSourceIPsPerGroup {
+		return nil, false, fmt.Errorf("%w: exceeds the maximum number of source IP addresses (%d > %d)",
+			ErrSecurityGroupCapacityExceeded, usage.SourceAddresses, MaxSecurityRuleSourceIPsPerGroup)
+	}
+	if usage.DestinationAddresses > MaxSecurityRuleDestinationIPsPerGroup {
+		return nil, false, fmt.Errorf("%w: exceeds the maximum number of destination IP addresses (%d > %d)",
+			ErrSecurityGroupCapacityExceeded, usage.DestinationAddresses, MaxSecurityRuleDestinationIPsPerGroup)
 	}
 
 	return rv, updated, nil
@@ -448,3 +625,213 @@ func makeSecurityGroupSnapshot(sg *armnetwork.SecurityGroup) []byte {
 	snapshot, _ := json.Marshal(sg)
 	return snapshot
 }
+
+// Usage is the number of rules and IP addresses in a SecurityGroup, which are limited by Azure.
+type Usage struct {
+	Rules                int
+	SourceAddresses      int
+	DestinationAddresses int
+}
+
+// usageOfSecurityRules counts the rules and IP addresses of the given security rules.
+func usageOfSecurityRules(rules []*armnetwork.SecurityRule) Usage {
+	var usage Usage
+	for _, rule := range rules {
+		usage.Rules++
+		if rule.Properties.SourceAddressPrefixes != nil {
+			usage.SourceAddresses += len(rule.Properties.SourceAddressPrefixes)
+		}
+		if rule.Properties.SourceAddressPrefix != nil {
+			usage.SourceAddresses++
+		}
+		if rule.Properties.DestinationAddressPrefixes != nil {
+			usage.DestinationAddresses += len(rule.Properties.DestinationAddressPrefixes)
+		}
+		if rule.Properties.DestinationAddressPrefix != nil {
+			usage.DestinationAddresses++
+		}
+	}
+	return usage
+}
+
+// Usage returns the number of rules and IP addresses the SecurityGroup would have with the patched rules.
+func (helper *RuleHelper) Usage() Usage {
+	rules := make([]*armnetwork.SecurityRule, 0, len(helper.rules))
+	for _, r := range helper.rules {
+		if !isRuleWithoutDestination(r) {
+			rules = append(rules, r)
+		}
+	}
+	return usageOfSecurityRules(rules)
+}
+
+// isRuleWithoutDestination checks if the rule has neither destination addresses nor application security groups.
+func isRuleWithoutDestination(rule *armnetwork.SecurityRule) bool {
+	return len(ListDestinationPrefixes(rule)) == 0 && len(rule.Properties.DestinationApplicationSecurityGroups) == 0
+}
+
+// reclaimRulePriorities removes the rules without destination, so their priorities can be used by new rules.
+// It returns the number of removed rules.
+func (helper *RuleHelper) reclaimRulePriorities() int {
+	var n int
+	for name, rule := range helper.rules {
+		if !isRuleWithoutDestination(rule) {
+			continue
+		}
+		helper.deleteRule(name)
+		n++
+	}
+	return n
+}
+
+// deleteRule removes the rule and releases its priority.
+func (helper *RuleHelper) deleteRule(name string) {
+	rule, found := helper.rules[name]
+	if !found {
+		return
+	}
+	delete(helper.rules, name)
+	if rule.Properties != nil && rule.Properties.Priority != nil && helper.priorities[*rule.Properties.Priority] == name {
+		delete(helper.priorities, *rule.Properties.Priority)
+	}
+}
+
+// compactionKeyOfRule returns the key to group the allow rules created by the cloud provider that can be merged.
+// The rules with the same key only differ in the destination ports. It returns false if the rule cannot be merged.
+func compactionKeyOfRule(rule *armnetwork.SecurityRule) (string, bool) {
+	var (
+		name  = ptr.Deref(rule.Name, "")
+		parts = strings.Split(name, SecurityRuleNameSep)
+	)
+	if len(parts) != 4 || parts[0] != SecurityRuleNamePrefix ||
+		(parts[1] != securityRuleKindAllow && parts[1] != securityRuleKindAllowApplicationSecurityGroup) {
+		return "", false
+	}
+	props := rule.Properties
+	if props == nil || props.Priority == nil || props.Protocol == nil ||
+		ptr.Deref(props.Access, "") != armnetwork.SecurityRuleAccessAllow ||
+		ptr.Deref(props.Direction, "") != armnetwork.SecurityRuleDirectionInbound ||
+		ptr.Deref(props.SourcePortRange, "") != "*" {
+		return "", false
+	}
+	if *props.Priority < consts.LoadBalancerMinimumPriority || consts.LoadBalancerMaximumPriority < *props.Priority {
+		return "", false
+	}
+	if _, err := ListDestinationPortRanges(rule); err != nil {
+		return "", false
+	}
+	if isRuleWithoutDestination(rule) {
+		return "", false
+	}
+
+	return strings.Join([]string{
+		parts[1],
+		parts[2],
+		string(*props.Protocol),
+		strings.Join(NormalizeSecurityRuleAddressPrefixes(ListSourcePrefixes(rule)), ","),
+		strings.Join(NormalizeSecurityRuleAddressPrefixes(ListDestinationPrefixes(rule)), ","),
+		strings.Join(NormalizeSecurityRuleAddressPrefixes(ListDestinationApplicationSecurityGroupIDs(rule)), ","),
+	}, "|"), true
+}
+
+// Compact reduces the number of rules in the SecurityGroup:
+//  1. The rules without destination are removed and their priorities are reclaimed.
+//  2. The allow rules created by the cloud provider with the same protocol, source and destination are merged
+//     into one rule with all their destination ports, which keeps the lowest priority among them.
+//  3. If the merged rule has the same name as another rule, i.e. the same protocol, source and destination ports,
+//     the destination of the merged rule is added to that rule.
+//
+// The allowed traffic is not changed by the compaction. It returns the number of removed rules.
+func (helper *RuleHelper) Compact() int {
+	logger := helper.logger.WithName("Compact")
+
+	removed := helper.reclaimRulePriorities()
+	for {
+		merged := helper.mergeRules()
+		if merged == 0 {
+			break
+		}
+		removed += merged
+	}
+
+	logger.V(4).Info("Compacted rules", "num-removed-rules", removed, "num-rules", len(helper.rules))
+	return removed
+}
+
+// mergeRules merges one group of the rules that only differ in the destination ports.
+// It returns the number of removed rules, and 0 if there is nothing to merge.
+func (helper *RuleHelper) mergeRules() int {
+	groups := make(map[string][]*armnetwork.SecurityRule)
+	for _, rule := range helper.rules {
+		if key, ok := compactionKeyOfRule(rule); ok {
+			groups[key] = append(groups[key], rule)
+		}
+	}
+	keys := make([]string, 0, len(groups))
+	for key, group := range groups {
+		if len(group) > 1 {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return 0
+	}
+	sort.Strings(keys)
+
+	group := groups[keys[0]]
+	sort.Slice(group, func(i, j int) bool {
+		return *group[i].Properties.Priority < *group[j].Properties.Priority
+	})
+
+	var (
+		base     = group[0]
+		portSet  = make(map[int32]bool)
+		ports    []int32
+		groupSet = make(map[string]bool, len(group))
+	)
+	for _, rule := range group {
+		rulePorts, _ := ListDestinationPortRanges(rule)
+		for _, port := range rulePorts {
+			if !portSet[port] {
+				portSet[port] = true
+				ports = append(ports, port)
+			}
+		}
+		groupSet[*rule.Name] = true
+	}
+
+	var (
+		ipFamily, _ = ipFamilyOfRuleName(*base.Name)
+		srcPrefixes = ListSourcePrefixes(base)
+		name        string
+	)
+	if len(base.Properties.DestinationApplicationSecurityGroups) > 0 {
+		name = GenerateAllowApplicationSecurityGroupRuleName(*base.Properties.Protocol, ipFamily, srcPrefixes, ports)
+	} else {
+		name = GenerateAllowSecurityRuleName(*base.Properties.Protocol, ipFamily, srcPrefixes, ports)
+	}
+
+	for _, rule := range group {
+		helper.deleteRule(*rule.Name)
+	}
+
+	if target, found := helper.rules[name]; found && !groupSet[name] {
+		// Another rule allows the same ports from the same source, merge the destination into it.
+		helper.logger.V(4).Info("Merging rules into an existing rule", "num-rules", len(group), "rule-name", name)
+		if dstPrefixes := ListDestinationPrefixes(base); len(dstPrefixes) > 0 {
+			SetDestinationPrefixes(target, append(ListDestinationPrefixes(target), dstPrefixes...))
+		}
+		if asgIDs := ListDestinationApplicationSecurityGroupIDs(base); len(asgIDs) > 0 {
+			SetDestinationApplicationSecurityGroupIDs(target, append(ListDestinationApplicationSecurityGroupIDs(target), asgIDs...))
+		}
+		return len(group)
+	}
+
+	helper.logger.V(4).Info("Merging rules", "num-rules", len(group), "rule-name", name, "priority", *base.Properties.Priority)
+	base.Name = ptr.To(name)
+	base.Properties.DestinationPortRange = nil
+	base.Properties.DestinationPortRanges = to.SliceOfPtrs(NormalizeDestinationPortRanges(ports)...)
+	helper.rules[name] = base
+	helper.priorities[*base.Properties.Priority] = name
+	return len(group) - 1
+}
@@ -1577,6 +1577,7 @@ func TestSecurityGroupHelper_SecurityGroup(t *testing.T) {
 
 		outputSG, updated, err := helper.SecurityGroup()
 		assert.Error(t, err)
+		assert.ErrorIs(t, err, ErrSecurityGroupCapacityExceeded)
 		assert.False(t, updated)
 		assert.Nil(t, outputSG)
 	})
@@ -1740,3 +1741,353 @@ func TestGenerateDenyAllSecurityRuleName(t *testing.T) {
 	assert.Equal(t, GenerateDenyAllSecurityRuleName(iputil.IPv4), "k8s-azure-lb_deny-all_IPv4")
 	assert.Equal(t, GenerateDenyAllSecurityRuleName(iputil.IPv6), "k8s-azure-lb_deny-all_IPv6")
 }
+
+func TestGenerateAllowApplicationSecurityGroupRuleName(t *testing.T) {
+	var (
+		protocol    = armnetwork.SecurityRuleProtocolTCP
+		srcPrefixes = []string{"foo", "bar"}
+		dstPorts    = []int32{80, 443}
+	)
+
+	assert.NotEqual(t,
+		GenerateAllowSecurityRuleName(protocol, iputil.IPv4, srcPrefixes, dstPorts),
+		GenerateAllowApplicationSecurityGroupRuleName(protocol, iputil.IPv4, srcPrefixes, dstPorts),
+	)
+	assert.NotEqual(t,
+		GenerateAllowApplicationSecurityGroupRuleName(protocol, iputil.IPv4, srcPrefixes, dstPorts),
+		GenerateAllowApplicationSecurityGroupRuleName(protocol, iputil.IPv6, srcPrefixes, dstPorts),
+	)
+	assert.Regexp(t, "^k8s-azure-lb_allow-asg_IPv4_[0-9a-f]{32}$", GenerateAllowApplicationSecurityGroupRuleName(protocol, iputil.IPv4, srcPrefixes, dstPorts))
+	assert.Equal(t, "k8s-azure-lb_deny-all-asg_IPv6", GenerateDenyAllApplicationSecurityGroupRuleName(iputil.IPv6))
+}
+
+func TestRuleHelper_AddRuleOnApplicationSecurityGroup(t *testing.T) {
+	fx := fixture.NewFixture()
+
+	t.Run("it should add rules targeting application security groups", func(t *testing.T) {
+		var (
+			sg       = fx.Azure().SecurityGroup().Build()
+			helper   = ExpectNewSecurityGroupHelper(t, sg)
+			ipRanges = []netip.Prefix{netip.MustParsePrefix("10.0.0.0/16"), netip.MustParsePrefix("10.1.0.0/16")}
+			dstPorts = []int32{443, 80}
+		)
+		assert.NoError(t, helper.AddRuleForAllowedIPRangesOnApplicationSecurityGroup(ipRanges, armnetwork.SecurityRuleProtocolTCP, "asg-1", dstPorts))
+		assert.NoError(t, helper.AddRuleForAllowedIPRangesOnApplicationSecurityGroup(ipRanges, armnetwork.SecurityRuleProtocolTCP, "asg-2", dstPorts))
+		assert.NoError(t, helper.AddRuleForAllowedServiceTagOnApplicationSecurityGroup("Internet", armnetwork.SecurityRuleProtocolUDP, iputil.IPv6, "asg-1", []int32{53}))
+		assert.NoError(t, helper.AddRuleForDenyAllOnApplicationSecurityGroup(iputil.IPv4, "asg-1"))
+
+		outputSG, updated, err := helper.SecurityGroup()
+		assert.NoError(t, err)
+		assert.True(t, updated)
+		testutil.ExpectEqualInJSON(t, []*armnetwork.SecurityRule{
+			{
+				Name: ptr.To(GenerateAllowApplicationSecurityGroupRuleName(armnetwork.SecurityRuleProtocolTCP, iputil.IPv4, []string{"10.0.0.0/16", "10.1.0.0/16"}, dstPorts)),
+				Properties: &armnetwork.SecurityRulePropertiesFormat{
+					Protocol:              to.Ptr(armnetwork.SecurityRuleProtocolTCP),
+					Access:                to.Ptr(armnetwork.SecurityRuleAccessAllow),
+					Direction:             to.Ptr(armnetwork.SecurityRuleDirectionInbound),
+					SourceAddressPrefixes: to.SliceOfPtrs("10.0.0.0/16", "10.1.0.0/16"),
+					SourcePortRange:       ptr.To("*"),
+					DestinationApplicationSecurityGroups: []*armnetwork.ApplicationSecurityGroup{
+						{ID: ptr.To("asg-1")},
+						{ID: ptr.To("asg-2")},
+					},
+					DestinationPortRanges: to.SliceOfPtrs("443", "80"),
+					Priority:              ptr.To(int32(500)),
+				},
+			},
+			{
+				Name: ptr.To(GenerateAllowApplicationSecurityGroupRuleName(armnetwork.SecurityRuleProtocolUDP, iputil.IPv6, []string{"Internet"}, []int32{53})),
+				Properties: &armnetwork.SecurityRulePropertiesFormat{
+					Protocol:            to.Ptr(armnetwork.SecurityRuleProtocolUDP),
+					Access:              to.Ptr(armnetwork.SecurityRuleAccessAllow),
+					Direction:           to.Ptr(armnetwork.SecurityRuleDirectionInbound),
+					SourceAddressPrefix: ptr.To("Internet"),
+					SourcePortRange:     ptr.To("*"),
+					DestinationApplicationSecurityGroups: []*armnetwork.ApplicationSecurityGroup{
+						{ID: ptr.To("asg-1")},
+					},
+					DestinationPortRanges: to.SliceOfPtrs("53"),
+					Priority:              ptr.To(int32(501)),
+				},
+			},
+			{
+				Name: ptr.To(GenerateDenyAllApplicationSecurityGroupRuleName(iputil.IPv4)),
+				Properties: &armnetwork.SecurityRulePropertiesFormat{
+					Protocol:            to.Ptr(armnetwork.SecurityRuleProtocolAsterisk),
+					Access:              to.Ptr(armnetwork.SecurityRuleAccessDeny),
+					Direction:           to.Ptr(armnetwork.SecurityRuleDirectionInbound),
+					SourceAddressPrefix: ptr.To("*"),
+					SourcePortRange:     ptr.To("*"),
+					DestinationApplicationSecurityGroups: []*armnetwork.ApplicationSecurityGroup{
+						{ID: ptr.To("asg-1")},
+					},
+					DestinationPortRange: ptr.To("*"),
+					Priority:             ptr.To(int32(4095)),
+				},
+			},
+		}, outputSG.Properties.SecurityRules)
+	})
+
+	t.Run("it should return error if the IP ranges are from different IP families", func(t *testing.T) {
+		var (
+			sg       = fx.Azure().SecurityGroup().Build()
+			helper   = ExpectNewSecurityGroupHelper(t, sg)
+			ipRanges = []netip.Prefix{netip.MustParsePrefix("10.0.0.0/16"), netip.MustParsePrefix("2001:db8::/32")}
+		)
+		err := helper.AddRuleForAllowedIPRangesOnApplicationSecurityGroup(ipRanges, armnetwork.SecurityRuleProtocolTCP, "asg-1", []int32{80})
+		assert.ErrorIs(t, err, ErrSecurityRuleSourceAddressesNotFromSameIPFamily)
+	})
+}
+
+func TestRuleHelper_RemoveApplicationSecurityGroupFromRules(t *testing.T) {
+	fx := fixture.NewFixture()
+
+	var (
+		srcPrefixes = []string{"10.0.0.0/16"}
+		dstPorts    = []int32{80, 443}
+		allowName   = GenerateAllowApplicationSecurityGroupRuleName(armnetwork.SecurityRuleProtocolTCP, iputil.IPv4, srcPrefixes, dstPorts)
+		addressRule = func() *armnetwork.SecurityRule {
+			return &armnetwork.SecurityRule{
+				Name: ptr.To("test-rule-address"),
+				Properties: &armnetwork.SecurityRulePropertiesFormat{
+					Protocol:                 to.Ptr(armnetwork.SecurityRuleProtocolTCP),
+					Access:                   to.Ptr(armnetwork.SecurityRuleAccessAllow),
+					Direction:                to.Ptr(armnetwork.SecurityRuleDirectionInbound),
+					SourceAddressPrefix:      ptr.To("Internet"),
+					SourcePortRange:          ptr.To("*"),
+					DestinationAddressPrefix: ptr.To("20.0.0.1"),
+					DestinationPortRanges:    to.SliceOfPtrs("80"),
+					Priority:                 ptr.To(int32(502)),
+				},
+			}
+		}
+		rules = func() []*armnetwork.SecurityRule {
+			return []*armnetwork.SecurityRule{
+				{
+					Name: ptr.To(allowName),
+					Properties: &armnetwork.SecurityRulePropertiesFormat{
+						Protocol:            to.Ptr(armnetwork.SecurityRuleProtocolTCP),
+						Access:              to.Ptr(armnetwork.SecurityRuleAccessAllow),
+						Direction:           to.Ptr(armnetwork.SecurityRuleDirectionInbound),
+						SourceAddressPrefix: ptr.To("10.0.0.0/16"),
+						SourcePortRange:     ptr.To("*"),
+						DestinationApplicationSecurityGroups: []*armnetwork.ApplicationSecurityGroup{
+							{ID: ptr.To("asg-1")},
+							{ID: ptr.To("asg-2")},
+						},
+						DestinationPortRanges: to.SliceOfPtrs("443", "80"),
+						Priority:              ptr.To(int32(500)),
+					},
+				},
+				{
+					Name: ptr.To(GenerateDenyAllApplicationSecurityGroupRuleName(iputil.IPv4)),
+					Properties: &armnetwork.SecurityRulePropertiesFormat{
+						Protocol:            to.Ptr(armnetwork.SecurityRuleProtocolAsterisk),
+						Access:              to.Ptr(armnetwork.SecurityRuleAccessDeny),
+						Direction:           to.Ptr(armnetwork.SecurityRuleDirectionInbound),
+						SourceAddressPrefix: ptr.To("*"),
+						SourcePortRange:     ptr.To("*"),
+						DestinationApplicationSecurityGroups: []*armnetwork.ApplicationSecurityGroup{
+							{ID: ptr.To("asg-1")},
+						},
+						DestinationPortRange: ptr.To("*"),
+						Priority:             ptr.To(int32(4095)),
+					},
+				},
+				addressRule(),
+			}
+		}
+	)
+
+	t.Run("it should not touch the rules targeting application security groups when removing addresses", func(t *testing.T) {
+		var (
+			sg     = fx.Azure().SecurityGroup().WithRules(rules()).Build()
+			helper = ExpectNewSecurityGroupHelper(t, sg)
+		)
+		assert.NoError(t, helper.RemoveDestinationFromRules(armnetwork.SecurityRuleProtocolAsterisk, []string{"20.0.0.2"}, nil))
+		assert.NoError(t, helper.RemoveDestinationFromRules(armnetwork.SecurityRuleProtocolTCP, []string{"20.0.0.2"}, nil))
+
+		_, updated, err := helper.SecurityGroup()
+		assert.NoError(t, err)
+		assert.False(t, updated)
+	})
+
+	t.Run("it should remove the application security group from the rules", func(t *testing.T) {
+		var (
+			sg     = fx.Azure().SecurityGroup().WithRules(rules()).Build()
+			helper = ExpectNewSecurityGroupHelper(t, sg)
+		)
+		assert.NoError(t, helper.RemoveApplicationSecurityGroupFromRules(armnetwork.SecurityRuleProtocolTCP, []string{"asg-1"}, nil))
+		assert.NoError(t, helper.RemoveApplicationSecurityGroupFromRules(armnetwork.SecurityRuleProtocolAsterisk, []string{"asg-1"}, nil))
+
+		outputSG, updated, err := helper.SecurityGroup()
+		assert.NoError(t, err)
+		assert.True(t, updated)
+
+		expected := rules()
+		expected[0].Properties.DestinationApplicationSecurityGroups = []*armnetwork.ApplicationSecurityGroup{{ID: ptr.To("asg-2")}}
+		// The deny rule without destination is removed.
+		expected = []*armnetwork.SecurityRule{expected[0], expected[2]}
+		testutil.ExpectEqualInJSON(t, expected, outputSG.Properties.SecurityRules)
+	})
+
+	t.Run("it should split the rule if some ports are retained", func(t *testing.T) {
+		var (
+			sg     = fx.Azure().SecurityGroup().WithRules(rules()).Build()
+			helper = ExpectNewSecurityGroupHelper(t, sg)
+		)
+		assert.NoError(t, helper.RemoveApplicationSecurityGroupFromRules(armnetwork.SecurityRuleProtocolTCP, []string{"asg-1"}, []int32{443}))
+
+		outputSG, updated, err := helper.SecurityGroup()
+		assert.NoError(t, err)
+		assert.True(t, updated)
+
+		var (
+			expected  = rules()
+			splitRule = &armnetwork.SecurityRule{
+				Name: ptr.To(GenerateAllowApplicationSecurityGroupRuleName(armnetwork.SecurityRuleProtocolTCP, iputil.IPv4, srcPrefixes, []int32{443})),
+				Properties: &armnetwork.SecurityRulePropertiesFormat{
+					Protocol:            to.Ptr(armnetwork.SecurityRuleProtocolTCP),
+					Access:              to.Ptr(armnetwork.SecurityRuleAccessAllow),
+					Direction:           to.Ptr(armnetwork.SecurityRuleDirectionInbound),
+					SourceAddressPrefix: ptr.To("10.0.0.0/16"),
+					SourcePortRange:     ptr.To("*"),
+					DestinationApplicationSecurityGroups: []*armnetwork.ApplicationSecurityGroup{
+						{ID: ptr.To("asg-1")},
+					},
+					DestinationPortRanges: to.SliceOfPtrs("443"),
+					Priority:              ptr.To(int32(501)),
+				},
+			}
+		)
+		expected[0].Properties.DestinationApplicationSecurityGroups = []*armnetwork.ApplicationSecurityGroup{{ID: ptr.To("asg-2")}}
+		// The rules are sorted by priority.
+		testutil.ExpectEqualInJSON(t, []*armnetwork.SecurityRule{expected[0], splitRule, expected[2], expected[1]}, outputSG.Properties.SecurityRules)
+	})
+}
+
+func TestRuleHelper_Compact(t *testing.T) {
+	var (
+		fx          = fixture.NewFixture()
+		srcPrefixes = []string{"10.0.0.0/16"}
+		srcRanges   = []netip.Prefix{netip.MustParsePrefix("10.0.0.0/16")}
+		dstA        = []netip.Addr{netip.MustParseAddr("20.0.0.1")}
+		dstB        = []netip.Addr{netip.MustParseAddr("20.0.0.2")}
+		dstAB       = append(append([]netip.Addr{}, dstA...), dstB...)
+		protocol    = armnetwork.SecurityRuleProtocolTCP
+	)
+
+	t.Run("it should merge the rules only differ in the destination ports", func(t *testing.T) {
+		var (
+			sg     = fx.Azure().SecurityGroup().WithRules(fx.Azure().NoiseSecurityRules()).Build()
+			helper = ExpectNewSecurityGroupHelper(t, sg)
+		)
+		assert.NoError(t, helper.AddRuleForAllowedIPRanges(srcRanges, protocol, dstAB, []int32{80}))
+		assert.NoError(t, helper.AddRuleForAllowedIPRanges(srcRanges, protocol, dstAB, []int32{443}))
+		assert.NoError(t, helper.AddRuleForAllowedIPRanges(srcRanges, protocol, dstA, []int32{8080}))
+		assert.Equal(t, 1, helper.Compact())
+
+		outputSG, updated, err := helper.SecurityGroup()
+		assert.NoError(t, err)
+		assert.True(t, updated)
+		testutil.ExpectHasSecurityRules(t, outputSG, []*armnetwork.SecurityRule{
+			{
+				Name: ptr.To(GenerateAllowSecurityRuleName(protocol, iputil.IPv4, srcPrefixes, []int32{80, 443})),
+				Properties: &armnetwork.SecurityRulePropertiesFormat{
+					Protocol:                   to.Ptr(protocol),
+					Access:                     to.Ptr(armnetwork.SecurityRuleAccessAllow),
+					Direction:                  to.Ptr(armnetwork.SecurityRuleDirectionInbound),
+					SourceAddressPrefix:        ptr.To("10.0.0.0/16"),
+					SourcePortRange:            ptr.To("*"),
+					DestinationAddressPrefixes: to.SliceOfPtrs("20.0.0.1", "20.0.0.2"),
+					DestinationPortRanges:      to.SliceOfPtrs("443", "80"),
+					Priority:                   ptr.To(int32(500)),
+				},
+			},
+			{
+				Name: ptr.To(GenerateAllowSecurityRuleName(protocol, iputil.IPv4, srcPrefixes, []int32{8080})),
+				Properties: &armnetwork.SecurityRulePropertiesFormat{
+					Protocol:                 to.Ptr(protocol),
+					Access:                   to.Ptr(armnetwork.SecurityRuleAccessAllow),
+					Direction:                to.Ptr(armnetwork.SecurityRuleDirectionInbound),
+					SourceAddressPrefix:      ptr.To("10.0.0.0/16"),
+					SourcePortRange:          ptr.To("*"),
+					DestinationAddressPrefix: ptr.To("20.0.0.1"),
+					DestinationPortRanges:    to.SliceOfPtrs("8080"),
+					Priority:                 ptr.To(int32(502)),
+				},
+			},
+		})
+		assert.Equal(t, len(fx.Azure().NoiseSecurityRules())+2, helper.Usage().Rules)
+	})
+
+	t.Run("it should merge the destination into the rule with the same destination ports", func(t *testing.T) {
+		var (
+			sg     = fx.Azure().SecurityGroup().Build()
+			helper = ExpectNewSecurityGroupHelper(t, sg)
+		)
+		assert.NoError(t, helper.AddRuleForAllowedIPRanges(srcRanges, protocol, dstB, []int32{80, 443}))
+		assert.NoError(t, helper.AddRuleForAllowedIPRanges(srcRanges, protocol, dstA, []int32{80}))
+		assert.NoError(t, helper.AddRuleForAllowedIPRanges(srcRanges, protocol, dstA, []int32{443}))
+		assert.Equal(t, 2, helper.Compact())
+
+		outputSG, _, err := helper.SecurityGroup()
+		assert.NoError(t, err)
+		assert.Len(t, outputSG.Properties.SecurityRules, 1)
+		rule := outputSG.Properties.SecurityRules[0]
+		assert.Equal(t, GenerateAllowSecurityRuleName(protocol, iputil.IPv4, srcPrefixes, []int32{80, 443}), *rule.Name)
+		assert.Equal(t, []string{"20.0.0.1", "20.0.0.2"}, ListDestinationPrefixes(rule))
+		assert.Equal(t, int32(500), *rule.Properties.Priority)
+	})
+
+	t.Run("it should not change the security group if the compacted rules are not changed", func(t *testing.T) {
+		var (
+			sg     = fx.Azure().SecurityGroup().Build()
+			helper = ExpectNewSecurityGroupHelper(t, sg)
+		)
+		assert.NoError(t, helper.AddRuleForAllowedIPRanges(srcRanges, protocol, dstAB, []int32{80}))
+		assert.NoError(t, helper.AddRuleForAllowedIPRanges(srcRanges, protocol, dstAB, []int32{443}))
+		helper.Compact()
+		compactedSG, _, err := helper.SecurityGroup()
+		assert.NoError(t, err)
+
+		// Reconcile the service with destination A and port 80, while port 443 is used by another service on A.
+		helper = ExpectNewSecurityGroupHelper(t, testutil.CloneInJSON(compactedSG))
+		assert.NoError(t, helper.RemoveDestinationFromRules(protocol, []string{"20.0.0.1"}, []int32{443}))
+		assert.NoError(t, helper.AddRuleForAllowedIPRanges(srcRanges, protocol, dstA, []int32{80}))
+		helper.Compact()
+		outputSG, updated, err := helper.SecurityGroup()
+		assert.NoError(t, err)
+		assert.False(t, updated)
+		testutil.ExpectEqualInJSON(t, compactedSG, outputSG)
+	})
+
+	t.Run("it should reclaim the priorities of the rules without destination", func(t *testing.T) {
+		var (
+			sg     = fx.Azure().SecurityGroup().Build()
+			helper = ExpectNewSecurityGroupHelper(t, sg)
+		)
+		assert.NoError(t, helper.AddRuleForAllowedIPRanges(srcRanges, protocol, dstA, []int32{80}))
+		assert.NoError(t, helper.RemoveDestinationFromRules(protocol, []string{"20.0.0.1"}, nil))
+		assert.Equal(t, 1, helper.Compact())
+
+		assert.NoError(t, helper.AddRuleForAllowedIPRanges(srcRanges, protocol, dstB, []int32{443}))
+		outputSG, _, err := helper.SecurityGroup()
+		assert.NoError(t, err)
+		assert.Len(t, outputSG.Properties.SecurityRules, 1)
+		assert.Equal(t, int32(500), *outputSG.Properties.SecurityRules[0].Properties.Priority)
+	})
+
+	t.Run("it should not merge the rules not created by the cloud provider", func(t *testing.T) {
+		var (
+			rules  = fx.Azure().NoiseSecurityRules()
+			sg     = fx.Azure().SecurityGroup().WithRules(rules).Build()
+			helper = ExpectNewSecurityGroupHelper(t, sg)
+		)
+		assert.Equal(t, 0, helper.Compact())
+		_, updated, err := helper.SecurityGroup()
+		assert.NoError(t, err)
+		assert.False(t, updated)
+	})
+}
@@ -31,6 +31,14 @@ import (
 	"sigs.k8s.io/cloud-provider-azure/pkg/util/iputil"
 )
 
+// The kinds of the security rules created by the cloud provider, which are part of the rule names.
+const (
+	securityRuleKindAllow                           = "allow"
+	securityRuleKindAllowApplicationSecurityGroup   = "allow-asg"
+	securityRuleKindDenyAll                         = "deny-all"
+	securityRuleKindDenyAllApplicationSecurityGroup = "deny-all-asg"
+)
+
 // GenerateAllowSecurityRuleName returns the AllowInbound rule name based on the given rule properties.
 func GenerateAllowSecurityRuleName(
 	protocol armnetwork.SecurityRuleProtocol,
@@ -38,31 +46,66 @@ func GenerateAllowSecurityRuleName(
 	srcPrefixes []string,
 	dstPorts []int32,
 ) string {
-	var ruleID string
-	{
-		dstPortRanges := fnutil.Map(func(p int32) string { return strconv.FormatInt(int64(p), 10) }, dstPorts)
-		// Generate rule ID from protocol, source prefixes and destination port ranges.
-		sort.Strings(srcPrefixes)
-		sort.Strings(dstPortRanges)
-
-		v := strings.Join([]string{
-			string(protocol),
-			strings.Join(srcPrefixes, ","),
-			strings.Join(dstPortRanges, ","),
-		}, "_")
-
-		h := md5.New() //nolint:gosec
-		h.Write([]byte(v))
-
-		ruleID = fmt.Sprintf("%x", h.Sum(nil))
-	}
+	ruleID := generateAllowSecurityRuleID(protocol, srcPrefixes, dstPorts)
+	return strings.Join([]string{SecurityRuleNamePrefix, securityRuleKindAllow, string(ipFamily), ruleID}, SecurityRuleNameSep)
+}
 
-	return strings.Join([]string{SecurityRuleNamePrefix, "allow", string(ipFamily), ruleID}, SecurityRuleNameSep)
+// GenerateAllowApplicationSecurityGroupRuleName returns the AllowInbound rule name targeting application security groups
+// based on the given rule properties. The rules targeting application security groups are kept apart from the ones
+// targeting IP addresses, because a security rule cannot have both of them as destination.
+func GenerateAllowApplicationSecurityGroupRuleName(
+	protocol armnetwork.SecurityRuleProtocol,
+	ipFamily iputil.Family,
+	srcPrefixes []string,
+	dstPorts []int32,
+) string {
+	ruleID := generateAllowSecurityRuleID(protocol, srcPrefixes, dstPorts)
+	return strings.Join([]string{SecurityRuleNamePrefix, securityRuleKindAllowApplicationSecurityGroup, string(ipFamily), ruleID}, SecurityRuleNameSep)
+}
+
+// generateAllowSecurityRuleID generates rule ID from protocol, source prefixes and destination port ranges.
+func generateAllowSecurityRuleID(
+	protocol armnetwork.SecurityRuleProtocol,
+	srcPrefixes []string,
+	dstPorts []int32,
+) string {
+	dstPortRanges := fnutil.Map(func(p int32) string { return strconv.FormatInt(int64(p), 10) }, dstPorts)
+	sort.Strings(srcPrefixes)
+	sort.Strings(dstPortRanges)
+
+	v := strings.Join([]string{
+		string(protocol),
+		strings.Join(srcPrefixes, ","),
+		strings.Join(dstPortRanges, ","),
+	}, "_")
+
+	h := md5.New() //nolint:gosec
+	h.Write([]byte(v))
+
+	return fmt.Sprintf("%x", h.Sum(nil))
 }
 
 // GenerateDenyAllSecurityRuleName returns the DenyInbound rule name based on the given rule properties.
 func GenerateDenyAllSecurityRuleName(ipFamily iputil.Family) string {
-	return strings.Join([]string{SecurityRuleNamePrefix, "deny-all", string(ipFamily)}, SecurityRuleNameSep)
+	return strings.Join([]string{SecurityRuleNamePrefix, securityRuleKindDenyAll, string(ipFamily)}, SecurityRuleNameSep)
+}
+
+// GenerateDenyAllApplicationSecurityGroupRuleName returns the DenyInbound rule name targeting application security groups.
+func GenerateDenyAllApplicationSecurityGroupRuleName(ipFamily iputil.Family) string {
+	return strings.Join([]string{SecurityRuleNamePrefix, securityRuleKindDenyAllApplicationSecurityGroup, string(ipFamily)}, SecurityRuleNameSep)
+}
+
+// ipFamilyOfRuleName returns the IP family in the name of the security rule generated by the cloud provider.
+func ipFamilyOfRuleName(name string) (iputil.Family, bool) {
+	parts := strings.Split(name, SecurityRuleNameSep)
+	if len(parts) < 3 || parts[0] != SecurityRuleNamePrefix {
+		return "", false
+	}
+	switch iputil.Family(parts[2]) {
+	case iputil.IPv4, iputil.IPv6:
+		return iputil.Family(parts[2]), true
+	}
+	return "", false
 }
 
 // NormalizeSecurityRuleAddressPrefixes normalizes the given rule address prefixes.
@@ -120,6 +163,27 @@ func SetDestinationPrefixes(r *armnetwork.SecurityRule, prefixes []string) {
 	}
 }
 
+func ListDestinationApplicationSecurityGroupIDs(r *armnetwork.SecurityRule) []string {
+	var rv []string
+	for _, asg := range r.Properties.DestinationApplicationSecurityGroups {
+		if asg != nil && asg.ID != nil {
+			rv = append(rv, *asg.ID)
+		}
+	}
+	return rv
+}
+
+func SetDestinationApplicationSecurityGroupIDs(r *armnetwork.SecurityRule, ids []string) {
+	ids = NormalizeSecurityRuleAddressPrefixes(ids)
+	if len(ids) == 0 {
+		r.Properties.DestinationApplicationSecurityGroups = nil
+		return
+	}
+	r.Properties.DestinationApplicationSecurityGroups = fnutil.Map(func(id string) *armnetwork.ApplicationSecurityGroup {
+		return &armnetwork.ApplicationSecurityGroup{ID: to.Ptr(id)}
+	}, ids)
+}
+
 func ListDestinationPortRanges(r *armnetwork.SecurityRule) ([]int32, error) {
 	var values []*string
 	if r.Properties.DestinationPortRange != nil {
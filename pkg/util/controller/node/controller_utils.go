@@ -101,3 +101,16 @@ func RecordNodeStatusChange(recorder record.EventRecorder, node *v1.Node, newSta
 	// and event is recorded or neither should happen, see issue #6055.
 	recorder.Eventf(ref, v1.EventTypeNormal, newStatus, "Node %s status is now: %s", node.Name, newStatus)
 }
+
+// RecordNodeEvent records an event related to a node.
+func RecordNodeEvent(recorder record.EventRecorder, node *v1.Node, eventtype, reason, event string) {
+	ref := &v1.ObjectReference{
+		APIVersion: "v1",
+		Kind:       "Node",
+		Name:       node.Name,
+		UID:        node.UID,
+		Namespace:  "",
+	}
+	klog.V(2).Infof("Recording %s event message for node %s", event, node.Name)
+	recorder.Eventf(ref, eventtype, reason, "Node %s event: %s", node.Name, event)
+}